@@ -0,0 +1,192 @@
+// Package agent runs tool loops: it sends a conversation to a model, runs the
+// tools the model asks for, sends back their results, and repeats until the
+// model answers. Models with native function calling use it; models without
+// it run a ReAct loop in plain text instead, behind the same RunTools call.
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultMaxSteps is the number of tool rounds allowed when WithMaxSteps is
+// not used.
+const defaultMaxSteps = 10
+
+// Strategy is how a model is asked to call tools.
+type Strategy string
+
+// Strategies.
+const (
+	// StrategyAuto uses StrategyReAct for providers whose Capabilities report
+	// no tool support, and StrategyNative otherwise. It is the default.
+	StrategyAuto Strategy = "auto"
+
+	// StrategyNative uses the provider's function calling.
+	StrategyNative Strategy = "native"
+
+	// StrategyReAct runs a Thought/Action/Observation loop in plain text, for
+	// models without function calling.
+	StrategyReAct Strategy = "react"
+)
+
+// ErrMaxSteps is returned when the model still asks for tools after the
+// maximum number of tool rounds.
+var ErrMaxSteps = stderrors.New("agent: maximum tool steps reached")
+
+// Option configures a run.
+type Option func(*runner) error
+
+// Result is the outcome of a run.
+type Result struct {
+	// Completion is the last response, whose first choice holds the answer.
+	// With StrategyReAct its content is the final answer alone.
+	Completion *providers.ChatCompletion
+
+	// Messages is the conversation: the messages the run started with, then
+	// each tool call, its results, and the answer, in the same form for every
+	// strategy.
+	Messages []providers.Message
+
+	// Steps is the number of tool rounds run.
+	Steps int
+
+	// Strategy is the strategy the run used.
+	Strategy Strategy
+}
+
+// ToolFunc runs a tool with the arguments the model gave, as JSON. An error
+// is reported to the model as a failed tool result, so it can recover; to
+// stop the run, cancel ctx.
+type ToolFunc func(ctx context.Context, arguments string) (providers.ToolResult, error)
+
+// runner holds the state of a run.
+type runner struct {
+	maxSteps int
+	provider providers.Provider
+	strategy Strategy
+	tools    map[string]ToolFunc
+}
+
+// RunTools sends params to provider and runs the tools it asks for, by name
+// from tools, until it answers without calling any. params.Tools describes
+// the tools to the model. The run stops with ErrMaxSteps, along with the
+// result so far, if the model keeps calling tools.
+func RunTools(
+	ctx context.Context,
+	provider providers.Provider,
+	params providers.CompletionParams,
+	tools map[string]ToolFunc,
+	opts ...Option,
+) (*Result, error) {
+	r := &runner{maxSteps: defaultMaxSteps, provider: provider, strategy: StrategyAuto, tools: tools}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tool := range params.Tools {
+		if _, ok := tools[tool.Function.Name]; !ok {
+			return nil, fmt.Errorf("tool %q has no function", tool.Function.Name)
+		}
+	}
+
+	if r.strategy == StrategyAuto {
+		r.strategy = StrategyNative
+		if cp, ok := providers.As[providers.CapabilityProvider](provider); ok && !cp.Capabilities().CompletionTools {
+			r.strategy = StrategyReAct
+		}
+	}
+
+	if r.strategy == StrategyReAct {
+		return r.runReAct(ctx, params)
+	}
+	return r.runNative(ctx, params)
+}
+
+// WithMaxSteps sets how many tool rounds a run may take. The default is 10.
+func WithMaxSteps(steps int) Option {
+	return func(r *runner) error {
+		if steps <= 0 {
+			return fmt.Errorf("max steps must be positive, got %d", steps)
+		}
+
+		r.maxSteps = steps
+		return nil
+	}
+}
+
+// WithStrategy sets how the model is asked to call tools. The default is
+// StrategyAuto.
+func WithStrategy(strategy Strategy) Option {
+	return func(r *runner) error {
+		switch strategy {
+		case StrategyAuto, StrategyNative, StrategyReAct:
+			r.strategy = strategy
+			return nil
+		default:
+			return fmt.Errorf("unknown strategy %q", strategy)
+		}
+	}
+}
+
+// runNative runs the tool loop with the provider's function calling.
+func (r *runner) runNative(ctx context.Context, params providers.CompletionParams) (*Result, error) {
+	result := &Result{Messages: params.Messages, Strategy: StrategyNative}
+
+	for {
+		params.Messages = result.Messages
+		resp, err := r.provider.Completion(ctx, params)
+		if err != nil {
+			return result, err
+		}
+		if len(resp.Choices) == 0 {
+			return result, fmt.Errorf("agent: response has no choices")
+		}
+
+		result.Completion = resp
+		msg := resp.Choices[0].Message
+		result.Messages = append(result.Messages[:len(result.Messages):len(result.Messages)], msg)
+		if len(msg.ToolCalls) == 0 {
+			return result, nil
+		}
+		if result.Steps == r.maxSteps {
+			return result, ErrMaxSteps
+		}
+
+		result.Steps++
+		for _, call := range msg.ToolCalls {
+			toolResult, err := r.runTool(ctx, call.Function.Name, call.Function.Arguments)
+			if err != nil {
+				return result, err
+			}
+			result.Messages = append(result.Messages, providers.NewToolResultMessage(call.ID, toolResult))
+		}
+	}
+}
+
+// runTool runs the named tool. Tool failures become failed results; only a
+// cancelled ctx is returned as an error.
+func (r *runner) runTool(ctx context.Context, name, arguments string) (providers.ToolResult, error) {
+	fn, ok := r.tools[name]
+	if !ok {
+		return providers.ToolResult{Content: fmt.Sprintf("unknown tool %q", name), IsError: true}, nil
+	}
+
+	result, err := fn(ctx, arguments)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return providers.ToolResult{}, ctxErr
+	}
+	if err != nil {
+		return providers.ToolResult{Content: err.Error(), IsError: true}, nil
+	}
+	return result, nil
+}
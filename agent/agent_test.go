@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// scripted returns a mock provider that replies with each message in turn.
+func scripted(replies ...providers.Message) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		reply := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+
+		finish := providers.FinishReasonStop
+		if len(reply.ToolCalls) > 0 {
+			finish = providers.FinishReasonToolCalls
+		}
+		return &providers.ChatCompletion{
+			ID:      "cmpl-1",
+			Model:   params.Model,
+			Choices: []providers.Choice{{Message: reply, FinishReason: finish}},
+		}, nil
+	}
+	return mock
+}
+
+// weather is a get_weather tool function.
+func weather(_ context.Context, arguments string) (providers.ToolResult, error) {
+	return providers.ToolResult{Content: "sunny, args " + arguments}, nil
+}
+
+func TestRunTools(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "model",
+		Messages: testutil.SimpleMessages(),
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	}
+	tools := map[string]ToolFunc{"get_weather": weather}
+
+	t.Run("runs native tool calls", func(t *testing.T) {
+		t.Parallel()
+
+		mock := scripted(
+			providers.Message{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Type:     providers.ToolChoiceTypeFunction,
+				Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+			}}},
+			providers.Message{Role: providers.RoleAssistant, Content: "It is sunny."},
+		)
+		mock.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionTools: true}
+		}
+
+		result, err := RunTools(context.Background(), mock, params, tools)
+		require.NoError(t, err)
+		require.Equal(t, StrategyNative, result.Strategy)
+		require.Equal(t, 1, result.Steps)
+		require.Equal(t, "It is sunny.", result.Completion.Choices[0].Message.ContentString())
+		require.Len(t, result.Messages, len(params.Messages)+3)
+		require.Len(t, params.Messages, 1)
+
+		toolMsg := result.Messages[len(params.Messages)+1]
+		require.Equal(t, providers.RoleTool, toolMsg.Role)
+		require.Equal(t, "call_1", toolMsg.ToolCallID)
+		require.Equal(t, `sunny, args {"location":"Paris"}`, toolMsg.ContentString())
+
+		require.Len(t, mock.CompletionCalls, 2)
+		require.Len(t, mock.CompletionCalls[1].Tools, 1)
+		require.Len(t, mock.CompletionCalls[1].Messages, len(params.Messages)+2)
+	})
+
+	t.Run("runs a ReAct loop for providers without tools", func(t *testing.T) {
+		t.Parallel()
+
+		mock := scripted(
+			providers.Message{
+				Role:    providers.RoleAssistant,
+				Content: "Thought: I need the weather.\nAction: get_weather\nAction Input: {\"location\": \"Paris\"}",
+			},
+			providers.Message{
+				Role:    providers.RoleAssistant,
+				Content: "Thought: I know the answer\nFinal Answer: It is sunny.",
+			},
+		)
+
+		result, err := RunTools(context.Background(), mock, params, tools)
+		require.NoError(t, err)
+		require.Equal(t, StrategyReAct, result.Strategy)
+		require.Equal(t, 1, result.Steps)
+		require.Equal(t, "It is sunny.", result.Completion.Choices[0].Message.ContentString())
+
+		call := result.Messages[len(params.Messages)]
+		require.Equal(t, "I need the weather.", call.ContentString())
+		require.Len(t, call.ToolCalls, 1)
+		require.Equal(t, "get_weather", call.ToolCalls[0].Function.Name)
+		require.Equal(t, call.ToolCalls[0].ID, result.Messages[len(params.Messages)+1].ToolCallID)
+
+		sent := mock.CompletionCalls[1]
+		require.Empty(t, sent.Tools)
+		require.Contains(t, sent.Stop, "Observation:")
+		require.Equal(t, providers.RoleSystem, sent.Messages[0].Role)
+		require.Contains(t, sent.Messages[0].ContentString(), "- get_weather: ")
+		last := sent.Messages[len(sent.Messages)-1]
+		require.Equal(t, providers.RoleUser, last.Role)
+		require.Equal(t, `Observation: sunny, args {"location": "Paris"}`, last.ContentString())
+	})
+
+	t.Run("reports tool failures to the model", func(t *testing.T) {
+		t.Parallel()
+
+		mock := scripted(
+			providers.Message{Role: providers.RoleAssistant, Content: "Action: get_weather\nAction Input: {}"},
+			providers.Message{Role: providers.RoleAssistant, Content: "Action: get_stock_price\nAction Input: {}"},
+			providers.Message{Role: providers.RoleAssistant, Content: "Final Answer: I could not find out."},
+		)
+		failing := map[string]ToolFunc{
+			"get_weather": func(context.Context, string) (providers.ToolResult, error) {
+				return providers.ToolResult{}, stderrors.New("service unavailable")
+			},
+		}
+
+		result, err := RunTools(context.Background(), mock, params, failing, WithStrategy(StrategyReAct))
+		require.NoError(t, err)
+		require.Equal(t, 2, result.Steps)
+
+		sent := mock.CompletionCalls[2].Messages
+		require.Equal(t, "Observation: Error: service unavailable", sent[len(sent)-3].ContentString())
+		require.Equal(t, `Observation: Error: unknown tool "get_stock_price"`, sent[len(sent)-1].ContentString())
+	})
+
+	t.Run("stops after the maximum steps", func(t *testing.T) {
+		t.Parallel()
+
+		mock := scripted(providers.Message{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+			ID:       "call_1",
+			Function: providers.FunctionCall{Name: "get_weather", Arguments: "{}"},
+		}}})
+
+		result, err := RunTools(context.Background(), mock, params, tools,
+			WithStrategy(StrategyNative), WithMaxSteps(2))
+		require.ErrorIs(t, err, ErrMaxSteps)
+		require.Equal(t, 2, result.Steps)
+		require.Len(t, mock.CompletionCalls, 3)
+	})
+
+	t.Run("requires a function for every tool", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RunTools(context.Background(), testutil.NewMockProvider(), params, nil)
+		require.ErrorContains(t, err, `tool "get_weather" has no function`)
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RunTools(context.Background(), testutil.NewMockProvider(), params, tools, WithMaxSteps(0))
+		require.Error(t, err)
+
+		_, err = RunTools(context.Background(), testutil.NewMockProvider(), params, tools, WithStrategy("plan"))
+		require.Error(t, err)
+	})
+}
+
+func TestParseReActStep(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want reactStep
+	}{
+		{
+			name: "action",
+			text: "Thought: I need the weather.\nAction: get_weather\nAction Input: {\"location\": \"Paris\"}",
+			want: reactStep{action: "get_weather", input: `{"location": "Paris"}`, text: "I need the weather."},
+		},
+		{
+			name: "action with fenced input and observation",
+			text: "Action: get_weather\nAction Input: ```json\n{}\n```\nObservation: sunny",
+			want: reactStep{action: "get_weather", input: "{}"},
+		},
+		{
+			name: "action without input",
+			text: "Action: get_current_date",
+			want: reactStep{action: "get_current_date", input: "{}"},
+		},
+		{
+			name: "final answer",
+			text: "Thought: I know the answer\nFinal Answer: It is sunny.",
+			want: reactStep{text: "It is sunny."},
+		},
+		{
+			name: "plain text",
+			text: " It is sunny. ",
+			want: reactStep{text: "It is sunny."},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, parseReActStep(tc.text))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/toolemu"
+)
+
+// ReAct reply markers.
+const (
+	markerAction      = "Action:"
+	markerActionInput = "Action Input:"
+	markerFinalAnswer = "Final Answer:"
+	markerObservation = "Observation:"
+	markerThought     = "Thought:"
+)
+
+// reactInstructions is the system prompt of a ReAct run. %s is the list of
+// tools.
+const reactInstructions = `You can use these tools:
+
+%s
+To use a tool, reply in exactly this format and stop:
+
+Thought: what you need to do next
+Action: the tool name
+Action Input: the tool arguments, as a JSON object
+
+The tool result will follow as "Observation: ...". Repeat as needed. When you know the answer, reply:
+
+Thought: I know the answer
+Final Answer: the answer`
+
+// toolCallIDPrefix prefixes the IDs of tool calls in ReAct runs.
+const toolCallIDPrefix = "call_"
+
+// reactStep is a parsed ReAct reply.
+type reactStep struct {
+	// action is the name of the tool to call, or empty for an answer.
+	action string
+
+	// input is the tool arguments.
+	input string
+
+	// text is the thought before an action, or the answer.
+	text string
+}
+
+// runReAct runs the tool loop as a Thought/Action/Observation exchange in
+// plain text.
+func (r *runner) runReAct(ctx context.Context, params providers.CompletionParams) (*Result, error) {
+	result := &Result{Messages: params.Messages, Strategy: StrategyReAct}
+	tools := params.Tools
+
+	params.Tools = nil
+	params.ToolChoice = nil
+	params.ParallelToolCalls = nil
+	params.Stop = append(slices.Clone(params.Stop), markerObservation)
+
+	for {
+		params.Messages = reactMessages(result.Messages, tools)
+		resp, err := r.provider.Completion(ctx, params)
+		if err != nil {
+			return result, err
+		}
+		if len(resp.Choices) == 0 {
+			return result, fmt.Errorf("agent: response has no choices")
+		}
+
+		result.Completion = resp
+		choice := &resp.Choices[0]
+		step := parseReActStep(choice.Message.ContentString())
+		if step.action == "" {
+			choice.Message.Content = step.text
+			result.Messages = append(result.Messages[:len(result.Messages):len(result.Messages)], choice.Message)
+			return result, nil
+		}
+
+		call := providers.ToolCall{
+			ID:       generateID(),
+			Type:     providers.ToolChoiceTypeFunction,
+			Function: providers.FunctionCall{Name: step.action, Arguments: step.input},
+		}
+		choice.Message = providers.Message{
+			Role:      providers.RoleAssistant,
+			Content:   step.text,
+			ToolCalls: []providers.ToolCall{call},
+		}
+		choice.FinishReason = providers.FinishReasonToolCalls
+		result.Messages = append(result.Messages[:len(result.Messages):len(result.Messages)], choice.Message)
+		if result.Steps == r.maxSteps {
+			return result, ErrMaxSteps
+		}
+
+		result.Steps++
+		toolResult := providers.ToolResult{Content: "Action Input must be a JSON object.", IsError: true}
+		if isObject(step.input) {
+			toolResult, err = r.runTool(ctx, step.action, step.input)
+			if err != nil {
+				return result, err
+			}
+		}
+		result.Messages = append(result.Messages, providers.NewToolResultMessage(call.ID, toolResult))
+	}
+}
+
+// generateID returns a random tool call ID.
+func generateID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b) // crypto/rand.Read never fails.
+	return toolCallIDPrefix + hex.EncodeToString(b)
+}
+
+// isObject reports whether text is a JSON object.
+func isObject(text string) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal([]byte(text), &object) == nil && object != nil
+}
+
+// parseReActStep parses a ReAct reply. A reply with a final answer, or with
+// neither an answer nor an action, is an answer.
+func parseReActStep(text string) reactStep {
+	if i := strings.LastIndex(text, markerFinalAnswer); i >= 0 {
+		return reactStep{text: strings.TrimSpace(text[i+len(markerFinalAnswer):])}
+	}
+
+	i := strings.LastIndex(text, markerAction)
+	if i < 0 {
+		return reactStep{text: strings.TrimSpace(text)}
+	}
+
+	thought := strings.TrimSpace(text[:i])
+	thought = strings.TrimSpace(strings.TrimPrefix(thought, markerThought))
+	rest := text[i+len(markerAction):]
+	if j := strings.Index(rest, markerObservation); j >= 0 {
+		rest = rest[:j]
+	}
+
+	action, input, _ := strings.Cut(rest, markerActionInput)
+	action, _, _ = strings.Cut(strings.TrimSpace(action), "\n")
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "```json")
+	input = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(input, "```"), "```"))
+	if input == "" {
+		input = "{}"
+	}
+
+	return reactStep{action: strings.TrimSpace(action), input: input, text: thought}
+}
+
+// reactMessages renders messages as a ReAct exchange: the tools in the system
+// prompt, tool calls as actions, and tool results as observations.
+func reactMessages(messages []providers.Message, tools []providers.Tool) []providers.Message {
+	converted := make([]providers.Message, 0, len(messages)+1)
+	for _, msg := range messages {
+		switch {
+		case msg.Role == providers.RoleAssistant && len(msg.ToolCalls) > 0:
+			var b strings.Builder
+			if thought := msg.ContentString(); thought != "" {
+				fmt.Fprintf(&b, "%s %s\n", markerThought, thought)
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&b, "%s %s\n%s %s\n", markerAction, tc.Function.Name, markerActionInput, tc.Function.Arguments)
+			}
+			converted = append(converted, providers.Message{
+				Role:    providers.RoleAssistant,
+				Content: strings.TrimSpace(b.String()),
+			})
+		case msg.Role == providers.RoleTool:
+			converted = append(converted, providers.Message{
+				Role:    providers.RoleUser,
+				Content: markerObservation + " " + msg.ContentString(),
+			})
+		default:
+			converted = append(converted, msg)
+		}
+	}
+
+	prompt := fmt.Sprintf(reactInstructions, toolemu.DescribeTools(tools))
+	if len(converted) > 0 && converted[0].Role == providers.RoleSystem && !converted[0].IsMultiModal() {
+		converted[0].Content = converted[0].ContentString() + "\n\n" + prompt
+		return converted
+	}
+	system := providers.Message{Role: providers.RoleSystem, Content: prompt}
+	return append([]providers.Message{system}, converted...)
+}
@@ -18,6 +18,7 @@
 package anyllm
 
 import (
+	"github.com/mozilla-ai/any-llm-go/bulk"
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
 	"github.com/mozilla-ai/any-llm-go/providers"
@@ -31,10 +32,19 @@ const (
 	RoleUser      = providers.RoleUser
 )
 
+// Content filter severities.
+const (
+	SeveritySafe   = providers.SeveritySafe
+	SeverityLow    = providers.SeverityLow
+	SeverityMedium = providers.SeverityMedium
+	SeverityHigh   = providers.SeverityHigh
+)
+
 // Finish reasons.
 const (
 	FinishReasonContentFilter = providers.FinishReasonContentFilter
 	FinishReasonLength        = providers.FinishReasonLength
+	FinishReasonRefusal       = providers.FinishReasonRefusal
 	FinishReasonStop          = providers.FinishReasonStop
 	FinishReasonToolCalls     = providers.FinishReasonToolCalls
 )
@@ -48,26 +58,143 @@ const (
 	ReasoningEffortNone   = providers.ReasoningEffortNone
 )
 
+// Capability names for WithCapabilityOverrides.
+const (
+	CapabilityCompletion           = config.CapabilityCompletion
+	CapabilityCompletionImage      = config.CapabilityCompletionImage
+	CapabilityCompletionJSONObject = config.CapabilityCompletionJSONObject
+	CapabilityCompletionJSONSchema = config.CapabilityCompletionJSONSchema
+	CapabilityCompletionPDF        = config.CapabilityCompletionPDF
+	CapabilityCompletionReasoning  = config.CapabilityCompletionReasoning
+	CapabilityCompletionStreaming  = config.CapabilityCompletionStreaming
+	CapabilityCompletionTools      = config.CapabilityCompletionTools
+	CapabilityEmbedding            = config.CapabilityEmbedding
+	CapabilityListModels           = config.CapabilityListModels
+)
+
+// Request compressions for WithRequestCompression.
+const (
+	CompressionGzip = config.CompressionGzip
+	CompressionZstd = config.CompressionZstd
+)
+
+// Reasoning policies for reasoning models inline in their content.
+const (
+	ReasoningKeep     = config.ReasoningKeep
+	ReasoningSeparate = config.ReasoningSeparate
+	ReasoningStrip    = config.ReasoningStrip
+)
+
+// Job kinds.
+const (
+	JobKindImage = providers.JobKindImage
+	JobKindVideo = providers.JobKindVideo
+)
+
+// Job statuses.
+const (
+	JobStatusFailed    = providers.JobStatusFailed
+	JobStatusRunning   = providers.JobStatusRunning
+	JobStatusSucceeded = providers.JobStatusSucceeded
+)
+
+// Stream event kinds.
+const (
+	EventKindAudio         = providers.EventKindAudio
+	EventKindContent       = providers.EventKindContent
+	EventKindContentFilter = providers.EventKindContentFilter
+	EventKindFinish        = providers.EventKindFinish
+	EventKindReasoning     = providers.EventKindReasoning
+	EventKindRefusal       = providers.EventKindRefusal
+	EventKindToolCall      = providers.EventKindToolCall
+	EventKindToolCallDelta = providers.EventKindToolCallDelta
+	EventKindUsage         = providers.EventKindUsage
+)
+
+// Output modalities.
+const (
+	ModalityAudio = providers.ModalityAudio
+	ModalityText  = providers.ModalityText
+)
+
 // Provider types.
 type (
 	Capabilities       = providers.Capabilities
 	CapabilityProvider = providers.CapabilityProvider
+	DocumentProvider   = providers.DocumentProvider
+	DryRunner          = providers.DryRunner
 	EmbeddingProvider  = providers.EmbeddingProvider
+	JobProvider        = providers.JobProvider
 	ModelLister        = providers.ModelLister
+	Prober             = providers.Prober
 	Provider           = providers.Provider
+	TextCompleter      = providers.TextCompleter
+	TokenCounter       = providers.TokenCounter
+	Wrapper            = providers.Wrapper
+)
+
+// ProviderFactory creates a provider registered by name.
+type ProviderFactory = providers.Factory
+
+// Provider registry. Built-in providers register themselves when their package
+// is imported; import providers/all to register every one of them.
+var (
+	NewProvider         = providers.New
+	RegisterProvider    = providers.Register
+	RegisteredProviders = providers.Registered
+)
+
+// Per-call limits on the retries made inside provider SDKs.
+var (
+	MaxProviderRetries     = providers.MaxProviderRetries
+	WithMaxProviderRetries = providers.WithMaxProviderRetries
 )
 
 // Request/Response types.
 type (
-	ChatCompletion      = providers.ChatCompletion
-	ChatCompletionChunk = providers.ChatCompletionChunk
-	Choice              = providers.Choice
-	ChunkChoice         = providers.ChunkChoice
-	ChunkDelta          = providers.ChunkDelta
-	CompletionParams    = providers.CompletionParams
-	EmbeddingParams     = providers.EmbeddingParams
-	EmbeddingResponse   = providers.EmbeddingResponse
-	ModelsResponse      = providers.ModelsResponse
+	Accumulator          = providers.Accumulator
+	Artifact             = providers.Artifact
+	Audio                = providers.Audio
+	AudioParams          = providers.AudioParams
+	ChatCompletion       = providers.ChatCompletion
+	ChatCompletionChunk  = providers.ChatCompletionChunk
+	Choice               = providers.Choice
+	ChunkChoice          = providers.ChunkChoice
+	ChunkDelta           = providers.ChunkDelta
+	CompletionParams     = providers.CompletionParams
+	ContentFilterResult  = providers.ContentFilterResult
+	DetokenizeParams     = providers.DetokenizeParams
+	DetokenizeResponse   = providers.DetokenizeResponse
+	EmbeddingParams      = providers.EmbeddingParams
+	EmbeddingResponse    = providers.EmbeddingResponse
+	Job                  = providers.Job
+	JobParams            = providers.JobParams
+	ModelsResponse       = providers.ModelsResponse
+	OCRPage              = providers.OCRPage
+	OCRParams            = providers.OCRParams
+	OCRResponse          = providers.OCRResponse
+	OCRUsage             = providers.OCRUsage
+	PromptFilterResult   = providers.PromptFilterResult
+	Provenance           = providers.Provenance
+	RateLimit            = providers.RateLimit
+	RateLimitState       = providers.RateLimitState
+	TextCompletion       = providers.TextCompletion
+	TextCompletionParams = providers.TextCompletionParams
+	Timings              = providers.Timings
+	TokenizeParams       = providers.TokenizeParams
+	TokenizeResponse     = providers.TokenizeResponse
+)
+
+// Typed stream events.
+type (
+	EventKind   = providers.EventKind
+	StreamEvent = providers.StreamEvent
+)
+
+// Typed stream event helpers.
+var (
+	CompletionEvents = providers.CompletionEvents
+	StreamEvents     = providers.StreamEvents
 )
 
 // Message types.
@@ -78,6 +205,16 @@ type (
 	Reasoning   = providers.Reasoning
 )
 
+// ValidateMessages checks that every message has a known role.
+var ValidateMessages = providers.ValidateMessages
+
+// Data URL helpers for sending local images and documents.
+var (
+	DataURL      = providers.DataURL
+	NewImagePart = providers.NewImagePart
+	ReadDataURL  = providers.ReadDataURL
+)
+
 // Tool types.
 type (
 	Function           = providers.Function
@@ -86,8 +223,56 @@ type (
 	ToolCall           = providers.ToolCall
 	ToolChoice         = providers.ToolChoice
 	ToolChoiceFunction = providers.ToolChoiceFunction
+	ToolResult         = providers.ToolResult
+)
+
+// Tool choice modes.
+const (
+	ToolChoiceAuto         = providers.ToolChoiceAuto
+	ToolChoiceNone         = providers.ToolChoiceNone
+	ToolChoiceRequired     = providers.ToolChoiceRequired
+	ToolChoiceTypeFunction = providers.ToolChoiceTypeFunction
+)
+
+// Tool helpers.
+var (
+	NewToolResultMessage  = providers.NewToolResultMessage
+	ToolChoiceForFunction = providers.ToolChoiceForFunction
+	ValidateToolChoice    = providers.ValidateToolChoice
+	ValidateTools         = providers.ValidateTools
+)
+
+// Tool schema validation.
+type SchemaLimits = providers.SchemaLimits
+
+// StrictSchema rewrites a JSON schema for OpenAI strict mode.
+var StrictSchema = providers.StrictSchema
+
+// Sampling parameter validation.
+type SamplingLimits = providers.SamplingLimits
+
+// ValidateSampling checks sampling parameters against a provider's limits.
+var ValidateSampling = providers.ValidateSampling
+
+// Bulk completion and embedding types.
+type (
+	BulkEmbedOptions     = bulk.EmbedOptions
+	BulkEmbeddingResult  = bulk.EmbeddingResult
+	BulkEmbeddingResults = bulk.EmbeddingResults
+	BulkOptions          = bulk.Options
+	BulkProgress         = bulk.Progress
+	BulkResult           = bulk.Result
+	BulkResults          = bulk.Results
 )
 
+// CompleteAll runs many completion requests with bounded parallelism and
+// returns the results in input order.
+var CompleteAll = bulk.CompleteAll
+
+// EmbedAll embeds many inputs in batches with bounded parallelism and returns
+// a result per input, in input order.
+var EmbedAll = bulk.EmbedAll
+
 // Response format types.
 type (
 	JSONSchema     = providers.JSONSchema
@@ -106,18 +291,29 @@ type (
 
 // Config types.
 type (
-	Config = config.Config
-	Option = config.Option
+	Compression     = config.Compression
+	Config          = config.Config
+	Option          = config.Option
+	ReasoningPolicy = config.ReasoningPolicy
 )
 
 // Configuration options.
 var (
-	NewConfig      = config.New
-	WithAPIKey     = config.WithAPIKey
-	WithBaseURL    = config.WithBaseURL
-	WithExtra      = config.WithExtra
-	WithHTTPClient = config.WithHTTPClient
-	WithTimeout    = config.WithTimeout
+	NewConfig                = config.New
+	WithAPIKey               = config.WithAPIKey
+	WithAppInfo              = config.WithAppInfo
+	WithAppURL               = config.WithAppURL
+	WithBaseURL              = config.WithBaseURL
+	WithBaseURLs             = config.WithBaseURLs
+	WithCapabilityOverrides  = config.WithCapabilityOverrides
+	WithCompressionThreshold = config.WithCompressionThreshold
+	WithExtra                = config.WithExtra
+	WithFailoverCooldown     = config.WithFailoverCooldown
+	WithHTTPClient           = config.WithHTTPClient
+	WithRawSchemas           = config.WithRawSchemas
+	WithReasoningPolicy      = config.WithReasoningPolicy
+	WithRequestCompression   = config.WithRequestCompression
+	WithTimeout              = config.WithTimeout
 )
 
 // Sentinel errors for type checking with errors.Is().
@@ -129,6 +325,8 @@ var (
 	ErrMissingAPIKey       = errors.ErrMissingAPIKey
 	ErrModelNotFound       = errors.ErrModelNotFound
 	ErrProvider            = errors.ErrProvider
+	ErrQueueTimeout        = errors.ErrQueueTimeout
+	ErrQuotaExceeded       = errors.ErrQuotaExceeded
 	ErrRateLimit           = errors.ErrRateLimit
 	ErrUnsupportedParam    = errors.ErrUnsupportedParam
 	ErrUnsupportedProvider = errors.ErrUnsupportedProvider
@@ -144,6 +342,8 @@ type (
 	MissingAPIKeyError       = errors.MissingAPIKeyError
 	ModelNotFoundError       = errors.ModelNotFoundError
 	ProviderError            = errors.ProviderError
+	QueueTimeoutError        = errors.QueueTimeoutError
+	QuotaExceededError       = errors.QuotaExceededError
 	RateLimitError           = errors.RateLimitError
 	UnsupportedParamError    = errors.UnsupportedParamError
 	UnsupportedProviderError = errors.UnsupportedProviderError
@@ -0,0 +1,425 @@
+// Package audit wraps a provider so every completion is written to a Store as
+// a structured record of the prompt and response, for compliance logging of
+// AI interactions. Records can be redacted and sampled before they are stored.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Redactable record fields, for use with WithRedactedFields.
+const (
+	FieldMessages  = "messages"
+	FieldResponse  = "response"
+	FieldToolCalls = "toolCalls"
+	FieldUser      = "user"
+)
+
+// Redacted replaces redacted values in records.
+const Redacted = "[REDACTED]"
+
+// Placeholders recorded for non-text content parts.
+const (
+	placeholderFile  = "[file]"
+	placeholderImage = "[image]"
+)
+
+// Ensure Provider and JSONLStore implement the required interfaces.
+var (
+	_ providers.Provider = (*Provider)(nil)
+	_ Store              = (*JSONLStore)(nil)
+)
+
+// JSONLStore writes records as JSON lines. It is safe for concurrent use.
+type JSONLStore struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Message is a request message as recorded. Content holds the message text,
+// with non-text parts replaced by placeholders such as "[image]".
+type Message struct {
+	Content string `json:"content"`
+	Role    string `json:"role"`
+}
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and writes an audit record for each completion.
+type Provider struct {
+	onError    func(error)
+	provider   providers.Provider
+	rand       func() float64
+	redactors  []Redactor
+	sampleRate float64
+	store      Store
+}
+
+// Record describes one completion request and its outcome.
+type Record struct {
+	Error        string               `json:"error,omitempty"`
+	FinishReason string               `json:"finishReason,omitempty"`
+	Latency      time.Duration        `json:"latency"`
+	Messages     []Message            `json:"messages"`
+	Model        string               `json:"model"`
+	Provider     string               `json:"provider"`
+	RequestID    string               `json:"requestId,omitempty"`
+	Response     string               `json:"response,omitempty"`
+	Stream       bool                 `json:"stream"`
+	Time         time.Time            `json:"time"`
+	ToolCalls    []providers.ToolCall `json:"toolCalls,omitempty"`
+	Usage        *providers.Usage     `json:"usage,omitempty"`
+	User         string               `json:"user,omitempty"`
+}
+
+// Redactor removes sensitive data from a record before it is stored.
+type Redactor func(*Record)
+
+// Store persists audit records.
+type Store interface {
+	// Write persists record.
+	Write(ctx context.Context, record Record) error
+}
+
+// New wraps provider so each completion is recorded in store.
+//
+// By default every request is recorded, and a failure to write a record is
+// returned as the request's error so no interaction goes unlogged. Use
+// WithErrorHandler to handle write failures without failing requests.
+func New(provider providers.Provider, store Store, opts ...Option) (*Provider, error) {
+	if store == nil {
+		return nil, fmt.Errorf("audit store is required")
+	}
+
+	p := &Provider{
+		provider:   provider,
+		rand:       rand.Float64,
+		sampleRate: 1,
+		store:      store,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// NewJSONLStore returns a Store that writes one JSON record per line to w.
+func NewJSONLStore(w io.Writer) *JSONLStore {
+	return &JSONLStore{w: w}
+}
+
+// WithErrorHandler calls fn with store write errors instead of failing the request.
+func WithErrorHandler(fn func(error)) Option {
+	return func(p *Provider) error {
+		if fn == nil {
+			return fmt.Errorf("error handler must not be nil")
+		}
+
+		p.onError = fn
+		return nil
+	}
+}
+
+// WithRedactedFields replaces the given record fields with Redacted before
+// records are stored. Fields are FieldMessages, FieldResponse, FieldToolCalls
+// and FieldUser; redacting messages keeps their roles.
+func WithRedactedFields(fields ...string) Option {
+	return func(p *Provider) error {
+		for _, field := range fields {
+			switch field {
+			case FieldMessages, FieldResponse, FieldToolCalls, FieldUser:
+			default:
+				return fmt.Errorf("unknown audit field %q", field)
+			}
+		}
+
+		p.redactors = append(p.redactors, redactFields(fields))
+		return nil
+	}
+}
+
+// WithRedactor adds a redactor. Redactors run in the order they were added.
+func WithRedactor(r Redactor) Option {
+	return func(p *Provider) error {
+		if r == nil {
+			return fmt.Errorf("redactor must not be nil")
+		}
+
+		p.redactors = append(p.redactors, r)
+		return nil
+	}
+}
+
+// WithSampleRate records only the given fraction of successful requests,
+// between 0 and 1. Failed requests are always recorded.
+func WithSampleRate(rate float64) Option {
+	return func(p *Provider) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sample rate must be between 0 and 1, got %v", rate)
+		}
+
+		p.sampleRate = rate
+		return nil
+	}
+}
+
+// Completion performs a chat completion request and records it.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	record := p.newRecord(params)
+
+	resp, err := p.provider.Completion(ctx, params)
+	record.Latency = time.Since(record.Time)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if resp != nil {
+		record.RequestID = resp.RequestID
+		record.Usage = resp.Usage
+		if len(resp.Choices) > 0 {
+			choice := resp.Choices[0]
+			record.FinishReason = choice.FinishReason
+			record.Response = choice.Message.ContentString()
+			record.ToolCalls = choice.Message.ToolCalls
+		}
+	}
+
+	if writeErr := p.write(ctx, record); writeErr != nil && err == nil {
+		return nil, writeErr
+	}
+
+	return resp, err
+}
+
+// CompletionStream performs a streaming chat completion request and records
+// it once the stream ends, with the content of the chunks concatenated.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		record := p.newRecord(params)
+		record.Stream = true
+
+		err := p.forwardStream(ctx, params, out, &record)
+		record.Latency = time.Since(record.Time)
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if writeErr := p.write(ctx, record); writeErr != nil && err == nil {
+			err = writeErr
+		}
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// Write writes record as one JSON line.
+func (s *JSONLStore) Write(_ context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit record: %w", err)
+	}
+	return nil
+}
+
+// forwardStream forwards the wrapped stream to out while collecting the
+// response into record. It returns the stream's error, if any.
+func (p *Provider) forwardStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+	record *Record,
+) error {
+	chunks, errs := p.provider.CompletionStream(ctx, params)
+
+	var content strings.Builder
+	defer func() { record.Response = content.String() }()
+
+	for chunk := range chunks {
+		if chunk.Usage != nil {
+			record.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) > 0 {
+			choice := chunk.Choices[0]
+			content.WriteString(choice.Delta.Content)
+			record.ToolCalls = appendToolCallDeltas(record.ToolCalls, choice.Delta.ToolCalls)
+			if choice.FinishReason != "" {
+				record.FinishReason = choice.FinishReason
+			}
+		}
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return <-errs
+}
+
+// newRecord starts a record for params.
+func (p *Provider) newRecord(params providers.CompletionParams) Record {
+	messages := make([]Message, len(params.Messages))
+	for i, msg := range params.Messages {
+		messages[i] = Message{Content: messageText(msg), Role: msg.Role}
+	}
+
+	return Record{
+		Messages: messages,
+		Model:    params.Model,
+		Provider: p.provider.Name(),
+		Time:     time.Now(),
+		User:     params.User,
+	}
+}
+
+// write redacts and stores record, unless it is sampled out. Write errors are
+// passed to the error handler when one is set, or returned.
+func (p *Provider) write(ctx context.Context, record Record) error {
+	if record.Error == "" && p.sampleRate < 1 && p.rand() >= p.sampleRate {
+		return nil
+	}
+
+	// Tool calls are shared with the response returned to the caller.
+	record.ToolCalls = append([]providers.ToolCall(nil), record.ToolCalls...)
+	for _, redact := range p.redactors {
+		redact(&record)
+	}
+
+	// Record requests even when the caller's context was cancelled.
+	err := p.store.Write(context.WithoutCancel(ctx), record)
+	if err != nil && p.onError != nil {
+		p.onError(err)
+		return nil
+	}
+	return err
+}
+
+// RedactPattern replaces every match of re in message content, the response,
+// tool call arguments and the error with Redacted. Use it to mask values such
+// as email addresses or API keys.
+func RedactPattern(re *regexp.Regexp) Redactor {
+	return func(r *Record) {
+		for i := range r.Messages {
+			r.Messages[i].Content = re.ReplaceAllString(r.Messages[i].Content, Redacted)
+		}
+		for i := range r.ToolCalls {
+			r.ToolCalls[i].Function.Arguments = re.ReplaceAllString(r.ToolCalls[i].Function.Arguments, Redacted)
+		}
+		r.Error = re.ReplaceAllString(r.Error, Redacted)
+		r.Response = re.ReplaceAllString(r.Response, Redacted)
+	}
+}
+
+// appendToolCallDeltas merges streamed tool call fragments into calls. A
+// fragment with an ID starts a new call; others extend the last one.
+func appendToolCallDeltas(calls []providers.ToolCall, deltas []providers.ToolCall) []providers.ToolCall {
+	for _, delta := range deltas {
+		if delta.ID != "" || len(calls) == 0 {
+			calls = append(calls, delta)
+			continue
+		}
+
+		last := &calls[len(calls)-1]
+		last.Function.Name += delta.Function.Name
+		last.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+// messageText returns the text of msg, with placeholders for non-text parts.
+func messageText(msg providers.Message) string {
+	parts := msg.ContentParts()
+	if parts == nil {
+		return msg.ContentString()
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.ImageURL != nil:
+			texts = append(texts, placeholderImage)
+		case part.FileID != "":
+			texts = append(texts, placeholderFile)
+		default:
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// redactFields returns a redactor for the given fields.
+func redactFields(fields []string) Redactor {
+	return func(r *Record) {
+		for _, field := range fields {
+			switch field {
+			case FieldMessages:
+				for i := range r.Messages {
+					r.Messages[i].Content = Redacted
+				}
+			case FieldResponse:
+				if r.Response != "" {
+					r.Response = Redacted
+				}
+			case FieldToolCalls:
+				for i := range r.ToolCalls {
+					r.ToolCalls[i].Function.Arguments = Redacted
+				}
+			case FieldUser:
+				if r.User != "" {
+					r.User = Redacted
+				}
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// memoryStore collects records for assertions.
+type memoryStore struct {
+	err     error
+	mu      sync.Mutex
+	records []Record
+}
+
+func (s *memoryStore) Write(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+// drain reads a stream to the end and returns its content and error.
+func drain(chunks <-chan providers.ChatCompletionChunk, errs <-chan error) (string, error) {
+	var content strings.Builder
+	for chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+	return content.String(), <-errs
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(testutil.NewMockProvider(), nil)
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), &memoryStore{}, WithSampleRate(1.5))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), &memoryStore{}, WithRedactedFields("password"))
+	require.ErrorContains(t, err, `unknown audit field "password"`)
+
+	_, err = New(testutil.NewMockProvider(), &memoryStore{}, WithRedactor(nil))
+	require.Error(t, err)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records request and response", func(t *testing.T) {
+		t.Parallel()
+
+		store := &memoryStore{}
+		provider, err := New(testutil.NewMockProvider(), store)
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Model: "model-a",
+			Messages: []providers.Message{
+				{Role: providers.RoleUser, Content: []providers.ContentPart{
+					{Type: "text", Text: "What is in this picture?"},
+					{Type: "image_url", ImageURL: &providers.ImageURL{URL: "data:image/png;base64,AAAA"}},
+				}},
+			},
+			User: "user-1",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+
+		require.Len(t, store.records, 1)
+		record := store.records[0]
+		require.Equal(t, "mock", record.Provider)
+		require.Equal(t, "model-a", record.Model)
+		require.Equal(t, "user-1", record.User)
+		require.Equal(t, []Message{{Content: "What is in this picture?\n[image]", Role: providers.RoleUser}}, record.Messages)
+		require.Equal(t, resp.Choices[0].Message.ContentString(), record.Response)
+		require.Equal(t, providers.FinishReasonStop, record.FinishReason)
+		require.NotNil(t, record.Usage)
+		require.False(t, record.Stream)
+		require.False(t, record.Time.IsZero())
+	})
+
+	t.Run("records errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+
+		store := &memoryStore{}
+		provider, err := New(mock, store, WithSampleRate(0))
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+
+		require.Len(t, store.records, 1)
+		require.Contains(t, store.records[0].Error, "slow down")
+	})
+
+	t.Run("fails when the record cannot be written", func(t *testing.T) {
+		t.Parallel()
+
+		store := &memoryStore{err: stderrors.New("disk full")}
+		provider, err := New(testutil.NewMockProvider(), store)
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		require.EqualError(t, err, "disk full")
+		require.Nil(t, resp)
+	})
+
+	t.Run("passes write errors to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		var handled error
+		store := &memoryStore{err: stderrors.New("disk full")}
+		provider, err := New(testutil.NewMockProvider(), store, WithErrorHandler(func(err error) { handled = err }))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		require.EqualError(t, handled, "disk full")
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		_ context.Context,
+		_ providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 4)
+		errs := make(chan error)
+		chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Type: "function", Function: providers.FunctionCall{
+				Name:      "get_weather",
+				Arguments: `{"location":`,
+			}}},
+		}}}}
+		chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+			ToolCalls: []providers.ToolCall{{Function: providers.FunctionCall{Arguments: `"Paris"}`}}},
+		}}}}
+		chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{
+			Delta:        providers.ChunkDelta{Content: "Checking."},
+			FinishReason: providers.FinishReasonToolCalls,
+		}}}
+		chunks <- providers.ChatCompletionChunk{Usage: &providers.Usage{TotalTokens: 12}}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	store := &memoryStore{}
+	provider, err := New(mock, store)
+	require.NoError(t, err)
+
+	content, err := drain(provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "Checking.", content)
+
+	require.Len(t, store.records, 1)
+	record := store.records[0]
+	require.True(t, record.Stream)
+	require.Equal(t, "Checking.", record.Response)
+	require.Equal(t, providers.FinishReasonToolCalls, record.FinishReason)
+	require.Equal(t, 12, record.Usage.TotalTokens)
+	require.Len(t, record.ToolCalls, 1)
+	require.Equal(t, "get_weather", record.ToolCalls[0].Function.Name)
+	require.Equal(t, `{"location":"Paris"}`, record.ToolCalls[0].Function.Arguments)
+}
+
+func TestRedaction(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: providers.FunctionCall{Name: "send_email", Arguments: `{"to":"jane@example.com"}`},
+		}}), nil
+	}
+
+	store := &memoryStore{}
+	provider, err := New(mock, store,
+		WithRedactedFields(FieldUser),
+		WithRedactor(RedactPattern(regexp.MustCompile(`[\w.]+@[\w.]+`))),
+	)
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Email jane@example.com the report."}},
+		User:     "jane",
+	})
+	require.NoError(t, err)
+
+	record := store.records[0]
+	require.Equal(t, "Email [REDACTED] the report.", record.Messages[0].Content)
+	require.Equal(t, `{"to":"[REDACTED]"}`, record.ToolCalls[0].Function.Arguments)
+	require.Equal(t, Redacted, record.User)
+
+	// The caller's response is not redacted.
+	require.Equal(t, `{"to":"jane@example.com"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+}
+
+func TestRedactFields(t *testing.T) {
+	t.Parallel()
+
+	record := Record{
+		Messages:  []Message{{Content: "secret prompt", Role: providers.RoleUser}},
+		Response:  "secret answer",
+		ToolCalls: []providers.ToolCall{{Function: providers.FunctionCall{Name: "lookup", Arguments: "{}"}}},
+	}
+
+	redactFields([]string{FieldMessages, FieldResponse, FieldToolCalls, FieldUser})(&record)
+
+	require.Equal(t, []Message{{Content: Redacted, Role: providers.RoleUser}}, record.Messages)
+	require.Equal(t, Redacted, record.Response)
+	require.Equal(t, "lookup", record.ToolCalls[0].Function.Name)
+	require.Equal(t, Redacted, record.ToolCalls[0].Function.Arguments)
+	require.Empty(t, record.User)
+}
+
+func TestSampling(t *testing.T) {
+	t.Parallel()
+
+	store := &memoryStore{}
+	provider, err := New(testutil.NewMockProvider(), store, WithSampleRate(0.5))
+	require.NoError(t, err)
+
+	draws := []float64{0.1, 0.7, 0.4}
+	provider.rand = func() float64 {
+		next := draws[0]
+		draws = draws[1:]
+		return next
+	}
+
+	for range 3 {
+		_, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, store.records, 2)
+}
+
+func TestJSONLStore(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	store := NewJSONLStore(&buf)
+
+	require.NoError(t, store.Write(context.Background(), Record{Model: "a", Provider: "mock"}))
+	require.NoError(t, store.Write(context.Background(), Record{Model: "b", Provider: "mock"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, "b", record["model"])
+	require.Contains(t, record, "messages")
+	require.NotContains(t, record, "error")
+}
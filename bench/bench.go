@@ -0,0 +1,254 @@
+// Package bench measures the latency and throughput of providers by firing
+// concurrent streaming completions at them.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Runner defaults.
+const (
+	defaultConcurrency = 4
+	defaultRequests    = 20
+)
+
+// Option configures a Runner.
+type Option func(*Runner) error
+
+// Result is the measurement of one request.
+type Result struct {
+	// Err is the request error, if any.
+	Err error
+
+	// Latency is the time until the stream finished.
+	Latency time.Duration
+
+	// OutputTokens is the completion token count, from usage when reported or
+	// else the number of content chunks.
+	OutputTokens int
+
+	// TimeToFirstToken is the time until the first content chunk arrived.
+	TimeToFirstToken time.Duration
+}
+
+// Runner runs benchmarks.
+type Runner struct {
+	concurrency int
+	requests    int
+}
+
+// Summary aggregates the results of one target.
+type Summary struct {
+	Errors          int           `json:"errors"`
+	FirstError      string        `json:"firstError,omitempty"`
+	LatencyP50      time.Duration `json:"latencyP50"`
+	LatencyP95      time.Duration `json:"latencyP95"`
+	Requests        int           `json:"requests"`
+	TTFTP50         time.Duration `json:"ttftP50"`
+	TTFTP95         time.Duration `json:"ttftP95"`
+	Target          string        `json:"target"`
+	TokensPerSecond float64       `json:"tokensPerSecond"`
+}
+
+// Target is a provider and model to benchmark.
+type Target struct {
+	// Model is the model to request.
+	Model string
+
+	// Name identifies the target in the summary. It defaults to "provider/model".
+	Name string
+
+	// Provider serves the requests.
+	Provider providers.Provider
+}
+
+// New returns a Runner. By default it sends 20 requests per target, four at a time.
+func New(opts ...Option) (*Runner, error) {
+	r := &Runner{
+		concurrency: defaultConcurrency,
+		requests:    defaultRequests,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// WithConcurrency sets how many requests per target run at the same time.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", n)
+		}
+
+		r.concurrency = n
+		return nil
+	}
+}
+
+// WithRequests sets how many requests are sent to each target.
+func WithRequests(n int) Option {
+	return func(r *Runner) error {
+		if n <= 0 {
+			return fmt.Errorf("requests must be positive, got %d", n)
+		}
+
+		r.requests = n
+		return nil
+	}
+}
+
+// Run benchmarks each target in turn with params, using the target's model.
+// Targets are run one after another so they don't compete for bandwidth.
+func (r *Runner) Run(ctx context.Context, params providers.CompletionParams, targets []Target) ([]Summary, error) {
+	summaries := make([]Summary, 0, len(targets))
+
+	for _, t := range targets {
+		results, err := r.runTarget(ctx, params, t)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, Summarize(targetName(t), results))
+	}
+
+	return summaries, nil
+}
+
+// runTarget sends the configured number of requests to t.
+func (r *Runner) runTarget(ctx context.Context, params providers.CompletionParams, t Target) ([]Result, error) {
+	params.Model = t.Model
+	params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+
+	results := make([]Result, r.requests)
+	sem := make(chan struct{}, r.concurrency)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = Measure(ctx, t.Provider, params)
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// Measure sends one streaming request and measures it.
+func Measure(ctx context.Context, provider providers.Provider, params providers.CompletionParams) Result {
+	var result Result
+	contentChunks := 0
+	usageTokens := 0
+
+	start := time.Now()
+	chunks, errs := provider.CompletionStream(ctx, params)
+	for chunk := range chunks {
+		if hasContent(chunk) {
+			if contentChunks == 0 {
+				result.TimeToFirstToken = time.Since(start)
+			}
+			contentChunks++
+		}
+		if chunk.Usage != nil {
+			usageTokens = chunk.Usage.CompletionTokens
+		}
+	}
+	result.Err = <-errs
+	result.Latency = time.Since(start)
+
+	result.OutputTokens = usageTokens
+	if result.OutputTokens == 0 {
+		result.OutputTokens = contentChunks
+	}
+
+	return result
+}
+
+// Summarize aggregates results. Percentiles and throughput only include
+// successful requests; throughput is output tokens per second of generation,
+// after the first token.
+func Summarize(target string, results []Result) Summary {
+	summary := Summary{Requests: len(results), Target: target}
+
+	var latencies, ttfts []time.Duration
+	var tokens int
+	var generation time.Duration
+	for _, res := range results {
+		if res.Err != nil {
+			if summary.Errors == 0 {
+				summary.FirstError = res.Err.Error()
+			}
+			summary.Errors++
+			continue
+		}
+		latencies = append(latencies, res.Latency)
+		ttfts = append(ttfts, res.TimeToFirstToken)
+		tokens += res.OutputTokens
+		generation += res.Latency - res.TimeToFirstToken
+	}
+
+	summary.LatencyP50 = percentile(latencies, 50)
+	summary.LatencyP95 = percentile(latencies, 95)
+	summary.TTFTP50 = percentile(ttfts, 50)
+	summary.TTFTP95 = percentile(ttfts, 95)
+	if generation > 0 {
+		summary.TokensPerSecond = float64(tokens) / generation.Seconds()
+	}
+
+	return summary
+}
+
+// hasContent reports whether chunk carries generated text.
+func hasContent(chunk providers.ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" || (choice.Delta.Reasoning != nil && choice.Delta.Reasoning.Content != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// percentile returns the p-th percentile of values using the nearest-rank method.
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// targetName returns the name of t in summaries.
+func targetName(t Target) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Provider.Name() + "/" + t.Model
+}
@@ -0,0 +1,155 @@
+package bench
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// streamProvider returns a mock provider that streams the given chunks and error.
+func streamProvider(chunks []providers.ChatCompletionChunk, err error) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		_ context.Context,
+		_ providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		out := make(chan providers.ChatCompletionChunk, len(chunks))
+		errs := make(chan error, 1)
+		for _, c := range chunks {
+			out <- c
+		}
+		close(out)
+		if err != nil {
+			errs <- err
+		}
+		close(errs)
+		return out, errs
+	}
+	return mock
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithConcurrency(0))
+	require.Error(t, err)
+
+	_, err = New(WithRequests(-1))
+	require.Error(t, err)
+
+	runner, err := New()
+	require.NoError(t, err)
+	require.Equal(t, defaultConcurrency, runner.concurrency)
+	require.Equal(t, defaultRequests, runner.requests)
+}
+
+func TestMeasure(t *testing.T) {
+	t.Parallel()
+
+	t.Run("uses reported usage for output tokens", func(t *testing.T) {
+		t.Parallel()
+
+		provider := streamProvider([]providers.ChatCompletionChunk{
+			{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Role: providers.RoleAssistant}}}},
+			testutil.ContentChunk("", "Hello"),
+			testutil.ContentChunk("", " world"),
+			{Usage: &providers.Usage{CompletionTokens: 7}},
+		}, nil)
+
+		result := Measure(context.Background(), provider, providers.CompletionParams{})
+		require.NoError(t, result.Err)
+		require.Equal(t, 7, result.OutputTokens)
+		require.LessOrEqual(t, result.TimeToFirstToken, result.Latency)
+	})
+
+	t.Run("counts content chunks without usage", func(t *testing.T) {
+		t.Parallel()
+
+		provider := streamProvider([]providers.ChatCompletionChunk{
+			testutil.ContentChunk("", "a"),
+			testutil.ContentChunk("", "b"),
+		}, nil)
+
+		result := Measure(context.Background(), provider, providers.CompletionParams{})
+		require.Equal(t, 2, result.OutputTokens)
+	})
+
+	t.Run("reports stream errors", func(t *testing.T) {
+		t.Parallel()
+
+		provider := streamProvider(nil, stderrors.New("boom"))
+
+		result := Measure(context.Background(), provider, providers.CompletionParams{})
+		require.EqualError(t, result.Err, "boom")
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	results := []Result{
+		{Latency: 400 * time.Millisecond, TimeToFirstToken: 100 * time.Millisecond, OutputTokens: 30},
+		{Latency: 200 * time.Millisecond, TimeToFirstToken: 50 * time.Millisecond, OutputTokens: 15},
+		{Latency: 300 * time.Millisecond, TimeToFirstToken: 150 * time.Millisecond, OutputTokens: 15},
+		{Err: stderrors.New("timeout")},
+	}
+
+	summary := Summarize("p/m", results)
+
+	require.Equal(t, "p/m", summary.Target)
+	require.Equal(t, 4, summary.Requests)
+	require.Equal(t, 1, summary.Errors)
+	require.Equal(t, "timeout", summary.FirstError)
+	require.Equal(t, 300*time.Millisecond, summary.LatencyP50)
+	require.Equal(t, 400*time.Millisecond, summary.LatencyP95)
+	require.Equal(t, 100*time.Millisecond, summary.TTFTP50)
+	require.Equal(t, 150*time.Millisecond, summary.TTFTP95)
+	// 60 tokens over 0.3+0.15+0.15 = 0.6s of generation.
+	require.InDelta(t, 100.0, summary.TokensPerSecond, 1e-9)
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	values := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+
+	require.Equal(t, time.Duration(0), percentile(nil, 50))
+	require.Equal(t, time.Duration(5), percentile(values, 50))
+	require.Equal(t, time.Duration(10), percentile(values, 95))
+	require.Equal(t, time.Duration(1), percentile(values, 0))
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	primary := streamProvider([]providers.ChatCompletionChunk{testutil.ContentChunk("", "hi")}, nil)
+	secondary := streamProvider(nil, stderrors.New("unavailable"))
+	secondary.NameFunc = func() string { return "secondary" }
+
+	runner, err := New(WithRequests(3), WithConcurrency(2))
+	require.NoError(t, err)
+
+	summaries, err := runner.Run(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	}, []Target{
+		{Provider: primary, Model: "model-a"},
+		{Provider: secondary, Model: "model-b", Name: "backup"},
+	})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+
+	require.Equal(t, "mock/model-a", summaries[0].Target)
+	require.Zero(t, summaries[0].Errors)
+	require.Equal(t, "backup", summaries[1].Target)
+	require.Equal(t, 3, summaries[1].Errors)
+
+	require.Len(t, primary.CompletionStreamCalls, 3)
+	require.Equal(t, "model-a", primary.CompletionStreamCalls[0].Model)
+	require.True(t, primary.CompletionStreamCalls[0].StreamOptions.IncludeUsage)
+}
@@ -0,0 +1,296 @@
+// Package bestofn samples several completions for the same request and
+// selects one of them, for self-consistency and best-of-N strategies.
+package bestofn
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultN is the number of samples drawn when WithN is not used.
+const defaultN = 5
+
+// judgeSystemPrompt instructs the judge model how to answer.
+const judgeSystemPrompt = "You compare candidate answers to a conversation and pick the best one. " +
+	"Reply with the number of the best candidate on the first line, " +
+	"followed by a short explanation of your choice."
+
+// Candidate is one sampled completion.
+type Candidate struct {
+	// Completion is the response, or nil if the request failed.
+	Completion *providers.ChatCompletion
+
+	// Err is the request error, if any.
+	Err error
+
+	// Model is the model the sample was requested from.
+	Model string
+
+	// Provider is the name of the provider the sample was requested from.
+	Provider string
+}
+
+// Option configures a Sampler.
+type Option func(*Sampler) error
+
+// Result holds every candidate and the selected one.
+type Result struct {
+	// Candidates are the samples in request order, including failed ones.
+	Candidates []Candidate
+
+	// Rationale explains why the candidate was selected.
+	Rationale string
+
+	// Selected is the index of the selected candidate in Candidates.
+	Selected int
+}
+
+// Sampler issues N completions concurrently and selects one with a Selector.
+type Sampler struct {
+	n        int
+	selector Selector
+	targets  []target
+}
+
+// Selection is a Selector's choice.
+type Selection struct {
+	// Index is the index of the chosen candidate.
+	Index int
+
+	// Rationale explains the choice.
+	Rationale string
+}
+
+// Selector chooses among candidates sampled for params.
+// Only successful candidates (Err == nil) may be selected.
+type Selector func(ctx context.Context, params providers.CompletionParams, candidates []Candidate) (Selection, error)
+
+// target is a provider and model to sample from.
+type target struct {
+	model    string
+	provider providers.Provider
+}
+
+// New returns a Sampler that samples from provider with the request's model.
+// By default it draws five samples and selects by MajorityVote.
+func New(provider providers.Provider, opts ...Option) (*Sampler, error) {
+	s := &Sampler{
+		n:        defaultN,
+		selector: MajorityVote(nil),
+		targets:  []target{{provider: provider}},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// WithN sets the number of samples.
+func WithN(n int) Option {
+	return func(s *Sampler) error {
+		if n <= 0 {
+			return fmt.Errorf("number of samples must be positive, got %d", n)
+		}
+
+		s.n = n
+		return nil
+	}
+}
+
+// WithSelector sets how the answer is chosen.
+func WithSelector(selector Selector) Option {
+	return func(s *Sampler) error {
+		if selector == nil {
+			return fmt.Errorf("selector must not be nil")
+		}
+
+		s.selector = selector
+		return nil
+	}
+}
+
+// WithTarget adds a provider and model to sample from. Samples are spread
+// round-robin over the Sampler's provider and all added targets.
+// An empty model uses the request's model.
+func WithTarget(provider providers.Provider, model string) Option {
+	return func(s *Sampler) error {
+		if provider == nil {
+			return fmt.Errorf("target provider must not be nil")
+		}
+
+		s.targets = append(s.targets, target{model: model, provider: provider})
+		return nil
+	}
+}
+
+// Complete samples params concurrently and returns all candidates with the
+// selected one. It fails only if every sample fails or the selector fails.
+// Sampling needs a non-zero temperature to produce different candidates.
+func (s *Sampler) Complete(ctx context.Context, params providers.CompletionParams) (*Result, error) {
+	candidates := make([]Candidate, s.n)
+
+	var wg sync.WaitGroup
+	for i := range candidates {
+		t := s.targets[i%len(s.targets)]
+
+		req := params
+		if t.model != "" {
+			req.Model = t.model
+		}
+		candidates[i] = Candidate{Model: req.Model, Provider: t.provider.Name()}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			candidates[i].Completion, candidates[i].Err = t.provider.Completion(ctx, req)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, c := range candidates {
+		if c.Err != nil {
+			errs = append(errs, c.Err)
+		}
+	}
+	if len(errs) == len(candidates) {
+		return nil, stderrors.Join(errs...)
+	}
+
+	selection, err := s.selector(ctx, params, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if selection.Index < 0 || selection.Index >= len(candidates) || candidates[selection.Index].Err != nil {
+		return nil, fmt.Errorf("selector chose invalid candidate %d", selection.Index)
+	}
+
+	return &Result{
+		Candidates: candidates,
+		Rationale:  selection.Rationale,
+		Selected:   selection.Index,
+	}, nil
+}
+
+// Best returns the selected completion.
+func (r *Result) Best() *providers.ChatCompletion {
+	return r.Candidates[r.Selected].Completion
+}
+
+// Judge returns a Selector that asks model on provider to pick the best
+// candidate. The judge sees the conversation and every successful candidate.
+func Judge(provider providers.Provider, model string) Selector {
+	return func(ctx context.Context, params providers.CompletionParams, candidates []Candidate) (Selection, error) {
+		var prompt strings.Builder
+		prompt.WriteString("Conversation:\n")
+		for _, msg := range params.Messages {
+			fmt.Fprintf(&prompt, "%s: %s\n", msg.Role, msg.ContentString())
+		}
+
+		// Number candidates from 1 for the judge.
+		var indices []int
+		for i, c := range candidates {
+			if c.Err != nil {
+				continue
+			}
+			indices = append(indices, i)
+			fmt.Fprintf(&prompt, "\nCandidate %d:\n%s\n", len(indices), answer(c))
+		}
+
+		resp, err := provider.Completion(ctx, providers.CompletionParams{
+			Model: model,
+			Messages: []providers.Message{
+				{Role: providers.RoleSystem, Content: judgeSystemPrompt},
+				{Role: providers.RoleUser, Content: prompt.String()},
+			},
+		})
+		if err != nil {
+			return Selection{}, err
+		}
+		if len(resp.Choices) == 0 {
+			return Selection{}, errors.NewProviderError(provider.Name(), fmt.Errorf("judge returned no choices"))
+		}
+
+		verdict := strings.TrimSpace(resp.Choices[0].Message.ContentString())
+		first, rationale, _ := strings.Cut(verdict, "\n")
+		number, err := strconv.Atoi(strings.Trim(strings.TrimSpace(first), ".:)#"))
+		if err != nil || number < 1 || number > len(indices) {
+			return Selection{}, fmt.Errorf("judge gave no valid candidate number: %q", first)
+		}
+
+		return Selection{Index: indices[number-1], Rationale: strings.TrimSpace(rationale)}, nil
+	}
+}
+
+// MajorityVote returns a Selector that picks the most common answer.
+// Answers are compared after normalize, which can extract the final answer
+// from a longer response; nil compares trimmed, lower-cased text. Ties go to
+// the answer seen first.
+func MajorityVote(normalize func(string) string) Selector {
+	if normalize == nil {
+		normalize = func(s string) string {
+			return strings.ToLower(strings.TrimSpace(s))
+		}
+	}
+
+	return func(_ context.Context, _ providers.CompletionParams, candidates []Candidate) (Selection, error) {
+		var keys []string
+		votes := make(map[string]int)
+		first := make(map[string]int)
+		valid := 0
+
+		for i, c := range candidates {
+			if c.Err != nil {
+				continue
+			}
+			valid++
+
+			key := normalize(answer(c))
+			if _, ok := first[key]; !ok {
+				first[key] = i
+				keys = append(keys, key)
+			}
+			votes[key]++
+		}
+
+		if valid == 0 {
+			return Selection{}, fmt.Errorf("no successful candidates")
+		}
+
+		// Keys are in order of first appearance, so the strict comparison breaks ties in favor of the earliest.
+		best := keys[0]
+		for _, key := range keys[1:] {
+			if votes[key] > votes[best] {
+				best = key
+			}
+		}
+
+		return Selection{
+			Index:     first[best],
+			Rationale: fmt.Sprintf("%d of %d candidates agreed", votes[best], valid),
+		}, nil
+	}
+}
+
+// answer returns the text of a candidate's first choice.
+func answer(c Candidate) string {
+	if c.Completion == nil || len(c.Completion.Choices) == 0 {
+		return ""
+	}
+	return c.Completion.Choices[0].Message.ContentString()
+}
@@ -0,0 +1,223 @@
+package bestofn
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// candidates builds successful candidates with the given answers.
+func candidates(answers ...string) []Candidate {
+	result := make([]Candidate, len(answers))
+	for i, a := range answers {
+		result[i] = Candidate{Completion: testutil.MockChatCompletion(a)}
+	}
+	return result
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(testutil.NewMockProvider(), WithN(0))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithSelector(nil))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithTarget(nil, "model"))
+	require.Error(t, err)
+
+	sampler, err := New(testutil.NewMockProvider())
+	require.NoError(t, err)
+	require.Equal(t, defaultN, sampler.n)
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	t.Run("samples concurrently and selects the majority", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedReplies("42")
+		sampler, err := New(mock, WithN(3))
+		require.NoError(t, err)
+
+		result, err := sampler.Complete(context.Background(), providers.CompletionParams{
+			Model:    "model-a",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Candidates, 3)
+		require.Len(t, mock.CompletionCalls, 3)
+		require.Equal(t, "42", result.Best().Choices[0].Message.Content)
+		require.Equal(t, "3 of 3 candidates agreed", result.Rationale)
+	})
+
+	t.Run("spreads samples over targets", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		secondary := testutil.NewMockProvider()
+		secondary.NameFunc = func() string { return "secondary" }
+
+		sampler, err := New(primary, WithN(4), WithTarget(secondary, "model-b"))
+		require.NoError(t, err)
+
+		result, err := sampler.Complete(context.Background(), providers.CompletionParams{
+			Model:    "model-a",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+
+		require.Len(t, primary.CompletionCalls, 2)
+		require.Len(t, secondary.CompletionCalls, 2)
+		require.Equal(t, "model-b", secondary.CompletionCalls[0].Model)
+		require.Equal(t, "secondary", result.Candidates[1].Provider)
+		require.Equal(t, "model-b", result.Candidates[1].Model)
+		require.Equal(t, "model-a", result.Candidates[2].Model)
+	})
+
+	t.Run("keeps failed candidates", func(t *testing.T) {
+		t.Parallel()
+
+		failing := testutil.NewMockProvider()
+		failing.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+
+		sampler, err := New(testutil.NewMockProvider(), WithN(2), WithTarget(failing, ""))
+		require.NoError(t, err)
+
+		result, err := sampler.Complete(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, 0, result.Selected)
+		require.ErrorIs(t, result.Candidates[1].Err, errors.ErrRateLimit)
+	})
+
+	t.Run("fails when every sample fails", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+
+		sampler, err := New(mock, WithN(2))
+		require.NoError(t, err)
+
+		_, err = sampler.Complete(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+	})
+
+	t.Run("rejects invalid selections", func(t *testing.T) {
+		t.Parallel()
+
+		sampler, err := New(testutil.NewMockProvider(), WithN(2), WithSelector(
+			func(_ context.Context, _ providers.CompletionParams, _ []Candidate) (Selection, error) {
+				return Selection{Index: 5}, nil
+			},
+		))
+		require.NoError(t, err)
+
+		_, err = sampler.Complete(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.Error(t, err)
+	})
+}
+
+func TestMajorityVote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		normalize func(string) string
+		answers   []string
+		want      int
+		rationale string
+	}{
+		{
+			name:      "picks most common answer",
+			answers:   []string{"4", "5", "5"},
+			want:      1,
+			rationale: "2 of 3 candidates agreed",
+		},
+		{
+			name:      "compares trimmed lower-case text by default",
+			answers:   []string{"Paris", "London", " paris "},
+			want:      0,
+			rationale: "2 of 3 candidates agreed",
+		},
+		{
+			name:      "breaks ties in favor of the first answer",
+			answers:   []string{"a", "b", "b", "a"},
+			want:      0,
+			rationale: "2 of 4 candidates agreed",
+		},
+		{
+			name: "uses custom normalization",
+			normalize: func(s string) string {
+				return s[len(s)-1:]
+			},
+			answers:   []string{"so it is 7", "x = 3", "answer: 7"},
+			want:      0,
+			rationale: "2 of 3 candidates agreed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vote := MajorityVote(tc.normalize)
+			selection, err := vote(context.Background(), providers.CompletionParams{}, candidates(tc.answers...))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, selection.Index)
+			require.Equal(t, tc.rationale, selection.Rationale)
+		})
+	}
+
+	t.Run("skips failed candidates", func(t *testing.T) {
+		t.Parallel()
+
+		cs := append([]Candidate{{Err: stderrors.New("failed")}}, candidates("ok")...)
+		selection, err := MajorityVote(nil)(context.Background(), providers.CompletionParams{}, cs)
+		require.NoError(t, err)
+		require.Equal(t, 1, selection.Index)
+	})
+}
+
+func TestJudge(t *testing.T) {
+	t.Parallel()
+
+	t.Run("maps the judge's number to the candidate", func(t *testing.T) {
+		t.Parallel()
+
+		judge := testutil.ScriptedReplies("2.\nIt is the most complete answer.")
+		cs := append([]Candidate{{Err: stderrors.New("failed")}}, candidates("short", "complete")...)
+
+		selection, err := Judge(judge, "judge-model")(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}, cs)
+		require.NoError(t, err)
+		require.Equal(t, 2, selection.Index)
+		require.Equal(t, "It is the most complete answer.", selection.Rationale)
+
+		require.Equal(t, "judge-model", judge.CompletionCalls[0].Model)
+		prompt := judge.CompletionCalls[0].Messages[1].ContentString()
+		require.Contains(t, prompt, "Candidate 1:\nshort")
+		require.Contains(t, prompt, "Candidate 2:\ncomplete")
+	})
+
+	t.Run("rejects unparseable verdicts", func(t *testing.T) {
+		t.Parallel()
+
+		judge := testutil.ScriptedReplies("The second one.")
+		_, err := Judge(judge, "judge-model")(context.Background(), providers.CompletionParams{}, candidates("a", "b"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,244 @@
+// Package bulk runs many completion or embedding requests with bounded
+// parallelism, for offline jobs such as enriching or classifying a dataset.
+// A failed item does not fail the others: each item's result carries either
+// its response or its error.
+package bulk
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// defaultConcurrency is the number of requests run at once when Options.Concurrency is not set.
+const defaultConcurrency = 4
+
+// convertedErrors match the errors of the errors package, which convertError
+// leaves as they are.
+var convertedErrors = []error{
+	errors.ErrAuthentication,
+	errors.ErrContentFilter,
+	errors.ErrContextLength,
+	errors.ErrInvalidRequest,
+	errors.ErrMissingAPIKey,
+	errors.ErrModelNotFound,
+	errors.ErrProvider,
+	errors.ErrQueueTimeout,
+	errors.ErrQuotaExceeded,
+	errors.ErrRateLimit,
+	errors.ErrUnsupportedParam,
+	errors.ErrUnsupportedProvider,
+}
+
+// Options configures CompleteAll. The zero value runs four requests at a
+// time without retries.
+type Options struct {
+	// Concurrency is the number of requests run at once.
+	Concurrency int
+
+	// OnProgress, if set, is called after each request finishes. Calls are
+	// serialized, so the callback does not need to be safe for concurrent use.
+	OnProgress func(Progress)
+
+	// Retry, if set, decides whether a failed request is retried.
+	Retry retry.Policy
+}
+
+// Progress reports how far CompleteAll has got.
+type Progress struct {
+	// Done is the number of finished requests, including failed ones.
+	Done int
+
+	// Failed is the number of requests that failed.
+	Failed int
+
+	// Result is the request that just finished.
+	Result Result
+
+	// Total is the number of requests.
+	Total int
+}
+
+// Result is the outcome of one request.
+type Result struct {
+	// Attempts is the number of times the request was sent, including
+	// retries. It is 0 if the request was not sent.
+	Attempts int
+
+	// Err is the request's error, if it failed. Errors are converted to the
+	// types in the errors package, except context errors.
+	Err error
+
+	// Index is the position of the request's params in the input.
+	Index int
+
+	// Latency is how long the request took, including retries.
+	Latency time.Duration
+
+	// Response is the completion, if the request succeeded.
+	Response *providers.ChatCompletion
+}
+
+// Results are the outcomes of CompleteAll, in input order.
+type Results []Result
+
+// CompleteAll sends each of params to provider and returns the results in
+// the same order. A failed request does not stop the others; check each
+// result's Err, or Results.Err. Once ctx is done, requests that have not
+// started fail with the context error.
+func CompleteAll(
+	ctx context.Context,
+	provider providers.Provider,
+	params []providers.CompletionParams,
+	opts Options,
+) Results {
+	results := make(Results, len(params))
+
+	var mu sync.Mutex // Serializes progress reports.
+	progress := Progress{Total: len(params)}
+	report := func(r Result) {
+		if opts.OnProgress == nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		progress.Done++
+		if r.Err != nil {
+			progress.Failed++
+		}
+		progress.Result = r
+		opts.OnProgress(progress)
+	}
+
+	parallel(opts.Concurrency, len(params), func(i int) {
+		results[i] = complete(ctx, provider, opts.Retry, i, params[i])
+		report(results[i])
+	})
+
+	return results
+}
+
+// Err returns an error listing the failed requests, or nil if all succeeded.
+// It matches each request's error with errors.Is and errors.As.
+func (r Results) Err() error {
+	return joinErrors(r.Failed(), func(res Result) (int, error) { return res.Index, res.Err })
+}
+
+// Failed returns the results of the requests that failed.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// complete runs the request at index i, retrying it with policy if set.
+func complete(
+	ctx context.Context,
+	provider providers.Provider,
+	policy retry.Policy,
+	i int,
+	params providers.CompletionParams,
+) Result {
+	if err := ctx.Err(); err != nil {
+		return Result{Err: err, Index: i}
+	}
+
+	start := time.Now()
+	var resp *providers.ChatCompletion
+	attempts, err := send(ctx, provider, policy, func() error {
+		var err error
+		resp, err = provider.Completion(ctx, params)
+		return err
+	})
+	if err != nil {
+		resp = nil
+	}
+
+	return Result{
+		Attempts: attempts,
+		Err:      err,
+		Index:    i,
+		Latency:  time.Since(start),
+		Response: resp,
+	}
+}
+
+// convertError converts err to the types in the errors package with the
+// provider's error converter. Context errors and errors already converted
+// are returned as they are.
+func convertError(provider providers.Provider, err error) error {
+	if err == nil || stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	for _, sentinel := range convertedErrors {
+		if stderrors.Is(err, sentinel) {
+			return err
+		}
+	}
+
+	if converter, ok := providers.As[providers.ErrorConverter](provider); ok {
+		return converter.ConvertError(err)
+	}
+	return errors.NewProviderError(provider.Name(), err)
+}
+
+// joinErrors returns an error listing failed, whose index and error are
+// returned by item, or nil if failed is empty.
+func joinErrors[T any](failed []T, item func(T) (int, error)) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(failed))
+	for i, f := range failed {
+		index, err := item(f)
+		errs[i] = fmt.Errorf("item %d: %w", index, err)
+	}
+	return fmt.Errorf("%d items failed: %w", len(failed), stderrors.Join(errs...))
+}
+
+// parallel calls fn for 0 to n-1, running up to concurrency calls at once.
+func parallel(concurrency int, n int, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, n) {
+		wg.Go(func() {
+			for i := range jobs {
+				fn(i)
+			}
+		})
+	}
+
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// send calls fn once, or with retries if policy is set, and returns the
+// number of attempts and the converted error.
+func send(ctx context.Context, provider providers.Provider, policy retry.Policy, fn func() error) (int, error) {
+	if policy == nil {
+		return 1, convertError(provider, fn())
+	}
+
+	attempts, err := retry.Do(ctx, policy, provider.Name(), fn)
+	return attempts, convertError(provider, err)
+}
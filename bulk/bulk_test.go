@@ -0,0 +1,218 @@
+package bulk
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// numberedParams returns n params whose models are "0", "1", ...
+func numberedParams(n int) []providers.CompletionParams {
+	params := make([]providers.CompletionParams, n)
+	for i := range params {
+		params[i] = providers.CompletionParams{Model: fmt.Sprint(i), Messages: testutil.SimpleMessages()}
+	}
+	return params
+}
+
+// echoProvider returns a mock that answers each request with its model.
+func echoProvider() *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return testutil.MockChatCompletion(params.Model), nil
+	}
+	return mock
+}
+
+func TestCompleteAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns results in input order", func(t *testing.T) {
+		t.Parallel()
+
+		mock := echoProvider()
+		results := CompleteAll(context.Background(), mock, numberedParams(20), Options{Concurrency: 3})
+
+		require.Len(t, results, 20)
+		for i, r := range results {
+			require.NoError(t, r.Err)
+			require.Equal(t, i, r.Index)
+			require.Equal(t, fmt.Sprint(i), r.Response.Choices[0].Message.ContentString())
+		}
+	})
+
+	t.Run("bounds parallelism", func(t *testing.T) {
+		t.Parallel()
+
+		var running, peak atomic.Int32
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return testutil.MockChatCompletion("ok"), nil
+		}
+
+		CompleteAll(context.Background(), mock, numberedParams(12), Options{Concurrency: 3})
+
+		require.Equal(t, int32(3), peak.Load())
+	})
+
+	t.Run("keeps going after failures", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if params.Model == "1" {
+				return nil, errors.NewInvalidRequestError("mock", stderrors.New("bad request"))
+			}
+			return testutil.MockChatCompletion(params.Model), nil
+		}
+
+		results := CompleteAll(context.Background(), mock, numberedParams(3), Options{})
+
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, errors.ErrInvalidRequest)
+		require.Nil(t, results[1].Response)
+		require.NoError(t, results[2].Err)
+	})
+
+	t.Run("retries failed items with the policy", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		attempts := map[string]int{}
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			mu.Lock()
+			attempts[params.Model]++
+			n := attempts[params.Model]
+			mu.Unlock()
+
+			if n == 1 {
+				return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+			}
+			return testutil.MockChatCompletion(params.Model), nil
+		}
+
+		results := CompleteAll(context.Background(), mock, numberedParams(4), Options{
+			Retry: retry.Backoff{BaseDelay: time.Millisecond},
+		})
+
+		for _, r := range results {
+			require.NoError(t, r.Err)
+			require.Equal(t, 2, r.Attempts)
+		}
+		require.Equal(t, map[string]int{"0": 2, "1": 2, "2": 2, "3": 2}, attempts)
+	})
+
+	t.Run("converts errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, stderrors.New("connection reset")
+		}
+
+		results := CompleteAll(context.Background(), mock, numberedParams(1), Options{})
+
+		var providerErr *errors.ProviderError
+		require.ErrorAs(t, results[0].Err, &providerErr)
+		require.Equal(t, "mock", providerErr.Provider)
+		require.Equal(t, 1, results[0].Attempts)
+	})
+
+	t.Run("collects failures", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if params.Model == "1" || params.Model == "3" {
+				return nil, errors.NewContextLengthError("mock", stderrors.New("too long"))
+			}
+			return testutil.MockChatCompletion(params.Model), nil
+		}
+
+		results := CompleteAll(context.Background(), mock, numberedParams(4), Options{})
+
+		failed := results.Failed()
+		require.Len(t, failed, 2)
+		require.Equal(t, 1, failed[0].Index)
+		require.Equal(t, 3, failed[1].Index)
+
+		err := results.Err()
+		require.ErrorIs(t, err, errors.ErrContextLength)
+		require.ErrorContains(t, err, "2 items failed")
+		require.ErrorContains(t, err, "item 3:")
+
+		require.NoError(t, CompleteAll(context.Background(), echoProvider(), numberedParams(2), Options{}).Err())
+	})
+
+	t.Run("reports progress", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if params.Model == "2" {
+				return nil, errors.NewProviderError("mock", stderrors.New("boom"))
+			}
+			return testutil.MockChatCompletion(params.Model), nil
+		}
+
+		var reports []Progress
+		CompleteAll(context.Background(), mock, numberedParams(5), Options{
+			Concurrency: 2,
+			OnProgress:  func(p Progress) { reports = append(reports, p) },
+		})
+
+		require.Len(t, reports, 5)
+		for i, p := range reports {
+			require.Equal(t, i+1, p.Done)
+			require.Equal(t, 5, p.Total)
+		}
+		require.Equal(t, 1, reports[4].Failed)
+	})
+
+	t.Run("fails unstarted items once the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			cancel()
+			return testutil.MockChatCompletion(params.Model), nil
+		}
+
+		results := CompleteAll(ctx, mock, numberedParams(5), Options{Concurrency: 1})
+
+		require.NoError(t, results[0].Err)
+		for _, r := range results[1:] {
+			require.ErrorIs(t, r.Err, context.Canceled)
+			require.Zero(t, r.Attempts)
+		}
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+
+	t.Run("handles empty input", func(t *testing.T) {
+		t.Parallel()
+
+		require.Empty(t, CompleteAll(context.Background(), echoProvider(), nil, Options{}))
+	})
+}
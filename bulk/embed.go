@@ -0,0 +1,204 @@
+package bulk
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// defaultBatchSize is the number of inputs embedded per request when
+// EmbedOptions.BatchSize is not set.
+const defaultBatchSize = 64
+
+// EmbedOptions configures EmbedAll. The zero value sends 64 inputs per
+// request, four requests at a time, without retries.
+type EmbedOptions struct {
+	// BatchSize is the number of inputs embedded per request.
+	BatchSize int
+
+	// Concurrency is the number of requests run at once.
+	Concurrency int
+
+	// Retry, if set, decides whether a failed request is retried.
+	Retry retry.Policy
+}
+
+// EmbeddingResult is the outcome of embedding one input.
+type EmbeddingResult struct {
+	// Attempts is the number of requests that included the input, including
+	// retries and the request that embedded it alone after its batch failed.
+	// It is 0 if the input was not sent.
+	Attempts int
+
+	// Embedding is the input's embedding, if it succeeded.
+	Embedding []float64
+
+	// Err is the input's error, if it failed. Errors are converted to the
+	// types in the errors package, except context errors.
+	Err error
+
+	// Index is the position of the input in the input slice.
+	Index int
+}
+
+// EmbeddingResults are the outcomes of EmbedAll, in input order.
+type EmbeddingResults []EmbeddingResult
+
+// EmbedAll embeds inputs with model on provider, in batches of
+// EmbedOptions.BatchSize, and returns a result per input in the same order.
+//
+// When a batch is rejected because of its inputs, such as an input longer
+// than the model's context, each of its inputs is embedded alone, so only the
+// inputs at fault fail. Other errors, such as authentication failures, fail
+// the batch's inputs without splitting it. Once ctx is done, batches that
+// have not started fail with the context error.
+func EmbedAll(
+	ctx context.Context,
+	provider providers.EmbeddingProvider,
+	model string,
+	inputs []string,
+	opts EmbedOptions,
+) EmbeddingResults {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	results := make(EmbeddingResults, len(inputs))
+	batches := (len(inputs) + batchSize - 1) / batchSize
+	parallel(opts.Concurrency, batches, func(b int) {
+		start := b * batchSize
+		end := min(start+batchSize, len(inputs))
+		embedBatch(ctx, provider, model, opts.Retry, inputs, start, results[start:end])
+	})
+
+	return results
+}
+
+// Embeddings returns the embeddings in input order, with nil for failed
+// inputs.
+func (r EmbeddingResults) Embeddings() [][]float64 {
+	embeddings := make([][]float64, len(r))
+	for i, res := range r {
+		embeddings[i] = res.Embedding
+	}
+	return embeddings
+}
+
+// Err returns an error listing the failed inputs, or nil if all succeeded.
+// It matches each input's error with errors.Is and errors.As.
+func (r EmbeddingResults) Err() error {
+	return joinErrors(r.Failed(), func(res EmbeddingResult) (int, error) { return res.Index, res.Err })
+}
+
+// Failed returns the results of the inputs that failed.
+func (r EmbeddingResults) Failed() EmbeddingResults {
+	var failed EmbeddingResults
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// embed sends one embedding request for texts and returns their embeddings
+// in order, with the number of attempts made.
+func embed(
+	ctx context.Context,
+	provider providers.EmbeddingProvider,
+	model string,
+	policy retry.Policy,
+	texts []string,
+) ([][]float64, int, error) {
+	var embeddings [][]float64
+	attempts, err := send(ctx, provider, policy, func() error {
+		resp, err := provider.Embedding(ctx, providers.EmbeddingParams{Model: model, Input: texts})
+		if err != nil {
+			return err
+		}
+
+		embeddings, err = ordered(provider.Name(), resp, len(texts))
+		return err
+	})
+	return embeddings, attempts, err
+}
+
+// embedBatch embeds inputs[start:start+len(results)] into results, splitting
+// the batch if it is rejected because of its inputs.
+func embedBatch(
+	ctx context.Context,
+	provider providers.EmbeddingProvider,
+	model string,
+	policy retry.Policy,
+	inputs []string,
+	start int,
+	results EmbeddingResults,
+) {
+	for i := range results {
+		results[i].Index = start + i
+	}
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return
+	}
+
+	texts := inputs[start : start+len(results)]
+	embeddings, attempts, err := embed(ctx, provider, model, policy, texts)
+	for i := range results {
+		results[i].Attempts = attempts
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Embedding = embeddings[i]
+	}
+	if err == nil || len(results) == 1 || !inputError(err) {
+		return
+	}
+
+	// Find the inputs at fault by embedding each alone.
+	for i := range results {
+		if ctx.Err() != nil {
+			return
+		}
+
+		embeddings, attempts, err := embed(ctx, provider, model, policy, texts[i:i+1])
+		results[i].Attempts += attempts
+		results[i].Err = err
+		if err == nil {
+			results[i].Embedding = embeddings[0]
+		}
+	}
+}
+
+// inputError reports whether err may be caused by some of a request's inputs
+// rather than the request as a whole.
+func inputError(err error) bool {
+	return stderrors.Is(err, errors.ErrInvalidRequest) ||
+		stderrors.Is(err, errors.ErrContextLength) ||
+		stderrors.Is(err, errors.ErrContentFilter)
+}
+
+// ordered returns the embeddings in resp by input index, checking that there
+// is one for each of n inputs.
+func ordered(provider string, resp *providers.EmbeddingResponse, n int) ([][]float64, error) {
+	if len(resp.Data) != n {
+		return nil, errors.NewProviderError(provider, fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), n))
+	}
+
+	embeddings := make([][]float64, n)
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= n || embeddings[data.Index] != nil {
+			return nil, errors.NewProviderError(provider, fmt.Errorf("unexpected embedding index %d", data.Index))
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,152 @@
+package bulk
+
+import (
+	"context"
+	stderrors "errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// testInputs are inputs to embed; "bad" inputs are rejected by mockEmbedder.
+var testInputs = []string{"a", "bb", "bad", "dddd", "eeeee"}
+
+// mockEmbedder returns a mock whose embedding of an input is its length,
+// and that rejects requests containing "bad".
+func mockEmbedder() *testutil.MockProvider {
+	var mu sync.Mutex
+	mock := testutil.NewMockProvider()
+	mock.EmbeddingFunc = func(_ context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		inputs, _ := params.Input.([]string) // EmbedAll always sends a slice.
+		if slices.Contains(inputs, "bad") {
+			return nil, errors.NewContextLengthError("mock", stderrors.New("input too long"))
+		}
+
+		resp := &providers.EmbeddingResponse{Model: params.Model}
+		// Reversed, to check that embeddings are ordered by index.
+		for i := len(inputs) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, providers.EmbeddingData{Embedding: []float64{float64(len(inputs[i]))}, Index: i})
+		}
+		return resp, nil
+	}
+	return mock
+}
+
+func TestEmbedAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns embeddings in input order", func(t *testing.T) {
+		t.Parallel()
+
+		mock := mockEmbedder()
+		inputs := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+		results := EmbedAll(context.Background(), mock, "embed", inputs, EmbedOptions{BatchSize: 2})
+
+		require.NoError(t, results.Err())
+		require.Equal(t, [][]float64{{1}, {2}, {3}, {4}, {5}}, results.Embeddings())
+		for i, r := range results {
+			require.Equal(t, i, r.Index)
+			require.Equal(t, 1, r.Attempts)
+		}
+		require.Len(t, mock.EmbeddingCalls, 3)
+	})
+
+	t.Run("isolates inputs that fail their batch", func(t *testing.T) {
+		t.Parallel()
+
+		mock := mockEmbedder()
+		results := EmbedAll(context.Background(), mock, "embed", testInputs, EmbedOptions{BatchSize: 3})
+
+		failed := results.Failed()
+		require.Len(t, failed, 1)
+		require.Equal(t, 2, failed[0].Index)
+		require.ErrorIs(t, failed[0].Err, errors.ErrContextLength)
+		require.Equal(t, 2, failed[0].Attempts)
+
+		require.Equal(t, [][]float64{{1}, {2}, nil, {4}, {5}}, results.Embeddings())
+		require.Equal(t, 2, results[0].Attempts)
+		require.Equal(t, 1, results[3].Attempts)
+
+		// One request per batch, then one per input of the failed batch.
+		require.Len(t, mock.EmbeddingCalls, 5)
+	})
+
+	t.Run("does not split batches failing as a whole", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.EmbeddingFunc = func(context.Context, providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			return nil, errors.NewAuthenticationError("mock", stderrors.New("bad key"))
+		}
+
+		results := EmbedAll(context.Background(), mock, "embed", testInputs, EmbedOptions{BatchSize: 5})
+
+		require.Len(t, results.Failed(), 5)
+		require.ErrorIs(t, results.Err(), errors.ErrAuthentication)
+		require.Len(t, mock.EmbeddingCalls, 1)
+	})
+
+	t.Run("retries failed batches with the policy", func(t *testing.T) {
+		t.Parallel()
+
+		mock := mockEmbedder()
+		embedder := mock.EmbeddingFunc
+		mock.EmbeddingFunc = func(ctx context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			if len(mock.EmbeddingCalls) == 1 {
+				return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+			}
+			return embedder(ctx, params)
+		}
+
+		results := EmbedAll(context.Background(), mock, "embed", []string{"a", "bb"}, EmbedOptions{
+			Retry: retry.Backoff{BaseDelay: time.Millisecond},
+		})
+
+		require.NoError(t, results.Err())
+		require.Equal(t, 2, results[0].Attempts)
+		require.Equal(t, 2, results[1].Attempts)
+	})
+
+	t.Run("rejects responses with missing embeddings", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider() // Returns one embedding per request.
+		results := EmbedAll(context.Background(), mock, "embed", []string{"a", "bb"}, EmbedOptions{})
+
+		require.ErrorIs(t, results.Err(), errors.ErrProvider)
+		require.ErrorContains(t, results[0].Err, "got 1 embeddings for 2 inputs")
+	})
+
+	t.Run("fails unstarted batches once the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mock := mockEmbedder()
+		results := EmbedAll(ctx, mock, "embed", testInputs, EmbedOptions{})
+
+		for _, r := range results {
+			require.ErrorIs(t, r.Err, context.Canceled)
+			require.Zero(t, r.Attempts)
+		}
+		require.Empty(t, mock.EmbeddingCalls)
+	})
+
+	t.Run("handles empty input", func(t *testing.T) {
+		t.Parallel()
+
+		require.Empty(t, EmbedAll(context.Background(), mockEmbedder(), "embed", nil, EmbedOptions{}))
+	})
+}
@@ -0,0 +1,241 @@
+package chat
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// encryptedPrefix starts the content of every message stored by an
+// EncryptedStore, followed by the key ID, a ":", and the base64 nonce and
+// ciphertext.
+const encryptedPrefix = "anyllm:aes-gcm:v1:"
+
+// Ensure types implement the required interfaces.
+var (
+	_ KeyProvider = (*StaticKeys)(nil)
+	_ Rewriter    = (*EncryptedStore)(nil)
+	_ Store       = (*EncryptedStore)(nil)
+)
+
+// EncryptedStore is a Store that encrypts messages with AES-GCM before
+// passing them to another store, so histories are never written in plaintext.
+// Each message is stored as a message holding only its ciphertext, bound to
+// its session so it cannot be moved to another one. Session IDs are not
+// encrypted.
+type EncryptedStore struct {
+	keys  KeyProvider
+	store Store
+}
+
+// KeyProvider supplies the keys an EncryptedStore uses, such as from a KMS or
+// secrets manager. Keys are 16, 24 or 32 bytes, for AES-128, AES-192 or
+// AES-256. Implementations must be safe for concurrent use.
+type KeyProvider interface {
+	// CurrentKey returns the key new messages are encrypted with, and its ID.
+	// IDs must not contain ":".
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+
+	// Key returns the key with the given ID, for decrypting messages
+	// encrypted with it.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider with a fixed set of keys. Keep retired keys in
+// the set until every message encrypted with them has been rewritten.
+type StaticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewEncryptedStore returns a store that encrypts messages with keys before
+// saving them to store.
+func NewEncryptedStore(store Store, keys KeyProvider) (*EncryptedStore, error) {
+	if store == nil || keys == nil {
+		return nil, fmt.Errorf("store and key provider are required")
+	}
+
+	return &EncryptedStore{keys: keys, store: store}, nil
+}
+
+// NewStaticKeys returns a key provider that encrypts with the key named
+// current and decrypts with any key in keys.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q not found", current)
+	}
+
+	s := &StaticKeys{current: current, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		s.keys[id] = key
+	}
+
+	return s, nil
+}
+
+// Append encrypts messages and adds them to the end of the session's history.
+func (e *EncryptedStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	encrypted, err := e.encrypt(ctx, sessionID, messages)
+	if err != nil {
+		return err
+	}
+
+	return e.store.Append(ctx, sessionID, encrypted...)
+}
+
+// List returns the IDs of all stored sessions.
+func (e *EncryptedStore) List(ctx context.Context) ([]string, error) {
+	return e.store.List(ctx)
+}
+
+// Load returns the session's decrypted history. It fails if any message is
+// not encrypted, was encrypted for another session, or has been tampered
+// with.
+func (e *EncryptedStore) Load(ctx context.Context, sessionID string) ([]providers.Message, error) {
+	stored, err := e.store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphers := make(map[string]cipher.AEAD)
+	var messages []providers.Message
+	for i, msg := range stored {
+		content, _ := msg.Content.(string)
+		rest, ok := strings.CutPrefix(content, encryptedPrefix)
+		if !ok {
+			return nil, fmt.Errorf("message %d of session %s is not encrypted", i, sessionID)
+		}
+		id, encoded, ok := strings.Cut(rest, ":")
+		if !ok {
+			return nil, fmt.Errorf("message %d of session %s has no key ID", i, sessionID)
+		}
+
+		aead, ok := ciphers[id]
+		if !ok {
+			key, err := e.keys.Key(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("getting key %q: %w", id, err)
+			}
+			if aead, err = newGCM(key); err != nil {
+				return nil, fmt.Errorf("key %q: %w", id, err)
+			}
+			ciphers[id] = aead
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return nil, fmt.Errorf("message %d of session %s is malformed", i, sessionID)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(sessionID))
+		if err != nil {
+			return nil, fmt.Errorf("decrypting message %d of session %s: %w", i, sessionID, err)
+		}
+
+		var decrypted providers.Message
+		if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+			return nil, fmt.Errorf("decoding message: %w", err)
+		}
+		messages = append(messages, decrypted)
+	}
+
+	return messages, nil
+}
+
+// Replace encrypts messages and replaces the session's history with them. The
+// underlying store must implement Rewriter.
+func (e *EncryptedStore) Replace(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	rewriter, ok := e.store.(Rewriter)
+	if !ok {
+		return fmt.Errorf("%T cannot rewrite histories; it must implement Rewriter", e.store)
+	}
+
+	var encrypted []providers.Message
+	if len(messages) > 0 {
+		var err error
+		if encrypted, err = e.encrypt(ctx, sessionID, messages); err != nil {
+			return err
+		}
+	}
+
+	return rewriter.Replace(ctx, sessionID, encrypted...)
+}
+
+// CurrentKey returns the key new messages are encrypted with.
+func (s *StaticKeys) CurrentKey(context.Context) (string, []byte, error) {
+	return s.current, s.keys[s.current], nil
+}
+
+// Key returns the key with the given ID.
+func (s *StaticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+// encrypt returns messages encrypted with the current key for the session.
+func (e *EncryptedStore) encrypt(
+	ctx context.Context,
+	sessionID string,
+	messages []providers.Message,
+) ([]providers.Message, error) {
+	id, key, err := e.keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting encryption key: %w", err)
+	}
+	if id == "" || strings.Contains(id, ":") {
+		return nil, fmt.Errorf("invalid key ID %q", id)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	encrypted := make([]providers.Message, 0, len(messages))
+	for _, msg := range messages {
+		plaintext, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("encoding message: %w", err)
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("generating nonce: %w", err)
+		}
+		sealed := aead.Seal(nonce, nonce, plaintext, []byte(sessionID))
+
+		encrypted = append(encrypted, providers.Message{
+			Content: encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed),
+		})
+	}
+
+	return encrypted, nil
+}
+
+// newGCM returns an AES-GCM cipher for key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testKeys returns a key provider encrypting with current.
+func testKeys(t *testing.T, current string) *StaticKeys {
+	t.Helper()
+
+	keys, err := NewStaticKeys(current, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+func TestEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	history := []providers.Message{
+		{Role: providers.RoleUser, Content: "my account number is 1234"},
+		{Role: providers.RoleAssistant, Content: "Thanks."},
+	}
+
+	t.Run("round-trips messages without storing plaintext", func(t *testing.T) {
+		t.Parallel()
+
+		backend := NewMemoryStore()
+		store, err := NewEncryptedStore(backend, testKeys(t, "k1"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Append(ctx, "s1", history...))
+		require.NoError(t, store.Append(ctx, "s1"))
+
+		raw, err := backend.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, raw, 2)
+		for _, msg := range raw {
+			require.Empty(t, msg.Role)
+			require.True(t, strings.HasPrefix(msg.ContentString(), encryptedPrefix+"k1:"))
+			require.NotContains(t, msg.ContentString(), "1234")
+		}
+
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Equal(t, history, loaded)
+
+		ids, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1"}, ids)
+	})
+
+	t.Run("decrypts with rotated keys", func(t *testing.T) {
+		t.Parallel()
+
+		backend := NewMemoryStore()
+		old, err := NewEncryptedStore(backend, testKeys(t, "k1"))
+		require.NoError(t, err)
+		require.NoError(t, old.Append(ctx, "s1", history[0]))
+
+		rotated, err := NewEncryptedStore(backend, testKeys(t, "k2"))
+		require.NoError(t, err)
+		require.NoError(t, rotated.Append(ctx, "s1", history[1]))
+
+		loaded, err := rotated.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Equal(t, history, loaded)
+	})
+
+	t.Run("rejects tampered, moved and plaintext messages", func(t *testing.T) {
+		t.Parallel()
+
+		backend := NewMemoryStore()
+		store, err := NewEncryptedStore(backend, testKeys(t, "k1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, "s1", history[0]))
+
+		raw, err := backend.Load(ctx, "s1")
+		require.NoError(t, err)
+
+		// A message copied into another session does not decrypt there.
+		require.NoError(t, backend.Append(ctx, "s2", raw[0]))
+		_, err = store.Load(ctx, "s2")
+		require.ErrorContains(t, err, "decrypting message 0 of session s2")
+
+		content := raw[0].ContentString()
+		tampered := content[:len(content)-4] + "AAAA"
+		require.NoError(t, backend.Append(ctx, "s3", providers.Message{Content: tampered}))
+		_, err = store.Load(ctx, "s3")
+		require.Error(t, err)
+
+		require.NoError(t, backend.Append(ctx, "s4", history[0]))
+		_, err = store.Load(ctx, "s4")
+		require.ErrorContains(t, err, "not encrypted")
+
+		unknown := strings.Replace(content, ":k1:", ":k9:", 1)
+		require.NoError(t, backend.Append(ctx, "s5", providers.Message{Content: unknown}))
+		_, err = store.Load(ctx, "s5")
+		require.ErrorContains(t, err, `unknown key "k9"`)
+	})
+
+	t.Run("works with sessions", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := NewEncryptedStore(NewMemoryStore(), testKeys(t, "k1"))
+		require.NoError(t, err)
+
+		session, err := NewSession(testutil.NewMockProvider(), providers.CompletionParams{}, WithStore(store))
+		require.NoError(t, err)
+		_, err = session.Send(ctx, providers.Message{Role: providers.RoleUser, Content: "Hello"})
+		require.NoError(t, err)
+
+		resumed, err := Resume(ctx, testutil.NewMockProvider(), providers.CompletionParams{}, store, session.ID())
+		require.NoError(t, err)
+		require.Equal(t, session.Messages(), resumed.Messages())
+	})
+}
+
+func TestNewStaticKeys(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{1}, 32)
+
+	_, err := NewStaticKeys("missing", map[string][]byte{"k1": key})
+	require.Error(t, err)
+
+	_, err = NewStaticKeys("k1", map[string][]byte{"k1": key[:10]})
+	require.Error(t, err)
+
+	_, err = NewStaticKeys("a:b", map[string][]byte{"a:b": key})
+	require.Error(t, err)
+
+	_, err = NewEncryptedStore(NewMemoryStore(), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Lua scripts run by RedisStore. Every key of a store shares the hash tag of
+// its prefix, so scripts can touch a history and the session index together,
+// also on a Redis cluster. Messages are pushed in slices, to stay within the
+// number of arguments Lua can unpack at once.
+const (
+	// redisAppendScript pushes ARGV[2:] onto the history at KEYS[1] and adds
+	// the session ARGV[1] to the index at KEYS[2].
+	redisAppendScript = `
+for i = 2, #ARGV, 1000 do
+	redis.call('RPUSH', KEYS[1], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+redis.call('SADD', KEYS[2], ARGV[1])
+return 0
+`
+
+	// redisListScript returns the session index at KEYS[1].
+	redisListScript = `return redis.call('SMEMBERS', KEYS[1])`
+
+	// redisLoadScript returns the history at KEYS[1].
+	redisLoadScript = `return redis.call('LRANGE', KEYS[1], 0, -1)`
+
+	// redisReplaceScript replaces the history at KEYS[1] with ARGV[2:], and
+	// adds the session ARGV[1] to the index at KEYS[2], or removes it without
+	// messages.
+	redisReplaceScript = `
+redis.call('DEL', KEYS[1])
+if #ARGV < 2 then
+	redis.call('SREM', KEYS[2], ARGV[1])
+	return 0
+end
+for i = 2, #ARGV, 1000 do
+	redis.call('RPUSH', KEYS[1], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+redis.call('SADD', KEYS[2], ARGV[1])
+return 0
+`
+)
+
+// Ensure RedisStore implements the Store and Rewriter interfaces.
+var (
+	_ Rewriter = (*RedisStore)(nil)
+	_ Store    = (*RedisStore)(nil)
+)
+
+// RedisClient runs Lua scripts on a Redis server. It matches the Client of
+// the redislimit package, so one adapter serves both.
+type RedisClient interface {
+	// Eval runs script with the given keys and arguments, as the EVAL command
+	// does, and returns its reply: int64 for integers, string for bulk
+	// strings and []any for arrays.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// RedisStore is a Store backed by Redis. Each history is a list of JSON
+// messages, and a set indexes the stored sessions. Every write is a single
+// script, so it is atomic.
+//
+// All keys of a store share the hash tag of its prefix, so that a script can
+// update a history and the index together. On Redis Cluster, a store's
+// sessions therefore live in a single slot, on one node; use several prefixes
+// to spread sessions across nodes.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore returns a store that keeps histories in Redis through client,
+// under keys starting with prefix. Stores with the same prefix share their
+// sessions.
+func NewRedisStore(client RedisClient, prefix string) (*RedisStore, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("key prefix is required")
+	}
+
+	return &RedisStore{client: client, prefix: prefix}, nil
+}
+
+// Append adds messages to the end of the session's history.
+func (r *RedisStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	args, err := redisArgs(sessionID, messages)
+	if err != nil {
+		return err
+	}
+	if _, err := r.eval(ctx, redisAppendScript, r.keys(sessionID), args...); err != nil {
+		return fmt.Errorf("appending to session %s: %w", sessionID, err)
+	}
+
+	return nil
+}
+
+// List returns the IDs of all stored sessions in lexical order.
+func (r *RedisStore) List(ctx context.Context) ([]string, error) {
+	reply, err := r.eval(ctx, redisListScript, []string{r.indexKey()})
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+
+	ids, err := redisStrings(reply)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	slices.Sort(ids)
+
+	return ids, nil
+}
+
+// Load returns the session's history.
+func (r *RedisStore) Load(ctx context.Context, sessionID string) ([]providers.Message, error) {
+	reply, err := r.eval(ctx, redisLoadScript, []string{r.historyKey(sessionID)})
+	if err != nil {
+		return nil, fmt.Errorf("loading session %s: %w", sessionID, err)
+	}
+
+	items, err := redisStrings(reply)
+	if err != nil {
+		return nil, fmt.Errorf("loading session %s: %w", sessionID, err)
+	}
+
+	var messages []providers.Message
+	for _, item := range items {
+		var msg providers.Message
+		if err := json.Unmarshal([]byte(item), &msg); err != nil {
+			return nil, fmt.Errorf("decoding message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Replace replaces the session's history with messages in a single script.
+func (r *RedisStore) Replace(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	args, err := redisArgs(sessionID, messages)
+	if err != nil {
+		return err
+	}
+	if _, err := r.eval(ctx, redisReplaceScript, r.keys(sessionID), args...); err != nil {
+		return fmt.Errorf("replacing session %s: %w", sessionID, err)
+	}
+
+	return nil
+}
+
+// eval runs script on the client and wraps its errors.
+func (r *RedisStore) eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	reply, err := r.client.Eval(ctx, script, keys, args...)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return reply, nil
+}
+
+// historyKey returns the key of the session's history.
+func (r *RedisStore) historyKey(sessionID string) string {
+	return "{" + r.prefix + "}:session:" + sessionID
+}
+
+// indexKey returns the key of the set of stored sessions.
+func (r *RedisStore) indexKey() string {
+	return "{" + r.prefix + "}:sessions"
+}
+
+// keys returns the keys the write scripts take for the session.
+func (r *RedisStore) keys(sessionID string) []string {
+	return []string{r.historyKey(sessionID), r.indexKey()}
+}
+
+// redisArgs returns the script arguments for writing messages to the
+// session: its ID followed by each message as JSON.
+func redisArgs(sessionID string, messages []providers.Message) ([]any, error) {
+	args := make([]any, 0, len(messages)+1)
+	args = append(args, sessionID)
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("encoding message: %w", err)
+		}
+		args = append(args, string(data))
+	}
+
+	return args, nil
+}
+
+// redisStrings converts an array reply of bulk strings.
+func redisStrings(reply any) ([]string, error) {
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply %T", reply)
+	}
+
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected reply item %T", item)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// fakeRedis is a RedisClient that runs the store's scripts in Go, on lists
+// and sets kept in memory, as Redis would run them in Lua.
+type fakeRedis struct {
+	err   error
+	keys  []string
+	lists map[string][]string
+	mu    sync.Mutex
+	sets  map[string]map[string]bool
+}
+
+// newFakeRedis returns an empty fakeRedis.
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{lists: map[string][]string{}, sets: map[string]map[string]bool{}}
+}
+
+// Eval runs script as Redis would.
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.keys = append(f.keys, keys...)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	switch script {
+	case redisAppendScript:
+		f.push(keys[0], args[1:])
+		f.add(keys[1], args[0].(string))
+		return int64(0), nil
+	case redisListScript:
+		members := []any{}
+		for member := range f.sets[keys[0]] {
+			members = append(members, member)
+		}
+		return members, nil
+	case redisLoadScript:
+		items := []any{}
+		for _, item := range f.lists[keys[0]] {
+			items = append(items, item)
+		}
+		return items, nil
+	case redisReplaceScript:
+		delete(f.lists, keys[0])
+		if len(args) < 2 {
+			delete(f.sets[keys[1]], args[0].(string))
+			return int64(0), nil
+		}
+		f.push(keys[0], args[1:])
+		f.add(keys[1], args[0].(string))
+		return int64(0), nil
+	default:
+		return nil, fmt.Errorf("unknown script")
+	}
+}
+
+// add adds member to the set at key.
+func (f *fakeRedis) add(key string, member string) {
+	if f.sets[key] == nil {
+		f.sets[key] = map[string]bool{}
+	}
+	f.sets[key][member] = true
+}
+
+// push appends values to the list at key.
+func (f *fakeRedis) push(key string, values []any) {
+	for _, value := range values {
+		f.lists[key] = append(f.lists[key], value.(string))
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRedisStore(nil, "chat")
+	require.Error(t, err)
+
+	_, err = NewRedisStore(newFakeRedis(), "")
+	require.Error(t, err)
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stores histories", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := NewRedisStore(newFakeRedis(), "chat")
+		require.NoError(t, err)
+		requireStore(t, store)
+	})
+
+	t.Run("keeps every key in the prefix's slot", func(t *testing.T) {
+		t.Parallel()
+
+		client := newFakeRedis()
+		store, err := NewRedisStore(client, "chat")
+		require.NoError(t, err)
+		require.NoError(t, store.Append(context.Background(), "a", providers.Message{Role: providers.RoleUser, Content: "hi"}))
+
+		require.Equal(t, []string{"{chat}:session:a", "{chat}:sessions"}, slices.Compact(client.keys))
+	})
+
+	t.Run("shares sessions between stores with the same prefix", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		client := newFakeRedis()
+		first, err := NewRedisStore(client, "chat")
+		require.NoError(t, err)
+		second, err := NewRedisStore(client, "chat")
+		require.NoError(t, err)
+		other, err := NewRedisStore(client, "other")
+		require.NoError(t, err)
+
+		require.NoError(t, first.Append(ctx, "a", providers.Message{Role: providers.RoleUser, Content: "hi"}))
+
+		messages, err := second.Load(ctx, "a")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+
+		ids, err := other.List(ctx)
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+
+	t.Run("wraps client errors", func(t *testing.T) {
+		t.Parallel()
+
+		client := newFakeRedis()
+		client.err = stderrors.New("connection refused")
+		store, err := NewRedisStore(client, "chat")
+		require.NoError(t, err)
+
+		_, err = store.Load(context.Background(), "a")
+		require.ErrorIs(t, err, client.err)
+		require.ErrorContains(t, err, "loading session a: redis: connection refused")
+	})
+}
@@ -0,0 +1,526 @@
+package chat
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Retention markers.
+const (
+	// hashPrefix starts values hashed with SHA-256.
+	hashPrefix = "sha256:"
+
+	// hmacPrefix starts values hashed with HMAC-SHA256 and the policy's key.
+	hmacPrefix = "hmac-sha256:"
+
+	// partTypeText is the type of text content parts.
+	partTypeText = "text"
+
+	// redacted replaces redacted values.
+	redacted = "[redacted]"
+
+	// retainedPrefix starts the content of every message stored by a
+	// RetentionStore, followed by its JSON envelope.
+	retainedPrefix = "anyllm:retained:v1:"
+)
+
+// Retention actions.
+const (
+	// ActionDelete removes the whole message.
+	ActionDelete RetentionAction = "delete"
+
+	// ActionHash replaces a field with its SHA-256 hash, or its HMAC-SHA256
+	// if the policy has a hash key. Equal values keep equal hashes, so
+	// messages can still be grouped or matched without being read.
+	ActionHash RetentionAction = "hash"
+
+	// ActionRedact replaces a field with "[redacted]".
+	ActionRedact RetentionAction = "redact"
+)
+
+// Message fields that retention rules scrub.
+const (
+	// FieldContent is the message's content: its text, the text parts of
+	// multimodal content, and its tool result. Image and file parts are
+	// removed.
+	FieldContent MessageField = "content"
+
+	// FieldReasoning is the message's reasoning.
+	FieldReasoning MessageField = "reasoning"
+
+	// FieldToolArguments is the arguments of the message's tool calls. Each
+	// is replaced with a JSON string, so it stays valid JSON.
+	FieldToolArguments MessageField = "tool_arguments"
+)
+
+// Ensure RetentionStore implements the Store interface.
+var _ Store = (*RetentionStore)(nil)
+
+// MessageField is a part of a message that a retention rule scrubs.
+type MessageField string
+
+// RetentionAction is what a retention rule does to a message.
+type RetentionAction string
+
+// RetentionPolicy is the set of retention rules a RetentionStore enforces.
+type RetentionPolicy struct {
+	// HashKey, if set, makes ActionHash use HMAC-SHA256 with this key, so
+	// hashes of guessable values such as e-mail addresses cannot be reversed
+	// by hashing candidates.
+	HashKey []byte
+
+	// Rules apply to individual messages, in order.
+	Rules []RetentionRule
+
+	// TTL, if set, expires a whole session once its last message is older
+	// than TTL.
+	TTL time.Duration
+}
+
+// RetentionReport summarizes a run of RetentionStore.Enforce.
+type RetentionReport struct {
+	// Deleted is the number of messages deleted, including those of expired
+	// sessions.
+	Deleted int `json:"deleted"`
+
+	// Expired is the number of sessions deleted because their TTL passed.
+	Expired int `json:"expired"`
+
+	// Scrubbed is the number of messages whose fields were hashed or
+	// redacted.
+	Scrubbed int `json:"scrubbed"`
+
+	// Sessions is the number of sessions checked.
+	Sessions int `json:"sessions"`
+}
+
+// RetentionRule applies an action to messages once they reach an age.
+type RetentionRule struct {
+	// Action is what to do to matching messages.
+	Action RetentionAction
+
+	// After is the age at which the rule applies. Zero applies it before
+	// messages are stored, so they are never written as they were.
+	After time.Duration
+
+	// Field is the field ActionHash and ActionRedact scrub. It defaults to
+	// FieldContent.
+	Field MessageField
+
+	// Role limits the rule to messages with this role. Empty matches every
+	// role.
+	Role string
+}
+
+// RetentionStore is a Store that enforces a retention policy on another
+// store. It records when each message was stored, scrubs messages before
+// storing them, and never returns messages or sessions that the policy has
+// removed, even before Enforce deletes them from the underlying store.
+//
+// Each message is stored as a message holding a JSON envelope with the
+// original message and its storage time. Messages in the underlying store
+// without an envelope, such as those written before it was wrapped, are
+// treated as stored when they are first read.
+type RetentionStore struct {
+	now    func() time.Time
+	policy RetentionPolicy
+	store  Store
+}
+
+// retained is the envelope a RetentionStore stores each message in.
+type retained struct {
+	Message  providers.Message                `json:"message"`
+	Scrubbed map[MessageField]RetentionAction `json:"scrubbed,omitempty"`
+	StoredAt int64                            `json:"storedAt"`
+}
+
+// NewRetentionStore returns a store that enforces policy on store.
+func NewRetentionStore(store Store, policy RetentionPolicy) (*RetentionStore, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store must not be nil")
+	}
+	if policy.TTL < 0 {
+		return nil, fmt.Errorf("TTL must not be negative, got %s", policy.TTL)
+	}
+
+	policy.Rules = slices.Clone(policy.Rules)
+	for i, rule := range policy.Rules {
+		switch rule.Action {
+		case ActionDelete, ActionHash, ActionRedact:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i, rule.Action)
+		}
+		switch rule.Field {
+		case "":
+			policy.Rules[i].Field = FieldContent
+		case FieldContent, FieldReasoning, FieldToolArguments:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown field %q", i, rule.Field)
+		}
+		if rule.After < 0 {
+			return nil, fmt.Errorf("rule %d: age must not be negative, got %s", i, rule.After)
+		}
+	}
+
+	return &RetentionStore{now: time.Now, policy: policy, store: store}, nil
+}
+
+// Append scrubs messages with the rules that apply at once, and adds the
+// rest to the end of the session's history.
+func (r *RetentionStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	now := r.now()
+	stored := make([]providers.Message, 0, len(messages))
+	for _, msg := range messages {
+		entry, keep := r.apply(retained{Message: msg, StoredAt: now.Unix()}, now)
+		if !keep {
+			continue
+		}
+
+		wrapped, err := wrap(entry)
+		if err != nil {
+			return err
+		}
+		stored = append(stored, wrapped)
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+	return r.store.Append(ctx, sessionID, stored...)
+}
+
+// Enforce applies the policy to every stored session, deleting expired
+// sessions and messages and scrubbing fields in the underlying store, which
+// must implement Rewriter. Run it periodically, for example from a cron job.
+//
+// Each history is rewritten with a load followed by a replace, so messages
+// appended to a session in between are lost. Run it while sessions are idle,
+// or hold off writers for its duration.
+func (r *RetentionStore) Enforce(ctx context.Context) (*RetentionReport, error) {
+	rewriter, ok := r.store.(Rewriter)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot rewrite histories; it must implement Rewriter", r.store)
+	}
+
+	ids, err := r.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{}
+	now := r.now()
+	for _, id := range ids {
+		report.Sessions++
+
+		entries, stamped, err := r.load(ctx, id, now)
+		if err != nil {
+			return report, err
+		}
+
+		if r.expired(entries, now) {
+			if err := rewriter.Replace(ctx, id); err != nil {
+				return report, fmt.Errorf("deleting session %s: %w", id, err)
+			}
+			report.Expired++
+			report.Deleted += len(entries)
+			continue
+		}
+
+		applied, keep, unpaired := r.applyAll(entries, now)
+		kept := make([]providers.Message, 0, len(entries))
+		var deleted, scrubbed int
+		for i, entry := range applied {
+			if !keep[i] {
+				deleted++
+				continue
+			}
+			if !maps.Equal(entry.Scrubbed, entries[i].Scrubbed) {
+				scrubbed++
+			}
+
+			wrapped, err := wrap(entry)
+			if err != nil {
+				return report, err
+			}
+			kept = append(kept, wrapped)
+		}
+		if !stamped && deleted == 0 && scrubbed == 0 && !unpaired {
+			continue
+		}
+
+		if err := rewriter.Replace(ctx, id, kept...); err != nil {
+			return report, fmt.Errorf("rewriting session %s: %w", id, err)
+		}
+		report.Deleted += deleted
+		report.Scrubbed += scrubbed
+	}
+
+	return report, nil
+}
+
+// List returns the IDs of all stored sessions, including expired sessions
+// that Enforce has not deleted yet.
+func (r *RetentionStore) List(ctx context.Context) ([]string, error) {
+	return r.store.List(ctx)
+}
+
+// Load returns the session's history as the policy allows it to be seen
+// now, or nil if the session has expired.
+func (r *RetentionStore) Load(ctx context.Context, sessionID string) ([]providers.Message, error) {
+	now := r.now()
+	entries, _, err := r.load(ctx, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+	if r.expired(entries, now) {
+		return nil, nil
+	}
+
+	applied, keep, _ := r.applyAll(entries, now)
+	var messages []providers.Message
+	for i, entry := range applied {
+		if keep[i] {
+			messages = append(messages, entry.Message)
+		}
+	}
+
+	return messages, nil
+}
+
+// apply applies the rules that are due for entry at now. It reports false if
+// the message is deleted. A field is scrubbed at most once per action, and a
+// redacted field is never hashed.
+func (r *RetentionStore) apply(entry retained, now time.Time) (retained, bool) {
+	age := now.Sub(time.Unix(entry.StoredAt, 0))
+	cloned := false
+	for _, rule := range r.policy.Rules {
+		if age < rule.After || (rule.Role != "" && rule.Role != entry.Message.Role) {
+			continue
+		}
+		if rule.Action == ActionDelete {
+			return entry, false
+		}
+
+		previous := entry.Scrubbed[rule.Field]
+		if previous == rule.Action || previous == ActionRedact {
+			continue
+		}
+		if !cloned {
+			entry.Scrubbed = maps.Clone(entry.Scrubbed)
+			if entry.Scrubbed == nil {
+				entry.Scrubbed = make(map[MessageField]RetentionAction)
+			}
+			cloned = true
+		}
+		entry.Message = r.scrub(entry.Message, rule.Field, rule.Action)
+		entry.Scrubbed[rule.Field] = rule.Action
+	}
+
+	return entry, true
+}
+
+// applyAll applies the rules that are due at now to every entry, and reports
+// which entries are kept. Tool calls and their results are kept or deleted
+// together, since providers reject either without the other: deleting a tool
+// result removes the call it answers from its assistant message, and
+// deleting an assistant message deletes the results of its calls. unpaired
+// reports whether a kept message lost tool calls.
+func (r *RetentionStore) applyAll(entries []retained, now time.Time) ([]retained, []bool, bool) {
+	applied := make([]retained, len(entries))
+	keep := make([]bool, len(entries))
+	for i, entry := range entries {
+		applied[i], keep[i] = r.apply(entry, now)
+	}
+
+	calls := make(map[string]bool)   // Call IDs of kept assistant messages.
+	results := make(map[string]bool) // Call IDs answered by kept tool results.
+	for i, entry := range applied {
+		if !keep[i] {
+			continue
+		}
+		for _, call := range entry.Message.ToolCalls {
+			calls[call.ID] = true
+		}
+		if entry.Message.Role == providers.RoleTool {
+			results[entry.Message.ToolCallID] = true
+		}
+	}
+
+	// Results whose call was deleted.
+	for i, entry := range applied {
+		if keep[i] && entry.Message.Role == providers.RoleTool && entry.Message.ToolCallID != "" &&
+			!calls[entry.Message.ToolCallID] {
+			keep[i] = false
+		}
+	}
+
+	// Calls whose result was deleted. The calls of the last assistant
+	// message may still be waiting for their results.
+	unpaired := false
+	pending := pendingCalls(applied, keep)
+	for i, entry := range applied {
+		msg := entry.Message
+		if !keep[i] || len(msg.ToolCalls) == 0 || i == pending {
+			continue
+		}
+
+		answered := slices.DeleteFunc(slices.Clone(msg.ToolCalls), func(call providers.ToolCall) bool {
+			return call.ID != "" && !results[call.ID]
+		})
+		if len(answered) == len(msg.ToolCalls) {
+			continue
+		}
+
+		unpaired = true
+		if len(answered) == 0 {
+			answered = nil
+			if msg.ContentString() == "" && msg.ContentParts() == nil && msg.Reasoning == nil {
+				keep[i] = false
+				continue
+			}
+		}
+		applied[i].Message.ToolCalls = answered
+	}
+
+	return applied, keep, unpaired
+}
+
+// expired reports whether the session with entries has outlived the TTL.
+func (r *RetentionStore) expired(entries []retained, now time.Time) bool {
+	if r.policy.TTL == 0 || len(entries) == 0 {
+		return false
+	}
+
+	last := slices.MaxFunc(entries, func(a, b retained) int { return cmp.Compare(a.StoredAt, b.StoredAt) })
+	return now.Sub(time.Unix(last.StoredAt, 0)) >= r.policy.TTL
+}
+
+// load returns the session's stored entries. Messages without an envelope are
+// stamped with now; stamped reports whether there were any.
+func (r *RetentionStore) load(ctx context.Context, sessionID string, now time.Time) ([]retained, bool, error) {
+	stored, err := r.store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]retained, 0, len(stored))
+	stamped := false
+	for i, msg := range stored {
+		data, ok := strings.CutPrefix(msg.ContentString(), retainedPrefix)
+		if !ok {
+			entries = append(entries, retained{Message: msg, StoredAt: now.Unix()})
+			stamped = true
+			continue
+		}
+
+		var entry retained
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, false, fmt.Errorf("decoding message %d of session %s: %w", i, sessionID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, stamped, nil
+}
+
+// scrub returns msg with field hashed or redacted.
+func (r *RetentionStore) scrub(msg providers.Message, field MessageField, action RetentionAction) providers.Message {
+	switch field {
+	case FieldContent:
+		if text := msg.ContentString(); text != "" {
+			msg.Content = r.transform(text, action)
+		} else if parts := msg.ContentParts(); parts != nil {
+			var scrubbed []providers.ContentPart
+			for _, part := range parts {
+				if part.Type == partTypeText {
+					scrubbed = append(scrubbed, providers.ContentPart{Type: partTypeText, Text: r.transform(part.Text, action)})
+				}
+			}
+			msg.Content = scrubbed
+		}
+
+		if msg.ToolResult != nil {
+			result := providers.ToolResult{Content: msg.ToolResult.Content, IsError: msg.ToolResult.IsError}
+			if result.Content == "" && msg.ToolResult.Data != nil {
+				result.Content = msg.ToolResult.Text()
+			}
+			if result.Content != "" {
+				result.Content = r.transform(result.Content, action)
+			}
+			msg.ToolResult = &result
+		}
+
+	case FieldReasoning:
+		if msg.Reasoning != nil && msg.Reasoning.Content != "" {
+			msg.Reasoning = &providers.Reasoning{Content: r.transform(msg.Reasoning.Content, action)}
+		}
+
+	case FieldToolArguments:
+		calls := slices.Clone(msg.ToolCalls)
+		for i := range calls {
+			arguments, _ := json.Marshal(r.transform(calls[i].Function.Arguments, action)) // Strings always encode.
+			calls[i].Function.Arguments = string(arguments)
+		}
+		msg.ToolCalls = calls
+	}
+
+	return msg
+}
+
+// transform returns the hashed or redacted form of value.
+func (r *RetentionStore) transform(value string, action RetentionAction) string {
+	if action == ActionRedact {
+		return redacted
+	}
+
+	if len(r.policy.HashKey) > 0 {
+		mac := hmac.New(sha256.New, r.policy.HashKey)
+		_, _ = mac.Write([]byte(value)) // Writes to a hash never fail.
+		return hmacPrefix + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// pendingCalls returns the index of the kept assistant message whose tool
+// calls may still be waiting for results, because only tool results follow
+// it, or -1 if there is none.
+func pendingCalls(entries []retained, keep []bool) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !keep[i] {
+			continue
+		}
+		switch entries[i].Message.Role {
+		case providers.RoleTool:
+			continue
+		case providers.RoleAssistant:
+			if len(entries[i].Message.ToolCalls) > 0 {
+				return i
+			}
+		}
+		return -1
+	}
+	return -1
+}
+
+// wrap returns the message that stores entry.
+func wrap(entry retained) (providers.Message, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return providers.Message{}, fmt.Errorf("encoding message: %w", err)
+	}
+
+	return providers.Message{Content: retainedPrefix + string(data)}, nil
+}
@@ -0,0 +1,454 @@
+package chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// retentionStart is when test messages are stored.
+var retentionStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// day is a day, as retention policies are usually written.
+const day = 24 * time.Hour
+
+// appendOnlyStore is a store that cannot rewrite histories.
+type appendOnlyStore struct {
+	store Store
+}
+
+func (a appendOnlyStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	return a.store.Append(ctx, sessionID, messages...)
+}
+
+func (a appendOnlyStore) List(ctx context.Context) ([]string, error) {
+	return a.store.List(ctx)
+}
+
+func (a appendOnlyStore) Load(ctx context.Context, sessionID string) ([]providers.Message, error) {
+	return a.store.Load(ctx, sessionID)
+}
+
+// testClock is a settable clock for retention tests.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// newTestRetentionStore returns a retention store over a memory store, with a
+// clock starting at retentionStart.
+func newTestRetentionStore(t *testing.T, policy RetentionPolicy) (*RetentionStore, *MemoryStore, *testClock) {
+	t.Helper()
+
+	backend := NewMemoryStore()
+	store, err := NewRetentionStore(backend, policy)
+	require.NoError(t, err)
+
+	clock := &testClock{now: retentionStart}
+	store.now = clock.Now
+	return store, backend, clock
+}
+
+// toolTurn returns a user question, an assistant tool call and its result.
+func toolTurn() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleUser, Content: "what is the balance of alice@example.com?"},
+		{
+			Role: providers.RoleAssistant,
+			ToolCalls: []providers.ToolCall{{
+				Function: providers.FunctionCall{Arguments: `{"email":"alice@example.com"}`, Name: "balance"},
+				ID:       "call_1",
+				Type:     "function",
+			}},
+		},
+		providers.NewToolResultMessage("call_1", providers.ToolResult{Content: "1234.56"}),
+		{Role: providers.RoleAssistant, Content: "The balance is 1234.56."},
+	}
+}
+
+func TestNewRetentionStore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		store   Store
+		policy  RetentionPolicy
+		wantErr string
+	}{
+		{name: "requires a store", wantErr: "store must not be nil"},
+		{
+			name:    "rejects a negative TTL",
+			store:   NewMemoryStore(),
+			policy:  RetentionPolicy{TTL: -time.Hour},
+			wantErr: "TTL must not be negative, got -1h0m0s",
+		},
+		{
+			name:    "rejects an unknown action",
+			store:   NewMemoryStore(),
+			policy:  RetentionPolicy{Rules: []RetentionRule{{Action: "encrypt"}}},
+			wantErr: `rule 0: unknown action "encrypt"`,
+		},
+		{
+			name:    "rejects an unknown field",
+			store:   NewMemoryStore(),
+			policy:  RetentionPolicy{Rules: []RetentionRule{{Action: ActionHash}, {Action: ActionHash, Field: "name"}}},
+			wantErr: `rule 1: unknown field "name"`,
+		},
+		{
+			name:    "rejects a negative age",
+			store:   NewMemoryStore(),
+			policy:  RetentionPolicy{Rules: []RetentionRule{{Action: ActionDelete, After: -day}}},
+			wantErr: "rule 0: age must not be negative, got -24h0m0s",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRetentionStore(tc.store, tc.policy)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRetentionStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("round-trips messages without rules", func(t *testing.T) {
+		t.Parallel()
+
+		store, backend, _ := newTestRetentionStore(t, RetentionPolicy{})
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()...))
+
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Equal(t, toolTurn(), loaded)
+
+		raw, err := backend.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(raw[0].ContentString(), retainedPrefix))
+	})
+
+	t.Run("scrubs before storing", func(t *testing.T) {
+		t.Parallel()
+
+		store, backend, _ := newTestRetentionStore(t, RetentionPolicy{
+			Rules: []RetentionRule{
+				{Action: ActionHash, Role: providers.RoleUser},
+				{Action: ActionRedact, Field: FieldToolArguments},
+				{Action: ActionDelete, Role: providers.RoleSystem},
+			},
+		})
+		messages := append([]providers.Message{{Role: providers.RoleSystem, Content: "be brief"}}, toolTurn()...)
+		require.NoError(t, store.Append(ctx, "s1", messages...))
+
+		raw, err := backend.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, raw, 4)
+		for _, msg := range raw {
+			require.NotContains(t, msg.ContentString(), "alice@example.com")
+		}
+
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		sum := sha256.Sum256([]byte("what is the balance of alice@example.com?"))
+		require.Equal(t, hashPrefix+hex.EncodeToString(sum[:]), loaded[0].ContentString())
+		require.Equal(t, `"[redacted]"`, loaded[1].ToolCalls[0].Function.Arguments)
+		require.Equal(t, "1234.56", loaded[2].ContentString())
+	})
+
+	t.Run("hashes equal values equally", func(t *testing.T) {
+		t.Parallel()
+
+		policy := RetentionPolicy{Rules: []RetentionRule{{Action: ActionHash}}}
+		plain, _, _ := newTestRetentionStore(t, policy)
+		policy.HashKey = []byte("secret")
+		keyed, _, _ := newTestRetentionStore(t, policy)
+
+		msg := providers.Message{Role: providers.RoleUser, Content: "alice@example.com"}
+		for _, store := range []*RetentionStore{plain, keyed} {
+			require.NoError(t, store.Append(ctx, "a", msg))
+			require.NoError(t, store.Append(ctx, "b", msg))
+		}
+
+		a, err := plain.Load(ctx, "a")
+		require.NoError(t, err)
+		b, err := plain.Load(ctx, "b")
+		require.NoError(t, err)
+		require.Equal(t, a, b)
+		require.True(t, strings.HasPrefix(a[0].ContentString(), hashPrefix))
+
+		k, err := keyed.Load(ctx, "a")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(k[0].ContentString(), hmacPrefix))
+		require.NotEqual(t, a[0].ContentString()[len(hashPrefix):], k[0].ContentString()[len(hmacPrefix):])
+	})
+
+	t.Run("applies rules as messages age", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, clock := newTestRetentionStore(t, RetentionPolicy{
+			Rules: []RetentionRule{
+				{Action: ActionRedact, After: 7 * day, Role: providers.RoleTool},
+				{Action: ActionDelete, After: 30 * day, Role: providers.RoleUser},
+			},
+		})
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()...))
+
+		clock.now = retentionStart.Add(7 * day)
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, loaded, 4)
+		require.Equal(t, redacted, loaded[2].ContentString())
+		require.Equal(t, redacted, loaded[2].ToolResult.Content)
+
+		clock.now = retentionStart.Add(30 * day)
+		loaded, err = store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, loaded, 3)
+		require.Equal(t, providers.RoleAssistant, loaded[0].Role)
+	})
+
+	t.Run("expires sessions after the TTL", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, clock := newTestRetentionStore(t, RetentionPolicy{TTL: 90 * day})
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()[0]))
+
+		clock.now = retentionStart.Add(60 * day)
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()[3]))
+
+		clock.now = retentionStart.Add(120 * day)
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+
+		clock.now = retentionStart.Add(150 * day)
+		loaded, err = store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Nil(t, loaded)
+	})
+
+	t.Run("scrubs multimodal content and reasoning", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, _ := newTestRetentionStore(t, RetentionPolicy{
+			Rules: []RetentionRule{
+				{Action: ActionRedact},
+				{Action: ActionRedact, Field: FieldReasoning},
+			},
+		})
+		require.NoError(t, store.Append(ctx, "s1",
+			providers.Message{
+				Content: []providers.ContentPart{
+					{Text: "who is this?", Type: "text"},
+					{ImageURL: &providers.ImageURL{URL: "data:image/png;base64,AAAA"}, Type: "image_url"},
+				},
+				Role: providers.RoleUser,
+			},
+			providers.Message{
+				Content:   "A cat.",
+				Reasoning: &providers.Reasoning{Content: "The photo shows a cat."},
+				Role:      providers.RoleAssistant,
+			},
+		))
+
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Equal(t, []providers.ContentPart{{Text: redacted, Type: "text"}}, loaded[0].ContentParts())
+		require.Equal(t, redacted, loaded[1].ContentString())
+		require.Equal(t, redacted, loaded[1].Reasoning.Content)
+	})
+}
+
+func TestRetentionStoreEnforce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("rewrites the underlying store", func(t *testing.T) {
+		t.Parallel()
+
+		store, backend, clock := newTestRetentionStore(t, RetentionPolicy{
+			Rules: []RetentionRule{
+				{Action: ActionHash, After: 7 * day, Role: providers.RoleUser},
+				{Action: ActionDelete, After: 7 * day, Role: providers.RoleTool},
+			},
+			TTL: 90 * day,
+		})
+		require.NoError(t, store.Append(ctx, "active", toolTurn()...))
+		require.NoError(t, store.Append(ctx, "stale", toolTurn()[0]))
+
+		clock.now = retentionStart.Add(60 * day)
+		require.NoError(t, store.Append(ctx, "active", toolTurn()[3]))
+
+		clock.now = retentionStart.Add(100 * day)
+		report, err := store.Enforce(ctx)
+		require.NoError(t, err)
+		// The tool result and the tool call it answers are deleted together.
+		require.Equal(t, &RetentionReport{Deleted: 3, Expired: 1, Scrubbed: 1, Sessions: 2}, report)
+
+		ids, err := backend.List(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"active"}, ids)
+
+		raw, err := backend.Load(ctx, "active")
+		require.NoError(t, err)
+		require.Len(t, raw, 3)
+		for _, msg := range raw {
+			require.NotContains(t, msg.ContentString(), "what is the balance")
+		}
+
+		// A second run finds nothing to do, and hashes are not hashed again.
+		loaded, err := store.Load(ctx, "active")
+		require.NoError(t, err)
+		testutil.RequireToolPairing(t, testutil.MessageEvents(loaded))
+
+		report, err = store.Enforce(ctx)
+		require.NoError(t, err)
+		require.Equal(t, &RetentionReport{Sessions: 1}, report)
+
+		reloaded, err := store.Load(ctx, "active")
+		require.NoError(t, err)
+		require.Equal(t, loaded, reloaded)
+	})
+
+	t.Run("stamps messages stored without an envelope", func(t *testing.T) {
+		t.Parallel()
+
+		store, backend, clock := newTestRetentionStore(t, RetentionPolicy{TTL: day})
+		require.NoError(t, backend.Append(ctx, "legacy", toolTurn()[0]))
+
+		report, err := store.Enforce(ctx)
+		require.NoError(t, err)
+		require.Equal(t, &RetentionReport{Sessions: 1}, report)
+
+		raw, err := backend.Load(ctx, "legacy")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(raw[0].ContentString(), retainedPrefix))
+
+		clock.now = retentionStart.Add(day)
+		report, err = store.Enforce(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, report.Expired)
+	})
+
+	t.Run("rewrites through an encrypted store", func(t *testing.T) {
+		t.Parallel()
+
+		backend := NewMemoryStore()
+		encrypted, err := NewEncryptedStore(backend, testKeys(t, "k1"))
+		require.NoError(t, err)
+		store, err := NewRetentionStore(encrypted, RetentionPolicy{
+			Rules: []RetentionRule{{Action: ActionDelete, After: day, Role: providers.RoleUser}},
+		})
+		require.NoError(t, err)
+		store.now = func() time.Time { return retentionStart }
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()...))
+
+		store.now = func() time.Time { return retentionStart.Add(day) }
+		report, err := store.Enforce(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, report.Deleted)
+
+		raw, err := backend.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, raw, 3)
+		require.True(t, strings.HasPrefix(raw[0].ContentString(), encryptedPrefix))
+	})
+
+	t.Run("keeps tool calls paired with their results", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name  string
+			rules []RetentionRule
+			want  []string
+		}{
+			{
+				name:  "deleting tool results deletes their calls",
+				rules: []RetentionRule{{Action: ActionDelete, After: 7 * day, Role: providers.RoleTool}},
+				want:  []string{providers.RoleUser, providers.RoleAssistant, providers.RoleUser},
+			},
+			{
+				name:  "deleting assistant messages deletes their results",
+				rules: []RetentionRule{{Action: ActionDelete, After: 7 * day, Role: providers.RoleAssistant}},
+				want:  []string{providers.RoleUser, providers.RoleUser},
+			},
+			{
+				name:  "deleting tool results before storing deletes their calls",
+				rules: []RetentionRule{{Action: ActionDelete, Role: providers.RoleTool}},
+				want:  []string{providers.RoleUser, providers.RoleAssistant, providers.RoleUser},
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				store, backend, clock := newTestRetentionStore(t, RetentionPolicy{Rules: tc.rules})
+				// Appended one by one, as an agent loop stores them.
+				for _, msg := range toolTurn() {
+					require.NoError(t, store.Append(ctx, "s1", msg))
+				}
+				require.NoError(t, store.Append(ctx, "s1", providers.Message{Role: providers.RoleUser, Content: "thanks"}))
+
+				clock.now = retentionStart.Add(7 * day)
+				_, err := store.Enforce(ctx)
+				require.NoError(t, err)
+
+				loaded, err := store.Load(ctx, "s1")
+				require.NoError(t, err)
+				testutil.RequireToolPairing(t, testutil.MessageEvents(loaded))
+
+				roles := make([]string, len(loaded))
+				for i, msg := range loaded {
+					roles[i] = msg.Role
+				}
+				require.Equal(t, tc.want, roles)
+
+				raw, err := backend.Load(ctx, "s1")
+				require.NoError(t, err)
+				require.Len(t, raw, len(tc.want))
+			})
+		}
+	})
+
+	t.Run("leaves calls waiting for their results", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, _ := newTestRetentionStore(t, RetentionPolicy{
+			Rules: []RetentionRule{{Action: ActionDelete, After: day, Role: providers.RoleTool}},
+		})
+		require.NoError(t, store.Append(ctx, "s1", toolTurn()[:2]...))
+
+		loaded, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		require.Len(t, loaded[1].ToolCalls, 1)
+	})
+
+	t.Run("requires a rewriter", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := NewRetentionStore(appendOnlyStore{NewMemoryStore()}, RetentionPolicy{})
+		require.NoError(t, err)
+
+		_, err = store.Enforce(ctx)
+		require.EqualError(t, err, "chat.appendOnlyStore cannot rewrite histories; it must implement Rewriter")
+	})
+}
@@ -0,0 +1,288 @@
+// Package chat manages multi-turn conversations on top of a provider.
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// idPrefixSession prefixes generated session IDs.
+const idPrefixSession = "sess_"
+
+// Option configures a Session.
+type Option func(*Session) error
+
+// Session is a conversation with a provider. It keeps the message history and
+// sends it with every turn, and sums the usage of the turns. A Session is safe
+// for concurrent use; turns are serialized.
+type Session struct {
+	budget   Budget
+	id       string
+	messages []providers.Message
+	mu       sync.Mutex
+	params   providers.CompletionParams
+	parentID string
+	pricing  *pricing
+	provider providers.Provider
+	store    Store
+	stored   int
+	usage    Usage
+}
+
+// NewSession starts a conversation with provider.
+// The params are used as the template for every request; params.Messages seeds
+// the history, for example with a system prompt.
+func NewSession(provider providers.Provider, params providers.CompletionParams, opts ...Option) (*Session, error) {
+	id, err := generateID(idPrefixSession)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := params.Messages
+	params.Messages = nil
+
+	s := &Session{
+		id:       id,
+		messages: messages[:len(messages):len(messages)],
+		params:   params,
+		provider: provider,
+	}
+
+	if err := s.apply(opts); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Resume continues a session previously saved to store.
+// The params are used as the template for every request; params.Messages is ignored.
+// Usage is not saved with the history, so the resumed session's usage starts
+// at zero unless set with WithUsage.
+func Resume(
+	ctx context.Context,
+	provider providers.Provider,
+	params providers.CompletionParams,
+	store Store,
+	id string,
+	opts ...Option,
+) (*Session, error) {
+	messages, err := store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, errors.NewInvalidRequestError(provider.Name(), fmt.Errorf("session %s not found", id))
+	}
+
+	params.Messages = nil
+
+	s := &Session{
+		id:       id,
+		messages: messages[:len(messages):len(messages)],
+		params:   params,
+		provider: provider,
+		store:    store,
+		stored:   len(messages),
+	}
+
+	if err := s.apply(opts); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// WithStore saves the session's history to store after every turn.
+func WithStore(store Store) Option {
+	return func(s *Session) error {
+		if store == nil {
+			return fmt.Errorf("store must not be nil")
+		}
+
+		s.store = store
+		return nil
+	}
+}
+
+// Append adds messages to the history without calling the provider.
+// They are saved to the store with the next turn or call to Save.
+func (s *Session) Append(messages ...providers.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, messages...)
+}
+
+// Complete requests a reply to the current history and appends it.
+// It is typically used on a fork to regenerate a response. If saving to the
+// store fails, the reply is kept in memory and saved by the next successful save.
+func (s *Session) Complete(ctx context.Context) (*providers.ChatCompletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, err := s.complete(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(ctx); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Fork returns an independent session whose history is the first n messages of s.
+// Forking is cheap: the branches share the common history, and appending to
+// either branch never affects the other. A fork uses the same store as s and
+// saves its full history under its own ID. It has the same pricing and budget
+// as s, and starts with the usage of s, which it then counts separately.
+func (s *Session) Fork(n int) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 0 || n > len(s.messages) {
+		return nil, errors.NewInvalidRequestError(
+			s.provider.Name(),
+			fmt.Errorf("fork index %d out of range [0, %d]", n, len(s.messages)),
+		)
+	}
+
+	id, err := generateID(idPrefixSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		budget: s.budget,
+		id:     id,
+		// Capping the capacity makes the next append copy, so branches never overwrite each other.
+		messages: s.messages[:n:n],
+		params:   s.params,
+		parentID: s.id,
+		pricing:  s.pricing,
+		provider: s.provider,
+		store:    s.store,
+		usage:    s.usage,
+	}, nil
+}
+
+// ID returns the session's unique identifier.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Messages returns a copy of the history.
+func (s *Session) Messages() []providers.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]providers.Message(nil), s.messages...)
+}
+
+// ParentID returns the ID of the session this one was forked from, or "" for a root session.
+func (s *Session) ParentID() string {
+	return s.parentID
+}
+
+// Save writes messages not yet saved to the store. It is a no-op without a store.
+func (s *Session) Save(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(ctx)
+}
+
+// Send appends msg to the history, requests a reply and appends it.
+// If the request fails, msg is removed again so the turn can be retried. If
+// saving to the store fails, both messages are kept in memory and saved by the
+// next successful save.
+func (s *Session) Send(ctx context.Context, msg providers.Message) (*providers.ChatCompletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, msg)
+
+	resp, err := s.complete(ctx)
+	if err != nil {
+		s.messages = s.messages[:len(s.messages)-1]
+		return nil, err
+	}
+
+	if err := s.save(ctx); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// apply applies opts to s.
+func (s *Session) apply(opts []Option) error {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+
+	if s.budget.MaxCost > 0 && s.pricing == nil {
+		return fmt.Errorf("a budget cost limit requires pricing")
+	}
+	return nil
+}
+
+// complete requests a reply to the history, appends it and records its
+// usage. The caller must hold s.mu.
+func (s *Session) complete(ctx context.Context) (*providers.ChatCompletion, error) {
+	if err := s.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	params := s.params
+	params.Messages = s.messages
+
+	resp, err := s.provider.Completion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	s.record(resp.Usage)
+
+	if len(resp.Choices) == 0 {
+		return nil, errors.NewProviderError(s.provider.Name(), fmt.Errorf("completion returned no choices"))
+	}
+
+	s.messages = append(s.messages, resp.Choices[0].Message)
+	return resp, nil
+}
+
+// save writes unsaved messages to the store. The caller must hold s.mu.
+func (s *Session) save(ctx context.Context) error {
+	if s.store == nil || s.stored == len(s.messages) {
+		return nil
+	}
+
+	if err := s.store.Append(ctx, s.id, s.messages[s.stored:]...); err != nil {
+		return fmt.Errorf("saving session %s: %w", s.id, err)
+	}
+
+	s.stored = len(s.messages)
+	return nil
+}
+
+// generateID generates a random ID with the given prefix.
+func generateID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating ID: %w", err)
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+const testModel = "test-model"
+
+// echoProvider returns a mock provider that replies with the number of messages it received.
+func echoProvider() *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return testutil.MockChatCompletion(fmt.Sprintf("reply to %d", len(params.Messages))), nil
+	}
+	return mock
+}
+
+func newTestSession(t *testing.T, provider providers.Provider) *Session {
+	t.Helper()
+
+	session, err := NewSession(provider, providers.CompletionParams{
+		Model:    testModel,
+		Messages: []providers.Message{{Role: providers.RoleSystem, Content: "be brief"}},
+	})
+	require.NoError(t, err)
+	return session
+}
+
+func TestNewSession(t *testing.T) {
+	t.Parallel()
+
+	session := newTestSession(t, echoProvider())
+
+	require.True(t, strings.HasPrefix(session.ID(), idPrefixSession))
+	require.Empty(t, session.ParentID())
+	require.Len(t, session.Messages(), 1)
+}
+
+func TestSessionSend(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sends history and appends reply", func(t *testing.T) {
+		t.Parallel()
+
+		mock := echoProvider()
+		session := newTestSession(t, mock)
+
+		resp, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+		require.Equal(t, "reply to 2", resp.Choices[0].Message.Content)
+
+		require.Equal(t, testModel, mock.CompletionCalls[0].Model)
+		require.Len(t, session.Messages(), 3)
+	})
+
+	t.Run("removes message when request fails", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+		session := newTestSession(t, mock)
+
+		_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		require.Len(t, session.Messages(), 1)
+	})
+}
+
+func TestSessionFork(t *testing.T) {
+	t.Parallel()
+
+	t.Run("branches are independent", func(t *testing.T) {
+		t.Parallel()
+
+		session := newTestSession(t, echoProvider())
+		_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+
+		// Fork before the assistant reply and regenerate it.
+		branch, err := session.Fork(2)
+		require.NoError(t, err)
+		require.Equal(t, session.ID(), branch.ParentID())
+		require.NotEqual(t, session.ID(), branch.ID())
+
+		branch.Append(providers.Message{Role: providers.RoleAssistant, Content: "alternative"})
+		session.Append(providers.Message{Role: providers.RoleUser, Content: "Tell me more"})
+
+		require.Equal(t, "alternative", branch.Messages()[2].Content)
+		require.Equal(t, "reply to 2", session.Messages()[2].Content)
+		require.Len(t, branch.Messages(), 3)
+		require.Len(t, session.Messages(), 4)
+	})
+
+	t.Run("appending to a shorter fork does not overwrite the parent", func(t *testing.T) {
+		t.Parallel()
+
+		session := newTestSession(t, echoProvider())
+		session.Append(
+			providers.Message{Role: providers.RoleUser, Content: "first"},
+			providers.Message{Role: providers.RoleAssistant, Content: "second"},
+		)
+
+		branch, err := session.Fork(1)
+		require.NoError(t, err)
+		branch.Append(providers.Message{Role: providers.RoleUser, Content: "other"})
+
+		require.Equal(t, "first", session.Messages()[1].Content)
+		require.Equal(t, "other", branch.Messages()[1].Content)
+	})
+
+	t.Run("complete regenerates a reply on a fork", func(t *testing.T) {
+		t.Parallel()
+
+		session := newTestSession(t, echoProvider())
+		_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+
+		branch, err := session.Fork(len(session.Messages()) - 1)
+		require.NoError(t, err)
+
+		resp, err := branch.Complete(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "reply to 2", resp.Choices[0].Message.Content)
+		require.Len(t, branch.Messages(), 3)
+	})
+
+	t.Run("rejects out of range index", func(t *testing.T) {
+		t.Parallel()
+
+		session := newTestSession(t, echoProvider())
+
+		_, err := session.Fork(5)
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+
+		_, err = session.Fork(-1)
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
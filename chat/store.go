@@ -0,0 +1,242 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// sqlTable is the table SQLStore keeps messages in.
+const sqlTable = "chat_messages"
+
+// Ensure stores implement the Store and Rewriter interfaces.
+var (
+	_ Rewriter = (*MemoryStore)(nil)
+	_ Rewriter = (*SQLStore)(nil)
+	_ Store    = (*MemoryStore)(nil)
+	_ Store    = (*SQLStore)(nil)
+)
+
+// Store persists session histories so sessions survive restarts and can be
+// shared between processes. Implementations must be safe for concurrent use.
+type Store interface {
+	// Append adds messages to the end of the session's history.
+	Append(ctx context.Context, sessionID string, messages ...providers.Message) error
+
+	// List returns the IDs of all stored sessions.
+	List(ctx context.Context) ([]string, error)
+
+	// Load returns the session's history, or nil if nothing is stored for it.
+	Load(ctx context.Context, sessionID string) ([]providers.Message, error)
+}
+
+// Rewriter is a Store that can replace a session's history, which
+// RetentionStore.Enforce needs to delete and scrub stored messages.
+type Rewriter interface {
+	Store
+
+	// Replace replaces the session's history with messages. Without
+	// messages, it deletes the session.
+	Replace(ctx context.Context, sessionID string, messages ...providers.Message) error
+}
+
+// MemoryStore is a Store that keeps histories in memory.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string][]providers.Message
+}
+
+// SQLStore is a Store backed by a database/sql database. Messages are stored as
+// JSON, one row per message. Only SQLite drivers are supported: queries use "?"
+// placeholders, which Postgres drivers reject, and the session ID is a TEXT
+// primary key, which MySQL rejects.
+//
+// Append reads the length of the history before inserting after it, so each
+// session must have a single writer at a time. Concurrent appends to one
+// session may fail on the primary key or with a busy database; appends to
+// different sessions do not conflict.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string][]providers.Message)}
+}
+
+// NewSQLStore returns a store using db, creating its table if it does not exist.
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+sqlTable+` (
+		session_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		message TEXT NOT NULL,
+		PRIMARY KEY (session_id, seq)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s table: %w", sqlTable, err)
+	}
+
+	return &SQLStore{db: db}, nil
+}
+
+// Append adds messages to the end of the session's history.
+func (m *MemoryStore) Append(_ context.Context, sessionID string, messages ...providers.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[sessionID] = append(m.sessions[sessionID], messages...)
+	return nil
+}
+
+// List returns the IDs of all stored sessions in lexical order.
+func (m *MemoryStore) List(_ context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(m.sessions)), nil
+}
+
+// Load returns a copy of the session's history.
+func (m *MemoryStore) Load(_ context.Context, sessionID string) ([]providers.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.sessions[sessionID]), nil
+}
+
+// Replace replaces the session's history with a copy of messages.
+func (m *MemoryStore) Replace(_ context.Context, sessionID string, messages ...providers.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(messages) == 0 {
+		delete(m.sessions, sessionID)
+		return nil
+	}
+	m.sessions[sessionID] = slices.Clone(messages)
+	return nil
+}
+
+// Append adds messages to the end of the session's history in a single
+// transaction. It does not support concurrent appends to the same session.
+func (s *SQLStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // No-op after a successful commit.
+
+	var next int
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(MAX(seq), -1) + 1 FROM `+sqlTable+` WHERE session_id = ?`,
+		sessionID,
+	).Scan(&next)
+	if err != nil {
+		return fmt.Errorf("reading history length: %w", err)
+	}
+
+	if err := insertMessages(ctx, tx, sessionID, next, messages); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// List returns the IDs of all stored sessions in lexical order.
+func (s *SQLStore) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT session_id FROM `+sqlTable+` ORDER BY session_id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }() // Iteration errors are reported by rows.Err.
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("reading session ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Load returns the session's history.
+func (s *SQLStore) Load(ctx context.Context, sessionID string) ([]providers.Message, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT message FROM `+sqlTable+` WHERE session_id = ? ORDER BY seq`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("loading session %s: %w", sessionID, err)
+	}
+	defer func() { _ = rows.Close() }() // Iteration errors are reported by rows.Err.
+
+	var messages []providers.Message
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("reading message: %w", err)
+		}
+
+		var msg providers.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("decoding message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// Replace replaces the session's history with messages in a single transaction.
+func (s *SQLStore) Replace(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // No-op after a successful commit.
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+sqlTable+` WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("deleting session %s: %w", sessionID, err)
+	}
+	if err := insertMessages(ctx, tx, sessionID, 0, messages); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertMessages inserts messages into the session's history, numbering them from first.
+func insertMessages(ctx context.Context, tx *sql.Tx, sessionID string, first int, messages []providers.Message) error {
+	for i, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("encoding message: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO `+sqlTable+` (session_id, seq, message) VALUES (?, ?, ?)`,
+			sessionID, first+i, string(data),
+		)
+		if err != nil {
+			return fmt.Errorf("inserting message: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// fakeSQL is an in-memory database for the queries SQLStore runs, reached
+// through a database/sql driver. Transactions work on a copy of the data that
+// replaces it on commit.
+type fakeSQL struct {
+	data   map[string]map[int64]string
+	mu     sync.Mutex
+	poison string
+}
+
+// fakeSQLConn is a connection to a fakeSQL, with the data of its open
+// transaction, if any.
+type fakeSQLConn struct {
+	db *fakeSQL
+	tx map[string]map[int64]string
+}
+
+// fakeSQLConnector opens connections to a fakeSQL.
+type fakeSQLConnector struct {
+	db *fakeSQL
+}
+
+// fakeSQLDriver is the driver of fakeSQLConnector.
+type fakeSQLDriver struct{}
+
+// fakeSQLRows are the rows of a query on a fakeSQL.
+type fakeSQLRows struct {
+	column string
+	values []driver.Value
+}
+
+// fakeSQLStmt is a query prepared on a fakeSQLConn.
+type fakeSQLStmt struct {
+	conn  *fakeSQLConn
+	query string
+}
+
+// failingStore is a store whose writes fail until fail is cleared.
+type failingStore struct {
+	*MemoryStore
+	fail bool
+}
+
+// newFakeSQL returns a database/sql handle on an empty fakeSQL. Inserting a
+// message that contains poison fails.
+func newFakeSQL(t *testing.T, poison string) *sql.DB {
+	t.Helper()
+
+	db := sql.OpenDB(fakeSQLConnector{db: &fakeSQL{data: map[string]map[int64]string{}, poison: poison}})
+	t.Cleanup(func() { _ = db.Close() }) // Closing the fake cannot fail.
+	return db
+}
+
+func (c fakeSQLConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeSQLConn{db: c.db}, nil
+}
+
+func (c fakeSQLConnector) Driver() driver.Driver { return fakeSQLDriver{} }
+
+func (fakeSQLDriver) Open(string) (driver.Conn, error) {
+	return nil, stderrors.New("open fake databases with their connector")
+}
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.tx = make(map[string]map[int64]string, len(c.db.data))
+	for id, rows := range c.db.data {
+		c.tx[id] = maps.Clone(rows)
+	}
+	return c, nil
+}
+
+func (c *fakeSQLConn) Close() error { return nil }
+
+func (c *fakeSQLConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.data, c.tx = c.tx, nil
+	return nil
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{conn: c, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeSQLConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+func (r *fakeSQLRows) Close() error { return nil }
+
+func (r *fakeSQLRows) Columns() []string { return []string{r.column} }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func (s *fakeSQLStmt) Close() error { return nil }
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+
+	data := s.data()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS "+sqlTable):
+	case strings.HasPrefix(s.query, "DELETE FROM "+sqlTable+" WHERE session_id = ?"):
+		delete(data, args[0].(string))
+	case strings.HasPrefix(s.query, "INSERT INTO "+sqlTable+" (session_id, seq, message)"):
+		id, seq, msg := args[0].(string), args[1].(int64), args[2].(string)
+		if s.conn.db.poison != "" && strings.Contains(msg, s.conn.db.poison) {
+			return nil, stderrors.New("constraint failed")
+		}
+		if _, ok := data[id][seq]; ok {
+			return nil, stderrors.New("duplicate primary key")
+		}
+		if data[id] == nil {
+			data[id] = map[int64]string{}
+		}
+		data[id][seq] = msg
+	default:
+		return nil, fmt.Errorf("unexpected statement %q", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSQLStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+
+	data := s.data()
+	switch s.query {
+	case "SELECT COALESCE(MAX(seq), -1) + 1 FROM " + sqlTable + " WHERE session_id = ?":
+		next := int64(0)
+		for seq := range data[args[0].(string)] {
+			next = max(next, seq+1)
+		}
+		return &fakeSQLRows{column: "next", values: []driver.Value{next}}, nil
+	case "SELECT DISTINCT session_id FROM " + sqlTable + " ORDER BY session_id":
+		rows := &fakeSQLRows{column: "session_id"}
+		for _, id := range slices.Sorted(maps.Keys(data)) {
+			rows.values = append(rows.values, id)
+		}
+		return rows, nil
+	case "SELECT message FROM " + sqlTable + " WHERE session_id = ? ORDER BY seq":
+		rows := &fakeSQLRows{column: "message"}
+		messages := data[args[0].(string)]
+		for _, seq := range slices.Sorted(maps.Keys(messages)) {
+			rows.values = append(rows.values, messages[seq])
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+}
+
+// data returns the rows the statement sees: those of its connection's
+// transaction, or the committed ones outside a transaction.
+func (s *fakeSQLStmt) data() map[string]map[int64]string {
+	if s.conn.tx != nil {
+		return s.conn.tx
+	}
+	return s.conn.db.data
+}
+
+func (f *failingStore) Append(ctx context.Context, sessionID string, messages ...providers.Message) error {
+	if f.fail {
+		return stderrors.New("store unavailable")
+	}
+	return f.MemoryStore.Append(ctx, sessionID, messages...)
+}
+
+// requireStore checks the behavior every Rewriter shares on an empty store.
+func requireStore(t *testing.T, store Rewriter) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	require.NoError(t, store.Append(ctx, "b", providers.Message{Role: providers.RoleUser, Content: "one"}))
+	require.NoError(t, store.Append(ctx, "b", providers.Message{Role: providers.RoleAssistant, Content: "two"}))
+	require.NoError(t, store.Append(ctx, "a", providers.Message{Role: providers.RoleUser, Content: "three"}))
+	require.NoError(t, store.Append(ctx, "a"))
+
+	ids, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, ids)
+
+	messages, err := store.Load(ctx, "b")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "one", messages[0].Content)
+	require.Equal(t, "two", messages[1].Content)
+
+	missing, err := store.Load(ctx, "missing")
+	require.NoError(t, err)
+	require.Empty(t, missing)
+
+	require.NoError(t, store.Replace(ctx, "b", providers.Message{Role: providers.RoleUser, Content: "four"}))
+	messages, err = store.Load(ctx, "b")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "four", messages[0].Content)
+
+	// Appends after a replace continue the new history.
+	require.NoError(t, store.Append(ctx, "b", providers.Message{Role: providers.RoleAssistant, Content: "five"}))
+	messages, err = store.Load(ctx, "b")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "five", messages[1].Content)
+
+	// Replacing with nothing deletes the session.
+	require.NoError(t, store.Replace(ctx, "a"))
+	ids, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, ids)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	requireStore(t, store)
+
+	// Loaded histories are copies.
+	messages, err := store.Load(ctx, "b")
+	require.NoError(t, err)
+	messages[0].Content = "changed"
+	reloaded, err := store.Load(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, "four", reloaded[0].Content)
+}
+
+func TestSQLStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stores histories", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := NewSQLStore(context.Background(), newFakeSQL(t, ""))
+		require.NoError(t, err)
+		requireStore(t, store)
+	})
+
+	t.Run("keeps histories whole when a write fails", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store, err := NewSQLStore(ctx, newFakeSQL(t, "poison"))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, "a", providers.Message{Role: providers.RoleUser, Content: "one"}))
+
+		err = store.Append(ctx, "a",
+			providers.Message{Role: providers.RoleAssistant, Content: "two"},
+			providers.Message{Role: providers.RoleUser, Content: "poison"},
+		)
+		require.ErrorContains(t, err, "inserting message")
+
+		err = store.Replace(ctx, "a", providers.Message{Role: providers.RoleUser, Content: "poison"})
+		require.ErrorContains(t, err, "inserting message")
+
+		messages, err := store.Load(ctx, "a")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "one", messages[0].Content)
+	})
+}
+
+func TestSessionStore(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    testModel,
+		Messages: []providers.Message{{Role: providers.RoleSystem, Content: "be brief"}},
+	}
+
+	t.Run("saves every turn and resumes", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := NewMemoryStore()
+		session, err := NewSession(echoProvider(), params, WithStore(store))
+		require.NoError(t, err)
+
+		_, err = session.Send(ctx, providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+
+		saved, err := store.Load(ctx, session.ID())
+		require.NoError(t, err)
+		require.Equal(t, session.Messages(), saved)
+
+		resumed, err := Resume(ctx, echoProvider(), providers.CompletionParams{Model: testModel}, store, session.ID())
+		require.NoError(t, err)
+		require.Equal(t, session.ID(), resumed.ID())
+		require.Len(t, resumed.Messages(), 3)
+
+		_, err = resumed.Send(ctx, providers.Message{Role: providers.RoleUser, Content: "More"})
+		require.NoError(t, err)
+
+		saved, err = store.Load(ctx, session.ID())
+		require.NoError(t, err)
+		require.Len(t, saved, 5)
+	})
+
+	t.Run("fork saves its full history under its own ID", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := NewMemoryStore()
+		session, err := NewSession(echoProvider(), params, WithStore(store))
+		require.NoError(t, err)
+		session.Append(providers.Message{Role: providers.RoleUser, Content: "Hi"})
+
+		branch, err := session.Fork(2)
+		require.NoError(t, err)
+		_, err = branch.Complete(ctx)
+		require.NoError(t, err)
+
+		saved, err := store.Load(ctx, branch.ID())
+		require.NoError(t, err)
+		require.Len(t, saved, 3)
+
+		ids, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{branch.ID()}, ids)
+	})
+
+	t.Run("keeps unsaved messages until the store recovers", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		store := &failingStore{MemoryStore: NewMemoryStore(), fail: true}
+		session, err := NewSession(echoProvider(), params, WithStore(store))
+		require.NoError(t, err)
+
+		_, err = session.Send(ctx, providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.Error(t, err)
+		require.Len(t, session.Messages(), 3)
+
+		store.fail = false
+		require.NoError(t, session.Save(ctx))
+
+		saved, err := store.Load(ctx, session.ID())
+		require.NoError(t, err)
+		require.Len(t, saved, 3)
+	})
+
+	t.Run("resume rejects unknown sessions", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Resume(context.Background(), echoProvider(), params, NewMemoryStore(), "sess_missing")
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("rejects nil store", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewSession(echoProvider(), params, WithStore(nil))
+		require.Error(t, err)
+	})
+}
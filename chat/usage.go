@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// tokensPerMillion converts per-million token prices to per-token prices.
+const tokensPerMillion = 1_000_000
+
+// Budget limits what a session may use. A session over its budget fails
+// further turns with errors.ErrQuotaExceeded. Limits are checked before each
+// request, so the turn that crosses a limit completes. Zero fields are not
+// limited.
+type Budget struct {
+	// MaxCost limits the estimated cost, in the currency of WithPricing.
+	MaxCost float64
+
+	// MaxRequests limits the number of completion requests.
+	MaxRequests int
+
+	// MaxTokens limits the total tokens.
+	MaxTokens int
+}
+
+// Usage is what a session has used, summed over its turns.
+type Usage struct {
+	providers.Usage
+
+	// Cost is the cost estimated from the prices set with WithPricing, or 0
+	// without them.
+	Cost float64
+
+	// Requests is the number of completion requests that succeeded.
+	Requests int
+}
+
+// pricing is the price of a million prompt and completion tokens.
+type pricing struct {
+	inputPerMillion  float64
+	outputPerMillion float64
+}
+
+// WithBudget limits what the session may use, for example to enforce a
+// per-user quota. A cost limit requires WithPricing.
+func WithBudget(budget Budget) Option {
+	return func(s *Session) error {
+		if budget.MaxCost < 0 || budget.MaxRequests < 0 || budget.MaxTokens < 0 {
+			return fmt.Errorf("budget limits must not be negative, got %+v", budget)
+		}
+
+		s.budget = budget
+		return nil
+	}
+}
+
+// WithPricing sets the price of a million prompt and completion tokens,
+// used to estimate the cost of the session's turns.
+func WithPricing(inputPerMillion, outputPerMillion float64) Option {
+	return func(s *Session) error {
+		if inputPerMillion < 0 || outputPerMillion < 0 {
+			return fmt.Errorf("prices must not be negative, got %v and %v", inputPerMillion, outputPerMillion)
+		}
+
+		s.pricing = &pricing{inputPerMillion: inputPerMillion, outputPerMillion: outputPerMillion}
+		return nil
+	}
+}
+
+// WithUsage starts the session's usage at usage instead of zero, for example
+// to carry a quota over when resuming a session.
+func WithUsage(usage Usage) Option {
+	return func(s *Session) error {
+		s.usage = usage
+		return nil
+	}
+}
+
+// Usage returns what the session has used so far.
+func (s *Session) Usage() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.usage
+}
+
+// checkBudget returns an error if the session has used up its budget. The
+// caller must hold s.mu.
+func (s *Session) checkBudget() error {
+	var exceeded string
+	switch b, u := s.budget, s.usage; {
+	case b.MaxCost > 0 && u.Cost >= b.MaxCost:
+		exceeded = fmt.Sprintf("cost %v of %v", u.Cost, b.MaxCost)
+	case b.MaxRequests > 0 && u.Requests >= b.MaxRequests:
+		exceeded = fmt.Sprintf("%d of %d requests", u.Requests, b.MaxRequests)
+	case b.MaxTokens > 0 && u.TotalTokens >= b.MaxTokens:
+		exceeded = fmt.Sprintf("%d of %d tokens", u.TotalTokens, b.MaxTokens)
+	default:
+		return nil
+	}
+
+	return errors.NewQuotaExceededError(
+		s.provider.Name(),
+		fmt.Errorf("session %s has used its budget: %s", s.id, exceeded),
+	)
+}
+
+// record adds the usage of a completion to the session's. The caller must
+// hold s.mu.
+func (s *Session) record(usage *providers.Usage) {
+	s.usage.Requests++
+	if usage == nil {
+		return
+	}
+
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += usage.TotalTokens
+	s.usage.ReasoningTokens += usage.ReasoningTokens
+	s.usage.CachedTokens += usage.CachedTokens
+
+	if s.pricing != nil {
+		s.usage.Cost += (float64(usage.PromptTokens)*s.pricing.inputPerMillion +
+			float64(usage.CompletionTokens)*s.pricing.outputPerMillion) / tokensPerMillion
+	}
+}
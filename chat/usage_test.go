@@ -0,0 +1,192 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// usageProvider returns a mock provider whose replies use prompt and
+// completion tokens.
+func usageProvider(prompt, completion int) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion("ok")
+		resp.Usage = &providers.Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		}
+		return resp, nil
+	}
+	return mock
+}
+
+func TestSessionOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name:    "rejects negative budgets",
+			opts:    []Option{WithBudget(Budget{MaxTokens: -1})},
+			wantErr: "budget limits must not be negative, got {MaxCost:0 MaxRequests:0 MaxTokens:-1}",
+		},
+		{
+			name:    "rejects negative prices",
+			opts:    []Option{WithPricing(-1, 2)},
+			wantErr: "prices must not be negative, got -1 and 2",
+		},
+		{
+			name:    "requires pricing for cost limits",
+			opts:    []Option{WithBudget(Budget{MaxCost: 5})},
+			wantErr: "a budget cost limit requires pricing",
+		},
+		{
+			name: "accepts a cost limit with pricing",
+			opts: []Option{WithBudget(Budget{MaxCost: 5}), WithPricing(1, 2)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewSession(testutil.NewMockProvider(), providers.CompletionParams{}, tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSessionUsage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sums usage and cost over turns", func(t *testing.T) {
+		t.Parallel()
+
+		session, err := NewSession(usageProvider(1_000, 500), providers.CompletionParams{}, WithPricing(2, 10))
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+			require.NoError(t, err)
+		}
+
+		usage := session.Usage()
+		require.Equal(t, 2, usage.Requests)
+		require.Equal(t, providers.Usage{PromptTokens: 2_000, CompletionTokens: 1_000, TotalTokens: 3_000}, usage.Usage)
+		require.InDelta(t, 0.014, usage.Cost, 1e-9)
+	})
+
+	t.Run("does not count failed turns", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", nil)
+		}
+		session := newTestSession(t, mock)
+
+		_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.Error(t, err)
+		require.Zero(t, session.Usage())
+	})
+
+	t.Run("forks start with the parent's usage", func(t *testing.T) {
+		t.Parallel()
+
+		session, err := NewSession(usageProvider(10, 5), providers.CompletionParams{})
+		require.NoError(t, err)
+		_, err = session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+
+		branch, err := session.Fork(1)
+		require.NoError(t, err)
+		_, err = branch.Complete(context.Background())
+		require.NoError(t, err)
+
+		require.Equal(t, 1, session.Usage().Requests)
+		require.Equal(t, 2, branch.Usage().Requests)
+		require.Equal(t, 30, branch.Usage().TotalTokens)
+	})
+
+	t.Run("resumes with a given usage", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewMemoryStore()
+		session, err := NewSession(usageProvider(10, 5), providers.CompletionParams{}, WithStore(store))
+		require.NoError(t, err)
+		_, err = session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+		require.NoError(t, err)
+
+		resumed, err := Resume(context.Background(), session.provider, providers.CompletionParams{}, store, session.ID(),
+			WithUsage(session.Usage()), WithBudget(Budget{MaxRequests: 1}))
+		require.NoError(t, err)
+
+		_, err = resumed.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Again"})
+		require.ErrorIs(t, err, errors.ErrQuotaExceeded)
+	})
+}
+
+func TestSessionBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		budget  Budget
+		turns   int
+		wantErr string
+	}{
+		{
+			name:    "limits tokens",
+			budget:  Budget{MaxTokens: 2_500},
+			turns:   2,
+			wantErr: "3000 of 2500 tokens",
+		},
+		{
+			name:    "limits requests",
+			budget:  Budget{MaxRequests: 1},
+			turns:   1,
+			wantErr: "1 of 1 requests",
+		},
+		{
+			name:    "limits cost",
+			budget:  Budget{MaxCost: 0.01},
+			turns:   2,
+			wantErr: "cost 0.014 of 0.01",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock := usageProvider(1_000, 500)
+			session, err := NewSession(mock, providers.CompletionParams{}, WithBudget(tc.budget), WithPricing(2, 10))
+			require.NoError(t, err)
+
+			for range tc.turns {
+				_, err := session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+				require.NoError(t, err)
+			}
+
+			_, err = session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+			require.ErrorIs(t, err, errors.ErrQuotaExceeded)
+			require.ErrorContains(t, err, tc.wantErr)
+			require.Len(t, mock.CompletionCalls, tc.turns)
+			require.Len(t, session.Messages(), 2*tc.turns)
+		})
+	}
+}
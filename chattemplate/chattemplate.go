@@ -0,0 +1,221 @@
+// Package chattemplate renders chat messages into a raw prompt string, the way
+// a model's chat template does on the server. It is used with raw text
+// completion when a server applies the wrong template for a model.
+//
+// Chat templates ship in GGUF metadata as Jinja source. Rather than evaluating
+// Jinja, the package provides the well-known prompt formats those templates
+// implement, and Detect recognizes which one a template's source uses. Custom
+// formats are defined by filling in a Template.
+package chattemplate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Content part types.
+const contentTypeText = "text"
+
+// systemSeparator joins a merged system prompt to the first user message.
+const systemSeparator = "\n\n"
+
+// Built-in templates. Their prompts leave out the beginning-of-sequence token,
+// since llama.cpp servers add it when tokenizing the prompt.
+var (
+	// ChatML is the format of Qwen, Yi, Hermes and many fine-tunes.
+	ChatML = Template{
+		Generation: "<|im_start|>assistant\n",
+		Name:       "chatml",
+		Turns: map[string]Turn{
+			providers.RoleAssistant: {Prefix: "<|im_start|>assistant\n", Suffix: "<|im_end|>\n"},
+			providers.RoleSystem:    {Prefix: "<|im_start|>system\n", Suffix: "<|im_end|>\n"},
+			providers.RoleTool:      {Prefix: "<|im_start|>tool\n", Suffix: "<|im_end|>\n"},
+			providers.RoleUser:      {Prefix: "<|im_start|>user\n", Suffix: "<|im_end|>\n"},
+		},
+		markers: []string{"<|im_start|>"},
+	}
+
+	// Gemma is the format of Google's Gemma models, which have no system role.
+	Gemma = Template{
+		Generation:  "<start_of_turn>model\n",
+		MergeSystem: true,
+		Name:        "gemma",
+		Turns: map[string]Turn{
+			providers.RoleAssistant: {Prefix: "<start_of_turn>model\n", Suffix: "<end_of_turn>\n"},
+			providers.RoleUser:      {Prefix: "<start_of_turn>user\n", Suffix: "<end_of_turn>\n"},
+		},
+		markers: []string{"<start_of_turn>"},
+	}
+
+	// Llama3 is the format of Meta's Llama 3 models.
+	Llama3 = Template{
+		Generation: "<|start_header_id|>assistant<|end_header_id|>\n\n",
+		Name:       "llama3",
+		Turns: map[string]Turn{
+			providers.RoleAssistant: {Prefix: "<|start_header_id|>assistant<|end_header_id|>\n\n", Suffix: "<|eot_id|>"},
+			providers.RoleSystem:    {Prefix: "<|start_header_id|>system<|end_header_id|>\n\n", Suffix: "<|eot_id|>"},
+			providers.RoleTool:      {Prefix: "<|start_header_id|>ipython<|end_header_id|>\n\n", Suffix: "<|eot_id|>"},
+			providers.RoleUser:      {Prefix: "<|start_header_id|>user<|end_header_id|>\n\n", Suffix: "<|eot_id|>"},
+		},
+		markers: []string{"<|start_header_id|>"},
+	}
+
+	// Mistral is the instruction format of Mistral and Llama 2 models, which
+	// have no system role.
+	Mistral = Template{
+		Generation:  "",
+		MergeSystem: true,
+		Name:        "mistral",
+		Turns: map[string]Turn{
+			providers.RoleAssistant: {Prefix: "", Suffix: "</s>"},
+			providers.RoleUser:      {Prefix: "[INST] ", Suffix: " [/INST]"},
+		},
+		markers: []string{"[INST]"},
+	}
+
+	// Phi3 is the format of Microsoft's Phi-3 models.
+	Phi3 = Template{
+		Generation: "<|assistant|>\n",
+		Name:       "phi3",
+		Turns: map[string]Turn{
+			providers.RoleAssistant: {Prefix: "<|assistant|>\n", Suffix: "<|end|>\n"},
+			providers.RoleSystem:    {Prefix: "<|system|>\n", Suffix: "<|end|>\n"},
+			providers.RoleUser:      {Prefix: "<|user|>\n", Suffix: "<|end|>\n"},
+		},
+		markers: []string{"<|user|>"},
+	}
+)
+
+// builtins lists the built-in templates in the order Detect tries them.
+var builtins = []Template{ChatML, Llama3, Gemma, Phi3, Mistral}
+
+// Template is a chat prompt format: the text each role's turn is wrapped in,
+// and the text that opens the assistant's reply.
+type Template struct {
+	// Generation opens the assistant turn the model completes.
+	Generation string
+
+	// MergeSystem prepends the system prompt to the first user message, for
+	// formats without a system role.
+	MergeSystem bool
+
+	// Name identifies the template.
+	Name string
+
+	// Turns maps each role the format supports to its turn markup. Messages
+	// with other roles are rejected.
+	Turns map[string]Turn
+
+	// markers are substrings of the Jinja source that identify the format.
+	markers []string
+}
+
+// Turn is the markup around one message's content.
+type Turn struct {
+	Prefix string
+	Suffix string
+}
+
+// Detect returns the built-in template whose format the Jinja chat template
+// source implements, such as the chat_template a llama.cpp server reports. It
+// reports false when the source matches none.
+func Detect(source string) (Template, bool) {
+	for _, tmpl := range builtins {
+		for _, marker := range tmpl.markers {
+			if strings.Contains(source, marker) {
+				return tmpl, true
+			}
+		}
+	}
+	return Template{}, false
+}
+
+// Lookup returns the built-in template with the given name.
+func Lookup(name string) (Template, bool) {
+	for _, tmpl := range builtins {
+		if tmpl.Name == name {
+			return tmpl, true
+		}
+	}
+	return Template{}, false
+}
+
+// Render renders messages as a prompt ending with an open assistant turn.
+// Only text content can be rendered; images, files and tool calls are
+// rejected, since their markup is specific to each model.
+func (t Template) Render(messages []providers.Message) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("at least one message is required")
+	}
+
+	turns, err := t.merge(messages)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, msg := range turns {
+		turn, ok := t.Turns[msg.Role]
+		if !ok {
+			return "", fmt.Errorf("chat template %q has no %q role", t.Name, msg.Role)
+		}
+		b.WriteString(turn.Prefix)
+		b.WriteString(msg.ContentString())
+		b.WriteString(turn.Suffix)
+	}
+	b.WriteString(t.Generation)
+
+	return b.String(), nil
+}
+
+// merge returns messages with their content reduced to text and, for
+// templates that merge the system prompt, the system messages folded into the
+// first user message.
+func (t Template) merge(messages []providers.Message) ([]providers.Message, error) {
+	var system []string
+	merged := make([]providers.Message, 0, len(messages))
+	for _, msg := range messages {
+		text, err := messageText(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.MergeSystem && msg.Role == providers.RoleSystem {
+			system = append(system, text)
+			continue
+		}
+		if len(system) > 0 && msg.Role == providers.RoleUser {
+			text = strings.Join(append(system, text), systemSeparator)
+			system = nil
+		}
+		merged = append(merged, providers.Message{Role: msg.Role, Content: text})
+	}
+
+	if len(system) > 0 {
+		return nil, fmt.Errorf("chat template %q needs a user message to carry the system prompt", t.Name)
+	}
+
+	return merged, nil
+}
+
+// messageText returns the text content of a message, joining the text parts
+// of multi-modal content.
+func messageText(msg providers.Message) (string, error) {
+	if len(msg.ToolCalls) > 0 {
+		return "", fmt.Errorf("tool calls cannot be rendered with a chat template")
+	}
+	if !msg.IsMultiModal() {
+		return msg.ContentString(), nil
+	}
+
+	texts := make([]string, 0, len(msg.ContentParts()))
+	for _, part := range msg.ContentParts() {
+		if part.Type != contentTypeText {
+			return "", fmt.Errorf("%s content cannot be rendered with a chat template", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
@@ -0,0 +1,179 @@
+package chattemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	messages := []providers.Message{
+		{Role: providers.RoleSystem, Content: "Be brief."},
+		{Role: providers.RoleUser, Content: "Hi"},
+		{Role: providers.RoleAssistant, Content: "Hello!"},
+		{Role: providers.RoleUser, Content: "Bye"},
+	}
+
+	tests := []struct {
+		name     string
+		template Template
+		expected string
+	}{
+		{
+			name:     "chatml",
+			template: ChatML,
+			expected: "<|im_start|>system\nBe brief.<|im_end|>\n" +
+				"<|im_start|>user\nHi<|im_end|>\n" +
+				"<|im_start|>assistant\nHello!<|im_end|>\n" +
+				"<|im_start|>user\nBye<|im_end|>\n" +
+				"<|im_start|>assistant\n",
+		},
+		{
+			name:     "llama3",
+			template: Llama3,
+			expected: "<|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\nHello!<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nBye<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+		{
+			name:     "gemma merges the system prompt",
+			template: Gemma,
+			expected: "<start_of_turn>user\nBe brief.\n\nHi<end_of_turn>\n" +
+				"<start_of_turn>model\nHello!<end_of_turn>\n" +
+				"<start_of_turn>user\nBye<end_of_turn>\n" +
+				"<start_of_turn>model\n",
+		},
+		{
+			name:     "mistral merges the system prompt",
+			template: Mistral,
+			expected: "[INST] Be brief.\n\nHi [/INST]Hello!</s>[INST] Bye [/INST]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			prompt, err := tc.template.Render(messages)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, prompt)
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template Template
+		messages []providers.Message
+		expected string
+	}{
+		{
+			name:     "no messages",
+			template: ChatML,
+			expected: "at least one message is required",
+		},
+		{
+			name:     "role without a turn",
+			template: Gemma,
+			messages: []providers.Message{{Role: providers.RoleTool, Content: "sunny"}},
+			expected: `chat template "gemma" has no "tool" role`,
+		},
+		{
+			name:     "system prompt without a user message",
+			template: Mistral,
+			messages: []providers.Message{{Role: providers.RoleSystem, Content: "Be brief."}},
+			expected: "needs a user message",
+		},
+		{
+			name:     "image content",
+			template: ChatML,
+			messages: []providers.Message{{Role: providers.RoleUser, Content: []providers.ContentPart{
+				{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/cat.png"}},
+			}}},
+			expected: "image_url content cannot be rendered",
+		},
+		{
+			name:     "tool calls",
+			template: ChatML,
+			messages: []providers.Message{{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{ID: "call_1"}}}},
+			expected: "tool calls cannot be rendered",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tc.template.Render(tc.messages)
+			require.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "chatml",
+			source:   "{% for message in messages %}{{'<|im_start|>' + message['role'] + '\n'}}{% endfor %}",
+			expected: ChatML.Name,
+		},
+		{
+			name:     "llama3",
+			source:   "{{ '<|start_header_id|>' + message['role'] + '<|end_header_id|>\n\n' }}",
+			expected: Llama3.Name,
+		},
+		{
+			name:     "gemma",
+			source:   "{{ '<start_of_turn>' + role + '\n' }}",
+			expected: Gemma.Name,
+		},
+		{
+			name:     "mistral",
+			source:   "{{ '[INST] ' + message['content'] + ' [/INST]' }}",
+			expected: Mistral.Name,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, ok := Detect(tc.source)
+			require.True(t, ok)
+			require.Equal(t, tc.expected, tmpl.Name)
+		})
+	}
+
+	t.Run("unknown format", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok := Detect("{{ messages }}")
+		require.False(t, ok)
+	})
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	tmpl, ok := Lookup("phi3")
+	require.True(t, ok)
+	require.Equal(t, Phi3.Generation, tmpl.Generation)
+
+	_, ok = Lookup("unknown")
+	require.False(t, ok)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/bench"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultBenchPrompt is sent when -prompt is not given.
+const defaultBenchPrompt = "Write a short paragraph about the history of the printing press."
+
+// targetFlags collects repeated -target flags.
+type targetFlags []string
+
+// Set implements flag.Value.
+func (t *targetFlags) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+// String implements flag.Value.
+func (t *targetFlags) String() string {
+	return strings.Join(*t, ",")
+}
+
+// runBench implements the bench command.
+func runBench(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	var targetValues targetFlags
+	fs.Var(&targetValues, "target", "provider:model to benchmark (repeatable)")
+	requests := fs.Int("n", 20, "requests per target")
+	concurrency := fs.Int("c", 4, "concurrent requests per target")
+	prompt := fs.String("prompt", defaultBenchPrompt, "prompt to send")
+	maxTokens := fs.Int("max-tokens", 256, "maximum output tokens per request")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if len(targetValues) == 0 {
+		return fmt.Errorf("at least one -target is required")
+	}
+
+	targets := make([]bench.Target, 0, len(targetValues))
+	for _, value := range targetValues {
+		name, model, err := parseTarget(value)
+		if err != nil {
+			return err
+		}
+
+		provider, err := providers.New(ctx, name)
+		if err != nil {
+			return fmt.Errorf("creating %s provider: %w", name, err)
+		}
+
+		targets = append(targets, bench.Target{Model: model, Name: value, Provider: provider})
+	}
+
+	runner, err := bench.New(bench.WithConcurrency(*concurrency), bench.WithRequests(*requests))
+	if err != nil {
+		return err
+	}
+
+	summaries, err := runner.Run(ctx, providers.CompletionParams{
+		Messages:  []providers.Message{{Role: providers.RoleUser, Content: *prompt}},
+		MaxTokens: maxTokens,
+	}, targets)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	return writeBenchTable(out, summaries)
+}
+
+// writeBenchTable prints summaries as an aligned table.
+func writeBenchTable(out io.Writer, summaries []bench.Summary) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tREQUESTS\tERRORS\tP50\tP95\tTTFT P50\tTTFT P95\tTOKENS/S")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%.1f\n",
+			s.Target,
+			s.Requests,
+			s.Errors,
+			s.LatencyP50.Round(time.Millisecond),
+			s.LatencyP95.Round(time.Millisecond),
+			s.TTFTP50.Round(time.Millisecond),
+			s.TTFTP95.Round(time.Millisecond),
+			s.TokensPerSecond,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range summaries {
+		if s.FirstError != "" {
+			fmt.Fprintf(out, "\n%s: %d of %d requests failed, first error: %s\n",
+				s.Target, s.Errors, s.Requests, s.FirstError)
+		}
+	}
+	return nil
+}
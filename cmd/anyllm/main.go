@@ -0,0 +1,74 @@
+// Command anyllm is a command line tool for working with any-llm providers.
+//
+// Usage:
+//
+//	anyllm bench -target groq:llama-3.1-8b-instant -target llamacpp:default -n 50 -c 8
+//	anyllm replay -rerun 3 -target openai:gpt-4o trace.jsonl
+//	anyllm transcript -session s1 trace.jsonl > transcript.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	_ "github.com/mozilla-ai/any-llm-go/providers/all"
+)
+
+// usage is printed for unknown or missing subcommands.
+const usage = `Usage: anyllm <command> [flags]
+
+Commands:
+  bench        Compare latency and throughput of providers
+  replay       Show a recorded trace and send its requests again
+  transcript   Export the conversation of a recorded trace as a transcript
+
+Run "anyllm <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "bench":
+		err = runBench(ctx, os.Args[2:], os.Stdout)
+	case "replay":
+		err = runReplay(ctx, os.Args[2:], os.Stdout)
+	case "transcript":
+		err = runTranscript(ctx, os.Args[2:], os.Stdout)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "anyllm: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseTarget splits a "provider:model" flag value.
+func parseTarget(value string) (string, string, error) {
+	provider, model, ok := strings.Cut(value, ":")
+	if !ok || provider == "" || model == "" {
+		return "", "", fmt.Errorf("invalid target %q: expected provider:model", value)
+	}
+	return provider, model, nil
+}
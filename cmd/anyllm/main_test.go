@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/replay"
+	"github.com/mozilla-ai/any-llm-go/transcript"
+)
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		value        string
+		wantProvider string
+		wantModel    string
+		wantErr      bool
+	}{
+		{
+			name:         "provider and model",
+			value:        "groq:llama-3.1-8b-instant",
+			wantProvider: "groq",
+			wantModel:    "llama-3.1-8b-instant",
+		},
+		{name: "model with colon", value: "ollama:llama3.2:1b", wantProvider: "ollama", wantModel: "llama3.2:1b"},
+		{name: "missing model", value: "openai", wantErr: true},
+		{name: "empty provider", value: ":gpt-4o", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, model, err := parseTarget(tc.value)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantProvider, provider)
+			require.Equal(t, tc.wantModel, model)
+		})
+	}
+}
+
+func TestRegisteredProviders(t *testing.T) {
+	t.Parallel()
+
+	require.Subset(t, providers.Registered(), []string{"anthropic", "groq", "llamacpp", "ollama", "openai"})
+
+	_, err := providers.New(context.Background(), "nope")
+	require.ErrorIs(t, err, errors.ErrUnsupportedProvider)
+}
+
+func TestRerunStep(t *testing.T) {
+	t.Parallel()
+
+	trace := &replay.Trace{Steps: []replay.Step{{
+		Index: 4,
+		Params: providers.CompletionParams{
+			Model:    "model-a",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		},
+		Provider: "mock",
+		Response: testutil.MockChatCompletion("Hi from model-a"),
+	}}}
+
+	var out bytes.Buffer
+	err := rerunStep(context.Background(), trace, 0, testutil.NewMockProvider(), "model-b", &out)
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "=== step 4 · mock model-a · completion")
+	require.Contains(t, out.String(), "→ assistant: Hi from model-a")
+	require.Contains(t, out.String(), "=== step 4 · mock model-b · completion")
+	require.Contains(t, out.String(), "(retry of step 4)\n→ assistant: Hello World")
+}
+
+func TestExportTrace(t *testing.T) {
+	t.Parallel()
+
+	trace := &replay.Trace{Steps: []replay.Step{{
+		Params: providers.CompletionParams{
+			Model:    "model-a",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		},
+		Provider: "mock",
+		Response: testutil.MockChatCompletion("Hi"),
+	}}}
+
+	var out bytes.Buffer
+	require.NoError(t, exportTrace(trace, &out))
+
+	exported, err := transcript.Read(&out)
+	require.NoError(t, err)
+	require.Equal(t, "model-a", exported.Model)
+	require.Len(t, exported.Turns, 2)
+	require.Equal(t, "Hi", exported.Turns[1].Message.ContentString())
+
+	require.EqualError(t, exportTrace(&replay.Trace{}, &out), "trace has no steps")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/replay"
+)
+
+// runReplay implements the replay command.
+func runReplay(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	session := fs.String("session", "", "show only the steps recorded with this session ID")
+	step := fs.Int("rerun", -1, "send the request of this step again and show the new response")
+	target := fs.String("target", "", "provider:model to send the -rerun request to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one trace file, got %d arguments", fs.NArg())
+	}
+
+	trace, err := replay.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *session != "" {
+		trace = trace.Session(*session)
+	}
+
+	if *step < 0 {
+		return trace.Render(out)
+	}
+	if *step >= len(trace.Steps) {
+		return fmt.Errorf("step %d out of range: trace has %d steps", *step, len(trace.Steps))
+	}
+	if *target == "" {
+		return fmt.Errorf("-rerun requires -target")
+	}
+
+	name, model, err := parseTarget(*target)
+	if err != nil {
+		return err
+	}
+
+	provider, err := providers.New(ctx, name)
+	if err != nil {
+		return fmt.Errorf("creating %s provider: %w", name, err)
+	}
+
+	return rerunStep(ctx, trace, *step, provider, model, out)
+}
+
+// rerunStep sends the request of step i to model on provider and renders the
+// recorded step followed by the new one.
+func rerunStep(
+	ctx context.Context,
+	trace *replay.Trace,
+	i int,
+	provider providers.Provider,
+	model string,
+	out io.Writer,
+) error {
+	var buf bytes.Buffer
+	recorder, err := replay.New(provider, &buf)
+	if err != nil {
+		return err
+	}
+
+	// A failed request is recorded and rendered with its error.
+	_, _ = trace.Rerun(ctx, recorder, i, func(params *providers.CompletionParams) {
+		params.Model = model
+	})
+
+	rerun, err := replay.Read(&buf)
+	if err != nil {
+		return err
+	}
+
+	steps := []replay.Step{trace.Steps[i]}
+	for _, step := range rerun.Steps {
+		step.Index = trace.Steps[i].Index
+		steps = append(steps, step)
+	}
+	return (&replay.Trace{Steps: steps}).Render(out)
+}
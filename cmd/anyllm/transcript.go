@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mozilla-ai/any-llm-go/replay"
+	"github.com/mozilla-ai/any-llm-go/transcript"
+)
+
+// runTranscript implements the transcript command.
+func runTranscript(_ context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("transcript", flag.ContinueOnError)
+	session := fs.String("session", "", "export only the steps recorded with this session ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one trace file, got %d arguments", fs.NArg())
+	}
+
+	trace, err := replay.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *session != "" {
+		trace = trace.Session(*session)
+	}
+
+	return exportTrace(trace, out)
+}
+
+// exportTrace writes the conversation of trace to out as a transcript.
+func exportTrace(trace *replay.Trace, out io.Writer) error {
+	t, err := transcript.FromTrace(trace)
+	if err != nil {
+		return err
+	}
+	return t.Write(out)
+}
@@ -0,0 +1,161 @@
+// Package coalesce wraps a provider so that identical requests in flight at
+// the same time are sent once. When several goroutines issue the same
+// CompletionParams concurrently, as bursty retrieval-augmented endpoints
+// often do, the first request is dispatched and every caller receives its
+// result.
+package coalesce
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Provider wraps a provider and coalesces identical concurrent completions.
+type Provider struct {
+	calls    map[[sha256.Size]byte]*call
+	mu       sync.Mutex
+	provider providers.Provider
+}
+
+// call is a completion in flight and the callers waiting for it.
+type call struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+	resp    *providers.ChatCompletion
+	waiters int
+}
+
+// New wraps provider so that concurrent Completion calls with identical
+// params share one request.
+func New(provider providers.Provider) *Provider {
+	return &Provider{calls: make(map[[sha256.Size]byte]*call), provider: provider}
+}
+
+// Completion performs a chat completion request, or waits for an identical
+// request already in flight and returns its result. Each caller receives its
+// own copy of the response and its choices.
+//
+// The shared request is canceled only when every caller waiting for it has
+// given up, so one caller's cancellation does not fail the others. It runs
+// with the values of the context of the caller that started it.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	key, ok := requestKey(params)
+	if !ok {
+		return p.provider.Completion(ctx, params)
+	}
+
+	p.mu.Lock()
+	c, inFlight := p.calls[key]
+	if !inFlight {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call{cancel: cancel, done: make(chan struct{})}
+		p.calls[key] = c
+		go p.run(callCtx, key, c, params)
+	}
+	c.waiters++
+	p.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if c.err != nil {
+			return nil, c.err
+		}
+		return clone(c.resp), nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody is waiting: stop the request, and let the next caller
+			// start a new one instead of joining the canceled one.
+			c.cancel()
+			p.forget(key, c)
+		}
+		p.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// CompletionStream performs a streaming chat completion request. Streams are
+// not coalesced, since each caller consumes its own chunks.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	return p.provider.CompletionStream(ctx, params)
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// forget removes c from the calls in flight, unless it has already been
+// replaced. p.mu must be held.
+func (p *Provider) forget(key [sha256.Size]byte, c *call) {
+	if p.calls[key] == c {
+		delete(p.calls, key)
+	}
+}
+
+// run sends the request for c and releases its waiters. The call is forgotten
+// before done is closed, so requests that arrive after it finishes are sent
+// again rather than served a stale result.
+func (p *Provider) run(ctx context.Context, key [sha256.Size]byte, c *call, params providers.CompletionParams) {
+	defer c.cancel()
+
+	c.resp, c.err = p.provider.Completion(ctx, params)
+
+	p.mu.Lock()
+	p.forget(key, c)
+	p.mu.Unlock()
+
+	close(c.done)
+}
+
+// clone returns a copy of resp with its own choices, so callers sharing a
+// response cannot affect each other by modifying it.
+func clone(resp *providers.ChatCompletion) *providers.ChatCompletion {
+	if resp == nil {
+		return nil
+	}
+
+	c := *resp
+	c.Choices = slices.Clone(resp.Choices)
+	if resp.Usage != nil {
+		usage := *resp.Usage
+		c.Usage = &usage
+	}
+	return &c
+}
+
+// requestKey returns the SHA-256 of params, including the provider-specific
+// Extra parameters. It reports false if params cannot be encoded, in which
+// case the request is not coalesced.
+func requestKey(params providers.CompletionParams) ([sha256.Size]byte, bool) {
+	b, err := json.Marshal(struct {
+		Extra  map[string]any             `json:"extra,omitempty"`
+		Params providers.CompletionParams `json:"params"`
+	}{Extra: params.Extra, Params: params})
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+
+	return sha256.Sum256(b), true
+}
@@ -0,0 +1,205 @@
+package coalesce
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// blocking returns a mock provider whose completions wait for release, and a
+// counter of the completions it has started.
+func blocking(release <-chan struct{}) (*testutil.MockProvider, *atomic.Int32) {
+	var started atomic.Int32
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(ctx context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+		started.Add(1)
+		select {
+		case <-release:
+			return testutil.MockChatCompletion("Hello World"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return mock, &started
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sends identical concurrent requests once", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		mock, started := blocking(release)
+		p := New(mock)
+		params := providers.CompletionParams{Model: "model", Messages: testutil.SimpleMessages()}
+
+		const callers = 5
+		responses := make([]*providers.ChatCompletion, callers)
+		errs := make([]error, callers)
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				responses[i], errs[i] = p.Completion(context.Background(), params)
+			}()
+		}
+
+		require.Eventually(t, func() bool {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.calls) == 1 && p.calls[mustKey(t, params)].waiters == callers
+		}, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), started.Load())
+		for i, resp := range responses {
+			require.NoError(t, errs[i])
+			require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+		}
+
+		// Callers receive their own copies.
+		responses[0].Choices[0].Message.Content = "changed"
+		require.Equal(t, "Hello World", responses[1].Choices[0].Message.ContentString())
+		require.Empty(t, p.calls)
+	})
+
+	t.Run("sends different requests separately", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		p := New(mock)
+
+		for _, model := range []string{"a", "b"} {
+			_, err := p.Completion(context.Background(), providers.CompletionParams{
+				Model:    model,
+				Messages: testutil.SimpleMessages(),
+			})
+			require.NoError(t, err)
+		}
+		require.Len(t, mock.CompletionCalls, 2)
+	})
+
+	t.Run("shares errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, stderrors.New("boom")
+		}
+
+		_, err := New(mock).Completion(context.Background(), providers.CompletionParams{})
+		require.EqualError(t, err, "boom")
+	})
+
+	t.Run("keeps the request running while a caller waits", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		mock, started := blocking(release)
+		p := New(mock)
+		params := providers.CompletionParams{Model: "model"}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		firstErr := make(chan error, 1)
+		go func() {
+			_, err := p.Completion(ctx, params)
+			firstErr <- err
+		}()
+		require.Eventually(t, func() bool { return started.Load() == 1 }, time.Second, time.Millisecond)
+
+		second := make(chan error, 1)
+		go func() {
+			_, err := p.Completion(context.Background(), params)
+			second <- err
+		}()
+		require.Eventually(t, func() bool {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return p.calls[mustKey(t, params)].waiters == 2
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-firstErr, context.Canceled)
+
+		close(release)
+		require.NoError(t, <-second)
+		require.Equal(t, int32(1), started.Load())
+	})
+
+	t.Run("cancels the request when every caller gives up", func(t *testing.T) {
+		t.Parallel()
+
+		mock, started := blocking(make(chan struct{}))
+		p := New(mock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := p.Completion(ctx, providers.CompletionParams{Model: "model"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, int32(1), started.Load())
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		require.Empty(t, p.calls)
+	})
+
+	t.Run("passes through requests that cannot be encoded", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		params := providers.CompletionParams{Extra: map[string]any{"callback": func() {}}}
+
+		_, err := New(mock).Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	p := New(mock)
+	require.Equal(t, "mock", p.Name())
+	require.Equal(t, mock, p.Unwrap())
+
+	chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+	require.Len(t, mock.CompletionStreamCalls, 1)
+}
+
+func TestRequestKey(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{Model: "model", Extra: map[string]any{"top_k": 5}}
+	other := params
+	other.Extra = map[string]any{"top_k": 6}
+
+	require.Equal(t, mustKey(t, params), mustKey(t, params))
+	require.NotEqual(t, mustKey(t, params), mustKey(t, other))
+}
+
+// mustKey returns the request key for params.
+func mustKey(t *testing.T, params providers.CompletionParams) [32]byte {
+	t.Helper()
+
+	key, ok := requestKey(params)
+	require.True(t, ok)
+	return key
+}
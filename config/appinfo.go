@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Application identification headers. X-Title and HTTP-Referer are the
+// headers OpenRouter attributes traffic with; other providers ignore them.
+const (
+	headerReferer   = "HTTP-Referer"
+	headerTitle     = "X-Title"
+	headerUserAgent = "User-Agent"
+)
+
+// appInfoTransport identifies the application in every request it sends.
+type appInfoTransport struct {
+	base      http.RoundTripper
+	referer   string
+	title     string
+	userAgent string
+}
+
+// WithAppInfo identifies the application to providers, so their dashboards
+// attribute traffic to it rather than to the SDK. The name and version are
+// added in front of the User-Agent header as "name/version", and the name is
+// sent in the X-Title header. The version may be empty.
+func WithAppInfo(name, version string) Option {
+	return func(c *Config) error {
+		name = strings.TrimSpace(name)
+		version = strings.TrimSpace(version)
+		if name == "" {
+			return fmt.Errorf("application name cannot be empty")
+		}
+		if strings.ContainsAny(name+version, "\r\n") || strings.ContainsAny(version, " \t/") {
+			return fmt.Errorf("invalid application name or version %q %q", name, version)
+		}
+
+		c.AppName = name
+		c.AppVersion = version
+		return nil
+	}
+}
+
+// WithAppURL sets the application's website, sent in the HTTP-Referer header.
+func WithAppURL(appURL string) Option {
+	return func(c *Config) error {
+		appURL = strings.TrimSpace(appURL)
+		parsed, err := url.Parse(appURL)
+		if err != nil {
+			return fmt.Errorf("invalid application URL: %w", err)
+		}
+		if parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("application URL must have scheme and host")
+		}
+
+		c.AppURL = appURL
+		return nil
+	}
+}
+
+// RoundTrip adds the application's headers to req. The SDK's own User-Agent
+// is kept after the application's, and headers already set are not replaced.
+func (t *appInfoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+
+	if t.userAgent != "" {
+		ua := t.userAgent
+		if sdk := r.Header.Get(headerUserAgent); sdk != "" {
+			ua += " " + sdk
+		}
+		r.Header.Set(headerUserAgent, ua)
+	}
+	if t.title != "" && r.Header.Get(headerTitle) == "" {
+		r.Header.Set(headerTitle, t.title)
+	}
+	if t.referer != "" && r.Header.Get(headerReferer) == "" {
+		r.Header.Set(headerReferer, t.referer)
+	}
+
+	return t.base.RoundTrip(r)
+}
+
+// UserAgent returns the User-Agent product token for the application, such
+// as "myapp/1.2.0", or "" if WithAppInfo was not used.
+func (c *Config) UserAgent() string {
+	if c.AppName == "" {
+		return ""
+	}
+
+	product := strings.Join(strings.Fields(c.AppName), "-")
+	if c.AppVersion == "" {
+		return product
+	}
+	return product + "/" + c.AppVersion
+}
+
+// newAppInfoClient returns a copy of client that identifies the application
+// in its requests.
+func newAppInfoClient(client *http.Client, userAgent, title, referer string) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	appInfo := *client
+	appInfo.Transport = &appInfoTransport{base: base, referer: referer, title: title, userAgent: userAgent}
+	return &appInfo
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAppInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		appName       string
+		version       string
+		wantUserAgent string
+		wantErr       bool
+	}{
+		{name: "name and version", appName: "myapp", version: "1.2.0", wantUserAgent: "myapp/1.2.0"},
+		{name: "name only", appName: "myapp", wantUserAgent: "myapp"},
+		{name: "spaces in name", appName: " My App ", version: "2", wantUserAgent: "My-App/2"},
+		{name: "empty name", appName: " ", wantErr: true},
+		{name: "space in version", appName: "myapp", version: "1 beta", wantErr: true},
+		{name: "newline in name", appName: "my\napp", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := New(WithAppInfo(tc.appName, tc.version))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantUserAgent, cfg.UserAgent())
+		})
+	}
+
+	t.Run("no app info", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := New()
+		require.NoError(t, err)
+		require.Empty(t, cfg.UserAgent())
+	})
+}
+
+func TestWithAppURL(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := New(WithAppURL(" https://example.com "))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", cfg.AppURL)
+
+	_, err = New(WithAppURL("example.com"))
+	require.Error(t, err)
+}
+
+func TestAppInfoTransport(t *testing.T) {
+	t.Parallel()
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	t.Cleanup(server.Close)
+
+	send := func(t *testing.T, cfg *Config, header http.Header) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := cfg.HTTPClient().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	t.Run("adds headers", func(t *testing.T) {
+		custom := &http.Client{}
+		cfg, err := New(WithHTTPClient(custom), WithAppInfo("myapp", "1.2.0"), WithAppURL("https://example.com"))
+		require.NoError(t, err)
+
+		send(t, cfg, http.Header{"User-Agent": {"OpenAI/Go 1.0.0"}})
+		require.Equal(t, "myapp/1.2.0 OpenAI/Go 1.0.0", got.Get("User-Agent"))
+		require.Equal(t, "myapp", got.Get("X-Title"))
+		require.Equal(t, "https://example.com", got.Get("HTTP-Referer"))
+		require.Nil(t, custom.Transport, "the custom client must not be modified")
+	})
+
+	t.Run("keeps headers already set", func(t *testing.T) {
+		cfg, err := New(WithAppInfo("myapp", ""))
+		require.NoError(t, err)
+
+		send(t, cfg, http.Header{"X-Title": {"other"}})
+		require.Equal(t, "other", got.Get("X-Title"))
+		require.Empty(t, got.Get("HTTP-Referer"))
+	})
+
+	t.Run("sends nothing without app info", func(t *testing.T) {
+		cfg, err := New()
+		require.NoError(t, err)
+
+		send(t, cfg, nil)
+		require.Empty(t, got.Get("X-Title"))
+		require.NotContains(t, got.Get("User-Agent"), "myapp")
+	})
+}
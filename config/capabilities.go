@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Capability names, for WithCapabilityOverrides. Each names a field of
+// providers.Capabilities.
+const (
+	CapabilityCompletion           = "completion"
+	CapabilityCompletionImage      = "completion_image"
+	CapabilityCompletionJSONObject = "completion_json_object"
+	CapabilityCompletionJSONSchema = "completion_json_schema"
+	CapabilityCompletionPDF        = "completion_pdf"
+	CapabilityCompletionReasoning  = "completion_reasoning"
+	CapabilityCompletionStreaming  = "completion_streaming"
+	CapabilityCompletionTools      = "completion_tools"
+	CapabilityEmbedding            = "embedding"
+	CapabilityListModels           = "list_models"
+)
+
+// capabilities is the set of known capability names.
+var capabilities = map[string]bool{
+	CapabilityCompletion:           true,
+	CapabilityCompletionImage:      true,
+	CapabilityCompletionJSONObject: true,
+	CapabilityCompletionJSONSchema: true,
+	CapabilityCompletionPDF:        true,
+	CapabilityCompletionReasoning:  true,
+	CapabilityCompletionStreaming:  true,
+	CapabilityCompletionTools:      true,
+	CapabilityEmbedding:            true,
+	CapabilityListModels:           true,
+}
+
+// WithCapabilityOverrides corrects the capabilities a provider reports, such
+// as for a provider pointed at a proxy or self-hosted endpoint that supports
+// more or less than the provider's own API. Keys are capability names, such
+// as CapabilityCompletionImage, and values replace what the provider reports,
+// including capabilities found by Probe. Unknown names are rejected, and
+// repeated calls add to the overrides.
+func WithCapabilityOverrides(overrides map[string]bool) Option {
+	return func(c *Config) error {
+		for name := range overrides {
+			if !capabilities[name] {
+				return fmt.Errorf("unknown capability %q, want one of %s", name, capabilityNames())
+			}
+		}
+
+		if c.CapabilityOverrides == nil {
+			c.CapabilityOverrides = make(map[string]bool, len(overrides))
+		}
+		maps.Copy(c.CapabilityOverrides, overrides)
+		return nil
+	}
+}
+
+// capabilityNames returns the known capability names, sorted and comma
+// separated.
+func capabilityNames() string {
+	return strings.Join(slices.Sorted(maps.Keys(capabilities)), ", ")
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCapabilityOverrides(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithCapabilityOverrides(map[string]bool{"vision": true}))
+	require.ErrorContains(t, err, `unknown capability "vision", want one of completion, completion_image,`)
+
+	cfg, err := New(
+		WithCapabilityOverrides(map[string]bool{CapabilityCompletionImage: true}),
+		WithCapabilityOverrides(map[string]bool{CapabilityListModels: false}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{CapabilityCompletionImage: true, CapabilityListModels: false}, cfg.CapabilityOverrides)
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultCompressionThreshold is the body size, in bytes, from which request
+// bodies are compressed. Smaller bodies gain little and cost a little CPU.
+const defaultCompressionThreshold = 16 << 10
+
+// headerContentEncoding is the header that names a request body's compression.
+const headerContentEncoding = "Content-Encoding"
+
+// Compression is a content coding that request bodies are compressed with.
+type Compression string
+
+// Request compressions.
+const (
+	// CompressionGzip compresses request bodies with gzip. Most servers and
+	// reverse proxies that accept compressed requests accept gzip.
+	CompressionGzip Compression = "gzip"
+
+	// CompressionZstd compresses request bodies with Zstandard, which is
+	// faster than gzip and compresses better.
+	CompressionZstd Compression = "zstd"
+)
+
+// compressTransport compresses the bodies of large requests. When the server
+// rejects a compressed body with 415 Unsupported Media Type, the request is
+// sent again uncompressed and compression is turned off.
+type compressTransport struct {
+	base        http.RoundTripper
+	compression Compression
+	disabled    atomic.Bool
+	threshold   int
+}
+
+// WithCompressionThreshold sets the body size, in bytes, from which requests
+// are compressed with WithRequestCompression. The default is 16 KiB.
+func WithCompressionThreshold(size int) Option {
+	return func(c *Config) error {
+		if size <= 0 {
+			return fmt.Errorf("compression threshold must be positive, got %d", size)
+		}
+
+		c.CompressionThreshold = size
+		return nil
+	}
+}
+
+// WithRequestCompression compresses request bodies, such as prompts with
+// large base64 images or long documents, to cut upload time. The server must
+// accept compressed requests, as vLLM and TGI do behind a reverse proxy that
+// decompresses them; hosted APIs generally do not. Only bodies from the
+// compression threshold up are compressed.
+func WithRequestCompression(compression Compression) Option {
+	return func(c *Config) error {
+		switch compression {
+		case CompressionGzip, CompressionZstd:
+		default:
+			return fmt.Errorf("unknown request compression %q", compression)
+		}
+
+		c.RequestCompression = compression
+		return nil
+	}
+}
+
+// RoundTrip compresses the body of req if it is at least the threshold in
+// size. Requests whose body is already encoded are passed through.
+func (t *compressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.disabled.Load() || req.Body == nil || req.Body == http.NoBody || req.Header.Get(headerContentEncoding) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// A length of 0 with a body is unknown, so the body is read to measure it.
+	if req.ContentLength > 0 && req.ContentLength < int64(t.threshold) {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close() // The body has been read in full.
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+	if len(body) < t.threshold {
+		return t.base.RoundTrip(withBody(req, body, ""))
+	}
+
+	compressed, err := compress(t.compression, body)
+	if err != nil {
+		return nil, fmt.Errorf("compressing request body: %w", err)
+	}
+
+	resp, err := t.base.RoundTrip(withBody(req, compressed, string(t.compression)))
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+
+	// The server does not accept compressed requests.
+	t.disabled.Store(true)
+	_, _ = io.Copy(io.Discard, resp.Body) // Drain so the connection can be reused.
+	_ = resp.Body.Close()                 // The response is replaced.
+	return t.base.RoundTrip(withBody(req, body, ""))
+}
+
+// compress returns body compressed with compression.
+func compress(compression Compression, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("unknown request compression %q", compression)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newCompressClient returns a copy of client that compresses request bodies
+// from threshold bytes up.
+func newCompressClient(client *http.Client, compression Compression, threshold int) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	compressing := *client
+	compressing.Transport = &compressTransport{base: base, compression: compression, threshold: threshold}
+	return &compressing
+}
+
+// withBody returns a copy of req that sends body, encoded with encoding if
+// it is not empty. The body can be replayed, so failover can resend it.
+func withBody(req *http.Request, body []byte, encoding string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	r.Header.Del(headerContentEncoding)
+	if encoding != "" {
+		r.Header.Set(headerContentEncoding, encoding)
+	}
+	return r
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// testReceived is a request body as a test server received it.
+type testReceived struct {
+	body     string
+	encoding string
+	size     int
+}
+
+// newDecompressingServer starts a server that decompresses request bodies
+// and records them. With reject, it answers compressed requests with 415.
+func newDecompressingServer(t *testing.T, reject bool) (*httptest.Server, func() []testReceived) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []testReceived
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		encoding := r.Header.Get(headerContentEncoding)
+		body := raw
+		switch encoding {
+		case "gzip":
+			zr, err := gzip.NewReader(bytes.NewReader(raw))
+			require.NoError(t, err)
+			body, err = io.ReadAll(zr)
+			require.NoError(t, err)
+		case "zstd":
+			zr, err := zstd.NewReader(bytes.NewReader(raw))
+			require.NoError(t, err)
+			body, err = io.ReadAll(zr)
+			require.NoError(t, err)
+		}
+
+		mu.Lock()
+		received = append(received, testReceived{body: string(body), encoding: encoding, size: len(raw)})
+		mu.Unlock()
+
+		if reject && encoding != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []testReceived {
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+func TestWithRequestCompression(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := New(WithRequestCompression(CompressionZstd), WithCompressionThreshold(1))
+	require.NoError(t, err)
+	require.Equal(t, CompressionZstd, cfg.RequestCompression)
+	require.Equal(t, 1, cfg.CompressionThreshold)
+
+	_, err = New(WithRequestCompression("br"))
+	require.EqualError(t, err, `unknown request compression "br"`)
+
+	_, err = New(WithCompressionThreshold(0))
+	require.EqualError(t, err, "compression threshold must be positive, got 0")
+}
+
+func TestCompressTransport(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("a long document ", 2000)
+
+	tests := []struct {
+		name         string
+		compression  Compression
+		body         string
+		wantEncoding string
+	}{
+		{name: "gzip", compression: CompressionGzip, body: large, wantEncoding: "gzip"},
+		{name: "zstd", compression: CompressionZstd, body: large, wantEncoding: "zstd"},
+		{name: "small body", compression: CompressionGzip, body: "hello", wantEncoding: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, received := newDecompressingServer(t, false)
+			cfg, err := New(WithRequestCompression(tc.compression))
+			require.NoError(t, err)
+
+			resp, err := cfg.HTTPClient().Post(server.URL, "application/json", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			got := received()
+			require.Len(t, got, 1)
+			require.Equal(t, tc.body, got[0].body)
+			require.Equal(t, tc.wantEncoding, got[0].encoding)
+			if tc.wantEncoding != "" {
+				require.Less(t, got[0].size, len(tc.body))
+			}
+		})
+	}
+
+	t.Run("body of unknown length", func(t *testing.T) {
+		t.Parallel()
+
+		server, received := newDecompressingServer(t, false)
+		cfg, err := New(WithRequestCompression(CompressionGzip), WithCompressionThreshold(4))
+		require.NoError(t, err)
+
+		// A reader the client cannot size, so the body is read to measure it.
+		body := io.MultiReader(strings.NewReader("hello"), strings.NewReader(" world"))
+		resp, err := cfg.HTTPClient().Post(server.URL, "text/plain", body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		got := received()
+		require.Len(t, got, 1)
+		require.Equal(t, "hello world", got[0].body)
+		require.Equal(t, "gzip", got[0].encoding)
+	})
+
+	t.Run("falls back when the server rejects compression", func(t *testing.T) {
+		t.Parallel()
+
+		server, received := newDecompressingServer(t, true)
+		cfg, err := New(WithRequestCompression(CompressionZstd))
+		require.NoError(t, err)
+
+		for range 2 {
+			resp, err := cfg.HTTPClient().Post(server.URL, "application/json", strings.NewReader(large))
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		got := received()
+		require.Len(t, got, 3)
+		require.Equal(t, []string{"zstd", "", ""}, []string{got[0].encoding, got[1].encoding, got[2].encoding})
+	})
+
+	t.Run("fails over with the compressed body", func(t *testing.T) {
+		t.Parallel()
+
+		down := newEndpointServer(t, http.StatusServiceUnavailable)
+		server, received := newDecompressingServer(t, false)
+		cfg, err := New(
+			WithBaseURLs(down.URL+"/v1", server.URL+"/v1"),
+			WithRequestCompression(CompressionGzip),
+		)
+		require.NoError(t, err)
+
+		resp, err := cfg.HTTPClient().Post(down.URL+"/v1/chat", "application/json", strings.NewReader(large))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Len(t, down.received(), 1)
+		got := received()
+		require.Len(t, got, 1)
+		require.Equal(t, large, got[0].body)
+		require.Equal(t, "gzip", got[0].encoding)
+	})
+}
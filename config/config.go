@@ -15,12 +15,49 @@ type Config struct {
 	// APIKey is the API key for authentication.
 	APIKey string
 
+	// AppName identifies the application to providers, set by WithAppInfo.
+	AppName string
+
+	// AppURL is the application's website, set by WithAppURL.
+	AppURL string
+
+	// AppVersion is the application's version, set by WithAppInfo.
+	AppVersion string
+
 	// BaseURL is the base URL for the API. If empty, the provider's default is used.
 	BaseURL string
 
+	// BaseURLs lists base URLs to fail over between, set by WithBaseURLs.
+	// The first is also BaseURL.
+	BaseURLs []string
+
+	// CapabilityOverrides replace capabilities the provider reports, by
+	// capability name, set by WithCapabilityOverrides.
+	CapabilityOverrides map[string]bool
+
+	// CompressionThreshold is the body size, in bytes, from which requests are
+	// compressed. If zero, a default threshold is used.
+	CompressionThreshold int
+
 	// Extra holds provider-specific configuration options.
 	Extra map[string]any
 
+	// FailoverCooldown is how long a failed base URL is skipped. If zero, a
+	// default cooldown is used.
+	FailoverCooldown time.Duration
+
+	// ReasoningPolicy is what happens to reasoning a model inlines in its
+	// content. If empty, it is moved to the Reasoning field.
+	ReasoningPolicy ReasoningPolicy
+
+	// RawSchemas disables the rewriting of strict JSON schemas, set by
+	// WithRawSchemas.
+	RawSchemas bool
+
+	// RequestCompression compresses request bodies, set by
+	// WithRequestCompression. If empty, requests are sent uncompressed.
+	RequestCompression Compression
+
 	// Timeout is the request timeout. If zero, a default timeout is used.
 	Timeout time.Duration
 
@@ -149,12 +186,34 @@ func (c *Config) ExtraValue(key string) (any, bool) {
 // the configured Timeout if no custom client was provided via WithHTTPClient.
 // The lazily-created client is cached and reused on subsequent calls.
 //
-// Note: If a custom client was provided via WithHTTPClient, that pointer is returned.
+// Note: If a custom client was provided via WithHTTPClient, that pointer is returned,
+// unless several base URLs were set with WithBaseURLs, application details were set
+// with WithAppInfo or WithAppURL, or WithRequestCompression was used. Then a copy of
+// the client is returned, with a transport that fails over between the URLs,
+// identifies the application or compresses request bodies.
 func (c *Config) HTTPClient() *http.Client {
 	c.httpClientOnce.Do(func() {
 		if c.httpClient == nil {
 			c.httpClient = &http.Client{Timeout: c.Timeout}
 		}
+		if c.AppName != "" || c.AppURL != "" {
+			c.httpClient = newAppInfoClient(c.httpClient, c.UserAgent(), c.AppName, c.AppURL)
+		}
+		if len(c.BaseURLs) > 1 {
+			cooldown := c.FailoverCooldown
+			if cooldown == 0 {
+				cooldown = defaultFailoverCooldown
+			}
+			c.httpClient = newFailoverClient(c.httpClient, c.BaseURLs, cooldown)
+		}
+		if c.RequestCompression != "" {
+			// Compress outside failover, which then resends the compressed body.
+			threshold := c.CompressionThreshold
+			if threshold == 0 {
+				threshold = defaultCompressionThreshold
+			}
+			c.httpClient = newCompressClient(c.httpClient, c.RequestCompression, threshold)
+		}
 	})
 
 	return c.httpClient
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFailoverCooldown is how long an endpoint that failed is avoided.
+const defaultFailoverCooldown = 30 * time.Second
+
+// endpoint is a base URL and its health.
+type endpoint struct {
+	// downUntil is when the endpoint is tried again after a failure.
+	downUntil time.Time
+	path      string
+	url       *url.URL
+}
+
+// failoverTransport sends requests for any of its endpoints to the first
+// healthy one, moving on to the next when an endpoint cannot be reached or
+// answers with a server error.
+type failoverTransport struct {
+	base      http.RoundTripper
+	cooldown  time.Duration
+	endpoints []*endpoint
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// WithBaseURLs sets several base URLs for the same API, such as the regions
+// of an Azure deployment or replicas of a vLLM server. Requests go to the
+// first URL that is healthy: an endpoint that cannot be reached or answers
+// with a 5xx status is skipped for the failover cooldown, after which it is
+// tried again. The first URL is also set as BaseURL.
+func WithBaseURLs(baseURLs ...string) Option {
+	return func(c *Config) error {
+		if len(baseURLs) == 0 {
+			return fmt.Errorf("at least one base URL is required")
+		}
+
+		urls := make([]string, len(baseURLs))
+		for i, baseURL := range baseURLs {
+			if err := WithBaseURL(baseURL)(c); err != nil {
+				return err
+			}
+			urls[i] = c.BaseURL
+		}
+
+		c.BaseURL = urls[0]
+		c.BaseURLs = urls
+		return nil
+	}
+}
+
+// WithFailoverCooldown sets how long an endpoint set with WithBaseURLs is
+// skipped after it fails. The default is 30 seconds.
+func WithFailoverCooldown(d time.Duration) Option {
+	return func(c *Config) error {
+		if d <= 0 {
+			return fmt.Errorf("failover cooldown must be positive, got %v", d)
+		}
+
+		c.FailoverCooldown = d
+		return nil
+	}
+}
+
+// RoundTrip sends req to the first healthy endpoint, failing over to the
+// others in turn. Requests for other hosts are passed through. A request
+// whose body cannot be replayed is sent to one endpoint only.
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	suffix, ok := t.match(req.URL)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	var err error
+	for i, ep := range t.order() {
+		if i > 0 && req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			break
+		}
+		if resp != nil {
+			// Discard the failed response so its connection can be reused.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		var r *http.Request
+		r, err = rewrite(req, ep, suffix, i > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = t.base.RoundTrip(r)
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			t.markUp(ep)
+			return resp, nil
+		}
+		t.markDown(ep)
+	}
+
+	return resp, err
+}
+
+// markDown records that ep failed.
+func (t *failoverTransport) markDown(ep *endpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ep.downUntil = t.now().Add(t.cooldown)
+}
+
+// markUp records that ep answered.
+func (t *failoverTransport) markUp(ep *endpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ep.downUntil = time.Time{}
+}
+
+// match returns the part of u's path after the endpoint it belongs to, and
+// false if it belongs to none.
+func (t *failoverTransport) match(u *url.URL) (string, bool) {
+	for _, ep := range t.endpoints {
+		if u.Scheme != ep.url.Scheme || u.Host != ep.url.Host {
+			continue
+		}
+		if u.Path == ep.path || strings.HasPrefix(u.Path, ep.path+"/") {
+			return strings.TrimPrefix(u.Path, ep.path), true
+		}
+	}
+	return "", false
+}
+
+// order returns the endpoints to try: healthy ones in the configured order,
+// then those in their cooldown, the soonest to recover first.
+func (t *failoverTransport) order() []*endpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var healthy, down []*endpoint
+	for _, ep := range t.endpoints {
+		if now.Before(ep.downUntil) {
+			down = append(down, ep)
+			continue
+		}
+		healthy = append(healthy, ep)
+	}
+
+	slices.SortStableFunc(down, func(a, b *endpoint) int {
+		return a.downUntil.Compare(b.downUntil)
+	})
+	return append(healthy, down...)
+}
+
+// newFailoverClient returns a copy of client that fails over between baseURLs.
+func newFailoverClient(client *http.Client, baseURLs []string, cooldown time.Duration) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &failoverTransport{
+		base:     base,
+		cooldown: cooldown,
+		now:      time.Now,
+	}
+	for _, baseURL := range baseURLs {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			continue // Validated by WithBaseURLs.
+		}
+		t.endpoints = append(t.endpoints, &endpoint{path: strings.TrimSuffix(u.Path, "/"), url: u})
+	}
+
+	failover := *client
+	failover.Transport = t
+	return &failover
+}
+
+// rewrite returns a copy of req for the endpoint ep, with a fresh body if the
+// body has been sent before.
+func rewrite(req *http.Request, ep *endpoint, suffix string, replay bool) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = ep.url.Scheme
+	r.URL.Host = ep.url.Host
+	r.URL.Path = ep.path + suffix
+	r.URL.RawPath = ""
+	r.Host = ""
+
+	if replay && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("replaying request body: %w", err)
+		}
+		r.Body = body
+	}
+
+	return r, nil
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// endpointServer is a test server that records the requests it receives and
+// answers them with status.
+type endpointServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+	status   int
+}
+
+// newEndpointServer starts an endpointServer answering with status.
+func newEndpointServer(t *testing.T, status int) *endpointServer {
+	t.Helper()
+
+	s := &endpointServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path+" "+string(body))
+		status := s.status
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// received returns the requests the server received, as "path body".
+func (s *endpointServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// setStatus changes the status the server answers with.
+func (s *endpointServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// post sends a POST with body to url.
+func post(t *testing.T, client *http.Client, url string, body string) int {
+	t.Helper()
+
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return resp.StatusCode
+}
+
+func TestWithBaseURLs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		urls    []string
+		wantErr string
+	}{
+		{
+			name:    "rejects no URLs",
+			wantErr: "at least one base URL is required",
+		},
+		{
+			name:    "rejects invalid URLs",
+			urls:    []string{"https://eastus.example.com", "westus"},
+			wantErr: "base URL must have scheme and host",
+		},
+		{
+			name: "accepts valid URLs",
+			urls: []string{" https://eastus.example.com/v1 ", "https://westus.example.com/v1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := New(WithBaseURLs(tc.urls...))
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "https://eastus.example.com/v1", cfg.BaseURL)
+			require.Equal(t, []string{"https://eastus.example.com/v1", "https://westus.example.com/v1"}, cfg.BaseURLs)
+		})
+	}
+}
+
+func TestWithFailoverCooldown(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithFailoverCooldown(0))
+	require.EqualError(t, err, "failover cooldown must be positive, got 0s")
+
+	cfg, err := New(WithFailoverCooldown(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, cfg.FailoverCooldown)
+}
+
+func TestFailoverTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fails over on server errors and replays the body", func(t *testing.T) {
+		t.Parallel()
+
+		primary := newEndpointServer(t, http.StatusServiceUnavailable)
+		secondary := newEndpointServer(t, http.StatusOK)
+
+		cfg, err := New(WithBaseURLs(primary.URL+"/v1", secondary.URL+"/api/v1"))
+		require.NoError(t, err)
+
+		status := post(t, cfg.HTTPClient(), cfg.BaseURL+"/chat/completions", `{"model":"m"}`)
+
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, []string{`/v1/chat/completions {"model":"m"}`}, primary.received())
+		require.Equal(t, []string{`/api/v1/chat/completions {"model":"m"}`}, secondary.received())
+	})
+
+	t.Run("fails over when an endpoint is unreachable", func(t *testing.T) {
+		t.Parallel()
+
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		up := newEndpointServer(t, http.StatusOK)
+
+		cfg, err := New(WithBaseURLs(down.URL, up.URL))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, post(t, cfg.HTTPClient(), cfg.BaseURL+"/chat", "{}"))
+		require.Len(t, up.received(), 1)
+	})
+
+	t.Run("skips failed endpoints until the cooldown ends", func(t *testing.T) {
+		t.Parallel()
+
+		primary := newEndpointServer(t, http.StatusBadGateway)
+		secondary := newEndpointServer(t, http.StatusOK)
+
+		cfg, err := New(WithBaseURLs(primary.URL, secondary.URL), WithFailoverCooldown(time.Minute))
+		require.NoError(t, err)
+
+		client := cfg.HTTPClient()
+		transport := client.Transport.(*failoverTransport)
+		now := time.Now()
+		transport.now = func() time.Time { return now }
+
+		post(t, client, cfg.BaseURL+"/chat", "{}")
+		post(t, client, cfg.BaseURL+"/chat", "{}")
+		require.Len(t, primary.received(), 1)
+		require.Len(t, secondary.received(), 2)
+
+		primary.setStatus(http.StatusOK)
+		now = now.Add(time.Minute)
+		post(t, client, cfg.BaseURL+"/chat", "{}")
+		require.Len(t, primary.received(), 2)
+		require.Len(t, secondary.received(), 2)
+	})
+
+	t.Run("does not fail over on client errors", func(t *testing.T) {
+		t.Parallel()
+
+		primary := newEndpointServer(t, http.StatusTooManyRequests)
+		secondary := newEndpointServer(t, http.StatusOK)
+
+		cfg, err := New(WithBaseURLs(primary.URL, secondary.URL))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusTooManyRequests, post(t, cfg.HTTPClient(), cfg.BaseURL+"/chat", "{}"))
+		require.Empty(t, secondary.received())
+	})
+
+	t.Run("returns the last failure when every endpoint fails", func(t *testing.T) {
+		t.Parallel()
+
+		primary := newEndpointServer(t, http.StatusInternalServerError)
+		secondary := newEndpointServer(t, http.StatusServiceUnavailable)
+
+		cfg, err := New(WithBaseURLs(primary.URL, secondary.URL))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusServiceUnavailable, post(t, cfg.HTTPClient(), cfg.BaseURL+"/chat", "{}"))
+	})
+
+	t.Run("passes other hosts through", func(t *testing.T) {
+		t.Parallel()
+
+		other := newEndpointServer(t, http.StatusOK)
+
+		cfg, err := New(WithBaseURLs("https://eastus.example.com", "https://westus.example.com"))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, post(t, cfg.HTTPClient(), other.URL+"/upload", "{}"))
+		require.Len(t, other.received(), 1)
+	})
+
+	t.Run("wraps a copy of a custom client", func(t *testing.T) {
+		t.Parallel()
+
+		custom := &http.Client{Timeout: 5 * time.Second}
+		cfg, err := New(WithHTTPClient(custom), WithBaseURLs("https://a.example.com", "https://b.example.com"))
+		require.NoError(t, err)
+
+		client := cfg.HTTPClient()
+		require.NotSame(t, custom, client)
+		require.Nil(t, custom.Transport)
+		require.Equal(t, 5*time.Second, client.Timeout)
+		require.IsType(t, &failoverTransport{}, client.Transport)
+	})
+}
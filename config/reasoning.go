@@ -0,0 +1,37 @@
+package config
+
+import "fmt"
+
+// ReasoningPolicy controls what happens to reasoning that a model inlines in
+// its content between <think> and </think> tags, as DeepSeek-R1 and Qwen
+// models do on some providers.
+type ReasoningPolicy string
+
+// Reasoning policies.
+const (
+	// ReasoningKeep leaves inline reasoning, tags included, in the content.
+	ReasoningKeep ReasoningPolicy = "keep"
+
+	// ReasoningSeparate moves inline reasoning out of the content and into the
+	// Reasoning field. It is the default.
+	ReasoningSeparate ReasoningPolicy = "separate"
+
+	// ReasoningStrip drops inline reasoning.
+	ReasoningStrip ReasoningPolicy = "strip"
+)
+
+// WithReasoningPolicy sets what providers do with reasoning a model inlines
+// in its content. It applies to providers that parse inline reasoning;
+// reasoning a provider returns in a dedicated field is always separate.
+func WithReasoningPolicy(policy ReasoningPolicy) Option {
+	return func(c *Config) error {
+		switch policy {
+		case ReasoningKeep, ReasoningSeparate, ReasoningStrip:
+		default:
+			return fmt.Errorf("unknown reasoning policy %q", policy)
+		}
+
+		c.ReasoningPolicy = policy
+		return nil
+	}
+}
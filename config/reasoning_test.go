@@ -0,0 +1,22 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReasoningPolicy(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithReasoningPolicy("hide"))
+	require.EqualError(t, err, `unknown reasoning policy "hide"`)
+
+	cfg, err := New()
+	require.NoError(t, err)
+	require.Empty(t, cfg.ReasoningPolicy)
+
+	cfg, err = New(WithReasoningPolicy(ReasoningStrip))
+	require.NoError(t, err)
+	require.Equal(t, ReasoningStrip, cfg.ReasoningPolicy)
+}
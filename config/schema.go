@@ -0,0 +1,13 @@
+package config
+
+// WithRawSchemas makes providers send the JSON schemas of strict tools and
+// response formats as given. By default, providers that support strict
+// schemas (OpenAI and OpenAI-compatible providers) rewrite them with
+// providers.StrictSchema, so that a schema written for any provider is
+// accepted in strict mode.
+func WithRawSchemas() Option {
+	return func(c *Config) error {
+		c.RawSchemas = true
+		return nil
+	}
+}
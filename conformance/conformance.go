@@ -0,0 +1,554 @@
+// Package conformance checks that a provider behaves the way any-llm and its
+// callers expect: that it answers completions, system prompts and multi-turn
+// conversations, streams chunks that join into the shape of its completions,
+// runs tool calling agent loops, and maps authentication failures to
+// errors.ErrAuthentication. Any provider, in this module or outside it, runs
+// the whole matrix against itself with one call:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(opts ...config.Option) (providers.Provider, error) {
+//			return acme.New(opts...)
+//		}, "acme-small")
+//	}
+//
+// The checks send real requests, so they run against a live endpoint or a
+// local server. Checks the provider's Capabilities rule out are skipped.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Checks.
+const (
+	// CheckAgentLoop calls the weather tool and answers from its result.
+	CheckAgentLoop Check = "agent_loop"
+
+	// CheckAgentLoopMultipleParams calls a tool with several required
+	// parameters and answers from its result, which catches parameters that
+	// are dropped or reordered in conversion.
+	CheckAgentLoopMultipleParams Check = "agent_loop_multiple_params"
+
+	// CheckAuthentication sends an invalid API key and expects an
+	// authentication error.
+	CheckAuthentication Check = "authentication"
+
+	// CheckCompletion sends a single user message.
+	CheckCompletion Check = "completion"
+
+	// CheckConversation sends a multi-turn conversation the answer depends on.
+	CheckConversation Check = "conversation"
+
+	// CheckStream streams a completion.
+	CheckStream Check = "stream"
+
+	// CheckStreamParity sends the same request with and without streaming and
+	// compares the shape of the two.
+	CheckStreamParity Check = "stream_parity"
+
+	// CheckSystemMessage sends a system prompt.
+	CheckSystemMessage Check = "system_message"
+
+	// CheckTools offers a tool the request calls for.
+	CheckTools Check = "tools"
+)
+
+// invalidAPIKey is the API key CheckAuthentication sends.
+const invalidAPIKey = "invalid-api-key"
+
+// Response object types.
+const (
+	objectChatCompletion      = "chat.completion"
+	objectChatCompletionChunk = "chat.completion.chunk"
+)
+
+// Tool names of the fixtures the checks use.
+const (
+	toolCalculate  = "calculate"
+	toolGetWeather = "get_weather"
+)
+
+// Check names one behavior Run checks.
+type Check string
+
+// Factory creates the provider under test with options, as the New function
+// of a provider package does.
+type Factory func(opts ...config.Option) (providers.Provider, error)
+
+// Option configures Run.
+type Option func(*suite) error
+
+// suite holds what Run checks and how.
+type suite struct {
+	model       string
+	newProvider Factory
+	options     []config.Option
+	skip        []Check
+	skipError   func(error) bool
+}
+
+// WithProviderOptions passes opts to the provider's Factory in every check,
+// such as the base URL of a local server.
+func WithProviderOptions(opts ...config.Option) Option {
+	return func(s *suite) error {
+		s.options = append(s.options, opts...)
+		return nil
+	}
+}
+
+// WithSkip skips checks that do not apply to the provider, such as
+// CheckAuthentication for a local server that accepts any key.
+func WithSkip(checks ...Check) Option {
+	return func(s *suite) error {
+		for _, check := range checks {
+			if !slices.Contains(allChecks(), check) {
+				return fmt.Errorf("unknown check %q", check)
+			}
+		}
+
+		s.skip = append(s.skip, checks...)
+		return nil
+	}
+}
+
+// WithSkipOnError skips a check, rather than failing it, when a request
+// fails with an error match reports. It is for failures the model causes
+// rather than the provider, such as Groq rejecting a malformed tool call the
+// model wrote.
+func WithSkipOnError(match func(error) bool) Option {
+	return func(s *suite) error {
+		if match == nil {
+			return fmt.Errorf("error matcher must not be nil")
+		}
+
+		s.skipError = match
+		return nil
+	}
+}
+
+// Run runs every check against the provider newProvider creates, sending
+// requests to model, each in a parallel subtest named after its Check.
+func Run(t *testing.T, newProvider Factory, model string, opts ...Option) {
+	t.Helper()
+
+	s := &suite{model: model, newProvider: newProvider}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checks := map[Check]func(*testing.T){
+		CheckAgentLoop:               s.agentLoop,
+		CheckAgentLoopMultipleParams: s.agentLoopMultipleParams,
+		CheckAuthentication:          s.authentication,
+		CheckCompletion:              s.completion,
+		CheckConversation:            s.conversation,
+		CheckStream:                  s.stream,
+		CheckStreamParity:            s.streamParity,
+		CheckSystemMessage:           s.systemMessage,
+		CheckTools:                   s.tools,
+	}
+
+	for _, check := range allChecks() {
+		t.Run(string(check), func(t *testing.T) {
+			t.Parallel()
+
+			if slices.Contains(s.skip, check) {
+				t.Skip("skipped for this provider")
+			}
+			checks[check](t)
+		})
+	}
+}
+
+// agentLoop checks CheckAgentLoop.
+func (s *suite) agentLoop(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{CompletionTools: true})
+	tools := []providers.Tool{weatherTool()}
+	messages := []providers.Message{
+		{Role: providers.RoleUser, Content: "What is the weather in Paris? Use the get_weather tool."},
+	}
+
+	resp := s.complete(t, provider, providers.CompletionParams{Messages: messages, Tools: tools, ToolChoice: "auto"})
+	call := requireToolCall(t, resp.Choices[0], toolGetWeather)
+	if call.ID == "" {
+		t.Fatal("tool call has no ID")
+	}
+
+	var args struct {
+		Location string `json:"location"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		t.Fatalf("tool arguments are not JSON: %v", err)
+	}
+	if !strings.Contains(strings.ToLower(args.Location), "paris") {
+		t.Fatalf("location is %q, want Paris", args.Location)
+	}
+
+	messages = append(messages, resp.Choices[0].Message, providers.Message{
+		Role:       providers.RoleTool,
+		Content:    weatherResult(args.Location),
+		ToolCallID: call.ID,
+	})
+
+	resp = s.complete(t, provider, providers.CompletionParams{Messages: messages, Tools: tools})
+	if got := resp.Choices[0].FinishReason; got != providers.FinishReasonStop {
+		t.Fatalf("finish reason is %q, want %q", got, providers.FinishReasonStop)
+	}
+	requireText(t, resp.Choices[0].Message, true)
+}
+
+// agentLoopMultipleParams checks CheckAgentLoopMultipleParams.
+func (s *suite) agentLoopMultipleParams(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{CompletionTools: true})
+	tools := []providers.Tool{calculatorTool()}
+	messages := []providers.Message{
+		{Role: providers.RoleUser, Content: "Use the calculate tool to add 15 and 27 together."},
+	}
+
+	resp := s.complete(t, provider, providers.CompletionParams{Messages: messages, Tools: tools, ToolChoice: "auto"})
+	if len(resp.Choices[0].Message.ToolCalls) == 0 {
+		t.Fatal("expected model to call the calculate tool")
+	}
+
+	call := resp.Choices[0].Message.ToolCalls[0]
+	if call.Function.Name != toolCalculate {
+		t.Fatalf("tool call name is %q, want %q", call.Function.Name, toolCalculate)
+	}
+
+	var args struct {
+		A         float64 `json:"a"`
+		B         float64 `json:"b"`
+		Operation string  `json:"operation"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		t.Fatalf("tool arguments are not JSON: %v", err)
+	}
+	if args.A != 15 || args.B != 27 || args.Operation != "add" {
+		t.Fatalf("tool arguments are %s, want a=15, b=27 and operation=add", call.Function.Arguments)
+	}
+
+	messages = append(messages, resp.Choices[0].Message, providers.Message{
+		Role:       providers.RoleTool,
+		Content:    calculatorResult(args.A, args.B, args.Operation),
+		ToolCallID: call.ID,
+	})
+
+	resp = s.complete(t, provider, providers.CompletionParams{Messages: messages, Tools: tools})
+	if content := resp.Choices[0].Message.ContentString(); !strings.Contains(content, "42") {
+		t.Fatalf("answer %q does not contain the tool result 42", content)
+	}
+}
+
+// authentication checks CheckAuthentication.
+func (s *suite) authentication(t *testing.T) {
+	opts := append(slices.Clone(s.options), config.WithAPIKey(invalidAPIKey))
+	provider, err := s.newProvider(opts...)
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	_, err = provider.Completion(context.Background(), s.params(providers.CompletionParams{
+		Messages: simpleMessages(),
+	}))
+	if !stderrors.Is(err, errors.ErrAuthentication) {
+		t.Fatalf("error is %v, want errors.ErrAuthentication", err)
+	}
+}
+
+// completion checks CheckCompletion.
+func (s *suite) completion(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{})
+
+	resp := s.complete(t, provider, providers.CompletionParams{Messages: simpleMessages()})
+	if resp.Object != objectChatCompletion {
+		t.Fatalf("object is %q, want %q", resp.Object, objectChatCompletion)
+	}
+	if got := resp.Choices[0].Message.Role; got != providers.RoleAssistant {
+		t.Fatalf("role is %q, want %q", got, providers.RoleAssistant)
+	}
+	requireText(t, resp.Choices[0].Message, false)
+	if resp.Usage == nil || resp.Usage.TotalTokens <= 0 {
+		t.Fatalf("usage is %+v, want total tokens", resp.Usage)
+	}
+}
+
+// conversation checks CheckConversation.
+func (s *suite) conversation(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{})
+
+	resp := s.complete(t, provider, providers.CompletionParams{Messages: conversationMessages()})
+	requireText(t, resp.Choices[0].Message, true)
+	if content := resp.Choices[0].Message.ContentString(); !strings.Contains(strings.ToLower(content), "alice") {
+		t.Fatalf("answer %q does not contain the name from an earlier turn", content)
+	}
+}
+
+// stream checks CheckStream.
+func (s *suite) stream(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{CompletionStreaming: true})
+
+	chunks, errs := provider.CompletionStream(context.Background(), s.params(providers.CompletionParams{
+		Messages: simpleMessages(),
+		Stream:   true,
+	}))
+
+	var content strings.Builder
+	var objects []string
+	for chunk := range chunks {
+		objects = append(objects, chunk.Object)
+		if len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	s.requireNoError(t, <-errs)
+
+	if len(objects) == 0 {
+		t.Fatal("stream has no chunks")
+	}
+	for i, object := range objects {
+		if object != objectChatCompletionChunk {
+			t.Fatalf("chunk %d object is %q, want %q", i, object, objectChatCompletionChunk)
+		}
+	}
+	if content.Len() == 0 {
+		t.Fatal("stream has no content")
+	}
+}
+
+// streamParity checks CheckStreamParity.
+func (s *suite) streamParity(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{CompletionStreaming: true})
+	params := s.params(providers.CompletionParams{Messages: simpleMessages()})
+
+	completion, err := provider.Completion(context.Background(), params)
+	s.requireNoError(t, err)
+
+	params.Stream = true
+	params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+	chunks, errs := provider.CompletionStream(context.Background(), params)
+
+	var acc providers.Accumulator
+	for chunk := range chunks {
+		acc.Add(chunk)
+	}
+	s.requireNoError(t, <-errs)
+
+	requireStreamParity(t, completion, acc.Completion())
+}
+
+// systemMessage checks CheckSystemMessage.
+func (s *suite) systemMessage(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{})
+
+	resp := s.complete(t, provider, providers.CompletionParams{Messages: systemMessages()})
+	requireText(t, resp.Choices[0].Message, false)
+}
+
+// tools checks CheckTools.
+func (s *suite) tools(t *testing.T) {
+	provider := s.provider(t, providers.Capabilities{CompletionTools: true})
+
+	resp := s.complete(t, provider, providers.CompletionParams{
+		Messages:   []providers.Message{{Role: providers.RoleUser, Content: "What is the weather in Paris?"}},
+		Tools:      []providers.Tool{weatherTool()},
+		ToolChoice: "auto",
+	})
+
+	choice := resp.Choices[0]
+	if len(choice.Message.ToolCalls) == 0 {
+		requireText(t, choice.Message, false)
+		return
+	}
+
+	call := requireToolCall(t, choice, toolGetWeather)
+	if !json.Valid([]byte(call.Function.Arguments)) {
+		t.Fatalf("tool arguments are not JSON: %s", call.Function.Arguments)
+	}
+	if !strings.Contains(strings.ToLower(call.Function.Arguments), "paris") {
+		t.Fatalf("tool arguments %s do not name Paris", call.Function.Arguments)
+	}
+}
+
+// complete sends params and checks that the response has an ID and one
+// choice.
+func (s *suite) complete(
+	t *testing.T,
+	provider providers.Provider,
+	params providers.CompletionParams,
+) *providers.ChatCompletion {
+	t.Helper()
+
+	resp, err := provider.Completion(context.Background(), s.params(params))
+	s.requireNoError(t, err)
+
+	if resp.ID == "" {
+		t.Fatal("completion has no ID")
+	}
+	if len(resp.Choices) != 1 {
+		t.Fatalf("completion has %d choices, want 1", len(resp.Choices))
+	}
+	return resp
+}
+
+// params returns params sent to the model under test.
+func (s *suite) params(params providers.CompletionParams) providers.CompletionParams {
+	params.Model = s.model
+	return params
+}
+
+// provider creates the provider under test, skipping t if the provider
+// reports capabilities without those required.
+func (s *suite) provider(t *testing.T, required providers.Capabilities) providers.Provider {
+	t.Helper()
+
+	provider, err := s.newProvider(s.options...)
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	cp, ok := providers.As[providers.CapabilityProvider](provider)
+	if !ok {
+		return provider
+	}
+
+	caps := cp.Capabilities()
+	if required.CompletionStreaming && !caps.CompletionStreaming {
+		t.Skip("provider does not support streaming")
+	}
+	if required.CompletionTools && !caps.CompletionTools {
+		t.Skip("provider does not support tools")
+	}
+	return provider
+}
+
+// requireNoError fails t if err is set, or skips it if the error matches
+// WithSkipOnError.
+func (s *suite) requireNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil && s.skipError != nil && s.skipError(err) {
+		t.Skipf("skipping on model error: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// allChecks returns every check, in the order Run runs them.
+func allChecks() []Check {
+	return []Check{
+		CheckAgentLoop,
+		CheckAgentLoopMultipleParams,
+		CheckAuthentication,
+		CheckCompletion,
+		CheckConversation,
+		CheckStream,
+		CheckStreamParity,
+		CheckSystemMessage,
+		CheckTools,
+	}
+}
+
+// requireStreamParity fails t unless streamed, a stream joined with
+// providers.Accumulator, has the shape of completion: the same choices, with
+// the same roles, finish reasons and tool call names, and content, reasoning,
+// tool call arguments that parse as JSON, and usage wherever completion has
+// them. The text itself is not compared, since the two come from separate
+// generations.
+func requireStreamParity(t *testing.T, completion *providers.ChatCompletion, streamed *providers.ChatCompletion) {
+	t.Helper()
+
+	if streamed.ID == "" {
+		t.Fatal("stream has no ID")
+	}
+	if streamed.Model != completion.Model {
+		t.Fatalf("stream model is %q, want %q", streamed.Model, completion.Model)
+	}
+	if (streamed.Usage != nil) != (completion.Usage != nil) {
+		t.Fatalf("stream usage is %+v, completion usage is %+v", streamed.Usage, completion.Usage)
+	}
+	if completion.Usage != nil && (streamed.Usage.PromptTokens <= 0 || streamed.Usage.CompletionTokens <= 0) {
+		t.Fatalf("stream usage is %+v, want prompt and completion tokens", streamed.Usage)
+	}
+
+	if len(streamed.Choices) != len(completion.Choices) {
+		t.Fatalf("stream has %d choices, want %d", len(streamed.Choices), len(completion.Choices))
+	}
+	for i, want := range completion.Choices {
+		got := streamed.Choices[i]
+		switch {
+		case got.Index != want.Index:
+			t.Fatalf("choice %d index is %d, want %d", i, got.Index, want.Index)
+		case got.Message.Role != want.Message.Role:
+			t.Fatalf("choice %d role is %q, want %q", i, got.Message.Role, want.Message.Role)
+		case got.FinishReason != want.FinishReason:
+			t.Fatalf("choice %d finish reason is %q, want %q", i, got.FinishReason, want.FinishReason)
+		case (got.Message.ContentString() != "") != (want.Message.ContentString() != ""):
+			t.Fatalf("choice %d content presence differs", i)
+		case (got.Message.Reasoning != nil) != (want.Message.Reasoning != nil):
+			t.Fatalf("choice %d reasoning presence differs", i)
+		case len(got.Message.ToolCalls) != len(want.Message.ToolCalls):
+			t.Fatalf("choice %d has %d tool calls, want %d", i, len(got.Message.ToolCalls), len(want.Message.ToolCalls))
+		}
+
+		for j, call := range want.Message.ToolCalls {
+			streamedCall := got.Message.ToolCalls[j]
+			switch {
+			case streamedCall.ID == "":
+				t.Fatalf("choice %d tool call %d has no ID", i, j)
+			case streamedCall.Function.Name != call.Function.Name:
+				t.Fatalf("choice %d tool call %d name is %q, want %q",
+					i, j, streamedCall.Function.Name, call.Function.Name)
+			case !json.Valid([]byte(streamedCall.Function.Arguments)):
+				t.Fatalf("choice %d tool call %d arguments are not JSON: %s", i, j, streamedCall.Function.Arguments)
+			}
+		}
+	}
+}
+
+// requireText fails t unless message has content, as a string when
+// onlyString is set.
+func requireText(t *testing.T, message providers.Message, onlyString bool) {
+	t.Helper()
+
+	if _, ok := message.Content.(string); onlyString && !ok {
+		t.Fatalf("message content is %T, want a string", message.Content)
+	}
+	if message.ContentString() == "" {
+		t.Fatal("message has no content")
+	}
+}
+
+// requireToolCall fails t unless choice finished by calling the tool name,
+// and returns the call.
+func requireToolCall(t *testing.T, choice providers.Choice, name string) providers.ToolCall {
+	t.Helper()
+
+	if len(choice.Message.ToolCalls) == 0 {
+		t.Fatalf("expected model to call the %s tool", name)
+	}
+	if choice.FinishReason != providers.FinishReasonToolCalls {
+		t.Fatalf("finish reason is %q, want %q", choice.FinishReason, providers.FinishReasonToolCalls)
+	}
+
+	call := choice.Message.ToolCalls[0]
+	if call.Function.Name != name {
+		t.Fatalf("tool call name is %q, want %q", call.Function.Name, name)
+	}
+	return call
+}
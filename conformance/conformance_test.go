@@ -0,0 +1,155 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// testRequest is the part of a chat completion request the fake server reads.
+type testRequest struct {
+	Messages []struct {
+		Content any    `json:"content"`
+		Role    string `json:"role"`
+	} `json:"messages"`
+	Model  string `json:"model"`
+	Stream bool   `json:"stream"`
+	Tools  []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// newFakeServer returns an OpenAI-compatible server whose scripted model
+// passes every check: it calls the offered tool, answers tool results, and
+// remembers names from earlier turns.
+func newFakeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer "+invalidAPIKey {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			body := `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`
+			_, _ = fmt.Fprint(w, body) // Write error surfaces in the client.
+			return
+		}
+
+		var req testRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		message, finishReason := fakeReply(req)
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{ // Write error surfaces in the client.
+				"id":      "chatcmpl-fake",
+				"object":  objectChatCompletion,
+				"created": 1700000000,
+				"model":   req.Model,
+				"choices": []any{map[string]any{"index": 0, "message": message, "finish_reason": finishReason}},
+				"usage":   map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []map[string]any{
+			{"choices": []any{map[string]any{"index": 0, "delta": fakeDelta(message)}}},
+			{"choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": finishReason}}},
+			{
+				"choices": []any{},
+				"usage":   map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+			},
+		} {
+			chunk["id"] = "chatcmpl-fake"
+			chunk["object"] = objectChatCompletionChunk
+			chunk["created"] = 1700000000
+			chunk["model"] = req.Model
+			data, err := json.Marshal(chunk)
+			require.NoError(t, err)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data) // Write error surfaces in the client.
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n") // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// fakeReply returns the scripted model's message and finish reason for req.
+func fakeReply(req testRequest) (map[string]any, string) {
+	last := req.Messages[len(req.Messages)-1]
+	if len(req.Tools) > 0 && last.Role == providers.RoleUser {
+		name := req.Tools[0].Function.Name
+		args := `{"location":"Paris"}`
+		if name == toolCalculate {
+			args = `{"a":15,"b":27,"operation":"add"}`
+		}
+		return map[string]any{
+			"role": providers.RoleAssistant,
+			"tool_calls": []any{map[string]any{
+				"id":       "call_1",
+				"type":     "function",
+				"function": map[string]any{"name": name, "arguments": args},
+			}},
+		}, providers.FinishReasonToolCalls
+	}
+
+	content := "Hello World"
+	switch {
+	case last.Role == providers.RoleTool:
+		content = "The answer is 42, and it is sunny."
+	case strings.Contains(fmt.Sprint(req.Messages), "Alice"):
+		content = "Your name is Alice."
+	}
+	return map[string]any{"role": providers.RoleAssistant, "content": content}, providers.FinishReasonStop
+}
+
+// fakeDelta returns message as a stream delta, giving its tool calls indexes.
+func fakeDelta(message map[string]any) map[string]any {
+	calls, ok := message["tool_calls"].([]any)
+	if !ok {
+		return message
+	}
+
+	delta := map[string]any{"role": message["role"]}
+	for i, call := range calls {
+		call.(map[string]any)["index"] = i
+	}
+	delta["tool_calls"] = calls
+	return delta
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeServer(t)
+
+	Run(t, func(opts ...config.Option) (providers.Provider, error) {
+		return openai.New(opts...)
+	}, "fake-model", WithProviderOptions(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL)))
+}
+
+func TestWithSkip(t *testing.T) {
+	t.Parallel()
+
+	err := WithSkip(CheckStream, "nonexistent")(&suite{})
+	require.ErrorContains(t, err, `unknown check "nonexistent"`)
+
+	s := &suite{}
+	require.NoError(t, WithSkip(CheckAuthentication)(s))
+	require.Equal(t, []Check{CheckAuthentication}, s.skip)
+}
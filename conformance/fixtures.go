@@ -0,0 +1,110 @@
+package conformance
+
+import (
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// calculatorResult returns the result the calculate tool would give for a
+// and b.
+func calculatorResult(a float64, b float64, operation string) string {
+	var result float64
+	switch operation {
+	case "add":
+		result = a + b
+	case "subtract":
+		result = a - b
+	case "multiply":
+		result = a * b
+	case "divide":
+		if b != 0 {
+			result = a / b
+		}
+	}
+
+	return fmt.Sprintf(`{"result": %g}`, result)
+}
+
+// calculatorTool returns a tool with several required parameters, so that
+// parameters dropped or reordered in conversion show up in its arguments.
+func calculatorTool() providers.Tool {
+	return providers.Tool{
+		Type: "function",
+		Function: providers.Function{
+			Name:        toolCalculate,
+			Description: "Perform a mathematical calculation on two numbers.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"a": map[string]any{
+						"type":        "number",
+						"description": "The first operand",
+					},
+					"b": map[string]any{
+						"type":        "number",
+						"description": "The second operand",
+					},
+					"operation": map[string]any{
+						"type":        "string",
+						"description": "The operation to perform",
+						"enum":        []string{"add", "subtract", "multiply", "divide"},
+					},
+				},
+				"required": []string{"a", "b", "operation"},
+			},
+		},
+	}
+}
+
+// conversationMessages returns a multi-turn conversation whose answer is
+// in an earlier turn.
+func conversationMessages() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleUser, Content: "My name is Alice."},
+		{Role: providers.RoleAssistant, Content: "Hello Alice! Nice to meet you."},
+		{Role: providers.RoleUser, Content: "What is my name?"},
+	}
+}
+
+// simpleMessages returns a single user message.
+func simpleMessages() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleUser, Content: "Say 'Hello World' exactly, nothing else."},
+	}
+}
+
+// systemMessages returns a user message after a system prompt.
+func systemMessages() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleSystem, Content: "You are a helpful assistant that follows instructions exactly."},
+		{Role: providers.RoleUser, Content: "Say 'Hello World' exactly, nothing else."},
+	}
+}
+
+// weatherResult returns the result the weather tool would give for
+// location.
+func weatherResult(location string) string {
+	return fmt.Sprintf(`{"location": %q, "temperature": 22, "unit": "celsius", "condition": "sunny"}`, location)
+}
+
+// weatherTool returns a tool that takes a location.
+func weatherTool() providers.Tool {
+	return providers.Tool{
+		Type: "function",
+		Function: providers.Function{
+			Name:        toolGetWeather,
+			Description: "Get the current weather for a location.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"location": map[string]any{
+						"type":        "string",
+						"description": "The city name, e.g. 'Paris, France'",
+					},
+				},
+				"required": []string{"location"},
+			},
+		},
+	}
+}
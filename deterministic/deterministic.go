@@ -0,0 +1,260 @@
+// Package deterministic wraps a provider to make its output as reproducible as
+// possible, for golden-file testing of LLM behavior. Requests are sent with
+// temperature 0, a fixed seed and parallel tool calls disabled, and a hash of
+// each response's content is recorded so runs can be compared.
+package deterministic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultSeed is the seed sent when WithSeed is not used.
+const defaultSeed = 42
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and forces deterministic sampling parameters.
+type Provider struct {
+	mu       sync.Mutex
+	provider providers.Provider
+	records  []Record
+	seed     int
+}
+
+// Record fingerprints one completion.
+type Record struct {
+	// ContentHash is the SHA-256 of the response content and tool calls, as
+	// returned by ContentHash. Streams hash the same as the equivalent completion.
+	ContentHash string
+
+	// Model is the model that served the request, as reported in the response.
+	Model string
+
+	// SystemFingerprint is the provider's backend fingerprint, when reported.
+	// A change explains drift that the seed cannot prevent.
+	SystemFingerprint string
+}
+
+// hashedChoice is the part of a choice covered by ContentHash.
+type hashedChoice struct {
+	Content   string                   `json:"content"`
+	ToolCalls []providers.FunctionCall `json:"toolCalls,omitempty"`
+}
+
+// New wraps provider so every request uses temperature 0, a fixed seed and,
+// when tools are given, no parallel tool calls. Top-p is cleared since it has
+// no effect at temperature 0 and some models reject it alongside temperature.
+// Providers that do not support a parameter ignore it.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{provider: provider, seed: defaultSeed}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithSeed sets the seed sent with every request. The default is 42.
+func WithSeed(seed int) Option {
+	return func(p *Provider) error {
+		p.seed = seed
+		return nil
+	}
+}
+
+// Completion performs a chat completion request with deterministic parameters
+// and records the response's content hash.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := p.provider.Completion(ctx, p.apply(params))
+	if err != nil {
+		return nil, err
+	}
+
+	p.record(Record{
+		ContentHash:       ContentHash(resp),
+		Model:             resp.Model,
+		SystemFingerprint: resp.SystemFingerprint,
+	})
+
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request with
+// deterministic parameters. The content hash is recorded when the stream
+// completes successfully.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		chunks, errs := p.provider.CompletionStream(ctx, p.apply(params))
+
+		choices := map[int]*hashedChoice{}
+		content := map[int]*strings.Builder{}
+		record := Record{}
+
+		for chunk := range chunks {
+			if chunk.Model != "" {
+				record.Model = chunk.Model
+			}
+			if chunk.SystemFingerprint != "" {
+				record.SystemFingerprint = chunk.SystemFingerprint
+			}
+			for _, choice := range chunk.Choices {
+				if _, ok := choices[choice.Index]; !ok {
+					choices[choice.Index] = &hashedChoice{}
+					content[choice.Index] = &strings.Builder{}
+				}
+				content[choice.Index].WriteString(choice.Delta.Content)
+				choices[choice.Index].ToolCalls = appendToolCallDeltas(
+					choices[choice.Index].ToolCalls,
+					choice.Delta.ToolCalls,
+				)
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			outErrs <- err
+			return
+		}
+
+		indexes := make([]int, 0, len(choices))
+		for i := range choices {
+			indexes = append(indexes, i)
+		}
+		slices.Sort(indexes)
+
+		hashed := make([]hashedChoice, 0, len(indexes))
+		for _, i := range indexes {
+			choices[i].Content = content[i].String()
+			hashed = append(hashed, *choices[i])
+		}
+
+		record.ContentHash = hashChoices(hashed)
+		p.record(record)
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Records returns the records of all completed requests, in completion order.
+func (p *Provider) Records() []Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.records)
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// apply returns params with the deterministic parameters set.
+func (p *Provider) apply(params providers.CompletionParams) providers.CompletionParams {
+	temperature := 0.0
+	seed := p.seed
+
+	params.Temperature = &temperature
+	params.TopP = nil
+	params.Seed = &seed
+
+	// Some APIs reject parallel_tool_calls on requests without tools.
+	if len(params.Tools) > 0 {
+		parallel := false
+		params.ParallelToolCalls = &parallel
+	}
+
+	return params
+}
+
+// record appends r to the records.
+func (p *Provider) record(r Record) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = append(p.records, r)
+}
+
+// ContentHash returns the hex SHA-256 of the content and tool calls of every
+// choice in resp. Tool call IDs and reasoning are excluded, since they vary
+// between otherwise identical responses.
+func ContentHash(resp *providers.ChatCompletion) string {
+	choices := slices.Clone(resp.Choices)
+	slices.SortStableFunc(choices, func(a, b providers.Choice) int {
+		return a.Index - b.Index
+	})
+
+	hashed := make([]hashedChoice, len(choices))
+	for i, choice := range choices {
+		hashed[i].Content = choice.Message.ContentString()
+		for _, call := range choice.Message.ToolCalls {
+			hashed[i].ToolCalls = append(hashed[i].ToolCalls, call.Function)
+		}
+	}
+
+	return hashChoices(hashed)
+}
+
+// appendToolCallDeltas merges streamed tool call fragments into calls. A
+// fragment with an ID starts a new call; others extend the last one.
+func appendToolCallDeltas(calls []providers.FunctionCall, deltas []providers.ToolCall) []providers.FunctionCall {
+	for _, delta := range deltas {
+		if delta.ID != "" || len(calls) == 0 {
+			calls = append(calls, delta.Function)
+			continue
+		}
+
+		last := &calls[len(calls)-1]
+		last.Name += delta.Function.Name
+		last.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+// hashChoices returns the hex SHA-256 of the JSON encoding of choices.
+func hashChoices(choices []hashedChoice) string {
+	// Encoding strings and slices of strings cannot fail.
+	data, _ := json.Marshal(choices)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
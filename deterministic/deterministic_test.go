@@ -0,0 +1,197 @@
+package deterministic
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// toolCallCompletion returns a completion that calls get_weather for Paris.
+func toolCallCompletion(id string) *providers.ChatCompletion {
+	return testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+	}})
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("forces deterministic parameters", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var got []providers.CompletionParams
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, params)
+			return testutil.MockChatCompletion("Hello!"), nil
+		}
+
+		provider, err := New(mock, WithSeed(7))
+		require.NoError(t, err)
+
+		temperature := 0.9
+		topP := 0.5
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{
+			Messages:    testutil.SimpleMessages(),
+			Temperature: &temperature,
+			TopP:        &topP,
+		})
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			Tools:    []providers.Tool{testutil.WeatherTool()},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, got, 2)
+		require.Equal(t, 0.0, *got[0].Temperature)
+		require.Nil(t, got[0].TopP)
+		require.Equal(t, 7, *got[0].Seed)
+		require.Nil(t, got[0].ParallelToolCalls)
+		require.False(t, *got[1].ParallelToolCalls)
+
+		// The caller's params are not modified.
+		require.Equal(t, 0.9, temperature)
+	})
+
+	t.Run("uses the default seed", func(t *testing.T) {
+		t.Parallel()
+
+		var seed int
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			seed = *params.Seed
+			return testutil.MockChatCompletion("Hello!"), nil
+		}
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, defaultSeed, seed)
+	})
+
+	t.Run("records content hashes", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			calls++
+			resp := toolCallCompletion(fmt.Sprintf("call_%d", calls))
+			resp.SystemFingerprint = "fp_1"
+			return resp, nil
+		}
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err := provider.Completion(context.Background(), providers.CompletionParams{
+				Messages: testutil.SimpleMessages(),
+			})
+			require.NoError(t, err)
+		}
+
+		records := provider.Records()
+		require.Len(t, records, 2)
+		require.Len(t, records[0].ContentHash, 64)
+		require.Equal(t, "fp_1", records[0].SystemFingerprint)
+
+		// Tool call IDs differ between calls but do not affect the hash.
+		require.Equal(t, records[0].ContentHash, records[1].ContentHash)
+	})
+
+	t.Run("does not record errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, stderrors.New("boom")
+		}
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.EqualError(t, err, "boom")
+		require.Empty(t, provider.Records())
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		_ context.Context,
+		params providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 3)
+		errs := make(chan error)
+		require.Equal(t, defaultSeed, *params.Seed)
+
+		chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+			ToolCalls: []providers.ToolCall{{ID: "call_stream", Type: "function", Function: providers.FunctionCall{
+				Name:      "get_weather",
+				Arguments: `{"location":`,
+			}}},
+		}}}}
+		chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+			ToolCalls: []providers.ToolCall{{Function: providers.FunctionCall{Arguments: `"Paris"}`}}},
+		}}}}
+		chunks <- providers.ChatCompletionChunk{
+			Choices:           []providers.ChunkChoice{{FinishReason: providers.FinishReasonToolCalls}},
+			SystemFingerprint: "fp_2",
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	provider, err := New(mock)
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	})
+	count := 0
+	for range chunks {
+		count++
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, 3, count)
+
+	records := provider.Records()
+	require.Len(t, records, 1)
+	require.Equal(t, "fp_2", records[0].SystemFingerprint)
+
+	// The stream hashes the same as the equivalent completion.
+	require.Equal(t, ContentHash(toolCallCompletion("call_other")), records[0].ContentHash)
+}
+
+func TestContentHash(t *testing.T) {
+	t.Parallel()
+
+	hello := testutil.MockChatCompletion("Hello!")
+	other := testutil.MockChatCompletion("Hello!")
+	other.Choices[0].Message.Content = "Something else"
+
+	require.Equal(t, ContentHash(hello), ContentHash(testutil.MockChatCompletion("Hello!")))
+	require.NotEqual(t, ContentHash(hello), ContentHash(other))
+	require.NotEqual(t, ContentHash(hello), ContentHash(toolCallCompletion("call_1")))
+}
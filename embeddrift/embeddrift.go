@@ -0,0 +1,247 @@
+// Package embeddrift compares two embedding models on the same inputs. It is
+// meant to catch a silent change of the model behind an existing vector index,
+// which would leave new embeddings incomparable with the indexed ones.
+//
+// Embeddings from different models live in different spaces, so they cannot be
+// compared directly. Instead, the cosine similarity of every pair of inputs is
+// computed under each model, and the two sets of similarities are correlated:
+// models that agree on which inputs are alike correlate near 1. When both
+// models have the same number of dimensions, each input's two embeddings are
+// also compared directly, which catches a changed model behind the same name.
+package embeddrift
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// batchSize is how many inputs are embedded per request.
+const batchSize = 64
+
+// minInputs is the fewest inputs that give a meaningful correlation.
+const minInputs = 3
+
+// Embedder is an embedding model on a provider.
+type Embedder struct {
+	// Model is the embedding model.
+	Model string
+
+	// Provider serves Model.
+	Provider providers.EmbeddingProvider
+}
+
+// Report is the result of a comparison.
+type Report struct {
+	// Aligned reports whether both models' embeddings have the same number of
+	// dimensions. Only then are MeanCosine, MinCosine and MinCosineInput set;
+	// otherwise they are zero.
+	Aligned bool `json:"aligned"`
+
+	// Correlation is the Pearson correlation between the pairwise cosine
+	// similarities of the inputs under each model. Near 1, the models agree on
+	// which inputs are alike; a drop means one of them changed. It is 0 when
+	// either model gives every pair the same similarity.
+	Correlation float64 `json:"correlation"`
+
+	// DimensionsA is the number of dimensions of the first model's embeddings.
+	DimensionsA int `json:"dimensionsA"`
+
+	// DimensionsB is the number of dimensions of the second model's embeddings.
+	DimensionsB int `json:"dimensionsB"`
+
+	// Inputs is the number of inputs compared.
+	Inputs int `json:"inputs"`
+
+	// MeanCosine is the mean cosine similarity between each input's two
+	// embeddings. It is near 1 when both are the same model.
+	MeanCosine float64 `json:"meanCosine"`
+
+	// MinCosine is the lowest cosine similarity between an input's two
+	// embeddings.
+	MinCosine float64 `json:"minCosine"`
+
+	// MinCosineInput is the index of the input with the lowest cosine
+	// similarity.
+	MinCosineInput int `json:"minCosineInput"`
+}
+
+// Compare embeds inputs with a and b, in parallel, and compares the results.
+// It needs at least three inputs; a few dozen varied texts, like those in the
+// index, give a stable correlation.
+func Compare(ctx context.Context, a Embedder, b Embedder, inputs []string) (*Report, error) {
+	if a.Provider == nil || b.Provider == nil {
+		return nil, fmt.Errorf("embedding providers are required")
+	}
+	if a.Model == "" || b.Model == "" {
+		return nil, fmt.Errorf("embedding models are required")
+	}
+	if len(inputs) < minInputs {
+		return nil, fmt.Errorf("at least %d inputs are required, got %d", minInputs, len(inputs))
+	}
+
+	var (
+		embeddingsA, embeddingsB [][]float64
+		errA, errB               error
+		wg                       sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		embeddingsA, errA = embed(ctx, a, inputs)
+	}()
+	go func() {
+		defer wg.Done()
+		embeddingsB, errB = embed(ctx, b, inputs)
+	}()
+	wg.Wait()
+
+	if errA != nil {
+		return nil, errA
+	}
+	if errB != nil {
+		return nil, errB
+	}
+
+	return CompareEmbeddings(embeddingsA, embeddingsB)
+}
+
+// CompareEmbeddings compares two models' embeddings of the same inputs, in the
+// same order. Use it to compare embeddings stored in an index with fresh ones
+// of the same texts.
+func CompareEmbeddings(a [][]float64, b [][]float64) (*Report, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("got %d and %d embeddings, want the same number", len(a), len(b))
+	}
+	if len(a) < minInputs {
+		return nil, fmt.Errorf("at least %d inputs are required, got %d", minInputs, len(a))
+	}
+
+	dimsA, err := dimensions(a)
+	if err != nil {
+		return nil, fmt.Errorf("first embeddings: %w", err)
+	}
+	dimsB, err := dimensions(b)
+	if err != nil {
+		return nil, fmt.Errorf("second embeddings: %w", err)
+	}
+
+	report := &Report{
+		Aligned:     dimsA == dimsB,
+		Correlation: pearson(pairwise(a), pairwise(b)),
+		DimensionsA: dimsA,
+		DimensionsB: dimsB,
+		Inputs:      len(a),
+	}
+
+	if report.Aligned {
+		report.MinCosine = math.Inf(1)
+		var sum float64
+		for i := range a {
+			c := cosine(a[i], b[i])
+			sum += c
+			if c < report.MinCosine {
+				report.MinCosine = c
+				report.MinCosineInput = i
+			}
+		}
+		report.MeanCosine = sum / float64(len(a))
+	}
+
+	return report, nil
+}
+
+// cosine returns the cosine similarity of x and y, which have the same length,
+// or 0 if either is zero.
+func cosine(x []float64, y []float64) float64 {
+	var dot, normX, normY float64
+	for i := range x {
+		dot += x[i] * y[i]
+		normX += x[i] * x[i]
+		normY += y[i] * y[i]
+	}
+	if normX == 0 || normY == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normX*normY)
+}
+
+// dimensions returns the length of embeddings, which must all be the same.
+func dimensions(embeddings [][]float64) (int, error) {
+	dims := len(embeddings[0])
+	if dims == 0 {
+		return 0, fmt.Errorf("embedding 0 is empty")
+	}
+	for i, e := range embeddings {
+		if len(e) != dims {
+			return 0, fmt.Errorf("embedding %d has %d dimensions, want %d", i, len(e), dims)
+		}
+	}
+	return dims, nil
+}
+
+// embed returns the embeddings of inputs by e, in order.
+func embed(ctx context.Context, e Embedder, inputs []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(inputs))
+
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+
+		resp, err := e.Provider.Embedding(ctx, providers.EmbeddingParams{Model: e.Model, Input: inputs[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != end-start {
+			return nil, errors.NewProviderError(e.Provider.Name(),
+				fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), end-start))
+		}
+
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= end-start {
+				return nil, errors.NewProviderError(e.Provider.Name(), fmt.Errorf("embedding index %d out of range", data.Index))
+			}
+			embeddings[start+data.Index] = data.Embedding
+		}
+	}
+
+	return embeddings, nil
+}
+
+// pairwise returns the cosine similarity of every pair of embeddings.
+func pairwise(embeddings [][]float64) []float64 {
+	sims := make([]float64, 0, len(embeddings)*(len(embeddings)-1)/2)
+	for i := range embeddings {
+		for j := i + 1; j < len(embeddings); j++ {
+			sims = append(sims, cosine(embeddings[i], embeddings[j]))
+		}
+	}
+	return sims
+}
+
+// pearson returns the Pearson correlation of x and y, which have the same
+// length, or 0 if either does not vary.
+func pearson(x []float64, y []float64) float64 {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,205 @@
+package embeddrift
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testInputs are varied enough that their pairwise similarities differ.
+var testInputs = []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff"}
+
+// lengthEmbedding embeds text by its length, so longer texts point further
+// from the first axis.
+func lengthEmbedding(text string) []float64 {
+	angle := float64(len(text)) / 4
+	return []float64{math.Cos(angle), math.Sin(angle)}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports no drift for the same model", func(t *testing.T) {
+		t.Parallel()
+
+		a := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+		b := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+
+		report, err := Compare(context.Background(), a, b, testInputs)
+		require.NoError(t, err)
+		require.True(t, report.Aligned)
+		require.InDelta(t, 1, report.Correlation, 1e-9)
+		require.InDelta(t, 1, report.MeanCosine, 1e-9)
+		require.InDelta(t, 1, report.MinCosine, 1e-9)
+		require.Equal(t, 2, report.DimensionsA)
+		require.Equal(t, len(testInputs), report.Inputs)
+	})
+
+	t.Run("correlates models with different spaces", func(t *testing.T) {
+		t.Parallel()
+
+		// The same geometry, rotated and with an extra dimension.
+		rotated := func(text string) []float64 {
+			e := lengthEmbedding(text)
+			return []float64{e[1], -e[0], 0}
+		}
+		a := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+		b := Embedder{Model: "other", Provider: testutil.NewEmbeddingMock(rotated)}
+
+		report, err := Compare(context.Background(), a, b, testInputs)
+		require.NoError(t, err)
+		require.False(t, report.Aligned)
+		require.InDelta(t, 1, report.Correlation, 1e-9)
+		require.Zero(t, report.MeanCosine)
+		require.Equal(t, 3, report.DimensionsB)
+	})
+
+	t.Run("detects a changed model", func(t *testing.T) {
+		t.Parallel()
+
+		// Same dimensions, but similarity no longer follows length.
+		changed := func(text string) []float64 {
+			angle := float64(len(text)%2) * 2
+			return []float64{math.Cos(angle), math.Sin(angle)}
+		}
+		a := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+		b := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(changed)}
+
+		report, err := Compare(context.Background(), a, b, testInputs)
+		require.NoError(t, err)
+		require.True(t, report.Aligned)
+		require.Less(t, report.Correlation, 0.5)
+		require.Less(t, report.MinCosine, 0.5)
+	})
+
+	t.Run("embeds in batches", func(t *testing.T) {
+		t.Parallel()
+
+		inputs := make([]string, 70)
+		for i := range inputs {
+			inputs[i] = fmt.Sprintf("%*s", i+1, "x")
+		}
+		mock := testutil.NewEmbeddingMock(lengthEmbedding)
+		a := Embedder{Model: "embed-v1", Provider: mock}
+		b := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+
+		report, err := Compare(context.Background(), a, b, inputs)
+		require.NoError(t, err)
+		require.InDelta(t, 1, report.MinCosine, 1e-9)
+		require.Len(t, mock.EmbeddingCalls, 2)
+		require.Len(t, mock.EmbeddingCalls[1].Input, 6)
+	})
+
+	t.Run("returns embedding errors", func(t *testing.T) {
+		t.Parallel()
+
+		failure := stderrors.New("model not found")
+		failing := testutil.NewMockProvider()
+		failing.EmbeddingFunc = func(context.Context, providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			return nil, failure
+		}
+		a := Embedder{Model: "embed-v1", Provider: testutil.NewEmbeddingMock(lengthEmbedding)}
+		b := Embedder{Model: "embed-v2", Provider: failing}
+
+		_, err := Compare(context.Background(), a, b, testInputs)
+		require.ErrorIs(t, err, failure)
+	})
+
+	t.Run("rejects short responses", func(t *testing.T) {
+		t.Parallel()
+
+		short := testutil.NewMockProvider()
+		short.EmbeddingFunc = func(context.Context, providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			return &providers.EmbeddingResponse{Data: []providers.EmbeddingData{{Embedding: []float64{1}}}}, nil
+		}
+		a := Embedder{Model: "embed-v1", Provider: short}
+
+		_, err := Compare(context.Background(), a, a, testInputs)
+		require.ErrorContains(t, err, "got 1 embeddings for 6 inputs")
+	})
+
+	t.Run("validates its arguments", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewEmbeddingMock(lengthEmbedding)
+
+		_, err := Compare(context.Background(), Embedder{Model: "m"}, Embedder{Model: "m", Provider: mock}, testInputs)
+		require.EqualError(t, err, "embedding providers are required")
+
+		_, err = Compare(context.Background(), Embedder{Provider: mock}, Embedder{Model: "m", Provider: mock}, testInputs)
+		require.EqualError(t, err, "embedding models are required")
+
+		_, err = Compare(context.Background(), Embedder{Model: "m", Provider: mock}, Embedder{Model: "m", Provider: mock}, testInputs[:2])
+		require.EqualError(t, err, "at least 3 inputs are required, got 2")
+	})
+}
+
+func TestCompareEmbeddings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		a       [][]float64
+		b       [][]float64
+		wantErr string
+	}{
+		{
+			name:    "rejects different counts",
+			a:       [][]float64{{1}, {2}, {3}},
+			b:       [][]float64{{1}, {2}},
+			wantErr: "got 3 and 2 embeddings, want the same number",
+		},
+		{
+			name:    "rejects ragged embeddings",
+			a:       [][]float64{{1, 0}, {0, 1}, {1}},
+			b:       [][]float64{{1}, {2}, {3}},
+			wantErr: "first embeddings: embedding 2 has 1 dimensions, want 2",
+		},
+		{
+			name:    "rejects empty embeddings",
+			a:       [][]float64{{1}, {2}, {3}},
+			b:       [][]float64{{}, {}, {}},
+			wantErr: "second embeddings: embedding 0 is empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := CompareEmbeddings(tc.a, tc.b)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("points at the input that moved most", func(t *testing.T) {
+		t.Parallel()
+
+		a := [][]float64{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+		b := [][]float64{{1, 0}, {0, 1}, {-1, -1}, {1, -1}}
+
+		report, err := CompareEmbeddings(a, b)
+		require.NoError(t, err)
+		require.Equal(t, 2, report.MinCosineInput)
+		require.InDelta(t, -1, report.MinCosine, 1e-9)
+		require.InDelta(t, 0.5, report.MeanCosine, 1e-9)
+	})
+
+	t.Run("reports no correlation for constant similarities", func(t *testing.T) {
+		t.Parallel()
+
+		a := [][]float64{{1, 0}, {0, 1}, {1, 1}}
+		b := [][]float64{{1, 0}, {1, 0}, {1, 0}}
+
+		report, err := CompareEmbeddings(a, b)
+		require.NoError(t, err)
+		require.Zero(t, report.Correlation)
+	})
+}
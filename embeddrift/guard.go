@@ -0,0 +1,197 @@
+package embeddrift
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// ErrIncompatible reports embeddings from a different vector space than the
+// one a Guard is pinned to.
+var ErrIncompatible = stderrors.New("embeddrift: incompatible embeddings")
+
+// Ensure GuardedProvider implements the required interfaces.
+var _ providers.EmbeddingProvider = (*GuardedProvider)(nil)
+
+// Guard refuses embeddings from any vector space but one, so that vectors from
+// different models or with different dimensions are never mixed in an index.
+// It is safe for concurrent use.
+type Guard struct {
+	mu    sync.Mutex
+	space Space
+}
+
+// GuardedProvider wraps an embedding provider so that every response is
+// checked by a Guard.
+type GuardedProvider struct {
+	guard    *Guard
+	provider providers.EmbeddingProvider
+}
+
+// Space identifies the vector space embeddings live in. Embeddings are only
+// comparable with others from the same model with the same number of
+// dimensions. Store it with an index, and pin a Guard to it when the index is
+// reopened.
+type Space struct {
+	// Dimensions is the number of dimensions of the embeddings.
+	Dimensions int `json:"dimensions"`
+
+	// Model is the embedding model.
+	Model string `json:"model"`
+
+	// Provider, if set, is the name of the provider that serves Model. It is
+	// only compared when both spaces name one, so that the same model may be
+	// served by another provider.
+	Provider string `json:"provider,omitempty"`
+}
+
+// NewGuard returns a Guard pinned to space. A zero space pins the Guard to the
+// first embeddings it checks.
+func NewGuard(space Space) *Guard {
+	return &Guard{space: space}
+}
+
+// Check returns an error wrapping ErrIncompatible if space is not the one g
+// is pinned to, or pins g to it if g is not pinned yet.
+func (g *Guard) Check(space Space) error {
+	if space.Model == "" || space.Dimensions <= 0 {
+		return fmt.Errorf("%w: %s does not name its model and dimensions", ErrIncompatible, space)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.space == (Space{}) {
+		g.space = space
+		return nil
+	}
+	if !g.space.matches(space) {
+		return fmt.Errorf("%w: the index holds %s, got %s", ErrIncompatible, g.space, space)
+	}
+	return nil
+}
+
+// CheckResponse checks the space of resp as Check does, after checking that
+// all of its embeddings have the same number of dimensions. A response
+// without embeddings passes.
+func (g *Guard) CheckResponse(resp *providers.EmbeddingResponse) error {
+	if len(resp.Data) == 0 {
+		return nil
+	}
+
+	space, err := SpaceOf(resp)
+	if err != nil {
+		return err
+	}
+	return g.Check(space)
+}
+
+// Space returns the space g is pinned to, and whether it is pinned yet.
+func (g *Guard) Space() (Space, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.space, g.space != (Space{})
+}
+
+// Wrap returns provider with every embedding response checked by g.
+func (g *Guard) Wrap(provider providers.EmbeddingProvider) *GuardedProvider {
+	return &GuardedProvider{guard: g, provider: provider}
+}
+
+// Completion performs a chat completion request on the wrapped provider.
+func (p *GuardedProvider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	return p.provider.Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request on the
+// wrapped provider.
+func (p *GuardedProvider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	return p.provider.CompletionStream(ctx, params)
+}
+
+// Embedding performs an embedding request on the wrapped provider, and
+// returns an error wrapping ErrIncompatible instead of embeddings from
+// another space. A response that does not name its model is taken to be from
+// the requested one.
+func (p *GuardedProvider) Embedding(
+	ctx context.Context,
+	params providers.EmbeddingParams,
+) (*providers.EmbeddingResponse, error) {
+	resp, err := p.provider.Embedding(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Model == "" {
+		resp.Model = params.Model
+	}
+	if err := p.guard.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Name returns the wrapped provider's name.
+func (p *GuardedProvider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *GuardedProvider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// String returns the space as "model on provider (n dimensions)".
+func (s Space) String() string {
+	model := s.Model
+	if model == "" {
+		model = "an unnamed model"
+	}
+	if s.Provider != "" {
+		model += " on " + s.Provider
+	}
+	return fmt.Sprintf("%s (%d dimensions)", model, s.Dimensions)
+}
+
+// matches reports whether embeddings from other are comparable with those
+// from s.
+func (s Space) matches(other Space) bool {
+	if s.Model != other.Model || s.Dimensions != other.Dimensions {
+		return false
+	}
+	return s.Provider == "" || other.Provider == "" || s.Provider == other.Provider
+}
+
+// SpaceOf returns the space of the embeddings in resp, which must all have
+// the same number of dimensions. Dimensions are taken from the embeddings if
+// the provider did not report them.
+func SpaceOf(resp *providers.EmbeddingResponse) (Space, error) {
+	if len(resp.Data) == 0 {
+		return Space{}, fmt.Errorf("response has no embeddings")
+	}
+
+	embeddings := make([][]float64, len(resp.Data))
+	for i, d := range resp.Data {
+		embeddings[i] = d.Embedding
+	}
+	dims, err := dimensions(embeddings)
+	if err != nil {
+		return Space{}, fmt.Errorf("%w: %w", ErrIncompatible, err)
+	}
+	if resp.Dimensions != 0 && resp.Dimensions != dims {
+		return Space{}, fmt.Errorf("%w: response reports %d dimensions, embeddings have %d",
+			ErrIncompatible, resp.Dimensions, dims)
+	}
+
+	return Space{Dimensions: dims, Model: resp.Model, Provider: resp.Provider}, nil
+}
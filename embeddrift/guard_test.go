@@ -0,0 +1,144 @@
+package embeddrift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// embeddingResponse returns a response from model on provider with the given
+// embeddings.
+func embeddingResponse(model string, provider string, embeddings ...[]float64) *providers.EmbeddingResponse {
+	resp := &providers.EmbeddingResponse{Model: model, Provider: provider}
+	for i, e := range embeddings {
+		resp.Data = append(resp.Data, providers.EmbeddingData{Embedding: e, Index: i})
+	}
+	return resp
+}
+
+func TestGuardCheck(t *testing.T) {
+	t.Parallel()
+
+	pinned := Space{Dimensions: 3, Model: "embed-v1", Provider: "openai"}
+
+	tests := []struct {
+		name    string
+		space   Space
+		wantErr string
+	}{
+		{name: "same space", space: pinned},
+		{name: "same model on an unnamed provider", space: Space{Dimensions: 3, Model: "embed-v1"}},
+		{
+			name:  "other model",
+			space: Space{Dimensions: 3, Model: "embed-v2", Provider: "openai"},
+			wantErr: "embeddrift: incompatible embeddings: the index holds embed-v1 on openai (3 dimensions), " +
+				"got embed-v2 on openai (3 dimensions)",
+		},
+		{
+			name:  "other dimensions",
+			space: Space{Dimensions: 2, Model: "embed-v1", Provider: "openai"},
+			wantErr: "embeddrift: incompatible embeddings: the index holds embed-v1 on openai (3 dimensions), " +
+				"got embed-v1 on openai (2 dimensions)",
+		},
+		{
+			name:  "other provider",
+			space: Space{Dimensions: 3, Model: "embed-v1", Provider: "ollama"},
+			wantErr: "embeddrift: incompatible embeddings: the index holds embed-v1 on openai (3 dimensions), " +
+				"got embed-v1 on ollama (3 dimensions)",
+		},
+		{
+			name:    "unnamed model",
+			space:   Space{Dimensions: 3},
+			wantErr: "embeddrift: incompatible embeddings: an unnamed model (3 dimensions) does not name its model and dimensions",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewGuard(pinned).Check(tc.space)
+			if tc.wantErr != "" {
+				require.ErrorIs(t, err, ErrIncompatible)
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGuardCheckResponse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pins to the first response", func(t *testing.T) {
+		t.Parallel()
+
+		g := NewGuard(Space{})
+		_, ok := g.Space()
+		require.False(t, ok)
+
+		require.NoError(t, g.CheckResponse(embeddingResponse("embed-v1", "", []float64{1, 0})))
+		space, ok := g.Space()
+		require.True(t, ok)
+		require.Equal(t, Space{Dimensions: 2, Model: "embed-v1"}, space)
+
+		require.NoError(t, g.CheckResponse(embeddingResponse("embed-v1", "openai", []float64{0, 1})))
+		require.ErrorIs(t, g.CheckResponse(embeddingResponse("embed-v2", "", []float64{0, 1})), ErrIncompatible)
+	})
+
+	t.Run("refuses mixed dimensions within a response", func(t *testing.T) {
+		t.Parallel()
+
+		err := NewGuard(Space{}).CheckResponse(embeddingResponse("embed-v1", "", []float64{1, 0}, []float64{1}))
+		require.ErrorIs(t, err, ErrIncompatible)
+		require.EqualError(t, err, "embeddrift: incompatible embeddings: embedding 1 has 1 dimensions, want 2")
+	})
+
+	t.Run("refuses reported dimensions that do not match", func(t *testing.T) {
+		t.Parallel()
+
+		resp := embeddingResponse("embed-v1", "", []float64{1, 0})
+		resp.Dimensions = 3
+		require.ErrorIs(t, NewGuard(Space{}).CheckResponse(resp), ErrIncompatible)
+	})
+
+	t.Run("passes a response without embeddings", func(t *testing.T) {
+		t.Parallel()
+
+		g := NewGuard(Space{})
+		require.NoError(t, g.CheckResponse(embeddingResponse("embed-v1", "")))
+		_, ok := g.Space()
+		require.False(t, ok)
+	})
+}
+
+func TestGuardedProvider(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.EmbeddingFunc = func(_ context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+		if params.Model == "wide" {
+			return embeddingResponse("", "", []float64{1, 0, 0, 0}), nil
+		}
+		return embeddingResponse("", "", []float64{1, 0, 0}), nil
+	}
+	provider := NewGuard(Space{}).Wrap(mock)
+	require.Equal(t, "mock", provider.Name())
+	require.Equal(t, mock, provider.Unwrap())
+
+	resp, err := provider.Embedding(context.Background(), providers.EmbeddingParams{Input: "a", Model: "narrow"})
+	require.NoError(t, err)
+	require.Equal(t, "narrow", resp.Model)
+
+	_, err = provider.Embedding(context.Background(), providers.EmbeddingParams{Input: "a", Model: "wide"})
+	require.ErrorIs(t, err, ErrIncompatible)
+
+	resp, err = provider.Embedding(context.Background(), providers.EmbeddingParams{Input: "b", Model: "narrow"})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+}
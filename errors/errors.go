@@ -3,6 +3,7 @@ package errors
 import (
 	stderrors "errors"
 	"fmt"
+	"net/http"
 )
 
 // Error codes used in BaseError.Code field.
@@ -14,6 +15,8 @@ const (
 	CodeContentFilter       = "content_filter"
 	CodeModelNotFound       = "model_not_found"
 	CodeProviderError       = "provider_error"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeQueueTimeout        = "queue_timeout"
 	CodeMissingAPIKey       = "missing_api_key"
 	CodeUnsupportedProvider = "unsupported_provider"
 	CodeUnsupportedParam    = "unsupported_parameter"
@@ -28,6 +31,8 @@ var (
 	ErrContentFilter       = stderrors.New("content filtered")
 	ErrModelNotFound       = stderrors.New("model not found")
 	ErrProvider            = stderrors.New("provider error")
+	ErrQuotaExceeded       = stderrors.New("quota exceeded")
+	ErrQueueTimeout        = stderrors.New("queue timeout")
 	ErrMissingAPIKey       = stderrors.New("missing API key")
 	ErrUnsupportedProvider = stderrors.New("unsupported provider")
 	ErrUnsupportedParam    = stderrors.New("unsupported parameter")
@@ -77,6 +82,19 @@ type RateLimitError struct {
 	RetryAfter int // Seconds until retry is allowed, if known
 }
 
+// QuotaExceededError is returned when the account has run out of quota or credits.
+// Unlike RateLimitError, retrying will not help until billing is resolved.
+type QuotaExceededError struct {
+	BaseError
+}
+
+// QueueTimeoutError is returned when a request could not start because the
+// provider's concurrency limit was reached and the request queue was full or
+// the wait for a free slot timed out. The request was never sent.
+type QueueTimeoutError struct {
+	BaseError
+}
+
 // AuthenticationError is returned when authentication fails.
 type AuthenticationError struct {
 	BaseError
@@ -85,6 +103,7 @@ type AuthenticationError struct {
 // InvalidRequestError is returned when the request is malformed.
 type InvalidRequestError struct {
 	BaseError
+	Param string // The offending parameter name, if known
 }
 
 // ContextLengthError is returned when the context exceeds the model's limit.
@@ -137,6 +156,30 @@ func NewRateLimitError(provider string, err error) *RateLimitError {
 	}
 }
 
+// NewQuotaExceededError creates a new QuotaExceededError.
+func NewQuotaExceededError(provider string, err error) *QuotaExceededError {
+	return &QuotaExceededError{
+		BaseError: BaseError{
+			Code:     CodeQuotaExceeded,
+			Provider: provider,
+			Err:      err,
+			sentinel: ErrQuotaExceeded,
+		},
+	}
+}
+
+// NewQueueTimeoutError creates a new QueueTimeoutError.
+func NewQueueTimeoutError(provider string, err error) *QueueTimeoutError {
+	return &QueueTimeoutError{
+		BaseError: BaseError{
+			Code:     CodeQueueTimeout,
+			Provider: provider,
+			Err:      err,
+			sentinel: ErrQueueTimeout,
+		},
+	}
+}
+
 // NewAuthenticationError creates a new AuthenticationError.
 func NewAuthenticationError(provider string, err error) *AuthenticationError {
 	return &AuthenticationError{
@@ -161,6 +204,13 @@ func NewInvalidRequestError(provider string, err error) *InvalidRequestError {
 	}
 }
 
+// NewInvalidParamError creates a new InvalidRequestError for an invalid parameter value.
+func NewInvalidParamError(provider string, param string, err error) *InvalidRequestError {
+	e := NewInvalidRequestError(provider, err)
+	e.Param = param
+	return e
+}
+
 // NewContextLengthError creates a new ContextLengthError.
 func NewContextLengthError(provider string, err error) *ContextLengthError {
 	return &ContextLengthError{
@@ -249,3 +299,23 @@ func NewUnsupportedParamError(provider string, param string) *UnsupportedParamEr
 		Param: param,
 	}
 }
+
+// FromStatusCode returns the unified error type for an HTTP status code.
+// It is intended for native endpoints called without a provider SDK; statuses
+// without a more specific mapping become a ProviderError carrying the status code.
+func FromStatusCode(provider string, statusCode int, err error) error {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return NewInvalidRequestError(provider, err)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return NewAuthenticationError(provider, err)
+	case http.StatusPaymentRequired:
+		return NewQuotaExceededError(provider, err)
+	case http.StatusTooManyRequests:
+		return NewRateLimitError(provider, err)
+	default:
+		providerErr := NewProviderError(provider, err)
+		providerErr.StatusCode = statusCode
+		return providerErr
+	}
+}
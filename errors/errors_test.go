@@ -2,6 +2,7 @@ package errors
 
 import (
 	stderrors "errors"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -30,6 +31,24 @@ func TestErrorIs(t *testing.T) {
 			target:    ErrAuthentication,
 			wantMatch: false,
 		},
+		{
+			name:      "QuotaExceededError matches ErrQuotaExceeded",
+			err:       NewQuotaExceededError("openai", originalErr),
+			target:    ErrQuotaExceeded,
+			wantMatch: true,
+		},
+		{
+			name:      "QuotaExceededError does not match ErrRateLimit",
+			err:       NewQuotaExceededError("openai", originalErr),
+			target:    ErrRateLimit,
+			wantMatch: false,
+		},
+		{
+			name:      "QueueTimeoutError matches ErrQueueTimeout",
+			err:       NewQueueTimeoutError("llamacpp", originalErr),
+			target:    ErrQueueTimeout,
+			wantMatch: true,
+		},
 		{
 			name:      "AuthenticationError matches ErrAuthentication",
 			err:       NewAuthenticationError("anthropic", originalErr),
@@ -154,6 +173,18 @@ func TestErrorCodes(t *testing.T) {
 		require.Equal(t, CodeRateLimit, err.Code)
 	})
 
+	t.Run("QuotaExceededError has correct code", func(t *testing.T) {
+		t.Parallel()
+		err := NewQuotaExceededError("openai", nil)
+		require.Equal(t, CodeQuotaExceeded, err.Code)
+	})
+
+	t.Run("QueueTimeoutError has correct code", func(t *testing.T) {
+		t.Parallel()
+		err := NewQueueTimeoutError("llamacpp", nil)
+		require.Equal(t, CodeQueueTimeout, err.Code)
+	})
+
 	t.Run("AuthenticationError has correct code", func(t *testing.T) {
 		t.Parallel()
 		err := NewAuthenticationError("openai", nil)
@@ -245,4 +276,55 @@ func TestErrorAs(t *testing.T) {
 		require.Equal(t, "frequency_penalty", paramErr.Param)
 		require.Equal(t, "openai", paramErr.Provider)
 	})
+
+	t.Run("can extract InvalidRequestError with Param", func(t *testing.T) {
+		t.Parallel()
+
+		err := NewInvalidParamError("anthropic", "temperature", stderrors.New("temperature must be at most 1"))
+
+		var invalidErr *InvalidRequestError
+		require.True(t, stderrors.As(err, &invalidErr))
+		require.Equal(t, "temperature", invalidErr.Param)
+		require.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
+
+func TestFromStatusCode(t *testing.T) {
+	t.Parallel()
+
+	originalErr := stderrors.New("original error")
+
+	tests := []struct {
+		name         string
+		statusCode   int
+		wantSentinel error
+	}{
+		{name: "bad request", statusCode: http.StatusBadRequest, wantSentinel: ErrInvalidRequest},
+		{name: "unprocessable entity", statusCode: http.StatusUnprocessableEntity, wantSentinel: ErrInvalidRequest},
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, wantSentinel: ErrAuthentication},
+		{name: "forbidden", statusCode: http.StatusForbidden, wantSentinel: ErrAuthentication},
+		{name: "payment required", statusCode: http.StatusPaymentRequired, wantSentinel: ErrQuotaExceeded},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, wantSentinel: ErrRateLimit},
+		{name: "server error", statusCode: http.StatusInternalServerError, wantSentinel: ErrProvider},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := FromStatusCode("test", tc.statusCode, originalErr)
+			require.ErrorIs(t, err, tc.wantSentinel)
+			require.ErrorIs(t, err, originalErr)
+		})
+	}
+
+	t.Run("records status code on provider errors", func(t *testing.T) {
+		t.Parallel()
+
+		err := FromStatusCode("test", http.StatusBadGateway, originalErr)
+
+		var providerErr *ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, http.StatusBadGateway, providerErr.StatusCode)
+	})
 }
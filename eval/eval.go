@@ -0,0 +1,510 @@
+// Package eval runs a suite of prompts against several providers and models
+// and collects accuracy, latency, token usage and cost into a comparable report.
+package eval
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/fingerprint"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/textdiff"
+)
+
+// Runner defaults.
+const (
+	defaultConcurrency = 4
+	tokensPerMillion   = 1_000_000
+)
+
+// Judge verdicts.
+const (
+	verdictFail = "FAIL"
+	verdictPass = "PASS"
+)
+
+// judgeSystemPrompt instructs the judge model how to grade an output.
+const judgeSystemPrompt = "You grade the output of an AI assistant against the given criteria. " +
+	"Reply with " + verdictPass + " or " + verdictFail + " on the first line, followed by a short reason."
+
+// csvHeader is the header row written by Report.WriteCSV.
+var csvHeader = []string{
+	"target", "case", "passed", "similarity", "latency_ms", "prompt_tokens", "completion_tokens", "cost", "error",
+	"output",
+}
+
+// Case is one prompt of a suite.
+// A case is graded against Expected if set, else by the judge with JudgePrompt
+// if set; otherwise it is only measured.
+type Case struct {
+	// Expected is the expected output, compared with the Runner's matcher.
+	Expected string `json:"expected,omitempty"`
+
+	// JudgePrompt describes what a correct output looks like, for grading by a judge model.
+	JudgePrompt string `json:"judgePrompt,omitempty"`
+
+	// Messages is the conversation sent to each target.
+	Messages []providers.Message `json:"messages"`
+
+	// Name identifies the case in the report.
+	Name string `json:"name"`
+}
+
+// CaseResult is the outcome of one case on one target.
+// Similarity is the token-level similarity of the output to the case's
+// Expected output, as reported by textdiff.Tokens, for cases with one.
+// With WithDuplicateDetection, Fingerprint is the output's fingerprint.Hash,
+// and DuplicateOf names the first case of the same target whose output is a
+// duplicate or near-duplicate of this one.
+type CaseResult struct {
+	Case        string           `json:"case"`
+	Cost        float64          `json:"cost,omitempty"`
+	DuplicateOf string           `json:"duplicateOf,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Fingerprint string           `json:"fingerprint,omitempty"`
+	Latency     time.Duration    `json:"latency"`
+	Output      string           `json:"output"`
+	Passed      *bool            `json:"passed,omitempty"`
+	Reason      string           `json:"reason,omitempty"`
+	Similarity  *float64         `json:"similarity,omitempty"`
+	Target      string           `json:"target"`
+	Usage       *providers.Usage `json:"usage,omitempty"`
+}
+
+// Matcher reports whether output matches the expected output.
+type Matcher func(expected, output string) bool
+
+// Option configures a Runner.
+type Option func(*Runner) error
+
+// Pricing is a target's price in currency units per million tokens.
+type Pricing struct {
+	InputPerMillion  float64 `json:"inputPerMillion"`
+	OutputPerMillion float64 `json:"outputPerMillion"`
+}
+
+// Report holds every case result and a summary per target.
+type Report struct {
+	Results   []CaseResult `json:"results"`
+	Summaries []Summary    `json:"summaries"`
+}
+
+// Runner runs suites against targets.
+type Runner struct {
+	concurrency        int
+	duplicateThreshold float64
+	judge              providers.Provider
+	judgeModel         string
+	matcher            Matcher
+}
+
+// Summary aggregates the results of one target.
+// MeanSimilarity is the mean Similarity of the cases with an expected output,
+// or 0 if there are none. Duplicates counts the cases with a DuplicateOf.
+type Summary struct {
+	// Accuracy is Passed divided by Graded, or 0 if nothing was graded.
+	Accuracy         float64       `json:"accuracy"`
+	Cases            int           `json:"cases"`
+	CompletionTokens int           `json:"completionTokens"`
+	Cost             float64       `json:"cost,omitempty"`
+	Duplicates       int           `json:"duplicates,omitempty"`
+	Errors           int           `json:"errors"`
+	Graded           int           `json:"graded"`
+	MeanLatency      time.Duration `json:"meanLatency"`
+	MeanSimilarity   float64       `json:"meanSimilarity,omitempty"`
+	Passed           int           `json:"passed"`
+	PromptTokens     int           `json:"promptTokens"`
+	Target           string        `json:"target"`
+}
+
+// Target is a provider and model to evaluate.
+type Target struct {
+	// Model is the model to request.
+	Model string
+
+	// Name identifies the target in the report. It defaults to "provider/model".
+	Name string
+
+	// Pricing is used to compute cost from token usage. Cost is omitted when nil.
+	Pricing *Pricing
+
+	// Provider serves the requests.
+	Provider providers.Provider
+}
+
+// New returns a Runner. By default it runs four requests at a time and
+// compares expected outputs with ExactMatch.
+func New(opts ...Option) (*Runner, error) {
+	r := &Runner{
+		concurrency: defaultConcurrency,
+		matcher:     ExactMatch,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// WithConcurrency sets how many requests run at the same time.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", n)
+		}
+
+		r.concurrency = n
+		return nil
+	}
+}
+
+// WithDuplicateDetection fingerprints outputs and flags the cases of a target
+// whose output is a duplicate or near-duplicate of an earlier case's, by
+// fingerprint.Similarity from threshold up. Different prompts that get the
+// same answer point to mode collapse or a cache bug. A threshold of 0 uses
+// fingerprint.DefaultThreshold; 1 flags only outputs that are the same once
+// normalized.
+func WithDuplicateDetection(threshold float64) Option {
+	return func(r *Runner) error {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("duplicate threshold must be between 0 and 1, got %v", threshold)
+		}
+		if threshold == 0 {
+			threshold = fingerprint.DefaultThreshold
+		}
+
+		r.duplicateThreshold = threshold
+		return nil
+	}
+}
+
+// WithJudge sets the model that grades cases with a JudgePrompt.
+func WithJudge(provider providers.Provider, model string) Option {
+	return func(r *Runner) error {
+		if provider == nil {
+			return fmt.Errorf("judge provider must not be nil")
+		}
+
+		r.judge = provider
+		r.judgeModel = model
+		return nil
+	}
+}
+
+// WithMatcher sets how outputs are compared with expected outputs.
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Runner) error {
+		if matcher == nil {
+			return fmt.Errorf("matcher must not be nil")
+		}
+
+		r.matcher = matcher
+		return nil
+	}
+}
+
+// Run sends every case to every target and grades the outputs.
+// Request and grading failures are recorded in the report; Run only fails
+// when the suite cannot be run at all.
+func (r *Runner) Run(ctx context.Context, cases []Case, targets []Target) (*Report, error) {
+	for _, c := range cases {
+		if c.Expected == "" && c.JudgePrompt != "" && r.judge == nil {
+			return nil, fmt.Errorf("case %q needs a judge: use WithJudge", c.Name)
+		}
+	}
+
+	results, err := runMatrix(ctx, r.concurrency, cases, targets, r.runCase)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Results: results}
+	for ti, t := range targets {
+		targetResults := results[ti*len(cases) : (ti+1)*len(cases)]
+		if r.duplicateThreshold > 0 {
+			markDuplicates(targetResults, r.duplicateThreshold)
+		}
+		report.Summaries = append(report.Summaries, summarize(targetName(t), targetResults))
+	}
+
+	return report, nil
+}
+
+// WriteCSV writes one row per case result, with latency in milliseconds.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, res := range r.Results {
+		passed := ""
+		if res.Passed != nil {
+			passed = strconv.FormatBool(*res.Passed)
+		}
+
+		similarity := ""
+		if res.Similarity != nil {
+			similarity = strconv.FormatFloat(*res.Similarity, 'f', 4, 64)
+		}
+
+		promptTokens, completionTokens := 0, 0
+		if res.Usage != nil {
+			promptTokens, completionTokens = res.Usage.PromptTokens, res.Usage.CompletionTokens
+		}
+
+		err := cw.Write([]string{
+			res.Target,
+			res.Case,
+			passed,
+			similarity,
+			strconv.FormatInt(res.Latency.Milliseconds(), 10),
+			strconv.Itoa(promptTokens),
+			strconv.Itoa(completionTokens),
+			strconv.FormatFloat(res.Cost, 'f', -1, 64),
+			res.Error,
+			res.Output,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// grade grades output for c, reporting whether it passed and why.
+// It returns a nil verdict for cases without expectations.
+func (r *Runner) grade(ctx context.Context, c Case, output string) (*bool, string, error) {
+	switch {
+	case c.Expected != "":
+		passed := r.matcher(c.Expected, output)
+		return &passed, "", nil
+	case c.JudgePrompt != "":
+		return r.judgeOutput(ctx, c, output)
+	default:
+		return nil, "", nil
+	}
+}
+
+// judgeOutput asks the judge model whether output meets c.JudgePrompt.
+func (r *Runner) judgeOutput(ctx context.Context, c Case, output string) (*bool, string, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Conversation:\n")
+	for _, msg := range c.Messages {
+		fmt.Fprintf(&prompt, "%s: %s\n", msg.Role, msg.ContentString())
+	}
+	fmt.Fprintf(&prompt, "\nCriteria:\n%s\n\nOutput:\n%s\n", c.JudgePrompt, output)
+
+	resp, err := r.judge.Completion(ctx, providers.CompletionParams{
+		Model: r.judgeModel,
+		Messages: []providers.Message{
+			{Role: providers.RoleSystem, Content: judgeSystemPrompt},
+			{Role: providers.RoleUser, Content: prompt.String()},
+		},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("judging: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, "", fmt.Errorf("judging: no choices returned")
+	}
+
+	verdict, reason, _ := strings.Cut(strings.TrimSpace(resp.Choices[0].Message.ContentString()), "\n")
+	var passed bool
+	switch strings.ToUpper(strings.Trim(strings.TrimSpace(verdict), ".:!")) {
+	case verdictPass:
+		passed = true
+	case verdictFail:
+		passed = false
+	default:
+		return nil, "", fmt.Errorf("judging: unexpected verdict %q", verdict)
+	}
+
+	return &passed, strings.TrimSpace(reason), nil
+}
+
+// runCase sends c to t and grades the output.
+func (r *Runner) runCase(ctx context.Context, t Target, c Case) CaseResult {
+	result := CaseResult{Case: c.Name, Target: targetName(t)}
+
+	start := time.Now()
+	resp, err := t.Provider.Completion(ctx, providers.CompletionParams{Model: t.Model, Messages: c.Messages})
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if len(resp.Choices) > 0 {
+		result.Output = resp.Choices[0].Message.ContentString()
+	}
+
+	result.Usage = resp.Usage
+	result.Cost = cost(t, resp.Usage)
+
+	if c.Expected != "" {
+		similarity := textdiff.Tokens(c.Expected, result.Output).Similarity
+		result.Similarity = &similarity
+	}
+
+	result.Passed, result.Reason, err = r.grade(ctx, c, result.Output)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Contains reports whether output contains expected, ignoring case.
+func Contains(expected, output string) bool {
+	return strings.Contains(strings.ToLower(output), strings.ToLower(expected))
+}
+
+// ExactMatch reports whether output equals expected, ignoring surrounding
+// whitespace and case.
+func ExactMatch(expected, output string) bool {
+	return strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+// Similar returns a Matcher that passes outputs whose token-level similarity
+// to the expected output, as reported by textdiff.Tokens, is at least
+// threshold, between 0 and 1. Use it to detect regressions when upgrading
+// models or prompts while tolerating small rewordings.
+func Similar(threshold float64) Matcher {
+	return func(expected, output string) bool {
+		return textdiff.Tokens(expected, output).Similarity >= threshold
+	}
+}
+
+// markDuplicates fingerprints the outputs of one target's results and sets
+// the DuplicateOf of each that duplicates an earlier one. Results without
+// output are skipped.
+func markDuplicates(results []CaseResult, threshold float64) {
+	fingerprints := make([]fingerprint.Fingerprint, len(results))
+	for i := range results {
+		if results[i].Output == "" {
+			continue
+		}
+		fingerprints[i] = fingerprint.Of(results[i].Output)
+		results[i].Fingerprint = fingerprints[i].Hash
+	}
+
+	for _, group := range fingerprint.DuplicatesOf(fingerprints, threshold) {
+		first := results[group.Indexes[0]].Case
+		for _, i := range group.Indexes[1:] {
+			results[i].DuplicateOf = first
+		}
+	}
+}
+
+// runMatrix runs every case on every target, at most concurrency at a time,
+// and returns the results grouped by target, in case order.
+func runMatrix[C, R any](
+	ctx context.Context,
+	concurrency int,
+	cases []C,
+	targets []Target,
+	run func(context.Context, Target, C) R,
+) ([]R, error) {
+	results := make([]R, len(targets)*len(cases))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for ti, t := range targets {
+		for ci, c := range cases {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return nil, ctx.Err()
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				results[ti*len(cases)+ci] = run(ctx, t, c)
+			}()
+		}
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// summarize aggregates the results of one target.
+func summarize(target string, results []CaseResult) Summary {
+	summary := Summary{Cases: len(results), Target: target}
+
+	var totalLatency time.Duration
+	var totalSimilarity float64
+	var similar int
+	for _, res := range results {
+		if res.Similarity != nil {
+			totalSimilarity += *res.Similarity
+			similar++
+		}
+		if res.DuplicateOf != "" {
+			summary.Duplicates++
+		}
+		if res.Error != "" {
+			summary.Errors++
+		}
+		totalLatency += res.Latency
+		if res.Passed != nil {
+			summary.Graded++
+			if *res.Passed {
+				summary.Passed++
+			}
+		}
+		if res.Usage != nil {
+			summary.PromptTokens += res.Usage.PromptTokens
+			summary.CompletionTokens += res.Usage.CompletionTokens
+		}
+		summary.Cost += res.Cost
+	}
+
+	if summary.Graded > 0 {
+		summary.Accuracy = float64(summary.Passed) / float64(summary.Graded)
+	}
+	if similar > 0 {
+		summary.MeanSimilarity = totalSimilarity / float64(similar)
+	}
+	if len(results) > 0 {
+		summary.MeanLatency = totalLatency / time.Duration(len(results))
+	}
+
+	return summary
+}
+
+// targetName returns the name of t in reports.
+func targetName(t Target) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Provider.Name() + "/" + t.Model
+}
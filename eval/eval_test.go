@@ -0,0 +1,288 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// replyingProvider returns a mock provider that always replies with content.
+func replyingProvider(name, content string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.NameFunc = func() string { return name }
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion(content)
+		resp.Model = params.Model
+		return resp, nil
+	}
+	return mock
+}
+
+func question(content string) []providers.Message {
+	return []providers.Message{{Role: providers.RoleUser, Content: content}}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithConcurrency(0))
+	require.Error(t, err)
+
+	_, err = New(WithJudge(nil, "judge"))
+	require.Error(t, err)
+
+	_, err = New(WithMatcher(nil))
+	require.Error(t, err)
+
+	_, err = New(WithDuplicateDetection(1.5))
+	require.Error(t, err)
+
+	runner, err := New()
+	require.NoError(t, err)
+	require.Equal(t, defaultConcurrency, runner.concurrency)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	cases := []Case{
+		{Name: "capital", Messages: question("Capital of France?"), Expected: "Paris"},
+		{Name: "greeting", Messages: question("Say hi")},
+	}
+
+	t.Run("grades and summarizes each target", func(t *testing.T) {
+		t.Parallel()
+
+		failing := testutil.NewMockProvider()
+		failing.NameFunc = func() string { return "broken" }
+		failing.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("broken", stderrors.New("slow down"))
+		}
+
+		runner, err := New(WithConcurrency(2))
+		require.NoError(t, err)
+
+		report, err := runner.Run(context.Background(), cases, []Target{
+			{
+				Provider: replyingProvider("good", "paris"),
+				Model:    "model-a",
+				Pricing:  &Pricing{InputPerMillion: 1, OutputPerMillion: 2},
+			},
+			{Provider: replyingProvider("wrong", "London"), Model: "model-b", Name: "wrong-model"},
+			{Provider: failing, Model: "model-c"},
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Results, 6)
+		require.Len(t, report.Summaries, 3)
+
+		good := report.Summaries[0]
+		require.Equal(t, "good/model-a", good.Target)
+		require.Equal(t, 2, good.Cases)
+		require.Equal(t, 1, good.Graded)
+		require.Equal(t, 1, good.Passed)
+		require.InDelta(t, 1.0, good.Accuracy, 1e-9)
+		require.Equal(t, 20, good.PromptTokens)
+		require.Equal(t, 10, good.CompletionTokens)
+		// Each request costs (10*1 + 5*2) / 1M.
+		require.InDelta(t, 40.0/tokensPerMillion, good.Cost, 1e-12)
+
+		wrong := report.Summaries[1]
+		require.Equal(t, "wrong-model", wrong.Target)
+		require.Equal(t, 0, wrong.Passed)
+		require.Zero(t, wrong.Cost)
+
+		broken := report.Summaries[2]
+		require.Equal(t, 2, broken.Errors)
+		require.Equal(t, 0, broken.Graded)
+
+		require.Nil(t, report.Results[1].Passed, "cases without expectations are not graded")
+		require.Nil(t, report.Results[1].Similarity)
+		require.Contains(t, report.Results[4].Error, "slow down")
+	})
+
+	t.Run("scores similarity to the expected output", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New(WithMatcher(Similar(0.8)))
+		require.NoError(t, err)
+
+		expected := "The capital of France is Paris."
+		report, err := runner.Run(context.Background(), []Case{
+			{Name: "capital", Messages: question("Capital of France?"), Expected: expected},
+		}, []Target{
+			{Provider: replyingProvider("same", expected), Model: "m"},
+			{Provider: replyingProvider("reworded", "The capital of France is Paris!"), Model: "m"},
+			{Provider: replyingProvider("regressed", "I don't know."), Model: "m"},
+		})
+		require.NoError(t, err)
+
+		require.InDelta(t, 1.0, *report.Results[0].Similarity, 1e-9)
+		require.True(t, *report.Results[0].Passed)
+		require.InDelta(t, 12.0/14, *report.Results[1].Similarity, 1e-9)
+		require.True(t, *report.Results[1].Passed)
+		require.Less(t, *report.Results[2].Similarity, 0.2)
+		require.False(t, *report.Results[2].Passed)
+		require.InDelta(t, *report.Results[1].Similarity, report.Summaries[1].MeanSimilarity, 1e-9)
+	})
+
+	t.Run("grades with a judge", func(t *testing.T) {
+		t.Parallel()
+
+		judge := replyingProvider("judge", "FAIL\nThe answer is not a greeting.")
+		runner, err := New(WithJudge(judge, "judge-model"))
+		require.NoError(t, err)
+
+		report, err := runner.Run(context.Background(), []Case{
+			{Name: "greeting", Messages: question("Say hi"), JudgePrompt: "The output greets the user."},
+		}, []Target{{Provider: replyingProvider("p", "Goodbye"), Model: "m"}})
+		require.NoError(t, err)
+
+		result := report.Results[0]
+		require.NotNil(t, result.Passed)
+		require.False(t, *result.Passed)
+		require.Equal(t, "The answer is not a greeting.", result.Reason)
+
+		prompt := judge.CompletionCalls[0].Messages[1].ContentString()
+		require.Contains(t, prompt, "The output greets the user.")
+		require.Contains(t, prompt, "Goodbye")
+	})
+
+	t.Run("records unexpected judge verdicts as errors", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New(WithJudge(replyingProvider("judge", "Maybe"), "judge-model"))
+		require.NoError(t, err)
+
+		report, err := runner.Run(context.Background(), []Case{
+			{Name: "greeting", Messages: question("Say hi"), JudgePrompt: "The output greets the user."},
+		}, []Target{{Provider: replyingProvider("p", "Hi"), Model: "m"}})
+		require.NoError(t, err)
+		require.Nil(t, report.Results[0].Passed)
+		require.Contains(t, report.Results[0].Error, "unexpected verdict")
+	})
+
+	t.Run("flags duplicate outputs", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New(WithDuplicateDetection(0))
+		require.NoError(t, err)
+
+		failing := testutil.NewMockProvider()
+		failing.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("mock", stderrors.New("boom"))
+		}
+
+		report, err := runner.Run(context.Background(), []Case{
+			{Name: "first", Messages: question("Tell me a joke")},
+			{Name: "second", Messages: question("Tell me another joke")},
+			{Name: "third", Messages: question("And another")},
+		}, []Target{
+			{Provider: replyingProvider("collapsed", "Why did the chicken cross the road?"), Model: "m"},
+			{Provider: failing, Model: "m"},
+		})
+		require.NoError(t, err)
+
+		require.Empty(t, report.Results[0].DuplicateOf)
+		require.Equal(t, "first", report.Results[1].DuplicateOf)
+		require.Equal(t, "first", report.Results[2].DuplicateOf)
+		require.Equal(t, report.Results[0].Fingerprint, report.Results[1].Fingerprint)
+		require.Equal(t, 2, report.Summaries[0].Duplicates)
+
+		// Failed requests have no output to compare.
+		require.Empty(t, report.Results[4].DuplicateOf)
+		require.Empty(t, report.Results[4].Fingerprint)
+		require.Zero(t, report.Summaries[1].Duplicates)
+	})
+
+	t.Run("does not fingerprint by default", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New()
+		require.NoError(t, err)
+
+		report, err := runner.Run(context.Background(), cases, []Target{{Provider: replyingProvider("p", "same"), Model: "m"}})
+		require.NoError(t, err)
+		require.Empty(t, report.Results[1].DuplicateOf)
+		require.Empty(t, report.Results[1].Fingerprint)
+	})
+
+	t.Run("requires a judge for judge prompts", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New()
+		require.NoError(t, err)
+
+		_, err = runner.Run(context.Background(), []Case{{Name: "c", JudgePrompt: "criteria"}}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestMatchers(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, ExactMatch("Paris", " paris\n"))
+	require.False(t, ExactMatch("Paris", "Paris, France"))
+	require.True(t, Contains("Paris", "It is PARIS."))
+	require.False(t, Contains("Paris", "London"))
+	require.True(t, Similar(0.5)("The answer is 42.", "The answer is 43."))
+	require.False(t, Similar(0.9)("The answer is 42.", "The answer is 43."))
+}
+
+func TestReportExport(t *testing.T) {
+	t.Parallel()
+
+	passed := true
+	similarity := 0.5
+	report := &Report{
+		Results: []CaseResult{
+			{
+				Case:       "capital",
+				Output:     "Paris, of course",
+				Passed:     &passed,
+				Similarity: &similarity,
+				Target:     "p/m",
+				Usage:      &providers.Usage{PromptTokens: 3, CompletionTokens: 4},
+			},
+			{Case: "greeting", Error: "rate limited", Target: "p/m"},
+		},
+		Summaries: []Summary{{Target: "p/m", Cases: 2, Graded: 1, Passed: 1, Accuracy: 1}},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		require.Equal(t, csvHeader, rows[0])
+		require.Equal(t, []string{"p/m", "capital", "true", "0.5000", "0", "3", "4", "0", "", "Paris, of course"}, rows[1])
+		require.Equal(t, "", rows[2][2])
+		require.Equal(t, "", rows[2][3])
+		require.Equal(t, "rate limited", rows[2][8])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+
+		var decoded Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		require.Equal(t, report.Summaries, decoded.Summaries)
+		require.True(t, *decoded.Results[0].Passed)
+		require.Nil(t, decoded.Results[1].Passed)
+	})
+}
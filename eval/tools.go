@@ -0,0 +1,460 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/agent"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Backend is a scripted tool backend. Each run of a case gets a fresh
+// ToolFunc from it, so scripted state is never shared between targets.
+type Backend func() agent.ToolFunc
+
+// CallScore is how one expected tool call was matched.
+type CallScore struct {
+	// Actual is the call the model made for it, if any.
+	Actual *providers.FunctionCall `json:"actual,omitempty"`
+
+	// ArgumentsMatched reports that the call had the expected arguments.
+	ArgumentsMatched bool `json:"argumentsMatched"`
+
+	// Expected is the expected call.
+	Expected ExpectedCall `json:"expected"`
+
+	// ToolMatched reports that the model called the expected tool.
+	ToolMatched bool `json:"toolMatched"`
+}
+
+// ExpectedCall is a tool call a ToolCase expects the model to make.
+type ExpectedCall struct {
+	// Arguments are the arguments the call must have. Each must be present
+	// with an equal JSON value; strings are compared like ExactMatch. Other
+	// arguments are allowed. With no Arguments, any arguments match.
+	Arguments map[string]any `json:"arguments,omitempty"`
+
+	// Name is the tool's name.
+	Name string `json:"name"`
+}
+
+// ToolCase is a multi-turn tool-use scenario. The model runs a tool loop
+// against scripted backends, and is graded on whether it called the right
+// tools with the right arguments, and optionally on its final answer.
+type ToolCase struct {
+	// AllowExtraCalls lets the model make calls beyond the expected ones,
+	// such as retries or lookups that do no harm.
+	AllowExtraCalls bool `json:"allowExtraCalls,omitempty"`
+
+	// AnyOrder accepts the expected calls in any order.
+	AnyOrder bool `json:"anyOrder,omitempty"`
+
+	// Backends runs each tool in Tools, by name.
+	Backends map[string]Backend `json:"-"`
+
+	// Calls are the tool calls the model should make, in order.
+	Calls []ExpectedCall `json:"calls"`
+
+	// Expected is the expected final answer, compared with the Runner's
+	// matcher. The answer is not graded when empty.
+	Expected string `json:"expected,omitempty"`
+
+	// MaxSteps caps the tool rounds, as with agent.WithMaxSteps.
+	MaxSteps int `json:"maxSteps,omitempty"`
+
+	// Messages is the conversation sent to each target.
+	Messages []providers.Message `json:"messages"`
+
+	// Name identifies the case in the report.
+	Name string `json:"name"`
+
+	// Tools describes the tools to the model.
+	Tools []providers.Tool `json:"tools"`
+}
+
+// ToolCaseResult is the outcome of one tool case on one target.
+type ToolCaseResult struct {
+	// Calls are every tool call the model made, in order.
+	Calls []providers.FunctionCall `json:"calls"`
+
+	// Case is the case's name.
+	Case string `json:"case"`
+
+	// Cost is the cost of every request of the run, at the target's prices.
+	Cost float64 `json:"cost,omitempty"`
+
+	// Error is the error that stopped the run, if any.
+	Error string `json:"error,omitempty"`
+
+	// ExtraCalls is the number of calls that matched no expected call.
+	ExtraCalls int `json:"extraCalls"`
+
+	// Latency is the duration of the whole run.
+	Latency time.Duration `json:"latency"`
+
+	// Output is the final answer.
+	Output string `json:"output"`
+
+	// Passed reports that every expected call was made with the expected
+	// arguments, in order unless AnyOrder is set, without extra calls unless
+	// allowed, and that the answer matched if one was expected.
+	Passed bool `json:"passed"`
+
+	// Reason explains why the case failed.
+	Reason string `json:"reason,omitempty"`
+
+	// Scores grades each expected call, in the order of ToolCase.Calls.
+	Scores []CallScore `json:"scores"`
+
+	// Steps is the number of tool rounds the model took.
+	Steps int `json:"steps"`
+
+	// Target is the target's name.
+	Target string `json:"target"`
+
+	// Usage sums the usage of every request of the run.
+	Usage *providers.Usage `json:"usage,omitempty"`
+}
+
+// ToolReport holds every tool case result and a summary per target.
+type ToolReport struct {
+	Results   []ToolCaseResult `json:"results"`
+	Summaries []ToolSummary    `json:"summaries"`
+}
+
+// ToolSummary aggregates the tool case results of one target.
+type ToolSummary struct {
+	// Accuracy is the share of cases that passed.
+	Accuracy float64 `json:"accuracy"`
+
+	// ArgumentAccuracy is the share of expected calls made to the right tool
+	// with the right arguments.
+	ArgumentAccuracy float64 `json:"argumentAccuracy"`
+
+	Cases            int           `json:"cases"`
+	CompletionTokens int           `json:"completionTokens"`
+	Cost             float64       `json:"cost,omitempty"`
+	Errors           int           `json:"errors"`
+	ExtraCalls       int           `json:"extraCalls"`
+	MeanLatency      time.Duration `json:"meanLatency"`
+	Passed           int           `json:"passed"`
+	PromptTokens     int           `json:"promptTokens"`
+	Target           string        `json:"target"`
+
+	// ToolAccuracy is the share of expected calls made to the right tool,
+	// whatever the arguments.
+	ToolAccuracy float64 `json:"toolAccuracy"`
+}
+
+// usageCounter is a provider wrapper that sums the usage of its completions.
+type usageCounter struct {
+	providers.Provider
+
+	mu    sync.Mutex
+	usage *providers.Usage
+}
+
+// RunTools runs every tool case on every target and scores the tool calls.
+// Models run the loop with agent.RunTools: natively when their provider
+// supports tools, as a ReAct loop otherwise. Request failures are recorded in
+// the report; RunTools only fails when the suite cannot be run at all.
+func (r *Runner) RunTools(ctx context.Context, cases []ToolCase, targets []Target) (*ToolReport, error) {
+	for _, c := range cases {
+		for _, tool := range c.Tools {
+			if c.Backends[tool.Function.Name] == nil {
+				return nil, fmt.Errorf("case %q: tool %q has no backend", c.Name, tool.Function.Name)
+			}
+		}
+		if c.MaxSteps < 0 {
+			return nil, fmt.Errorf("case %q: max steps must not be negative, got %d", c.Name, c.MaxSteps)
+		}
+	}
+
+	results, err := runMatrix(ctx, r.concurrency, cases, targets, r.runToolCase)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ToolReport{Results: results}
+	for ti, t := range targets {
+		report.Summaries = append(
+			report.Summaries,
+			summarizeTools(targetName(t), cases, results[ti*len(cases):(ti+1)*len(cases)]),
+		)
+	}
+
+	return report, nil
+}
+
+// Completion sends params to the wrapped provider and adds the response's usage.
+func (u *usageCounter) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := u.Provider.Completion(ctx, params)
+	if err != nil || resp.Usage == nil {
+		return resp, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.usage == nil {
+		u.usage = &providers.Usage{}
+	}
+	u.usage.PromptTokens += resp.Usage.PromptTokens
+	u.usage.CompletionTokens += resp.Usage.CompletionTokens
+	u.usage.TotalTokens += resp.Usage.TotalTokens
+	return resp, nil
+}
+
+// runToolCase runs the tool loop of c on t and scores it.
+func (r *Runner) runToolCase(ctx context.Context, t Target, c ToolCase) ToolCaseResult {
+	result := ToolCaseResult{Case: c.Name, Target: targetName(t)}
+
+	tools := make(map[string]agent.ToolFunc, len(c.Backends))
+	for name, backend := range c.Backends {
+		if backend != nil {
+			tools[name] = backend()
+		}
+	}
+
+	// The strategy is chosen from the target's own provider, which the usage
+	// counter hides.
+	opts := []agent.Option{agent.WithStrategy(agent.StrategyNative)}
+	if cp, ok := providers.As[providers.CapabilityProvider](t.Provider); ok && !cp.Capabilities().CompletionTools {
+		opts[0] = agent.WithStrategy(agent.StrategyReAct)
+	}
+	if c.MaxSteps > 0 {
+		opts = append(opts, agent.WithMaxSteps(c.MaxSteps))
+	}
+
+	counter := &usageCounter{Provider: t.Provider}
+	start := time.Now()
+	run, err := agent.RunTools(
+		ctx,
+		counter,
+		providers.CompletionParams{Messages: c.Messages, Model: t.Model, Tools: c.Tools},
+		tools,
+		opts...,
+	)
+	result.Latency = time.Since(start)
+	result.Usage = counter.usage
+	result.Cost = cost(t, result.Usage)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if run == nil {
+		result.Reason = "the run failed"
+		return result
+	}
+
+	result.Steps = run.Steps
+	for _, msg := range run.Messages[len(c.Messages):] {
+		for _, call := range msg.ToolCalls {
+			result.Calls = append(result.Calls, call.Function)
+		}
+	}
+	if run.Completion != nil && len(run.Completion.Choices) > 0 && len(run.Completion.Choices[0].Message.ToolCalls) == 0 {
+		result.Output = run.Completion.Choices[0].Message.ContentString()
+	}
+
+	result.Scores, result.ExtraCalls = scoreCalls(c, result.Calls)
+	result.Passed, result.Reason = r.judgeToolCase(c, result, err)
+	return result
+}
+
+// judgeToolCase decides whether result passes c, and why not.
+func (r *Runner) judgeToolCase(c ToolCase, result ToolCaseResult, err error) (bool, string) {
+	if err != nil {
+		return false, "the run failed"
+	}
+
+	for _, score := range result.Scores {
+		switch {
+		case !score.ToolMatched:
+			return false, fmt.Sprintf("%s was not called", score.Expected.Name)
+		case !score.ArgumentsMatched:
+			return false, fmt.Sprintf("%s was called with %s", score.Expected.Name, score.Actual.Arguments)
+		}
+	}
+	if result.ExtraCalls > 0 && !c.AllowExtraCalls {
+		return false, fmt.Sprintf("%d unexpected calls", result.ExtraCalls)
+	}
+	if c.Expected != "" && !r.matcher(c.Expected, result.Output) {
+		return false, "the answer did not match"
+	}
+
+	return true, ""
+}
+
+// argumentsMatch reports whether the JSON arguments hold every expected
+// argument.
+func argumentsMatch(expected map[string]any, arguments string) bool {
+	if len(expected) == 0 {
+		return true
+	}
+
+	var actual map[string]any
+	if err := json.Unmarshal([]byte(arguments), &actual); err != nil {
+		return false
+	}
+
+	for name, want := range expected {
+		got, ok := actual[name]
+		if !ok || !valuesMatch(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+// cost returns the cost of usage at t's prices, or 0 without pricing.
+func cost(t Target, usage *providers.Usage) float64 {
+	if t.Pricing == nil || usage == nil {
+		return 0
+	}
+
+	return (float64(usage.PromptTokens)*t.Pricing.InputPerMillion +
+		float64(usage.CompletionTokens)*t.Pricing.OutputPerMillion) / tokensPerMillion
+}
+
+// scoreCalls matches the calls the model made against c's expected calls and
+// counts the calls that matched none. In order, each expected call matches
+// the first later call to its tool; with AnyOrder, any unmatched call to its
+// tool, preferring one with the expected arguments.
+func scoreCalls(c ToolCase, calls []providers.FunctionCall) ([]CallScore, int) {
+	scores := make([]CallScore, len(c.Calls))
+	used := make([]bool, len(calls))
+	next := 0
+
+	for i, expected := range c.Calls {
+		scores[i].Expected = expected
+
+		start := next
+		if c.AnyOrder {
+			start = 0
+		}
+		match := -1
+		for j := start; j < len(calls); j++ {
+			if used[j] || calls[j].Name != expected.Name {
+				continue
+			}
+			if match < 0 {
+				match = j
+			}
+			if !c.AnyOrder {
+				break
+			}
+			if argumentsMatch(expected.Arguments, calls[j].Arguments) {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			continue
+		}
+
+		used[match] = true
+		next = match + 1
+		actual := calls[match]
+		scores[i].Actual = &actual
+		scores[i].ToolMatched = true
+		scores[i].ArgumentsMatched = argumentsMatch(expected.Arguments, actual.Arguments)
+	}
+
+	extra := 0
+	for _, u := range used {
+		if !u {
+			extra++
+		}
+	}
+	return scores, extra
+}
+
+// summarizeTools aggregates the tool case results of one target.
+func summarizeTools(target string, cases []ToolCase, results []ToolCaseResult) ToolSummary {
+	summary := ToolSummary{Cases: len(results), Target: target}
+
+	var expected, toolsMatched, argumentsMatched int
+	var totalLatency time.Duration
+	for i, res := range results {
+		expected += len(cases[i].Calls)
+		for _, score := range res.Scores {
+			if score.ToolMatched {
+				toolsMatched++
+			}
+			if score.ArgumentsMatched {
+				argumentsMatched++
+			}
+		}
+		if res.Error != "" {
+			summary.Errors++
+		}
+		if res.Passed {
+			summary.Passed++
+		}
+		if res.Usage != nil {
+			summary.PromptTokens += res.Usage.PromptTokens
+			summary.CompletionTokens += res.Usage.CompletionTokens
+		}
+		summary.Cost += res.Cost
+		summary.ExtraCalls += res.ExtraCalls
+		totalLatency += res.Latency
+	}
+
+	if len(results) > 0 {
+		summary.Accuracy = float64(summary.Passed) / float64(len(results))
+		summary.MeanLatency = totalLatency / time.Duration(len(results))
+	}
+	if expected > 0 {
+		summary.ToolAccuracy = float64(toolsMatched) / float64(expected)
+		summary.ArgumentAccuracy = float64(argumentsMatched) / float64(expected)
+	}
+
+	return summary
+}
+
+// Script returns a backend that returns results in order, one per call,
+// repeating the last one once they run out.
+func Script(results ...string) Backend {
+	return func() agent.ToolFunc {
+		var mu sync.Mutex
+		calls := 0
+		return func(context.Context, string) (providers.ToolResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(results) == 0 {
+				return providers.ToolResult{}, nil
+			}
+			result := results[min(calls, len(results)-1)]
+			calls++
+			return providers.ToolResult{Content: result}, nil
+		}
+	}
+}
+
+// valuesMatch reports whether a decoded JSON argument equals the expected
+// value. Strings are compared like ExactMatch and numbers by value.
+func valuesMatch(want, got any) bool {
+	if s, ok := want.(string); ok {
+		g, ok := got.(string)
+		return ok && ExactMatch(s, g)
+	}
+
+	// Round-trip the expected value so that its types match decoded JSON.
+	data, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalized, got)
+}
@@ -0,0 +1,228 @@
+package eval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// weatherCase expects a single get_weather call for Salvaterra.
+func weatherCase() ToolCase {
+	return ToolCase{
+		Backends: map[string]Backend{"get_weather": Script("sunny, 22°C")},
+		Calls:    []ExpectedCall{{Arguments: map[string]any{"location": "Salvaterra"}, Name: "get_weather"}},
+		Expected: "sunny",
+		Messages: testutil.AgentLoopMessages()[:1],
+		Name:     "weather",
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	}
+}
+
+func TestRunTools(t *testing.T) {
+	t.Parallel()
+
+	weather := func(arguments string) providers.FunctionCall {
+		return providers.FunctionCall{Arguments: arguments, Name: "get_weather"}
+	}
+
+	tests := []struct {
+		name       string
+		provider   *testutil.MockProvider
+		edit       func(*ToolCase)
+		wantPassed bool
+		wantReason string
+		wantExtra  int
+	}{
+		{
+			name:       "passes the right call",
+			provider:   testutil.ScriptedToolCalls("It is sunny.", []providers.FunctionCall{weather(`{"location":"salvaterra "}`)}),
+			wantPassed: true,
+		},
+		{
+			name:       "fails a missing call",
+			provider:   testutil.ScriptedToolCalls("I don't know."),
+			wantReason: "get_weather was not called",
+		},
+		{
+			name:       "fails wrong arguments",
+			provider:   testutil.ScriptedToolCalls("It is sunny.", []providers.FunctionCall{weather(`{"location":"Paris"}`)}),
+			wantReason: `get_weather was called with {"location":"Paris"}`,
+		},
+		{
+			name:       "fails arguments that are not JSON",
+			provider:   testutil.ScriptedToolCalls("It is sunny.", []providers.FunctionCall{weather(`Salvaterra`)}),
+			wantReason: "get_weather was called with Salvaterra",
+		},
+		{
+			name: "fails extra calls",
+			provider: testutil.ScriptedToolCalls("It is sunny.",
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+			),
+			wantReason: "1 unexpected calls",
+			wantExtra:  1,
+		},
+		{
+			name: "allows extra calls when asked",
+			provider: testutil.ScriptedToolCalls("It is sunny.",
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+			),
+			edit:       func(c *ToolCase) { c.AllowExtraCalls = true },
+			wantPassed: true,
+			wantExtra:  1,
+		},
+		{
+			name:       "fails a wrong answer",
+			provider:   testutil.ScriptedToolCalls("It is raining.", []providers.FunctionCall{weather(`{"location":"Salvaterra"}`)}),
+			wantReason: "the answer did not match",
+		},
+		{
+			name: "fails a run that exceeds its steps",
+			provider: testutil.ScriptedToolCalls("It is sunny.",
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+				[]providers.FunctionCall{weather(`{"location":"Salvaterra"}`)},
+			),
+			edit:       func(c *ToolCase) { c.MaxSteps = 1 },
+			wantReason: "the run failed",
+			wantExtra:  1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := weatherCase()
+			if tc.edit != nil {
+				tc.edit(&c)
+			}
+
+			runner, err := New(WithMatcher(Contains))
+			require.NoError(t, err)
+
+			report, err := runner.RunTools(context.Background(), []ToolCase{c}, []Target{{Model: "m", Provider: tc.provider}})
+			require.NoError(t, err)
+			require.Len(t, report.Results, 1)
+
+			result := report.Results[0]
+			require.Equal(t, tc.wantPassed, result.Passed, result.Reason)
+			require.Equal(t, tc.wantReason, result.Reason)
+			require.Equal(t, tc.wantExtra, result.ExtraCalls)
+		})
+	}
+}
+
+func TestRunToolsScoring(t *testing.T) {
+	t.Parallel()
+
+	lookup := func(name, arguments string) providers.FunctionCall {
+		return providers.FunctionCall{Arguments: arguments, Name: name}
+	}
+	tools := []providers.Tool{
+		{Function: providers.Function{Name: "find_user"}, Type: "function"},
+		{Function: providers.Function{Name: "get_balance"}, Type: "function"},
+	}
+	c := ToolCase{
+		Backends: map[string]Backend{
+			"find_user":   Script(`{"id": 7}`),
+			"get_balance": Script("100", "200"),
+		},
+		Calls: []ExpectedCall{
+			{Arguments: map[string]any{"email": "a@example.com"}, Name: "find_user"},
+			{Arguments: map[string]any{"id": 7}, Name: "get_balance"},
+		},
+		Messages: []providers.Message{{Content: "What is my balance? I am a@example.com", Role: providers.RoleUser}},
+		Name:     "balance",
+		Tools:    tools,
+	}
+
+	t.Run("scores tools and arguments separately", func(t *testing.T) {
+		t.Parallel()
+
+		provider := testutil.ScriptedToolCalls("100",
+			[]providers.FunctionCall{lookup("find_user", `{"email":"a@example.com"}`)},
+			[]providers.FunctionCall{lookup("get_balance", `{"id":8}`)},
+		)
+		provider.NameFunc = func() string { return "mock" }
+
+		runner, err := New()
+		require.NoError(t, err)
+
+		report, err := runner.RunTools(context.Background(), []ToolCase{c}, []Target{
+			{Model: "m", Pricing: &Pricing{InputPerMillion: 1_000_000}, Provider: provider},
+		})
+		require.NoError(t, err)
+
+		result := report.Results[0]
+		require.False(t, result.Passed)
+		require.Equal(t, 2, result.Steps)
+		require.Len(t, result.Calls, 2)
+		require.True(t, result.Scores[0].ArgumentsMatched)
+		require.True(t, result.Scores[1].ToolMatched)
+		require.False(t, result.Scores[1].ArgumentsMatched)
+		require.Equal(t, 30, result.Usage.PromptTokens)
+		require.InDelta(t, 30, result.Cost, 1e-9)
+
+		summary := report.Summaries[0]
+		require.Equal(t, "mock/m", summary.Target)
+		require.InDelta(t, 1, summary.ToolAccuracy, 1e-9)
+		require.InDelta(t, 0.5, summary.ArgumentAccuracy, 1e-9)
+		require.Zero(t, summary.Accuracy)
+	})
+
+	t.Run("requires the expected order", func(t *testing.T) {
+		t.Parallel()
+
+		provider := testutil.ScriptedToolCalls("100",
+			[]providers.FunctionCall{lookup("get_balance", `{"id":7}`), lookup("find_user", `{"email":"a@example.com"}`)},
+		)
+
+		runner, err := New()
+		require.NoError(t, err)
+
+		report, err := runner.RunTools(context.Background(), []ToolCase{c}, []Target{{Model: "m", Provider: provider}})
+		require.NoError(t, err)
+		require.False(t, report.Results[0].Passed)
+		require.Equal(t, "get_balance was not called", report.Results[0].Reason)
+
+		anyOrder := c
+		anyOrder.AnyOrder = true
+		report, err = runner.RunTools(context.Background(), []ToolCase{anyOrder}, []Target{{Model: "m", Provider: provider}})
+		require.NoError(t, err)
+		require.True(t, report.Results[0].Passed, report.Results[0].Reason)
+	})
+
+	t.Run("validates cases", func(t *testing.T) {
+		t.Parallel()
+
+		runner, err := New()
+		require.NoError(t, err)
+
+		missing := c
+		missing.Backends = map[string]Backend{"find_user": Script("{}")}
+		_, err = runner.RunTools(context.Background(), []ToolCase{missing}, nil)
+		require.EqualError(t, err, `case "balance": tool "get_balance" has no backend`)
+	})
+}
+
+func TestScript(t *testing.T) {
+	t.Parallel()
+
+	backend := Script("first", "second")
+	fn := backend()
+	for _, want := range []string{"first", "second", "second"} {
+		result, err := fn(context.Background(), "{}")
+		require.NoError(t, err)
+		require.Equal(t, want, result.Content)
+	}
+
+	// Each run starts the script over.
+	result, err := backend()(context.Background(), "{}")
+	require.NoError(t, err)
+	require.Equal(t, "first", result.Content)
+}
@@ -0,0 +1,358 @@
+// Package finetune exports stored conversations as fine-tuning datasets in the
+// JSONL formats of OpenAI and Mistral, so production traffic can be filtered,
+// scrubbed of personal data and used to customize a model.
+package finetune
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/chat"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Dataset formats.
+const (
+	FormatMistral Format = "mistral"
+	FormatOpenAI  Format = "openai"
+)
+
+// mistralToolCallIDLength is the length of tool call IDs Mistral accepts.
+const mistralToolCallIDLength = 9
+
+// Placeholders ScrubPII replaces personal data with.
+const (
+	PlaceholderCard  = "[CARD]"
+	PlaceholderEmail = "[EMAIL]"
+	PlaceholderIP    = "[IP]"
+	PlaceholderPhone = "[PHONE]"
+)
+
+// piiPatterns are applied by ScrubPII in order. Card numbers come before
+// phone numbers, which would otherwise match parts of them.
+var piiPatterns = []struct {
+	placeholder string
+	re          *regexp.Regexp
+}{
+	{PlaceholderEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{PlaceholderCard, regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)},
+	{PlaceholderIP, regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+	{PlaceholderPhone, regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`)},
+}
+
+// Exporter writes conversations to a fine-tuning dataset, one JSON line per
+// conversation. An Exporter is not safe for concurrent use.
+type Exporter struct {
+	filters   []Filter
+	format    Format
+	scrubbers []Scrubber
+	stats     Stats
+	w         io.Writer
+}
+
+// Filter decides whether a conversation is exported. It sees the messages
+// before they are scrubbed.
+type Filter func(sessionID string, messages []providers.Message) bool
+
+// Format is a fine-tuning dataset format.
+type Format string
+
+// Option configures an Exporter.
+type Option func(*Exporter) error
+
+// Scrubber rewrites text before it is exported, for example to remove
+// personal data.
+type Scrubber func(text string) string
+
+// Stats counts the conversations an Exporter has seen.
+type Stats struct {
+	// Exported is the number of conversations written.
+	Exported int
+
+	// Filtered is the number of conversations rejected by a filter.
+	Filtered int
+
+	// Skipped is the number of conversations that cannot be used for
+	// training: those without an assistant reply, and those with content
+	// the format does not support.
+	Skipped int
+}
+
+// example is one line of a dataset.
+type example struct {
+	Messages []message `json:"messages"`
+}
+
+// message is a message in the dataset formats, which follow the OpenAI chat
+// format.
+type message struct {
+	Content    any                  `json:"content,omitempty"`
+	Name       string               `json:"name,omitempty"`
+	Role       string               `json:"role"`
+	ToolCallID string               `json:"tool_call_id,omitempty"` //nolint:tagliatelle // OpenAI chat format.
+	ToolCalls  []providers.ToolCall `json:"tool_calls,omitempty"`   //nolint:tagliatelle // OpenAI chat format.
+}
+
+// New returns an Exporter that writes conversations to w in format.
+func New(w io.Writer, format Format, opts ...Option) (*Exporter, error) {
+	switch format {
+	case FormatMistral, FormatOpenAI:
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	e := &Exporter{format: format, w: w}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// WithFilter adds a filter. A conversation is exported only if every filter
+// accepts it.
+func WithFilter(f Filter) Option {
+	return func(e *Exporter) error {
+		if f == nil {
+			return fmt.Errorf("filter must not be nil")
+		}
+		e.filters = append(e.filters, f)
+		return nil
+	}
+}
+
+// WithScrubber adds a scrubber, applied to message text and tool call
+// arguments. Scrubbers run in the order they were added.
+func WithScrubber(s Scrubber) Option {
+	return func(e *Exporter) error {
+		if s == nil {
+			return fmt.Errorf("scrubber must not be nil")
+		}
+		e.scrubbers = append(e.scrubbers, s)
+		return nil
+	}
+}
+
+// Export writes every session in store, in the order the store lists them.
+func (e *Exporter) Export(ctx context.Context, store chat.Store) error {
+	ids, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		messages, err := store.Load(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := e.Write(id, messages); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the counts of conversations seen so far.
+func (e *Exporter) Stats() Stats {
+	return e.stats
+}
+
+// Write writes one conversation, unless a filter rejects it or it cannot be
+// used for training. Messages after the last assistant reply are dropped,
+// since there is nothing to learn from them.
+func (e *Exporter) Write(sessionID string, messages []providers.Message) error {
+	for _, f := range e.filters {
+		if !f(sessionID, messages) {
+			e.stats.Filtered++
+			return nil
+		}
+	}
+
+	ex, ok := e.convert(messages)
+	if !ok {
+		e.stats.Skipped++
+		return nil
+	}
+
+	data, err := json.Marshal(ex)
+	if err != nil {
+		return fmt.Errorf("encoding session %s: %w", sessionID, err)
+	}
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing session %s: %w", sessionID, err)
+	}
+
+	e.stats.Exported++
+	return nil
+}
+
+// content returns the scrubbed content of msg in the exporter's format, and
+// false if the format cannot represent it. OpenAI datasets may include
+// images; Mistral datasets are text only.
+func (e *Exporter) content(msg providers.Message) (any, bool) {
+	parts := msg.ContentParts()
+	if parts == nil {
+		text := e.scrub(msg.ContentString())
+		if text == "" && e.format == FormatOpenAI {
+			return nil, true
+		}
+		return text, true
+	}
+
+	out := make([]providers.ContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.FileID != "":
+			return nil, false
+		case part.ImageURL != nil:
+			if e.format != FormatOpenAI {
+				return nil, false
+			}
+			out = append(out, part)
+		default:
+			part.Text = e.scrub(part.Text)
+			out = append(out, part)
+		}
+	}
+	return out, true
+}
+
+// convert returns messages as a dataset line, and false if they cannot be
+// used for training.
+func (e *Exporter) convert(messages []providers.Message) (example, bool) {
+	last := -1
+	for i, msg := range messages {
+		if msg.Role == providers.RoleAssistant {
+			last = i
+		}
+	}
+	if last < 0 {
+		return example{}, false
+	}
+
+	out := make([]message, 0, last+1)
+	for _, msg := range messages[:last+1] {
+		content, ok := e.content(msg)
+		if !ok {
+			return example{}, false
+		}
+
+		m := message{
+			Content:    content,
+			Role:       msg.Role,
+			ToolCallID: e.toolCallID(msg.ToolCallID),
+		}
+		if e.format == FormatOpenAI {
+			m.Name = msg.Name
+		}
+		for _, call := range msg.ToolCalls {
+			arguments, ok := e.scrubArguments(call.Function.Arguments)
+			if !ok {
+				return example{}, false
+			}
+			call.ID = e.toolCallID(call.ID)
+			call.Function.Arguments = arguments
+			m.ToolCalls = append(m.ToolCalls, call)
+		}
+		out = append(out, m)
+	}
+
+	return example{Messages: out}, true
+}
+
+// scrub applies the scrubbers to text.
+func (e *Exporter) scrub(text string) string {
+	for _, s := range e.scrubbers {
+		text = s(text)
+	}
+	return text
+}
+
+// scrubArguments applies the scrubbers to the string values of JSON tool
+// call arguments, so that they cannot break the JSON or rewrite its keys. It
+// returns false if arguments are not valid JSON.
+func (e *Exporter) scrubArguments(arguments string) (string, bool) {
+	if arguments == "" {
+		return arguments, true
+	}
+	if len(e.scrubbers) == 0 {
+		return arguments, json.Valid([]byte(arguments))
+	}
+
+	dec := json.NewDecoder(strings.NewReader(arguments))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil || dec.More() {
+		return "", false
+	}
+
+	data, err := json.Marshal(e.scrubValue(value))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// scrubValue applies the scrubbers to the strings in a decoded JSON value.
+func (e *Exporter) scrubValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return e.scrub(v)
+	case []any:
+		for i := range v {
+			v[i] = e.scrubValue(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = e.scrubValue(v[key])
+		}
+	}
+	return value
+}
+
+// toolCallID returns id in the exporter's format. Mistral only accepts IDs
+// of nine letters and digits, so IDs are replaced by a prefix of their hash,
+// which keeps calls and results matched.
+func (e *Exporter) toolCallID(id string) string {
+	if id == "" || e.format != FormatMistral {
+		return id
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])[:mistralToolCallIDLength]
+}
+
+// ScrubPattern returns a scrubber that replaces every match of re with
+// replacement.
+func ScrubPattern(re *regexp.Regexp, replacement string) Scrubber {
+	return func(text string) string {
+		return re.ReplaceAllString(text, replacement)
+	}
+}
+
+// ScrubPII replaces email addresses, payment card numbers, IPv4 addresses and
+// phone numbers with placeholders such as "[EMAIL]". It catches common
+// formats only; add ScrubPattern scrubbers for data specific to your domain.
+func ScrubPII(text string) string {
+	for _, p := range piiPatterns {
+		text = p.re.ReplaceAllString(text, p.placeholder)
+	}
+	return text
+}
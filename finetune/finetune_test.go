@@ -0,0 +1,327 @@
+package finetune
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/chat"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// toolConversation returns a conversation with a tool call and its result.
+func toolConversation() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleUser, Content: "What's the weather in Paris?"},
+		{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+			ID:       "call_abc123def456",
+			Type:     "function",
+			Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}}},
+		{Role: providers.RoleTool, Content: "18°C and sunny", ToolCallID: "call_abc123def456"},
+		{Role: providers.RoleAssistant, Content: "It's 18°C and sunny in Paris."},
+	}
+}
+
+// lines decodes the dataset in buf.
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var out []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		format  Format
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name:    "rejects unknown formats",
+			format:  "anthropic",
+			wantErr: `unsupported format "anthropic"`,
+		},
+		{
+			name:    "rejects nil filters",
+			format:  FormatOpenAI,
+			opts:    []Option{WithFilter(nil)},
+			wantErr: "filter must not be nil",
+		},
+		{
+			name:    "rejects nil scrubbers",
+			format:  FormatMistral,
+			opts:    []Option{WithScrubber(nil)},
+			wantErr: "scrubber must not be nil",
+		},
+		{
+			name:   "accepts valid options",
+			format: FormatMistral,
+			opts:   []Option{nil, WithScrubber(ScrubPII)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(&bytes.Buffer{}, tc.format, tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestExporterWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes OpenAI examples", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI)
+		require.NoError(t, err)
+
+		require.NoError(t, e.Write("s1", toolConversation()))
+
+		want := `{"messages":[` +
+			`{"content":"What's the weather in Paris?","role":"user"},` +
+			`{"role":"assistant","tool_calls":[{"id":"call_abc123def456","type":"function",` +
+			`"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},` +
+			`{"content":"18°C and sunny","role":"tool","tool_call_id":"call_abc123def456"},` +
+			`{"content":"It's 18°C and sunny in Paris.","role":"assistant"}]}` + "\n"
+		require.Equal(t, want, buf.String())
+		require.Equal(t, Stats{Exported: 1}, e.Stats())
+	})
+
+	t.Run("writes Mistral examples with short tool call IDs", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatMistral)
+		require.NoError(t, err)
+
+		require.NoError(t, e.Write("s1", toolConversation()))
+
+		messages := lines(t, &buf)[0]["messages"].([]any)
+		call := messages[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+		result := messages[2].(map[string]any)
+
+		require.Equal(t, "", messages[1].(map[string]any)["content"])
+		require.Regexp(t, `^[0-9a-zA-Z]{9}$`, call["id"])
+		require.Equal(t, call["id"], result["tool_call_id"])
+	})
+
+	t.Run("drops messages after the last assistant reply", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI)
+		require.NoError(t, err)
+
+		conversation := append(toolConversation(), providers.Message{Role: providers.RoleUser, Content: "Thanks!"})
+		require.NoError(t, e.Write("s1", conversation))
+
+		require.Len(t, lines(t, &buf)[0]["messages"], 4)
+	})
+
+	t.Run("skips conversations that cannot be used for training", func(t *testing.T) {
+		t.Parallel()
+
+		image := []providers.ContentPart{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/cat.png"}},
+		}
+		conversations := [][]providers.Message{
+			{{Role: providers.RoleUser, Content: "Hello?"}},
+			{
+				{Role: providers.RoleUser, Content: image},
+				{Role: providers.RoleAssistant, Content: "A cat."},
+			},
+		}
+
+		var openaiBuf, mistralBuf bytes.Buffer
+		openai, err := New(&openaiBuf, FormatOpenAI)
+		require.NoError(t, err)
+		mistral, err := New(&mistralBuf, FormatMistral)
+		require.NoError(t, err)
+
+		for _, c := range conversations {
+			require.NoError(t, openai.Write("s", c))
+			require.NoError(t, mistral.Write("s", c))
+		}
+
+		// OpenAI datasets may include images; Mistral datasets may not.
+		require.Equal(t, Stats{Exported: 1, Skipped: 1}, openai.Stats())
+		require.Equal(t, Stats{Skipped: 2}, mistral.Stats())
+		require.Empty(t, mistralBuf.String())
+	})
+
+	t.Run("applies filters", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI, WithFilter(func(id string, _ []providers.Message) bool {
+			return id != "rejected"
+		}))
+		require.NoError(t, err)
+
+		require.NoError(t, e.Write("rejected", toolConversation()))
+		require.NoError(t, e.Write("accepted", toolConversation()))
+
+		require.Equal(t, Stats{Exported: 1, Filtered: 1}, e.Stats())
+	})
+
+	t.Run("scrubs text, content parts and tool call arguments", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI,
+			WithScrubber(ScrubPII),
+			WithScrubber(ScrubPattern(regexp.MustCompile(`ACME-\d+`), "[ACCOUNT]")),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, e.Write("s1", []providers.Message{
+			{Role: providers.RoleUser, Content: []providers.ContentPart{
+				{Type: "text", Text: "I'm jane@example.com, account ACME-42."},
+			}},
+			{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: providers.FunctionCall{Name: "lookup", Arguments: `{"email":"jane@example.com"}`},
+			}}},
+			{Role: providers.RoleTool, Content: "Phone: (555) 123-4567", ToolCallID: "call_1"},
+			{Role: providers.RoleAssistant, Content: "Found your account."},
+		}))
+
+		out := buf.String()
+		require.NotContains(t, out, "jane@example.com")
+		require.NotContains(t, out, "ACME-42")
+		require.NotContains(t, out, "123-4567")
+		require.Contains(t, out, "[ACCOUNT]")
+		require.Contains(t, out, `{\"email\":\"[EMAIL]\"}`)
+	})
+
+	t.Run("scrubs only string values of tool call arguments", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI,
+			WithScrubber(ScrubPattern(regexp.MustCompile(`email|ACME-\d+`), `"[REDACTED]"`)),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, e.Write("s1", []providers.Message{
+			{Role: providers.RoleUser, Content: "Look up my account."},
+			{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: providers.FunctionCall{Name: "lookup", Arguments: `{"email":"ACME-42","ids":[7,"ACME-7"]}`},
+			}}},
+			{Role: providers.RoleTool, Content: "Found.", ToolCallID: "call_1"},
+			{Role: providers.RoleAssistant, Content: "Found your account."},
+		}))
+
+		var ex example
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &ex))
+		arguments := ex.Messages[1].ToolCalls[0].Function.Arguments
+		require.JSONEq(t, `{"email":"\"[REDACTED]\"","ids":[7,"\"[REDACTED]\""]}`, arguments)
+	})
+
+	t.Run("skips conversations with invalid tool call arguments", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		e, err := New(&buf, FormatOpenAI, WithScrubber(ScrubPII))
+		require.NoError(t, err)
+
+		conversation := toolConversation()
+		conversation[1].ToolCalls[0].Function.Arguments = `{"city":"Paris"`
+		require.NoError(t, e.Write("s1", conversation))
+
+		require.Equal(t, Stats{Skipped: 1}, e.Stats())
+		require.Empty(t, buf.String())
+	})
+}
+
+func TestExporterExport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := chat.NewMemoryStore()
+	require.NoError(t, store.Append(ctx, "b", toolConversation()...))
+	require.NoError(t, store.Append(ctx, "a", toolConversation()...))
+	require.NoError(t, store.Append(ctx, "c", providers.Message{Role: providers.RoleUser, Content: "Hi"}))
+
+	var buf bytes.Buffer
+	e, err := New(&buf, FormatOpenAI)
+	require.NoError(t, err)
+
+	require.NoError(t, e.Export(ctx, store))
+	require.Len(t, lines(t, &buf), 2)
+	require.Equal(t, Stats{Exported: 2, Skipped: 1}, e.Stats())
+}
+
+func TestScrubPII(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "email addresses",
+			text: "Write to jane.doe+news@mail.example.org today.",
+			want: "Write to [EMAIL] today.",
+		},
+		{
+			name: "card numbers",
+			text: "Card 4111 1111 1111 1111 expires soon.",
+			want: "Card [CARD] expires soon.",
+		},
+		{
+			name: "IP addresses",
+			text: "Request from 192.168.0.12 was blocked.",
+			want: "Request from [IP] was blocked.",
+		},
+		{
+			name: "phone numbers",
+			text: "Call +1 555-123-4567 or (555) 987 6543.",
+			want: "Call [PHONE] or [PHONE].",
+		},
+		{
+			name: "plain text and short numbers",
+			text: "Order 42 shipped in 2026.",
+			want: "Order 42 shipped in 2026.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, ScrubPII(tc.text))
+		})
+	}
+}
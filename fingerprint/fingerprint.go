@@ -0,0 +1,223 @@
+// Package fingerprint hashes model outputs and finds near-duplicates in a
+// batch of them. Many outputs that should differ but are the same, or nearly
+// the same, point to mode collapse or to a cache that returns the wrong
+// entry.
+//
+// Outputs are normalized before hashing, so that case, punctuation and
+// whitespace do not count. Each output gets an exact hash of its normalized
+// text and a MinHash signature of its word pairs, from which the similarity
+// of two outputs is estimated without comparing their texts.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the similarity from which outputs are near-duplicates
+// when Duplicates is given a threshold of 0. It flags rewordings of a few
+// words in outputs of a paragraph or more.
+const DefaultThreshold = 0.8
+
+// MinHash settings.
+const (
+	// shingleSize is the number of words in a shingle.
+	shingleSize = 2
+
+	// signatureSize is the number of hashes in a signature. Similarity
+	// estimates are within about 0.06 of the true value.
+	signatureSize = 64
+)
+
+// Fingerprint identifies an output.
+type Fingerprint struct {
+	// Hash is the hex SHA-256 of the normalized output. Outputs with the same
+	// Hash are duplicates.
+	Hash string `json:"hash"`
+
+	// MinHash is the MinHash signature of the normalized output's word
+	// pairs, from which Similarity estimates how much of their wording two
+	// outputs share. It is empty for outputs that are empty once normalized.
+	MinHash []uint64 `json:"minhash,omitempty"`
+}
+
+// Group is a set of outputs that are duplicates or near-duplicates.
+type Group struct {
+	// Exact reports that all the outputs have the same Hash.
+	Exact bool `json:"exact"`
+
+	// Indexes are the positions of the outputs in the input, in order.
+	Indexes []int `json:"indexes"`
+}
+
+// Of returns the fingerprint of output.
+func Of(output string) Fingerprint {
+	normalized := Normalize(output)
+	sum := sha256.Sum256([]byte(normalized))
+
+	return Fingerprint{
+		Hash:    hex.EncodeToString(sum[:]),
+		MinHash: minHash(strings.Fields(normalized)),
+	}
+}
+
+// Similarity estimates the Jaccard similarity of the word pairs of the
+// outputs of f and g: 1 for outputs that normalize to the same text, near 1
+// for rewordings, and near 0 for unrelated outputs.
+func (f Fingerprint) Similarity(g Fingerprint) float64 {
+	if f.Hash == g.Hash {
+		return 1
+	}
+	if len(f.MinHash) == 0 || len(f.MinHash) != len(g.MinHash) {
+		return 0
+	}
+
+	same := 0
+	for i := range f.MinHash {
+		if f.MinHash[i] == g.MinHash[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(f.MinHash))
+}
+
+// Duplicates returns the groups of outputs that are duplicates or
+// near-duplicates of each other, ordered by their first output. Outputs are
+// near-duplicates when their Similarity is at least threshold, and
+// near-duplicates of near-duplicates join the same group. A threshold of 1
+// finds only outputs that normalize to the same text; 0 uses
+// DefaultThreshold. Outputs that are empty once normalized are ignored.
+func Duplicates(outputs []string, threshold float64) []Group {
+	fingerprints := make([]Fingerprint, len(outputs))
+	for i, output := range outputs {
+		fingerprints[i] = Of(output)
+	}
+	return DuplicatesOf(fingerprints, threshold)
+}
+
+// DuplicatesOf returns the groups of duplicates among fingerprints, like
+// Duplicates. Use it when the fingerprints were stored, to compare outputs
+// across runs without keeping them. Fingerprints without a MinHash, such as
+// those of empty outputs and zero Fingerprints, are ignored.
+func DuplicatesOf(fingerprints []Fingerprint, threshold float64) []Group {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	// Union-find over the pairs of near-duplicates.
+	parent := make([]int, len(fingerprints))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range fingerprints {
+		if len(fingerprints[i].MinHash) == 0 {
+			continue
+		}
+		for j := i + 1; j < len(fingerprints); j++ {
+			if len(fingerprints[j].MinHash) == 0 {
+				continue
+			}
+			if fingerprints[i].Similarity(fingerprints[j]) >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range fingerprints {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+
+	var groups []Group
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+
+		exact := true
+		for _, i := range indexes[1:] {
+			exact = exact && fingerprints[i].Hash == fingerprints[indexes[0]].Hash
+		}
+		groups = append(groups, Group{Exact: exact, Indexes: indexes})
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return a.Indexes[0] - b.Indexes[0]
+	})
+
+	return groups
+}
+
+// Normalize returns output lowercased, without punctuation or symbols, and
+// with runs of whitespace collapsed to single spaces.
+func Normalize(output string) string {
+	var b strings.Builder
+	b.Grow(len(output))
+
+	space := false
+	for _, r := range output {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+
+	return b.String()
+}
+
+// minHash returns the MinHash signature of the shingles of words, or of the
+// words joined if there are fewer than a shingle's worth.
+func minHash(words []string) []uint64 {
+	if len(words) == 0 {
+		return nil
+	}
+
+	signature := make([]uint64, signatureSize)
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	add := func(shingle string) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(shingle)) // Writes to a hash never fail.
+		sum := h.Sum64()
+		for i := range signature {
+			signature[i] = min(signature[i], mix(sum+uint64(i)*0x9e3779b97f4a7c15))
+		}
+	}
+
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	return signature
+}
+
+// mix returns a well-distributed hash of x (SplitMix64's finalizer), which
+// turns one shingle hash into the signatureSize hashes of a signature.
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,114 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testParagraph is an output long enough for near-duplicate detection.
+const testParagraph = "The quarterly report shows revenue grew by twelve percent, driven mostly " +
+	"by subscriptions in Europe and Asia. Costs stayed flat, so the operating margin " +
+	"improved for the third quarter in a row. The board expects growth to slow next " +
+	"year as the new pricing takes effect and competition in the region increases."
+
+// testRewording is testParagraph with one word changed.
+const testRewording = "The quarterly report shows revenue grew by twelve percent, driven mostly " +
+	"by subscriptions in Europe and Asia. Costs stayed flat, so the operating margin " +
+	"improved for the third quarter in a row. The board expects growth to slow next " +
+	"year as the new pricing takes effect and competition in the market increases."
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "lowercases", output: "Hello World", want: "hello world"},
+		{name: "drops punctuation", output: "Hello, world! (Really?)", want: "hello world really"},
+		{name: "collapses whitespace", output: "  hello \n\n\tworld  ", want: "hello world"},
+		{name: "keeps numbers and letters", output: "Café costs €4.50", want: "café costs 450"},
+		{name: "empty", output: " ... ", want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, Normalize(tc.output))
+		})
+	}
+}
+
+func TestOf(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ignores case, punctuation and whitespace", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, Of("Hello, World!"), Of("hello   world"))
+		require.NotEqual(t, Of("hello world").Hash, Of("hello there").Hash)
+	})
+
+	t.Run("estimates similarity", func(t *testing.T) {
+		t.Parallel()
+
+		f, g := Of(testParagraph), Of(testRewording)
+		require.NotEqual(t, f.Hash, g.Hash)
+		require.Equal(t, 1.0, f.Similarity(Of(testParagraph)))
+		require.GreaterOrEqual(t, f.Similarity(g), DefaultThreshold)
+		require.Less(t, f.Similarity(Of("A completely different answer about the weather in Lisbon today.")), 0.2)
+	})
+
+	t.Run("has no signature for empty outputs", func(t *testing.T) {
+		t.Parallel()
+
+		require.Empty(t, Of(" ! ").MinHash)
+		require.Zero(t, Of("").Similarity(Of("hello")))
+	})
+}
+
+func TestDuplicates(t *testing.T) {
+	t.Parallel()
+
+	outputs := []string{
+		testParagraph,
+		"Paris is the capital of France.",
+		"paris is the capital of france",
+		testRewording,
+		"Berlin is the capital of Germany.",
+		"",
+		"  ",
+	}
+
+	t.Run("groups duplicates and near-duplicates", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, []Group{
+			{Exact: false, Indexes: []int{0, 3}},
+			{Exact: true, Indexes: []int{1, 2}},
+		}, Duplicates(outputs, 0))
+	})
+
+	t.Run("finds only exact duplicates with a threshold of 1", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, []Group{{Exact: true, Indexes: []int{1, 2}}}, Duplicates(outputs, 1))
+	})
+
+	t.Run("matches stored fingerprints", func(t *testing.T) {
+		t.Parallel()
+
+		fingerprints := []Fingerprint{Of("one answer"), Of("another answer"), Of("One answer.")}
+		require.Equal(t, []Group{{Exact: true, Indexes: []int{0, 2}}}, DuplicatesOf(fingerprints, 0))
+	})
+
+	t.Run("handles no duplicates", func(t *testing.T) {
+		t.Parallel()
+
+		require.Empty(t, Duplicates([]string{"yes", "no"}, 0))
+		require.Empty(t, Duplicates(nil, 0))
+	})
+}
@@ -5,6 +5,7 @@ go 1.25
 require (
 	github.com/anthropics/anthropic-sdk-go v1.21.0
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mozilla-ai/any-llm-platform-client-go v0.0.1
 	github.com/ollama/ollama v0.15.4
 	github.com/openai/openai-go v1.12.0
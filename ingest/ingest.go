@@ -0,0 +1,243 @@
+// Package ingest prepares documents for retrieval: a document is read with
+// OCR, the text of each page is split into chunks, and the chunks are
+// embedded. It works with any providers.DocumentProvider and
+// providers.EmbeddingProvider, which need not be the same provider.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Pipeline defaults.
+const (
+	defaultBatchSize = 64
+	defaultChunkSize = 2000
+)
+
+// mediaTypePDF is the media type of PDF data URLs.
+const mediaTypePDF = "application/pdf"
+
+// separators are tried in order to split text that does not fit a chunk.
+var separators = []string{"\n\n", "\n", ". ", " "}
+
+// Option configures a Pipeline.
+type Option func(*Pipeline) error
+
+// Chunk is a piece of a document with its embedding.
+type Chunk struct {
+	// Embedding is the embedding of Text.
+	Embedding []float64
+
+	// Page is the index of the page the chunk is from. Chunks never span
+	// pages.
+	Page int
+
+	// Text is the chunk's text, in Markdown as the OCR model wrote it.
+	Text string
+}
+
+// Pipeline reads, chunks and embeds documents.
+type Pipeline struct {
+	batchSize      int
+	chunkSize      int
+	embedder       providers.EmbeddingProvider
+	embeddingModel string
+	ocr            providers.DocumentProvider
+	ocrModel       string
+}
+
+// Result is an ingested document.
+type Result struct {
+	// Chunks are the document's chunks, in order.
+	Chunks []Chunk
+
+	// EmbeddingUsage is the total usage of the embedding requests.
+	EmbeddingUsage providers.EmbeddingUsage
+
+	// Pages is the number of pages read.
+	Pages int
+}
+
+// New returns a Pipeline that reads documents with ocrModel on ocr and embeds
+// their chunks with embeddingModel on embedder. By default chunks are up to
+// 2000 characters, and are embedded 64 at a time.
+func New(
+	ocr providers.DocumentProvider,
+	ocrModel string,
+	embedder providers.EmbeddingProvider,
+	embeddingModel string,
+	opts ...Option,
+) (*Pipeline, error) {
+	if ocrModel == "" || embeddingModel == "" {
+		return nil, fmt.Errorf("OCR and embedding models are required")
+	}
+
+	p := &Pipeline{
+		batchSize:      defaultBatchSize,
+		chunkSize:      defaultChunkSize,
+		embedder:       embedder,
+		embeddingModel: embeddingModel,
+		ocr:            ocr,
+		ocrModel:       ocrModel,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithBatchSize sets how many chunks are embedded per request.
+func WithBatchSize(n int) Option {
+	return func(p *Pipeline) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be positive, got %d", n)
+		}
+		p.batchSize = n
+		return nil
+	}
+}
+
+// WithChunkSize sets the maximum size of a chunk in characters. Text is split
+// at paragraphs, then lines, sentences and words, to keep chunks under it.
+func WithChunkSize(chars int) Option {
+	return func(p *Pipeline) error {
+		if chars <= 0 {
+			return fmt.Errorf("chunk size must be positive, got %d", chars)
+		}
+		p.chunkSize = chars
+		return nil
+	}
+}
+
+// Ingest reads, chunks and embeds a PDF.
+func (p *Pipeline) Ingest(ctx context.Context, pdf []byte) (*Result, error) {
+	if len(pdf) == 0 {
+		return nil, errors.NewInvalidRequestError(p.ocr.Name(), fmt.Errorf("document is empty"))
+	}
+	return p.IngestURL(ctx, providers.DataURL(mediaTypePDF, pdf))
+}
+
+// IngestURL reads, chunks and embeds the document at url, which may be a data
+// URL. Which URLs and document types are accepted depends on the OCR
+// provider.
+func (p *Pipeline) IngestURL(ctx context.Context, url string) (*Result, error) {
+	doc, err := p.ocr.OCR(ctx, providers.OCRParams{Model: p.ocrModel, Document: url})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Pages: len(doc.Pages)}
+	for _, page := range doc.Pages {
+		for _, text := range split(page.Markdown, p.chunkSize) {
+			result.Chunks = append(result.Chunks, Chunk{Page: page.Index, Text: text})
+		}
+	}
+
+	for start := 0; start < len(result.Chunks); start += p.batchSize {
+		end := min(start+p.batchSize, len(result.Chunks))
+		if err := p.embed(ctx, result.Chunks[start:end], result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// embed embeds a batch of chunks, and adds the usage to result.
+func (p *Pipeline) embed(ctx context.Context, chunks []Chunk, result *Result) error {
+	input := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		input = append(input, chunk.Text)
+	}
+
+	resp, err := p.embedder.Embedding(ctx, providers.EmbeddingParams{Model: p.embeddingModel, Input: input})
+	if err != nil {
+		return err
+	}
+	if len(resp.Data) != len(chunks) {
+		return errors.NewProviderError(p.embedder.Name(),
+			fmt.Errorf("got %d embeddings for %d chunks", len(resp.Data), len(chunks)))
+	}
+
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(chunks) {
+			return errors.NewProviderError(p.embedder.Name(), fmt.Errorf("embedding index %d out of range", data.Index))
+		}
+		chunks[data.Index].Embedding = data.Embedding
+	}
+	if resp.Usage != nil {
+		result.EmbeddingUsage.PromptTokens += resp.Usage.PromptTokens
+		result.EmbeddingUsage.TotalTokens += resp.Usage.TotalTokens
+	}
+
+	return nil
+}
+
+// split splits text into chunks of at most limit characters. It cuts at the
+// first separator that occurs in text, packs neighbouring pieces back together
+// while they fit, and splits pieces that are still too long further.
+func split(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	for _, sep := range separators {
+		parts := strings.SplitAfter(text, sep)
+		if len(parts) < 2 {
+			continue
+		}
+
+		var chunks []string
+		var current strings.Builder
+		size := 0
+		flush := func() {
+			if chunk := strings.TrimSpace(current.String()); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+			current.Reset()
+			size = 0
+		}
+
+		for _, part := range parts {
+			// The separator at the end of a part is dropped if it ends a chunk.
+			n := utf8.RuneCountInString(strings.TrimRightFunc(part, unicode.IsSpace))
+			if size > 0 && size+n > limit {
+				flush()
+			}
+			if n > limit {
+				chunks = append(chunks, split(part, limit)...)
+				continue
+			}
+			current.WriteString(part)
+			size += utf8.RuneCountInString(part)
+		}
+		flush()
+
+		return chunks
+	}
+
+	runes := []rune(text)
+	chunks := make([]string, 0, len(runes)/limit+1)
+	for start := 0; start < len(runes); start += limit {
+		chunks = append(chunks, string(runes[start:min(start+limit, len(runes))]))
+	}
+	return chunks
+}
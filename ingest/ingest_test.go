@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// lengthEmbedding embeds text as its length.
+func lengthEmbedding(text string) []float64 {
+	return []float64{float64(len(text))}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+
+	pipeline, err := New(mock, "ocr", mock, "embed", nil, WithBatchSize(8), WithChunkSize(500))
+	require.NoError(t, err)
+	require.Equal(t, 8, pipeline.batchSize)
+	require.Equal(t, 500, pipeline.chunkSize)
+
+	_, err = New(mock, "", mock, "embed")
+	require.Error(t, err)
+
+	_, err = New(mock, "ocr", mock, "embed", WithBatchSize(0))
+	require.Error(t, err)
+
+	_, err = New(mock, "ocr", mock, "embed", WithChunkSize(-1))
+	require.Error(t, err)
+}
+
+func TestIngest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reads, chunks and embeds a PDF", func(t *testing.T) {
+		t.Parallel()
+
+		ocr := testutil.NewMockProvider()
+		ocr.OCRFunc = func(_ context.Context, params providers.OCRParams) (*providers.OCRResponse, error) {
+			return &providers.OCRResponse{Model: params.Model, Pages: []providers.OCRPage{
+				{Index: 0, Markdown: "# Title\n\nFirst paragraph.\n\nSecond paragraph."},
+				{Index: 1, Markdown: "Last page."},
+			}}, nil
+		}
+		embedder := testutil.NewEmbeddingMock(lengthEmbedding)
+
+		pipeline, err := New(ocr, "ocr-model", embedder, "embed-model", WithChunkSize(30), WithBatchSize(2))
+		require.NoError(t, err)
+
+		result, err := pipeline.Ingest(context.Background(), []byte("%PDF-"))
+		require.NoError(t, err)
+
+		require.Equal(t, []providers.OCRParams{
+			{Model: "ocr-model", Document: "data:application/pdf;base64,JVBERi0="},
+		}, ocr.OCRCalls)
+		require.Equal(t, 2, result.Pages)
+		require.Equal(t, []Chunk{
+			{Embedding: []float64{25}, Page: 0, Text: "# Title\n\nFirst paragraph."},
+			{Embedding: []float64{17}, Page: 0, Text: "Second paragraph."},
+			{Embedding: []float64{10}, Page: 1, Text: "Last page."},
+		}, result.Chunks)
+		require.Len(t, embedder.EmbeddingCalls, 2)
+		require.Equal(t, "embed-model", embedder.EmbeddingCalls[0].Model)
+		require.Equal(t, providers.EmbeddingUsage{PromptTokens: 3, TotalTokens: 3}, result.EmbeddingUsage)
+	})
+
+	t.Run("rejects an empty document", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		pipeline, err := New(mock, "ocr", mock, "embed")
+		require.NoError(t, err)
+
+		_, err = pipeline.Ingest(context.Background(), nil)
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+		require.Empty(t, mock.OCRCalls)
+	})
+
+	t.Run("rejects missing embeddings", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.OCRFunc = func(context.Context, providers.OCRParams) (*providers.OCRResponse, error) {
+			return &providers.OCRResponse{Pages: []providers.OCRPage{{Markdown: "One.\n\nTwo."}}}, nil
+		}
+		pipeline, err := New(mock, "ocr", mock, "embed", WithChunkSize(5))
+		require.NoError(t, err)
+
+		_, err = pipeline.IngestURL(context.Background(), "https://example.com/a.pdf")
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "empty", text: " \n ", limit: 10, want: nil},
+		{name: "fits", text: " Hello. ", limit: 10, want: []string{"Hello."}},
+		{name: "paragraphs", text: "aaa\n\nbbb\n\nccc", limit: 8, want: []string{"aaa\n\nbbb", "ccc"}},
+		{name: "long paragraph by sentence", text: "One. Two. Three.\n\nFour", limit: 10,
+			want: []string{"One. Two.", "Three.", "Four"}},
+		{name: "words", text: "alpha beta gamma", limit: 11, want: []string{"alpha beta", "gamma"}},
+		{name: "no separator", text: "abcdefgh", limit: 3, want: []string{"abc", "def", "gh"}},
+		{name: "multibyte", text: "ééééé", limit: 2, want: []string{"éé", "éé", "é"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			chunks := split(tc.text, tc.limit)
+			require.Equal(t, tc.want, chunks)
+			for _, chunk := range chunks {
+				require.LessOrEqual(t, utf8.RuneCountInString(chunk), tc.limit)
+			}
+		})
+	}
+
+	t.Run("keeps all words", func(t *testing.T) {
+		t.Parallel()
+
+		text := strings.Repeat("word ", 500) + "\n\n" + strings.Repeat("line\n", 100)
+		chunks := split(text, 37)
+		require.Equal(t, strings.Fields(text), strings.Fields(strings.Join(chunks, " ")))
+	})
+}
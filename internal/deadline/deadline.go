@@ -0,0 +1,96 @@
+// Package deadline keeps the retry loops inside the Anthropic and OpenAI SDKs
+// within the caller's context.
+//
+// Both SDKs retry failed requests on their own and sleep between attempts
+// without watching the request's context, so a retry can start after the
+// caller has given up, or the caller can wait out a delay that ends past its
+// deadline only to have the context error returned.
+package deadline
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers read and written by the SDKs' retry loops.
+const (
+	headerRetryAfter   = "Retry-After"
+	headerRetryAfterMs = "Retry-After-Ms"
+	headerRetryCount   = "X-Stainless-Retry-Count"
+	headerShouldRetry  = "X-Should-Retry"
+)
+
+// The SDKs' retry delays: the server's Retry-After if it is under a minute,
+// otherwise exponential backoff with up to a quarter taken off as jitter.
+const (
+	maxRetryAfter  = time.Minute
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// Middleware is SDK middleware, installed with the SDKs' option.WithMiddleware,
+// that stops their retry loops from outliving the request's context. It does
+// not start an attempt once the context is done, and it marks a failed attempt
+// as final when the context's deadline will pass before the SDK's shortest
+// wait for the next attempt ends, so the error is returned at once.
+func Middleware(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp, err := next(req)
+	if err == nil && resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok || ctx.Err() != nil || time.Now().Add(minRetryDelay(req, resp)).Before(deadline) {
+		return resp, err
+	}
+
+	if resp == nil {
+		// The SDKs retry failed connections unless told otherwise by a response.
+		resp = &http.Response{Body: http.NoBody, Request: req}
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	resp.Header.Set(headerShouldRetry, "false")
+	return resp, err
+}
+
+// minRetryDelay returns the shortest time the SDK waits before retrying req
+// after resp, which is nil if the connection failed.
+func minRetryDelay(req *http.Request, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header); ok && delay >= 0 && delay < maxRetryAfter {
+			return delay
+		}
+	}
+
+	retryCount, _ := strconv.Atoi(req.Header.Get(headerRetryCount)) // A missing count is the first attempt.
+	delay := time.Duration(float64(retryBaseDelay) * math.Pow(2, float64(retryCount)))
+	delay = min(delay, retryMaxDelay)
+	return delay - delay/4
+}
+
+// retryAfter returns the delay the server asked for, as the SDKs read it:
+// milliseconds from Retry-After-Ms, or seconds or an HTTP date from
+// Retry-After.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	if ms, err := strconv.ParseFloat(header.Get(headerRetryAfterMs), 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+
+	value := header.Get(headerRetryAfter)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if at, err := time.Parse(time.RFC1123, value); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
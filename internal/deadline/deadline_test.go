@@ -0,0 +1,137 @@
+package deadline
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// respond returns a next handler that answers with status and header, and
+// counts its calls.
+func respond(status int, header http.Header, calls *int) func(*http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls++
+		return &http.Response{Body: http.NoBody, Header: header.Clone(), Request: req, StatusCode: status}, nil
+	}
+}
+
+// newRequest returns a request with ctx that is the SDK's retryCount'th retry.
+func newRequest(t *testing.T, ctx context.Context, retryCount int) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://example.com/v1/messages", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set(headerRetryCount, strconv.Itoa(retryCount))
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("does not start attempts once the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls int
+		_, err := Middleware(newRequest(t, ctx, 1), respond(http.StatusOK, nil, &calls))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, calls)
+	})
+
+	t.Run("stops retries that would end past the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		var calls int
+		resp, err := Middleware(newRequest(t, ctx, 0), respond(http.StatusServiceUnavailable, nil, &calls))
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+		require.Equal(t, "false", resp.Header.Get(headerShouldRetry))
+	})
+
+	t.Run("keeps retries that end before the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var calls int
+		resp, err := Middleware(newRequest(t, ctx, 0), respond(http.StatusServiceUnavailable, nil, &calls))
+		require.NoError(t, err)
+		require.Empty(t, resp.Header.Get(headerShouldRetry))
+	})
+
+	t.Run("keeps short server-requested retries", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		var calls int
+		header := http.Header{headerRetryAfterMs: []string{"1"}}
+		resp, err := Middleware(newRequest(t, ctx, 0), respond(http.StatusTooManyRequests, header, &calls))
+		require.NoError(t, err)
+		require.Empty(t, resp.Header.Get(headerShouldRetry))
+	})
+
+	t.Run("leaves requests without a deadline alone", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int
+		resp, err := Middleware(newRequest(t, context.Background(), 0), respond(http.StatusServiceUnavailable, nil, &calls))
+		require.NoError(t, err)
+		require.Empty(t, resp.Header.Get(headerShouldRetry))
+	})
+
+	t.Run("stops retries of failed connections", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		dialErr := stderrors.New("connection refused")
+		resp, err := Middleware(newRequest(t, ctx, 0), func(*http.Request) (*http.Response, error) {
+			return nil, dialErr
+		})
+		require.ErrorIs(t, err, dialErr)
+		require.NotNil(t, resp)
+		require.Equal(t, "false", resp.Header.Get(headerShouldRetry))
+	})
+}
+
+func TestMinRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		retryCount int
+		want       time.Duration
+	}{
+		{name: "first retry", want: 375 * time.Millisecond},
+		{name: "third retry", retryCount: 2, want: 1500 * time.Millisecond},
+		{name: "capped backoff", retryCount: 10, want: 6 * time.Second},
+		{name: "retry after ms", header: http.Header{headerRetryAfterMs: []string{"250"}}, want: 250 * time.Millisecond},
+		{name: "retry after seconds", header: http.Header{headerRetryAfter: []string{"2"}}, want: 2 * time.Second},
+		{name: "retry after too long", header: http.Header{headerRetryAfter: []string{"120"}}, want: 375 * time.Millisecond},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := newRequest(t, context.Background(), tc.retryCount)
+			resp := &http.Response{Header: tc.header}
+			require.Equal(t, tc.want, minRetryDelay(req, resp))
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Package llamaserver provides access to the native (non-OpenAI) endpoints
+// exposed by the llama.cpp HTTP server, which llamafile also embeds.
+package llamaserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Native endpoint paths, relative to the server root.
+const (
+	pathCompletion = "/completion"
+	pathDetokenize = "/detokenize"
+	pathProps      = "/props"
+	pathTokenize   = "/tokenize"
+)
+
+// ggufExtension ends the file names of models loaded by llama.cpp.
+const ggufExtension = ".gguf"
+
+// HTTP and server-sent event constants.
+const (
+	authorizationBearer  = "Bearer "
+	contentTypeJSON      = "application/json"
+	headerAuthorization  = "Authorization"
+	headerContentType    = "Content-Type"
+	initialStreamBufSize = 64 * 1024
+	maxStreamLineSize    = 1024 * 1024
+	openAIPathSuffix     = "/v1"
+	sseDataPrefix        = "data:"
+)
+
+// stopTypeLimit is the /completion stop type for generation that ended at
+// the token limit.
+const stopTypeLimit = "limit"
+
+// quantizationPattern matches the quantization type in a GGUF file name, such
+// as "Q4_K_M" in "llama-3-8b-instruct.Q4_K_M.gguf".
+var quantizationPattern = regexp.MustCompile(`(?i)(?:^|[-._])(I?Q\d+(?:_[A-Z0-9]+)*|BF16|F16|F32)(?:[-._]|$)`)
+
+// Client calls the native endpoints of a llama.cpp server.
+type Client struct {
+	apiKey       string
+	baseURL      string
+	httpClient   *http.Client
+	providerName string
+}
+
+// completionRequest is the request body for /completion.
+type completionRequest struct {
+	NPredict    *int     `json:"n_predict,omitempty"`
+	Prompt      string   `json:"prompt"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Stream      bool     `json:"stream,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// completionResponse is the response body for /completion, and each event of
+// its stream. Stop is set on the final event.
+type completionResponse struct {
+	Content string `json:"content"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Stop            bool   `json:"stop"`
+	StopType        string `json:"stop_type"`
+	StoppedLimit    bool   `json:"stopped_limit"` // Reported by servers without stop_type.
+	TokensEvaluated int    `json:"tokens_evaluated"`
+	TokensPredicted int    `json:"tokens_predicted"`
+}
+
+// propsResponse is the part of the /props response a model card is read from.
+type propsResponse struct {
+	ChatTemplate              string `json:"chat_template"`
+	DefaultGenerationSettings struct {
+		NCtx int `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+	ModelPath string `json:"model_path"`
+}
+
+// New creates a Client for the server at baseURL.
+// The baseURL may be the OpenAI-compatible URL (ending in /v1); the native
+// endpoints live at the server root, so the suffix is stripped. A non-empty
+// apiKey is sent as a bearer token, for servers started with --api-key.
+func New(providerName, baseURL, apiKey string, httpClient *http.Client) *Client {
+	return &Client{
+		apiKey:       apiKey,
+		baseURL:      ServerRoot(baseURL),
+		httpClient:   httpClient,
+		providerName: providerName,
+	}
+}
+
+// Complete sends a raw prompt to the server's /completion endpoint, which
+// applies no chat template.
+func (c *Client) Complete(
+	ctx context.Context,
+	params providers.TextCompletionParams,
+) (*providers.TextCompletion, error) {
+	var resp completionResponse
+	if err := c.post(ctx, pathCompletion, convertCompletionRequest(params, false), &resp); err != nil {
+		return nil, err
+	}
+
+	return convertCompletionResponse(&resp, params.Model), nil
+}
+
+// CompleteStream streams the completion of a raw prompt from the /completion
+// endpoint. Each value carries the next piece of content; the last also
+// carries the finish reason and usage.
+func (c *Client) CompleteStream(
+	ctx context.Context,
+	params providers.TextCompletionParams,
+) (<-chan providers.TextCompletion, <-chan error) {
+	deltas := make(chan providers.TextCompletion)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(deltas)
+		defer close(errs)
+
+		payload, err := json.Marshal(convertCompletionRequest(params, true))
+		if err != nil {
+			errs <- errors.NewInvalidRequestError(c.providerName, fmt.Errorf("encoding request: %w", err))
+			return
+		}
+
+		resp, err := c.send(ctx, http.MethodPost, pathCompletion, bytes.NewReader(payload))
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, initialStreamBufSize), maxStreamLineSize)
+
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), sseDataPrefix)
+			if !ok {
+				continue
+			}
+
+			var event completionResponse
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				errs <- errors.NewProviderError(c.providerName, fmt.Errorf("decoding stream event: %w", err))
+				return
+			}
+
+			if event.Error != nil {
+				errs <- errors.NewProviderError(c.providerName, stderrors.New(event.Error.Message))
+				return
+			}
+
+			delta := providers.TextCompletion{Content: event.Content, Model: params.Model}
+			if event.Stop {
+				delta = *convertCompletionResponse(&event, params.Model)
+			}
+
+			select {
+			case deltas <- delta:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() != nil {
+				errs <- ctx.Err() // The read failed because the stream was cancelled.
+				return
+			}
+			errs <- errors.NewProviderError(c.providerName, err)
+		}
+	}()
+
+	return deltas, errs
+}
+
+// CompletionBody returns the /completion request body Complete would send for
+// params, without sending it.
+func (c *Client) CompletionBody(params providers.TextCompletionParams) (json.RawMessage, error) {
+	body, err := json.Marshal(convertCompletionRequest(params, false))
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(c.providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
+// Detokenize converts tokens back into text using the server's tokenizer.
+func (c *Client) Detokenize(
+	ctx context.Context,
+	params providers.DetokenizeParams,
+) (*providers.DetokenizeResponse, error) {
+	var resp providers.DetokenizeResponse
+	if err := c.post(ctx, pathDetokenize, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ModelCard reads the loaded model's metadata from the server's /props
+// endpoint: the context size the server was started with, the chat template,
+// and the quantization named in the model's file name. A server hosting
+// several models selects one by model; a single-model server ignores it.
+func (c *Client) ModelCard(ctx context.Context, model string) (*providers.ModelCard, error) {
+	target := pathProps
+	if model != "" {
+		target += "?" + url.Values{"model": {model}}.Encode()
+	}
+
+	var resp propsResponse
+	if err := c.do(ctx, http.MethodGet, target, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(path.Base(resp.ModelPath), ggufExtension)
+	card := &providers.ModelCard{
+		ChatTemplate:  resp.ChatTemplate,
+		ContextLength: resp.DefaultGenerationSettings.NCtx,
+		ID:            model,
+		Quantization:  quantization(name),
+	}
+	if card.ID == "" && resp.ModelPath != "" {
+		card.ID = name
+	}
+
+	return card, nil
+}
+
+// Tokenize converts text into tokens using the server's tokenizer.
+func (c *Client) Tokenize(
+	ctx context.Context,
+	params providers.TokenizeParams,
+) (*providers.TokenizeResponse, error) {
+	var resp providers.TokenizeResponse
+	if err := c.post(ctx, pathTokenize, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// do sends a request with an optional JSON body to the given path and decodes
+// the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.NewProviderError(c.providerName, fmt.Errorf("decoding %s response: %w", path, err))
+	}
+
+	return nil
+}
+
+// post sends a JSON request to the given path and decodes the JSON response into out.
+func (c *Client) post(ctx context.Context, path string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errors.NewInvalidRequestError(c.providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return c.do(ctx, http.MethodPost, path, bytes.NewReader(payload), out)
+}
+
+// send sends a request with an optional JSON body to the given path,
+// returning an error for non-2xx statuses. The caller must close the response
+// body.
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(c.providerName, err)
+	}
+	if body != nil {
+		req.Header.Set(headerContentType, contentTypeJSON)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(headerAuthorization, authorizationBearer+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.NewProviderError(c.providerName, err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+		respBody, _ := io.ReadAll(resp.Body)     // Best effort; body is only used for the error message.
+		statusErr := fmt.Errorf("%s returned status %d: %s", path, resp.StatusCode, bytes.TrimSpace(respBody))
+		return nil, errors.FromStatusCode(c.providerName, resp.StatusCode, statusErr)
+	}
+
+	return resp, nil
+}
+
+// convertCompletionRequest converts text completion params to a /completion
+// request body.
+func convertCompletionRequest(params providers.TextCompletionParams, stream bool) completionRequest {
+	return completionRequest{
+		NPredict:    params.MaxTokens,
+		Prompt:      params.Prompt,
+		Seed:        params.Seed,
+		Stop:        params.Stop,
+		Stream:      stream,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+	}
+}
+
+// convertCompletionResponse converts a final /completion response to a text
+// completion.
+func convertCompletionResponse(resp *completionResponse, model string) *providers.TextCompletion {
+	finishReason := providers.FinishReasonStop
+	if resp.StopType == stopTypeLimit || resp.StoppedLimit {
+		finishReason = providers.FinishReasonLength
+	}
+
+	return &providers.TextCompletion{
+		Content:      resp.Content,
+		FinishReason: finishReason,
+		Model:        model,
+		Usage: &providers.Usage{
+			CompletionTokens: resp.TokensPredicted,
+			PromptTokens:     resp.TokensEvaluated,
+			TotalTokens:      resp.TokensEvaluated + resp.TokensPredicted,
+		},
+	}
+}
+
+// quantization returns the quantization type named in a model file name, in
+// upper case, or "" if it names none.
+func quantization(name string) string {
+	m := quantizationPattern.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
+
+// ServerRoot strips a trailing OpenAI-compatible "/v1" path from baseURL.
+func ServerRoot(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimRight(baseURL, "/"), openAIPathSuffix)
+}
@@ -0,0 +1,320 @@
+package llamaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+const testProviderName = "test-provider"
+
+func TestServerRoot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		expected string
+	}{
+		{name: "strips v1 suffix", baseURL: "http://localhost:8080/v1", expected: "http://localhost:8080"},
+		{name: "strips v1 suffix with trailing slash", baseURL: "http://localhost:8080/v1/", expected: "http://localhost:8080"},
+		{name: "keeps root URL", baseURL: "http://localhost:8080", expected: "http://localhost:8080"},
+		{name: "keeps other paths", baseURL: "http://proxy/llama", expected: "http://proxy/llama"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, ServerRoot(tc.baseURL))
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("posts content and decodes tokens", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, pathTokenize, r.URL.Path)
+
+			var params providers.TokenizeParams
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+			require.Equal(t, "Hello world", params.Content)
+			require.True(t, params.AddSpecial)
+
+			_, _ = w.Write([]byte(`{"tokens":[1,15043,3186]}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL+"/v1", "", server.Client())
+		resp, err := client.Tokenize(context.Background(), providers.TokenizeParams{
+			Content:    "Hello world",
+			AddSpecial: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 15043, 3186}, resp.Tokens)
+		require.Equal(t, 3, resp.Count())
+	})
+
+	t.Run("sends the API key as a bearer token", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				http.Error(w, `{"error":{"message":"Invalid API Key"}}`, http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"tokens":[1]}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := New(testProviderName, server.URL, "", server.Client()).Tokenize(
+			context.Background(),
+			providers.TokenizeParams{Content: "x"},
+		)
+		require.ErrorIs(t, err, errors.ErrAuthentication)
+
+		resp, err := New(testProviderName, server.URL, "secret", server.Client()).Tokenize(
+			context.Background(),
+			providers.TokenizeParams{Content: "x"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, 1, resp.Count())
+	})
+
+	t.Run("converts error statuses", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "bad content", http.StatusBadRequest)
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		_, err := client.Tokenize(context.Background(), providers.TokenizeParams{Content: "x"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("records status code on provider errors", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "not found", http.StatusNotFound)
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		_, err := client.Tokenize(context.Background(), providers.TokenizeParams{Content: "x"})
+
+		var providerErr *errors.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, http.StatusNotFound, providerErr.StatusCode)
+		require.Equal(t, testProviderName, providerErr.Provider)
+	})
+}
+
+func TestDetokenize(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, pathDetokenize, r.URL.Path)
+
+		var params providers.DetokenizeParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		require.Equal(t, []int{15043, 3186}, params.Tokens)
+
+		_, _ = w.Write([]byte(`{"content":"Hello world"}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(testProviderName, server.URL, "", server.Client())
+	resp, err := client.Detokenize(context.Background(), providers.DetokenizeParams{Tokens: []int{15043, 3186}})
+	require.NoError(t, err)
+	require.Equal(t, "Hello world", resp.Content)
+}
+
+func TestModelCard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reads the loaded model's props", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodGet, r.Method)
+			require.Equal(t, pathProps, r.URL.Path)
+			require.Empty(t, r.URL.Query().Get("model"))
+
+			_, _ = w.Write([]byte(`{
+				"default_generation_settings": {"n_ctx": 8192},
+				"chat_template": "{{ messages }}",
+				"model_path": "/models/llama-3-8b-instruct.Q4_K_M.gguf"
+			}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL+"/v1", "", server.Client())
+		card, err := client.ModelCard(context.Background(), "")
+		require.NoError(t, err)
+		require.Equal(t, &providers.ModelCard{
+			ChatTemplate:  "{{ messages }}",
+			ContextLength: 8192,
+			ID:            "llama-3-8b-instruct.Q4_K_M",
+			Quantization:  "Q4_K_M",
+		}, card)
+	})
+
+	t.Run("selects the model on multi-model servers", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "qwen", r.URL.Query().Get("model"))
+			_, _ = w.Write([]byte(`{"default_generation_settings": {"n_ctx": 4096}}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		card, err := client.ModelCard(context.Background(), "qwen")
+		require.NoError(t, err)
+		require.Equal(t, "qwen", card.ID)
+		require.Equal(t, 4096, card.ContextLength)
+	})
+
+	t.Run("converts error statuses", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "not found", http.StatusNotFound)
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		_, err := client.ModelCard(context.Background(), "")
+
+		var providerErr *errors.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, http.StatusNotFound, providerErr.StatusCode)
+	})
+}
+
+func TestQuantization(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{name: "k-quant", file: "llama-3-8b-instruct.Q4_K_M", expected: "Q4_K_M"},
+		{name: "legacy quant", file: "mistral-7b-q8_0", expected: "Q8_0"},
+		{name: "i-quant", file: "phi-3-mini-IQ3_XS", expected: "IQ3_XS"},
+		{name: "float", file: "gemma-2b-f16", expected: "F16"},
+		{name: "bfloat", file: "gemma-2b-BF16", expected: "BF16"},
+		{name: "none", file: "tinyllama", expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, quantization(tc.file))
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, pathCompletion, r.URL.Path)
+
+		var req completionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "Once upon a time", req.Prompt)
+		require.Equal(t, 16, *req.NPredict)
+		require.False(t, req.Stream)
+
+		_, _ = w.Write([]byte(`{
+			"content": " there was a llama.",
+			"stop": true,
+			"stop_type": "limit",
+			"tokens_evaluated": 5,
+			"tokens_predicted": 16
+		}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	maxTokens := 16
+	client := New(testProviderName, server.URL, "", server.Client())
+	resp, err := client.Complete(context.Background(), providers.TextCompletionParams{
+		MaxTokens: &maxTokens,
+		Model:     "llama",
+		Prompt:    "Once upon a time",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &providers.TextCompletion{
+		Content:      " there was a llama.",
+		FinishReason: providers.FinishReasonLength,
+		Model:        "llama",
+		Usage:        &providers.Usage{CompletionTokens: 16, PromptTokens: 5, TotalTokens: 21},
+	}, resp)
+}
+
+func TestCompleteStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streams content then the finish reason", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req completionRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.True(t, req.Stream)
+
+			_, _ = w.Write([]byte("data: {\"content\":\"Hello\",\"stop\":false}\n\n" +
+				"data: {\"content\":\" world\",\"stop\":false}\n\n" +
+				"data: {\"content\":\"\",\"stop\":true,\"stop_type\":\"eos\",\"tokens_evaluated\":3,\"tokens_predicted\":2}\n\n",
+			)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		deltas, errs := client.CompleteStream(context.Background(), providers.TextCompletionParams{Prompt: "Say hi"})
+
+		var content strings.Builder
+		var last providers.TextCompletion
+		for delta := range deltas {
+			content.WriteString(delta.Content)
+			last = delta
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, "Hello world", content.String())
+		require.Equal(t, providers.FinishReasonStop, last.FinishReason)
+		require.Equal(t, 5, last.Usage.TotalTokens)
+	})
+
+	t.Run("surfaces error events", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("data: {\"error\":{\"message\":\"context full\"}}\n\n")) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		client := New(testProviderName, server.URL, "", server.Client())
+		deltas, errs := client.CompleteStream(context.Background(), providers.TextCompletionParams{Prompt: "x"})
+		for range deltas {
+			t.Fatal("unexpected delta")
+		}
+		require.ErrorContains(t, <-errs, "context full")
+	})
+}
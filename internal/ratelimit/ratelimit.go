@@ -0,0 +1,113 @@
+// Package ratelimit parses the rate limit state providers report in response headers.
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Anthropic header formats, filled with the window name ("requests" or "tokens").
+// Anthropic reports resets as RFC 3339 timestamps.
+const (
+	anthropicLimit     = "anthropic-ratelimit-%s-limit"
+	anthropicRemaining = "anthropic-ratelimit-%s-remaining"
+	anthropicReset     = "anthropic-ratelimit-%s-reset"
+)
+
+// OpenAI header formats, also used by Groq and other OpenAI-compatible servers.
+// OpenAI reports resets as durations such as "1s" or "6m0s".
+const (
+	openAILimit     = "x-ratelimit-limit-%s"
+	openAIRemaining = "x-ratelimit-remaining-%s"
+	openAIReset     = "x-ratelimit-reset-%s"
+)
+
+// Rate limit windows.
+const (
+	windowRequests = "requests"
+	windowTokens   = "tokens"
+)
+
+// headerSet names the headers of one window.
+type headerSet struct {
+	limit     string
+	remaining string
+	reset     string
+}
+
+// Parse returns the rate limit state in h, or nil when h reports none.
+// It understands the OpenAI x-ratelimit-* and Anthropic anthropic-ratelimit-*
+// headers. now is when the response was received, for converting reset times.
+func Parse(h http.Header, now time.Time) *providers.RateLimitState {
+	state := &providers.RateLimitState{
+		Requests: parseWindow(h, now, windowRequests),
+		Tokens:   parseWindow(h, now, windowTokens),
+	}
+
+	if state.Requests == nil && state.Tokens == nil {
+		return nil
+	}
+	return state
+}
+
+// headerSets returns the header names that may carry window, in lookup order.
+func headerSets(window string) []headerSet {
+	return []headerSet{
+		{
+			limit:     fmt.Sprintf(openAILimit, window),
+			remaining: fmt.Sprintf(openAIRemaining, window),
+			reset:     fmt.Sprintf(openAIReset, window),
+		},
+		{
+			limit:     fmt.Sprintf(anthropicLimit, window),
+			remaining: fmt.Sprintf(anthropicRemaining, window),
+			reset:     fmt.Sprintf(anthropicReset, window),
+		},
+	}
+}
+
+// parseReset converts a reset header value to the time until the reset.
+// It accepts Go-style durations ("6m0s", "20ms"), plain seconds ("30") and
+// RFC 3339 timestamps.
+func parseReset(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// parseWindow returns the state of window, or nil when no remaining count is reported.
+func parseWindow(h http.Header, now time.Time, window string) *providers.RateLimit {
+	for _, set := range headerSets(window) {
+		remaining, err := strconv.Atoi(h.Get(set.remaining))
+		if err != nil {
+			continue
+		}
+
+		limit, _ := strconv.Atoi(h.Get(set.limit))
+		return &providers.RateLimit{
+			Limit:     limit,
+			Remaining: remaining,
+			Reset:     parseReset(h.Get(set.reset), now),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    *providers.RateLimitState
+	}{
+		{
+			name: "no headers",
+			want: nil,
+		},
+		{
+			name: "openai",
+			headers: map[string]string{
+				"x-ratelimit-limit-requests":     "60",
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-reset-requests":     "1m30.5s",
+				"x-ratelimit-limit-tokens":       "150000",
+				"x-ratelimit-remaining-tokens":   "149984",
+				"x-ratelimit-reset-tokens":       "20ms",
+			},
+			want: &providers.RateLimitState{
+				Requests: &providers.RateLimit{Limit: 60, Remaining: 0, Reset: 90*time.Second + 500*time.Millisecond},
+				Tokens:   &providers.RateLimit{Limit: 150000, Remaining: 149984, Reset: 20 * time.Millisecond},
+			},
+		},
+		{
+			name: "anthropic",
+			headers: map[string]string{
+				"anthropic-ratelimit-requests-limit":     "50",
+				"anthropic-ratelimit-requests-remaining": "49",
+				"anthropic-ratelimit-requests-reset":     "2026-01-02T03:04:35Z",
+			},
+			want: &providers.RateLimitState{
+				Requests: &providers.RateLimit{Limit: 50, Remaining: 49, Reset: 30 * time.Second},
+			},
+		},
+		{
+			name: "reset in seconds without limit",
+			headers: map[string]string{
+				"x-ratelimit-remaining-tokens": "10",
+				"x-ratelimit-reset-tokens":     "7",
+			},
+			want: &providers.RateLimitState{
+				Tokens: &providers.RateLimit{Remaining: 10, Reset: 7 * time.Second},
+			},
+		},
+		{
+			name: "reset in the past",
+			headers: map[string]string{
+				"anthropic-ratelimit-tokens-remaining": "5",
+				"anthropic-ratelimit-tokens-reset":     "2026-01-02T03:00:00Z",
+			},
+			want: &providers.RateLimitState{
+				Tokens: &providers.RateLimit{Remaining: 5},
+			},
+		},
+		{
+			name: "malformed remaining",
+			headers: map[string]string{
+				"x-ratelimit-remaining-requests": "lots",
+			},
+			want: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+
+			require.Equal(t, tc.want, Parse(h, now))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package streambench measures the cost of converting streamed chunks: the
+// time and allocations each provider spends per chunk between reading a
+// server-sent event and delivering the chunk on its channel. Streams are
+// served from memory, so the network does not distort the numbers.
+//
+// Run the benchmarks with:
+//
+//	go test -run '^$' -bench . -benchmem ./internal/streambench
+package streambench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Response constants.
+const (
+	contentTypeEventStream = "text/event-stream"
+	headerContentType      = "Content-Type"
+)
+
+// delta is the text each generated chunk carries.
+const delta = "token "
+
+// Transport answers every request with the same server-sent event stream.
+type Transport struct {
+	body string
+}
+
+// NewTransport returns a Transport that streams body.
+func NewTransport(body string) *Transport {
+	return &Transport{body: body}
+}
+
+// RoundTrip answers req with the Transport's stream.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body) // Draining the request is best effort.
+		_ = req.Body.Close()                 // Close error is not actionable.
+	}
+
+	return &http.Response{
+		Body:          io.NopCloser(strings.NewReader(t.body)),
+		ContentLength: int64(len(t.body)),
+		Header:        http.Header{headerContentType: {contentTypeEventStream}},
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Request:       req,
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+	}, nil
+}
+
+// AnthropicStream returns an Anthropic Messages stream with n text deltas.
+func AnthropicStream(n int) string {
+	var b strings.Builder
+	writeEvent(&b, "message_start", `{"type":"message_start","message":{"id":"msg_bench","type":"message",`+
+		`"role":"assistant","model":"claude-bench","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`)
+	writeEvent(&b, "content_block_start",
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`)
+	for range n {
+		writeEvent(&b, "content_block_delta",
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"`+delta+`"}}`)
+	}
+	writeEvent(&b, "content_block_stop", `{"type":"content_block_stop","index":0}`)
+	writeEvent(&b, "message_delta", fmt.Sprintf(
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":%d}}`, n))
+	writeEvent(&b, "message_stop", `{"type":"message_stop"}`)
+	return b.String()
+}
+
+// OpenAIStream returns an OpenAI chat completion stream with n content
+// deltas, followed by the finish reason and usage.
+func OpenAIStream(n int) string {
+	const prefix = `{"id":"chatcmpl-bench","object":"chat.completion.chunk","created":1700000000,"model":"gpt-bench",`
+
+	var b strings.Builder
+	writeData(&b, prefix+`"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`)
+	for range n {
+		writeData(&b, prefix+`"choices":[{"index":0,"delta":{"content":"`+delta+`"},"finish_reason":null}]}`)
+	}
+	writeData(&b, prefix+`"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)
+	writeData(&b, prefix+fmt.Sprintf(
+		`"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":%d,"total_tokens":%d}}`, n, n+10))
+	writeData(&b, "[DONE]")
+	return b.String()
+}
+
+// Run benchmarks streaming params from the provider newProvider creates with
+// a Transport serving body, which holds chunks deltas. Besides the usual
+// per-operation figures, it reports the allocations and time per chunk.
+func Run(
+	b *testing.B,
+	newProvider func(opts ...config.Option) (providers.Provider, error),
+	body string,
+	chunks int,
+	params providers.CompletionParams,
+) {
+	b.Helper()
+
+	provider, err := newProvider(
+		config.WithAPIKey("bench"),
+		config.WithBaseURL("http://bench.invalid"),
+		config.WithHTTPClient(&http.Client{Transport: NewTransport(body)}),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var before, after runtime.MemStats
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	runtime.ReadMemStats(&before)
+
+	for range b.N {
+		stream, errs := provider.CompletionStream(ctx, params)
+		for range stream {
+			// Draining the stream is the work measured.
+		}
+		if err := <-errs; err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	runtime.ReadMemStats(&after)
+	b.StopTimer()
+
+	total := float64(b.N * chunks)
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/total, "allocs/chunk")
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/total, "ns/chunk")
+}
+
+// writeData writes an unnamed server-sent event.
+func writeData(b *strings.Builder, data string) {
+	b.WriteString("data: ")
+	b.WriteString(data)
+	b.WriteString("\n\n")
+}
+
+// writeEvent writes a named server-sent event.
+func writeEvent(b *strings.Builder, event, data string) {
+	b.WriteString("event: ")
+	b.WriteString(event)
+	b.WriteString("\n")
+	writeData(b, data)
+}
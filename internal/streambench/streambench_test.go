@@ -0,0 +1,70 @@
+package streambench
+
+import (
+	"testing"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/anthropic"
+	"github.com/mozilla-ai/any-llm-go/providers/groq"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// benchChunks is the number of content deltas in each benchmarked stream.
+const benchChunks = 256
+
+// benchParams returns the params of every benchmarked request.
+func benchParams(model string) providers.CompletionParams {
+	maxTokens := 1024
+	return providers.CompletionParams{
+		Model:     model,
+		MaxTokens: &maxTokens,
+		Messages:  []providers.Message{{Role: providers.RoleUser, Content: "Count."}},
+		Stream:    true,
+	}
+}
+
+func BenchmarkAnthropicStream(b *testing.B) {
+	Run(b, func(opts ...config.Option) (providers.Provider, error) {
+		return anthropic.New(opts...)
+	}, AnthropicStream(benchChunks), benchChunks, benchParams("claude-bench"))
+}
+
+func BenchmarkGroqStream(b *testing.B) {
+	Run(b, func(opts ...config.Option) (providers.Provider, error) {
+		return groq.New(opts...)
+	}, OpenAIStream(benchChunks), benchChunks, benchParams("qwen-bench"))
+}
+
+func BenchmarkOpenAIStream(b *testing.B) {
+	Run(b, func(opts ...config.Option) (providers.Provider, error) {
+		return openai.New(opts...)
+	}, OpenAIStream(benchChunks), benchChunks, benchParams("gpt-bench"))
+}
+
+func BenchmarkAccumulator(b *testing.B) {
+	chunk := providers.ChatCompletionChunk{
+		ID:      "chatcmpl-bench",
+		Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: delta}}},
+	}
+
+	b.ReportAllocs()
+	for range b.N {
+		var acc providers.Accumulator
+		for range benchChunks {
+			acc.Add(chunk)
+		}
+		_ = acc.Completion() // Joining the chunks is part of the work measured.
+	}
+}
+
+func TestStreams(t *testing.T) {
+	t.Parallel()
+
+	// The benchmark streams must convert without errors, or the benchmarks
+	// measure the error path.
+	result := testing.Benchmark(BenchmarkOpenAIStream)
+	if result.N == 0 {
+		t.Fatal("OpenAI stream benchmark did not run")
+	}
+}
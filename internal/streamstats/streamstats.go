@@ -0,0 +1,83 @@
+// Package streamstats measures the chunk cadence and output throughput of
+// streaming completions.
+package streamstats
+
+import (
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Recorder tracks chunk timing for a single stream.
+// It is only accessed from the streaming goroutine, so no synchronization is needed.
+type Recorder struct {
+	chunks   int
+	first    time.Time
+	last     time.Time
+	maxGap   time.Duration
+	now      func() time.Time
+	start    time.Time
+	totalGap time.Duration
+}
+
+// New creates a Recorder whose clock starts now. Create it just before the
+// request is sent, so that time to first chunk and duration include
+// connection and header latency.
+func New() *Recorder {
+	return newWithClock(time.Now)
+}
+
+// Observe records that a chunk is being emitted.
+func (r *Recorder) Observe() {
+	now := r.now()
+
+	if r.chunks == 0 {
+		r.first = now
+	} else {
+		gap := now.Sub(r.last)
+		r.totalGap += gap
+		r.maxGap = max(r.maxGap, gap)
+	}
+
+	r.last = now
+	r.chunks++
+}
+
+// Timings returns the statistics for the chunks observed so far.
+// Tokens per second is derived from outputTokens over the time between the
+// first and last chunk, so it excludes time to first chunk; it is omitted
+// when outputTokens is unknown.
+func (r *Recorder) Timings(outputTokens int) *providers.Timings {
+	if r.chunks == 0 {
+		return &providers.Timings{}
+	}
+
+	timings := &providers.Timings{
+		ChunkCount:       r.chunks,
+		Duration:         r.last.Sub(r.start),
+		TimeToFirstChunk: r.first.Sub(r.start),
+		MaxChunkGap:      r.maxGap,
+	}
+
+	if r.chunks > 1 {
+		timings.MeanChunkGap = r.totalGap / time.Duration(r.chunks-1)
+	}
+
+	window := r.last.Sub(r.first)
+	if window <= 0 {
+		window = timings.Duration
+	}
+	if outputTokens > 0 && window > 0 {
+		timings.TokensPerSecond = float64(outputTokens) / window.Seconds()
+	}
+
+	return timings
+}
+
+// newWithClock creates a Recorder that reads the time from now.
+func newWithClock(now func() time.Time) *Recorder {
+	return &Recorder{
+		now:   now,
+		start: now(),
+	}
+}
@@ -0,0 +1,80 @@
+package streamstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// fakeClock returns a clock that yields each of the given offsets from a fixed start in turn.
+func fakeClock(offsets ...time.Duration) func() time.Time {
+	start := time.Unix(1700000000, 0)
+	i := 0
+	return func() time.Time {
+		offset := offsets[i]
+		i++
+		return start.Add(offset)
+	}
+}
+
+func TestRecorderTimings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("computes cadence and throughput", func(t *testing.T) {
+		t.Parallel()
+
+		r := newWithClock(fakeClock(
+			0,                    // Request sent.
+			200*time.Millisecond, // First chunk.
+			300*time.Millisecond,
+			600*time.Millisecond,
+			1200*time.Millisecond, // Final chunk.
+		))
+		for range 4 {
+			r.Observe()
+		}
+
+		require.Equal(t, &providers.Timings{
+			ChunkCount:       4,
+			Duration:         1200 * time.Millisecond,
+			TimeToFirstChunk: 200 * time.Millisecond,
+			MeanChunkGap:     time.Second / 3,
+			MaxChunkGap:      600 * time.Millisecond,
+			TokensPerSecond:  20,
+		}, r.Timings(20))
+	})
+
+	t.Run("omits throughput without token count", func(t *testing.T) {
+		t.Parallel()
+
+		r := newWithClock(fakeClock(0, 100*time.Millisecond, 500*time.Millisecond))
+		r.Observe()
+		r.Observe()
+
+		timings := r.Timings(0)
+		require.Equal(t, 2, timings.ChunkCount)
+		require.Zero(t, timings.TokensPerSecond)
+	})
+
+	t.Run("uses total duration for a single chunk", func(t *testing.T) {
+		t.Parallel()
+
+		r := newWithClock(fakeClock(0, 500*time.Millisecond))
+		r.Observe()
+
+		timings := r.Timings(10)
+		require.Equal(t, 1, timings.ChunkCount)
+		require.Zero(t, timings.MeanChunkGap)
+		require.InDelta(t, 20.0, timings.TokensPerSecond, 0.001)
+	})
+
+	t.Run("returns empty timings before any chunk", func(t *testing.T) {
+		t.Parallel()
+
+		r := newWithClock(fakeClock(0))
+		require.Equal(t, &providers.Timings{}, r.Timings(10))
+	})
+}
@@ -0,0 +1,262 @@
+package testutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Kinds of MessageEvent.
+const (
+	EventImage      = "image"
+	EventText       = "text"
+	EventToolCall   = "tool_call"
+	EventToolResult = "tool_result"
+)
+
+// Content part types.
+const (
+	contentTypeImageURL = "image_url"
+	contentTypeText     = "text"
+)
+
+// conversationSeeds is the number of seeds AddConversationSeeds adds to a
+// fuzz corpus.
+const conversationSeeds = 64
+
+// conversationTools are the names of the tools random conversations call.
+var conversationTools = []string{"get_weather", "get_date", "search"}
+
+// MessageEvent is one piece of content in a conversation: text, an image, a
+// tool call or a tool result. Conversion tests flatten messages, and the
+// requests a provider builds from them, into events to compare the two.
+type MessageEvent struct {
+	// Role is the role of the message the event is in. Tool results have
+	// RoleTool, whatever role the provider sends them with.
+	Role string
+
+	// Kind is the kind of event, one of the Event constants.
+	Kind string
+
+	// Value is the text, the image URL, the arguments of a tool call, or the
+	// content of a tool result.
+	Value string
+
+	// ID is the ID of a tool call, or the ID of the call a tool result
+	// answers.
+	ID string
+
+	// Name is the name of the function a tool call or tool result is for.
+	Name string
+}
+
+// AddConversationSeeds adds the seeds of RandomConversation to a fuzz corpus.
+func AddConversationSeeds(f *testing.F) {
+	f.Helper()
+
+	for seed := range uint64(conversationSeeds) {
+		f.Add(seed)
+	}
+}
+
+// ImageEvent returns an image event in a message with role. url is the
+// image's URL, or its data URL for inline images.
+func ImageEvent(role string, url string) MessageEvent {
+	return MessageEvent{Role: role, Kind: EventImage, Value: url}
+}
+
+// InlineImageEvent returns an image event for inline image data, as its data
+// URL. data is the base64 encoding of the image.
+func InlineImageEvent(role string, mimeType string, data string) MessageEvent {
+	return ImageEvent(role, "data:"+mimeType+";base64,"+data)
+}
+
+// MessageEvents flattens messages into events, in order. A tool result
+// without a Name takes the name of the tool call it answers, as providers
+// that pair results with calls by name do.
+func MessageEvents(messages []providers.Message) []MessageEvent {
+	var events []MessageEvent
+	callNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == providers.RoleTool:
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			events = append(events, ToolResultEvent(msg.ToolCallID, name, msg.ContentString()))
+		case msg.IsMultiModal():
+			for _, part := range msg.ContentParts() {
+				switch part.Type {
+				case contentTypeImageURL:
+					events = append(events, ImageEvent(msg.Role, part.ImageURL.URL))
+				case contentTypeText:
+					events = append(events, TextEvent(msg.Role, part.Text))
+				default:
+				}
+			}
+		default:
+			if text := msg.ContentString(); text != "" {
+				events = append(events, TextEvent(msg.Role, text))
+			}
+		}
+
+		for _, tc := range msg.ToolCalls {
+			callNames[tc.ID] = tc.Function.Name
+			events = append(events, ToolCallEvent(msg.Role, tc.ID, tc.Function.Name, tc.Function.Arguments))
+		}
+	}
+
+	return events
+}
+
+// RandomConversation returns a random, well-formed conversation for seed: an
+// optional system prompt, then turns of user messages with text and images,
+// assistant replies with text or parallel tool calls, and a tool result for
+// every call. Every text, argument and result is unique, so conversion tests
+// can tell a dropped or reordered piece of content from a repeated one.
+func RandomConversation(seed uint64) []providers.Message {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var messages []providers.Message
+	n := 0
+	unique := func(prefix string) string {
+		n++
+		return fmt.Sprintf("%s %d", prefix, n)
+	}
+
+	if rng.IntN(2) == 0 {
+		messages = append(messages, providers.Message{Role: providers.RoleSystem, Content: unique("system")})
+	}
+
+	for range 1 + rng.IntN(4) {
+		messages = append(messages, randomUserMessage(rng, unique))
+
+		for rng.IntN(3) > 0 {
+			calls := make([]providers.ToolCall, 0, 3)
+			for range 1 + rng.IntN(3) {
+				calls = append(calls, providers.ToolCall{
+					ID:   unique("call"),
+					Type: "function",
+					Function: providers.FunctionCall{
+						Name:      conversationTools[rng.IntN(len(conversationTools))],
+						Arguments: fmt.Sprintf(`{"query":%q}`, unique("query")),
+					},
+				})
+			}
+
+			assistant := providers.Message{Role: providers.RoleAssistant, ToolCalls: calls}
+			if rng.IntN(2) == 0 {
+				assistant.Content = unique("thinking aloud")
+			}
+			messages = append(messages, assistant)
+
+			for _, call := range calls {
+				result := providers.NewToolResultMessage(call.ID, providers.ToolResult{Content: unique("result")})
+				if rng.IntN(2) == 0 {
+					result.Name = call.Function.Name
+				}
+				messages = append(messages, result)
+			}
+		}
+
+		messages = append(messages, providers.Message{Role: providers.RoleAssistant, Content: unique("answer")})
+	}
+
+	return messages
+}
+
+// RequireToolPairing fails t unless every tool result in events answers an
+// earlier tool call that no other result answers, and every tool call is
+// answered. Results are matched by ID or, for providers that have no IDs, by
+// name.
+func RequireToolPairing(t *testing.T, events []MessageEvent) {
+	t.Helper()
+
+	var pending []MessageEvent
+	for _, event := range events {
+		switch event.Kind {
+		case EventToolCall:
+			pending = append(pending, event)
+		case EventToolResult:
+			i := slices.IndexFunc(pending, func(call MessageEvent) bool {
+				if event.ID != "" {
+					return call.ID == event.ID
+				}
+				return call.Name == event.Name
+			})
+			require.NotEqual(t, -1, i, "tool result %+v answers no pending tool call", event)
+			pending = slices.Delete(pending, i, i+1)
+		default:
+		}
+	}
+
+	require.Empty(t, pending, "tool calls without a result")
+}
+
+// TextEvent returns a text event in a message with role.
+func TextEvent(role string, text string) MessageEvent {
+	return MessageEvent{Role: role, Kind: EventText, Value: text}
+}
+
+// ToolCallEvent returns a tool call event in a message with role. arguments
+// is the JSON of the call's arguments.
+func ToolCallEvent(role string, id string, name string, arguments string) MessageEvent {
+	return MessageEvent{Role: role, Kind: EventToolCall, Value: arguments, ID: id, Name: name}
+}
+
+// ToolCallEventOf returns a tool call event like ToolCallEvent, for
+// arguments that a provider's SDK holds decoded, encoding them as JSON.
+func ToolCallEventOf(t *testing.T, role string, id string, name string, arguments any) MessageEvent {
+	t.Helper()
+
+	data, err := json.Marshal(arguments)
+	require.NoError(t, err)
+	return ToolCallEvent(role, id, name, string(data))
+}
+
+// ToolResultEvent returns the event of a tool result answering the call with
+// the given ID, which has RoleTool whatever role the provider sends it with.
+func ToolResultEvent(id string, name string, content string) MessageEvent {
+	return MessageEvent{Role: providers.RoleTool, Kind: EventToolResult, Value: content, ID: id, Name: name}
+}
+
+// WithUnknownRole returns a copy of messages with a message of an unknown
+// role inserted at a position chosen by seed.
+func WithUnknownRole(messages []providers.Message, seed uint64) []providers.Message {
+	i := int(seed % uint64(len(messages)+1))
+	return slices.Insert(slices.Clone(messages), i, providers.Message{Role: "developer", Content: "Be brief."})
+}
+
+// randomImageURL returns an image URL: either a data URL or a remote URL.
+func randomImageURL(rng *rand.Rand, unique func(string) string) string {
+	if rng.IntN(2) == 0 {
+		return "https://example.com/" + strings.ReplaceAll(unique("image"), " ", "-") + ".png"
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(unique("image")))
+}
+
+// randomUserMessage returns a user message with text and, sometimes, images.
+func randomUserMessage(rng *rand.Rand, unique func(string) string) providers.Message {
+	text := unique("question")
+	if rng.IntN(2) == 0 {
+		return providers.Message{Role: providers.RoleUser, Content: text}
+	}
+
+	parts := []providers.ContentPart{{Type: contentTypeText, Text: text}}
+	for range 1 + rng.IntN(2) {
+		parts = append(parts, providers.ContentPart{
+			Type:     contentTypeImageURL,
+			ImageURL: &providers.ImageURL{URL: randomImageURL(rng, unique)},
+		})
+	}
+	return providers.Message{Role: providers.RoleUser, Content: parts}
+}
@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"context"
+	"sync"
 
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
@@ -10,16 +11,27 @@ import (
 type MockProvider struct {
 	NameFunc             func() string
 	CompletionFunc       func(ctx context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error)
-	CompletionStreamFunc func(ctx context.Context, params providers.CompletionParams) (<-chan providers.ChatCompletionChunk, <-chan error)
-	EmbeddingFunc        func(ctx context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error)
-	ListModelsFunc       func(ctx context.Context) (*providers.ModelsResponse, error)
-	CapabilitiesFunc     func() providers.Capabilities
+	CompletionStreamFunc func(
+		ctx context.Context,
+		params providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error)
+	EmbeddingFunc func(
+		ctx context.Context,
+		params providers.EmbeddingParams,
+	) (*providers.EmbeddingResponse, error)
+	ListModelsFunc   func(ctx context.Context) (*providers.ModelsResponse, error)
+	OCRFunc          func(ctx context.Context, params providers.OCRParams) (*providers.OCRResponse, error)
+	CapabilitiesFunc func() providers.Capabilities
 
 	// Track calls for assertions.
 	CompletionCalls       []providers.CompletionParams
 	CompletionStreamCalls []providers.CompletionParams
 	EmbeddingCalls        []providers.EmbeddingParams
 	ListModelsCalls       int
+	OCRCalls              []providers.OCRParams
+
+	// mu guards call tracking so the mock can be called concurrently.
+	mu sync.Mutex
 }
 
 // Ensure MockProvider implements all interfaces.
@@ -28,13 +40,14 @@ var (
 	_ providers.EmbeddingProvider  = (*MockProvider)(nil)
 	_ providers.ModelLister        = (*MockProvider)(nil)
 	_ providers.CapabilityProvider = (*MockProvider)(nil)
+	_ providers.DocumentProvider   = (*MockProvider)(nil)
 )
 
 // NewMockProvider creates a new MockProvider with default implementations.
 func NewMockProvider() *MockProvider {
 	return &MockProvider{
 		NameFunc: func() string { return "mock" },
-		CompletionFunc: func(ctx context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		CompletionFunc: func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
 			return &providers.ChatCompletion{
 				ID:     "mock-completion-id",
 				Object: "chat.completion",
@@ -56,7 +69,10 @@ func NewMockProvider() *MockProvider {
 				},
 			}, nil
 		},
-		CompletionStreamFunc: func(ctx context.Context, params providers.CompletionParams) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		CompletionStreamFunc: func(
+			_ context.Context,
+			params providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
 			chunks := make(chan providers.ChatCompletionChunk, 3)
 			errs := make(chan error, 1)
 
@@ -92,7 +108,10 @@ func NewMockProvider() *MockProvider {
 
 			return chunks, errs
 		},
-		EmbeddingFunc: func(ctx context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+		EmbeddingFunc: func(
+			_ context.Context,
+			params providers.EmbeddingParams,
+		) (*providers.EmbeddingResponse, error) {
 			return &providers.EmbeddingResponse{
 				Object: "list",
 				Model:  params.Model,
@@ -118,6 +137,13 @@ func NewMockProvider() *MockProvider {
 				},
 			}, nil
 		},
+		OCRFunc: func(ctx context.Context, params providers.OCRParams) (*providers.OCRResponse, error) {
+			return &providers.OCRResponse{
+				Model: params.Model,
+				Pages: []providers.OCRPage{{Index: 0, Markdown: "# Hello World"}},
+				Usage: &providers.OCRUsage{PagesProcessed: 1},
+			}, nil
+		},
 		CapabilitiesFunc: func() providers.Capabilities {
 			return providers.Capabilities{
 				Completion:          true,
@@ -137,7 +163,9 @@ func (m *MockProvider) Completion(
 	ctx context.Context,
 	params providers.CompletionParams,
 ) (*providers.ChatCompletion, error) {
+	m.mu.Lock()
 	m.CompletionCalls = append(m.CompletionCalls, params)
+	m.mu.Unlock()
 	return m.CompletionFunc(ctx, params)
 }
 
@@ -145,7 +173,9 @@ func (m *MockProvider) CompletionStream(
 	ctx context.Context,
 	params providers.CompletionParams,
 ) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	m.mu.Lock()
 	m.CompletionStreamCalls = append(m.CompletionStreamCalls, params)
+	m.mu.Unlock()
 	return m.CompletionStreamFunc(ctx, params)
 }
 
@@ -153,15 +183,26 @@ func (m *MockProvider) Embedding(
 	ctx context.Context,
 	params providers.EmbeddingParams,
 ) (*providers.EmbeddingResponse, error) {
+	m.mu.Lock()
 	m.EmbeddingCalls = append(m.EmbeddingCalls, params)
+	m.mu.Unlock()
 	return m.EmbeddingFunc(ctx, params)
 }
 
 func (m *MockProvider) ListModels(ctx context.Context) (*providers.ModelsResponse, error) {
+	m.mu.Lock()
 	m.ListModelsCalls++
+	m.mu.Unlock()
 	return m.ListModelsFunc(ctx)
 }
 
+func (m *MockProvider) OCR(ctx context.Context, params providers.OCRParams) (*providers.OCRResponse, error) {
+	m.mu.Lock()
+	m.OCRCalls = append(m.OCRCalls, params)
+	m.mu.Unlock()
+	return m.OCRFunc(ctx, params)
+}
+
 func (m *MockProvider) Capabilities() providers.Capabilities {
 	return m.CapabilitiesFunc()
 }
@@ -0,0 +1,361 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// EnvRecordFixtures enables recording of stream parity fixtures. When it is
+// set to 1, VerifyStreamParity records fresh fixtures from the real API of
+// every provider whose API key is set, before replaying them.
+const EnvRecordFixtures = "ANYLLM_RECORD_FIXTURES"
+
+// parityDir is where stream parity fixtures live, relative to the package of
+// the test.
+const parityDir = "testdata/parity"
+
+// Fixture is a recorded exchange with a provider: the HTTP responses to a
+// completion and to a streaming completion with the same params. Only the
+// status, content type and body of the responses are kept; requests and
+// other headers, which may hold credentials, are not recorded.
+type Fixture struct {
+	// Params are the params both requests were sent with.
+	Params providers.CompletionParams `json:"params"`
+
+	// Provider is the name of the provider the responses came from.
+	Provider string `json:"provider"`
+
+	// Responses are the responses in the order they were received: the
+	// completion first, then the stream.
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// ParityScenario is a request that VerifyStreamParity sends with and
+// without streaming.
+type ParityScenario struct {
+	// Name names the fixture file.
+	Name string
+
+	// Params returns the params for a model.
+	Params func(model string) providers.CompletionParams
+}
+
+// ProviderFactory creates a provider with options, as the New function of a
+// provider package does.
+type ProviderFactory func(opts ...config.Option) (providers.Provider, error)
+
+// RecordedResponse is an HTTP response in a Fixture.
+type RecordedResponse struct {
+	Body        string `json:"body"`
+	ContentType string `json:"contentType"`
+	Status      int    `json:"status"`
+}
+
+// recordingTransport records the responses that pass through it.
+type recordingTransport struct {
+	base      http.RoundTripper
+	mu        sync.Mutex
+	responses []*recordingBody
+}
+
+// recordingBody is a response body that keeps what is read from it.
+type recordingBody struct {
+	io.ReadCloser
+
+	buf      bytes.Buffer
+	response RecordedResponse
+}
+
+// replayTransport answers requests with recorded responses, in order.
+type replayTransport struct {
+	mu        sync.Mutex
+	responses []RecordedResponse
+}
+
+// ParityScenarios returns the scenarios VerifyStreamParity runs: a short text
+// answer and a tool call.
+func ParityScenarios() []ParityScenario {
+	return []ParityScenario{
+		{
+			Name: "text",
+			Params: func(model string) providers.CompletionParams {
+				return providers.CompletionParams{Model: model, Messages: SimpleMessages()}
+			},
+		},
+		{
+			Name: "tool_call",
+			Params: func(model string) providers.CompletionParams {
+				return providers.CompletionParams{
+					Model:      model,
+					Messages:   ToolCallMessages(),
+					Tools:      []providers.Tool{WeatherTool()},
+					ToolChoice: "required",
+				}
+			},
+		},
+	}
+}
+
+// LoadFixture reads a fixture from path.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("decoding fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// RecordFixture sends params to the provider newProvider creates, once
+// without and once with streaming, and returns the responses as a Fixture.
+// The stream asks for usage, as ReplayFixture does.
+func RecordFixture(
+	ctx context.Context,
+	name string,
+	newProvider ProviderFactory,
+	params providers.CompletionParams,
+) (*Fixture, error) {
+	transport := &recordingTransport{base: http.DefaultTransport}
+	opts := append(ClientOptions(name), config.WithHTTPClient(&http.Client{Transport: transport}))
+
+	provider, err := newProvider(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := provider.Completion(ctx, params); err != nil {
+		return nil, fmt.Errorf("completion: %w", err)
+	}
+
+	params.Stream = true
+	params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+	chunks, errs := provider.CompletionStream(ctx, params)
+	for range chunks {
+		// Reading the stream records it.
+	}
+	if err := <-errs; err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	params.Stream = false
+	params.StreamOptions = nil
+
+	return &Fixture{Params: params, Provider: name, Responses: transport.recorded()}, nil
+}
+
+// ReplayFixture sends the fixture's params to the provider newProvider
+// creates, once without and once with streaming, answering its requests with
+// the fixture's responses. It returns the completion and the stream joined
+// with a providers.Accumulator.
+func ReplayFixture(
+	ctx context.Context,
+	newProvider ProviderFactory,
+	fixture *Fixture,
+) (completion *providers.ChatCompletion, streamed *providers.ChatCompletion, err error) {
+	transport := &replayTransport{responses: fixture.Responses}
+
+	// The base URL is for providers that only use the HTTP client for
+	// failover; the transport answers every request, whatever its host.
+	provider, err := newProvider(
+		config.WithAPIKey("replay"),
+		config.WithBaseURL("http://replay.invalid"),
+		config.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	completion, err = provider.Completion(ctx, fixture.Params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("completion: %w", err)
+	}
+
+	params := fixture.Params
+	params.Stream = true
+	params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+	chunks, errs := provider.CompletionStream(ctx, params)
+
+	var acc providers.Accumulator
+	for chunk := range chunks {
+		acc.Add(chunk)
+	}
+	if err := <-errs; err != nil {
+		return nil, nil, fmt.Errorf("stream: %w", err)
+	}
+
+	return completion, acc.Completion(), nil
+}
+
+// RequireStreamParity fails t unless streamed, a stream joined with a
+// providers.Accumulator, has the shape of completion: the same choices, with
+// the same roles, finish reasons and tool call names, and content, reasoning,
+// tool call arguments that parse as JSON, and usage wherever completion has
+// them. The text itself is not compared, since the two come from separate
+// generations.
+func RequireStreamParity(t *testing.T, completion *providers.ChatCompletion, streamed *providers.ChatCompletion) {
+	t.Helper()
+
+	require.NotEmpty(t, streamed.ID, "stream has no ID")
+	require.Equal(t, completion.Model, streamed.Model, "model")
+	require.Equal(t, completion.Usage != nil, streamed.Usage != nil, "usage presence")
+	if completion.Usage != nil {
+		require.Positive(t, streamed.Usage.PromptTokens, "stream prompt tokens")
+		require.Positive(t, streamed.Usage.CompletionTokens, "stream completion tokens")
+	}
+
+	require.Len(t, streamed.Choices, len(completion.Choices), "choices")
+	for i, want := range completion.Choices {
+		got := streamed.Choices[i]
+
+		require.Equal(t, want.Index, got.Index, "choice %d index", i)
+		require.Equal(t, want.Message.Role, got.Message.Role, "choice %d role", i)
+		require.Equal(t, want.FinishReason, got.FinishReason, "choice %d finish reason", i)
+		require.Equal(t, want.Message.ContentString() != "", got.Message.ContentString() != "",
+			"choice %d content presence", i)
+		require.Equal(t, want.Message.Reasoning != nil, got.Message.Reasoning != nil, "choice %d reasoning presence", i)
+
+		require.Len(t, got.Message.ToolCalls, len(want.Message.ToolCalls), "choice %d tool calls", i)
+		for j, call := range want.Message.ToolCalls {
+			streamedCall := got.Message.ToolCalls[j]
+			require.NotEmpty(t, streamedCall.ID, "choice %d tool call %d ID", i, j)
+			require.Equal(t, call.Function.Name, streamedCall.Function.Name, "choice %d tool call %d name", i, j)
+			require.True(t, json.Valid([]byte(streamedCall.Function.Arguments)),
+				"choice %d tool call %d arguments are not JSON: %s", i, j, streamedCall.Function.Arguments)
+		}
+	}
+}
+
+// VerifyStreamParity replays the fixtures of a provider's ParityScenarios,
+// in testdata/parity/<scenario>.json, and checks that each stream joins into
+// the shape of its completion with RequireStreamParity. With
+// ANYLLM_RECORD_FIXTURES=1 and the provider's API key set, it records the
+// fixtures from the real API first. Scenarios without a fixture are skipped.
+func VerifyStreamParity(t *testing.T, name string, newProvider ProviderFactory) {
+	t.Helper()
+
+	record := os.Getenv(EnvRecordFixtures) == "1" && HasAPIKey(name)
+
+	for _, scenario := range ParityScenarios() {
+		t.Run(scenario.Name, func(t *testing.T) {
+			path := filepath.Join(parityDir, scenario.Name+".json")
+
+			if record {
+				fixture, err := RecordFixture(context.Background(), name, newProvider, scenario.Params(TestModel(name)))
+				require.NoError(t, err)
+				require.NoError(t, writeFixture(path, fixture))
+			}
+
+			fixture, err := LoadFixture(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Skipf("no fixture at %s; set %s=1 and the provider's API key to record it", path, EnvRecordFixtures)
+			}
+			require.NoError(t, err)
+
+			completion, streamed, err := ReplayFixture(context.Background(), newProvider, fixture)
+			require.NoError(t, err)
+			RequireStreamParity(t, completion, streamed)
+		})
+	}
+}
+
+// Close records the body once its reader is done with it.
+func (b *recordingBody) Close() error {
+	b.response.Body = b.buf.String()
+	return b.ReadCloser.Close()
+}
+
+// Read reads from the body, keeping what was read.
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// RoundTrip sends req and records its response.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &recordingBody{
+		ReadCloser: resp.Body,
+		response:   RecordedResponse{ContentType: resp.Header.Get("Content-Type"), Status: resp.StatusCode},
+	}
+	resp.Body = body
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.responses = append(t.responses, body)
+	return resp, nil
+}
+
+// recorded returns the responses recorded so far.
+func (t *recordingTransport) recorded() []RecordedResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	responses := make([]RecordedResponse, 0, len(t.responses))
+	for _, body := range t.responses {
+		responses = append(responses, body.response)
+	}
+	return responses
+}
+
+// RoundTrip answers req with the next recorded response.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.responses) == 0 {
+		return nil, fmt.Errorf("no recorded response left for %s %s", req.Method, req.URL.Path)
+	}
+
+	recorded := t.responses[0]
+	t.responses = t.responses[1:]
+
+	return &http.Response{
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Header:        http.Header{"Content-Type": []string{recorded.ContentType}},
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Request:       req,
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+	}, nil
+}
+
+// writeFixture writes fixture to path as indented JSON.
+func writeFixture(path string, fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
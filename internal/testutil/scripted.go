@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// ScriptedStream is one stream played by a ScriptedStreams mock: its chunks,
+// then its error, if any.
+type ScriptedStream struct {
+	Chunks []providers.ChatCompletionChunk
+	Err    error
+}
+
+// NewEmbeddingMock returns a mock provider that embeds each input with embed,
+// listing the embeddings in reverse order so callers must sort them by index.
+// Usage counts one token per input.
+func NewEmbeddingMock(embed func(input string) []float64) *MockProvider {
+	mock := NewMockProvider()
+	mock.EmbeddingFunc = func(_ context.Context, params providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+		input, _ := params.Input.([]string) // Callers under test always send a batch.
+		resp := &providers.EmbeddingResponse{
+			Model: params.Model,
+			Usage: &providers.EmbeddingUsage{PromptTokens: len(input), TotalTokens: len(input)},
+		}
+		for i := len(input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, providers.EmbeddingData{Embedding: embed(input[i]), Index: i})
+		}
+		return resp, nil
+	}
+	return mock
+}
+
+// ScriptedReplies returns a mock provider whose completions answer with
+// replies in call order, starting over after the last.
+func ScriptedReplies(replies ...string) *MockProvider {
+	var calls atomic.Int32
+	mock := NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+		i := int(calls.Add(1)) - 1
+		return MockChatCompletion(replies[i%len(replies)]), nil
+	}
+	return mock
+}
+
+// ScriptedStreams returns a mock provider whose streams play streams in call
+// order.
+func ScriptedStreams(streams ...ScriptedStream) *MockProvider {
+	var mu sync.Mutex
+	calls := 0
+
+	mock := NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		_ context.Context,
+		_ providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		mu.Lock()
+		s := streams[calls]
+		calls++
+		mu.Unlock()
+
+		chunks := make(chan providers.ChatCompletionChunk, len(s.Chunks))
+		errs := make(chan error, 1)
+		for _, chunk := range s.Chunks {
+			chunks <- chunk
+		}
+		if s.Err != nil {
+			errs <- s.Err
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+	return mock
+}
+
+// ScriptedToolCalls returns a mock provider with native tool calling that
+// makes the given rounds of tool calls, then answers with answer. The round
+// is read from the conversation, as the number of assistant messages in it,
+// so conversations can run concurrently.
+func ScriptedToolCalls(answer string, rounds ...[]providers.FunctionCall) *MockProvider {
+	mock := NewMockProvider()
+	mock.CapabilitiesFunc = func() providers.Capabilities {
+		return providers.Capabilities{Completion: true, CompletionTools: true}
+	}
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		round := 0
+		for _, msg := range params.Messages {
+			if msg.Role == providers.RoleAssistant {
+				round++
+			}
+		}
+		if round >= len(rounds) {
+			return MockChatCompletion(answer), nil
+		}
+
+		var calls []providers.ToolCall
+		for i, fn := range rounds[round] {
+			calls = append(calls, providers.ToolCall{Function: fn, ID: fn.Name + string(rune('a'+i)), Type: "function"})
+		}
+		return MockChatCompletionWithToolCalls(calls), nil
+	}
+	return mock
+}
@@ -0,0 +1,29 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/textdiff"
+)
+
+// RequireSimilar fails the test unless actual's token-level similarity to
+// expected, as reported by textdiff.Tokens, is at least threshold. The
+// failure message shows the word diff, so a regression in a model's output
+// can be read at a glance.
+func RequireSimilar(t *testing.T, expected, actual string, threshold float64) {
+	t.Helper()
+
+	d := textdiff.Tokens(expected, actual)
+	require.GreaterOrEqualf(t, d.Similarity, threshold, "output differs from expected:\n%s", d)
+}
+
+// RequireSimilarSentences is RequireSimilar with sentence-level similarity,
+// for long outputs where whole sentences are added, removed or reworded.
+func RequireSimilarSentences(t *testing.T, expected, actual string, threshold float64) {
+	t.Helper()
+
+	d := textdiff.Sentences(expected, actual)
+	require.GreaterOrEqualf(t, d.Similarity, threshold, "output differs from expected:\n%s", d)
+}
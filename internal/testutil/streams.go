@@ -0,0 +1,215 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// modulePath prefixes the stack frames of goroutines started by this module.
+const modulePath = "github.com/mozilla-ai/any-llm-go/"
+
+// streamTimeout bounds each wait in the stream helpers.
+const streamTimeout = 5 * time.Second
+
+// StreamFunc starts a stream, as CompletionStream does with fixed params.
+type StreamFunc func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error)
+
+// ChunksContent returns the content of chunks, joined in order.
+func ChunksContent(chunks []providers.ChatCompletionChunk) string {
+	var content strings.Builder
+	for _, chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+	return content.String()
+}
+
+// Collect reads a stream to the end, returning its chunks and error.
+func Collect(chunks <-chan providers.ChatCompletionChunk, errs <-chan error) ([]providers.ChatCompletionChunk, error) {
+	var got []providers.ChatCompletionChunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	return got, <-errs
+}
+
+// CollectContent reads a stream to the end, returning its content and error.
+func CollectContent(chunks <-chan providers.ChatCompletionChunk, errs <-chan error) (string, error) {
+	got, err := Collect(chunks, errs)
+	return ChunksContent(got), err
+}
+
+// ContentChunk returns a chunk with the given ID carrying content.
+func ContentChunk(id string, content string) providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{
+		ID:      id,
+		Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: content}}},
+	}
+}
+
+// NewHangingServer returns a server that never answers: it holds every
+// request open until the client goes away. The headers of each request are
+// sent on the returned channel, which holds up to 16 of them, so tests can
+// count attempts and check what the client sent. The server is closed when
+// the test ends.
+func NewHangingServer(t *testing.T) (*httptest.Server, <-chan http.Header) {
+	t.Helper()
+
+	requests := make(chan http.Header, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case requests <- r.Header.Clone():
+		default:
+		}
+		// The server notices the client going away only once the body is read.
+		_, _ = io.Copy(io.Discard, r.Body) // Read error means the client has gone.
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+// NewStallingServer returns a server that answers every request with body, as
+// contentType, and then holds the response open until the client goes away.
+// The returned channel is closed once the client has gone, which shows that
+// the response body was released. The server is closed when the test ends.
+func NewStallingServer(t *testing.T, contentType string, body string) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+
+	released := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write([]byte(body)) // Write error surfaces in the client.
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		<-r.Context().Done()
+		once.Do(func() { close(released) })
+	}))
+	t.Cleanup(server.Close)
+
+	return server, released
+}
+
+// VerifyNoGoroutineLeaks fails t if goroutines started by this module during
+// the test are still running when it ends. Call it first, so that its cleanup
+// runs after every other cleanup (for example httptest.Server.Close).
+// Goroutines from other packages, such as idle HTTP connections, are ignored.
+// Tests that use it must not call t.Parallel, since goroutines of concurrent
+// tests would be reported as leaks.
+func VerifyNoGoroutineLeaks(t *testing.T) {
+	t.Helper()
+
+	before := goroutines()
+
+	t.Cleanup(func() {
+		deadline := time.Now().Add(streamTimeout)
+		for {
+			var leaked []string
+			for id, stack := range goroutines() {
+				if _, ok := before[id]; !ok && strings.Contains(stack, modulePath) {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%d goroutine(s) leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// VerifyStreamCancellation checks the cancellation contract of
+// providers.Provider.CompletionStream. It reads the first chunk, cancels the
+// context and stops reading chunks. The error channel must then report
+// context.Canceled, both channels must close, and released (from
+// NewStallingServer) must close. The stream should have at least two chunks
+// ready, so that cancellation also interrupts a blocked send. Pair it with
+// VerifyNoGoroutineLeaks.
+func VerifyStreamCancellation(t *testing.T, stream StreamFunc, released <-chan struct{}) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks, errs := stream(ctx)
+
+	select {
+	case _, ok := <-chunks:
+		if !ok {
+			t.Fatalf("stream ended before its first chunk: %v", <-errs)
+		}
+	case <-time.After(streamTimeout):
+		t.Fatal("timed out waiting for the first chunk")
+	}
+
+	cancel()
+
+	select {
+	case err := <-errs:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(streamTimeout):
+		t.Fatal("timed out waiting for the cancellation error")
+	}
+
+	select {
+	case _, ok := <-errs:
+		require.False(t, ok, "error channel reported more than one error")
+	case <-time.After(streamTimeout):
+		t.Fatal("error channel was not closed")
+	}
+
+	select {
+	case _, ok := <-chunks:
+		require.False(t, ok, "chunk sent after cancellation")
+	case <-time.After(streamTimeout):
+		t.Fatal("chunk channel was not closed")
+	}
+
+	select {
+	case <-released:
+	case <-time.After(streamTimeout):
+		t.Fatal("response body was not released")
+	}
+}
+
+// goroutines returns the stacks of all goroutines, keyed by their header line
+// ("goroutine 7" without the state).
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := map[string]string{}
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		header, _, _ := bytes.Cut(stack, []byte(" ["))
+		stacks[string(header)] = string(stack)
+	}
+	return stacks
+}
@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Ensure the token mocks implement the optional interfaces they stand in for.
+var (
+	_ providers.ModelCardProvider = ModelCardMock{}
+	_ providers.TokenCounter      = TokenCountingMock{}
+)
+
+// ModelCardMock is a TokenCountingMock that reports a model card with
+// ContextLength.
+type ModelCardMock struct {
+	TokenCountingMock
+
+	// ContextLength is the context length of every model.
+	ContextLength int
+}
+
+// TokenCountingMock is a mock provider with a tokenizer that counts one token
+// per word.
+type TokenCountingMock struct {
+	*MockProvider
+
+	// FailOn, if set, makes Tokenize fail on text that contains it.
+	FailOn string
+
+	// TokenizeCalls counts the calls to Tokenize.
+	TokenizeCalls *atomic.Int32
+}
+
+// NewTokenCountingMock returns a TokenCountingMock around mock.
+func NewTokenCountingMock(mock *MockProvider) TokenCountingMock {
+	return TokenCountingMock{MockProvider: mock, TokenizeCalls: &atomic.Int32{}}
+}
+
+// ModelCard returns a model card for model with the mock's context length.
+func (m ModelCardMock) ModelCard(_ context.Context, model string) (*providers.ModelCard, error) {
+	return &providers.ModelCard{ContextLength: m.ContextLength, ID: model}, nil
+}
+
+// Detokenize returns no text; only counts are mocked.
+func (m TokenCountingMock) Detokenize(
+	_ context.Context,
+	_ providers.DetokenizeParams,
+) (*providers.DetokenizeResponse, error) {
+	return &providers.DetokenizeResponse{}, nil
+}
+
+// Tokenize returns a token per word of the content.
+func (m TokenCountingMock) Tokenize(
+	_ context.Context,
+	params providers.TokenizeParams,
+) (*providers.TokenizeResponse, error) {
+	m.TokenizeCalls.Add(1)
+	if m.FailOn != "" && strings.Contains(params.Content, m.FailOn) {
+		return nil, stderrors.New("tokenizer unavailable")
+	}
+	return &providers.TokenizeResponse{Tokens: make([]int, len(strings.Fields(params.Content)))}, nil
+}
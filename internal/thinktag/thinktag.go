@@ -0,0 +1,201 @@
+// Package thinktag separates reasoning that models inline in their content
+// between <think> and </think> tags, in responses and in streams whose chunks
+// may split the tags.
+package thinktag
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Tags that enclose inline reasoning.
+const (
+	Close = "</think>"
+	Open  = "<think>"
+)
+
+// Parser separates inline reasoning from content written to it in pieces.
+// It is not safe for concurrent use.
+type Parser struct {
+	inThink bool
+	pending string
+	policy  config.ReasoningPolicy
+	seen    bool
+	trim    bool
+}
+
+// Stream separates inline reasoning in the content deltas of a stream's
+// chunks, keeping a Parser for each choice. It is not safe for concurrent use.
+type Stream struct {
+	parsers map[int]*Parser
+	policy  config.ReasoningPolicy
+}
+
+// NewParser returns a Parser that handles reasoning per policy. An empty
+// policy separates it.
+func NewParser(policy config.ReasoningPolicy) *Parser {
+	return &Parser{policy: policy}
+}
+
+// NewStream returns a Stream that handles reasoning per policy. An empty
+// policy separates it.
+func NewStream(policy config.ReasoningPolicy) *Stream {
+	return &Stream{parsers: make(map[int]*Parser), policy: policy}
+}
+
+// Flush returns the text held back by Write, for use once the content ends.
+func (p *Parser) Flush() (content, reasoning string) {
+	p.emit(&content, &reasoning, p.pending)
+	p.pending = ""
+
+	return content, p.reasoning(reasoning)
+}
+
+// Write consumes the next piece of content and returns the content and
+// reasoning it completes. Text that may begin a tag is held back until the
+// next Write or Flush. Whitespace that follows a closing tag is dropped.
+func (p *Parser) Write(s string) (content, reasoning string) {
+	if p.policy == config.ReasoningKeep {
+		return s, ""
+	}
+
+	text := p.pending + s
+	p.pending = ""
+
+	for text != "" {
+		tag := Open
+		if p.inThink {
+			tag = Close
+		}
+
+		if i := strings.Index(text, tag); i >= 0 {
+			p.emit(&content, &reasoning, text[:i])
+			text = text[i+len(tag):]
+			p.inThink = !p.inThink
+			p.seen = true
+			p.trim = !p.inThink
+			continue
+		}
+
+		n := partialSuffix(text, tag)
+		p.emit(&content, &reasoning, text[:len(text)-n])
+		p.pending = text[len(text)-n:]
+		break
+	}
+
+	return content, p.reasoning(reasoning)
+}
+
+// Chunk moves the inline reasoning in the content deltas of chunk into their
+// Reasoning, or drops it, per the policy. Text held back for a choice is
+// flushed into the chunk that carries its finish reason.
+func (s *Stream) Chunk(chunk *providers.ChatCompletionChunk) {
+	if s.policy == config.ReasoningKeep {
+		return
+	}
+
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+
+		p, ok := s.parsers[choice.Index]
+		if !ok {
+			p = NewParser(s.policy)
+			s.parsers[choice.Index] = p
+		}
+
+		content, reasoning := p.Write(choice.Delta.Content)
+		if choice.FinishReason != "" {
+			c, r := p.Flush()
+			content += c
+			reasoning += r
+		}
+
+		choice.Delta.Content = content
+		choice.Delta.Reasoning = appendReasoning(choice.Delta.Reasoning, reasoning)
+	}
+}
+
+// emit appends text to the content or the reasoning, depending on whether it
+// is inside tags. A single piece is kept as a substring of the input, so the
+// common case of a delta without tags does not allocate.
+func (p *Parser) emit(content, reasoning *string, text string) {
+	if p.inThink {
+		*reasoning += text
+		return
+	}
+
+	if p.trim {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		p.trim = text == ""
+	}
+	*content += text
+}
+
+// reasoning returns r, or nothing if the policy strips reasoning.
+func (p *Parser) reasoning(r string) string {
+	if p.policy == config.ReasoningStrip {
+		return ""
+	}
+	return r
+}
+
+// Response moves the inline reasoning in the string content of resp's
+// messages into their Reasoning, or drops it, per policy.
+func Response(resp *providers.ChatCompletion, policy config.ReasoningPolicy) {
+	if resp == nil || policy == config.ReasoningKeep {
+		return
+	}
+
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		text, ok := msg.Content.(string)
+		if !ok {
+			continue
+		}
+
+		content, reasoning := Split(text, policy)
+		msg.Content = content
+		msg.Reasoning = appendReasoning(msg.Reasoning, reasoning)
+	}
+}
+
+// Split separates the inline reasoning in text per policy. If text has
+// reasoning, the content is trimmed of surrounding whitespace.
+func Split(text string, policy config.ReasoningPolicy) (content, reasoning string) {
+	p := NewParser(policy)
+	content, reasoning = p.Write(text)
+	c, r := p.Flush()
+	content += c
+	reasoning += r
+
+	if p.seen {
+		content = strings.TrimSpace(content)
+	}
+	return content, reasoning
+}
+
+// appendReasoning returns existing with text appended, leaving it unchanged
+// if text is empty.
+func appendReasoning(existing *providers.Reasoning, text string) *providers.Reasoning {
+	if text == "" {
+		return existing
+	}
+	if existing == nil {
+		return &providers.Reasoning{Content: text}
+	}
+	return &providers.Reasoning{Content: existing.Content + text}
+}
+
+// partialSuffix returns the length of the longest suffix of text that is a
+// proper prefix of tag.
+func partialSuffix(text, tag string) int {
+	for n := min(len(tag)-1, len(text)); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
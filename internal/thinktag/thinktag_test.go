@@ -0,0 +1,232 @@
+package thinktag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		text          string
+		policy        config.ReasoningPolicy
+		wantContent   string
+		wantReasoning string
+	}{
+		{
+			name:          "separates reasoning by default",
+			text:          "<think>Let me think</think>\n\nHello world",
+			wantContent:   "Hello world",
+			wantReasoning: "Let me think",
+		},
+		{
+			name:          "separates reasoning",
+			text:          "<think>Let me think</think>Hello world",
+			policy:        config.ReasoningSeparate,
+			wantContent:   "Hello world",
+			wantReasoning: "Let me think",
+		},
+		{
+			name:        "strips reasoning",
+			text:        "<think>Let me think</think>Hello world",
+			policy:      config.ReasoningStrip,
+			wantContent: "Hello world",
+		},
+		{
+			name:        "keeps reasoning in the content",
+			text:        "<think>Let me think</think>Hello world",
+			policy:      config.ReasoningKeep,
+			wantContent: "<think>Let me think</think>Hello world",
+		},
+		{
+			name:          "treats unclosed tags as reasoning",
+			text:          "<think>Let me",
+			wantReasoning: "Let me",
+		},
+		{
+			name:        "leaves content without tags unchanged",
+			text:        " Use <b> for bold. ",
+			wantContent: " Use <b> for bold. ",
+		},
+		{
+			name:          "handles several blocks",
+			text:          "<think>a</think>Hello<think>b</think> world",
+			wantContent:   "Helloworld",
+			wantReasoning: "ab",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			content, reasoning := Split(tc.text, tc.policy)
+			require.Equal(t, tc.wantContent, content)
+			require.Equal(t, tc.wantReasoning, reasoning)
+		})
+	}
+}
+
+func TestParserWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("never returns reasoning as content wherever the text is split", func(t *testing.T) {
+		t.Parallel()
+
+		const text = "<think>I should greet them.</think>\nHello <there>!"
+		for i := range len(text) {
+			for j := i; j < len(text); j++ {
+				p := NewParser(config.ReasoningSeparate)
+
+				var content, reasoning strings.Builder
+				for _, piece := range []string{text[:i], text[i:j], text[j:]} {
+					c, r := p.Write(piece)
+					require.NotContains(t, c, "think")
+					content.WriteString(c)
+					reasoning.WriteString(r)
+				}
+				c, r := p.Flush()
+				content.WriteString(c)
+				reasoning.WriteString(r)
+
+				require.Equal(t, "Hello <there>!", content.String(), "split at %d and %d", i, j)
+				require.Equal(t, "I should greet them.", reasoning.String(), "split at %d and %d", i, j)
+			}
+		}
+	})
+
+	t.Run("holds back a possible tag until flushed", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewParser(config.ReasoningSeparate)
+
+		content, reasoning := p.Write("a <thi")
+		require.Equal(t, "a ", content)
+		require.Empty(t, reasoning)
+
+		content, reasoning = p.Flush()
+		require.Equal(t, "<thi", content)
+		require.Empty(t, reasoning)
+	})
+}
+
+// TestParserWriteAllocs is not parallel, since AllocsPerRun cannot be.
+func TestParserWriteAllocs(t *testing.T) {
+	p := NewParser(config.ReasoningSeparate)
+	_, _ = p.Write("<think>hmm</think>") // Only the parser state is needed.
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = p.Write("token ") // Only the allocations are measured.
+	})
+	require.Zero(t, allocs)
+}
+
+func TestStreamChunk(t *testing.T) {
+	t.Parallel()
+
+	chunk := func(index int, content, finishReason string) providers.ChatCompletionChunk {
+		return providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{
+			Index:        index,
+			Delta:        providers.ChunkDelta{Content: content},
+			FinishReason: finishReason,
+		}}}
+	}
+
+	t.Run("separates reasoning per choice", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewStream(config.ReasoningSeparate)
+
+		first := chunk(0, "<think>Hm", "")
+		second := chunk(1, "<think>Ok</think>Yes", "")
+		third := chunk(0, "m</think>No", "")
+		for _, c := range []*providers.ChatCompletionChunk{&first, &second, &third} {
+			s.Chunk(c)
+		}
+
+		require.Empty(t, first.Choices[0].Delta.Content)
+		require.Equal(t, &providers.Reasoning{Content: "Hm"}, first.Choices[0].Delta.Reasoning)
+		require.Equal(t, "Yes", second.Choices[0].Delta.Content)
+		require.Equal(t, &providers.Reasoning{Content: "Ok"}, second.Choices[0].Delta.Reasoning)
+		require.Equal(t, "No", third.Choices[0].Delta.Content)
+		require.Equal(t, &providers.Reasoning{Content: "m"}, third.Choices[0].Delta.Reasoning)
+	})
+
+	t.Run("appends to reasoning the provider returned", func(t *testing.T) {
+		t.Parallel()
+
+		c := chunk(0, "<think>b</think>", "")
+		c.Choices[0].Delta.Reasoning = &providers.Reasoning{Content: "a"}
+
+		NewStream("").Chunk(&c)
+
+		require.Equal(t, &providers.Reasoning{Content: "ab"}, c.Choices[0].Delta.Reasoning)
+	})
+
+	t.Run("flushes held back text with the finish reason", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewStream(config.ReasoningSeparate)
+
+		first := chunk(0, "x <", "")
+		last := chunk(0, "", providers.FinishReasonStop)
+		s.Chunk(&first)
+		s.Chunk(&last)
+
+		require.Equal(t, "x ", first.Choices[0].Delta.Content)
+		require.Equal(t, "<", last.Choices[0].Delta.Content)
+	})
+
+	t.Run("strips reasoning", func(t *testing.T) {
+		t.Parallel()
+
+		c := chunk(0, "<think>a</think>b", providers.FinishReasonStop)
+		NewStream(config.ReasoningStrip).Chunk(&c)
+
+		require.Equal(t, "b", c.Choices[0].Delta.Content)
+		require.Nil(t, c.Choices[0].Delta.Reasoning)
+	})
+
+	t.Run("keeps reasoning in the content", func(t *testing.T) {
+		t.Parallel()
+
+		c := chunk(0, "<think>a</think>b", providers.FinishReasonStop)
+		NewStream(config.ReasoningKeep).Chunk(&c)
+
+		require.Equal(t, "<think>a</think>b", c.Choices[0].Delta.Content)
+		require.Nil(t, c.Choices[0].Delta.Reasoning)
+	})
+}
+
+func TestResponse(t *testing.T) {
+	t.Parallel()
+
+	resp := &providers.ChatCompletion{Choices: []providers.Choice{
+		{Message: providers.Message{Content: "<think>a</think>b"}},
+		{Message: providers.Message{Content: []providers.ContentPart{{Type: "text", Text: "<think>"}}}},
+	}}
+
+	Response(resp, config.ReasoningSeparate)
+
+	require.Equal(t, "b", resp.Choices[0].Message.Content)
+	require.Equal(t, &providers.Reasoning{Content: "a"}, resp.Choices[0].Message.Reasoning)
+	require.Nil(t, resp.Choices[1].Message.Reasoning)
+
+	Response(nil, config.ReasoningSeparate)
+}
+
+func BenchmarkParserWrite(b *testing.B) {
+	p := NewParser(config.ReasoningSeparate)
+
+	b.ReportAllocs()
+	for range b.N {
+		_, _ = p.Write("token ") // Only the cost is measured.
+	}
+}
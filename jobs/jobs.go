@@ -0,0 +1,137 @@
+// Package jobs runs long-running provider jobs, such as video generation, to
+// completion: it submits a job, polls it until it finishes, and fetches its
+// artifacts. It works with any providers.JobProvider.
+package jobs
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultPollInterval is how often jobs are polled when WithPollInterval is
+// not used. Video generation takes minutes, so there is little to gain from
+// polling faster.
+const defaultPollInterval = 10 * time.Second
+
+// Option configures how a job is waited for.
+type Option func(*poller) error
+
+// poller holds the settings for waiting on a job.
+type poller struct {
+	client   *http.Client
+	interval time.Duration
+	webhook  *webhook
+}
+
+// Artifacts returns the data of each of job's artifacts, in order, fetching
+// the ones that only have a URI from provider.
+func Artifacts(ctx context.Context, provider providers.JobProvider, job *providers.Job) ([][]byte, error) {
+	data := make([][]byte, 0, len(job.Artifacts))
+	for _, artifact := range job.Artifacts {
+		if artifact.Data != nil {
+			data = append(data, artifact.Data)
+			continue
+		}
+
+		b, err := provider.FetchArtifact(ctx, artifact)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b)
+	}
+
+	return data, nil
+}
+
+// Run submits a job to provider and waits for it to finish, as Wait does.
+func Run(
+	ctx context.Context,
+	provider providers.JobProvider,
+	params providers.JobParams,
+	opts ...Option,
+) (*providers.Job, error) {
+	job, err := provider.SubmitJob(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return Wait(ctx, provider, job, opts...)
+}
+
+// Wait polls job until it finishes or ctx is done, and returns its final
+// state. A failed job is returned along with a provider error holding its
+// error message. If ctx is done first, the last polled state is returned with
+// the context error. With WithWebhook, the finished job is also posted to the
+// webhook, and a delivery failure is returned along with the job.
+func Wait(
+	ctx context.Context,
+	provider providers.JobProvider,
+	job *providers.Job,
+	opts ...Option,
+) (*providers.Job, error) {
+	p := &poller{client: http.DefaultClient, interval: defaultPollInterval}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	for !job.Done() {
+		if job.ID == "" {
+			return job, errors.NewProviderError(provider.Name(), fmt.Errorf("job is %s but has no ID", job.Status))
+		}
+		if err := wait(ctx, p.interval); err != nil {
+			return job, err
+		}
+
+		next, err := provider.GetJob(ctx, job.ID)
+		if err != nil {
+			return job, err
+		}
+		job = next
+	}
+
+	var err error
+	if job.Status == providers.JobStatusFailed {
+		err = errors.NewProviderError(provider.Name(), fmt.Errorf("job failed: %s", job.Error))
+	}
+	if p.webhook != nil {
+		err = stderrors.Join(err, p.webhook.send(ctx, p.client, job))
+	}
+	return job, err
+}
+
+// WithPollInterval sets how long to wait between polls. The default is 10
+// seconds.
+func WithPollInterval(interval time.Duration) Option {
+	return func(p *poller) error {
+		if interval <= 0 {
+			return fmt.Errorf("poll interval must be positive, got %s", interval)
+		}
+
+		p.interval = interval
+		return nil
+	}
+}
+
+// wait blocks for delay or until ctx is done, returning the context error in the latter case.
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// fakeJobProvider reports a job as running for a number of polls, then as
+// states[len(states)-1].
+type fakeJobProvider struct {
+	*testutil.MockProvider
+
+	fetched   []providers.Artifact
+	polls     int
+	states    []*providers.Job
+	submitted []providers.JobParams
+}
+
+func (f *fakeJobProvider) FetchArtifact(_ context.Context, artifact providers.Artifact) ([]byte, error) {
+	f.fetched = append(f.fetched, artifact)
+	if artifact.URI == "" {
+		return nil, fmt.Errorf("no URI")
+	}
+	return []byte("fetched " + artifact.URI), nil
+}
+
+func (f *fakeJobProvider) GetJob(_ context.Context, id string) (*providers.Job, error) {
+	if id != "job-1" {
+		return nil, fmt.Errorf("unknown job %q", id)
+	}
+	job := f.states[min(f.polls, len(f.states)-1)]
+	f.polls++
+	return job, nil
+}
+
+func (f *fakeJobProvider) SubmitJob(_ context.Context, params providers.JobParams) (*providers.Job, error) {
+	f.submitted = append(f.submitted, params)
+	return running(), nil
+}
+
+// running returns a running job.
+func running() *providers.Job {
+	return &providers.Job{ID: "job-1", Kind: providers.JobKindVideo, Status: providers.JobStatusRunning}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	done := &providers.Job{
+		Artifacts: []providers.Artifact{{URI: "https://example.com/video"}},
+		ID:        "job-1",
+		Kind:      providers.JobKindVideo,
+		Status:    providers.JobStatusSucceeded,
+	}
+	provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider(), states: []*providers.Job{running(), done}}
+
+	params := providers.JobParams{Kind: providers.JobKindVideo, Model: "veo", Prompt: "A cat surfing"}
+	job, err := Run(context.Background(), provider, params, WithPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, done, job)
+	require.Equal(t, []providers.JobParams{params}, provider.submitted)
+	require.Equal(t, 2, provider.polls)
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns finished jobs without polling", func(t *testing.T) {
+		t.Parallel()
+
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider()}
+		done := &providers.Job{Kind: providers.JobKindImage, Status: providers.JobStatusSucceeded}
+
+		job, err := Wait(context.Background(), provider, done)
+		require.NoError(t, err)
+		require.Equal(t, done, job)
+		require.Zero(t, provider.polls)
+	})
+
+	t.Run("reports failed jobs", func(t *testing.T) {
+		t.Parallel()
+
+		failed := &providers.Job{ID: "job-1", Error: "filtered", Status: providers.JobStatusFailed}
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider(), states: []*providers.Job{failed}}
+
+		job, err := Wait(context.Background(), provider, running(), WithPollInterval(time.Millisecond))
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.ErrorContains(t, err, "filtered")
+		require.Equal(t, failed, job)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider(), states: []*providers.Job{running()}}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		job, err := Wait(ctx, provider, running(), WithPollInterval(time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, running(), job)
+		require.Positive(t, provider.polls)
+	})
+
+	t.Run("rejects running jobs without an ID", func(t *testing.T) {
+		t.Parallel()
+
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider()}
+		_, err := Wait(context.Background(), provider, &providers.Job{Status: providers.JobStatusRunning})
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("rejects invalid intervals", func(t *testing.T) {
+		t.Parallel()
+
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider()}
+		_, err := Wait(context.Background(), provider, running(), WithPollInterval(0))
+		require.Error(t, err)
+	})
+}
+
+func TestArtifacts(t *testing.T) {
+	t.Parallel()
+
+	provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider()}
+	job := &providers.Job{Artifacts: []providers.Artifact{
+		{Data: []byte("inline")},
+		{URI: "https://example.com/video"},
+	}}
+
+	data, err := Artifacts(context.Background(), provider, job)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("inline"), []byte("fetched https://example.com/video")}, data)
+	require.Equal(t, []providers.Artifact{{URI: "https://example.com/video"}}, provider.fetched)
+
+	_, err = Artifacts(context.Background(), provider, &providers.Job{Artifacts: []providers.Artifact{{}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Webhook headers.
+const (
+	// SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of the
+	// timestamp, a ".", and the body, keyed with the webhook secret.
+	SignatureHeader = "X-Webhook-Signature"
+
+	// TimestampHeader holds the Unix time the webhook was sent at.
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// Webhook defaults.
+const (
+	// DefaultWebhookTolerance is how old a webhook VerifyWebhook accepts.
+	DefaultWebhookTolerance = 5 * time.Minute
+
+	// maxWebhookBody is the largest webhook body VerifyWebhook reads.
+	maxWebhookBody = 1 << 20
+
+	// signaturePrefix comes before the hex signature in SignatureHeader.
+	signaturePrefix = "sha256="
+)
+
+// webhook is where finished jobs are reported.
+type webhook struct {
+	secret []byte
+	url    string
+}
+
+// VerifyWebhook checks the signature and age of a webhook sent by Wait and
+// returns the job it reports. Webhooks older than tolerance, or
+// DefaultWebhookTolerance if it is zero, are rejected, so a captured webhook
+// cannot be replayed later.
+func VerifyWebhook(r *http.Request, secret string, tolerance time.Duration) (*providers.Job, error) {
+	if tolerance == 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, fmt.Errorf("reading webhook body: %w", err)
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook timestamp %q", timestamp)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return nil, fmt.Errorf("webhook timestamp is outside the %s tolerance", tolerance)
+	}
+
+	signature, ok := strings.CutPrefix(r.Header.Get(SignatureHeader), signaturePrefix)
+	if !ok {
+		return nil, fmt.Errorf("missing webhook signature")
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, sign([]byte(secret), timestamp, body)) {
+		return nil, fmt.Errorf("invalid webhook signature")
+	}
+
+	var job providers.Job
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, fmt.Errorf("decoding webhook job: %w", err)
+	}
+	return &job, nil
+}
+
+// WithWebhook posts the job to url as JSON when it finishes, signed with
+// secret; see SignatureHeader. Artifact data is left out, so receivers fetch
+// the artifacts they need. Receivers check webhooks with VerifyWebhook.
+func WithWebhook(url, secret string) Option {
+	return func(p *poller) error {
+		if url == "" || secret == "" {
+			return fmt.Errorf("webhook URL and secret are required")
+		}
+
+		p.webhook = &webhook{secret: []byte(secret), url: url}
+		return nil
+	}
+}
+
+// WithWebhookClient sets the HTTP client webhooks are sent with. The default
+// is http.DefaultClient.
+func WithWebhookClient(client *http.Client) Option {
+	return func(p *poller) error {
+		if client == nil {
+			return fmt.Errorf("webhook client cannot be nil")
+		}
+
+		p.client = client
+		return nil
+	}
+}
+
+// send posts job to the webhook with client.
+func (w *webhook) send(ctx context.Context, client *http.Client, job *providers.Job) error {
+	payload := *job
+	payload.Artifacts = make([]providers.Artifact, len(job.Artifacts))
+	for i, artifact := range job.Artifacts {
+		artifact.Data = nil
+		payload.Artifacts[i] = artifact
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding webhook job: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, signaturePrefix+hex.EncodeToString(sign(w.secret, timestamp, body)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the HMAC-SHA256 of timestamp, ".", and body keyed with secret.
+func sign(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
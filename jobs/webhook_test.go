@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("posts the finished job", func(t *testing.T) {
+		t.Parallel()
+
+		received := make(chan *providers.Job, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			job, err := VerifyWebhook(r, "secret", 0)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			received <- job
+		}))
+		defer server.Close()
+
+		done := &providers.Job{
+			Artifacts: []providers.Artifact{{Data: []byte("video"), MIMEType: "video/mp4", URI: "https://example.com/v"}},
+			ID:        "job-1",
+			Kind:      providers.JobKindVideo,
+			Status:    providers.JobStatusSucceeded,
+		}
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider(), states: []*providers.Job{done}}
+
+		job, err := Wait(context.Background(), provider, running(),
+			WithPollInterval(time.Millisecond),
+			WithWebhook(server.URL, "secret"),
+			WithWebhookClient(server.Client()),
+		)
+		require.NoError(t, err)
+		require.Equal(t, done, job)
+
+		// Artifact data is left out of the webhook.
+		require.Equal(t, &providers.Job{
+			Artifacts: []providers.Artifact{{MIMEType: "video/mp4", URI: "https://example.com/v"}},
+			ID:        "job-1",
+			Kind:      providers.JobKindVideo,
+			Status:    providers.JobStatusSucceeded,
+		}, <-received)
+		require.Equal(t, []byte("video"), done.Artifacts[0].Data)
+	})
+
+	t.Run("reports failed jobs and delivery failures", func(t *testing.T) {
+		t.Parallel()
+
+		// The receiver expects a different secret.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := VerifyWebhook(r, "other", 0); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		defer server.Close()
+
+		failed := &providers.Job{ID: "job-1", Error: "filtered", Status: providers.JobStatusFailed}
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider(), states: []*providers.Job{failed}}
+
+		job, err := Wait(context.Background(), provider, running(),
+			WithPollInterval(time.Millisecond),
+			WithWebhook(server.URL, "secret"),
+		)
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.ErrorContains(t, err, "webhook returned status 401")
+		require.Equal(t, failed, job)
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		provider := &fakeJobProvider{MockProvider: testutil.NewMockProvider()}
+		_, err := Wait(context.Background(), provider, running(), WithWebhook("", "secret"))
+		require.Error(t, err)
+
+		_, err = Wait(context.Background(), provider, running(), WithWebhookClient(nil))
+		require.Error(t, err)
+	})
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"kind":"video","status":"succeeded"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		wantErr   bool
+	}{
+		{name: "valid", timestamp: now, signature: signature("secret", now, body)},
+		{name: "wrong secret", timestamp: now, signature: signature("other", now, body), wantErr: true},
+		{name: "missing signature", timestamp: now, wantErr: true},
+		{name: "malformed signature", timestamp: now, signature: "sha256=zz", wantErr: true},
+		{name: "expired", timestamp: old, signature: signature("secret", old, body), wantErr: true},
+		{name: "missing timestamp", signature: signature("secret", now, body), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set(TimestampHeader, tc.timestamp)
+			if tc.signature != "" {
+				r.Header.Set(SignatureHeader, tc.signature)
+			}
+
+			job, err := VerifyWebhook(r, "secret", 0)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, &providers.Job{Kind: providers.JobKindVideo, Status: providers.JobStatusSucceeded}, job)
+		})
+	}
+}
+
+// signature returns the SignatureHeader value for body.
+func signature(secret, timestamp string, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(sign([]byte(secret), timestamp, body))
+}
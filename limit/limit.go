@@ -0,0 +1,346 @@
+// Package limit wraps a provider so that at most a fixed number of requests
+// are in flight at once. Requests over the limit wait in a FIFO queue, which
+// can be bounded in length and in waiting time. This protects servers with
+// few slots, such as a local llama.cpp server, from being overloaded.
+//
+// Requests are interactive unless their context is marked with WithPriority.
+// Batch requests wait behind interactive ones, may be kept out of reserved
+// slots, and are preempted from a full queue by interactive requests, so
+// backfill jobs can share a provider with chat traffic without slowing it.
+package limit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// unboundedQueue is the queue size that lets any number of requests wait.
+const unboundedQueue = -1
+
+// Request priorities.
+const (
+	// PriorityInteractive is for requests someone is waiting on, such as
+	// chat. It is the default.
+	PriorityInteractive Priority = iota
+
+	// PriorityBatch is for background work, such as backfills, that can
+	// wait and be retried.
+	PriorityBatch
+)
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Priority is the priority class of a request.
+type Priority int
+
+// Provider wraps a provider and limits its in-flight requests.
+type Provider struct {
+	inFlight      int
+	maxInFlight   int
+	mu            sync.Mutex
+	provider      providers.Provider
+	queueSize     int
+	queueTimeout  time.Duration
+	reservedSlots int
+
+	// waiters holds the queued requests, interactive ones first, each class
+	// in arrival order.
+	waiters []*waiter
+}
+
+// priorityKey is the context key for a request's priority.
+type priorityKey struct{}
+
+// waiter is a queued request. Its channel is closed when it is handed a slot,
+// or when it is preempted, in which case err is set.
+type waiter struct {
+	err      error
+	priority Priority
+	ready    chan struct{}
+}
+
+// New wraps provider so that at most maxInFlight requests run at once. By
+// default, requests over the limit wait for a free slot for as long as their
+// context allows; use WithQueueSize and WithQueueTimeout to bound the wait.
+// A stream holds its slot until it ends.
+func New(provider providers.Provider, maxInFlight int, opts ...Option) (*Provider, error) {
+	if maxInFlight <= 0 {
+		return nil, fmt.Errorf("max in-flight requests must be positive, got %d", maxInFlight)
+	}
+
+	p := &Provider{
+		maxInFlight: maxInFlight,
+		provider:    provider,
+		queueSize:   unboundedQueue,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithQueueSize sets how many requests may wait for a free slot. Requests
+// arriving when the queue is full fail at once with errors.ErrQueueTimeout,
+// except interactive requests when batch requests are queued: the most recent
+// batch request is preempted and fails with errors.ErrQueueTimeout instead.
+// A size of 0 disables queueing.
+func WithQueueSize(size int) Option {
+	return func(p *Provider) error {
+		if size < 0 {
+			return fmt.Errorf("queue size must not be negative, got %d", size)
+		}
+		p.queueSize = size
+		return nil
+	}
+}
+
+// WithQueueTimeout sets how long a request may wait for a free slot before it
+// fails with errors.ErrQueueTimeout.
+func WithQueueTimeout(timeout time.Duration) Option {
+	return func(p *Provider) error {
+		if timeout <= 0 {
+			return fmt.Errorf("queue timeout must be positive, got %v", timeout)
+		}
+		p.queueTimeout = timeout
+		return nil
+	}
+}
+
+// WithReservedSlots keeps n slots for interactive requests: batch requests
+// only start while more than n slots are free. n must be less than the
+// in-flight limit.
+func WithReservedSlots(n int) Option {
+	return func(p *Provider) error {
+		if n < 0 || n >= p.maxInFlight {
+			return fmt.Errorf("reserved slots must be between 0 and %d, got %d", p.maxInFlight-1, n)
+		}
+		p.reservedSlots = n
+		return nil
+	}
+}
+
+// Completion performs a chat completion request once a slot is free.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	return p.provider.Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request once a slot
+// is free. The slot is released when the stream ends.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		if err := p.acquire(ctx); err != nil {
+			outErrs <- err
+			return
+		}
+		defer p.release()
+
+		chunks, errs := p.provider.CompletionStream(ctx, params)
+		for chunk := range chunks {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// InFlight returns the number of requests currently running.
+func (p *Provider) InFlight() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.inFlight
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Queued returns the number of requests waiting for a free slot.
+func (p *Provider) Queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.waiters)
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// acquire takes a slot, waiting in the queue if none is free. It fails when
+// the queue is full, the queue timeout expires, the request is preempted or
+// ctx is done.
+func (p *Provider) acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	priority := PriorityFrom(ctx)
+
+	p.mu.Lock()
+	if p.canStart(priority) && !p.queuedAhead(priority) {
+		p.inFlight++
+		p.mu.Unlock()
+		return nil
+	}
+	if p.queueSize != unboundedQueue && len(p.waiters) >= p.queueSize && !p.preempt(priority) {
+		p.mu.Unlock()
+		return errors.NewQueueTimeoutError(p.provider.Name(), fmt.Errorf(
+			"all %d slots are busy and the queue is full", p.maxInFlight,
+		))
+	}
+	w := &waiter{priority: priority, ready: make(chan struct{})}
+	p.enqueue(w)
+	p.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if p.queueTimeout > 0 {
+		timer := time.NewTimer(p.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return w.err // Set before ready is closed.
+	case <-timeout:
+		err = errors.NewQueueTimeoutError(p.provider.Name(), fmt.Errorf(
+			"no slot became free within %v", p.queueTimeout,
+		))
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	if i := slices.Index(p.waiters, w); i >= 0 {
+		p.waiters = slices.Delete(p.waiters, i, i+1)
+		p.mu.Unlock()
+		return err
+	}
+	preempted := w.err != nil
+	p.mu.Unlock()
+
+	// A slot was handed over while giving up; pass it on.
+	if !preempted {
+		p.release()
+	}
+	return err
+}
+
+// canStart reports whether a request with priority may take a free slot now.
+// It must be called with p.mu held.
+func (p *Provider) canStart(priority Priority) bool {
+	if priority >= PriorityBatch {
+		return p.inFlight < p.maxInFlight-p.reservedSlots
+	}
+	return p.inFlight < p.maxInFlight
+}
+
+// dispatch hands free slots to queued requests, in queue order. It must be
+// called with p.mu held.
+func (p *Provider) dispatch() {
+	for len(p.waiters) > 0 && p.canStart(p.waiters[0].priority) {
+		p.inFlight++
+		close(p.waiters[0].ready)
+		p.waiters = slices.Delete(p.waiters, 0, 1)
+	}
+}
+
+// enqueue adds w to the queue, after the requests of its priority and higher.
+// It must be called with p.mu held.
+func (p *Provider) enqueue(w *waiter) {
+	i := len(p.waiters)
+	for i > 0 && p.waiters[i-1].priority > w.priority {
+		i--
+	}
+	p.waiters = slices.Insert(p.waiters, i, w)
+}
+
+// preempt makes room in the queue for a request with priority by failing the
+// most recent lower priority request, and reports whether it did. It must be
+// called with p.mu held.
+func (p *Provider) preempt(priority Priority) bool {
+	last := len(p.waiters) - 1
+	if last < 0 || p.waiters[last].priority <= priority {
+		return false
+	}
+
+	w := p.waiters[last]
+	w.err = errors.NewQueueTimeoutError(p.provider.Name(), fmt.Errorf("preempted by a higher priority request"))
+	close(w.ready)
+	p.waiters = p.waiters[:last]
+	return true
+}
+
+// queuedAhead reports whether a queued request would be served before a new
+// request with priority. It must be called with p.mu held.
+func (p *Provider) queuedAhead(priority Priority) bool {
+	return len(p.waiters) > 0 && p.waiters[0].priority <= priority
+}
+
+// release frees a slot, handing it to the first queued request that may take
+// it.
+func (p *Provider) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight--
+	p.dispatch()
+}
+
+// PriorityFrom returns the priority of requests with ctx, which is
+// PriorityInteractive unless set with WithPriority.
+func PriorityFrom(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// WithPriority returns a copy of ctx that marks requests with priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
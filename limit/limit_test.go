@@ -0,0 +1,394 @@
+package limit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// blockingProvider returns a mock whose completions block until gate is
+// closed, and which reports each request's model on started as it begins.
+func blockingProvider(gate <-chan struct{}) (*testutil.MockProvider, <-chan string) {
+	started := make(chan string, 100)
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		started <- params.Model
+		<-gate
+		return testutil.MockChatCompletion("done"), nil
+	}
+	return mock, started
+}
+
+// waitFor polls cond until it holds, failing t after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	require.Eventually(t, cond, time.Second, time.Millisecond)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		maxInFlight int
+		opts        []Option
+		wantErr     string
+	}{
+		{
+			name:        "rejects a zero limit",
+			maxInFlight: 0,
+			wantErr:     "max in-flight requests must be positive, got 0",
+		},
+		{
+			name:        "rejects negative queue sizes",
+			maxInFlight: 1,
+			opts:        []Option{WithQueueSize(-1)},
+			wantErr:     "queue size must not be negative, got -1",
+		},
+		{
+			name:        "rejects non-positive queue timeouts",
+			maxInFlight: 1,
+			opts:        []Option{WithQueueTimeout(0)},
+			wantErr:     "queue timeout must be positive, got 0s",
+		},
+		{
+			name:        "rejects reserving every slot",
+			maxInFlight: 2,
+			opts:        []Option{WithReservedSlots(2)},
+			wantErr:     "reserved slots must be between 0 and 1, got 2",
+		},
+		{
+			name:        "accepts valid options",
+			maxInFlight: 2,
+			opts:        []Option{nil, WithQueueSize(4), WithQueueTimeout(time.Second), WithReservedSlots(1)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(testutil.NewMockProvider(), tc.maxInFlight, tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "mock", p.Name())
+			require.NotNil(t, p.Unwrap())
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("limits in-flight requests", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 2)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() {
+				_, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+				require.NoError(t, err)
+			})
+		}
+
+		waitFor(t, func() bool { return p.Queued() == 3 })
+		require.Equal(t, 2, p.InFlight())
+		require.Len(t, started, 2)
+
+		close(gate)
+		wg.Wait()
+		require.Zero(t, p.InFlight())
+		require.Zero(t, p.Queued())
+	})
+
+	t.Run("serves queued requests in arrival order", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i, model := range []string{"first", "second", "third", "fourth"} {
+			wg.Go(func() {
+				_, err := p.Completion(context.Background(), providers.CompletionParams{
+					Messages: testutil.SimpleMessages(),
+					Model:    model,
+				})
+				require.NoError(t, err)
+			})
+			waitFor(t, func() bool { return p.InFlight()+p.Queued() == i+1 })
+		}
+
+		close(gate)
+		wg.Wait()
+
+		order := make([]string, 0, 4)
+		for range 4 {
+			order = append(order, <-started)
+		}
+		require.Equal(t, []string{"first", "second", "third", "fourth"}, order)
+	})
+
+	t.Run("fails at once when the queue is full", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		defer close(gate)
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1, WithQueueSize(0))
+		require.NoError(t, err)
+
+		go func() {
+			_, _ = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		}()
+		<-started
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrQueueTimeout)
+		require.Contains(t, err.Error(), "queue is full")
+	})
+
+	t.Run("fails when the queue timeout expires", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		defer close(gate)
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1, WithQueueTimeout(10*time.Millisecond))
+		require.NoError(t, err)
+
+		go func() {
+			_, _ = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		}()
+		<-started
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		var queueErr *errors.QueueTimeoutError
+		require.ErrorAs(t, err, &queueErr)
+		require.Equal(t, "mock", queueErr.Provider)
+		require.Zero(t, p.Queued())
+	})
+
+	t.Run("serves interactive requests before batch ones", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1)
+		require.NoError(t, err)
+
+		batch := WithPriority(context.Background(), PriorityBatch)
+		var wg sync.WaitGroup
+		for i, req := range []struct {
+			ctx   context.Context
+			model string
+		}{
+			{ctx: batch, model: "running"},
+			{ctx: batch, model: "batch"},
+			{ctx: context.Background(), model: "interactive"},
+		} {
+			wg.Go(func() {
+				_, err := p.Completion(req.ctx, providers.CompletionParams{
+					Messages: testutil.SimpleMessages(),
+					Model:    req.model,
+				})
+				require.NoError(t, err)
+			})
+			waitFor(t, func() bool { return p.InFlight()+p.Queued() == i+1 })
+		}
+
+		close(gate)
+		wg.Wait()
+
+		order := make([]string, 0, 3)
+		for range 3 {
+			order = append(order, <-started)
+		}
+		require.Equal(t, []string{"running", "interactive", "batch"}, order)
+	})
+
+	t.Run("preempts queued batch requests when the queue is full", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1, WithQueueSize(1))
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		}()
+		<-started
+
+		batchErrs := make(chan error)
+		go func() {
+			_, err := p.Completion(WithPriority(context.Background(), PriorityBatch), providers.CompletionParams{
+				Messages: testutil.SimpleMessages(),
+			})
+			batchErrs <- err
+		}()
+		waitFor(t, func() bool { return p.Queued() == 1 })
+
+		interactiveErrs := make(chan error)
+		go func() {
+			_, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			interactiveErrs <- err
+		}()
+
+		err = <-batchErrs
+		require.ErrorIs(t, err, errors.ErrQueueTimeout)
+		require.Contains(t, err.Error(), "preempted")
+
+		_, err = p.Completion(WithPriority(context.Background(), PriorityBatch), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		require.ErrorIs(t, err, errors.ErrQueueTimeout, "batch requests cannot preempt interactive ones")
+
+		close(gate)
+		require.NoError(t, <-interactiveErrs)
+		<-done
+		require.Zero(t, p.InFlight())
+	})
+
+	t.Run("keeps reserved slots for interactive requests", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 2, WithReservedSlots(1))
+		require.NoError(t, err)
+
+		batch := WithPriority(context.Background(), PriorityBatch)
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() {
+				_, err := p.Completion(batch, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+				require.NoError(t, err)
+			})
+		}
+		waitFor(t, func() bool { return p.InFlight() == 1 && p.Queued() == 1 })
+
+		wg.Go(func() {
+			_, err := p.Completion(context.Background(), providers.CompletionParams{
+				Messages: testutil.SimpleMessages(),
+				Model:    "interactive",
+			})
+			require.NoError(t, err)
+		})
+		waitFor(t, func() bool { return p.InFlight() == 2 })
+		require.Equal(t, 1, p.Queued())
+
+		close(gate)
+		wg.Wait()
+		require.Len(t, started, 3)
+		require.Zero(t, p.InFlight())
+	})
+
+	t.Run("leaves the queue when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1)
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		}()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			_, err := p.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			errs <- err
+		}()
+		waitFor(t, func() bool { return p.Queued() == 1 })
+
+		cancel()
+		require.ErrorIs(t, <-errs, context.Canceled)
+		require.Zero(t, p.Queued())
+
+		close(gate)
+		<-done
+		require.Zero(t, p.InFlight())
+	})
+}
+
+func TestPriorityFrom(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, PriorityInteractive, PriorityFrom(context.Background()))
+	require.Equal(t, PriorityBatch, PriorityFrom(WithPriority(context.Background(), PriorityBatch)))
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("holds the slot until the stream ends", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		p, err := New(mock, 1, WithQueueSize(0))
+		require.NoError(t, err)
+
+		chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		<-chunks
+		require.Equal(t, 1, p.InFlight())
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrQueueTimeout)
+
+		// Drain the stream.
+		for range chunks {
+		}
+		require.NoError(t, <-errs)
+		waitFor(t, func() bool { return p.InFlight() == 0 })
+	})
+
+	t.Run("reports queue errors on the error channel", func(t *testing.T) {
+		t.Parallel()
+
+		gate := make(chan struct{})
+		defer close(gate)
+		mock, started := blockingProvider(gate)
+		p, err := New(mock, 1, WithQueueSize(0))
+		require.NoError(t, err)
+
+		go func() {
+			_, _ = p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		}()
+		<-started
+
+		chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		// Drain the stream.
+		for range chunks {
+		}
+		require.ErrorIs(t, <-errs, errors.ErrQueueTimeout)
+	})
+}
@@ -0,0 +1,432 @@
+// Package multimodal wraps a provider so that images and PDFs reach it even
+// when it cannot read them. When a request has content the provider's
+// Capabilities do not support, the content is, by policy, replaced with a
+// text description from a vision model or OCR provider, replaced with a
+// placeholder, or rejected before the request is sent.
+package multimodal
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Content part types.
+const (
+	partTypeImageURL = "image_url"
+	partTypeText     = "text"
+)
+
+// Defaults.
+const (
+	// DefaultPrompt asks the vision model for a description that can stand
+	// in for an image.
+	DefaultPrompt = "Describe this image in detail for someone who cannot see it. " +
+		"Transcribe any text it contains exactly."
+
+	// defaultCacheSize is how many descriptions are kept.
+	defaultCacheSize = 256
+
+	// hashChunkSize is how much of a URL is hashed at a time.
+	hashChunkSize = 4 << 10
+)
+
+// Degradation policies.
+const (
+	// PolicyDescribe replaces unsupported content with a description: images
+	// are described by the vision model, and PDFs are read by the OCR
+	// provider, or by the vision model if it supports PDFs. It is the default.
+	PolicyDescribe Policy = "describe"
+
+	// PolicyDrop replaces unsupported content with a placeholder saying it
+	// was removed.
+	PolicyDrop Policy = "drop"
+
+	// PolicyError rejects requests with unsupported content.
+	PolicyError Policy = "error"
+)
+
+// Placeholders for dropped content.
+const (
+	droppedDocument = "[A PDF document was attached here but could not be shown to the model.]"
+	droppedImage    = "[An image was attached here but could not be shown to the model.]"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Policy is what happens to content the provider cannot read.
+type Policy string
+
+// Provider wraps a provider and degrades the content it cannot read.
+type Provider struct {
+	cacheSize    int
+	descriptions map[[sha256.Size]byte]string
+	mu           sync.Mutex
+	ocr          providers.DocumentProvider
+	ocrModel     string
+	policy       Policy
+	prompt       string
+	provider     providers.Provider
+	vision       providers.Provider
+	visionModel  string
+}
+
+// New wraps provider so that content its Capabilities do not support is
+// handled by the policy, PolicyDescribe by default. Providers that do not
+// report capabilities are assumed to support all content.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{
+		cacheSize:    defaultCacheSize,
+		descriptions: make(map[[sha256.Size]byte]string),
+		policy:       PolicyDescribe,
+		prompt:       DefaultPrompt,
+		provider:     provider,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.policy == PolicyDescribe && p.vision == nil && p.ocr == nil {
+		return nil, fmt.Errorf("describe policy requires a vision or OCR provider")
+	}
+
+	return p, nil
+}
+
+// WithOCR reads PDFs with model on ocr. Without it, PDFs are read by the
+// vision model when it supports them.
+func WithOCR(ocr providers.DocumentProvider, model string) Option {
+	return func(p *Provider) error {
+		if ocr == nil || model == "" {
+			return fmt.Errorf("OCR provider and model are required")
+		}
+
+		p.ocr = ocr
+		p.ocrModel = model
+		return nil
+	}
+}
+
+// WithPolicy sets what happens to content the provider cannot read.
+func WithPolicy(policy Policy) Option {
+	return func(p *Provider) error {
+		switch policy {
+		case PolicyDescribe, PolicyDrop, PolicyError:
+			p.policy = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown policy %q", policy)
+		}
+	}
+}
+
+// WithPrompt sets the instruction sent to the vision model with each image
+// or PDF. The default is DefaultPrompt.
+func WithPrompt(prompt string) Option {
+	return func(p *Provider) error {
+		if strings.TrimSpace(prompt) == "" {
+			return fmt.Errorf("prompt cannot be empty")
+		}
+
+		p.prompt = prompt
+		return nil
+	}
+}
+
+// WithVision describes images, and PDFs if no OCR provider is set, with model
+// on vision.
+func WithVision(vision providers.Provider, model string) Option {
+	return func(p *Provider) error {
+		if vision == nil || model == "" {
+			return fmt.Errorf("vision provider and model are required")
+		}
+
+		p.vision = vision
+		p.visionModel = model
+		return nil
+	}
+}
+
+// Capabilities returns the wrapped provider's capabilities, with image and
+// PDF support when the policy can make up for their absence.
+func (p *Provider) Capabilities() providers.Capabilities {
+	caps := p.capabilities()
+
+	if p.policy == PolicyDescribe {
+		caps.CompletionImage = caps.CompletionImage || p.vision != nil
+		caps.CompletionPDF = caps.CompletionPDF || p.ocr != nil || p.visionPDF()
+	}
+
+	return caps
+}
+
+// Completion performs a chat completion request, first replacing content the
+// provider cannot read according to the policy.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	params, err := p.convertParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.provider.Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request, first
+// replacing content the provider cannot read according to the policy.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	params, err := p.convertParams(ctx, params)
+	if err != nil {
+		chunks := make(chan providers.ChatCompletionChunk)
+		errs := make(chan error, 1)
+		close(chunks)
+		errs <- err
+		close(errs)
+		return chunks, errs
+	}
+
+	return p.provider.CompletionStream(ctx, params)
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// capabilities returns the wrapped provider's capabilities, or full support
+// if it does not report them.
+func (p *Provider) capabilities() providers.Capabilities {
+	if cp, ok := providers.As[providers.CapabilityProvider](p.provider); ok {
+		return cp.Capabilities()
+	}
+
+	return providers.Capabilities{Completion: true, CompletionImage: true, CompletionPDF: true}
+}
+
+// convertParams returns params with the content the provider cannot read
+// replaced. params is not modified.
+func (p *Provider) convertParams(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (providers.CompletionParams, error) {
+	caps := p.capabilities()
+
+	var messages []providers.Message
+	for i, msg := range params.Messages {
+		parts := msg.ContentParts()
+		if parts == nil {
+			continue
+		}
+
+		var converted []providers.ContentPart
+		for j, part := range parts {
+			if part.Type != partTypeImageURL || part.ImageURL == nil {
+				continue
+			}
+
+			pdf := isPDF(part.ImageURL.URL)
+			if (pdf && caps.CompletionPDF) || (!pdf && caps.CompletionImage) {
+				continue
+			}
+
+			text, err := p.replace(ctx, part.ImageURL, pdf)
+			if err != nil {
+				return params, err
+			}
+
+			if converted == nil {
+				converted = append([]providers.ContentPart(nil), parts...)
+			}
+			converted[j] = providers.ContentPart{Type: partTypeText, Text: text}
+		}
+
+		if converted == nil {
+			continue
+		}
+		if messages == nil {
+			messages = append([]providers.Message(nil), params.Messages...)
+		}
+		messages[i].Content = converted
+	}
+
+	if messages != nil {
+		params.Messages = messages
+	}
+	return params, nil
+}
+
+// describe returns a description of the image or PDF at img, from the cache
+// if it has been described before.
+func (p *Provider) describe(ctx context.Context, img *providers.ImageURL, pdf bool) (string, error) {
+	key := hashURL(img.URL)
+
+	p.mu.Lock()
+	description, ok := p.descriptions[key]
+	p.mu.Unlock()
+	if ok {
+		return description, nil
+	}
+
+	var err error
+	switch {
+	case pdf && p.ocr != nil:
+		description, err = p.readDocument(ctx, img.URL)
+	case pdf && p.visionPDF(), !pdf && p.vision != nil:
+		description, err = p.describeImage(ctx, img)
+	default:
+		kind := "images"
+		if pdf {
+			kind = "PDFs"
+		}
+		return "", errors.NewInvalidRequestError(p.provider.Name(),
+			fmt.Errorf("provider does not support %s and no provider is set to describe them", kind))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	if len(p.descriptions) >= p.cacheSize {
+		clear(p.descriptions)
+	}
+	p.descriptions[key] = description
+	p.mu.Unlock()
+
+	return description, nil
+}
+
+// describeImage asks the vision model to describe img.
+func (p *Provider) describeImage(ctx context.Context, img *providers.ImageURL) (string, error) {
+	resp, err := p.vision.Completion(ctx, providers.CompletionParams{
+		Model: p.visionModel,
+		Messages: []providers.Message{{
+			Role: providers.RoleUser,
+			Content: []providers.ContentPart{
+				{Type: partTypeText, Text: p.prompt},
+				{Type: partTypeImageURL, ImageURL: img},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.NewProviderError(p.vision.Name(), fmt.Errorf("vision model returned no choices"))
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Message.ContentString()), nil
+}
+
+// readDocument reads the document at documentURL with the OCR provider.
+func (p *Provider) readDocument(ctx context.Context, documentURL string) (string, error) {
+	resp, err := p.ocr.OCR(ctx, providers.OCRParams{Model: p.ocrModel, Document: documentURL})
+	if err != nil {
+		return "", err
+	}
+
+	pages := make([]string, 0, len(resp.Pages))
+	for _, page := range resp.Pages {
+		pages = append(pages, strings.TrimSpace(page.Markdown))
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// replace returns the text that stands in for the image or PDF at img under
+// the policy.
+func (p *Provider) replace(ctx context.Context, img *providers.ImageURL, pdf bool) (string, error) {
+	switch p.policy {
+	case PolicyDrop:
+		if pdf {
+			return droppedDocument, nil
+		}
+		return droppedImage, nil
+	case PolicyError:
+		param := "images"
+		if pdf {
+			param = "pdf"
+		}
+		return "", errors.NewUnsupportedParamError(p.provider.Name(), param)
+	case PolicyDescribe:
+		description, err := p.describe(ctx, img, pdf)
+		if err != nil {
+			return "", err
+		}
+		if pdf {
+			return "[Content of an attached PDF document:]\n" + description, nil
+		}
+		return "[Description of an attached image:]\n" + description, nil
+	default:
+		return "", fmt.Errorf("unknown policy %q", p.policy)
+	}
+}
+
+// visionPDF reports whether the vision model can read PDFs.
+func (p *Provider) visionPDF() bool {
+	if p.vision == nil {
+		return false
+	}
+	if cp, ok := providers.As[providers.CapabilityProvider](p.vision); ok {
+		return cp.Capabilities().CompletionPDF
+	}
+	return false
+}
+
+// hashURL returns the SHA-256 hash of rawURL. It hashes the URL in pieces
+// rather than converting it to a byte slice, which would copy data URLs of
+// large images on every request.
+func hashURL(rawURL string) [sha256.Size]byte {
+	var buf [hashChunkSize]byte
+	h := sha256.New()
+	for rawURL != "" {
+		n := copy(buf[:], rawURL)
+		_, _ = h.Write(buf[:n]) // Hash writes never fail.
+		rawURL = rawURL[n:]
+	}
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// isPDF reports whether rawURL is a PDF: a PDF data URL, or a URL whose path
+// ends in ".pdf".
+func isPDF(rawURL string) bool {
+	if header, _, ok := strings.Cut(rawURL, ","); ok && strings.HasPrefix(header, "data:") {
+		return strings.HasPrefix(strings.TrimPrefix(header, "data:"), "application/pdf")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+}
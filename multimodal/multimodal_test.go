@@ -0,0 +1,254 @@
+package multimodal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+const (
+	imageURL = "https://example.com/cat.png"
+	pdfURL   = "data:application/pdf;base64,JVBERi0="
+)
+
+// withAttachment returns a user message asking about the attachment at url.
+func withAttachment(url string) []providers.Message {
+	return []providers.Message{{
+		Role: providers.RoleUser,
+		Content: []providers.ContentPart{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image_url", ImageURL: &providers.ImageURL{URL: url}},
+		},
+	}}
+}
+
+// sentParts returns the content parts of the first message the mock received.
+func sentParts(t *testing.T, mock *testutil.MockProvider) []providers.ContentPart {
+	t.Helper()
+
+	require.Len(t, mock.CompletionCalls, 1)
+	return mock.CompletionCalls[0].Messages[0].ContentParts()
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	vision := testutil.NewMockProvider()
+
+	p, err := New(testutil.NewMockProvider(), nil, WithVision(vision, "vision-model"), WithPrompt("Caption it."))
+	require.NoError(t, err)
+	require.Equal(t, PolicyDescribe, p.policy)
+	require.Equal(t, "Caption it.", p.prompt)
+	require.Equal(t, "mock", p.Name())
+	require.NotNil(t, p.Unwrap())
+
+	_, err = New(testutil.NewMockProvider())
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithPolicy(PolicyDrop))
+	require.NoError(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithPolicy("ignore"))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithVision(nil, "vision-model"))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithOCR(vision, ""))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithPolicy(PolicyError), WithPrompt(" "))
+	require.Error(t, err)
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(testutil.NewMockProvider(), WithVision(testutil.NewMockProvider(), "vision-model"))
+	require.NoError(t, err)
+	require.True(t, p.Capabilities().CompletionImage)
+	require.False(t, p.Capabilities().CompletionPDF)
+
+	p, err = New(testutil.NewMockProvider(), WithOCR(testutil.NewMockProvider(), "ocr-model"))
+	require.NoError(t, err)
+	require.False(t, p.Capabilities().CompletionImage)
+	require.True(t, p.Capabilities().CompletionPDF)
+
+	p, err = New(testutil.NewMockProvider(), WithPolicy(PolicyDrop))
+	require.NoError(t, err)
+	require.False(t, p.Capabilities().CompletionImage)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("describes images with the vision model", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		vision := testutil.NewMockProvider()
+		vision.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return testutil.MockChatCompletion(" A cat on a mat. "), nil
+		}
+
+		p, err := New(mock, WithVision(vision, "vision-model"))
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(imageURL)})
+			require.NoError(t, err)
+		}
+
+		// The second request reuses the cached description.
+		require.Len(t, vision.CompletionCalls, 1)
+		require.Equal(t, "vision-model", vision.CompletionCalls[0].Model)
+		sent := vision.CompletionCalls[0].Messages[0].ContentParts()
+		require.Equal(t, DefaultPrompt, sent[0].Text)
+		require.Equal(t, imageURL, sent[1].ImageURL.URL)
+
+		require.Len(t, mock.CompletionCalls, 2)
+		parts := mock.CompletionCalls[1].Messages[0].ContentParts()
+		require.Equal(t, "What is this?", parts[0].Text)
+		require.Equal(t, providers.ContentPart{
+			Type: "text",
+			Text: "[Description of an attached image:]\nA cat on a mat.",
+		}, parts[1])
+	})
+
+	t.Run("reads PDFs with the OCR provider", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		ocr := testutil.NewMockProvider()
+
+		p, err := New(mock, WithOCR(ocr, "ocr-model"))
+		require.NoError(t, err)
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(pdfURL)})
+		require.NoError(t, err)
+		require.Equal(t, []providers.OCRParams{{Model: "ocr-model", Document: pdfURL}}, ocr.OCRCalls)
+		require.Equal(t, "[Content of an attached PDF document:]\n# Hello World", sentParts(t, mock)[1].Text)
+	})
+
+	t.Run("reads PDFs with a vision model that supports them", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		vision := testutil.NewMockProvider()
+		vision.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionPDF: true}
+		}
+
+		p, err := New(mock, WithVision(vision, "vision-model"))
+		require.NoError(t, err)
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(pdfURL)})
+		require.NoError(t, err)
+		require.Len(t, vision.CompletionCalls, 1)
+		require.Equal(t, "[Content of an attached PDF document:]\nHello World", sentParts(t, mock)[1].Text)
+	})
+
+	t.Run("fails when nothing can describe the content", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(testutil.NewMockProvider(), WithVision(testutil.NewMockProvider(), "vision-model"))
+		require.NoError(t, err)
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(pdfURL)})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("drops content", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		p, err := New(mock, WithPolicy(PolicyDrop))
+		require.NoError(t, err)
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(imageURL)})
+		require.NoError(t, err)
+		require.Equal(t, droppedImage, sentParts(t, mock)[1].Text)
+	})
+
+	t.Run("rejects content", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		p, err := New(mock, WithPolicy(PolicyError))
+		require.NoError(t, err)
+
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: withAttachment(imageURL)})
+		require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+		require.Empty(t, mock.CompletionCalls)
+	})
+
+	t.Run("passes supported content through", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionImage: true}
+		}
+
+		p, err := New(mock, WithPolicy(PolicyError))
+		require.NoError(t, err)
+
+		messages := withAttachment(imageURL)
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: messages})
+		require.NoError(t, err)
+		require.Equal(t, messages, mock.CompletionCalls[0].Messages)
+	})
+
+	t.Run("does not modify the caller's messages", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(testutil.NewMockProvider(), WithPolicy(PolicyDrop))
+		require.NoError(t, err)
+
+		messages := withAttachment(imageURL)
+		_, err = p.Completion(context.Background(), providers.CompletionParams{Messages: messages})
+		require.NoError(t, err)
+		require.Equal(t, withAttachment(imageURL), messages)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(testutil.NewMockProvider(), WithPolicy(PolicyError))
+	require.NoError(t, err)
+
+	chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: withAttachment(imageURL),
+	})
+	for range chunks {
+		require.Fail(t, "unexpected chunk")
+	}
+	require.ErrorIs(t, <-errs, errors.ErrUnsupportedParam)
+}
+
+func TestIsPDF(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: pdfURL, want: true},
+		{url: "https://example.com/report.PDF?download=1", want: true},
+		{url: "data:image/png;base64,iVBORw0KGgo=", want: false},
+		{url: imageURL, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, isPDF(tc.url))
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package pace wraps a provider to shape how streamed text is delivered.
+// Models often stream one token per chunk, hundreds of times a second; pace
+// coalesces those micro-chunks into larger deltas at a fixed interval, and
+// can cap delivery at a number of characters per second, so front-ends can
+// render a smooth typing effect from a steady flow of chunks.
+package pace
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultInterval is how often text is delivered when WithInterval is not
+// used.
+const defaultInterval = 50 * time.Millisecond
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and shapes the delivery of its streams.
+type Provider struct {
+	interval time.Duration
+	provider providers.Provider
+	rate     float64
+}
+
+// New wraps provider so that its streamed text is delivered at most once per
+// interval, 50ms by default, with the text that arrived in between merged
+// into one chunk.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{interval: defaultInterval, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithInterval sets how often text is delivered.
+func WithInterval(interval time.Duration) Option {
+	return func(p *Provider) error {
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive, got %v", interval)
+		}
+
+		p.interval = interval
+		return nil
+	}
+}
+
+// WithMaxRate caps delivery at charsPerSecond characters per second. Text
+// that arrives faster is held back and split across later chunks, so the
+// stream ends after the model has finished.
+func WithMaxRate(charsPerSecond float64) Option {
+	return func(p *Provider) error {
+		if charsPerSecond <= 0 {
+			return fmt.Errorf("max rate must be positive, got %v", charsPerSecond)
+		}
+
+		p.rate = charsPerSecond
+		return nil
+	}
+}
+
+// Completion performs a chat completion request. Responses are not streamed,
+// so they are returned as they are.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	return p.provider.Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request, delivering
+// its text at the configured interval and rate. Only chunks that carry
+// nothing but text are merged or split; other chunks, such as tool calls,
+// reasoning, usage and the finish reason, are delivered as they are, in
+// order, once the text before them has been.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	in, inErrs := p.provider.CompletionStream(ctx, params)
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		var pending []providers.ChatCompletionChunk
+		var budget float64
+		for in != nil || len(pending) > 0 {
+			select {
+			case chunk, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				pending = queue(pending, chunk)
+			case <-ticker.C:
+				if p.rate > 0 {
+					budget += p.rate * p.interval.Seconds()
+				}
+
+				var err error
+				if pending, budget, err = p.deliver(ctx, pending, budget, out); err != nil {
+					outErrs <- err
+					return
+				}
+				if len(pending) == 0 {
+					budget = 0 // Idle time does not build up a burst.
+				}
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-inErrs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// deliver sends the pending chunks to out, as far as the budget allows when a
+// rate is set, and returns the chunks and budget left.
+func (p *Provider) deliver(
+	ctx context.Context,
+	pending []providers.ChatCompletionChunk,
+	budget float64,
+	out chan<- providers.ChatCompletionChunk,
+) ([]providers.ChatCompletionChunk, float64, error) {
+	for len(pending) > 0 {
+		chunk := pending[0]
+
+		split := false
+		if p.rate > 0 && isText(chunk) {
+			content := chunk.Choices[0].Delta.Content
+			n := min(int(budget), utf8.RuneCountInString(content))
+			if n == 0 {
+				break
+			}
+			budget -= float64(n)
+
+			if head, rest := splitRunes(content, n); rest != "" {
+				// The rest of the chunk waits for more budget.
+				pending[0] = withContent(chunk, rest)
+				pending[0].Choices[0].Delta.Role = ""
+				chunk = withContent(chunk, head)
+				split = true
+			}
+		}
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return pending, budget, ctx.Err()
+		}
+
+		if !split {
+			pending = pending[1:]
+		}
+	}
+
+	return pending, budget, nil
+}
+
+// isText reports whether chunk carries only text, for one choice, so that it
+// can be merged with its neighbours or split.
+func isText(chunk providers.ChatCompletionChunk) bool {
+	if len(chunk.Choices) != 1 || chunk.Usage != nil {
+		return false
+	}
+
+	choice := chunk.Choices[0]
+	delta := choice.Delta
+	return choice.FinishReason == "" && delta.Content != "" && len(delta.ToolCalls) == 0 &&
+		delta.Reasoning == nil && delta.Audio == nil
+}
+
+// queue adds chunk to pending, merging its text into the last pending chunk
+// when both carry only text for the same choice.
+func queue(
+	pending []providers.ChatCompletionChunk,
+	chunk providers.ChatCompletionChunk,
+) []providers.ChatCompletionChunk {
+	if len(pending) == 0 || !isText(chunk) {
+		return append(pending, chunk)
+	}
+
+	last := pending[len(pending)-1]
+	if !isText(last) || last.Choices[0].Index != chunk.Choices[0].Index || chunk.Choices[0].Delta.Role != "" {
+		return append(pending, chunk)
+	}
+
+	pending[len(pending)-1] = withContent(last, last.Choices[0].Delta.Content+chunk.Choices[0].Delta.Content)
+	return pending
+}
+
+// splitRunes splits s after its first n runes.
+func splitRunes(s string, n int) (string, string) {
+	i := 0
+	for range n {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i], s[i:]
+}
+
+// withContent returns a copy of a text chunk with its content replaced. The
+// choices are copied, so the original chunk is not modified.
+func withContent(chunk providers.ChatCompletionChunk, content string) providers.ChatCompletionChunk {
+	choice := chunk.Choices[0]
+	choice.Delta.Content = content
+	chunk.Choices = []providers.ChunkChoice{choice}
+	return chunk
+}
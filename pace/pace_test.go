@@ -0,0 +1,173 @@
+package pace
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// streaming returns a mock provider that streams chunks at once, then err.
+func streaming(err error, chunks ...providers.ChatCompletionChunk) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		out := make(chan providers.ChatCompletionChunk, len(chunks))
+		errs := make(chan error, 1)
+		for _, chunk := range chunks {
+			out <- chunk
+		}
+		if err != nil {
+			errs <- err
+		}
+		close(out)
+		close(errs)
+		return out, errs
+	}
+	return mock
+}
+
+// text returns a chunk with content.
+func text(content string) providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{
+		ID:      "chunk",
+		Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: content}}},
+	}
+}
+
+// finish returns a chunk with a finish reason.
+func finish() providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{
+		ID:      "chunk",
+		Choices: []providers.ChunkChoice{{FinishReason: providers.FinishReasonStop}},
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(testutil.NewMockProvider(), nil, WithInterval(time.Second), WithMaxRate(40))
+	require.NoError(t, err)
+	require.Equal(t, time.Second, p.interval)
+	require.InDelta(t, 40, p.rate, 0)
+	require.Equal(t, "mock", p.Name())
+	require.NotNil(t, p.Unwrap())
+
+	_, err = New(testutil.NewMockProvider(), WithInterval(0))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), WithMaxRate(-1))
+	require.Error(t, err)
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("coalesces micro-chunks", func(t *testing.T) {
+		t.Parallel()
+
+		first := text("H")
+		first.Choices[0].Delta.Role = providers.RoleAssistant
+		mock := streaming(nil, first, text("e"), text("l"), text("l"), text("o"), finish())
+
+		p, err := New(mock, WithInterval(10*time.Millisecond))
+		require.NoError(t, err)
+
+		got, err := testutil.Collect(p.CompletionStream(context.Background(), providers.CompletionParams{}))
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, "Hello", got[0].Choices[0].Delta.Content)
+		require.Equal(t, providers.RoleAssistant, got[0].Choices[0].Delta.Role)
+		require.Equal(t, "chunk", got[0].ID)
+		require.Equal(t, finish(), got[1])
+	})
+
+	t.Run("keeps other chunks in order", func(t *testing.T) {
+		t.Parallel()
+
+		toolCall := providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Function: providers.FunctionCall{Name: "get_weather"}}},
+		}}}}
+		usage := providers.ChatCompletionChunk{Usage: &providers.Usage{TotalTokens: 5}}
+		mock := streaming(nil, text("a"), text("b"), toolCall, text("c"), finish(), usage)
+
+		p, err := New(mock, WithInterval(5*time.Millisecond))
+		require.NoError(t, err)
+
+		got, err := testutil.Collect(p.CompletionStream(context.Background(), providers.CompletionParams{}))
+		require.NoError(t, err)
+		require.Equal(t, []providers.ChatCompletionChunk{text("ab"), toolCall, text("c"), finish(), usage}, got)
+	})
+
+	t.Run("caps the rate", func(t *testing.T) {
+		t.Parallel()
+
+		content := strings.Repeat("é", 50)
+		mock := streaming(nil, text(content), finish())
+
+		// 1000 characters per second in 10ms ticks: 10 characters per chunk.
+		p, err := New(mock, WithInterval(10*time.Millisecond), WithMaxRate(1000))
+		require.NoError(t, err)
+
+		start := time.Now()
+		got, err := testutil.Collect(p.CompletionStream(context.Background(), providers.CompletionParams{}))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+		var b strings.Builder
+		for _, chunk := range got[:len(got)-1] {
+			delta := chunk.Choices[0].Delta.Content
+			require.LessOrEqual(t, utf8.RuneCountInString(delta), 10)
+			b.WriteString(delta)
+		}
+		require.Equal(t, content, b.String())
+		require.Len(t, got, 6)
+		require.Equal(t, finish(), got[len(got)-1])
+	})
+
+	t.Run("returns stream errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := streaming(stderrors.New("boom"), text("partial"))
+		p, err := New(mock, WithInterval(time.Millisecond))
+		require.NoError(t, err)
+
+		got, err := testutil.Collect(p.CompletionStream(context.Background(), providers.CompletionParams{}))
+		require.EqualError(t, err, "boom")
+		require.Equal(t, []providers.ChatCompletionChunk{text("partial")}, got)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		mock := streaming(nil, text(strings.Repeat("a", 1000)))
+		p, err := New(mock, WithInterval(time.Millisecond), WithMaxRate(100))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err = testutil.Collect(p.CompletionStream(ctx, providers.CompletionParams{}))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(testutil.NewMockProvider())
+	require.NoError(t, err)
+
+	resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+	require.NoError(t, err)
+	require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+}
@@ -0,0 +1,183 @@
+package promptstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// responseFormatJSONSchema is the response format type used for prompt schemas.
+const responseFormatJSONSchema = "json_schema"
+
+// Prompt is one version of a named prompt.
+type Prompt struct {
+	// Description says what the prompt is for.
+	Description string `json:"description,omitempty"`
+
+	// Messages are the prompt's messages. Text content is a text/template,
+	// executed with the variables passed to Render.
+	Messages []providers.Message `json:"messages"`
+
+	// Name is the prompt's name. Load takes it from the directory name.
+	Name string `json:"-"`
+
+	// Params are the request's default parameters, such as the model and
+	// temperature, in the request's JSON format. Their messages are ignored.
+	Params providers.CompletionParams `json:"params"`
+
+	// Schema, if set, is the JSON schema responses must follow.
+	Schema *providers.JSONSchema `json:"schema,omitempty"`
+
+	// Version is the prompt's version. Load takes it from the file name.
+	Version string `json:"-"`
+
+	// templates holds the parsed templates of each message's text: one for
+	// string content, or one per content part, nil for parts without text.
+	templates [][]*template.Template
+}
+
+// Ref identifies a prompt version.
+type Ref struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Response is a completion with the prompt version that produced it.
+type Response struct {
+	*providers.ChatCompletion
+
+	// Prompt is the prompt version the request was rendered from.
+	Prompt Ref
+}
+
+// Complete renders the prompt with vars, sends it to provider and returns the
+// response with the prompt's Ref, so it can be logged or stored with the
+// version that produced it.
+func (p *Prompt) Complete(ctx context.Context, provider providers.Provider, vars any) (*Response, error) {
+	params, err := p.Render(vars)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(provider.Name(), err)
+	}
+
+	resp, err := provider.Completion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{ChatCompletion: resp, Prompt: p.Ref()}, nil
+}
+
+// Ref returns the prompt's name and version.
+func (p *Prompt) Ref() Ref {
+	return Ref{Name: p.Name, Version: p.Version}
+}
+
+// Render returns the request for the prompt: its default parameters, its
+// messages with templates executed with vars, and its schema as the response
+// format. Templates fail on variables missing from vars. Change the result to
+// override defaults, or use it to stream.
+func (p *Prompt) Render(vars any) (providers.CompletionParams, error) {
+	params := p.Params
+	params.Messages = make([]providers.Message, len(p.Messages))
+
+	for i, msg := range p.Messages {
+		templates := p.templates[i]
+
+		parts := msg.ContentParts()
+		if parts == nil {
+			text, err := execute(templates[0], vars)
+			if err != nil {
+				return providers.CompletionParams{}, err
+			}
+			msg.Content = text
+			params.Messages[i] = msg
+			continue
+		}
+
+		rendered := make([]providers.ContentPart, len(parts))
+		for j, part := range parts {
+			if templates[j] != nil {
+				text, err := execute(templates[j], vars)
+				if err != nil {
+					return providers.CompletionParams{}, err
+				}
+				part.Text = text
+			}
+			rendered[j] = part
+		}
+		msg.Content = rendered
+		params.Messages[i] = msg
+	}
+
+	if p.Schema != nil {
+		params.ResponseFormat = &providers.ResponseFormat{Type: responseFormatJSONSchema, JSONSchema: p.Schema}
+	}
+
+	return params, nil
+}
+
+// parse parses the templates of the prompt's messages, normalizing content
+// parts decoded from JSON.
+func (p *Prompt) parse() error {
+	if len(p.Messages) == 0 {
+		return fmt.Errorf("prompt %s has no messages", p.Ref())
+	}
+
+	p.templates = make([][]*template.Template, len(p.Messages))
+	for i := range p.Messages {
+		msg := &p.Messages[i]
+
+		parts := msg.ContentParts()
+		if parts == nil {
+			tmpl, err := p.parseText(i, 0, msg.ContentString())
+			if err != nil {
+				return err
+			}
+			p.templates[i] = []*template.Template{tmpl}
+			continue
+		}
+
+		msg.Content = parts
+		p.templates[i] = make([]*template.Template, len(parts))
+		for j, part := range parts {
+			if part.Text == "" {
+				continue
+			}
+			tmpl, err := p.parseText(i, j, part.Text)
+			if err != nil {
+				return err
+			}
+			p.templates[i][j] = tmpl
+		}
+	}
+
+	return nil
+}
+
+// parseText parses the text of part j of message i.
+func (p *Prompt) parseText(i int, j int, text string) (*template.Template, error) {
+	name := fmt.Sprintf("%s message %d part %d", p.Ref(), i, j)
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing prompt %s: %w", p.Ref(), err)
+	}
+	return tmpl, nil
+}
+
+// String returns the reference as name@version.
+func (r Ref) String() string {
+	return r.Name + "@" + r.Version
+}
+
+// execute runs tmpl with vars.
+func execute(tmpl *template.Template, vars any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("rendering prompt: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,121 @@
+package promptstore
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// classifyPrompt is a prompt with default params, content parts and a schema.
+const classifyPrompt = `{
+	"description": "Classifies support tickets.",
+	"messages": [
+		{"role": "system", "content": "You classify tickets for {{.Product}}."},
+		{"role": "user", "content": [
+			{"type": "text", "text": "Ticket: {{.Ticket}}"},
+			{"type": "image_url", "image_url": {"url": "https://example.com/screenshot.png"}}
+		]}
+	],
+	"params": {"model": "gpt-4o-mini", "temperature": 0.2, "max_tokens": 50},
+	"schema": {"name": "ticket", "schema": {"type": "object"}}
+}`
+
+// loadClassify loads classifyPrompt as classify@v3.
+func loadClassify(t *testing.T) *Prompt {
+	t.Helper()
+
+	s, err := Load(fstest.MapFS{"classify/v3.json": {Data: []byte(classifyPrompt)}})
+	require.NoError(t, err)
+
+	prompt, err := s.Get("classify", "v3")
+	require.NoError(t, err)
+	return prompt
+}
+
+func TestPromptRender(t *testing.T) {
+	t.Parallel()
+
+	t.Run("renders messages, params and schema", func(t *testing.T) {
+		t.Parallel()
+
+		prompt := loadClassify(t)
+		params, err := prompt.Render(map[string]string{"Product": "Acme", "Ticket": "Login fails"})
+		require.NoError(t, err)
+
+		require.Equal(t, "gpt-4o-mini", params.Model)
+		require.Equal(t, 0.2, *params.Temperature)
+		require.Equal(t, 50, *params.MaxTokens)
+		require.Equal(t, "json_schema", params.ResponseFormat.Type)
+		require.Equal(t, "ticket", params.ResponseFormat.JSONSchema.Name)
+
+		require.Equal(t, "You classify tickets for Acme.", params.Messages[0].Content)
+		parts := params.Messages[1].ContentParts()
+		require.Equal(t, "Ticket: Login fails", parts[0].Text)
+		require.Equal(t, "https://example.com/screenshot.png", parts[1].ImageURL.URL)
+	})
+
+	t.Run("leaves the prompt unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		prompt := loadClassify(t)
+		_, err := prompt.Render(map[string]string{"Product": "Acme", "Ticket": "Login fails"})
+		require.NoError(t, err)
+
+		require.Equal(t, "You classify tickets for {{.Product}}.", prompt.Messages[0].Content)
+		require.Equal(t, "Ticket: {{.Ticket}}", prompt.Messages[1].ContentParts()[0].Text)
+	})
+
+	t.Run("fails on missing variables", func(t *testing.T) {
+		t.Parallel()
+
+		prompt := loadClassify(t)
+		_, err := prompt.Render(map[string]string{"Product": "Acme"})
+		require.ErrorContains(t, err, `map has no entry for key "Ticket"`)
+	})
+}
+
+func TestPromptComplete(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records the prompt version", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		resp, err := loadClassify(t).Complete(context.Background(), mock, struct{ Product, Ticket string }{
+			Product: "Acme",
+			Ticket:  "Login fails",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, Ref{Name: "classify", Version: "v3"}, resp.Prompt)
+		require.Equal(t, "classify@v3", resp.Prompt.String())
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+		require.Equal(t, "gpt-4o-mini", mock.CompletionCalls[0].Model)
+	})
+
+	t.Run("returns render errors as invalid requests", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		_, err := loadClassify(t).Complete(context.Background(), mock, map[string]string{})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+		require.Empty(t, mock.CompletionCalls)
+	})
+
+	t.Run("returns provider errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", nil)
+		}
+		_, err := loadClassify(t).Complete(context.Background(), mock, map[string]string{"Product": "A", "Ticket": "B"})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+	})
+}
@@ -0,0 +1,256 @@
+// Package promptstore manages named, versioned prompts: message templates with
+// default request parameters and a response schema, loaded from a directory or
+// an embedded file system. Versions can be pinned per environment, and
+// responses record the prompt version that produced them.
+package promptstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// File layout of a prompt directory.
+const (
+	// pinsFile, at the root, maps environments to pinned prompt versions.
+	pinsFile = "pins.json"
+
+	// versionExt is the extension of prompt version files, which are stored
+	// as <name>/<version>.json.
+	versionExt = ".json"
+)
+
+// Store holds prompts by name and version. It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	pins    map[string]map[string]string
+	prompts map[string]map[string]*Prompt
+}
+
+// NewStore returns an empty store. Add prompts with Add, or use Load to read
+// them from files.
+func NewStore() *Store {
+	return &Store{
+		pins:    make(map[string]map[string]string),
+		prompts: make(map[string]map[string]*Prompt),
+	}
+}
+
+// Load reads prompts from fsys, such as an embed.FS or the result of
+// os.DirFS. Each prompt version is a JSON file at <name>/<version>.json, and
+// an optional pins.json at the root pins versions per environment:
+//
+//	{"production": {"support-reply": "v2"}}
+//
+// Files with other extensions are ignored, so prompts can sit next to a README.
+func Load(fsys fs.FS) (*Store, error) {
+	s := NewStore()
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading prompt directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := s.loadPrompt(fsys, entry.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := fs.ReadFile(fsys, pinsFile)
+	switch {
+	case err == nil:
+		var pins map[string]map[string]string
+		if err := json.Unmarshal(data, &pins); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", pinsFile, err)
+		}
+		for env, versions := range pins {
+			for name, version := range versions {
+				if err := s.Pin(env, name, version); err != nil {
+					return nil, fmt.Errorf("%s: %w", pinsFile, err)
+				}
+			}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return nil, fmt.Errorf("reading %s: %w", pinsFile, err)
+	}
+
+	return s, nil
+}
+
+// LoadDir reads prompts from the directory dir. See Load for the layout.
+func LoadDir(dir string) (*Store, error) {
+	return Load(os.DirFS(dir))
+}
+
+// Add adds prompt to the store, replacing any prompt with the same name and
+// version. The prompt's templates are checked before it is added.
+func (s *Store) Add(prompt *Prompt) error {
+	if prompt.Name == "" || prompt.Version == "" {
+		return fmt.Errorf("prompt name and version must not be empty")
+	}
+	if err := prompt.parse(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prompts[prompt.Name] == nil {
+		s.prompts[prompt.Name] = make(map[string]*Prompt)
+	}
+	s.prompts[prompt.Name][prompt.Version] = prompt
+	return nil
+}
+
+// Get returns the given version of the named prompt.
+func (s *Store) Get(name string, version string) (*Prompt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prompt, ok := s.prompts[name][version]
+	if !ok {
+		return nil, fmt.Errorf("prompt %s not found", Ref{Name: name, Version: version})
+	}
+	return prompt, nil
+}
+
+// Latest returns the highest version of the named prompt. Versions are
+// compared with numbers in them taken as numbers, so "v10" is later than "v9".
+func (s *Store) Latest(name string) (*Prompt, error) {
+	versions := s.Versions(name)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("prompt %q not found", name)
+	}
+	return s.Get(name, versions[len(versions)-1])
+}
+
+// Names returns the names of all prompts in lexical order.
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(s.prompts))
+}
+
+// Pin makes Resolve return the given version of the named prompt in env.
+func (s *Store) Pin(env string, name string, version string) error {
+	if _, err := s.Get(name, version); err != nil {
+		return fmt.Errorf("pinning %s in %s: %w", name, env, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pins[env] == nil {
+		s.pins[env] = make(map[string]string)
+	}
+	s.pins[env][name] = version
+	return nil
+}
+
+// Resolve returns the version of the named prompt pinned in env, or the
+// latest version if none is pinned.
+func (s *Store) Resolve(name string, env string) (*Prompt, error) {
+	s.mu.RLock()
+	version, ok := s.pins[env][name]
+	s.mu.RUnlock()
+
+	if ok {
+		return s.Get(name, version)
+	}
+	return s.Latest(name)
+}
+
+// Versions returns the versions of the named prompt, earliest first.
+func (s *Store) Versions(name string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := slices.Collect(maps.Keys(s.prompts[name]))
+	slices.SortFunc(versions, compareVersions)
+	return versions
+}
+
+// loadPrompt reads the versions of the prompt in directory name.
+func (s *Store) loadPrompt(fsys fs.FS, name string) error {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return fmt.Errorf("reading prompt %s: %w", name, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != versionExt {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(name, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("reading prompt %s: %w", entry.Name(), err)
+		}
+
+		prompt := &Prompt{}
+		if err := json.Unmarshal(data, prompt); err != nil {
+			return fmt.Errorf("decoding prompt %s/%s: %w", name, entry.Name(), err)
+		}
+		prompt.Name = name
+		prompt.Version = strings.TrimSuffix(entry.Name(), versionExt)
+
+		if err := s.Add(prompt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compareVersions orders versions, comparing runs of digits by their value
+// and other runs lexically.
+func compareVersions(a string, b string) int {
+	for a != "" && b != "" {
+		ra, restA := versionRun(a)
+		rb, restB := versionRun(b)
+
+		if isDigits(ra) && isDigits(rb) {
+			ra, rb = strings.TrimLeft(ra, "0"), strings.TrimLeft(rb, "0")
+			if c := len(ra) - len(rb); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+
+		a, b = restA, restB
+	}
+	return len(a) - len(b)
+}
+
+// isDigits reports whether s starts with a digit. Runs from versionRun are
+// either all digits or none.
+func isDigits(s string) bool {
+	return s != "" && unicode.IsDigit(rune(s[0]))
+}
+
+// versionRun splits the leading run of digits or non-digits off s.
+func versionRun(s string) (string, string) {
+	digits := isDigits(s)
+	for i, r := range s {
+		if unicode.IsDigit(r) != digits {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
@@ -0,0 +1,150 @@
+package promptstore
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// promptFile returns the JSON of a prompt with one user message.
+func promptFile(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(`{"messages":[{"role":"user","content":"` + content + `"}]}`)}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads prompts and pins", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := Load(fstest.MapFS{
+			"README.md":           {Data: []byte("# Prompts")},
+			"greet/v1.json":       promptFile("Hi {{.Name}}"),
+			"greet/v2.json":       promptFile("Hello {{.Name}}"),
+			"greet/notes.txt":     {Data: []byte("ignored")},
+			"summarize/v10.json":  promptFile("Summarize {{.Text}}"),
+			"summarize/v9.json":   promptFile("Summarise {{.Text}}"),
+			"pins.json":           {Data: []byte(`{"production":{"greet":"v1"}}`)},
+			"summarize/old/x.txt": {Data: []byte("ignored")},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"greet", "summarize"}, s.Names())
+		require.Equal(t, []string{"v9", "v10"}, s.Versions("summarize"))
+
+		latest, err := s.Latest("summarize")
+		require.NoError(t, err)
+		require.Equal(t, Ref{Name: "summarize", Version: "v10"}, latest.Ref())
+
+		pinned, err := s.Resolve("greet", "production")
+		require.NoError(t, err)
+		require.Equal(t, "v1", pinned.Version)
+
+		unpinned, err := s.Resolve("greet", "staging")
+		require.NoError(t, err)
+		require.Equal(t, "v2", unpinned.Version)
+	})
+
+	t.Run("rejects pins of unknown versions", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Load(fstest.MapFS{
+			"greet/v1.json": promptFile("Hi"),
+			"pins.json":     {Data: []byte(`{"production":{"greet":"v3"}}`)},
+		})
+		require.EqualError(t, err, "pins.json: pinning greet in production: prompt greet@v3 not found")
+	})
+
+	t.Run("rejects invalid templates", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Load(fstest.MapFS{"greet/v1.json": promptFile("Hi {{.Name")})
+		require.ErrorContains(t, err, "parsing prompt greet@v1")
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Load(fstest.MapFS{"greet/v1.json": {Data: []byte("{")}})
+		require.ErrorContains(t, err, "decoding prompt greet/v1.json")
+	})
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("adds and pins prompts", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewStore()
+		for _, version := range []string{"2026-01-05", "2026-03-01"} {
+			require.NoError(t, s.Add(&Prompt{
+				Name:     "greet",
+				Version:  version,
+				Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hi"}},
+			}))
+		}
+
+		latest, err := s.Latest("greet")
+		require.NoError(t, err)
+		require.Equal(t, "2026-03-01", latest.Version)
+
+		require.NoError(t, s.Pin("production", "greet", "2026-01-05"))
+		pinned, err := s.Resolve("greet", "production")
+		require.NoError(t, err)
+		require.Equal(t, "2026-01-05", pinned.Version)
+	})
+
+	t.Run("rejects prompts without a name, version or messages", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewStore()
+		require.EqualError(t, s.Add(&Prompt{Name: "greet"}), "prompt name and version must not be empty")
+		require.EqualError(t, s.Add(&Prompt{Name: "greet", Version: "v1"}), "prompt greet@v1 has no messages")
+	})
+
+	t.Run("reports missing prompts", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewStore()
+		_, err := s.Get("greet", "v1")
+		require.EqualError(t, err, "prompt greet@v1 not found")
+		_, err = s.Resolve("greet", "production")
+		require.EqualError(t, err, `prompt "greet" not found`)
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v9", b: "v10", want: -1},
+		{a: "v2", b: "v2", want: 0},
+		{a: "1.10.0", b: "1.9.3", want: 1},
+		{a: "v1", b: "v1.1", want: -1},
+		{a: "v01", b: "v1", want: 0},
+		{a: "beta", b: "alpha", want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			t.Parallel()
+
+			got := compareVersions(tc.a, tc.b)
+			switch {
+			case tc.want < 0:
+				require.Negative(t, got)
+			case tc.want > 0:
+				require.Positive(t, got)
+			default:
+				require.Zero(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package provenance wraps a provider to tag responses with where they came
+// from: the model, the provider, the prompt version and the request ID. The
+// tags are set in the responses' Provenance field and can also be written into
+// the content, as an HTML comment or a JSON field, for downstream systems that
+// only see the text and must track AI-generated content.
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Markers, which write the provenance into the content of responses.
+const (
+	// MarkerHTMLComment appends the provenance to the content as an HTML
+	// comment, which Markdown and HTML renderers hide.
+	MarkerHTMLComment = "html_comment"
+
+	// MarkerJSONField adds the provenance to content that is a JSON object,
+	// as the JSONField field. Other content is left as it is.
+	MarkerJSONField = "json_field"
+)
+
+// JSONField is the field MarkerJSONField adds to JSON object content.
+const JSONField = "_provenance"
+
+// htmlCommentFormat formats the comment MarkerHTMLComment appends.
+const htmlCommentFormat = "\n\n<!-- provenance: %s -->"
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and tags its responses with their provenance.
+type Provider struct {
+	marker   string
+	now      func() time.Time
+	provider providers.Provider
+}
+
+// promptVersionKey is the context key for the version set by
+// WithPromptVersion.
+type promptVersionKey struct{}
+
+// New wraps provider so that its responses carry their provenance. Without
+// options, the provenance is only set in the Provenance field.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	p := &Provider{now: time.Now, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithMarker also writes the provenance into the content of each choice that
+// has content, as MarkerHTMLComment or MarkerJSONField. Streams end with a
+// chunk carrying the HTML comment; MarkerJSONField needs the whole content,
+// so it does not apply to streams.
+func WithMarker(marker string) Option {
+	return func(p *Provider) error {
+		if marker != MarkerHTMLComment && marker != MarkerJSONField {
+			return fmt.Errorf("unknown marker %q", marker)
+		}
+
+		p.marker = marker
+		return nil
+	}
+}
+
+// Completion performs a chat completion request and sets the response's
+// Provenance field, and its marker if one is configured.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := p.provider.Completion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = params.Model
+	}
+	resp.Provenance = p.provenance(ctx, model, resp.RequestID, resp.PromptVariant)
+
+	switch p.marker {
+	case MarkerHTMLComment:
+		comment := htmlComment(resp.Provenance)
+		for i := range resp.Choices {
+			appendText(&resp.Choices[i].Message, comment)
+		}
+	case MarkerJSONField:
+		for i := range resp.Choices {
+			addJSONField(&resp.Choices[i].Message, resp.Provenance)
+		}
+	default:
+	}
+
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request. Once the
+// stream has ended, it sends one more chunk carrying the Provenance field and,
+// with MarkerHTMLComment, the comment as content for each choice that streamed
+// content.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	in, inErrs := p.provider.CompletionStream(ctx, params)
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		last := providers.ChatCompletionChunk{Model: params.Model}
+		var indexes []int // Choices that streamed content, in order.
+		for chunk := range in {
+			last.ID, last.Object, last.Created = chunk.ID, chunk.Object, chunk.Created
+			if chunk.Model != "" {
+				last.Model = chunk.Model
+			}
+			if chunk.PromptVariant != "" {
+				last.PromptVariant = chunk.PromptVariant
+			}
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Content != "" && !slices.Contains(indexes, choice.Index) {
+					indexes = append(indexes, choice.Index)
+				}
+			}
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-inErrs; err != nil {
+			errs <- err
+			return
+		}
+
+		final := providers.ChatCompletionChunk{
+			ID:            last.ID,
+			Object:        last.Object,
+			Created:       last.Created,
+			Model:         last.Model,
+			Choices:       []providers.ChunkChoice{},
+			PromptVariant: last.PromptVariant,
+			Provenance:    p.provenance(ctx, last.Model, "", last.PromptVariant),
+		}
+		if p.marker == MarkerHTMLComment {
+			comment := htmlComment(final.Provenance)
+			for _, i := range indexes {
+				final.Choices = append(final.Choices, providers.ChunkChoice{
+					Index: i,
+					Delta: providers.ChunkDelta{Content: comment},
+				})
+			}
+		}
+
+		select {
+		case chunks <- final:
+		case <-ctx.Done():
+			errs <- ctx.Err()
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// provenance returns the provenance of a response to a request with ctx. The
+// prompt version set with WithPromptVersion takes precedence over the prompt
+// variant chosen by router.Prompts.
+func (p *Provider) provenance(
+	ctx context.Context,
+	model string,
+	requestID string,
+	variant string,
+) *providers.Provenance {
+	version, ok := ctx.Value(promptVersionKey{}).(string)
+	if !ok {
+		version = variant
+	}
+
+	return &providers.Provenance{
+		GeneratedAt:   p.now().UTC(),
+		Model:         model,
+		PromptVersion: version,
+		Provider:      p.provider.Name(),
+		RequestID:     requestID,
+	}
+}
+
+// WithPromptVersion returns a copy of ctx carrying version, such as the name
+// and version of a promptstore prompt, to be recorded in the provenance of
+// responses to requests made with it.
+func WithPromptVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, promptVersionKey{}, version)
+}
+
+// addJSONField adds prov to msg's content as JSONField, if the content is a
+// JSON object. The field is added last, so the rest of the content keeps its
+// order and formatting.
+func addJSONField(msg *providers.Message, prov *providers.Provenance) {
+	if msg.IsMultiModal() {
+		return
+	}
+
+	content := strings.TrimSpace(msg.ContentString())
+	if !strings.HasPrefix(content, "{") || !json.Valid([]byte(content)) {
+		return
+	}
+
+	field, err := json.Marshal(prov)
+	if err != nil {
+		return
+	}
+
+	body := strings.TrimSpace(strings.TrimSuffix(content, "}"))
+	separator := ","
+	if body == "{" {
+		separator = ""
+	}
+	msg.Content = fmt.Sprintf("%s%s%q:%s}", body, separator, JSONField, field)
+}
+
+// appendText appends text to msg's content, as a text part if the content is
+// multi-modal. Messages without content, such as tool calls, are left alone.
+func appendText(msg *providers.Message, text string) {
+	switch {
+	case msg.IsMultiModal():
+		part := providers.ContentPart{Type: "text", Text: strings.TrimLeft(text, "\n")}
+		msg.Content = append(msg.ContentParts(), part)
+	case msg.ContentString() != "":
+		msg.Content = msg.ContentString() + text
+	default:
+	}
+}
+
+// htmlComment returns the HTML comment MarkerHTMLComment appends for prov.
+func htmlComment(prov *providers.Provenance) string {
+	data, err := json.Marshal(prov)
+	if err != nil {
+		return ""
+	}
+
+	// json.Marshal escapes "<" and ">", so no value can end the comment early.
+	return fmt.Sprintf(htmlCommentFormat, data)
+}
@@ -0,0 +1,235 @@
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testTime is the time test providers report as GeneratedAt.
+var testTime = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestProvider wraps a mock that answers with content, with a fixed clock.
+func newTestProvider(t *testing.T, content string, opts ...Option) *Provider {
+	t.Helper()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion(content)
+		resp.Model = "gpt-test"
+		resp.RequestID = "req_123"
+		return resp, nil
+	}
+
+	p, err := New(mock, opts...)
+	require.NoError(t, err)
+	p.now = func() time.Time { return testTime }
+
+	return p
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("requires a provider", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(nil)
+		require.EqualError(t, err, "provider is required")
+	})
+
+	t.Run("rejects unknown markers", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(testutil.NewMockProvider(), WithMarker("xml"))
+		require.EqualError(t, err, `unknown marker "xml"`)
+	})
+
+	t.Run("wraps the provider", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		p, err := New(mock, nil)
+		require.NoError(t, err)
+		require.Equal(t, "mock", p.Name())
+		require.Same(t, mock, p.Unwrap())
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	want := &providers.Provenance{
+		GeneratedAt: testTime,
+		Model:       "gpt-test",
+		Provider:    "mock",
+		RequestID:   "req_123",
+	}
+
+	t.Run("sets the provenance", func(t *testing.T) {
+		t.Parallel()
+
+		p := newTestProvider(t, "Hello")
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, want, resp.Provenance)
+		require.Equal(t, "Hello", resp.Choices[0].Message.Content)
+	})
+
+	t.Run("records the prompt version", func(t *testing.T) {
+		t.Parallel()
+
+		p := newTestProvider(t, "Hello")
+
+		ctx := WithPromptVersion(context.Background(), "support-agent@v3")
+		resp, err := p.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "support-agent@v3", resp.Provenance.PromptVersion)
+	})
+
+	t.Run("falls back to the prompt variant", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			resp := testutil.MockChatCompletion("Hello")
+			resp.PromptVariant = "terse"
+			return resp, nil
+		}
+		p, err := New(mock)
+		require.NoError(t, err)
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			Model:    "gpt-request",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "terse", resp.Provenance.PromptVersion)
+		require.NotEmpty(t, resp.Provenance.Model)
+	})
+
+	t.Run("appends an HTML comment", func(t *testing.T) {
+		t.Parallel()
+
+		p := newTestProvider(t, "Hello", WithMarker(MarkerHTMLComment))
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "Hello\n\n<!-- provenance: "+
+			`{"generated_at":"2025-06-01T12:00:00Z","model":"gpt-test","provider":"mock","request_id":"req_123"}`+
+			" -->", resp.Choices[0].Message.Content)
+	})
+
+	t.Run("adds a JSON field to JSON objects", func(t *testing.T) {
+		t.Parallel()
+
+		p := newTestProvider(t, `{"name": "Ada", "age": 36}`, WithMarker(MarkerJSONField))
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+
+		content := resp.Choices[0].Message.ContentString()
+		require.True(t, strings.HasPrefix(content, `{"name": "Ada", "age": 36,"_provenance":{`), content)
+
+		var got struct {
+			Name       string                `json:"name"`
+			Provenance *providers.Provenance `json:"_provenance"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(content), &got))
+		require.Equal(t, "Ada", got.Name)
+		require.Equal(t, want, got.Provenance)
+	})
+
+	t.Run("adds a JSON field to empty objects", func(t *testing.T) {
+		t.Parallel()
+
+		p := newTestProvider(t, "{ }", WithMarker(MarkerJSONField))
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.True(t, json.Valid([]byte(resp.Choices[0].Message.ContentString())))
+	})
+
+	t.Run("leaves other content alone with the JSON field marker", func(t *testing.T) {
+		t.Parallel()
+
+		for _, content := range []string{"Hello", "[1, 2]", `{"unterminated": `} {
+			p := newTestProvider(t, content, WithMarker(MarkerJSONField))
+
+			resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+			require.Equal(t, content, resp.Choices[0].Message.Content)
+			require.NotNil(t, resp.Provenance)
+		}
+	})
+
+	t.Run("leaves tool calls alone", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+			}}), nil
+		}
+		p, err := New(mock, WithMarker(MarkerHTMLComment))
+		require.NoError(t, err)
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Empty(t, resp.Choices[0].Message.ContentString())
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	p := newTestProvider(t, "", WithMarker(MarkerHTMLComment))
+	p.provider.(*testutil.MockProvider).CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 2)
+		errs := make(chan error)
+		for _, content := range []string{"Hello", ", world"} {
+			chunks <- providers.ChatCompletionChunk{
+				ID:      "chunk-1",
+				Model:   "gpt-test",
+				Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: content}}},
+			}
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+
+	var acc providers.Accumulator
+	var received []providers.ChatCompletionChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+		acc.Add(chunk)
+	}
+	require.NoError(t, <-errs)
+	require.Len(t, received, 3)
+	require.Nil(t, received[0].Provenance)
+
+	final := received[2]
+	require.Equal(t, "chunk-1", final.ID)
+	require.Equal(t, &providers.Provenance{GeneratedAt: testTime, Model: "gpt-test", Provider: "mock"}, final.Provenance)
+
+	completion := acc.Completion()
+	require.Equal(t, final.Provenance, completion.Provenance)
+	require.Equal(t, "Hello, world"+htmlComment(final.Provenance), completion.Choices[0].Message.Content)
+}
@@ -0,0 +1,219 @@
+package providers
+
+import (
+	"encoding/base64"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// objectChatCompletion is the object type of an accumulated completion.
+const objectChatCompletion = "chat.completion"
+
+// Accumulator joins the chunks of a stream into the ChatCompletion the
+// provider would have returned without streaming. The zero value is ready to
+// use. It is not safe for concurrent use.
+type Accumulator struct {
+	choices    map[int]*accumulatedChoice
+	completion ChatCompletion
+}
+
+// accumulatedChoice is a choice whose deltas are being joined.
+type accumulatedChoice struct {
+	audio     []byte
+	choice    Choice
+	content   strings.Builder
+	reasoning strings.Builder
+	refusal   strings.Builder
+}
+
+// Add joins chunk to the chunks added before it.
+//
+// Tool call fragments follow the OpenAI convention: a fragment with an ID
+// starts a new call, unless it repeats the ID of the last call, and
+// fragments without one extend the last call.
+func (a *Accumulator) Add(chunk ChatCompletionChunk) {
+	c := &a.completion
+	if c.ID == "" {
+		c.ID = chunk.ID
+	}
+	if chunk.Created != 0 {
+		c.Created = chunk.Created
+	}
+	if chunk.Model != "" {
+		c.Model = chunk.Model
+	}
+	if chunk.SystemFingerprint != "" {
+		c.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.Usage != nil {
+		c.Usage = chunk.Usage
+	}
+	if c.RateLimit == nil {
+		c.RateLimit = chunk.RateLimit
+	}
+	if chunk.Arm != "" {
+		c.Arm = chunk.Arm
+	}
+	if chunk.PromptVariant != "" {
+		c.PromptVariant = chunk.PromptVariant
+	}
+	if chunk.ResponseFormatMode != "" {
+		c.ResponseFormatMode = chunk.ResponseFormatMode
+	}
+	if chunk.Provenance != nil {
+		c.Provenance = chunk.Provenance
+	}
+	c.PromptFilterResults = append(c.PromptFilterResults, chunk.PromptFilterResults...)
+
+	for _, delta := range chunk.Choices {
+		a.choice(delta.Index).add(delta)
+	}
+}
+
+// Completion returns the completion joined so far, with its choices ordered
+// by index. Choices without a role are reported as from the assistant.
+func (a *Accumulator) Completion() *ChatCompletion {
+	completion := a.completion
+	completion.Object = objectChatCompletion
+	completion.PromptFilterResults = slices.Clone(completion.PromptFilterResults)
+	completion.Choices = make([]Choice, 0, len(a.choices))
+
+	for _, i := range slices.Sorted(maps.Keys(a.choices)) {
+		completion.Choices = append(completion.Choices, a.choices[i].result())
+	}
+	return &completion
+}
+
+// choice returns the choice with index, adding it if needed.
+func (a *Accumulator) choice(index int) *accumulatedChoice {
+	if a.choices == nil {
+		a.choices = make(map[int]*accumulatedChoice)
+	}
+
+	ac, ok := a.choices[index]
+	if !ok {
+		ac = &accumulatedChoice{choice: Choice{Index: index}}
+		a.choices[index] = ac
+	}
+	return ac
+}
+
+// add joins delta to the choice.
+func (ac *accumulatedChoice) add(delta ChunkChoice) {
+	msg := &ac.choice.Message
+	if msg.Role == "" {
+		msg.Role = delta.Delta.Role
+	}
+	if delta.FinishReason != "" {
+		ac.choice.FinishReason = delta.FinishReason
+	}
+
+	ac.content.WriteString(delta.Delta.Content)
+	ac.refusal.WriteString(delta.Delta.Refusal)
+	if delta.Delta.Reasoning != nil {
+		ac.reasoning.WriteString(delta.Delta.Reasoning.Content)
+	}
+	for _, call := range delta.Delta.ToolCalls {
+		ac.addToolCall(call)
+	}
+	if delta.Delta.Audio != nil {
+		ac.addAudio(*delta.Delta.Audio)
+	}
+	for _, result := range delta.ContentFilterResults {
+		ac.addContentFilterResult(result)
+	}
+}
+
+// addAudio joins an audio fragment, whose Data is base64-encoded on its own.
+func (ac *accumulatedChoice) addAudio(fragment Audio) {
+	msg := &ac.choice.Message
+	if msg.Audio == nil {
+		msg.Audio = &Audio{}
+	}
+	if fragment.ID != "" {
+		msg.Audio.ID = fragment.ID
+	}
+	if fragment.ExpiresAt != 0 {
+		msg.Audio.ExpiresAt = fragment.ExpiresAt
+	}
+	msg.Audio.Transcript += fragment.Transcript
+
+	if data, err := base64.StdEncoding.DecodeString(fragment.Data); err == nil {
+		ac.audio = append(ac.audio, data...)
+	}
+}
+
+// addContentFilterResult joins a content filter verdict on part of the
+// stream: a category is filtered or detected if any part was, at the highest
+// severity reported.
+func (ac *accumulatedChoice) addContentFilterResult(result ContentFilterResult) {
+	results := ac.choice.ContentFilterResults
+	i := slices.IndexFunc(results, func(r ContentFilterResult) bool { return r.Category == result.Category })
+	if i < 0 {
+		ac.choice.ContentFilterResults = append(results, result)
+		return
+	}
+
+	joined := &results[i]
+	joined.Filtered = joined.Filtered || result.Filtered
+	joined.Detected = joined.Detected || result.Detected
+	if severityRank(result.Severity) > severityRank(joined.Severity) {
+		joined.Severity = result.Severity
+	}
+}
+
+// addToolCall joins a tool call fragment.
+func (ac *accumulatedChoice) addToolCall(fragment ToolCall) {
+	calls := ac.choice.Message.ToolCalls
+	if len(calls) == 0 || (fragment.ID != "" && fragment.ID != calls[len(calls)-1].ID) {
+		ac.choice.Message.ToolCalls = append(calls, fragment)
+		return
+	}
+
+	last := &calls[len(calls)-1]
+	if last.Type == "" {
+		last.Type = fragment.Type
+	}
+	last.Function.Name += fragment.Function.Name
+	last.Function.Arguments += fragment.Function.Arguments
+}
+
+// result returns the joined choice.
+func (ac *accumulatedChoice) result() Choice {
+	choice := ac.choice
+	msg := &choice.Message
+	if msg.Role == "" {
+		msg.Role = RoleAssistant
+	}
+
+	msg.Content = ac.content.String()
+	msg.Refusal = ac.refusal.String()
+	if ac.reasoning.Len() > 0 {
+		msg.Reasoning = &Reasoning{Content: ac.reasoning.String()}
+	}
+	msg.ToolCalls = slices.Clone(msg.ToolCalls)
+	choice.ContentFilterResults = slices.Clone(choice.ContentFilterResults)
+	if msg.Audio != nil {
+		audio := *msg.Audio
+		audio.Data = base64.StdEncoding.EncodeToString(ac.audio)
+		msg.Audio = &audio
+	}
+	return choice
+}
+
+// severityRank orders content filter severities, with unknown ones lowest.
+func severityRank(severity string) int {
+	switch severity {
+	case SeveritySafe:
+		return 1
+	case SeverityLow:
+		return 2
+	case SeverityMedium:
+		return 3
+	case SeverityHigh:
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccumulator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("joins content, reasoning and metadata", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		acc.Add(ChatCompletionChunk{
+			ID:      "chatcmpl-1",
+			Created: 1,
+			Model:   "m",
+			Choices: []ChunkChoice{{Delta: ChunkDelta{Role: RoleAssistant, Reasoning: &Reasoning{Content: "Hm"}}}},
+		})
+		acc.Add(ChatCompletionChunk{
+			ID:      "chatcmpl-1",
+			Model:   "m",
+			Choices: []ChunkChoice{{Delta: ChunkDelta{Content: "Hello"}}},
+		})
+		acc.Add(ChatCompletionChunk{
+			ID:                "chatcmpl-1",
+			Model:             "m",
+			SystemFingerprint: "fp",
+			Choices:           []ChunkChoice{{Delta: ChunkDelta{Content: " world"}, FinishReason: FinishReasonStop}},
+			Usage:             &Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+		})
+
+		require.Equal(t, &ChatCompletion{
+			ID:      "chatcmpl-1",
+			Object:  "chat.completion",
+			Created: 1,
+			Model:   "m",
+			Choices: []Choice{{
+				Message: Message{
+					Role:      RoleAssistant,
+					Content:   "Hello world",
+					Reasoning: &Reasoning{Content: "Hm"},
+				},
+				FinishReason: FinishReasonStop,
+			}},
+			Usage:             &Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+			SystemFingerprint: "fp",
+		}, acc.Completion())
+	})
+
+	t.Run("joins tool call fragments", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		for _, call := range []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather"}},
+			{Function: FunctionCall{Arguments: `{"location":`}},
+			{ID: "call_1", Function: FunctionCall{Arguments: `"Paris"}`}},
+			{ID: "call_2", Type: "function", Function: FunctionCall{Name: "get_date", Arguments: "{}"}},
+		} {
+			acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{{Delta: ChunkDelta{ToolCalls: []ToolCall{call}}}}})
+		}
+
+		require.Equal(t, []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`}},
+			{ID: "call_2", Type: "function", Function: FunctionCall{Name: "get_date", Arguments: "{}"}},
+		}, acc.Completion().Choices[0].Message.ToolCalls)
+	})
+
+	t.Run("orders choices by index", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{
+			{Index: 1, Delta: ChunkDelta{Content: "b"}},
+			{Index: 0, Delta: ChunkDelta{Content: "a"}},
+		}})
+
+		choices := acc.Completion().Choices
+		require.Len(t, choices, 2)
+		require.Equal(t, "a", choices[0].Message.Content)
+		require.Equal(t, RoleAssistant, choices[1].Message.Role)
+		require.Equal(t, "b", choices[1].Message.Content)
+	})
+
+	t.Run("joins audio fragments", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{{Delta: ChunkDelta{
+			Audio: &Audio{ID: "audio_1", Data: "AAE=", Transcript: "Hel"},
+		}}}})
+		acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{{Delta: ChunkDelta{
+			Audio: &Audio{Data: "Ag==", ExpiresAt: 10, Transcript: "lo"},
+		}}}})
+
+		require.Equal(t, &Audio{ID: "audio_1", Data: "AAEC", ExpiresAt: 10, Transcript: "Hello"},
+			acc.Completion().Choices[0].Message.Audio)
+	})
+
+	t.Run("returns no choices for an empty stream", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		require.Empty(t, acc.Completion().Choices)
+	})
+
+	t.Run("joins content filter results", func(t *testing.T) {
+		t.Parallel()
+
+		var acc Accumulator
+		acc.Add(ChatCompletionChunk{
+			PromptFilterResults: []PromptFilterResult{{
+				ContentFilterResults: []ContentFilterResult{{Category: "hate", Severity: SeveritySafe}},
+			}},
+		})
+		acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{{
+			Delta: ChunkDelta{Content: "Hello"},
+			ContentFilterResults: []ContentFilterResult{
+				{Category: "hate", Severity: SeverityMedium},
+				{Category: "jailbreak"},
+			},
+		}}})
+		acc.Add(ChatCompletionChunk{Choices: []ChunkChoice{{
+			FinishReason: FinishReasonContentFilter,
+			ContentFilterResults: []ContentFilterResult{
+				{Category: "hate", Filtered: true, Severity: SeverityLow},
+				{Category: "jailbreak", Detected: true},
+			},
+		}}})
+
+		completion := acc.Completion()
+		require.Equal(t, []PromptFilterResult{{
+			ContentFilterResults: []ContentFilterResult{{Category: "hate", Severity: SeveritySafe}},
+		}}, completion.PromptFilterResults)
+		require.Equal(t, "Hello", completion.Choices[0].Message.ContentString())
+		require.Equal(t, []ContentFilterResult{
+			{Category: "hate", Filtered: true, Severity: SeverityMedium},
+			{Category: "jailbreak", Detected: true},
+		}, completion.Choices[0].ContentFilterResults)
+	})
+}
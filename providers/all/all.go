@@ -0,0 +1,23 @@
+// Package all registers every built-in provider, so they can be created by
+// name with anyllm.NewProvider. Import it for its side effects:
+//
+//	import _ "github.com/mozilla-ai/any-llm-go/providers/all"
+package all
+
+import (
+	_ "github.com/mozilla-ai/any-llm-go/providers/anthropic"
+	_ "github.com/mozilla-ai/any-llm-go/providers/cohere"
+	_ "github.com/mozilla-ai/any-llm-go/providers/deepseek"
+	_ "github.com/mozilla-ai/any-llm-go/providers/gateway"
+	_ "github.com/mozilla-ai/any-llm-go/providers/gemini"
+	_ "github.com/mozilla-ai/any-llm-go/providers/groq"
+	_ "github.com/mozilla-ai/any-llm-go/providers/llamacpp"
+	_ "github.com/mozilla-ai/any-llm-go/providers/llamafile"
+	_ "github.com/mozilla-ai/any-llm-go/providers/mistral"
+	_ "github.com/mozilla-ai/any-llm-go/providers/mlx"
+	_ "github.com/mozilla-ai/any-llm-go/providers/ollama"
+	_ "github.com/mozilla-ai/any-llm-go/providers/openai"
+	_ "github.com/mozilla-ai/any-llm-go/providers/openrouter"
+	_ "github.com/mozilla-ai/any-llm-go/providers/platform"
+	_ "github.com/mozilla-ai/any-llm-go/providers/tgi"
+)
@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/deadline"
+	"github.com/mozilla-ai/any-llm-go/internal/ratelimit"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
@@ -30,6 +36,31 @@ const (
 	blockTypeToolUse  = "tool_use"
 )
 
+// Content part types.
+const (
+	contentTypeFile     = "file"
+	contentTypeImageURL = "image_url"
+	contentTypeText     = "text"
+)
+
+// Files API constants. File sources are only accepted with the beta header.
+const (
+	betaFilesAPI        = "files-api-2025-04-14"
+	documentSourceFile  = "file"
+	headerAnthropicBeta = "anthropic-beta"
+)
+
+// Response header carrying Anthropic's request ID.
+const headerRequestID = "request-id"
+
+// maxTemperature is the highest temperature Anthropic accepts.
+const maxTemperature = 1
+
+// Anthropic tool choice modes.
+const (
+	toolChoiceAny = "any"
+)
+
 // Anthropic delta types.
 const (
 	deltaTypeInputJSON = "input_json_delta"
@@ -39,7 +70,9 @@ const (
 
 // Anthropic error response patterns (checked in raw JSON).
 const (
+	errorPatternBilling       = "billing_error"
 	errorPatternContextLength = "context_length"
+	errorPatternCreditBalance = "credit balance"
 	errorPatternToken         = "token"
 	errorPatternContent       = "content"
 	errorPatternSafety        = "safety"
@@ -57,6 +90,7 @@ const (
 const (
 	stopReasonEndTurn      = "end_turn"
 	stopReasonMaxTokens    = "max_tokens"
+	stopReasonRefusal      = "refusal"
 	stopReasonStopSequence = "stop_sequence"
 	stopReasonToolUse      = "tool_use"
 )
@@ -70,26 +104,42 @@ const (
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
 
+// legacySamplingModelPrefixes match models released before Claude Opus 4.1.
+// They accept temperature and top_p together; later models reject requests that set both.
+var legacySamplingModelPrefixes = []string{
+	"claude-3",
+	"claude-opus-4-0",
+	"claude-opus-4-2",
+	"claude-sonnet-4-0",
+	"claude-sonnet-4-2",
+}
+
 // Provider implements the providers.Provider interface for Anthropic.
 type Provider struct {
 	client *anthropic.Client
 	config *config.Config
 }
 
-// streamState tracks accumulated state during streaming.
+// streamState tracks the state chunks are built from during streaming.
 // Note: Only accessed from a single goroutine, so no synchronization needed.
 type streamState struct {
 	messageID      string
 	model          string
-	content        strings.Builder
-	reasoning      strings.Builder
 	toolCalls      []providers.ToolCall
 	currentToolIdx int
 	inputUsage     int64
+	timings        *streamstats.Recorder
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
 // New creates a new Anthropic provider.
@@ -106,6 +156,8 @@ func New(opts ...config.Option) (*Provider, error) {
 
 	clientOpts := []option.RequestOption{
 		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(cfg.HTTPClient()),
+		option.WithMiddleware(deadline.Middleware),
 	}
 
 	if cfg.BaseURL != "" {
@@ -122,15 +174,19 @@ func New(opts ...config.Option) (*Provider, error) {
 
 // Capabilities returns the provider's capabilities.
 func (p *Provider) Capabilities() providers.Capabilities {
-	return providers.Capabilities{
-		Completion:          true,
-		CompletionStreaming: true,
-		CompletionReasoning: true,
-		CompletionImage:     true,
-		CompletionPDF:       true,
-		Embedding:           false,
-		ListModels:          false,
+	caps := providers.Capabilities{
+		Completion:           true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		CompletionReasoning:  true,
+		CompletionImage:      true,
+		CompletionJSONObject: false,
+		CompletionJSONSchema: false,
+		CompletionPDF:        true,
+		Embedding:            false,
+		ListModels:           false,
 	}
+	return providers.OverrideCapabilities(caps, p.config)
 }
 
 // Completion performs a chat completion request.
@@ -143,16 +199,44 @@ func (p *Provider) Completion(
 		return nil, err
 	}
 
-	resp, err := p.client.Messages.New(ctx, req)
+	var httpResp *http.Response
+	opts := append(requestOptions(ctx, params), option.WithResponseInto(&httpResp))
+
+	resp, err := p.client.Messages.New(ctx, req, opts...)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // The request was cut short by the caller's deadline or cancellation.
+		}
 		return nil, p.ConvertError(err)
 	}
 
-	return convertResponse(resp), nil
+	result := convertResponse(resp)
+	if httpResp != nil {
+		result.RequestID = httpResp.Header.Get(headerRequestID)
+		result.RateLimit = ratelimit.Parse(httpResp.Header, time.Now())
+	}
+
+	return result, nil
 }
 
 // convertParams converts providers.CompletionParams to Anthropic request parameters.
 func (p *Provider) convertParams(params providers.CompletionParams) (anthropic.MessageNewParams, error) {
+	if slices.Contains(params.Modalities, providers.ModalityAudio) {
+		return anthropic.MessageNewParams{}, errors.NewUnsupportedParamError(providerName, "modalities")
+	}
+
+	if err := providers.ValidateSampling(providerName, params, samplingLimits(params.Model)); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	if err := providers.ValidateTools(providerName, params.Tools, providers.SchemaLimits{}); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	if err := providers.ValidateMessages(providerName, params.Messages); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
 	messages, system := convertMessages(params.Messages)
 
 	maxTokens := int64(defaultMaxTokens)
@@ -197,7 +281,11 @@ func (p *Provider) convertParams(params providers.CompletionParams) (anthropic.M
 	}
 
 	if params.ToolChoice != nil {
-		req.ToolChoice = convertToolChoice(params.ToolChoice, params.ParallelToolCalls)
+		toolChoice, err := convertToolChoice(params.ToolChoice, params.ParallelToolCalls)
+		if err != nil {
+			return anthropic.MessageNewParams{}, errors.NewInvalidRequestError(providerName, err)
+		}
+		req.ToolChoice = toolChoice
 	}
 
 	applyThinking(&req, params.ReasoningEffort, maxTokens)
@@ -223,30 +311,60 @@ func (p *Provider) CompletionStream(
 			return
 		}
 
-		stream := p.client.Messages.NewStreaming(ctx, req)
-		state := newStreamState()
+		var httpResp *http.Response
+		opts := append(requestOptions(ctx, params), option.WithResponseInto(&httpResp))
+
+		state := newStreamState(streamstats.New()) // Started before the request, to include its latency.
+		stream := p.client.Messages.NewStreaming(ctx, req, opts...)
+		defer func() { _ = stream.Close() }() // Releases the response body; close error is not actionable.
+
+		var rateLimit *providers.RateLimitState
+		if httpResp != nil {
+			rateLimit = ratelimit.Parse(httpResp.Header, time.Now())
+		}
 
 		for stream.Next() {
 			event := stream.Current()
 
+			// Chunks are passed by value so that none escapes to the heap.
+			var chunk providers.ChatCompletionChunk
+			ok := true
 			switch event.Type {
 			case eventMessageStart:
-				chunks <- state.handleMessageStart(event.AsMessageStart())
+				chunk = state.handleMessageStart(event.AsMessageStart())
+				chunk.RateLimit = rateLimit
 
 			case eventContentBlockStart:
-				state.handleContentBlockStart(event.AsContentBlockStart())
+				chunk, ok = state.handleContentBlockStart(event.AsContentBlockStart())
 
 			case eventContentBlockDelta:
-				if chunk := state.handleContentBlockDelta(event.AsContentBlockDelta()); chunk != nil {
-					chunks <- *chunk
-				}
+				chunk, ok = state.handleContentBlockDelta(event.AsContentBlockDelta())
 
 			case eventMessageDelta:
-				chunks <- state.handleMessageDelta(event.AsMessageDelta())
+				chunk = state.handleMessageDelta(event.AsMessageDelta())
+
+			default:
+				// Other events (ping, content_block_stop, message_stop) carry nothing to forward.
+				ok = false
+			}
+
+			if !ok {
+				continue
+			}
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
 			}
 		}
 
 		if err := stream.Err(); err != nil {
+			if ctx.Err() != nil {
+				errs <- ctx.Err() // The read failed because the stream was cancelled.
+				return
+			}
 			errs <- p.ConvertError(err)
 		}
 	}()
@@ -254,20 +372,39 @@ func (p *Provider) CompletionStream(
 	return chunks, errs
 }
 
+// DryRun returns the body Completion would send for params, without sending it.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(_ context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	req, err := p.convertParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return providerName
 }
 
-// newStreamState creates a new stream state with default values.
-func newStreamState() *streamState {
+// newStreamState creates a new stream state with default values, recording
+// chunk timings in timings.
+func newStreamState(timings *streamstats.Recorder) *streamState {
 	return &streamState{
 		currentToolIdx: -1,
+		timings:        timings,
 	}
 }
 
-// chunk creates a ChatCompletionChunk with the given delta.
+// chunk creates a ChatCompletionChunk with the given delta and records it in the stream timings.
 func (s *streamState) chunk(delta providers.ChunkDelta) providers.ChatCompletionChunk {
+	s.timings.Observe()
 	return providers.ChatCompletionChunk{
 		ID:     s.messageID,
 		Object: "chat.completion.chunk",
@@ -279,25 +416,30 @@ func (s *streamState) chunk(delta providers.ChunkDelta) providers.ChatCompletion
 	}
 }
 
-// handleContentBlockDelta processes a content_block_delta event and returns a chunk if applicable.
-func (s *streamState) handleContentBlockDelta(event anthropic.ContentBlockDeltaEvent) *providers.ChatCompletionChunk {
+// handleContentBlockDelta processes a content_block_delta event and returns a chunk, reporting
+// false if the event carries nothing to forward.
+func (s *streamState) handleContentBlockDelta(
+	event anthropic.ContentBlockDeltaEvent,
+) (providers.ChatCompletionChunk, bool) {
 	switch event.Delta.Type {
 	case deltaTypeText:
-		return s.handleTextDelta(event.Delta.Text)
+		return s.handleTextDelta(event.Delta.Text), true
 	case deltaTypeThinking:
-		return s.handleThinkingDelta(event.Delta.Thinking)
+		return s.handleThinkingDelta(event.Delta.Thinking), true
 	case deltaTypeInputJSON:
 		return s.handleInputJSONDelta(event.Delta.PartialJSON)
 	default:
-		return nil
+		return providers.ChatCompletionChunk{}, false
 	}
 }
 
-// handleContentBlockStart processes a content_block_start event.
-func (s *streamState) handleContentBlockStart(event anthropic.ContentBlockStartEvent) {
+// handleContentBlockStart processes a content_block_start event and returns a chunk, reporting
+// false if the event carries nothing to forward.
+// A tool use block starts a tool call, whose arguments follow in input JSON deltas.
+func (s *streamState) handleContentBlockStart(
+	event anthropic.ContentBlockStartEvent,
+) (providers.ChatCompletionChunk, bool) {
 	switch event.ContentBlock.Type {
-	case blockTypeThinking:
-		// Reasoning block started - no action needed.
 	case blockTypeToolUse:
 		s.currentToolIdx++
 		// TODO: Extract to newToolCallFromBlock() if this pattern is needed elsewhere.
@@ -309,20 +451,27 @@ func (s *streamState) handleContentBlockStart(event anthropic.ContentBlockStartE
 			},
 		}
 		s.toolCalls = append(s.toolCalls, tc)
+
+		return s.chunk(providers.ChunkDelta{ToolCalls: []providers.ToolCall{tc}}), true
+	default:
+		// Text and thinking blocks arrive in their deltas.
+		return providers.ChatCompletionChunk{}, false
 	}
 }
 
-// handleInputJSONDelta processes a tool input JSON delta and returns a chunk if applicable.
-func (s *streamState) handleInputJSONDelta(partialJSON string) *providers.ChatCompletionChunk {
-	if s.currentToolIdx < 0 || s.currentToolIdx >= len(s.toolCalls) {
-		return nil
+// handleInputJSONDelta processes a tool input JSON delta and returns a chunk, reporting false
+// if there is no tool call to extend or nothing to extend it with.
+// The chunk carries only the new fragment of the arguments, without the call's ID, so
+// that it extends the call started by handleContentBlockStart.
+func (s *streamState) handleInputJSONDelta(partialJSON string) (providers.ChatCompletionChunk, bool) {
+	if s.currentToolIdx < 0 || s.currentToolIdx >= len(s.toolCalls) || partialJSON == "" {
+		return providers.ChatCompletionChunk{}, false
 	}
 
 	s.toolCalls[s.currentToolIdx].Function.Arguments += partialJSON
-	chunk := s.chunk(providers.ChunkDelta{
-		ToolCalls: []providers.ToolCall{s.toolCalls[s.currentToolIdx]},
-	})
-	return &chunk
+	return s.chunk(providers.ChunkDelta{
+		ToolCalls: []providers.ToolCall{{Function: providers.FunctionCall{Arguments: partialJSON}}},
+	}), true
 }
 
 // handleMessageDelta processes a message_delta event and returns the final chunk.
@@ -330,11 +479,13 @@ func (s *streamState) handleMessageDelta(event anthropic.MessageDeltaEvent) prov
 	finishReason := convertStopReason(string(event.Delta.StopReason))
 	chunk := s.chunk(providers.ChunkDelta{})
 	chunk.Choices[0].FinishReason = finishReason
+	chunk.Choices[0].StopSequence = event.Delta.StopSequence
 	chunk.Usage = &providers.Usage{
 		PromptTokens:     int(s.inputUsage),
 		CompletionTokens: int(event.Usage.OutputTokens),
 		TotalTokens:      int(s.inputUsage + event.Usage.OutputTokens),
 	}
+	chunk.Timings = s.timings.Timings(int(event.Usage.OutputTokens))
 	return chunk
 }
 
@@ -348,19 +499,15 @@ func (s *streamState) handleMessageStart(event anthropic.MessageStartEvent) prov
 }
 
 // handleThinkingDelta processes a thinking delta and returns a chunk.
-func (s *streamState) handleThinkingDelta(thinking string) *providers.ChatCompletionChunk {
-	s.reasoning.WriteString(thinking)
-	chunk := s.chunk(providers.ChunkDelta{
+func (s *streamState) handleThinkingDelta(thinking string) providers.ChatCompletionChunk {
+	return s.chunk(providers.ChunkDelta{
 		Reasoning: &providers.Reasoning{Content: thinking},
 	})
-	return &chunk
 }
 
 // handleTextDelta processes a text delta and returns a chunk.
-func (s *streamState) handleTextDelta(text string) *providers.ChatCompletionChunk {
-	s.content.WriteString(text)
-	chunk := s.chunk(providers.ChunkDelta{Content: text})
-	return &chunk
+func (s *streamState) handleTextDelta(text string) providers.ChatCompletionChunk {
+	return s.chunk(providers.ChunkDelta{Content: text})
 }
 
 // applyThinking configures thinking/reasoning on the request if applicable.
@@ -403,6 +550,20 @@ func convertAssistantMessage(msg providers.Message) *anthropic.MessageParam {
 	return &m
 }
 
+// convertFilePart converts an uploaded file reference to an Anthropic document block.
+// The SDK's document source union has no file variant outside the beta API, so the
+// source is set through extra fields.
+func convertFilePart(fileID string) anthropic.ContentBlockParamUnion {
+	document := anthropic.DocumentBlockParam{}
+	document.SetExtraFields(map[string]any{
+		"source": map[string]any{
+			"type":    documentSourceFile,
+			"file_id": fileID,
+		},
+	})
+	return anthropic.ContentBlockParamUnion{OfDocument: &document}
+}
+
 // convertImagePart converts an image URL to Anthropic format.
 func convertImagePart(img *providers.ImageURL) anthropic.ContentBlockParamUnion {
 	url := img.URL
@@ -508,6 +669,7 @@ func convertResponse(resp *anthropic.Message) *providers.ChatCompletion {
 			Index:        0,
 			Message:      message,
 			FinishReason: finishReason,
+			StopSequence: resp.StopSequence,
 		}},
 		Usage: &providers.Usage{
 			PromptTokens:     int(resp.Usage.InputTokens),
@@ -524,6 +686,8 @@ func convertStopReason(reason string) string {
 		return providers.FinishReasonStop
 	case stopReasonMaxTokens:
 		return providers.FinishReasonLength
+	case stopReasonRefusal:
+		return providers.FinishReasonRefusal
 	case stopReasonToolUse:
 		return providers.FinishReasonToolCalls
 	case stopReasonStopSequence:
@@ -592,52 +756,56 @@ func convertToolCall(tc providers.ToolCall) anthropic.ContentBlockParamUnion {
 }
 
 // convertToolChoice converts providers tool choice to Anthropic format.
-func convertToolChoice(choice any, parallelToolCalls *bool) anthropic.ToolChoiceUnionParam {
-	disableParallel := parallelToolCalls != nil && !*parallelToolCalls
-
-	switch v := choice.(type) {
-	case string:
-		switch v {
-		case "auto":
-			return anthropic.ToolChoiceUnionParam{
-				OfAuto: &anthropic.ToolChoiceAutoParam{
-					DisableParallelToolUse: anthropic.Bool(disableParallel),
-				},
-			}
-		case "none":
-			return anthropic.ToolChoiceUnionParam{
-				OfNone: &anthropic.ToolChoiceNoneParam{},
-			}
-		case "required", "any":
-			return anthropic.ToolChoiceUnionParam{
-				OfAny: &anthropic.ToolChoiceAnyParam{
-					DisableParallelToolUse: anthropic.Bool(disableParallel),
-				},
-			}
-		}
-	case providers.ToolChoice:
-		if v.Function != nil {
-			return anthropic.ToolChoiceUnionParam{
-				OfTool: &anthropic.ToolChoiceToolParam{
-					Name:                   v.Function.Name,
-					DisableParallelToolUse: anthropic.Bool(disableParallel),
-				},
-			}
-		}
+// Anthropic's own "any" mode is accepted as an alias for "required".
+func convertToolChoice(choice any, parallelToolCalls *bool) (anthropic.ToolChoiceUnionParam, error) {
+	if choice == toolChoiceAny {
+		choice = providers.ToolChoiceRequired
+	}
+	if err := providers.ValidateToolChoice(choice); err != nil {
+		return anthropic.ToolChoiceUnionParam{}, err
 	}
 
-	return anthropic.ToolChoiceUnionParam{
-		OfAuto: &anthropic.ToolChoiceAutoParam{
-			DisableParallelToolUse: anthropic.Bool(disableParallel),
-		},
+	disableParallel := parallelToolCalls != nil && !*parallelToolCalls
+
+	if v, ok := choice.(providers.ToolChoice); ok {
+		return anthropic.ToolChoiceUnionParam{
+			OfTool: &anthropic.ToolChoiceToolParam{
+				Name:                   v.Function.Name,
+				DisableParallelToolUse: anthropic.Bool(disableParallel),
+			},
+		}, nil
+	}
+
+	switch choice {
+	case providers.ToolChoiceNone:
+		return anthropic.ToolChoiceUnionParam{
+			OfNone: &anthropic.ToolChoiceNoneParam{},
+		}, nil
+	case providers.ToolChoiceRequired:
+		return anthropic.ToolChoiceUnionParam{
+			OfAny: &anthropic.ToolChoiceAnyParam{
+				DisableParallelToolUse: anthropic.Bool(disableParallel),
+			},
+		}, nil
+	default:
+		return anthropic.ToolChoiceUnionParam{
+			OfAuto: &anthropic.ToolChoiceAutoParam{
+				DisableParallelToolUse: anthropic.Bool(disableParallel),
+			},
+		}, nil
 	}
 }
 
 // convertToolMessage converts a tool result message to Anthropic format.
+// A failed ToolResult sets the block's is_error flag, so its text is sent
+// without the "Error: " prefix of the message content.
 func convertToolMessage(msg providers.Message) *anthropic.MessageParam {
-	m := anthropic.NewUserMessage(
-		anthropic.NewToolResultBlock(msg.ToolCallID, msg.ContentString(), false),
-	)
+	content, isError := msg.ContentString(), false
+	if msg.ToolResult != nil {
+		content, isError = msg.ToolResult.Text(), msg.ToolResult.IsError
+	}
+
+	m := anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, content, isError))
 	return &m
 }
 
@@ -651,9 +819,11 @@ func convertUserMessage(msg providers.Message) *anthropic.MessageParam {
 	content := make([]anthropic.ContentBlockParamUnion, 0)
 	for _, part := range msg.ContentParts() {
 		switch part.Type {
-		case "text":
+		case contentTypeFile:
+			content = append(content, convertFilePart(part.FileID))
+		case contentTypeText:
 			content = append(content, anthropic.NewTextBlock(part.Text))
-		case "image_url":
+		case contentTypeImageURL:
 			if part.ImageURL != nil {
 				content = append(content, convertImagePart(part.ImageURL))
 			}
@@ -663,6 +833,44 @@ func convertUserMessage(msg providers.Message) *anthropic.MessageParam {
 	return &m
 }
 
+// hasFileParts reports whether any message references an uploaded file.
+func hasFileParts(messages []providers.Message) bool {
+	for _, msg := range messages {
+		for _, part := range msg.ContentParts() {
+			if part.Type == contentTypeFile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestOptions returns per-request options required by the params, such
+// as the beta header for referencing uploaded files, and the SDK retry limit
+// set on ctx.
+func requestOptions(ctx context.Context, params providers.CompletionParams) []option.RequestOption {
+	var opts []option.RequestOption
+	if hasFileParts(params.Messages) {
+		opts = append(opts, option.WithHeaderAdd(headerAnthropicBeta, betaFilesAPI))
+	}
+	if retries, ok := providers.MaxProviderRetries(ctx); ok {
+		opts = append(opts, option.WithMaxRetries(retries))
+	}
+	return opts
+}
+
+// samplingLimits returns the sampling limits of an Anthropic model.
+func samplingLimits(model string) providers.SamplingLimits {
+	legacy := slices.ContainsFunc(legacySamplingModelPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(model, prefix)
+	})
+
+	return providers.SamplingLimits{
+		ExclusiveTemperatureTopP: !legacy,
+		MaxTemperature:           maxTemperature,
+	}
+}
+
 // thinkingBudget returns the token budget for the given reasoning effort.
 // Returns the budget and true if the effort level is supported, or 0 and false otherwise.
 func thinkingBudget(effort providers.ReasoningEffort) (int64, bool) {
@@ -721,10 +929,15 @@ func (p *Provider) ConvertError(err error) error {
 		return errors.NewRateLimitError(providerName, err)
 	case 404:
 		return errors.NewModelNotFoundError(providerName, err)
+	case 402:
+		return errors.NewQuotaExceededError(providerName, err)
 	case 400:
-		// Anthropic uses 400 for various client errors.
-		// Check the raw JSON for context length indicators.
+		// Anthropic uses 400 for various client errors, including an exhausted
+		// credit balance. Check the raw JSON for billing and context length indicators.
 		rawJSON := apiErr.RawJSON()
+		if strings.Contains(rawJSON, errorPatternBilling) || strings.Contains(rawJSON, errorPatternCreditBalance) {
+			return errors.NewQuotaExceededError(providerName, err)
+		}
 		if strings.Contains(rawJSON, errorPatternContextLength) || strings.Contains(rawJSON, errorPatternToken) {
 			return errors.NewContextLengthError(providerName, err)
 		}
@@ -737,6 +950,9 @@ func (p *Provider) ConvertError(err error) error {
 		}
 		return errors.NewAuthenticationError(providerName, err)
 	default:
-		return errors.NewProviderError(providerName, err)
+		// Includes 529 (overloaded); keep the status so retry policies can match on it.
+		providerErr := errors.NewProviderError(providerName, err)
+		providerErr.StatusCode = apiErr.StatusCode
+		return providerErr
 	}
 }
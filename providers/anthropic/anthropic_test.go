@@ -5,15 +5,20 @@ import (
 	"encoding/json"
 	stderrors "errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/conformance"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
 	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
@@ -61,7 +66,9 @@ func TestCapabilities(t *testing.T) {
 	require.True(t, caps.CompletionReasoning)
 	require.True(t, caps.CompletionImage)
 	require.True(t, caps.CompletionPDF)
-	require.False(t, caps.Embedding) // Anthropic doesn't support embeddings.
+	require.True(t, caps.CompletionTools)
+	require.False(t, caps.CompletionJSONSchema) // Anthropic has no JSON schema response format.
+	require.False(t, caps.Embedding)            // Anthropic doesn't support embeddings.
 	require.False(t, caps.ListModels)
 }
 
@@ -175,6 +182,50 @@ func TestConvertMessages(t *testing.T) {
 	})
 }
 
+func TestConvertToolMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		msg         providers.Message
+		wantText    string
+		wantIsError bool
+	}{
+		{
+			name:     "plain tool message",
+			msg:      providers.Message{Role: providers.RoleTool, Content: "sunny", ToolCallID: "call_1"},
+			wantText: "sunny",
+		},
+		{
+			name: "failed tool result sets is_error without the text prefix",
+			msg: providers.NewToolResultMessage("call_1", providers.ToolResult{
+				Content: "city not found",
+				IsError: true,
+			}),
+			wantText:    "city not found",
+			wantIsError: true,
+		},
+		{
+			name:     "structured tool result",
+			msg:      providers.NewToolResultMessage("call_1", providers.ToolResult{Data: map[string]int{"celsius": 22}}),
+			wantText: `{"celsius":22}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := convertToolMessage(tc.msg)
+			block := result.Content[0].OfToolResult
+			require.NotNil(t, block)
+			require.Equal(t, "call_1", block.ToolUseID)
+			require.Equal(t, tc.wantIsError, block.IsError.Value)
+			require.Equal(t, tc.wantText, block.Content[0].OfText.Text)
+		})
+	}
+}
+
 func TestConvertImagePart(t *testing.T) {
 	t.Parallel()
 
@@ -195,6 +246,55 @@ func TestConvertImagePart(t *testing.T) {
 	})
 }
 
+func TestConvertFilePart(t *testing.T) {
+	t.Parallel()
+
+	result := convertFilePart("file_abc123")
+	require.NotNil(t, result.OfDocument)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"document","source":{"type":"file","file_id":"file_abc123"}}`, string(data))
+}
+
+func TestRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("adds files beta header for file parts", func(t *testing.T) {
+		t.Parallel()
+
+		params := providers.CompletionParams{
+			Messages: []providers.Message{{
+				Role: providers.RoleUser,
+				Content: []providers.ContentPart{
+					{Type: contentTypeText, Text: "Summarize this report."},
+					{Type: contentTypeFile, FileID: "file_abc123"},
+				},
+			}},
+		}
+		require.Len(t, requestOptions(context.Background(), params), 1)
+	})
+
+	t.Run("returns no options without file parts", func(t *testing.T) {
+		t.Parallel()
+
+		params := providers.CompletionParams{
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		}
+		require.Empty(t, requestOptions(context.Background(), params))
+	})
+
+	t.Run("limits SDK retries when the context sets a limit", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := providers.WithMaxProviderRetries(context.Background(), 0)
+		params := providers.CompletionParams{
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		}
+		require.Len(t, requestOptions(ctx, params), 1)
+	})
+}
+
 func TestConvertStopReason(t *testing.T) {
 	t.Parallel()
 
@@ -213,6 +313,11 @@ func TestConvertStopReason(t *testing.T) {
 			input:    "max_tokens",
 			expected: providers.FinishReasonLength,
 		},
+		{
+			name:     "refusal",
+			input:    "refusal",
+			expected: providers.FinishReasonRefusal,
+		},
 		{
 			name:     "tool_use",
 			input:    "tool_use",
@@ -243,7 +348,7 @@ func TestConvertStopReason(t *testing.T) {
 func TestNewStreamState(t *testing.T) {
 	t.Parallel()
 
-	state := newStreamState()
+	state := newStreamState(streamstats.New())
 	require.NotNil(t, state)
 	require.Equal(t, -1, state.currentToolIdx)
 	require.Empty(t, state.messageID)
@@ -254,70 +359,63 @@ func TestNewStreamState(t *testing.T) {
 func TestStreamStateHandleTextDelta(t *testing.T) {
 	t.Parallel()
 
-	state := newStreamState()
+	state := newStreamState(streamstats.New())
 	state.messageID = "msg_123"
 	state.model = "claude-3"
 
 	chunk := state.handleTextDelta("Hello ")
-	require.NotNil(t, chunk)
 	require.Equal(t, "msg_123", chunk.ID)
 	require.Equal(t, "claude-3", chunk.Model)
 	require.Equal(t, "chat.completion.chunk", chunk.Object)
 	require.Len(t, chunk.Choices, 1)
 	require.Equal(t, "Hello ", chunk.Choices[0].Delta.Content)
 
-	// Verify content is accumulated.
+	// Each chunk carries only its own delta.
 	chunk2 := state.handleTextDelta("world!")
-	require.NotNil(t, chunk2)
 	require.Equal(t, "world!", chunk2.Choices[0].Delta.Content)
-	require.Equal(t, "Hello world!", state.content.String())
 }
 
 func TestStreamStateHandleThinkingDelta(t *testing.T) {
 	t.Parallel()
 
-	state := newStreamState()
+	state := newStreamState(streamstats.New())
 	state.messageID = "msg_123"
 	state.model = "claude-3"
 
 	chunk := state.handleThinkingDelta("Let me think...")
-	require.NotNil(t, chunk)
 	require.Equal(t, "msg_123", chunk.ID)
 	require.Len(t, chunk.Choices, 1)
 	require.NotNil(t, chunk.Choices[0].Delta.Reasoning)
 	require.Equal(t, "Let me think...", chunk.Choices[0].Delta.Reasoning.Content)
-
-	// Verify reasoning is accumulated.
-	require.Equal(t, "Let me think...", state.reasoning.String())
 }
 
 func TestStreamStateHandleInputJSONDelta(t *testing.T) {
 	t.Parallel()
 
-	t.Run("returns nil when no tool calls", func(t *testing.T) {
+	t.Run("returns no chunk when no tool calls", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
-		chunk := state.handleInputJSONDelta(`{"key":`)
-		require.Nil(t, chunk)
+		state := newStreamState(streamstats.New())
+		_, ok := state.handleInputJSONDelta(`{"key":`)
+		require.False(t, ok)
 	})
 
-	t.Run("returns nil when tool index out of bounds", func(t *testing.T) {
+	t.Run("returns no chunk when tool index out of bounds", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(streamstats.New())
 		state.currentToolIdx = 5 // Out of bounds.
 		state.toolCalls = []providers.ToolCall{
 			{ID: "call_1", Type: "function", Function: providers.FunctionCall{Name: "get_weather", Arguments: ""}},
 		}
-		chunk := state.handleInputJSONDelta(`{"key":`)
-		require.Nil(t, chunk)
+		_, ok := state.handleInputJSONDelta(`{"key":`)
+		require.False(t, ok)
 	})
 
 	t.Run("appends to current tool call arguments", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(streamstats.New())
 		state.messageID = "msg_123"
 		state.model = "claude-3"
 		state.currentToolIdx = 0
@@ -325,16 +423,105 @@ func TestStreamStateHandleInputJSONDelta(t *testing.T) {
 			{ID: "call_1", Type: "function", Function: providers.FunctionCall{Name: "get_weather", Arguments: ""}},
 		}
 
-		chunk := state.handleInputJSONDelta(`{"location":`)
-		require.NotNil(t, chunk)
+		_, ok := state.handleInputJSONDelta(`{"location":`)
+		require.True(t, ok)
 		require.Equal(t, `{"location":`, state.toolCalls[0].Function.Arguments)
 
-		chunk2 := state.handleInputJSONDelta(`"Paris"}`)
-		require.NotNil(t, chunk2)
+		chunk2, ok := state.handleInputJSONDelta(`"Paris"}`)
+		require.True(t, ok)
 		require.Equal(t, `{"location":"Paris"}`, state.toolCalls[0].Function.Arguments)
+
+		// Chunks carry only the new fragment, which extends the call.
+		require.Equal(t, []providers.ToolCall{{Function: providers.FunctionCall{Arguments: `"Paris"}`}}},
+			chunk2.Choices[0].Delta.ToolCalls)
+	})
+
+	t.Run("returns no chunk for empty fragments", func(t *testing.T) {
+		t.Parallel()
+
+		state := newStreamState(streamstats.New())
+		state.currentToolIdx = 0
+		state.toolCalls = []providers.ToolCall{{ID: "call_1", Type: "function"}}
+
+		_, ok := state.handleInputJSONDelta("")
+		require.False(t, ok)
+	})
+}
+
+func TestStreamStateHandleContentBlockStart(t *testing.T) {
+	t.Parallel()
+
+	t.Run("starts a tool call", func(t *testing.T) {
+		t.Parallel()
+
+		state := newStreamState(streamstats.New())
+		chunk, ok := state.handleContentBlockStart(anthropic.ContentBlockStartEvent{
+			ContentBlock: anthropic.ContentBlockStartEventContentBlockUnion{
+				Type: blockTypeToolUse,
+				ID:   "toolu_1",
+				Name: "get_weather",
+			},
+		})
+
+		require.True(t, ok)
+		want := providers.ToolCall{ID: "toolu_1", Type: "function", Function: providers.FunctionCall{Name: "get_weather"}}
+		require.Equal(t, []providers.ToolCall{want}, chunk.Choices[0].Delta.ToolCalls)
+		require.Equal(t, []providers.ToolCall{want}, state.toolCalls)
+	})
+
+	t.Run("returns no chunk for text blocks", func(t *testing.T) {
+		t.Parallel()
+
+		state := newStreamState(streamstats.New())
+		_, ok := state.handleContentBlockStart(anthropic.ContentBlockStartEvent{
+			ContentBlock: anthropic.ContentBlockStartEventContentBlockUnion{Type: "text"},
+		})
+
+		require.False(t, ok)
+		require.Empty(t, state.toolCalls)
 	})
 }
 
+func TestStreamStateHandleMessageDelta(t *testing.T) {
+	t.Parallel()
+
+	state := newStreamState(streamstats.New())
+	state.handleMessageStart(anthropic.MessageStartEvent{
+		Message: anthropic.Message{ID: "msg_123", Model: "claude-3", Usage: anthropic.Usage{InputTokens: 5}},
+	})
+	state.handleTextDelta("Hello")
+
+	chunk := state.handleMessageDelta(anthropic.MessageDeltaEvent{
+		Delta: anthropic.MessageDeltaEventDelta{StopReason: anthropic.StopReasonEndTurn},
+		Usage: anthropic.MessageDeltaUsage{OutputTokens: 3},
+	})
+
+	require.Equal(t, providers.FinishReasonStop, chunk.Choices[0].FinishReason)
+	require.Equal(t, 8, chunk.Usage.TotalTokens)
+	require.NotNil(t, chunk.Timings)
+	require.Equal(t, 3, chunk.Timings.ChunkCount)
+	require.Empty(t, chunk.Choices[0].StopSequence)
+
+	chunk = state.handleMessageDelta(anthropic.MessageDeltaEvent{
+		Delta: anthropic.MessageDeltaEventDelta{StopReason: anthropic.StopReasonStopSequence, StopSequence: "\n\nHuman:"},
+	})
+	require.Equal(t, providers.FinishReasonStop, chunk.Choices[0].FinishReason)
+	require.Equal(t, "\n\nHuman:", chunk.Choices[0].StopSequence)
+}
+
+func TestConvertResponseStopSequence(t *testing.T) {
+	t.Parallel()
+
+	resp := convertResponse(&anthropic.Message{
+		Content:      []anthropic.ContentBlockUnion{{Type: "text", Text: "1, 2, 3"}},
+		StopReason:   anthropic.StopReasonStopSequence,
+		StopSequence: "4",
+	})
+	require.Equal(t, "1, 2, 3", resp.Choices[0].Message.ContentString())
+	require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
+	require.Equal(t, "4", resp.Choices[0].StopSequence)
+}
+
 func TestApplyThinking(t *testing.T) {
 	t.Parallel()
 
@@ -680,6 +867,214 @@ func TestConvertTool(t *testing.T) {
 	})
 }
 
+func TestConvertToolChoice(t *testing.T) {
+	t.Parallel()
+
+	t.Run("maps modes", func(t *testing.T) {
+		t.Parallel()
+
+		auto, err := convertToolChoice(providers.ToolChoiceAuto, nil)
+		require.NoError(t, err)
+		require.NotNil(t, auto.OfAuto)
+
+		none, err := convertToolChoice(providers.ToolChoiceNone, nil)
+		require.NoError(t, err)
+		require.NotNil(t, none.OfNone)
+
+		required, err := convertToolChoice(providers.ToolChoiceRequired, nil)
+		require.NoError(t, err)
+		require.NotNil(t, required.OfAny)
+
+		alias, err := convertToolChoice("any", nil)
+		require.NoError(t, err)
+		require.NotNil(t, alias.OfAny)
+	})
+
+	t.Run("forces a specific function", func(t *testing.T) {
+		t.Parallel()
+
+		parallel := false
+		result, err := convertToolChoice(providers.ToolChoiceForFunction("get_weather"), &parallel)
+		require.NoError(t, err)
+		require.NotNil(t, result.OfTool)
+		require.Equal(t, "get_weather", result.OfTool.Name)
+		require.True(t, result.OfTool.DisableParallelToolUse.Value)
+	})
+
+	t.Run("rejects unknown values", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertToolChoice("sometimes", nil)
+		require.Error(t, err)
+	})
+
+	t.Run("convertParams returns invalid request error", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
+
+		_, err = provider.convertParams(providers.CompletionParams{
+			Model:      "claude-test",
+			Messages:   testutil.SimpleMessages(),
+			ToolChoice: providers.ToolChoice{Type: providers.ToolChoiceTypeFunction},
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+func TestCompletionRequestID(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(headerRequestID, "req_123")
+		_, _ = w.Write([]byte(`{
+			"id": "msg_123",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-test",
+			"content": [{"type": "text", "text": "Hi"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    "claude-test",
+		Messages: testutil.SimpleMessages(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "msg_123", resp.ID)
+	require.Equal(t, "req_123", resp.RequestID)
+}
+
+func TestCompletionRateLimit(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(30 * time.Second).UTC().Format(time.RFC3339)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "49")
+		w.Header().Set("anthropic-ratelimit-requests-reset", reset)
+		w.Header().Set("anthropic-ratelimit-tokens-limit", "40000")
+		w.Header().Set("anthropic-ratelimit-tokens-remaining", "38000")
+		_, _ = w.Write([]byte(`{
+			"id": "msg_123",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-test",
+			"content": [{"type": "text", "text": "Hi"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    "claude-test",
+		Messages: testutil.SimpleMessages(),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.RateLimit)
+	require.Equal(t, 50, resp.RateLimit.Requests.Limit)
+	require.Equal(t, 49, resp.RateLimit.Requests.Remaining)
+	require.InDelta(t, 30*time.Second, resp.RateLimit.Requests.Reset, float64(2*time.Second))
+	require.Equal(t, 38000, resp.RateLimit.Tokens.Remaining)
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	body, err := provider.DryRun(context.Background(), providers.CompletionParams{
+		Model:    "claude-test",
+		Messages: testutil.MessagesWithSystem(),
+	})
+	require.NoError(t, err)
+
+	var req map[string]any
+	require.NoError(t, json.Unmarshal(body, &req))
+	require.Equal(t, "claude-test", req["model"])
+	require.NotEmpty(t, req["system"])
+	require.InDelta(t, float64(defaultMaxTokens), req["max_tokens"], 0)
+
+	_, err = provider.DryRun(context.Background(), providers.CompletionParams{
+		Model:      "claude-test",
+		Messages:   testutil.SimpleMessages(),
+		Modalities: []string{providers.ModalityAudio},
+	})
+	require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+}
+
+func TestConvertParamsRejectsAudioOutput(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	_, err = provider.convertParams(providers.CompletionParams{
+		Model:      "claude-test",
+		Messages:   testutil.SimpleMessages(),
+		Modalities: []string{providers.ModalityText, providers.ModalityAudio},
+	})
+	require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+}
+
+func TestSamplingLimits(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		model     string
+		temp      *float64
+		topP      *float64
+		wantParam string
+	}{
+		{name: "temperature within range", model: "claude-sonnet-4-5", temp: ptr(1)},
+		{name: "temperature above one", model: "claude-sonnet-4-5", temp: ptr(1.2), wantParam: "temperature"},
+		{name: "both on a legacy model", model: "claude-3-5-haiku-latest", temp: ptr(0.5), topP: ptr(0.9)},
+		{name: "both on a dated Sonnet 4", model: "claude-sonnet-4-20250514", temp: ptr(0.5), topP: ptr(0.9)},
+		{name: "both on a newer model", model: "claude-opus-4-1", temp: ptr(0.5), topP: ptr(0.9), wantParam: "top_p"},
+	}
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := provider.DryRun(context.Background(), providers.CompletionParams{
+				Model:       tc.model,
+				Messages:    testutil.SimpleMessages(),
+				Temperature: tc.temp,
+				TopP:        tc.topP,
+			})
+			if tc.wantParam == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var invalidErr *errors.InvalidRequestError
+			require.ErrorAs(t, err, &invalidErr)
+			require.Equal(t, tc.wantParam, invalidErr.Param)
+		})
+	}
+}
+
 func TestThinkingBudget(t *testing.T) {
 	t.Parallel()
 
@@ -798,127 +1193,141 @@ func TestToStringSlice(t *testing.T) {
 }
 
 // Integration tests - only run if API key is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	server, released := testutil.NewStallingServer(t, "text/event-stream", strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"id":"msg_123","type":"message","role":"assistant",` +
+			`"model":"claude-test","content":[],"usage":{"input_tokens":1,"output_tokens":0}}}`,
+		"",
+		"event: content_block_start",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		"",
+		"",
+	}, "\n"))
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "claude-test",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
 
-func TestIntegrationCompletion(t *testing.T) {
+func TestCompletionDeadline(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
+	server, requests := testutil.NewHangingServer(t)
 
-	provider, err := New()
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
 	require.NoError(t, err)
 
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: testutil.SimpleMessages(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
 
-	require.NotEmpty(t, resp.ID)
-	require.Equal(t, "chat.completion", resp.Object)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
-	require.Equal(t, providers.RoleAssistant, resp.Choices[0].Message.Role)
-	require.NotNil(t, resp.Usage)
-	require.Greater(t, resp.Usage.TotalTokens, 0)
+	start := time.Now()
+	_, err = provider.Completion(ctx, providers.CompletionParams{
+		Model:    "claude-test",
+		Messages: testutil.SimpleMessages(),
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	require.Len(t, requests, 1, "the SDK must not retry once the deadline has passed")
 }
 
-func TestIntegrationCompletionWithSystemMessage(t *testing.T) {
+func TestCompletionRetriesWithinDeadline(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(529)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
 
-	provider, err := New()
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
 	require.NoError(t, err)
 
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: testutil.MessagesWithSystem(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
+	// The SDK waits at least 375ms before its first retry, past the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
 
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
+	_, err = provider.Completion(ctx, providers.CompletionParams{
+		Model:    "claude-test",
+		Messages: testutil.SimpleMessages(),
+	})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, context.DeadlineExceeded, "the provider's error should be returned before the deadline")
+	require.Equal(t, int32(1), attempts.Load())
 }
 
-func TestIntegrationCompletionStream(t *testing.T) {
+func TestMaxProviderRetries(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: testutil.SimpleMessages(),
-		Stream:   true,
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		attempts int32
+	}{
+		{name: "SDK default", ctx: context.Background(), attempts: 3},
+		{name: "no retries", ctx: providers.WithMaxProviderRetries(context.Background(), 0), attempts: 1},
+		{name: "one retry", ctx: providers.WithMaxProviderRetries(context.Background(), 1), attempts: 2},
 	}
 
-	chunks, errs := provider.CompletionStream(ctx, params)
-
-	var content strings.Builder
-	chunkCount := 0
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-	for chunk := range chunks {
-		chunkCount++
-		require.Equal(t, "chat.completion.chunk", chunk.Object)
-		if len(chunk.Choices) > 0 {
-			content.WriteString(chunk.Choices[0].Delta.Content)
-		}
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				attempts.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After-Ms", "1")
+				w.WriteHeader(529)
+				_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)) // Write error surfaces in the client.
+			}))
+			t.Cleanup(server.Close)
+
+			provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+			require.NoError(t, err)
+
+			_, err = provider.Completion(tc.ctx, providers.CompletionParams{
+				Model:    "claude-test",
+				Messages: testutil.SimpleMessages(),
+			})
+			require.Error(t, err)
+			require.Equal(t, tc.attempts, attempts.Load())
+		})
 	}
+}
 
-	err = <-errs
-	require.NoError(t, err)
+func TestStreamParity(t *testing.T) {
+	t.Parallel()
 
-	require.Greater(t, chunkCount, 0)
-	require.NotEmpty(t, content.String())
+	testutil.VerifyStreamParity(t, providerName, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
-func TestIntegrationCompletionWithTools(t *testing.T) {
+func TestIntegrationConformance(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey("anthropic") {
+	if testutil.SkipIfNoAPIKey(providerName) {
 		t.Skip("ANTHROPIC_API_KEY not set")
 	}
 
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:      testutil.TestModel("anthropic"),
-		Messages:   testutil.ToolCallMessages(),
-		Tools:      []providers.Tool{testutil.WeatherTool()},
-		ToolChoice: "auto",
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
-
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-
-	// The model should call the weather tool.
-	if len(resp.Choices[0].Message.ToolCalls) > 0 {
-		tc := resp.Choices[0].Message.ToolCalls[0]
-		require.Equal(t, "get_weather", tc.Function.Name)
-		require.Contains(t, strings.ToLower(tc.Function.Arguments), "paris")
-		require.Equal(t, providers.FinishReasonToolCalls, resp.Choices[0].FinishReason)
-	}
+	conformance.Run(t, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	}, testutil.TestModel(providerName))
 }
 
 func TestIntegrationCompletionWithToolsParallelDisabled(t *testing.T) {
@@ -950,170 +1359,6 @@ func TestIntegrationCompletionWithToolsParallelDisabled(t *testing.T) {
 	require.Len(t, resp.Choices, 1)
 }
 
-func TestIntegrationAgentLoop(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	tools := []providers.Tool{testutil.WeatherTool()}
-
-	// Step 1: Send initial message asking about weather.
-	messages := []providers.Message{
-		{Role: providers.RoleUser, Content: "What is the weather in Paris? Use the get_weather tool."},
-	}
-
-	resp, err := provider.Completion(ctx, providers.CompletionParams{
-		Model:      testutil.TestModel("anthropic"),
-		Messages:   messages,
-		Tools:      tools,
-		ToolChoice: "auto",
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Step 2: Verify the model called the tool.
-	require.NotEmpty(t, resp.Choices[0].Message.ToolCalls, "expected model to call get_weather tool")
-	require.Equal(t, providers.FinishReasonToolCalls, resp.Choices[0].FinishReason)
-
-	tc := resp.Choices[0].Message.ToolCalls[0]
-	require.Equal(t, "get_weather", tc.Function.Name)
-	require.NotEmpty(t, tc.ID)
-
-	// Step 3: Parse the arguments - this verifies parameters were sent correctly.
-	var args struct {
-		Location string `json:"location"`
-	}
-	err = json.Unmarshal([]byte(tc.Function.Arguments), &args)
-	require.NoError(t, err, "tool arguments should be valid JSON")
-	require.NotEmpty(t, args.Location, "location argument should be present")
-	require.Contains(t, strings.ToLower(args.Location), "paris")
-
-	// Step 4: Add assistant message with tool call and tool result.
-	messages = append(messages, resp.Choices[0].Message)
-	messages = append(messages, providers.Message{
-		Role:       providers.RoleTool,
-		Content:    testutil.MockWeatherResult(t, args.Location),
-		ToolCallID: tc.ID,
-	})
-
-	// Step 5: Continue conversation with tool result.
-	resp, err = provider.Completion(ctx, providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: messages,
-		Tools:    tools,
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Step 6: Verify the model produced a final response.
-	require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok, "expected string content in final response")
-	require.NotEmpty(t, contentStr)
-}
-
-func TestIntegrationAgentLoopMultipleParams(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	tools := []providers.Tool{testutil.NewTestCalculatorTool(t)}
-
-	// Ask the model to use the calculator with specific values.
-	messages := []providers.Message{
-		{Role: providers.RoleUser, Content: "Use the calculate tool to add 15 and 27 together."},
-	}
-
-	resp, err := provider.Completion(ctx, providers.CompletionParams{
-		Model:      testutil.TestModel("anthropic"),
-		Messages:   messages,
-		Tools:      tools,
-		ToolChoice: "auto",
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Verify the model called the tool with correct parameters.
-	require.NotEmpty(t, resp.Choices[0].Message.ToolCalls, "expected model to call calculate tool")
-
-	tc := resp.Choices[0].Message.ToolCalls[0]
-	require.Equal(t, "calculate", tc.Function.Name)
-
-	// Parse and verify all required parameters are present.
-	var args struct {
-		A         float64 `json:"a"`
-		B         float64 `json:"b"`
-		Operation string  `json:"operation"`
-	}
-	err = json.Unmarshal([]byte(tc.Function.Arguments), &args)
-	require.NoError(t, err, "tool arguments should be valid JSON")
-
-	// Verify the parameters - this catches "wrong order" bugs.
-	require.Equal(t, 15.0, args.A, "first operand should be 15")
-	require.Equal(t, 27.0, args.B, "second operand should be 27")
-	require.Equal(t, "add", args.Operation, "operation should be 'add'")
-
-	// Complete the agent loop with tool result.
-	messages = append(messages, resp.Choices[0].Message)
-	messages = append(messages, providers.Message{
-		Role:       providers.RoleTool,
-		Content:    testutil.MockCalculatorResult(t, args.A, args.B, args.Operation),
-		ToolCallID: tc.ID,
-	})
-
-	resp, err = provider.Completion(ctx, providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: messages,
-		Tools:    tools,
-	})
-	require.NoError(t, err)
-
-	// Verify final response mentions the result.
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok)
-	require.Contains(t, contentStr, "42")
-}
-
-func TestIntegrationCompletionConversation(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey("anthropic") {
-		t.Skip("ANTHROPIC_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel("anthropic"),
-		Messages: testutil.ConversationMessages(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
-
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-
-	// The model should remember the name "Alice".
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok, "expected string content")
-	require.Contains(t, strings.ToLower(contentStr), "alice")
-}
-
 func TestIntegrationCompletionReasoning(t *testing.T) {
 	t.Parallel()
 
@@ -1239,6 +1484,11 @@ func TestConvertError(t *testing.T) {
 			err:          newTestAPIError(t, 429),
 			wantSentinel: errors.ErrRateLimit,
 		},
+		{
+			name:         "402 status becomes QuotaExceededError",
+			err:          newTestAPIError(t, 402),
+			wantSentinel: errors.ErrQuotaExceeded,
+		},
 		{
 			name:         "404 status becomes ModelNotFoundError",
 			err:          newTestAPIError(t, 404),
@@ -1300,3 +1550,77 @@ func newTestAPIError(t *testing.T, statusCode int) *anthropic.Error {
 		Response:   &http.Response{StatusCode: statusCode},
 	}
 }
+
+func FuzzConvertMessages(f *testing.F) {
+	testutil.AddConversationSeeds(f)
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		messages := testutil.RandomConversation(seed)
+
+		req, err := provider.convertParams(providers.CompletionParams{Model: "claude-test", Messages: messages})
+		require.NoError(t, err)
+
+		// Tool results are paired with calls by ID; their names are not sent.
+		want := testutil.MessageEvents(messages)
+		for i := range want {
+			if want[i].Kind == testutil.EventToolResult {
+				want[i].Name = ""
+			}
+		}
+
+		got := requestEvents(t, req)
+		require.Equal(t, want, got)
+		testutil.RequireToolPairing(t, got)
+
+		_, err = provider.convertParams(providers.CompletionParams{
+			Model:    "claude-test",
+			Messages: testutil.WithUnknownRole(messages, seed),
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+// requestEvents flattens the system prompt and messages of a request into
+// events.
+func requestEvents(t *testing.T, req anthropic.MessageNewParams) []testutil.MessageEvent {
+	t.Helper()
+
+	var events []testutil.MessageEvent
+	for _, block := range req.System {
+		events = append(events, testutil.TextEvent(providers.RoleSystem, block.Text))
+	}
+
+	for _, msg := range req.Messages {
+		role := string(msg.Role)
+		for _, block := range msg.Content {
+			switch {
+			case block.OfText != nil:
+				events = append(events, testutil.TextEvent(role, block.OfText.Text))
+			case block.OfImage != nil:
+				if src := block.OfImage.Source.OfBase64; src != nil {
+					events = append(events, testutil.InlineImageEvent(role, string(src.MediaType), src.Data))
+				} else if src := block.OfImage.Source.OfURL; src != nil {
+					events = append(events, testutil.ImageEvent(role, src.URL))
+				} else {
+					events = append(events, testutil.ImageEvent(role, ""))
+				}
+			case block.OfToolUse != nil:
+				events = append(events, testutil.ToolCallEventOf(t, role, block.OfToolUse.ID, block.OfToolUse.Name,
+					block.OfToolUse.Input))
+			case block.OfToolResult != nil:
+				var content strings.Builder
+				for _, part := range block.OfToolResult.Content {
+					if part.OfText != nil {
+						content.WriteString(part.OfText.Text)
+					}
+				}
+				events = append(events, testutil.ToolResultEvent(block.OfToolResult.ToolUseID, "", content.String()))
+			default:
+			}
+		}
+	}
+	return events
+}
@@ -0,0 +1,1064 @@
+// Package cohere provides a Cohere provider implementation for any-llm.
+//
+// It uses Cohere's native v2 API (/v2/chat and /v2/embed) rather than its
+// OpenAI compatibility layer, so tool plans, thinking and embedding input
+// types are available.
+package cohere
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Provider configuration constants.
+const (
+	defaultBaseURL = "https://api.cohere.com"
+	envAPIKey      = "COHERE_API_KEY"
+	providerName   = "cohere"
+)
+
+// ExtraInputType is the config Extra key for the default embedding input
+// type, set with WithDefaultInputType.
+const ExtraInputType = "input_type"
+
+// Embedding input types. Cohere's v3 and later embedding models require one,
+// and embed queries and the documents searched with them differently.
+const (
+	InputTypeClassification = "classification"
+	InputTypeClustering     = "clustering"
+	InputTypeSearchDocument = "search_document"
+	InputTypeSearchQuery    = "search_query"
+)
+
+// API endpoint paths, relative to the base URL.
+const (
+	pathChat  = "/v2/chat"
+	pathEmbed = "/v2/embed"
+)
+
+// HTTP and server-sent event constants.
+const (
+	authorizationBearer  = "Bearer "
+	contentTypeJSON      = "application/json"
+	headerAuthorization  = "Authorization"
+	headerContentType    = "Content-Type"
+	headerRequestID      = "x-request-id"
+	initialStreamBufSize = 64 * 1024
+	maxStreamLineSize    = 1024 * 1024
+	sseDataPrefix        = "data:"
+	sseDone              = "[DONE]"
+)
+
+// Content part types.
+const (
+	contentTypeImageURL = "image_url"
+	contentTypeText     = "text"
+	contentTypeThinking = "thinking"
+)
+
+// Cohere roles, which match the providers roles.
+const (
+	roleAssistant = "assistant"
+	roleTool      = "tool"
+)
+
+// Cohere finish reasons.
+const (
+	finishComplete     = "COMPLETE"
+	finishError        = "ERROR"
+	finishMaxTokens    = "MAX_TOKENS"
+	finishStopSequence = "STOP_SEQUENCE"
+	finishTimeout      = "TIMEOUT"
+	finishToolCall     = "TOOL_CALL"
+)
+
+// Cohere tool choice modes. Leaving tool_choice unset lets the model decide.
+const (
+	toolChoiceNone     = "NONE"
+	toolChoiceRequired = "REQUIRED"
+)
+
+// Cohere response format types. JSON schemas are sent with the json_object type.
+const (
+	responseFormatJSONObject = "json_object"
+	responseFormatJSONSchema = "json_schema"
+)
+
+// Cohere thinking modes.
+const (
+	thinkingDisabled = "disabled"
+	thinkingEnabled  = "enabled"
+)
+
+// Cohere streaming event types.
+const (
+	eventContentDelta  = "content-delta"
+	eventMessageEnd    = "message-end"
+	eventMessageStart  = "message-start"
+	eventToolCallDelta = "tool-call-delta"
+	eventToolCallStart = "tool-call-start"
+	eventToolPlanDelta = "tool-plan-delta"
+)
+
+// Cohere error message patterns.
+const (
+	errorPatternContextLength = "context length"
+	errorPatternTooManyTokens = "too many tokens"
+)
+
+// embeddingTypeFloat requests float embeddings.
+const embeddingTypeFloat = "float"
+
+// maxTemperature is the highest temperature Cohere accepts.
+const maxTemperature = 1
+
+// Object type constants.
+const (
+	objectChatCompletion      = "chat.completion"
+	objectChatCompletionChunk = "chat.completion.chunk"
+	objectEmbedding           = "embedding"
+	objectList                = "list"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
+	_ providers.EmbeddingProvider  = (*Provider)(nil)
+	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Provider implements the providers.Provider interface for Cohere.
+type Provider struct {
+	apiKey     string
+	baseURL    string
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// apiError is an error response from the Cohere API.
+type apiError struct {
+	Message    string
+	StatusCode int
+}
+
+// billedUnits holds token counts, either billed or as seen by the model.
+type billedUnits struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+// chatContent is a content item of a message.
+type chatContent struct {
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+	Text     string        `json:"text,omitempty"`
+	Thinking string        `json:"thinking,omitempty"`
+	Type     string        `json:"type"`
+}
+
+// chatImageURL is the image of an image_url content item.
+type chatImageURL struct {
+	URL string `json:"url"`
+}
+
+// chatMessage is a message in a /v2/chat request.
+// Content is a string or a []chatContent.
+type chatMessage struct {
+	Content    any            `json:"content,omitempty"`
+	Role       string         `json:"role"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolPlan   string         `json:"tool_plan,omitempty"`
+}
+
+// chatRequest is the request body for /v2/chat.
+type chatRequest struct {
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Messages       []chatMessage   `json:"messages"`
+	Model          string          `json:"model"`
+	P              *float64        `json:"p,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	StopSequences  []string        `json:"stop_sequences,omitempty"`
+	Stream         bool            `json:"stream"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	Thinking       *thinking       `json:"thinking,omitempty"`
+	ToolChoice     string          `json:"tool_choice,omitempty"`
+	Tools          []chatTool      `json:"tools,omitempty"`
+}
+
+// chatResponse is the response body for /v2/chat.
+type chatResponse struct {
+	FinishReason string          `json:"finish_reason"`
+	ID           string          `json:"id"`
+	Message      responseMessage `json:"message"`
+	Usage        *usage          `json:"usage"`
+}
+
+// chatTool is a tool definition.
+type chatTool struct {
+	Function chatToolFunction `json:"function"`
+	Type     string           `json:"type"`
+}
+
+// chatToolCall is a tool call made by the model.
+type chatToolCall struct {
+	Function chatToolCallFunction `json:"function"`
+	ID       string               `json:"id,omitempty"`
+	Type     string               `json:"type,omitempty"`
+}
+
+// chatToolCallFunction is the function called by a tool call.
+type chatToolCallFunction struct {
+	Arguments string `json:"arguments"`
+	Name      string `json:"name,omitempty"`
+}
+
+// chatToolFunction is the function of a tool definition.
+type chatToolFunction struct {
+	Description string         `json:"description,omitempty"`
+	Name        string         `json:"name"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// embedRequest is the request body for /v2/embed.
+type embedRequest struct {
+	EmbeddingTypes  []string `json:"embedding_types"`
+	InputType       string   `json:"input_type"`
+	Model           string   `json:"model"`
+	OutputDimension *int     `json:"output_dimension,omitempty"`
+	Texts           []string `json:"texts"`
+}
+
+// embedResponse is the response body for /v2/embed.
+type embedResponse struct {
+	Embeddings struct {
+		Float [][]float64 `json:"float"`
+	} `json:"embeddings"`
+	Meta struct {
+		BilledUnits *billedUnits `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// errorResponse is the body of a Cohere error response.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// inputTypeKey is the context key for the per-call embedding input type.
+type inputTypeKey struct{}
+
+// responseFormat is the response_format of a /v2/chat request.
+type responseFormat struct {
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+	Type       string         `json:"type"`
+}
+
+// responseMessage is the message of a /v2/chat response.
+type responseMessage struct {
+	Content   []chatContent  `json:"content"`
+	ToolCalls []chatToolCall `json:"tool_calls"`
+	ToolPlan  string         `json:"tool_plan"`
+}
+
+// streamContentDelta is the content of a content-delta event.
+type streamContentDelta struct {
+	Text     string `json:"text"`
+	Thinking string `json:"thinking"`
+}
+
+// streamDelta is the delta of a streaming event. Content and ToolCalls are
+// arrays in message-start and single items in later events, so they are
+// decoded per event type.
+type streamDelta struct {
+	FinishReason string `json:"finish_reason"`
+	Message      struct {
+		Content   json.RawMessage `json:"content"`
+		ToolCalls json.RawMessage `json:"tool_calls"`
+		ToolPlan  string          `json:"tool_plan"`
+	} `json:"message"`
+	Usage *usage `json:"usage"`
+}
+
+// streamEvent is a single server-sent event from a /v2/chat stream.
+type streamEvent struct {
+	Delta streamDelta `json:"delta"`
+	ID    string      `json:"id"`
+	Type  string      `json:"type"`
+}
+
+// streamState tracks the state chunks are built from during streaming.
+// Note: Only accessed from a single goroutine, so no synchronization needed.
+type streamState struct {
+	created int64
+	id      string
+	model   string
+	timings *streamstats.Recorder
+}
+
+// thinking configures thinking for reasoning models.
+type thinking struct {
+	TokenBudget int64  `json:"token_budget,omitempty"`
+	Type        string `json:"type"`
+}
+
+// usage is the token usage of a /v2/chat response.
+type usage struct {
+	BilledUnits *billedUnits `json:"billed_units"`
+	Tokens      *billedUnits `json:"tokens"`
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+// New creates a new Cohere provider.
+func New(opts ...config.Option) (*Provider, error) {
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	apiKey := cfg.ResolveAPIKey(envAPIKey)
+	if apiKey == "" {
+		return nil, errors.NewMissingAPIKeyError(providerName, envAPIKey)
+	}
+
+	baseURL := defaultBaseURL
+	if cfg.BaseURL != "" {
+		baseURL = cfg.BaseURL
+	}
+
+	return &Provider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		config:     cfg,
+		httpClient: cfg.HTTPClient(),
+	}, nil
+}
+
+// WithDefaultInputType sets the embedding input type used when the call does
+// not set one with WithInputType. Without it, embeddings use
+// InputTypeSearchDocument.
+func WithDefaultInputType(inputType string) config.Option {
+	return func(c *config.Config) error {
+		if err := validateInputType(inputType); err != nil {
+			return err
+		}
+		return config.WithExtra(ExtraInputType, inputType)(c)
+	}
+}
+
+// WithInputType returns a context that makes Embedding calls use inputType,
+// such as InputTypeSearchQuery when embedding a query to search documents
+// embedded with InputTypeSearchDocument.
+func WithInputType(ctx context.Context, inputType string) context.Context {
+	return context.WithValue(ctx, inputTypeKey{}, inputType)
+}
+
+// Capabilities returns the provider's capabilities.
+func (p *Provider) Capabilities() providers.Capabilities {
+	caps := providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      true, // Vision models only.
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true,
+		ListModels:           false,
+	}
+	return providers.OverrideCapabilities(caps, p.config)
+}
+
+// Completion performs a chat completion request.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	req, err := convertParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(ctx, pathChat, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, errors.NewProviderError(providerName, fmt.Errorf("decoding %s response: %w", pathChat, err))
+	}
+
+	if chatResp.FinishReason == finishError {
+		err := fmt.Errorf("generation failed with finish reason %s", finishError)
+		return nil, errors.NewProviderError(providerName, err)
+	}
+
+	result := convertResponse(&chatResp, params.Model)
+	result.RequestID = resp.Header.Get(headerRequestID)
+
+	return result, nil
+}
+
+// CompletionStream performs a streaming chat completion request.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		req, err := convertParams(params)
+		if err != nil {
+			errs <- err
+			return
+		}
+		req.Stream = true
+
+		timings := streamstats.New() // Started before the request, to include its latency.
+		resp, err := p.do(ctx, pathChat, req)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+		state := &streamState{
+			created: time.Now().Unix(),
+			model:   params.Model,
+			timings: timings,
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, initialStreamBufSize), maxStreamLineSize)
+
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), sseDataPrefix)
+			data = strings.TrimSpace(data)
+			if !ok || data == sseDone {
+				continue
+			}
+
+			var event streamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				errs <- errors.NewProviderError(providerName, fmt.Errorf("decoding stream event: %w", err))
+				return
+			}
+
+			chunk, ok, err := state.handleEvent(&event)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !ok {
+				continue
+			}
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() != nil {
+				errs <- ctx.Err() // The read failed because the stream was cancelled.
+				return
+			}
+			errs <- errors.NewProviderError(providerName, err)
+		}
+	}()
+
+	return chunks, errs
+}
+
+// ConvertError converts a Cohere API error to a unified error type.
+// Implements providers.ErrorConverter.
+func (p *Provider) ConvertError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *apiError
+	if !stderrors.As(err, &apiErr) {
+		return errors.NewProviderError(providerName, err)
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return errors.NewModelNotFoundError(providerName, err)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		msg := strings.ToLower(apiErr.Message)
+		if strings.Contains(msg, errorPatternContextLength) || strings.Contains(msg, errorPatternTooManyTokens) {
+			return errors.NewContextLengthError(providerName, err)
+		}
+		return errors.NewInvalidRequestError(providerName, err)
+	default:
+		return errors.FromStatusCode(providerName, apiErr.StatusCode, err)
+	}
+}
+
+// DryRun returns the body Completion would send for params, without sending it.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(_ context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	req, err := convertParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
+// Embedding performs an embedding request.
+// The input type comes from WithInputType, then WithDefaultInputType, and
+// defaults to InputTypeSearchDocument.
+func (p *Provider) Embedding(
+	ctx context.Context,
+	params providers.EmbeddingParams,
+) (*providers.EmbeddingResponse, error) {
+	req, err := p.convertEmbeddingParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(ctx, pathEmbed, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+	var embedResp embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, errors.NewProviderError(providerName, fmt.Errorf("decoding %s response: %w", pathEmbed, err))
+	}
+
+	return convertEmbeddingResponse(&embedResp, params.Model), nil
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return providerName
+}
+
+// convertEmbeddingParams converts embedding params to a /v2/embed request.
+func (p *Provider) convertEmbeddingParams(
+	ctx context.Context,
+	params providers.EmbeddingParams,
+) (*embedRequest, error) {
+	var texts []string
+	switch v := params.Input.(type) {
+	case string:
+		texts = []string{v}
+	case []string:
+		texts = v
+	default:
+		return nil, errors.NewInvalidParamError(providerName, "input",
+			fmt.Errorf("expected string or []string, got %T", params.Input))
+	}
+
+	if params.EncodingFormat != "" && params.EncodingFormat != embeddingTypeFloat {
+		return nil, errors.NewUnsupportedParamError(providerName, "encoding_format")
+	}
+
+	inputType := InputTypeSearchDocument
+	if v, ok := p.config.ExtraValue(ExtraInputType); ok {
+		inputType, _ = v.(string) // WithDefaultInputType only stores strings.
+	}
+	if v, ok := ctx.Value(inputTypeKey{}).(string); ok {
+		inputType = v
+	}
+	if err := validateInputType(inputType); err != nil {
+		return nil, errors.NewInvalidParamError(providerName, ExtraInputType, err)
+	}
+
+	return &embedRequest{
+		EmbeddingTypes:  []string{embeddingTypeFloat},
+		InputType:       inputType,
+		Model:           params.Model,
+		OutputDimension: params.Dimensions,
+		Texts:           texts,
+	}, nil
+}
+
+// do sends a POST request to a Cohere endpoint, returning a converted error for
+// failures and non-2xx statuses. The caller must close the response body.
+func (p *Provider) do(ctx context.Context, path string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, err)
+	}
+	req.Header.Set(headerAuthorization, authorizationBearer+p.apiKey)
+	req.Header.Set(headerContentType, contentTypeJSON)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // The request was cut short by the caller's deadline or cancellation.
+		}
+		return nil, errors.NewProviderError(providerName, err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+		respBody, _ := io.ReadAll(resp.Body)     // Best effort; body is only used for the error message.
+		return nil, p.ConvertError(newAPIError(resp.StatusCode, respBody))
+	}
+
+	return resp, nil
+}
+
+// Error implements the error interface.
+func (e *apiError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// chunk creates a ChatCompletionChunk with the given delta and records it in the stream timings.
+func (s *streamState) chunk(delta providers.ChunkDelta) providers.ChatCompletionChunk {
+	s.timings.Observe()
+	return providers.ChatCompletionChunk{
+		ID:      s.id,
+		Object:  objectChatCompletionChunk,
+		Created: s.created,
+		Model:   s.model,
+		Choices: []providers.ChunkChoice{{
+			Index: 0,
+			Delta: delta,
+		}},
+	}
+}
+
+// handleEvent converts a stream event to a chunk, reporting false if the event
+// carries nothing to forward.
+// A tool-call-start event starts a tool call, whose arguments follow in
+// tool-call-delta events as fragments without the call's ID.
+func (s *streamState) handleEvent(event *streamEvent) (providers.ChatCompletionChunk, bool, error) {
+	msg := event.Delta.Message
+
+	switch event.Type {
+	case eventMessageStart:
+		s.id = event.ID
+		return s.chunk(providers.ChunkDelta{Role: providers.RoleAssistant}), true, nil
+
+	case eventContentDelta:
+		var content streamContentDelta
+		if err := json.Unmarshal(msg.Content, &content); err != nil {
+			return providers.ChatCompletionChunk{}, false, errors.NewProviderError(providerName,
+				fmt.Errorf("decoding %s event: %w", event.Type, err))
+		}
+		if content.Thinking != "" {
+			return s.chunk(providers.ChunkDelta{Reasoning: &providers.Reasoning{Content: content.Thinking}}), true, nil
+		}
+		if content.Text == "" {
+			return providers.ChatCompletionChunk{}, false, nil
+		}
+		return s.chunk(providers.ChunkDelta{Content: content.Text}), true, nil
+
+	case eventToolPlanDelta:
+		if msg.ToolPlan == "" {
+			return providers.ChatCompletionChunk{}, false, nil
+		}
+		return s.chunk(providers.ChunkDelta{Reasoning: &providers.Reasoning{Content: msg.ToolPlan}}), true, nil
+
+	case eventToolCallStart, eventToolCallDelta:
+		var call chatToolCall
+		if err := json.Unmarshal(msg.ToolCalls, &call); err != nil {
+			return providers.ChatCompletionChunk{}, false, errors.NewProviderError(providerName,
+				fmt.Errorf("decoding %s event: %w", event.Type, err))
+		}
+		if event.Type == eventToolCallDelta && call.Function.Arguments == "" {
+			return providers.ChatCompletionChunk{}, false, nil
+		}
+		return s.chunk(providers.ChunkDelta{ToolCalls: []providers.ToolCall{convertToolCall(call)}}), true, nil
+
+	case eventMessageEnd:
+		if event.Delta.FinishReason == finishError {
+			return providers.ChatCompletionChunk{}, false, errors.NewProviderError(providerName,
+				fmt.Errorf("generation failed with finish reason %s", finishError))
+		}
+		chunk := s.chunk(providers.ChunkDelta{})
+		chunk.Choices[0].FinishReason = convertFinishReason(event.Delta.FinishReason)
+		chunk.Usage = convertUsage(event.Delta.Usage)
+		completionTokens := 0
+		if chunk.Usage != nil {
+			completionTokens = chunk.Usage.CompletionTokens
+		}
+		chunk.Timings = s.timings.Timings(completionTokens)
+		return chunk, true, nil
+
+	default:
+		// Other events (content-start, content-end, tool-call-end, citations) carry nothing to forward.
+		return providers.ChatCompletionChunk{}, false, nil
+	}
+}
+
+// convertAssistantMessage converts an assistant message to Cohere format.
+// The reasoning of a message with tool calls is sent back as its tool plan.
+func convertAssistantMessage(msg providers.Message) chatMessage {
+	result := chatMessage{Role: roleAssistant}
+	if content := msg.ContentString(); content != "" {
+		result.Content = content
+	}
+
+	if len(msg.ToolCalls) == 0 {
+		return result
+	}
+
+	result.ToolCalls = make([]chatToolCall, 0, len(msg.ToolCalls))
+	for _, tc := range msg.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, chatToolCall{
+			Function: chatToolCallFunction{Arguments: tc.Function.Arguments, Name: tc.Function.Name},
+			ID:       tc.ID,
+			Type:     providers.ToolChoiceTypeFunction,
+		})
+	}
+	if msg.Reasoning != nil {
+		result.ToolPlan = msg.Reasoning.Content
+	}
+
+	return result
+}
+
+// convertEmbeddingResponse converts a /v2/embed response to provider format.
+func convertEmbeddingResponse(resp *embedResponse, model string) *providers.EmbeddingResponse {
+	data := make([]providers.EmbeddingData, 0, len(resp.Embeddings.Float))
+	for i, embedding := range resp.Embeddings.Float {
+		data = append(data, providers.EmbeddingData{
+			Object:    objectEmbedding,
+			Embedding: embedding,
+			Index:     i,
+		})
+	}
+
+	result := &providers.EmbeddingResponse{
+		Object:     objectList,
+		Data:       data,
+		Model:      model,
+		Dimensions: providers.EmbeddingDimensions(data),
+		Provider:   providerName,
+	}
+	if units := resp.Meta.BilledUnits; units != nil {
+		result.Usage = &providers.EmbeddingUsage{
+			PromptTokens: int(units.InputTokens),
+			TotalTokens:  int(units.InputTokens),
+		}
+	}
+
+	return result
+}
+
+// convertFinishReason converts a Cohere finish reason to an OpenAI finish reason.
+// A timed-out generation is cut short like one that hit the token limit.
+func convertFinishReason(reason string) string {
+	switch reason {
+	case finishComplete, finishStopSequence:
+		return providers.FinishReasonStop
+	case finishMaxTokens, finishTimeout:
+		return providers.FinishReasonLength
+	case finishToolCall:
+		return providers.FinishReasonToolCalls
+	default:
+		return providers.FinishReasonStop
+	}
+}
+
+// convertMessages converts providers messages to Cohere format.
+func convertMessages(messages []providers.Message) ([]chatMessage, error) {
+	result := make([]chatMessage, 0, len(messages))
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleAssistant:
+			result = append(result, convertAssistantMessage(msg))
+		case providers.RoleTool:
+			result = append(result, chatMessage{
+				Content:    msg.ContentString(),
+				Role:       roleTool,
+				ToolCallID: msg.ToolCallID,
+			})
+		case providers.RoleUser:
+			converted, err := convertUserMessage(msg)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, converted)
+		default:
+			result = append(result, chatMessage{Content: msg.ContentString(), Role: msg.Role})
+		}
+	}
+	return result, nil
+}
+
+// convertParams converts completion params to a /v2/chat request.
+func convertParams(params providers.CompletionParams) (*chatRequest, error) {
+	if len(params.Messages) == 0 {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("at least one message is required"))
+	}
+
+	limits := providers.SamplingLimits{MaxTemperature: maxTemperature}
+	if err := providers.ValidateSampling(providerName, params, limits); err != nil {
+		return nil, err
+	}
+
+	if err := providers.ValidateTools(providerName, params.Tools, providers.SchemaLimits{}); err != nil {
+		return nil, err
+	}
+
+	if err := providers.ValidateMessages(providerName, params.Messages); err != nil {
+		return nil, err
+	}
+
+	messages, err := convertMessages(params.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &chatRequest{
+		MaxTokens:     params.MaxTokens,
+		Messages:      messages,
+		Model:         params.Model,
+		P:             params.TopP,
+		Seed:          params.Seed,
+		StopSequences: params.Stop,
+		Temperature:   params.Temperature,
+		Thinking:      convertReasoningEffort(params.ReasoningEffort),
+	}
+
+	tools := params.Tools
+	if params.ToolChoice != nil {
+		if err := providers.ValidateToolChoice(params.ToolChoice); err != nil {
+			return nil, errors.NewInvalidRequestError(providerName, err)
+		}
+		tools, req.ToolChoice, err = convertToolChoice(params.ToolChoice, params.Tools)
+		if err != nil {
+			return nil, errors.NewInvalidRequestError(providerName, err)
+		}
+	}
+
+	for _, tool := range tools {
+		req.Tools = append(req.Tools, chatTool{
+			Function: chatToolFunction{
+				Description: tool.Function.Description,
+				Name:        tool.Function.Name,
+				Parameters:  tool.Function.Parameters,
+			},
+			Type: providers.ToolChoiceTypeFunction,
+		})
+	}
+
+	if rf := params.ResponseFormat; rf != nil {
+		switch rf.Type {
+		case responseFormatJSONObject:
+			req.ResponseFormat = &responseFormat{Type: responseFormatJSONObject}
+		case responseFormatJSONSchema:
+			req.ResponseFormat = &responseFormat{Type: responseFormatJSONObject}
+			if rf.JSONSchema != nil {
+				req.ResponseFormat.JSONSchema = rf.JSONSchema.Schema
+			}
+		}
+	}
+
+	return req, nil
+}
+
+// convertReasoningEffort converts a reasoning effort to Cohere's thinking
+// settings. Auto enables thinking without a budget.
+func convertReasoningEffort(effort providers.ReasoningEffort) *thinking {
+	switch effort {
+	case providers.ReasoningEffortNone:
+		return &thinking{Type: thinkingDisabled}
+	case providers.ReasoningEffortAuto:
+		return &thinking{Type: thinkingEnabled}
+	case providers.ReasoningEffortLow:
+		return &thinking{TokenBudget: 1024, Type: thinkingEnabled}
+	case providers.ReasoningEffortMedium:
+		return &thinking{TokenBudget: 4096, Type: thinkingEnabled}
+	case providers.ReasoningEffortHigh:
+		return &thinking{TokenBudget: 16384, Type: thinkingEnabled}
+	default:
+		return nil
+	}
+}
+
+// convertResponse converts a /v2/chat response to provider format.
+// Thinking, or else the tool plan, becomes the message's reasoning.
+func convertResponse(resp *chatResponse, model string) *providers.ChatCompletion {
+	var content, thought strings.Builder
+	for _, item := range resp.Message.Content {
+		switch item.Type {
+		case contentTypeText:
+			content.WriteString(item.Text)
+		case contentTypeThinking:
+			thought.WriteString(item.Thinking)
+		}
+	}
+
+	message := providers.Message{
+		Role:    providers.RoleAssistant,
+		Content: content.String(),
+	}
+	if thought.Len() == 0 {
+		thought.WriteString(resp.Message.ToolPlan)
+	}
+	if thought.Len() > 0 {
+		message.Reasoning = &providers.Reasoning{Content: thought.String()}
+	}
+	for _, tc := range resp.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, convertToolCall(tc))
+	}
+
+	return &providers.ChatCompletion{
+		ID:      resp.ID,
+		Object:  objectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []providers.Choice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: convertFinishReason(resp.FinishReason),
+		}},
+		Usage: convertUsage(resp.Usage),
+	}
+}
+
+// convertToolCall converts a Cohere tool call to provider format.
+// Calls without an ID are argument fragments that extend the previous call.
+func convertToolCall(tc chatToolCall) providers.ToolCall {
+	call := providers.ToolCall{
+		ID: tc.ID,
+		Function: providers.FunctionCall{
+			Arguments: tc.Function.Arguments,
+			Name:      tc.Function.Name,
+		},
+	}
+	if tc.ID != "" {
+		call.Type = providers.ToolChoiceTypeFunction
+	}
+	return call
+}
+
+// convertToolChoice converts a tool choice to Cohere's tool_choice, returning
+// the tools to send. Cohere cannot force a specific tool, so a specific choice
+// sends only that tool and requires a call.
+func convertToolChoice(choice any, tools []providers.Tool) ([]providers.Tool, string, error) {
+	if v, ok := choice.(providers.ToolChoice); ok {
+		for _, tool := range tools {
+			if tool.Function.Name == v.Function.Name {
+				return []providers.Tool{tool}, toolChoiceRequired, nil
+			}
+		}
+		return nil, "", fmt.Errorf("tool choice %q is not one of the tools", v.Function.Name)
+	}
+
+	switch choice {
+	case providers.ToolChoiceNone:
+		return tools, toolChoiceNone, nil
+	case providers.ToolChoiceRequired:
+		return tools, toolChoiceRequired, nil
+	default:
+		return tools, "", nil
+	}
+}
+
+// convertUsage converts Cohere usage to provider format, preferring the tokens
+// the model saw over the billed units.
+func convertUsage(u *usage) *providers.Usage {
+	if u == nil {
+		return nil
+	}
+
+	tokens := u.Tokens
+	if tokens == nil {
+		tokens = u.BilledUnits
+	}
+	if tokens == nil {
+		return nil
+	}
+
+	return &providers.Usage{
+		PromptTokens:     int(tokens.InputTokens),
+		CompletionTokens: int(tokens.OutputTokens),
+		TotalTokens:      int(tokens.InputTokens + tokens.OutputTokens),
+	}
+}
+
+// convertUserMessage converts a user message to Cohere format.
+func convertUserMessage(msg providers.Message) (chatMessage, error) {
+	if !msg.IsMultiModal() {
+		return chatMessage{Content: msg.ContentString(), Role: providers.RoleUser}, nil
+	}
+
+	content := make([]chatContent, 0, len(msg.ContentParts()))
+	for _, part := range msg.ContentParts() {
+		switch part.Type {
+		case contentTypeText:
+			content = append(content, chatContent{Text: part.Text, Type: contentTypeText})
+		case contentTypeImageURL:
+			if part.ImageURL != nil {
+				content = append(content, chatContent{
+					ImageURL: &chatImageURL{URL: part.ImageURL.URL},
+					Type:     contentTypeImageURL,
+				})
+			}
+		default:
+			return chatMessage{}, errors.NewUnsupportedParamError(providerName, "content part type "+part.Type)
+		}
+	}
+
+	return chatMessage{Content: content, Role: providers.RoleUser}, nil
+}
+
+// newAPIError creates an apiError from an error response, using the raw body
+// as the message when it is not a Cohere error object.
+func newAPIError(statusCode int, body []byte) *apiError {
+	var resp errorResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Message == "" {
+		resp.Message = string(bytes.TrimSpace(body))
+	}
+	return &apiError{Message: resp.Message, StatusCode: statusCode}
+}
+
+// validateInputType reports an error if inputType is not a Cohere embedding input type.
+func validateInputType(inputType string) error {
+	switch inputType {
+	case InputTypeClassification, InputTypeClustering, InputTypeSearchDocument, InputTypeSearchQuery:
+		return nil
+	default:
+		return fmt.Errorf("unknown input type %q", inputType)
+	}
+}
@@ -0,0 +1,670 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/conformance"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testStream is a /v2/chat stream with a tool plan and a tool call.
+const testStream = `event: message-start
+data: {"id":"msg-1","type":"message-start","delta":{"message":{"role":"assistant","content":[],"tool_plan":"","tool_calls":[],"citations":[]}}}
+
+event: tool-plan-delta
+data: {"type":"tool-plan-delta","delta":{"message":{"tool_plan":"I will check the weather."}}}
+
+event: tool-call-start
+data: {"type":"tool-call-start","index":0,"delta":{"message":{"tool_calls":{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}}}}
+
+event: tool-call-delta
+data: {"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"{\"location\":"}}}}}
+
+event: tool-call-delta
+data: {"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"\"Paris\"}"}}}}}
+
+event: tool-call-end
+data: {"type":"tool-call-end","index":0}
+
+event: message-end
+data: {"type":"message-end","delta":{"finish_reason":"TOOL_CALL","usage":{"billed_units":{"input_tokens":10,"output_tokens":5},"tokens":{"input_tokens":80,"output_tokens":20}}}}
+
+data: [DONE]
+`
+
+// newTestServer returns a Cohere server that answers every request with
+// status and body, and a channel receiving each request body.
+func newTestServer(t *testing.T, status int, body string) (*httptest.Server, <-chan []byte) {
+	t.Helper()
+
+	requests := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body) // Test server; a failed read shows up as a bad request body.
+		requests <- reqBody
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("x-request-id", "req_123")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestNew(t *testing.T) {
+	t.Run("creates provider with API key", func(t *testing.T) {
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
+		require.Equal(t, providerName, provider.Name())
+		require.Equal(t, defaultBaseURL, provider.baseURL)
+	})
+
+	t.Run("trims the base URL", func(t *testing.T) {
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL("https://cohere.example.com/"))
+		require.NoError(t, err)
+		require.Equal(t, "https://cohere.example.com", provider.baseURL)
+	})
+
+	t.Run("returns error when API key is missing", func(t *testing.T) {
+		t.Setenv(envAPIKey, "")
+
+		provider, err := New()
+		require.Nil(t, provider)
+
+		var missingKeyErr *errors.MissingAPIKeyError
+		require.ErrorAs(t, err, &missingKeyErr)
+		require.Equal(t, envAPIKey, missingKeyErr.EnvVar)
+	})
+
+	t.Run("rejects unknown input types", func(t *testing.T) {
+		_, err := New(config.WithAPIKey("test-key"), WithDefaultInputType("image"))
+		require.ErrorContains(t, err, `unknown input type "image"`)
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+	require.True(t, caps.Completion)
+	require.True(t, caps.CompletionStreaming)
+	require.True(t, caps.CompletionTools)
+	require.True(t, caps.CompletionReasoning)
+	require.True(t, caps.Embedding)
+	require.False(t, caps.ListModels)
+}
+
+func TestConvertParams(t *testing.T) {
+	t.Parallel()
+
+	weatherTool := providers.Tool{
+		Type: "function",
+		Function: providers.Function{
+			Name:        "get_weather",
+			Description: "Get the weather",
+			Parameters:  map[string]any{"type": "object"},
+		},
+	}
+	timeTool := providers.Tool{Type: "function", Function: providers.Function{Name: "get_time"}}
+
+	t.Run("converts a tool conversation", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := convertParams(providers.CompletionParams{
+			Model: "command-r",
+			Messages: []providers.Message{
+				{Role: providers.RoleSystem, Content: "Be brief."},
+				{Role: providers.RoleUser, Content: "Weather in Paris?"},
+				{
+					Role:      providers.RoleAssistant,
+					Reasoning: &providers.Reasoning{Content: "I will check the weather."},
+					ToolCalls: []providers.ToolCall{{
+						ID:       "call_1",
+						Type:     "function",
+						Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+					}},
+				},
+				{Role: providers.RoleTool, ToolCallID: "call_1", Content: "Sunny"},
+			},
+			Tools: []providers.Tool{weatherTool},
+			Stop:  []string{"END"},
+		})
+		require.NoError(t, err)
+
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"model": "command-r",
+			"stream": false,
+			"stop_sequences": ["END"],
+			"messages": [
+				{"role": "system", "content": "Be brief."},
+				{"role": "user", "content": "Weather in Paris?"},
+				{
+					"role": "assistant",
+					"tool_plan": "I will check the weather.",
+					"tool_calls": [{"id": "call_1", "type": "function",
+						"function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}}]
+				},
+				{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"}
+			],
+			"tools": [{"type": "function", "function": {"name": "get_weather",
+				"description": "Get the weather", "parameters": {"type": "object"}}}]
+		}`, string(body))
+	})
+
+	t.Run("converts images", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := convertParams(providers.CompletionParams{
+			Model: "command-a-vision",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: []providers.ContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/cat.png"}},
+			}}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []chatContent{
+			{Text: "What is this?", Type: contentTypeText},
+			{ImageURL: &chatImageURL{URL: "https://example.com/cat.png"}, Type: contentTypeImageURL},
+		}, req.Messages[0].Content)
+	})
+
+	t.Run("rejects files", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertParams(providers.CompletionParams{
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: []providers.ContentPart{
+				{Type: "file", FileID: "file-1"},
+			}}},
+		})
+		require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+	})
+
+	t.Run("converts the response format", func(t *testing.T) {
+		t.Parallel()
+
+		schema := map[string]any{"type": "object"}
+		req, err := convertParams(providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			ResponseFormat: &providers.ResponseFormat{
+				Type:       "json_schema",
+				JSONSchema: &providers.JSONSchema{Name: "answer", Schema: schema},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, &responseFormat{JSONSchema: schema, Type: responseFormatJSONObject}, req.ResponseFormat)
+	})
+
+	t.Run("rejects temperatures above 1", func(t *testing.T) {
+		t.Parallel()
+
+		temperature := 1.5
+		_, err := convertParams(providers.CompletionParams{Messages: testutil.SimpleMessages(), Temperature: &temperature})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	toolChoices := []struct {
+		name       string
+		choice     any
+		wantChoice string
+		wantTools  []string
+	}{
+		{name: "auto", choice: providers.ToolChoiceAuto, wantTools: []string{"get_weather", "get_time"}},
+		{name: "none", choice: providers.ToolChoiceNone, wantChoice: toolChoiceNone, wantTools: []string{"get_weather", "get_time"}},
+		{
+			name:       "required",
+			choice:     providers.ToolChoiceRequired,
+			wantChoice: toolChoiceRequired,
+			wantTools:  []string{"get_weather", "get_time"},
+		},
+		{
+			name:       "specific tool",
+			choice:     providers.ToolChoice{Type: "function", Function: &providers.ToolChoiceFunction{Name: "get_time"}},
+			wantChoice: toolChoiceRequired,
+			wantTools:  []string{"get_time"},
+		},
+	}
+
+	for _, tc := range toolChoices {
+		t.Run("tool choice "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := convertParams(providers.CompletionParams{
+				Messages:   testutil.SimpleMessages(),
+				Tools:      []providers.Tool{weatherTool, timeTool},
+				ToolChoice: tc.choice,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantChoice, req.ToolChoice)
+
+			names := make([]string, 0, len(req.Tools))
+			for _, tool := range req.Tools {
+				names = append(names, tool.Function.Name)
+			}
+			require.Equal(t, tc.wantTools, names)
+		})
+	}
+
+	t.Run("rejects a choice of an unknown tool", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertParams(providers.CompletionParams{
+			Messages:   testutil.SimpleMessages(),
+			Tools:      []providers.Tool{weatherTool},
+			ToolChoice: providers.ToolChoice{Type: "function", Function: &providers.ToolChoiceFunction{Name: "get_time"}},
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	efforts := []struct {
+		effort providers.ReasoningEffort
+		want   *thinking
+	}{
+		{effort: "", want: nil},
+		{effort: providers.ReasoningEffortNone, want: &thinking{Type: thinkingDisabled}},
+		{effort: providers.ReasoningEffortAuto, want: &thinking{Type: thinkingEnabled}},
+		{effort: providers.ReasoningEffortLow, want: &thinking{TokenBudget: 1024, Type: thinkingEnabled}},
+		{effort: providers.ReasoningEffortHigh, want: &thinking{TokenBudget: 16384, Type: thinkingEnabled}},
+	}
+
+	for _, tc := range efforts {
+		t.Run("reasoning effort "+string(tc.effort), func(t *testing.T) {
+			t.Parallel()
+
+			req, err := convertParams(providers.CompletionParams{Messages: testutil.SimpleMessages(), ReasoningEffort: tc.effort})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, req.Thinking)
+		})
+	}
+}
+
+func TestConvertFinishReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{reason: "COMPLETE", want: providers.FinishReasonStop},
+		{reason: "STOP_SEQUENCE", want: providers.FinishReasonStop},
+		{reason: "MAX_TOKENS", want: providers.FinishReasonLength},
+		{reason: "TIMEOUT", want: providers.FinishReasonLength},
+		{reason: "TOOL_CALL", want: providers.FinishReasonToolCalls},
+		{reason: "", want: providers.FinishReasonStop},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.reason, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, convertFinishReason(tc.reason))
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("converts the response", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newTestServer(t, http.StatusOK, `{
+			"id": "msg-1",
+			"finish_reason": "TOOL_CALL",
+			"message": {
+				"role": "assistant",
+				"tool_plan": "I will check the weather.",
+				"tool_calls": [{"id": "call_1", "type": "function",
+					"function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}}]
+			},
+			"usage": {"billed_units": {"input_tokens": 10, "output_tokens": 5}}
+		}`)
+
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Model:    "command-r",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(<-requests, &req))
+		require.Equal(t, "command-r", req["model"])
+
+		require.Equal(t, "msg-1", resp.ID)
+		require.Equal(t, "req_123", resp.RequestID)
+		require.Equal(t, "command-r", resp.Model)
+		require.Equal(t, providers.FinishReasonToolCalls, resp.Choices[0].FinishReason)
+		require.Equal(t, "I will check the weather.", resp.Choices[0].Message.Reasoning.Content)
+		require.Equal(t, []providers.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+		}}, resp.Choices[0].Message.ToolCalls)
+		require.Equal(t, &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, resp.Usage)
+	})
+
+	t.Run("joins text and reasoning", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newTestServer(t, http.StatusOK, `{
+			"id": "msg-1",
+			"finish_reason": "COMPLETE",
+			"message": {"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "The user greets me."},
+				{"type": "text", "text": "Hello"},
+				{"type": "text", "text": "!"}
+			]}
+		}`)
+
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "Hello!", resp.Choices[0].Message.Content)
+		require.Equal(t, "The user greets me.", resp.Choices[0].Message.Reasoning.Content)
+		require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
+		require.Nil(t, resp.Usage)
+	})
+
+	t.Run("returns an error for failed generations", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newTestServer(t, http.StatusOK, `{"id": "msg-1", "finish_reason": "ERROR", "message": {}}`)
+
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("converts error responses", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newTestServer(t, http.StatusBadRequest,
+			`{"id": "err-1", "message": "too many tokens: total number of tokens in the prompt cannot exceed 128000"}`)
+
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrContextLength)
+		require.ErrorContains(t, err, "cannot exceed 128000")
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	server, requests := newTestServer(t, http.StatusOK, testStream)
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Model:    "command-r",
+		Messages: testutil.SimpleMessages(),
+	})
+
+	var acc providers.Accumulator
+	var received []providers.ChatCompletionChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+		acc.Add(chunk)
+	}
+	require.NoError(t, <-errs)
+	require.Len(t, received, 6)
+	require.Equal(t, "msg-1", received[0].ID)
+
+	var req map[string]any
+	require.NoError(t, json.Unmarshal(<-requests, &req))
+	require.Equal(t, true, req["stream"])
+
+	completion := acc.Completion()
+	require.Equal(t, providers.FinishReasonToolCalls, completion.Choices[0].FinishReason)
+	require.Equal(t, "I will check the weather.", completion.Choices[0].Message.Reasoning.Content)
+	require.Equal(t, []providers.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+	}}, completion.Choices[0].Message.ToolCalls)
+	require.Equal(t, &providers.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}, completion.Usage)
+}
+
+func TestEmbedding(t *testing.T) {
+	t.Parallel()
+
+	const embedResponse = `{
+		"id": "emb-1",
+		"embeddings": {"float": [[0.1, 0.2], [0.3, 0.4]]},
+		"meta": {"billed_units": {"input_tokens": 4}}
+	}`
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		opts          []config.Option
+		wantInputType string
+	}{
+		{
+			name:          "defaults to search documents",
+			ctx:           context.Background(),
+			wantInputType: InputTypeSearchDocument,
+		},
+		{
+			name:          "uses the provider default",
+			ctx:           context.Background(),
+			opts:          []config.Option{WithDefaultInputType(InputTypeClustering)},
+			wantInputType: InputTypeClustering,
+		},
+		{
+			name:          "uses the call's input type",
+			ctx:           WithInputType(context.Background(), InputTypeSearchQuery),
+			opts:          []config.Option{WithDefaultInputType(InputTypeClustering)},
+			wantInputType: InputTypeSearchQuery,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, requests := newTestServer(t, http.StatusOK, embedResponse)
+
+			opts := append([]config.Option{config.WithAPIKey("test-key"), config.WithBaseURL(server.URL)}, tc.opts...)
+			provider, err := New(opts...)
+			require.NoError(t, err)
+
+			resp, err := provider.Embedding(tc.ctx, providers.EmbeddingParams{
+				Model: "embed-english-v3.0",
+				Input: []string{"Hello", "world"},
+			})
+			require.NoError(t, err)
+
+			var req embedRequest
+			require.NoError(t, json.Unmarshal(<-requests, &req))
+			require.Equal(t, tc.wantInputType, req.InputType)
+			require.Equal(t, []string{"Hello", "world"}, req.Texts)
+			require.Equal(t, []string{"float"}, req.EmbeddingTypes)
+
+			require.Len(t, resp.Data, 2)
+			require.Equal(t, []float64{0.3, 0.4}, resp.Data[1].Embedding)
+			require.Equal(t, 1, resp.Data[1].Index)
+			require.Equal(t, 2, resp.Dimensions)
+			require.Equal(t, providerName, resp.Provider)
+			require.Equal(t, &providers.EmbeddingUsage{PromptTokens: 4, TotalTokens: 4}, resp.Usage)
+		})
+	}
+
+	t.Run("rejects unknown input types", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
+
+		ctx := WithInputType(context.Background(), "image")
+		_, err = provider.Embedding(ctx, providers.EmbeddingParams{Model: "embed-english-v3.0", Input: "Hello"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("rejects base64 encoding", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
+
+		_, err = provider.Embedding(context.Background(), providers.EmbeddingParams{
+			Model:          "embed-english-v3.0",
+			Input:          "Hello",
+			EncodingFormat: "base64",
+		})
+		require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+	})
+}
+
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	const eventJSON = `{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hi"}}}}`
+
+	server, released := testutil.NewStallingServer(t, "text/event-stream", strings.Repeat("data: "+eventJSON+"\n\n", 2))
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "command-r",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
+
+func TestStreamParity(t *testing.T) {
+	t.Parallel()
+
+	testutil.VerifyStreamParity(t, providerName, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+func TestIntegrationConformance(t *testing.T) {
+	t.Parallel()
+
+	if testutil.SkipIfNoAPIKey(providerName) {
+		t.Skip("COHERE_API_KEY not set")
+	}
+
+	conformance.Run(t, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	}, testutil.TestModel(providerName))
+}
+
+func TestIntegrationEmbedding(t *testing.T) {
+	t.Parallel()
+
+	if testutil.SkipIfNoAPIKey(providerName) {
+		t.Skip("COHERE_API_KEY not set")
+	}
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	ctx := WithInputType(context.Background(), InputTypeSearchQuery)
+	resp, err := provider.Embedding(ctx, providers.EmbeddingParams{
+		Model: testutil.EmbeddingModel(providerName),
+		Input: "Hello, world!",
+	})
+	require.NoError(t, err)
+
+	require.NotEmpty(t, resp.Data)
+	require.NotEmpty(t, resp.Data[0].Embedding)
+}
+
+func TestConvertError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		err          error
+		wantSentinel error
+	}{
+		{
+			name:         "nil error returns nil",
+			err:          nil,
+			wantSentinel: nil,
+		},
+		{
+			name:         "non-API error becomes ProviderError",
+			err:          stderrors.New("network timeout"),
+			wantSentinel: errors.ErrProvider,
+		},
+		{
+			name:         "401 status becomes AuthenticationError",
+			err:          &apiError{Message: "invalid api token", StatusCode: http.StatusUnauthorized},
+			wantSentinel: errors.ErrAuthentication,
+		},
+		{
+			name:         "404 status becomes ModelNotFoundError",
+			err:          &apiError{Message: "model 'x' not found", StatusCode: http.StatusNotFound},
+			wantSentinel: errors.ErrModelNotFound,
+		},
+		{
+			name:         "400 status becomes InvalidRequestError",
+			err:          &apiError{Message: "invalid request", StatusCode: http.StatusBadRequest},
+			wantSentinel: errors.ErrInvalidRequest,
+		},
+		{
+			name:         "400 status with too many tokens becomes ContextLengthError",
+			err:          &apiError{Message: "too many tokens", StatusCode: http.StatusBadRequest},
+			wantSentinel: errors.ErrContextLength,
+		},
+		{
+			name:         "429 status becomes RateLimitError",
+			err:          &apiError{Message: "rate limited", StatusCode: http.StatusTooManyRequests},
+			wantSentinel: errors.ErrRateLimit,
+		},
+		{
+			name:         "500 status becomes ProviderError",
+			err:          &apiError{Message: "internal error", StatusCode: http.StatusInternalServerError},
+			wantSentinel: errors.ErrProvider,
+		},
+	}
+
+	provider := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := provider.ConvertError(tc.err)
+			if tc.wantSentinel == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantSentinel)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Data URL encoding constants.
+const (
+	contentTypeImageURL = "image_url"
+	dataURLBase64       = ";base64,"
+	dataURLScheme       = "data:"
+
+	// encodeChunkSize is how much raw content is encoded at a time. It is a
+	// multiple of 3, so chunks encode without padding and can be appended.
+	encodeChunkSize = 48 << 10
+)
+
+// encodeBuffers pools the scratch space content is encoded in: a raw chunk
+// followed by room for its encoding.
+var encodeBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, encodeChunkSize+base64.StdEncoding.EncodedLen(encodeChunkSize))
+		return &buf
+	},
+}
+
+// DataURL returns a base64 data URL holding data, such as an image for an
+// ImageURL. mediaType is its MIME type, such as "image/png". The URL is built
+// with a single allocation of its final size, using pooled scratch space, so
+// that encoding large images does not leave intermediate copies behind.
+func DataURL(mediaType string, data []byte) string {
+	scratch := encodeBuffers.Get().(*[]byte)
+	defer encodeBuffers.Put(scratch)
+
+	var b strings.Builder
+	b.Grow(dataURLLen(mediaType, len(data)))
+	writeDataURLPrefix(&b, mediaType)
+
+	for len(data) > 0 {
+		n := min(len(data), encodeChunkSize)
+		encodeChunk(&b, *scratch, data[:n])
+		data = data[n:]
+	}
+
+	return b.String()
+}
+
+// NewImagePart returns an image content part holding data as a data URL.
+func NewImagePart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: contentTypeImageURL, ImageURL: &ImageURL{URL: DataURL(mediaType, data)}}
+}
+
+// ReadDataURL is like DataURL, but reads the content from r until EOF and
+// encodes it as it is read, so the raw content is never held in memory whole.
+// size is the content's length if known, such as a file's size, and is used
+// to allocate the URL once; pass 0 if it is unknown.
+func ReadDataURL(mediaType string, r io.Reader, size int64) (string, error) {
+	scratch := encodeBuffers.Get().(*[]byte)
+	defer encodeBuffers.Put(scratch)
+
+	var b strings.Builder
+	if size > 0 {
+		b.Grow(dataURLLen(mediaType, int(size)))
+	}
+	writeDataURLPrefix(&b, mediaType)
+
+	raw := (*scratch)[:encodeChunkSize]
+	for {
+		n, err := io.ReadFull(r, raw)
+		if n > 0 {
+			encodeChunk(&b, *scratch, raw[:n])
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// dataURLLen returns the length of a data URL of n bytes of content.
+func dataURLLen(mediaType string, n int) int {
+	return len(dataURLScheme) + len(mediaType) + len(dataURLBase64) + base64.StdEncoding.EncodedLen(n)
+}
+
+// encodeChunk base64-encodes raw, at most encodeChunkSize bytes, into the
+// encoding space of scratch and appends it to b.
+func encodeChunk(b *strings.Builder, scratch, raw []byte) {
+	encoded := scratch[encodeChunkSize : encodeChunkSize+base64.StdEncoding.EncodedLen(len(raw))]
+	base64.StdEncoding.Encode(encoded, raw)
+	b.Write(encoded)
+}
+
+// writeDataURLPrefix writes the part of a data URL before the content.
+func writeDataURLPrefix(b *strings.Builder, mediaType string) {
+	b.WriteString(dataURLScheme)
+	b.WriteString(mediaType)
+	b.WriteString(dataURLBase64)
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/base64"
+	stderrors "errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testContent returns n bytes of varied content.
+func testContent(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestDataURL(t *testing.T) {
+	t.Parallel()
+
+	// Sizes around the chunk boundary check that chunks join without padding.
+	for _, n := range []int{0, 1, 2, 3, encodeChunkSize - 1, encodeChunkSize, encodeChunkSize + 1, 3*encodeChunkSize + 2} {
+		data := testContent(n)
+		expected := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+
+		require.Equal(t, expected, DataURL("image/png", data), "size %d", n)
+
+		got, err := ReadDataURL("image/png", bytes.NewReader(data), int64(n))
+		require.NoError(t, err, "size %d", n)
+		require.Equal(t, expected, got, "size %d", n)
+
+		got, err = ReadDataURL("image/png", iotest.HalfReader(bytes.NewReader(data)), 0)
+		require.NoError(t, err, "size %d", n)
+		require.Equal(t, expected, got, "size %d", n)
+	}
+}
+
+func TestReadDataURLError(t *testing.T) {
+	t.Parallel()
+
+	failure := stderrors.New("disk on fire")
+	r := io.MultiReader(bytes.NewReader(testContent(10)), iotest.ErrReader(failure))
+
+	_, err := ReadDataURL("image/png", r, 0)
+	require.ErrorIs(t, err, failure)
+}
+
+func TestNewImagePart(t *testing.T) {
+	t.Parallel()
+
+	part := NewImagePart("image/jpeg", []byte("jpeg"))
+
+	require.Equal(t, contentTypeImageURL, part.Type)
+	require.Equal(t, "data:image/jpeg;base64,anBlZw==", part.ImageURL.URL)
+}
+
+// TestDataURLAllocs is not parallel, since AllocsPerRun cannot be.
+func TestDataURLAllocs(t *testing.T) {
+	data := testContent(1000)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = DataURL("image/png", data) // Only the allocations are measured.
+	})
+	require.Equal(t, 1.0, allocs)
+}
+
+func BenchmarkDataURL(b *testing.B) {
+	data := testContent(4 << 20)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		_ = DataURL("image/png", data) // Only the cost is measured.
+	}
+}
@@ -36,8 +36,10 @@ const (
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
 
@@ -47,16 +49,25 @@ type Provider struct {
 	*openai.CompatibleProvider
 }
 
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new DeepSeek provider.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := openai.NewCompatible(openai.CompatibleConfig{
-		APIKeyEnvVar:   envAPIKey,
-		BaseURLEnvVar:  "",
-		Capabilities:   deepseekCapabilities(),
-		DefaultAPIKey:  "",
-		DefaultBaseURL: defaultBaseURL,
-		Name:           providerName,
-		RequireAPIKey:  true,
+		APIKeyEnvVar:        envAPIKey,
+		BaseURLEnvVar:       "",
+		Capabilities:        deepseekCapabilities(),
+		DefaultAPIKey:       "",
+		DefaultBaseURL:      defaultBaseURL,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    preprocessParams,
+		RequireAPIKey:       true,
 	}, opts...)
 	if err != nil {
 		return nil, err
@@ -65,36 +76,19 @@ func New(opts ...config.Option) (*Provider, error) {
 	return &Provider{CompatibleProvider: base}, nil
 }
 
-// Completion performs a chat completion request.
-// It overrides the base implementation to handle DeepSeek's JSON mode quirks.
-func (p *Provider) Completion(
-	ctx context.Context,
-	params providers.CompletionParams,
-) (*providers.ChatCompletion, error) {
-	params = preprocessParams(params)
-	return p.CompatibleProvider.Completion(ctx, params)
-}
-
-// CompletionStream performs a streaming chat completion request.
-// It overrides the base implementation to handle DeepSeek's JSON mode quirks.
-func (p *Provider) CompletionStream(
-	ctx context.Context,
-	params providers.CompletionParams,
-) (<-chan providers.ChatCompletionChunk, <-chan error) {
-	params = preprocessParams(params)
-	return p.CompatibleProvider.CompletionStream(ctx, params)
-}
-
 // deepseekCapabilities returns the capabilities for the DeepSeek provider.
 func deepseekCapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     false, // DeepSeek doesn't support images.
-		CompletionPDF:       false,
-		CompletionReasoning: true, // DeepSeek R1 supports reasoning.
-		CompletionStreaming: true,
-		Embedding:           false, // DeepSeek doesn't host embedding models.
-		ListModels:          true,
+		Completion:           true,
+		CompletionImage:      false, // DeepSeek doesn't support images.
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true, // Emulated with JSON mode and a schema prompt.
+		CompletionPDF:        false,
+		CompletionReasoning:  true, // DeepSeek R1 supports reasoning.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            false, // DeepSeek doesn't host embedding models.
+		ListModels:           true,
 	}
 }
 
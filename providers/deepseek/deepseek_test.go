@@ -354,6 +354,24 @@ func TestPreprocessMessagesForJSONSchema(t *testing.T) {
 }
 
 // Integration tests - only run if DeepSeek API key is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	const chunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+		`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`
+
+	server, released := testutil.NewStallingServer(t, "text/event-stream", strings.Repeat("data: "+chunkJSON+"\n\n", 2))
+
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "m",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
 
 func TestIntegrationCompletion(t *testing.T) {
 	t.Parallel()
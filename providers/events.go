@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// Stream event kinds. Kinds are added as providers stream new kinds of
+// output, so switch statements over them should have a default case.
+const (
+	EventKindAudio         EventKind = "audio"
+	EventKindContent       EventKind = "content"
+	EventKindContentFilter EventKind = "content_filter"
+	EventKindFinish        EventKind = "finish"
+	EventKindReasoning     EventKind = "reasoning"
+	EventKindRefusal       EventKind = "refusal"
+	EventKindToolCall      EventKind = "tool_call"
+	EventKindToolCallDelta EventKind = "tool_call_delta"
+	EventKindUsage         EventKind = "usage"
+)
+
+// EventKind is the kind of a StreamEvent.
+type EventKind string
+
+// StreamEvent is one typed piece of a stream, such as a fragment of content
+// or a finished tool call, as an alternative to reading the sparse fields of
+// ChunkDelta. Only the fields of its Kind are set.
+type StreamEvent struct {
+	// Audio is a fragment of the audio output, for EventKindAudio. Its Data
+	// is base64-encoded on its own.
+	Audio *Audio `json:"audio,omitempty"`
+
+	// Choice is the index of the choice the event belongs to. Usage events
+	// belong to no choice and report 0.
+	Choice int `json:"choice"`
+
+	// ContentFilterResults are the content filter verdicts on part of the
+	// output, for EventKindContentFilter.
+	ContentFilterResults []ContentFilterResult `json:"content_filter_results,omitempty"`
+
+	// FinishReason is why the choice ended, for EventKindFinish.
+	FinishReason string `json:"finish_reason,omitempty"`
+
+	// Kind is the kind of the event.
+	Kind EventKind `json:"kind"`
+
+	// StopSequence is the stop sequence that ended the choice, if reported,
+	// for EventKindFinish.
+	StopSequence string `json:"stop_sequence,omitempty"`
+
+	// Text is a fragment of text, for EventKindContent, EventKindReasoning
+	// and EventKindRefusal.
+	Text string `json:"text,omitempty"`
+
+	// ToolCall is a fragment of a tool call as it was streamed, for
+	// EventKindToolCallDelta, or the whole call once it is complete, for
+	// EventKindToolCall.
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+
+	// Usage is the usage of the request, for EventKindUsage.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// eventDecoder turns chunks into events. It joins tool call fragments as
+// Accumulator does, to report each call once it is complete.
+type eventDecoder struct {
+	calls map[int]*ToolCall
+}
+
+// decode returns the events of chunk.
+func (d *eventDecoder) decode(chunk ChatCompletionChunk) []StreamEvent {
+	var events []StreamEvent
+	for _, choice := range chunk.Choices {
+		events = d.decodeChoice(events, choice)
+	}
+	if chunk.Usage != nil {
+		events = append(events, StreamEvent{Kind: EventKindUsage, Usage: chunk.Usage})
+	}
+	return events
+}
+
+// decodeChoice appends the events of a choice's delta to events.
+func (d *eventDecoder) decodeChoice(events []StreamEvent, choice ChunkChoice) []StreamEvent {
+	i, delta := choice.Index, choice.Delta
+
+	if delta.Reasoning != nil && delta.Reasoning.Content != "" {
+		events = append(events, StreamEvent{Choice: i, Kind: EventKindReasoning, Text: delta.Reasoning.Content})
+	}
+	if delta.Refusal != "" {
+		events = append(events, StreamEvent{Choice: i, Kind: EventKindRefusal, Text: delta.Refusal})
+	}
+	if delta.Content != "" {
+		events = append(events, StreamEvent{Choice: i, Kind: EventKindContent, Text: delta.Content})
+	}
+	if delta.Audio != nil {
+		audio := *delta.Audio
+		events = append(events, StreamEvent{Audio: &audio, Choice: i, Kind: EventKindAudio})
+	}
+	for _, fragment := range delta.ToolCalls {
+		events = d.decodeToolCall(events, i, fragment)
+	}
+	if len(choice.ContentFilterResults) > 0 {
+		events = append(events, StreamEvent{
+			Choice:               i,
+			ContentFilterResults: slices.Clone(choice.ContentFilterResults),
+			Kind:                 EventKindContentFilter,
+		})
+	}
+	if choice.FinishReason != "" {
+		events = d.finishToolCall(events, i)
+		events = append(events, StreamEvent{
+			Choice:       i,
+			FinishReason: choice.FinishReason,
+			Kind:         EventKindFinish,
+			StopSequence: choice.StopSequence,
+		})
+	}
+	return events
+}
+
+// decodeToolCall appends the events of a tool call fragment to events. A
+// fragment with a new ID finishes the choice's previous call.
+func (d *eventDecoder) decodeToolCall(events []StreamEvent, choice int, fragment ToolCall) []StreamEvent {
+	call, ok := d.calls[choice]
+	if ok && (fragment.ID == "" || fragment.ID == call.ID) {
+		if call.Type == "" {
+			call.Type = fragment.Type
+		}
+		call.Function.Name += fragment.Function.Name
+		call.Function.Arguments += fragment.Function.Arguments
+	} else {
+		events = d.finishToolCall(events, choice)
+		if d.calls == nil {
+			d.calls = make(map[int]*ToolCall)
+		}
+		started := fragment
+		d.calls[choice] = &started
+	}
+
+	delta := fragment
+	return append(events, StreamEvent{Choice: choice, Kind: EventKindToolCallDelta, ToolCall: &delta})
+}
+
+// finishToolCall appends the choice's call being streamed, if any, to events.
+func (d *eventDecoder) finishToolCall(events []StreamEvent, choice int) []StreamEvent {
+	call, ok := d.calls[choice]
+	if !ok {
+		return events
+	}
+
+	delete(d.calls, choice)
+	return append(events, StreamEvent{Choice: choice, Kind: EventKindToolCall, ToolCall: call})
+}
+
+// flush returns the events of the calls still being streamed when the stream
+// ends, in choice order.
+func (d *eventDecoder) flush() []StreamEvent {
+	var events []StreamEvent
+	for _, choice := range slices.Sorted(maps.Keys(d.calls)) {
+		events = d.finishToolCall(events, choice)
+	}
+	return events
+}
+
+// CompletionEvents performs a streaming chat completion request on provider
+// and returns its output as typed events. See StreamEvents.
+func CompletionEvents(
+	ctx context.Context,
+	provider Provider,
+	params CompletionParams,
+) (<-chan StreamEvent, <-chan error) {
+	chunks, errs := provider.CompletionStream(ctx, params)
+	return StreamEvents(ctx, chunks, errs)
+}
+
+// StreamEvents converts a stream of chunks into typed events, in the order
+// the stream carried them. Within a chunk, each choice's reasoning, refusal,
+// content, audio, tool call fragments, content filter results and finish
+// reason are reported in that order, followed by the chunk's usage. A tool
+// call is reported whole, as EventKindToolCall, when the next call of its
+// choice starts, when its choice finishes or when the stream ends without
+// an error.
+//
+// The error channel reports the stream's error, or ctx.Err() if ctx is done
+// before the stream ends.
+func StreamEvents(
+	ctx context.Context,
+	chunks <-chan ChatCompletionChunk,
+	errs <-chan error,
+) (<-chan StreamEvent, <-chan error) {
+	out := make(chan StreamEvent)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		send := func(events []StreamEvent) bool {
+			for _, event := range events {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					outErrs <- ctx.Err()
+					return false
+				}
+			}
+			return true
+		}
+
+		var d eventDecoder
+		for chunk := range chunks {
+			if !send(d.decode(chunk)) {
+				return
+			}
+		}
+
+		// A call cut off by an error is incomplete, so it is not reported.
+		if err := <-errs; err != nil {
+			outErrs <- err
+			return
+		}
+		send(d.flush()) // A failed send has reported ctx.Err().
+	}()
+
+	return out, outErrs
+}
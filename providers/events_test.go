@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStream returns a finished stream of chunks that ends with err.
+func testStream(err error, chunks ...ChatCompletionChunk) (<-chan ChatCompletionChunk, <-chan error) {
+	out := make(chan ChatCompletionChunk, len(chunks))
+	errs := make(chan error, 1)
+	for _, chunk := range chunks {
+		out <- chunk
+	}
+	if err != nil {
+		errs <- err
+	}
+	close(out)
+	close(errs)
+	return out, errs
+}
+
+// collectEvents reads all events of a stream and its error.
+func collectEvents(events <-chan StreamEvent, errs <-chan error) ([]StreamEvent, error) {
+	var all []StreamEvent
+	for event := range events {
+		all = append(all, event)
+	}
+	return all, <-errs
+}
+
+func TestStreamEvents(t *testing.T) {
+	t.Parallel()
+
+	usage := &Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3}
+	weather := func(id string, name string, args string) ToolCall {
+		return ToolCall{ID: id, Function: FunctionCall{Name: name, Arguments: args}}
+	}
+
+	tests := []struct {
+		name    string
+		chunks  []ChatCompletionChunk
+		err     error
+		want    []StreamEvent
+		wantErr string
+	}{
+		{
+			name: "text, finish and usage",
+			chunks: []ChatCompletionChunk{
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{Role: RoleAssistant, Reasoning: &Reasoning{Content: "Hm"}}}}},
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{Content: "Hi"}}}},
+				{
+					Choices: []ChunkChoice{{FinishReason: FinishReasonStop, StopSequence: "END"}},
+					Usage:   usage,
+				},
+			},
+			want: []StreamEvent{
+				{Kind: EventKindReasoning, Text: "Hm"},
+				{Kind: EventKindContent, Text: "Hi"},
+				{FinishReason: FinishReasonStop, Kind: EventKindFinish, StopSequence: "END"},
+				{Kind: EventKindUsage, Usage: usage},
+			},
+		},
+		{
+			name: "refusal, audio and content filter results",
+			chunks: []ChatCompletionChunk{{Choices: []ChunkChoice{{
+				ContentFilterResults: []ContentFilterResult{{Category: "violence", Filtered: true}},
+				Delta:                ChunkDelta{Audio: &Audio{Transcript: "No"}, Refusal: "I can't"},
+				FinishReason:         FinishReasonRefusal,
+			}}}},
+			want: []StreamEvent{
+				{Kind: EventKindRefusal, Text: "I can't"},
+				{Audio: &Audio{Transcript: "No"}, Kind: EventKindAudio},
+				{
+					ContentFilterResults: []ContentFilterResult{{Category: "violence", Filtered: true}},
+					Kind:                 EventKindContentFilter,
+				},
+				{FinishReason: FinishReasonRefusal, Kind: EventKindFinish},
+			},
+		},
+		{
+			name: "tool calls are reported whole when the next one starts and on finish",
+			chunks: []ChatCompletionChunk{
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{ToolCalls: []ToolCall{weather("call_1", "weather", `{"city":`)}}}}},
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{ToolCalls: []ToolCall{weather("", "", `"Paris"}`)}}}}},
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{ToolCalls: []ToolCall{weather("call_2", "time", `{}`)}}}}},
+				{Choices: []ChunkChoice{{FinishReason: FinishReasonToolCalls}}},
+			},
+			want: []StreamEvent{
+				{Kind: EventKindToolCallDelta, ToolCall: &ToolCall{ID: "call_1", Function: FunctionCall{Name: "weather", Arguments: `{"city":`}}},
+				{Kind: EventKindToolCallDelta, ToolCall: &ToolCall{Function: FunctionCall{Arguments: `"Paris"}`}}},
+				{Kind: EventKindToolCall, ToolCall: &ToolCall{ID: "call_1", Function: FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`}}},
+				{Kind: EventKindToolCallDelta, ToolCall: &ToolCall{ID: "call_2", Function: FunctionCall{Name: "time", Arguments: `{}`}}},
+				{Kind: EventKindToolCall, ToolCall: &ToolCall{ID: "call_2", Function: FunctionCall{Name: "time", Arguments: `{}`}}},
+				{FinishReason: FinishReasonToolCalls, Kind: EventKindFinish},
+			},
+		},
+		{
+			name: "tool calls of each choice are reported when the stream ends",
+			chunks: []ChatCompletionChunk{{Choices: []ChunkChoice{
+				{Index: 1, Delta: ChunkDelta{ToolCalls: []ToolCall{weather("call_b", "b", "{}")}}},
+				{Index: 0, Delta: ChunkDelta{ToolCalls: []ToolCall{weather("call_a", "a", "{}")}}},
+			}}},
+			want: []StreamEvent{
+				{Choice: 1, Kind: EventKindToolCallDelta, ToolCall: &ToolCall{ID: "call_b", Function: FunctionCall{Name: "b", Arguments: "{}"}}},
+				{Kind: EventKindToolCallDelta, ToolCall: &ToolCall{ID: "call_a", Function: FunctionCall{Name: "a", Arguments: "{}"}}},
+				{Kind: EventKindToolCall, ToolCall: &ToolCall{ID: "call_a", Function: FunctionCall{Name: "a", Arguments: "{}"}}},
+				{Choice: 1, Kind: EventKindToolCall, ToolCall: &ToolCall{ID: "call_b", Function: FunctionCall{Name: "b", Arguments: "{}"}}},
+			},
+		},
+		{
+			name: "tool calls cut off by an error are not reported whole",
+			chunks: []ChatCompletionChunk{
+				{Choices: []ChunkChoice{{Delta: ChunkDelta{ToolCalls: []ToolCall{weather("call_1", "weather", `{"ci`)}}}}},
+			},
+			err: stderrors.New("connection reset"),
+			want: []StreamEvent{
+				{Kind: EventKindToolCallDelta, ToolCall: &ToolCall{ID: "call_1", Function: FunctionCall{Name: "weather", Arguments: `{"ci`}}},
+			},
+			wantErr: "connection reset",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			chunks, errs := testStream(tc.err, tc.chunks...)
+			events, err := collectEvents(StreamEvents(context.Background(), chunks, errs))
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.want, events)
+		})
+	}
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		chunks, errs := testStream(nil, ChatCompletionChunk{Choices: []ChunkChoice{{Delta: ChunkDelta{Content: "Hi"}}}})
+		events, eventErrs := StreamEvents(ctx, chunks, errs)
+		cancel()
+
+		require.ErrorIs(t, <-eventErrs, context.Canceled)
+		_, ok := <-events
+		require.False(t, ok)
+	})
+}
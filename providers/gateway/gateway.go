@@ -0,0 +1,271 @@
+// Package gateway provides a provider for LiteLLM-style gateways: proxies
+// that expose many upstream providers behind one OpenAI-compatible API.
+//
+// Gateways authenticate with virtual keys, sent like an OpenAI API key. They
+// route a request by the provider prefix of its model name, such as
+// "openai/gpt-4o" or "anthropic/claude-3-5-sonnet", or by an alias configured
+// on the gateway. Gateways also read headers of their own, such as LiteLLM's
+// spend tags, which this provider sends for every request or per call.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// Provider configuration constants.
+const (
+	defaultBaseURL = "http://localhost:4000/v1"
+	envAPIKey      = "LITELLM_API_KEY"
+	envBaseURL     = "LITELLM_BASE_URL"
+	providerName   = "gateway"
+)
+
+// Extra configuration keys.
+const (
+	extraHeaders     = "gateway_headers"
+	extraModelPrefix = "gateway_model_prefix"
+	extraSpendTags   = "gateway_spend_tags"
+)
+
+// headerSpendTags is the header LiteLLM reads spend tags from, separated by
+// commas.
+const headerSpendTags = "x-litellm-tags"
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
+	_ providers.EmbeddingProvider  = (*Provider)(nil)
+	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Provider implements the providers.Provider interface for LiteLLM-style
+// gateways. It embeds openai.CompatibleProvider since gateways expose an
+// OpenAI-compatible API.
+type Provider struct {
+	*openai.CompatibleProvider
+	settings settings
+}
+
+// contextKey is the context key for per-call gateway headers.
+type contextKey struct{}
+
+// requestSettings are the gateway headers and spend tags of one call.
+type requestSettings struct {
+	headers   http.Header
+	spendTags []string
+}
+
+// settings are the gateway settings of a provider.
+type settings struct {
+	headers     http.Header
+	modelPrefix string
+	spendTags   []string
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+// New creates a new gateway provider. The virtual key is read from WithAPIKey
+// or the LITELLM_API_KEY environment variable, and may be omitted for a
+// gateway without authentication. The base URL defaults to a local LiteLLM
+// proxy, or the LITELLM_BASE_URL environment variable when set.
+func New(opts ...config.Option) (*Provider, error) {
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	s := newSettings(cfg)
+
+	base, err := openai.NewCompatible(openai.CompatibleConfig{
+		APIKeyEnvVar:        envAPIKey,
+		BaseURLEnvVar:       envBaseURL,
+		Capabilities:        gatewayCapabilities(),
+		DefaultAPIKey:       "",
+		DefaultBaseURL:      defaultBaseURL,
+		InlineThinking:      false,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    s.preprocessParams,
+		RequestHeaders:      s.requestHeaders,
+		RequireAPIKey:       false, // Gateways may run without authentication.
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{CompatibleProvider: base, settings: s}, nil
+}
+
+// WithHeaders sets headers to send with every request, such as those a
+// gateway reads for routing, caching or budgets. They replace headers of the
+// same name set by the SDK.
+func WithHeaders(headers map[string]string) config.Option {
+	return func(c *config.Config) error {
+		h := make(http.Header, len(headers))
+		for name, value := range headers {
+			if err := validateHeader(name, value); err != nil {
+				return err
+			}
+			h.Set(name, value)
+		}
+		return config.WithExtra(extraHeaders, h)(c)
+	}
+}
+
+// WithModelPrefix routes models named without a provider prefix to prefix,
+// so that "gpt-4o" is sent as "openai/gpt-4o" with WithModelPrefix("openai").
+// Models that already have a prefix, such as "anthropic/claude-3-5-sonnet",
+// are sent as they are.
+func WithModelPrefix(prefix string) config.Option {
+	return func(c *config.Config) error {
+		prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+		if prefix == "" {
+			return fmt.Errorf("model prefix cannot be empty")
+		}
+		return config.WithExtra(extraModelPrefix, prefix)(c)
+	}
+}
+
+// WithRequestHeaders returns a context that adds headers to the gateway
+// requests sent with it. They replace headers of the same name set with
+// WithHeaders.
+func WithRequestHeaders(ctx context.Context, headers map[string]string) context.Context {
+	r := requestSettingsFrom(ctx)
+	h := r.headers.Clone()
+	if h == nil {
+		h = make(http.Header, len(headers))
+	}
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+	r.headers = h
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// WithRequestSpendTags returns a context that adds spend tags to the gateway
+// requests sent with it, after those set with WithSpendTags.
+func WithRequestSpendTags(ctx context.Context, tags ...string) context.Context {
+	r := requestSettingsFrom(ctx)
+	r.spendTags = append(r.spendTags[:len(r.spendTags):len(r.spendTags)], tags...)
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// WithSpendTags sets spend tags to send with every request, in LiteLLM's
+// x-litellm-tags header, to break down spend by team, feature or
+// environment. Tags cannot contain commas.
+func WithSpendTags(tags ...string) config.Option {
+	return func(c *config.Config) error {
+		for _, tag := range tags {
+			if strings.TrimSpace(tag) == "" || strings.ContainsAny(tag, ",\r\n") {
+				return fmt.Errorf("invalid spend tag %q", tag)
+			}
+		}
+		return config.WithExtra(extraSpendTags, tags)(c)
+	}
+}
+
+// Embedding creates embeddings, routing the model like completions.
+func (p *Provider) Embedding(
+	ctx context.Context,
+	params providers.EmbeddingParams,
+) (*providers.EmbeddingResponse, error) {
+	params.Model = p.settings.route(params.Model)
+	return p.CompatibleProvider.Embedding(ctx, params)
+}
+
+// preprocessParams routes the model of a completion.
+func (s settings) preprocessParams(params providers.CompletionParams) providers.CompletionParams {
+	params.Model = s.route(params.Model)
+	return params
+}
+
+// requestHeaders returns the gateway headers of a request sent with ctx.
+func (s settings) requestHeaders(ctx context.Context) http.Header {
+	r := requestSettingsFrom(ctx)
+
+	headers := s.headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	for name, values := range r.headers {
+		headers[name] = values
+	}
+
+	tags := append(s.spendTags[:len(s.spendTags):len(s.spendTags)], r.spendTags...)
+	if len(tags) > 0 {
+		headers.Set(headerSpendTags, strings.Join(tags, ","))
+	}
+	return headers
+}
+
+// route returns model with the model prefix, if it has none.
+func (s settings) route(model string) string {
+	if s.modelPrefix == "" || model == "" || strings.Contains(model, "/") {
+		return model
+	}
+	return s.modelPrefix + "/" + model
+}
+
+// gatewayCapabilities returns the capabilities for the gateway provider. They
+// depend on the upstream model, so Probe a model to find out what it supports.
+func gatewayCapabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  true, // Gateways translate reasoning_effort for upstreams that support it.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true,
+		ListModels:           true, // Lists the models and aliases configured on the gateway.
+	}
+}
+
+// newSettings returns the gateway settings in cfg.
+func newSettings(cfg *config.Config) settings {
+	var s settings
+	if v, ok := cfg.ExtraValue(extraHeaders); ok {
+		s.headers, _ = v.(http.Header) // Set by WithHeaders.
+	}
+	if v, ok := cfg.ExtraValue(extraModelPrefix); ok {
+		s.modelPrefix, _ = v.(string) // Set by WithModelPrefix.
+	}
+	if v, ok := cfg.ExtraValue(extraSpendTags); ok {
+		s.spendTags, _ = v.([]string) // Set by WithSpendTags.
+	}
+	return s
+}
+
+// requestSettingsFrom returns the per-call settings in ctx.
+func requestSettingsFrom(ctx context.Context) requestSettings {
+	r, _ := ctx.Value(contextKey{}).(requestSettings) // Zero when unset.
+	return r
+}
+
+// validateHeader checks that a header can be sent.
+func validateHeader(name, value string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("invalid value for header %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testRequest is a request as the fake gateway received it.
+type testRequest struct {
+	header http.Header
+	model  string
+	path   string
+}
+
+// newFakeGateway starts a server that answers completions and embeddings and
+// records the requests it receives.
+func newFakeGateway(t *testing.T) (*httptest.Server, func() []testRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []testRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		mu.Lock()
+		requests = append(requests, testRequest{header: r.Header.Clone(), model: body.Model, path: r.URL.Path})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/embeddings" {
+			_, _ = w.Write([]byte(`{"object":"list","model":"m","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}]}`)) // Write error surfaces in the client.
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"m",` + // Write error surfaces in the client.
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []testRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// testComplete sends a completion for model through provider.
+func testComplete(t *testing.T, ctx context.Context, provider *Provider, model string) {
+	t.Helper()
+
+	_, err := provider.Completion(ctx, providers.CompletionParams{
+		Model:    model,
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	})
+	require.NoError(t, err)
+}
+
+func TestNew(t *testing.T) {
+	// Note: Not using t.Parallel() here because child test uses t.Setenv.
+
+	t.Run("creates provider without a key", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+		require.Equal(t, providerName, provider.Name())
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(WithModelPrefix(" / "))
+		require.ErrorContains(t, err, "model prefix cannot be empty")
+
+		_, err = New(WithSpendTags("team-a,team-b"))
+		require.ErrorContains(t, err, `invalid spend tag "team-a,team-b"`)
+
+		_, err = New(WithHeaders(map[string]string{"x-bad header": "v"}))
+		require.ErrorContains(t, err, `invalid header name "x-bad header"`)
+
+		_, err = New(WithHeaders(map[string]string{"x-header": "a\r\nb"}))
+		require.ErrorContains(t, err, `invalid value for header "x-header"`)
+	})
+
+	t.Run("sends the virtual key from LITELLM_API_KEY", func(t *testing.T) {
+		server, requests := newFakeGateway(t)
+		t.Setenv("LITELLM_API_KEY", "sk-virtual")
+		t.Setenv("LITELLM_BASE_URL", server.URL+"/v1")
+
+		provider, err := New()
+		require.NoError(t, err)
+		testComplete(t, context.Background(), provider, "openai/gpt-4o")
+
+		require.Equal(t, "Bearer sk-virtual", requests()[0].header.Get("Authorization"))
+	})
+}
+
+func TestModelPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		model string
+		want  string
+	}{
+		{name: "adds the prefix", model: "gpt-4o", want: "openai/gpt-4o"},
+		{name: "keeps another prefix", model: "anthropic/claude-3-5-sonnet", want: "anthropic/claude-3-5-sonnet"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, requests := newFakeGateway(t)
+			provider, err := New(config.WithBaseURL(server.URL+"/v1"), WithModelPrefix("openai/"))
+			require.NoError(t, err)
+
+			testComplete(t, context.Background(), provider, tc.model)
+			_, err = provider.Embedding(context.Background(), providers.EmbeddingParams{Model: "text-embedding-3-small", Input: "Hi"})
+			require.NoError(t, err)
+
+			got := requests()
+			require.Len(t, got, 2)
+			require.Equal(t, tc.want, got[0].model)
+			require.Equal(t, "openai/text-embedding-3-small", got[1].model)
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sends configured headers and spend tags", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newFakeGateway(t)
+		provider, err := New(
+			config.WithBaseURL(server.URL+"/v1"),
+			WithHeaders(map[string]string{"x-litellm-cache": "no-cache", "x-team": "search"}),
+			WithSpendTags("prod", "search"),
+		)
+		require.NoError(t, err)
+
+		testComplete(t, context.Background(), provider, "gpt-4o")
+
+		header := requests()[0].header
+		require.Equal(t, "no-cache", header.Get("x-litellm-cache"))
+		require.Equal(t, "search", header.Get("x-team"))
+		require.Equal(t, "prod,search", header.Get(headerSpendTags))
+	})
+
+	t.Run("adds per-request headers and spend tags", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newFakeGateway(t)
+		provider, err := New(
+			config.WithBaseURL(server.URL+"/v1"),
+			WithHeaders(map[string]string{"x-team": "search"}),
+			WithSpendTags("prod"),
+		)
+		require.NoError(t, err)
+
+		ctx := WithRequestHeaders(context.Background(), map[string]string{"x-team": "ranking"})
+		ctx = WithRequestSpendTags(ctx, "user-42")
+		testComplete(t, ctx, provider, "gpt-4o")
+		testComplete(t, context.Background(), provider, "gpt-4o")
+
+		got := requests()
+		require.Equal(t, "ranking", got[0].header.Get("x-team"))
+		require.Equal(t, "prod,user-42", got[0].header.Get(headerSpendTags))
+		require.Equal(t, "search", got[1].header.Get("x-team"))
+		require.Equal(t, "prod", got[1].header.Get(headerSpendTags))
+	})
+
+	t.Run("sends no gateway headers by default", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newFakeGateway(t)
+		provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+		require.NoError(t, err)
+
+		testComplete(t, context.Background(), provider, "gpt-4o")
+		require.Empty(t, requests()[0].header.Get(headerSpendTags))
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+	require.True(t, caps.Completion)
+	require.True(t, caps.CompletionStreaming)
+	require.True(t, caps.CompletionTools)
+	require.True(t, caps.Embedding)
+	require.True(t, caps.ListModels)
+}
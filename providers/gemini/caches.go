@@ -0,0 +1,133 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// ExtraCachedContent is the CompletionParams.Extra key that references a cached
+// content by name (as returned by CreateCache). The cached messages are
+// prepended to the request's messages by Gemini.
+const ExtraCachedContent = "cached_content"
+
+// CacheParams describes content to cache with CreateCache.
+type CacheParams struct {
+	// DisplayName is an optional human-readable label.
+	DisplayName string
+
+	// Messages is the content to cache. System messages become the cached
+	// system instruction.
+	Messages []providers.Message
+
+	// Model is the model the cache is created for. Only requests to the same
+	// model can use it.
+	Model string
+
+	// Tools are cached along with the messages.
+	Tools []providers.Tool
+
+	// TTL is how long the cache lives. Gemini applies its default (one hour) when zero.
+	TTL time.Duration
+}
+
+// CachedContent describes a Gemini cached content.
+type CachedContent struct {
+	CreateTime  time.Time
+	DisplayName string
+	ExpireTime  time.Time
+	Model       string
+	Name        string
+	TokenCount  int
+}
+
+// CreateCache caches content so later requests can reference it with
+// ExtraCachedContent instead of resending it. Requests using a cache must not
+// repeat its system instruction or tools.
+func (p *Provider) CreateCache(ctx context.Context, params CacheParams) (*CachedContent, error) {
+	if params.Model == "" {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("model is required"))
+	}
+
+	if err := providers.ValidateMessages(providerName, params.Messages); err != nil {
+		return nil, err
+	}
+
+	contents, systemInstruction := convertMessages(params.Messages)
+
+	cfg := &genai.CreateCachedContentConfig{
+		Contents:          contents,
+		DisplayName:       params.DisplayName,
+		SystemInstruction: systemInstruction,
+		TTL:               params.TTL,
+	}
+	if len(params.Tools) > 0 {
+		cfg.Tools = convertTools(params.Tools, false)
+	}
+
+	cache, err := p.client.Caches.Create(ctx, params.Model, cfg)
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+
+	return convertCachedContent(cache), nil
+}
+
+// DeleteCache deletes the cached content with the given name.
+func (p *Provider) DeleteCache(ctx context.Context, name string) error {
+	if _, err := p.client.Caches.Delete(ctx, name, nil); err != nil {
+		return p.ConvertError(err)
+	}
+	return nil
+}
+
+// ListCaches returns all cached contents.
+func (p *Provider) ListCaches(ctx context.Context) ([]CachedContent, error) {
+	var caches []CachedContent
+
+	for cache, err := range p.client.Caches.All(ctx) {
+		if err != nil {
+			return nil, p.ConvertError(err)
+		}
+		caches = append(caches, *convertCachedContent(cache))
+	}
+
+	return caches, nil
+}
+
+// cachedContentName returns the cached content referenced by the request, if any.
+func cachedContentName(params providers.CompletionParams) (string, error) {
+	v, ok := params.Extra[ExtraCachedContent]
+	if !ok {
+		return "", nil
+	}
+
+	name, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: expected string, got %T", ExtraCachedContent, v)
+	}
+
+	return name, nil
+}
+
+// convertCachedContent converts a Gemini cached content to CachedContent.
+func convertCachedContent(cache *genai.CachedContent) *CachedContent {
+	result := &CachedContent{
+		CreateTime:  cache.CreateTime,
+		DisplayName: cache.DisplayName,
+		ExpireTime:  cache.ExpireTime,
+		Model:       cache.Model,
+		Name:        cache.Name,
+	}
+
+	if cache.UsageMetadata != nil {
+		result.TokenCount = int(cache.UsageMetadata.TotalTokenCount)
+	}
+
+	return result
+}
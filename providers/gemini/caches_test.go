@@ -0,0 +1,170 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+const testCacheName = "cachedContents/abc123"
+
+// newCacheTestProvider returns a provider whose client talks to handler.
+func newCacheTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	require.NoError(t, err)
+
+	return &Provider{client: client}
+}
+
+func TestCreateCache(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Contains(t, r.URL.Path, "/cachedContents")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"name": "` + testCacheName + `",
+			"displayName": "handbook",
+			"model": "models/gemini-2.0-flash",
+			"expireTime": "2026-01-01T01:00:00Z",
+			"usageMetadata": {"totalTokenCount": 42000}
+		}`))
+	})
+
+	cache, err := provider.CreateCache(context.Background(), CacheParams{
+		DisplayName: "handbook",
+		Messages: []providers.Message{
+			{Role: providers.RoleSystem, Content: "Answer from the handbook."},
+			{Role: providers.RoleUser, Content: "<the handbook>"},
+		},
+		Model: "gemini-2.0-flash",
+		TTL:   time.Hour,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, testCacheName, cache.Name)
+	require.Equal(t, "handbook", cache.DisplayName)
+	require.Equal(t, 42000, cache.TokenCount)
+	require.Equal(t, time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC), cache.ExpireTime)
+
+	require.Equal(t, "models/gemini-2.0-flash", body["model"])
+	require.Equal(t, "3600s", body["ttl"])
+	require.NotNil(t, body["systemInstruction"])
+	require.Len(t, body["contents"], 1)
+}
+
+func TestCreateCacheRequiresModel(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	_, err = provider.CreateCache(context.Background(), CacheParams{Messages: testutil.SimpleMessages()})
+	require.ErrorIs(t, err, errors.ErrInvalidRequest)
+}
+
+func TestListCaches(t *testing.T) {
+	t.Parallel()
+
+	provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"cachedContents": [{"name": "cachedContents/a"}], "nextPageToken": "next"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"cachedContents": [{"name": "cachedContents/b"}]}`))
+	})
+
+	caches, err := provider.ListCaches(context.Background())
+	require.NoError(t, err)
+	require.Len(t, caches, 2)
+	require.Equal(t, "cachedContents/a", caches[0].Name)
+	require.Equal(t, "cachedContents/b", caches[1].Name)
+}
+
+func TestDeleteCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("deletes by name", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodDelete, r.Method)
+			require.Contains(t, r.URL.Path, testCacheName)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		})
+
+		require.NoError(t, provider.DeleteCache(context.Background(), testCacheName))
+	})
+
+	t.Run("converts not found errors", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newCacheTestProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found", "status": "NOT_FOUND"}}`))
+		})
+
+		err := provider.DeleteCache(context.Background(), testCacheName)
+		require.ErrorIs(t, err, errors.ErrModelNotFound)
+	})
+}
+
+func TestConvertParamsCachedContent(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	t.Run("sets cached content from extra", func(t *testing.T) {
+		t.Parallel()
+
+		_, cfg, err := provider.convertParams(providers.CompletionParams{
+			Model:    "gemini-test",
+			Messages: testutil.SimpleMessages(),
+			Extra:    map[string]any{ExtraCachedContent: testCacheName},
+		})
+		require.NoError(t, err)
+		require.Equal(t, testCacheName, cfg.CachedContent)
+	})
+
+	t.Run("rejects non-string names", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := provider.convertParams(providers.CompletionParams{
+			Model:    "gemini-test",
+			Messages: testutil.SimpleMessages(),
+			Extra:    map[string]any{ExtraCachedContent: 42},
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
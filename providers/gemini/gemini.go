@@ -10,6 +10,7 @@ import (
 	stderrors "errors"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
@@ -37,6 +39,7 @@ const (
 
 // Content part types.
 const (
+	contentPartTypeFile     = "file"
 	contentPartTypeImageURL = "image_url"
 	contentPartTypeText     = "text"
 )
@@ -62,6 +65,7 @@ const (
 	responseFormatJSON   = "json_object"
 	toolCallFallbackName = "function"
 	toolCallType         = "function"
+	toolChoiceAny        = "any"
 )
 
 // ID prefix constants for generated identifiers.
@@ -70,6 +74,12 @@ const (
 	idPrefixToolCall   = "call_"
 )
 
+// Function response keys for tool results that are not JSON objects.
+const (
+	functionResponseKeyError  = "error"
+	functionResponseKeyResult = "result"
+)
+
 // Default MIME type for image URLs when type cannot be determined.
 const defaultImageMIMEType = "image/jpeg"
 
@@ -83,9 +93,21 @@ const (
 	errMsgBlock   = "block"
 )
 
+// schemaLimits is the subset of JSON Schema Gemini accepts in function
+// declarations.
+var schemaLimits = providers.SchemaLimits{
+	Keywords: []string{
+		"$anchor", "$defs", "$id", "$ref", "additionalProperties", "anyOf", "default", "description", "enum",
+		"example", "format", "items", "maxItems", "maxLength", "maxProperties", "maximum", "minItems",
+		"minLength", "minProperties", "minimum", "nullable", "oneOf", "pattern", "prefixItems", "properties",
+		"propertyOrdering", "required", "title", "type",
+	},
+}
+
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
@@ -98,6 +120,13 @@ type Provider struct {
 	config *config.Config
 }
 
+// dryRunRequest is the body returned by DryRun.
+type dryRunRequest struct {
+	Config   *genai.GenerateContentConfig `json:"config,omitempty"`
+	Contents []*genai.Content             `json:"contents"`
+	Model    string                       `json:"model"`
+}
+
 // streamState tracks accumulated state during streaming.
 type streamState struct {
 	content      strings.Builder
@@ -105,10 +134,18 @@ type streamState struct {
 	messageID    string
 	model        string
 	reasoning    strings.Builder
+	refused      bool
+	timings      *streamstats.Recorder
 	toolCalls    []providers.ToolCall
 	usage        *providers.Usage
 }
 
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new Gemini provider.
 func New(opts ...config.Option) (*Provider, error) {
 	cfg, err := config.New(opts...)
@@ -141,15 +178,19 @@ func New(opts ...config.Option) (*Provider, error) {
 
 // Capabilities returns the provider's capabilities.
 func (p *Provider) Capabilities() providers.Capabilities {
-	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     true,
-		CompletionPDF:       false,
-		CompletionReasoning: true,
-		CompletionStreaming: true,
-		Embedding:           true,
-		ListModels:          true,
-	}
+	caps := providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true,
+		ListModels:           true,
+	}
+	return providers.OverrideCapabilities(caps, p.config)
 }
 
 // Completion performs a chat completion request.
@@ -157,10 +198,16 @@ func (p *Provider) Completion(
 	ctx context.Context,
 	params providers.CompletionParams,
 ) (*providers.ChatCompletion, error) {
-	contents, cfg := p.convertParams(params)
+	contents, cfg, err := p.convertParams(params)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := p.client.Models.GenerateContent(ctx, params.Model, contents, cfg)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // The request was cut short by the caller's deadline or cancellation.
+		}
 		return nil, p.ConvertError(err)
 	}
 
@@ -179,8 +226,16 @@ func (p *Provider) CompletionStream(
 		defer close(chunks)
 		defer close(errs)
 
-		contents, cfg := p.convertParams(params)
-		state, err := newStreamState(params.Model)
+		contents, cfg, err := p.convertParams(params)
+		if err != nil {
+			select {
+			case errs <- err:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		state, err := newStreamState(params.Model, streamstats.New())
 		if err != nil {
 			select {
 			case errs <- err:
@@ -191,10 +246,12 @@ func (p *Provider) CompletionStream(
 
 		for resp, err := range p.client.Models.GenerateContentStream(ctx, params.Model, contents, cfg) {
 			if err != nil {
-				select {
-				case errs <- p.ConvertError(err):
-				case <-ctx.Done():
+				if ctx.Err() != nil {
+					err = ctx.Err() // The read failed because the stream was cancelled.
+				} else {
+					err = p.ConvertError(err)
 				}
+				errs <- err
 				return
 			}
 
@@ -211,6 +268,7 @@ func (p *Provider) CompletionStream(
 				select {
 				case chunks <- chunk:
 				case <-ctx.Done():
+					errs <- ctx.Err()
 					return
 				}
 			}
@@ -221,6 +279,7 @@ func (p *Provider) CompletionStream(
 			select {
 			case chunks <- *finalChunk:
 			case <-ctx.Done():
+				errs <- ctx.Err()
 			}
 		}
 	}()
@@ -234,8 +293,14 @@ func (p *Provider) ConvertError(err error) error {
 		return nil
 	}
 
-	var apiErr *genai.APIError
-	if !stderrors.As(err, &apiErr) {
+	// The SDK returns APIError by value; pointers are accepted for callers that wrap their own.
+	var apiErr genai.APIError
+	var apiErrPtr *genai.APIError
+	switch {
+	case stderrors.As(err, &apiErr):
+	case stderrors.As(err, &apiErrPtr):
+		apiErr = *apiErrPtr
+	default:
 		return errors.NewProviderError(providerName, err)
 	}
 
@@ -262,6 +327,23 @@ func (p *Provider) ConvertError(err error) error {
 	}
 }
 
+// DryRun returns the request Completion would send for params, without sending
+// it. The body holds the model, contents and config as passed to the Gemini SDK.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(_ context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	contents, cfg, err := p.convertParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(dryRunRequest{Config: cfg, Contents: contents, Model: params.Model})
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
 // Embedding generates embeddings for the given input.
 func (p *Provider) Embedding(
 	ctx context.Context,
@@ -288,9 +370,11 @@ func (p *Provider) Embedding(
 	}
 
 	return &providers.EmbeddingResponse{
-		Object: objectList,
-		Data:   data,
-		Model:  params.Model,
+		Object:     objectList,
+		Data:       data,
+		Model:      params.Model,
+		Dimensions: providers.EmbeddingDimensions(data),
+		Provider:   providerName,
 	}, nil
 }
 
@@ -337,7 +421,25 @@ func (p *Provider) Name() string {
 }
 
 // convertParams converts providers.CompletionParams to Gemini request format.
-func (p *Provider) convertParams(params providers.CompletionParams) ([]*genai.Content, *genai.GenerateContentConfig) {
+func (p *Provider) convertParams(
+	params providers.CompletionParams,
+) ([]*genai.Content, *genai.GenerateContentConfig, error) {
+	if slices.Contains(params.Modalities, providers.ModalityAudio) {
+		return nil, nil, errors.NewUnsupportedParamError(providerName, "modalities")
+	}
+
+	if err := providers.ValidateSampling(providerName, params, providers.SamplingLimits{}); err != nil {
+		return nil, nil, err
+	}
+
+	if err := providers.ValidateTools(providerName, params.Tools, schemaLimits); err != nil {
+		return nil, nil, err
+	}
+
+	if err := providers.ValidateMessages(providerName, params.Messages); err != nil {
+		return nil, nil, err
+	}
+
 	contents, systemInstruction := convertMessages(params.Messages)
 
 	cfg := &genai.GenerateContentConfig{}
@@ -364,12 +466,25 @@ func (p *Provider) convertParams(params providers.CompletionParams) ([]*genai.Co
 		cfg.StopSequences = params.Stop
 	}
 
+	if params.Seed != nil {
+		seed := int32(*params.Seed)
+		cfg.Seed = &seed
+	}
+
 	if len(params.Tools) > 0 {
-		cfg.Tools = convertTools(params.Tools)
+		typed, err := typedSchemas(params)
+		if err != nil {
+			return nil, nil, errors.NewInvalidRequestError(providerName, err)
+		}
+		cfg.Tools = convertTools(params.Tools, typed)
 	}
 
 	if params.ToolChoice != nil {
-		cfg.ToolConfig = convertToolChoice(params.ToolChoice)
+		toolConfig, err := convertToolChoice(params.ToolChoice)
+		if err != nil {
+			return nil, nil, errors.NewInvalidRequestError(providerName, err)
+		}
+		cfg.ToolConfig = toolConfig
 	}
 
 	applyThinking(cfg, params.ReasoningEffort)
@@ -378,11 +493,18 @@ func (p *Provider) convertParams(params providers.CompletionParams) ([]*genai.Co
 		applyResponseFormat(cfg, params.ResponseFormat)
 	}
 
-	return contents, cfg
+	cachedContent, err := cachedContentName(params)
+	if err != nil {
+		return nil, nil, errors.NewInvalidRequestError(providerName, err)
+	}
+	cfg.CachedContent = cachedContent
+
+	return contents, cfg, nil
 }
 
-// newStreamState creates a new stream state.
-func newStreamState(model string) (*streamState, error) {
+// newStreamState creates a new stream state, recording chunk timings in
+// timings. Create timings before the request is sent.
+func newStreamState(model string, timings *streamstats.Recorder) (*streamState, error) {
 	id, err := generateID(idPrefixCompletion)
 	if err != nil {
 		return nil, err
@@ -390,11 +512,13 @@ func newStreamState(model string) (*streamState, error) {
 	return &streamState{
 		messageID: id,
 		model:     model,
+		timings:   timings,
 	}, nil
 }
 
-// chunk creates a ChatCompletionChunk with the given delta.
+// chunk creates a ChatCompletionChunk with the given delta and records it in the stream timings.
 func (s *streamState) chunk(delta providers.ChunkDelta) providers.ChatCompletionChunk {
+	s.timings.Observe()
 	return providers.ChatCompletionChunk{
 		ID:     s.messageID,
 		Object: objectChatCompletionChunk,
@@ -411,12 +535,22 @@ func (s *streamState) finalChunk() *providers.ChatCompletionChunk {
 	chunk := s.chunk(providers.ChunkDelta{})
 
 	finishReason := convertFinishReason(s.finishReason)
-	if len(s.toolCalls) > 0 && finishReason == providers.FinishReasonStop {
+	switch {
+	case s.refused:
+		finishReason = providers.FinishReasonRefusal
+	case len(s.toolCalls) > 0 && finishReason == providers.FinishReasonStop:
 		finishReason = providers.FinishReasonToolCalls
 	}
 
 	chunk.Choices[0].FinishReason = finishReason
 	chunk.Usage = s.usage
+
+	outputTokens := 0
+	if s.usage != nil {
+		outputTokens = s.usage.CompletionTokens
+	}
+	chunk.Timings = s.timings.Timings(outputTokens)
+
 	return &chunk
 }
 
@@ -430,9 +564,15 @@ func (s *streamState) processResponse(resp *genai.GenerateContentResponse) ([]pr
 			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
 			TotalTokens:      int(resp.UsageMetadata.PromptTokenCount + resp.UsageMetadata.CandidatesTokenCount),
 			ReasoningTokens:  int(resp.UsageMetadata.ThoughtsTokenCount),
+			CachedTokens:     int(resp.UsageMetadata.CachedContentTokenCount),
 		}
 	}
 
+	if refusal, ok := promptRefusal(resp); ok {
+		s.refused = true
+		return append(result, s.chunk(providers.ChunkDelta{Refusal: refusal})), nil
+	}
+
 	if len(resp.Candidates) == 0 {
 		return result, nil
 	}
@@ -625,14 +765,27 @@ func convertMessage(msg providers.Message) *genai.Content {
 
 // convertMessages converts providers messages to Gemini format.
 // Returns the contents and the system instruction (if any).
+// Gemini pairs function responses with calls by name, so a tool result
+// without a Name takes the name of the tool call it answers.
 func convertMessages(messages []providers.Message) ([]*genai.Content, *genai.Content) {
 	var contents []*genai.Content
 	var systemParts []string
+	callNames := make(map[string]string)
 
 	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
+		switch msg.Role {
+		case providers.RoleSystem:
 			systemParts = append(systemParts, msg.ContentString())
 			continue
+		case providers.RoleAssistant:
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+			}
+		case providers.RoleTool:
+			if msg.Name == "" {
+				msg.Name = callNames[msg.ToolCallID]
+			}
+		default:
 		}
 
 		if converted := convertMessage(msg); converted != nil {
@@ -708,6 +861,11 @@ func convertResponse(resp *genai.GenerateContentResponse, model string) (*provid
 		Reasoning: reasoning,
 	}
 
+	if refusal, ok := promptRefusal(resp); ok {
+		message.Refusal = refusal
+		finishReason = providers.FinishReasonRefusal
+	}
+
 	id, err := generateID(idPrefixCompletion)
 	if err != nil {
 		return nil, err
@@ -723,6 +881,7 @@ func convertResponse(resp *genai.GenerateContentResponse, model string) (*provid
 			Message:      message,
 			FinishReason: finishReason,
 		}},
+		RequestID: resp.ResponseID,
 	}
 
 	if resp.UsageMetadata != nil {
@@ -731,6 +890,7 @@ func convertResponse(resp *genai.GenerateContentResponse, model string) (*provid
 			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
 			TotalTokens:      int(resp.UsageMetadata.PromptTokenCount + resp.UsageMetadata.CandidatesTokenCount),
 			ReasoningTokens:  int(resp.UsageMetadata.ThoughtsTokenCount),
+			CachedTokens:     int(resp.UsageMetadata.CachedContentTokenCount),
 		}
 	}
 
@@ -738,41 +898,37 @@ func convertResponse(resp *genai.GenerateContentResponse, model string) (*provid
 }
 
 // convertToolChoice converts providers tool choice to Gemini format.
-func convertToolChoice(choice any) *genai.ToolConfig {
-	switch v := choice.(type) {
-	case string:
-		switch v {
-		case "auto":
-			return &genai.ToolConfig{
-				FunctionCallingConfig: &genai.FunctionCallingConfig{
-					Mode: genai.FunctionCallingConfigModeAuto,
-				},
-			}
-		case "none":
-			return &genai.ToolConfig{
-				FunctionCallingConfig: &genai.FunctionCallingConfig{
-					Mode: genai.FunctionCallingConfigModeNone,
-				},
-			}
-		case "required", "any":
-			return &genai.ToolConfig{
-				FunctionCallingConfig: &genai.FunctionCallingConfig{
-					Mode: genai.FunctionCallingConfigModeAny,
-				},
-			}
-		}
-	case providers.ToolChoice:
-		if v.Function != nil {
-			return &genai.ToolConfig{
-				FunctionCallingConfig: &genai.FunctionCallingConfig{
-					Mode:                 genai.FunctionCallingConfigModeAny,
-					AllowedFunctionNames: []string{v.Function.Name},
-				},
-			}
-		}
+// Gemini's own "any" mode is accepted as an alias for "required".
+func convertToolChoice(choice any) (*genai.ToolConfig, error) {
+	if choice == toolChoiceAny {
+		choice = providers.ToolChoiceRequired
+	}
+	if err := providers.ValidateToolChoice(choice); err != nil {
+		return nil, err
+	}
+
+	if v, ok := choice.(providers.ToolChoice); ok {
+		return &genai.ToolConfig{
+			FunctionCallingConfig: &genai.FunctionCallingConfig{
+				Mode:                 genai.FunctionCallingConfigModeAny,
+				AllowedFunctionNames: []string{v.Function.Name},
+			},
+		}, nil
+	}
+
+	mode := genai.FunctionCallingConfigModeAuto
+	switch choice {
+	case providers.ToolChoiceNone:
+		mode = genai.FunctionCallingConfigModeNone
+	case providers.ToolChoiceRequired:
+		mode = genai.FunctionCallingConfigModeAny
+	default:
+		// ToolChoiceAuto; other values were rejected above.
 	}
 
-	return nil
+	return &genai.ToolConfig{
+		FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: mode},
+	}, nil
 }
 
 // convertToolMessage converts a tool result message to Gemini format.
@@ -782,25 +938,50 @@ func convertToolMessage(msg providers.Message) *genai.Content {
 		name = toolCallFallbackName
 	}
 
-	content := msg.ContentString()
+	return &genai.Content{
+		Role:  roleUser,
+		Parts: []*genai.Part{genai.NewPartFromFunctionResponse(name, convertToolResponse(msg))},
+	}
+}
+
+// convertToolResponse returns the function response for a tool result message.
+// Failed ToolResults are sent as {"error": text}. Otherwise a JSON object is
+// sent as is, and anything else is wrapped as {"result": value}.
+func convertToolResponse(msg providers.Message) map[string]any {
+	result := msg.ToolResult
+	if result == nil {
+		result = &providers.ToolResult{Content: msg.ContentString()}
+	}
+
+	if result.IsError {
+		return map[string]any{functionResponseKeyError: result.Text()}
+	}
+
+	if result.Content == "" && result.Data != nil {
+		// Normalize Data to JSON types, so structs become objects.
+		var data any
+		if raw, err := json.Marshal(result.Data); err == nil && json.Unmarshal(raw, &data) == nil {
+			if response, ok := data.(map[string]any); ok {
+				return response
+			}
+			return map[string]any{functionResponseKeyResult: data}
+		}
+	}
 
 	// Try to parse content as JSON first (structured tool responses).
 	// If parsing fails, wrap the raw content as {"result": content}.
 	var response map[string]any
-	if err := json.Unmarshal([]byte(content), &response); err != nil {
+	if err := json.Unmarshal([]byte(result.Text()), &response); err != nil {
 		response = map[string]any{
-			"result": content,
+			functionResponseKeyResult: result.Text(),
 		}
 	}
-
-	return &genai.Content{
-		Role:  roleUser,
-		Parts: []*genai.Part{genai.NewPartFromFunctionResponse(name, response)},
-	}
+	return response
 }
 
-// convertTools converts providers tools to Gemini format.
-func convertTools(tools []providers.Tool) []*genai.Tool {
+// convertTools converts providers tools to Gemini format. With typed set,
+// parameters are sent as a typed schema rather than as JSON Schema.
+func convertTools(tools []providers.Tool, typed bool) []*genai.Tool {
 	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
 
 	for _, tool := range tools {
@@ -811,6 +992,11 @@ func convertTools(tools []providers.Tool) []*genai.Tool {
 
 		if tool.Function.Parameters != nil {
 			decl.ParametersJsonSchema = tool.Function.Parameters
+			if typed {
+				if schema, ok := convertSchema(tool.Function.Parameters); ok {
+					decl.Parameters, decl.ParametersJsonSchema = schema, nil
+				}
+			}
 		}
 
 		declarations = append(declarations, decl)
@@ -830,6 +1016,9 @@ func convertUserMessage(msg providers.Message) *genai.Content {
 	var parts []*genai.Part
 	for _, part := range msg.ContentParts() {
 		switch part.Type {
+		case contentPartTypeFile:
+			// The MIME type is optional for files uploaded through the Files API.
+			parts = append(parts, &genai.Part{FileData: &genai.FileData{FileURI: part.FileID}})
 		case contentPartTypeText:
 			parts = append(parts, genai.NewPartFromText(part.Text))
 		case contentPartTypeImageURL:
@@ -851,6 +1040,19 @@ func generateID(prefix string) (string, error) {
 	return prefix + hex.EncodeToString(b), nil
 }
 
+// promptRefusal returns the refusal message of a response whose prompt was
+// blocked, and whether it was. Gemini reports a blocked prompt with no
+// candidates and the reason in the prompt feedback.
+func promptRefusal(resp *genai.GenerateContentResponse) (string, bool) {
+	if len(resp.Candidates) > 0 || resp.PromptFeedback == nil || resp.PromptFeedback.BlockReason == "" {
+		return "", false
+	}
+	if msg := resp.PromptFeedback.BlockReasonMessage; msg != "" {
+		return msg, true
+	}
+	return fmt.Sprintf("prompt blocked: %s", resp.PromptFeedback.BlockReason), true
+}
+
 // thinkingBudget returns the token budget for the given reasoning effort.
 func thinkingBudget(effort providers.ReasoningEffort) (int32, bool) {
 	switch effort {
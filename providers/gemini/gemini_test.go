@@ -2,15 +2,21 @@ package gemini
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	stderrors "errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/genai"
 
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/conformance"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
 	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
@@ -201,6 +207,32 @@ func TestConvertMessages(t *testing.T) {
 		require.Equal(t, "sunny", result[1].Parts[0].FunctionResponse.Response["condition"])
 	})
 
+	t.Run("converts failed tool results to error responses", func(t *testing.T) {
+		t.Parallel()
+
+		msg := providers.NewToolResultMessage("call_1", providers.ToolResult{Content: "city not found", IsError: true})
+		msg.Name = "get_weather"
+
+		result, _ := convertMessages([]providers.Message{msg})
+
+		require.Equal(t, map[string]any{"error": "city not found"}, result[0].Parts[0].FunctionResponse.Response)
+	})
+
+	t.Run("converts structured tool results", func(t *testing.T) {
+		t.Parallel()
+
+		type weather struct {
+			Celsius int `json:"celsius"`
+		}
+		object := providers.NewToolResultMessage("call_1", providers.ToolResult{Data: weather{Celsius: 22}})
+		list := providers.NewToolResultMessage("call_2", providers.ToolResult{Data: []string{"Paris", "Lyon"}})
+
+		result, _ := convertMessages([]providers.Message{object, list})
+
+		require.Equal(t, map[string]any{"celsius": float64(22)}, result[0].Parts[0].FunctionResponse.Response)
+		require.Equal(t, map[string]any{"result": []any{"Paris", "Lyon"}}, result[1].Parts[0].FunctionResponse.Response)
+	})
+
 	t.Run("converts tool result message with fallback name", func(t *testing.T) {
 		t.Parallel()
 
@@ -315,7 +347,7 @@ func TestConvertTools(t *testing.T) {
 		},
 	}
 
-	result := convertTools(tools)
+	result := convertTools(tools, false)
 
 	require.Len(t, result, 1)
 	require.Len(t, result[0].FunctionDeclarations, 1)
@@ -330,45 +362,135 @@ func TestConvertToolChoice(t *testing.T) {
 	t.Run("auto string", func(t *testing.T) {
 		t.Parallel()
 
-		result := convertToolChoice("auto")
-		require.NotNil(t, result)
+		result, err := convertToolChoice(providers.ToolChoiceAuto)
+		require.NoError(t, err)
 		require.Equal(t, genai.FunctionCallingConfigModeAuto, result.FunctionCallingConfig.Mode)
 	})
 
 	t.Run("none string", func(t *testing.T) {
 		t.Parallel()
 
-		result := convertToolChoice("none")
-		require.NotNil(t, result)
+		result, err := convertToolChoice(providers.ToolChoiceNone)
+		require.NoError(t, err)
 		require.Equal(t, genai.FunctionCallingConfigModeNone, result.FunctionCallingConfig.Mode)
 	})
 
 	t.Run("required string", func(t *testing.T) {
 		t.Parallel()
 
-		result := convertToolChoice("required")
-		require.NotNil(t, result)
+		result, err := convertToolChoice(providers.ToolChoiceRequired)
+		require.NoError(t, err)
+		require.Equal(t, genai.FunctionCallingConfigModeAny, result.FunctionCallingConfig.Mode)
+	})
+
+	t.Run("any string is an alias for required", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := convertToolChoice("any")
+		require.NoError(t, err)
 		require.Equal(t, genai.FunctionCallingConfigModeAny, result.FunctionCallingConfig.Mode)
 	})
 
 	t.Run("specific function", func(t *testing.T) {
 		t.Parallel()
 
-		result := convertToolChoice(providers.ToolChoice{
-			Type:     "function",
-			Function: &providers.ToolChoiceFunction{Name: "get_weather"},
-		})
-		require.NotNil(t, result)
+		result, err := convertToolChoice(providers.ToolChoiceForFunction("get_weather"))
+		require.NoError(t, err)
 		require.Equal(t, genai.FunctionCallingConfigModeAny, result.FunctionCallingConfig.Mode)
 		require.Contains(t, result.FunctionCallingConfig.AllowedFunctionNames, "get_weather")
 	})
 
-	t.Run("unknown returns nil", func(t *testing.T) {
+	t.Run("unknown value returns error", func(t *testing.T) {
 		t.Parallel()
 
-		result := convertToolChoice("unknown_value")
+		result, err := convertToolChoice("unknown_value")
+		require.Error(t, err)
 		require.Nil(t, result)
 	})
+
+	t.Run("function without name returns error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertToolChoice(providers.ToolChoice{Type: providers.ToolChoiceTypeFunction})
+		require.Error(t, err)
+	})
+}
+
+func TestConvertParamsRejectsAudioOutput(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	_, _, err = provider.convertParams(providers.CompletionParams{
+		Model:      "gemini-test",
+		Messages:   testutil.SimpleMessages(),
+		Modalities: []string{providers.ModalityAudio},
+	})
+	require.ErrorIs(t, err, errors.ErrUnsupportedParam)
+}
+
+func TestConvertParamsRejectsUnsupportedSchemaKeywords(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	tool := testutil.WeatherTool()
+	tool.Function.Parameters["allOf"] = []any{map[string]any{"required": []string{"location"}}}
+
+	_, _, err = provider.convertParams(providers.CompletionParams{
+		Model:    "gemini-test",
+		Messages: testutil.SimpleMessages(),
+		Tools:    []providers.Tool{tool},
+	})
+	require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	require.ErrorContains(t, err, `tool "get_weather": parameters: keyword "allOf" is not supported`)
+}
+
+func TestConvertParamsSeed(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	seed := 42
+	_, cfg, err := provider.convertParams(providers.CompletionParams{
+		Model:    "gemini-test",
+		Messages: testutil.SimpleMessages(),
+		Seed:     &seed,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Seed)
+	require.Equal(t, int32(42), *cfg.Seed)
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	body, err := provider.DryRun(context.Background(), providers.CompletionParams{
+		Model:    "gemini-test",
+		Messages: testutil.MessagesWithSystem(),
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	})
+	require.NoError(t, err)
+
+	var req struct {
+		Config struct {
+			SystemInstruction map[string]any   `json:"systemInstruction"`
+			Tools             []map[string]any `json:"tools"`
+		} `json:"config"`
+		Contents []map[string]any `json:"contents"`
+		Model    string           `json:"model"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+	require.Equal(t, "gemini-test", req.Model)
+	require.Len(t, req.Contents, 1)
+	require.NotEmpty(t, req.Config.SystemInstruction)
+	require.Len(t, req.Config.Tools, 1)
 }
 
 func TestConvertError(t *testing.T) {
@@ -394,6 +516,11 @@ func TestConvertError(t *testing.T) {
 			err:          &genai.APIError{Code: 401, Message: "unauthorized"},
 			wantSentinel: errors.ErrAuthentication,
 		},
+		{
+			name:         "API error returned by value is converted",
+			err:          fmt.Errorf("calling API: %w", genai.APIError{Code: 429, Message: "quota"}),
+			wantSentinel: errors.ErrRateLimit,
+		},
 		{
 			name:         "403 status becomes AuthenticationError",
 			err:          &genai.APIError{Code: 403, Message: "forbidden"},
@@ -577,6 +704,24 @@ func TestConvertImagePart(t *testing.T) {
 	})
 }
 
+func TestConvertUserMessageFilePart(t *testing.T) {
+	t.Parallel()
+
+	fileURI := "https://generativelanguage.googleapis.com/v1beta/files/abc123"
+	msg := providers.Message{
+		Role: providers.RoleUser,
+		Content: []providers.ContentPart{
+			{Type: contentPartTypeText, Text: "Summarize this report."},
+			{Type: contentPartTypeFile, FileID: fileURI},
+		},
+	}
+
+	result := convertUserMessage(msg)
+	require.Len(t, result.Parts, 2)
+	require.NotNil(t, result.Parts[1].FileData)
+	require.Equal(t, fileURI, result.Parts[1].FileData.FileURI)
+}
+
 func TestConvertEmbeddingInput(t *testing.T) {
 	t.Parallel()
 
@@ -634,7 +779,7 @@ func TestGenerateID(t *testing.T) {
 func TestNewStreamState(t *testing.T) {
 	t.Parallel()
 
-	state, err := newStreamState("gemini-1.5-flash")
+	state, err := newStreamState("gemini-1.5-flash", streamstats.New())
 	require.NoError(t, err)
 	require.NotNil(t, state)
 	require.Equal(t, "gemini-1.5-flash", state.model)
@@ -649,7 +794,7 @@ func TestStreamStateProcessResponse(t *testing.T) {
 	t.Run("processes text content", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		resp := &genai.GenerateContentResponse{
 			Candidates: []*genai.Candidate{{
@@ -669,7 +814,7 @@ func TestStreamStateProcessResponse(t *testing.T) {
 	t.Run("processes thinking content", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		resp := &genai.GenerateContentResponse{
 			Candidates: []*genai.Candidate{{
@@ -689,7 +834,7 @@ func TestStreamStateProcessResponse(t *testing.T) {
 	t.Run("processes function call", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		resp := &genai.GenerateContentResponse{
 			Candidates: []*genai.Candidate{{
@@ -715,7 +860,7 @@ func TestStreamStateProcessResponse(t *testing.T) {
 	t.Run("tracks usage metadata", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		resp := &genai.GenerateContentResponse{
 			UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
@@ -740,7 +885,7 @@ func TestStreamStateProcessResponse(t *testing.T) {
 	t.Run("returns empty slice for empty candidates", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		resp := &genai.GenerateContentResponse{}
 
@@ -756,7 +901,7 @@ func TestStreamStateFinalChunk(t *testing.T) {
 	t.Run("defaults to stop finish reason", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		state.finishReason = genai.FinishReasonStop
 
@@ -767,7 +912,7 @@ func TestStreamStateFinalChunk(t *testing.T) {
 	t.Run("uses tool_calls when tool calls present", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		state.finishReason = genai.FinishReasonStop
 		state.toolCalls = []providers.ToolCall{
@@ -778,10 +923,27 @@ func TestStreamStateFinalChunk(t *testing.T) {
 		require.Equal(t, providers.FinishReasonToolCalls, chunk.Choices[0].FinishReason)
 	})
 
+	t.Run("uses refusal when the prompt was blocked", func(t *testing.T) {
+		t.Parallel()
+
+		state, err := newStreamState("test-model", streamstats.New())
+		require.NoError(t, err)
+
+		chunks, err := state.processResponse(&genai.GenerateContentResponse{
+			PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonProhibitedContent},
+		})
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		require.Equal(t, "prompt blocked: PROHIBITED_CONTENT", chunks[0].Choices[0].Delta.Refusal)
+
+		chunk := state.finalChunk()
+		require.Equal(t, providers.FinishReasonRefusal, chunk.Choices[0].FinishReason)
+	})
+
 	t.Run("uses max_tokens finish reason", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		state.finishReason = genai.FinishReasonMaxTokens
 
@@ -789,10 +951,23 @@ func TestStreamStateFinalChunk(t *testing.T) {
 		require.Equal(t, providers.FinishReasonLength, chunk.Choices[0].FinishReason)
 	})
 
+	t.Run("includes stream timings", func(t *testing.T) {
+		t.Parallel()
+
+		state, err := newStreamState("test-model", streamstats.New())
+		require.NoError(t, err)
+		state.chunk(providers.ChunkDelta{Content: "Hello"})
+		state.usage = &providers.Usage{CompletionTokens: 2}
+
+		chunk := state.finalChunk()
+		require.NotNil(t, chunk.Timings)
+		require.Equal(t, 2, chunk.Timings.ChunkCount)
+	})
+
 	t.Run("includes usage", func(t *testing.T) {
 		t.Parallel()
 
-		state, err := newStreamState("test-model")
+		state, err := newStreamState("test-model", streamstats.New())
 		require.NoError(t, err)
 		state.usage = &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
 
@@ -819,12 +994,14 @@ func TestConvertResponse(t *testing.T) {
 				PromptTokenCount:     10,
 				CandidatesTokenCount: 5,
 			},
+			ResponseID: "resp-123",
 		}
 
 		result, err := convertResponse(resp, "gemini-1.5-flash")
 		require.NoError(t, err)
 		require.Equal(t, objectChatCompletion, result.Object)
 		require.Equal(t, "gemini-1.5-flash", result.Model)
+		require.Equal(t, "resp-123", result.RequestID)
 		require.Len(t, result.Choices, 1)
 		require.Equal(t, "Hello World", result.Choices[0].Message.ContentString())
 		require.Equal(t, providers.RoleAssistant, result.Choices[0].Message.Role)
@@ -880,6 +1057,42 @@ func TestConvertResponse(t *testing.T) {
 		require.NotNil(t, result.Choices[0].Message.Reasoning)
 		require.Equal(t, "Let me think...", result.Choices[0].Message.Reasoning.Content)
 	})
+
+	t.Run("converts a blocked prompt to a refusal", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name        string
+			feedback    *genai.GenerateContentResponsePromptFeedback
+			wantRefusal string
+		}{
+			{
+				name: "with a message",
+				feedback: &genai.GenerateContentResponsePromptFeedback{
+					BlockReason:        genai.BlockedReasonSafety,
+					BlockReasonMessage: "The prompt was blocked for safety.",
+				},
+				wantRefusal: "The prompt was blocked for safety.",
+			},
+			{
+				name:        "without a message",
+				feedback:    &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonJailbreak},
+				wantRefusal: "prompt blocked: JAILBREAK",
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				result, err := convertResponse(&genai.GenerateContentResponse{PromptFeedback: tc.feedback}, "gemini-2.0-flash")
+				require.NoError(t, err)
+				require.Equal(t, providers.FinishReasonRefusal, result.Choices[0].FinishReason)
+				require.Equal(t, tc.wantRefusal, result.Choices[0].Message.Refusal)
+				require.Empty(t, result.Choices[0].Message.ContentString())
+			})
+		}
+	})
 }
 
 func TestApplyResponseFormat(t *testing.T) {
@@ -903,121 +1116,74 @@ func TestApplyResponseFormat(t *testing.T) {
 }
 
 // Integration tests - only run if API key is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
 
-func TestIntegrationCompletion(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("GEMINI_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.SimpleMessages(),
-	}
+	server, released := testutil.NewStallingServer(t, "text/event-stream", strings.Repeat(
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"index":0}]}`+"\n\n", 2))
 
-	resp, err := provider.Completion(ctx, params)
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
 	require.NoError(t, err)
+	provider := &Provider{client: client}
 
-	require.NotEmpty(t, resp.ID)
-	require.Equal(t, objectChatCompletion, resp.Object)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
-	require.Equal(t, providers.RoleAssistant, resp.Choices[0].Message.Role)
-	require.NotNil(t, resp.Usage)
-	require.Greater(t, resp.Usage.TotalTokens, 0)
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "gemini-test",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
 }
 
-func TestIntegrationCompletionWithSystemMessage(t *testing.T) {
+func TestCompletionDeadline(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("GEMINI_API_KEY not set")
-	}
+	server, requests := testutil.NewHangingServer(t)
 
-	provider, err := New()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
 	require.NoError(t, err)
+	provider := &Provider{client: client}
 
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.MessagesWithSystem(),
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
 
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
+	_, err = provider.Completion(ctx, providers.CompletionParams{
+		Model:    "gemini-test",
+		Messages: testutil.SimpleMessages(),
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, requests, 1)
 
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
+	// The deadline is passed on to the server too.
+	header := <-requests
+	require.Equal(t, "1", header.Get("x-server-timeout"))
 }
 
-func TestIntegrationCompletionStream(t *testing.T) {
+func TestStreamParity(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("GEMINI_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.SimpleMessages(),
-		Stream:   true,
-	}
-
-	chunks, errs := provider.CompletionStream(ctx, params)
-
-	var content strings.Builder
-	chunkCount := 0
-
-	for chunk := range chunks {
-		chunkCount++
-		require.Equal(t, objectChatCompletionChunk, chunk.Object)
-		if len(chunk.Choices) > 0 {
-			content.WriteString(chunk.Choices[0].Delta.Content)
-		}
-	}
-
-	err = <-errs
-	require.NoError(t, err)
-
-	require.Greater(t, chunkCount, 0)
-	require.NotEmpty(t, content.String())
+	testutil.VerifyStreamParity(t, providerName, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
-func TestIntegrationCompletionConversation(t *testing.T) {
+func TestIntegrationConformance(t *testing.T) {
 	t.Parallel()
 
 	if testutil.SkipIfNoAPIKey(providerName) {
 		t.Skip("GEMINI_API_KEY not set")
 	}
 
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.ConversationMessages(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
-
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok, "expected string content")
-	require.Contains(t, strings.ToLower(contentStr), "alice")
+	conformance.Run(t, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	}, testutil.TestModel(providerName))
 }
 
 func TestIntegrationEmbedding(t *testing.T) {
@@ -1069,3 +1235,71 @@ func TestIntegrationListModels(t *testing.T) {
 		require.Equal(t, "google", model.OwnedBy)
 	}
 }
+
+func FuzzConvertMessages(f *testing.F) {
+	testutil.AddConversationSeeds(f)
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		messages := testutil.RandomConversation(seed)
+
+		contents, cfg, err := provider.convertParams(providers.CompletionParams{Model: "gemini-test", Messages: messages})
+		require.NoError(t, err)
+
+		// Gemini has no tool call IDs; results are paired with calls by name.
+		want := testutil.MessageEvents(messages)
+		for i := range want {
+			want[i].ID = ""
+		}
+
+		got := requestEvents(t, cfg.SystemInstruction, contents)
+		require.Equal(t, want, got)
+		testutil.RequireToolPairing(t, got)
+
+		_, _, err = provider.convertParams(providers.CompletionParams{
+			Model:    "gemini-test",
+			Messages: testutil.WithUnknownRole(messages, seed),
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+// requestEvents flattens the system instruction and contents of a request
+// into events.
+func requestEvents(t *testing.T, system *genai.Content, contents []*genai.Content) []testutil.MessageEvent {
+	t.Helper()
+
+	var events []testutil.MessageEvent
+	if system != nil {
+		for _, part := range system.Parts {
+			events = append(events, testutil.TextEvent(providers.RoleSystem, part.Text))
+		}
+	}
+
+	for _, content := range contents {
+		role := providers.RoleUser
+		if content.Role == roleModel {
+			role = providers.RoleAssistant
+		}
+
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				events = append(events, testutil.ToolCallEventOf(t, role, "", part.FunctionCall.Name, part.FunctionCall.Args))
+			case part.FunctionResponse != nil:
+				value, _ := part.FunctionResponse.Response["result"].(string)
+				events = append(events, testutil.ToolResultEvent("", part.FunctionResponse.Name, value))
+			case part.InlineData != nil:
+				events = append(events, testutil.InlineImageEvent(role, part.InlineData.MIMEType,
+					base64.StdEncoding.EncodeToString(part.InlineData.Data)))
+			case part.FileData != nil:
+				events = append(events, testutil.ImageEvent(role, part.FileData.FileURI))
+			default:
+				events = append(events, testutil.TextEvent(role, part.Text))
+			}
+		}
+	}
+	return events
+}
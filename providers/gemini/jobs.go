@@ -0,0 +1,208 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Job request constants.
+const (
+	operationModelsPath = "models/"
+	operationsPath      = "/operations/"
+	paramImage          = "image"
+	paramJobKind        = "kind"
+	paramJobModel       = "model"
+	paramNegativePrompt = "negative_prompt"
+)
+
+// Ensure Provider implements the job interface.
+var _ providers.JobProvider = (*Provider)(nil)
+
+// FetchArtifact downloads a generated video from its URI.
+// Implements providers.JobProvider.
+func (p *Provider) FetchArtifact(ctx context.Context, artifact providers.Artifact) ([]byte, error) {
+	if artifact.Data != nil {
+		return artifact.Data, nil
+	}
+	if artifact.URI == "" {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("artifact has no data or URI"))
+	}
+
+	data, err := p.client.Files.Download(ctx, genai.NewDownloadURIFromVideo(&genai.Video{URI: artifact.URI}), nil)
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+	return data, nil
+}
+
+// GetJob returns the state of a video generation job.
+// Implements providers.JobProvider.
+func (p *Provider) GetJob(ctx context.Context, id string) (*providers.Job, error) {
+	if !strings.Contains(id, operationsPath) {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("%q is not a video generation job", id))
+	}
+
+	op, err := p.client.Operations.GetVideosOperation(ctx, &genai.GenerateVideosOperation{Name: id}, nil)
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+	return convertVideosOperation(op), nil
+}
+
+// SubmitJob starts a Veo video generation job, or generates images with
+// Imagen. Image generation completes at submission, so its job is already
+// done and has no ID.
+// Implements providers.JobProvider.
+func (p *Provider) SubmitJob(ctx context.Context, params providers.JobParams) (*providers.Job, error) {
+	if params.Model == "" {
+		return nil, errors.NewInvalidParamError(providerName, paramJobModel, fmt.Errorf("model is required"))
+	}
+
+	switch params.Kind {
+	case providers.JobKindImage:
+		return p.generateImages(ctx, params)
+	case providers.JobKindVideo:
+		return p.generateVideos(ctx, params)
+	default:
+		return nil, errors.NewInvalidParamError(providerName, paramJobKind,
+			fmt.Errorf("unsupported job kind %q", params.Kind))
+	}
+}
+
+// generateImages generates images with an Imagen model.
+func (p *Provider) generateImages(ctx context.Context, params providers.JobParams) (*providers.Job, error) {
+	// The Gemini API cannot edit images or take negative prompts for Imagen.
+	if params.Image != nil {
+		return nil, errors.NewUnsupportedParamError(providerName, paramImage)
+	}
+	if params.NegativePrompt != "" {
+		return nil, errors.NewUnsupportedParamError(providerName, paramNegativePrompt)
+	}
+
+	resp, err := p.client.Models.GenerateImages(ctx, params.Model, params.Prompt, &genai.GenerateImagesConfig{
+		AspectRatio:    params.AspectRatio,
+		NumberOfImages: int32(params.N),
+	})
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+
+	job := &providers.Job{Kind: providers.JobKindImage, Model: params.Model, Status: providers.JobStatusSucceeded}
+	for _, generated := range resp.GeneratedImages {
+		if generated.Image == nil {
+			continue
+		}
+		job.Artifacts = append(job.Artifacts, providers.Artifact{
+			Data:     generated.Image.ImageBytes,
+			MIMEType: generated.Image.MIMEType,
+			URI:      generated.Image.GCSURI,
+		})
+	}
+	if len(job.Artifacts) == 0 {
+		job.Status = providers.JobStatusFailed
+		job.Error = "no images were generated; the prompt may have been filtered"
+	}
+
+	return job, nil
+}
+
+// generateVideos starts a video generation job with a Veo model.
+func (p *Provider) generateVideos(ctx context.Context, params providers.JobParams) (*providers.Job, error) {
+	var image *genai.Image
+	if params.Image != nil {
+		var err error
+		if image, err = convertJobImage(params.Image); err != nil {
+			return nil, errors.NewInvalidParamError(providerName, paramImage, err)
+		}
+	}
+
+	op, err := p.client.Models.GenerateVideos(ctx, params.Model, params.Prompt, image, &genai.GenerateVideosConfig{
+		AspectRatio:    params.AspectRatio,
+		NegativePrompt: params.NegativePrompt,
+		NumberOfVideos: int32(params.N),
+	})
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+
+	return convertVideosOperation(op), nil
+}
+
+// convertJobImage converts a base64 data URL to a Gemini image.
+func convertJobImage(img *providers.ImageURL) (*genai.Image, error) {
+	header, data, ok := strings.Cut(img.URL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("image must be a base64 data URL")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+
+	mediaType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	return &genai.Image{ImageBytes: decoded, MIMEType: mediaType}, nil
+}
+
+// convertVideosOperation converts a video generation operation to a job.
+func convertVideosOperation(op *genai.GenerateVideosOperation) *providers.Job {
+	job := &providers.Job{ID: op.Name, Kind: providers.JobKindVideo, Status: providers.JobStatusRunning}
+	if rest, ok := strings.CutPrefix(op.Name, operationModelsPath); ok {
+		job.Model, _, _ = strings.Cut(rest, operationsPath)
+	}
+
+	switch {
+	case !op.Done:
+		return job
+	case op.Error != nil:
+		job.Status = providers.JobStatusFailed
+		job.Error = operationError(op.Error)
+		return job
+	default:
+	}
+
+	job.Status = providers.JobStatusSucceeded
+	if op.Response != nil {
+		for _, generated := range op.Response.GeneratedVideos {
+			if generated.Video == nil {
+				continue
+			}
+			job.Artifacts = append(job.Artifacts, providers.Artifact{
+				Data:     generated.Video.VideoBytes,
+				MIMEType: generated.Video.MIMEType,
+				URI:      generated.Video.URI,
+			})
+		}
+	}
+	if len(job.Artifacts) == 0 {
+		job.Status = providers.JobStatusFailed
+		job.Error = "no videos were generated; the prompt may have been filtered"
+		if op.Response != nil && len(op.Response.RAIMediaFilteredReasons) > 0 {
+			job.Error = strings.Join(op.Response.RAIMediaFilteredReasons, "; ")
+		}
+	}
+
+	return job
+}
+
+// operationError returns the message of an operation error, or the whole
+// error as JSON if it has none.
+func operationError(opErr map[string]any) string {
+	if msg, ok := opErr["message"].(string); ok && msg != "" {
+		return msg
+	}
+
+	data, err := json.Marshal(opErr)
+	if err != nil {
+		return fmt.Sprint(opErr)
+	}
+	return string(data)
+}
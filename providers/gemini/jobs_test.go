@@ -0,0 +1,235 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+const testOperationName = "models/veo-2.0-generate-001/operations/op123"
+
+func TestSubmitJobVideo(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.True(t, strings.HasSuffix(r.URL.Path, "/models/veo-2.0-generate-001:predictLongRunning"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "` + testOperationName + `"}`))
+	})
+
+	job, err := provider.SubmitJob(context.Background(), providers.JobParams{
+		AspectRatio: "16:9",
+		Image:       &providers.ImageURL{URL: "data:image/png;base64,aW1n"},
+		Kind:        providers.JobKindVideo,
+		Model:       "veo-2.0-generate-001",
+		N:           2,
+		Prompt:      "A cat surfing",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &providers.Job{
+		ID:     testOperationName,
+		Kind:   providers.JobKindVideo,
+		Model:  "veo-2.0-generate-001",
+		Status: providers.JobStatusRunning,
+	}, job)
+	require.False(t, job.Done())
+
+	instance := body["instances"].([]any)[0].(map[string]any)
+	require.Equal(t, "A cat surfing", instance["prompt"])
+	require.Equal(t, map[string]any{"bytesBase64Encoded": "aW1n", "mimeType": "image/png"}, instance["image"])
+	require.Equal(t, map[string]any{"aspectRatio": "16:9", "sampleCount": float64(2)}, body["parameters"])
+}
+
+func TestSubmitJobImage(t *testing.T) {
+	t.Parallel()
+
+	provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasSuffix(r.URL.Path, "/models/imagen-3.0-generate-002:predict"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"predictions": [{"bytesBase64Encoded": "aW1n", "mimeType": "image/png"}]}`))
+	})
+
+	job, err := provider.SubmitJob(context.Background(), providers.JobParams{
+		Kind:   providers.JobKindImage,
+		Model:  "imagen-3.0-generate-002",
+		Prompt: "A cat surfing",
+	})
+	require.NoError(t, err)
+	require.True(t, job.Done())
+	require.Empty(t, job.ID)
+	require.Equal(t, providers.JobStatusSucceeded, job.Status)
+	require.Equal(t, []providers.Artifact{{Data: []byte("img"), MIMEType: "image/png"}}, job.Artifacts)
+}
+
+func TestSubmitJobValidation(t *testing.T) {
+	t.Parallel()
+
+	provider := newCacheTestProvider(t, func(http.ResponseWriter, *http.Request) {
+		t.Error("no request should be sent")
+	})
+
+	tests := []struct {
+		name    string
+		params  providers.JobParams
+		wantErr error
+	}{
+		{
+			name:    "missing model",
+			params:  providers.JobParams{Kind: providers.JobKindVideo, Prompt: "p"},
+			wantErr: errors.ErrInvalidRequest,
+		},
+		{
+			name:    "unknown kind",
+			params:  providers.JobParams{Kind: "audio", Model: "m", Prompt: "p"},
+			wantErr: errors.ErrInvalidRequest,
+		},
+		{
+			name: "image URL for video",
+			params: providers.JobParams{
+				Image: &providers.ImageURL{URL: "https://example.com/cat.png"},
+				Kind:  providers.JobKindVideo,
+				Model: "m",
+			},
+			wantErr: errors.ErrInvalidRequest,
+		},
+		{
+			name: "image editing",
+			params: providers.JobParams{
+				Image: &providers.ImageURL{URL: "data:image/png;base64,aW1n"},
+				Kind:  providers.JobKindImage,
+				Model: "m",
+			},
+			wantErr: errors.ErrUnsupportedParam,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := provider.SubmitJob(context.Background(), tc.params)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestGetJob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response string
+		want     *providers.Job
+	}{
+		{
+			name:     "running",
+			response: `{"name": "` + testOperationName + `"}`,
+			want: &providers.Job{
+				ID:     testOperationName,
+				Kind:   providers.JobKindVideo,
+				Model:  "veo-2.0-generate-001",
+				Status: providers.JobStatusRunning,
+			},
+		},
+		{
+			name: "succeeded",
+			response: `{"name": "` + testOperationName + `", "done": true, "response": {"generateVideoResponse": {
+				"generatedSamples": [{"video": {"uri": "https://example.com/v1beta/files/vid:download?alt=media"}}]
+			}}}`,
+			want: &providers.Job{
+				Artifacts: []providers.Artifact{{URI: "https://example.com/v1beta/files/vid:download?alt=media"}},
+				ID:        testOperationName,
+				Kind:      providers.JobKindVideo,
+				Model:     "veo-2.0-generate-001",
+				Status:    providers.JobStatusSucceeded,
+			},
+		},
+		{
+			name:     "failed",
+			response: `{"name": "` + testOperationName + `", "done": true, "error": {"code": 3, "message": "bad prompt"}}`,
+			want: &providers.Job{
+				Error:  "bad prompt",
+				ID:     testOperationName,
+				Kind:   providers.JobKindVideo,
+				Model:  "veo-2.0-generate-001",
+				Status: providers.JobStatusFailed,
+			},
+		},
+		{
+			name: "filtered",
+			response: `{"name": "` + testOperationName + `", "done": true, "response": {"generateVideoResponse": {
+				"raiMediaFilteredCount": 1, "raiMediaFilteredReasons": ["unsafe"]
+			}}}`,
+			want: &providers.Job{
+				Error:  "unsafe",
+				ID:     testOperationName,
+				Kind:   providers.JobKindVideo,
+				Model:  "veo-2.0-generate-001",
+				Status: providers.JobStatusFailed,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodGet, r.Method)
+				require.True(t, strings.HasSuffix(r.URL.Path, "/"+testOperationName))
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tc.response))
+			})
+
+			job, err := provider.GetJob(context.Background(), testOperationName)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, job)
+		})
+	}
+
+	t.Run("rejects other IDs", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newCacheTestProvider(t, func(http.ResponseWriter, *http.Request) {
+			t.Error("no request should be sent")
+		})
+
+		_, err := provider.GetJob(context.Background(), "cachedContents/abc")
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+func TestFetchArtifact(t *testing.T) {
+	t.Parallel()
+
+	provider := newCacheTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasSuffix(r.URL.Path, "/files/vid:download"))
+		_, _ = w.Write([]byte("video"))
+	})
+
+	data, err := provider.FetchArtifact(context.Background(), providers.Artifact{
+		URI: "https://example.com/v1beta/files/vid:download?alt=media",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("video"), data)
+
+	data, err = provider.FetchArtifact(context.Background(), providers.Artifact{Data: []byte("inline")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("inline"), data)
+
+	_, err = provider.FetchArtifact(context.Background(), providers.Artifact{})
+	require.ErrorIs(t, err, errors.ErrInvalidRequest)
+}
@@ -0,0 +1,291 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// ExtraTypedSchemas is the CompletionParams.Extra key that sends tool
+// parameters as a typed genai.Schema, the OpenAPI subset every Gemini model
+// and endpoint accepts, instead of as JSON Schema. Set it to true for models
+// and endpoints that reject JSON Schema. JSON Schema features the typed
+// subset lacks fall back to the closest feature it has.
+const ExtraTypedSchemas = "typed_schemas"
+
+// refPrefixes are the prefixes of the local references a typed schema can
+// inline.
+var refPrefixes = []string{"#/$defs/", "#/definitions/"}
+
+// schemaTypes maps JSON Schema types to Gemini types.
+var schemaTypes = map[string]genai.Type{
+	"array":   genai.TypeArray,
+	"boolean": genai.TypeBoolean,
+	"integer": genai.TypeInteger,
+	"number":  genai.TypeNumber,
+	"object":  genai.TypeObject,
+	"string":  genai.TypeString,
+}
+
+// schemaConverter converts a JSON Schema to a typed Gemini schema.
+type schemaConverter struct {
+	defs      map[string]any
+	resolving map[string]bool
+}
+
+// convert converts a decoded JSON Schema.
+func (c *schemaConverter) convert(value any) *genai.Schema {
+	schema, ok := value.(map[string]any)
+	if !ok {
+		// Boolean schemas have no typed equivalent; accept anything.
+		return &genai.Schema{}
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		return c.resolve(ref, schema)
+	}
+
+	out := &genai.Schema{
+		Default: schema["default"],
+		Example: schema["example"],
+	}
+	out.Description, _ = schema["description"].(string)
+	out.Format, _ = schema["format"].(string)
+	out.Pattern, _ = schema["pattern"].(string)
+	out.Title, _ = schema["title"].(string)
+	if nullable, ok := schema["nullable"].(bool); ok && nullable {
+		out.Nullable = &nullable
+	}
+
+	c.convertType(out, schema["type"])
+	c.convertEnum(out, schema["enum"])
+	c.convertBounds(out, schema)
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		out.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			out.Properties[name] = c.convert(property)
+		}
+		if out.Type == "" {
+			out.Type = genai.TypeObject
+		}
+	}
+	out.Required = stringList(schema["required"])
+	out.PropertyOrdering = stringList(schema["propertyOrdering"])
+
+	switch items := schema["items"].(type) {
+	case map[string]any, bool:
+		out.Items = c.convert(items)
+	case []any:
+		out.Items = &genai.Schema{AnyOf: c.convertList(items)}
+	default:
+		if prefixItems, ok := schema["prefixItems"].([]any); ok {
+			out.Items = &genai.Schema{AnyOf: c.convertList(prefixItems)}
+		}
+	}
+	if out.Items != nil && out.Type == "" {
+		out.Type = genai.TypeArray
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		if alternatives, ok := schema[keyword].([]any); ok {
+			out.AnyOf = append(out.AnyOf, c.convertList(alternatives)...)
+		}
+	}
+
+	return out
+}
+
+// convertBounds converts the numeric, length and size bounds of a schema.
+func (c *schemaConverter) convertBounds(out *genai.Schema, schema map[string]any) {
+	if v, ok := schema["minimum"].(float64); ok {
+		out.Minimum = &v
+	}
+	if v, ok := schema["maximum"].(float64); ok {
+		out.Maximum = &v
+	}
+
+	for keyword, field := range map[string]**int64{
+		"maxItems":      &out.MaxItems,
+		"maxLength":     &out.MaxLength,
+		"maxProperties": &out.MaxProperties,
+		"minItems":      &out.MinItems,
+		"minLength":     &out.MinLength,
+		"minProperties": &out.MinProperties,
+	} {
+		if v, ok := schema[keyword].(float64); ok {
+			n := int64(v)
+			*field = &n
+		}
+	}
+}
+
+// convertEnum converts enum values, which Gemini takes as strings. A null
+// value makes the schema nullable instead.
+func (c *schemaConverter) convertEnum(out *genai.Schema, value any) {
+	values, ok := value.([]any)
+	if !ok {
+		return
+	}
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			nullable := true
+			out.Nullable = &nullable
+		case string:
+			out.Enum = append(out.Enum, v)
+		default:
+			data, _ := json.Marshal(v) // Values decoded from JSON always encode.
+			out.Enum = append(out.Enum, string(data))
+		}
+	}
+
+	switch out.Type {
+	case "":
+		out.Type = genai.TypeString
+	case genai.TypeString:
+	default:
+		out.Format = "enum" // Gemini's marker for enums of other types.
+	}
+}
+
+// convertList converts a list of schemas.
+func (c *schemaConverter) convertList(values []any) []*genai.Schema {
+	schemas := make([]*genai.Schema, 0, len(values))
+	for _, v := range values {
+		schemas = append(schemas, c.convert(v))
+	}
+	return schemas
+}
+
+// convertType converts the type keyword: a type name or a list of them.
+func (c *schemaConverter) convertType(out *genai.Schema, value any) {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = []string{v}
+	case []any:
+		names = stringList(v)
+	default:
+		return
+	}
+
+	var types []genai.Type
+	for _, name := range names {
+		if name == "null" {
+			nullable := true
+			out.Nullable = &nullable
+			continue
+		}
+		if t, ok := schemaTypes[name]; ok {
+			types = append(types, t)
+		}
+	}
+
+	switch len(types) {
+	case 0:
+	case 1:
+		out.Type = types[0]
+	default:
+		for _, t := range types {
+			out.AnyOf = append(out.AnyOf, &genai.Schema{Type: t})
+		}
+	}
+}
+
+// resolve inlines the definition a $ref points to. The description of the
+// referencing schema, if any, replaces the definition's.
+func (c *schemaConverter) resolve(ref string, schema map[string]any) *genai.Schema {
+	var name string
+	for _, prefix := range refPrefixes {
+		if n, ok := strings.CutPrefix(ref, prefix); ok {
+			name = n
+			break
+		}
+	}
+
+	def, ok := c.defs[name]
+	if !ok || c.resolving[name] {
+		// Remote and recursive references cannot be inlined.
+		return &genai.Schema{Type: genai.TypeObject}
+	}
+
+	c.resolving[name] = true
+	out := c.convert(def)
+	delete(c.resolving, name)
+
+	if description, ok := schema["description"].(string); ok {
+		copied := *out
+		copied.Description = description
+		out = &copied
+	}
+	return out
+}
+
+// convertSchema converts a JSON Schema to a typed Gemini schema. It returns
+// false if the schema cannot be decoded. Features the typed schema lacks
+// fall back as follows:
+//   - $ref is inlined from $defs or definitions; a recursive reference
+//     becomes an object without properties.
+//   - A type list becomes a nullable type, or anyOf the types.
+//   - oneOf becomes anyOf, and a tuple of items becomes items that match
+//     any of them.
+//   - Enum values that are not strings are sent as their JSON encoding.
+//   - Other keywords, such as additionalProperties, are dropped.
+func convertSchema(parameters map[string]any) (*genai.Schema, bool) {
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, false
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, false
+	}
+
+	c := &schemaConverter{defs: map[string]any{}, resolving: map[string]bool{}}
+	for _, keyword := range []string{"definitions", "$defs"} {
+		if defs, ok := schema[keyword].(map[string]any); ok {
+			maps.Copy(c.defs, defs)
+		}
+	}
+
+	return c.convert(schema), true
+}
+
+// stringList returns the strings in a decoded JSON array.
+func stringList(value any) []string {
+	values, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// typedSchemas reports whether the request asks for typed tool schemas.
+func typedSchemas(params providers.CompletionParams) (bool, error) {
+	v, ok := params.Extra[ExtraTypedSchemas]
+	if !ok {
+		return false, nil
+	}
+
+	typed, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s: expected bool, got %T", ExtraTypedSchemas, v)
+	}
+
+	return typed, nil
+}
@@ -0,0 +1,186 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestConvertSchema(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(v bool) *bool { return &v }
+	count := int64(1)
+	minimum := 0.0
+
+	tests := []struct {
+		name   string
+		schema map[string]any
+		want   *genai.Schema
+	}{
+		{
+			name: "converts the typed subset",
+			schema: map[string]any{
+				"type":        "object",
+				"description": "A search.",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+					"limit": map[string]any{"type": "integer", "minimum": 0},
+					"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+				"required": []string{"query"},
+			},
+			want: &genai.Schema{
+				Type:        genai.TypeObject,
+				Description: "A search.",
+				Properties: map[string]*genai.Schema{
+					"query": {Type: genai.TypeString, MinLength: &count, Pattern: "^[a-z]+$"},
+					"limit": {Type: genai.TypeInteger, Minimum: &minimum},
+					"tags":  {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+				},
+				Required: []string{"query"},
+			},
+		},
+		{
+			name: "turns type lists into nullable types or anyOf",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"unit": map[string]any{"type": []string{"string", "null"}},
+					"id":   map[string]any{"type": []string{"string", "integer"}},
+				},
+			},
+			want: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"unit": {Type: genai.TypeString, Nullable: ptr(true)},
+					"id":   {AnyOf: []*genai.Schema{{Type: genai.TypeString}, {Type: genai.TypeInteger}}},
+				},
+			},
+		},
+		{
+			name: "inlines references and drops unsupported keywords",
+			schema: map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"home": map[string]any{"$ref": "#/$defs/place", "description": "Where they live."},
+					"next": map[string]any{"$ref": "#/$defs/node"},
+				},
+				"$defs": map[string]any{
+					"place": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"city": map[string]any{"type": "string"}},
+					},
+					"node": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"next": map[string]any{"$ref": "#/$defs/node"}},
+					},
+				},
+			},
+			want: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"home": {
+						Type:        genai.TypeObject,
+						Description: "Where they live.",
+						Properties:  map[string]*genai.Schema{"city": {Type: genai.TypeString}},
+					},
+					"next": {
+						Type:       genai.TypeObject,
+						Properties: map[string]*genai.Schema{"next": {Type: genai.TypeObject}},
+					},
+				},
+			},
+		},
+		{
+			name: "converts oneOf, tuples and enums",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"when":  map[string]any{"oneOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "number"}}},
+					"pair":  map[string]any{"type": "array", "prefixItems": []any{map[string]any{"type": "string"}, true}},
+					"level": map[string]any{"type": "integer", "enum": []any{1, 2, nil}},
+					"mode":  map[string]any{"enum": []string{"fast", "slow"}},
+				},
+			},
+			want: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"when": {AnyOf: []*genai.Schema{{Type: genai.TypeString}, {Type: genai.TypeNumber}}},
+					"pair": {Type: genai.TypeArray, Items: &genai.Schema{AnyOf: []*genai.Schema{{Type: genai.TypeString}, {}}}},
+					"level": {
+						Type:     genai.TypeInteger,
+						Format:   "enum",
+						Enum:     []string{"1", "2"},
+						Nullable: ptr(true),
+					},
+					"mode": {Type: genai.TypeString, Enum: []string{"fast", "slow"}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := convertSchema(tc.schema)
+			require.True(t, ok)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConvertParamsTypedSchemas(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("test-key"))
+	require.NoError(t, err)
+
+	convert := func(extra map[string]any) (*genai.FunctionDeclaration, error) {
+		_, cfg, err := provider.convertParams(providers.CompletionParams{
+			Model:    "gemini-test",
+			Messages: testutil.SimpleMessages(),
+			Tools:    []providers.Tool{testutil.WeatherTool()},
+			Extra:    extra,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cfg.Tools[0].FunctionDeclarations[0], nil
+	}
+
+	t.Run("sends JSON Schema by default", func(t *testing.T) {
+		t.Parallel()
+
+		decl, err := convert(nil)
+		require.NoError(t, err)
+		require.NotNil(t, decl.ParametersJsonSchema)
+		require.Nil(t, decl.Parameters)
+	})
+
+	t.Run("sends a typed schema when asked", func(t *testing.T) {
+		t.Parallel()
+
+		decl, err := convert(map[string]any{ExtraTypedSchemas: true})
+		require.NoError(t, err)
+		require.Nil(t, decl.ParametersJsonSchema)
+		require.Equal(t, genai.TypeObject, decl.Parameters.Type)
+		require.Equal(t, []string{"location"}, decl.Parameters.Required)
+		require.Equal(t, genai.TypeString, decl.Parameters.Properties["location"].Type)
+	})
+
+	t.Run("rejects a value that is not a bool", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convert(map[string]any{ExtraTypedSchemas: "yes"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
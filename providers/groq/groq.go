@@ -3,6 +3,8 @@
 package groq
 
 import (
+	"context"
+
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/providers"
 	"github.com/mozilla-ai/any-llm-go/providers/openai"
@@ -16,17 +18,15 @@ const (
 )
 
 // Object type constants for API responses.
-const (
-	objectChatCompletion      = "chat.completion"
-	objectChatCompletionChunk = "chat.completion.chunk"
-	objectList                = "list"
-)
+const objectList = "list"
 
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
 
@@ -36,16 +36,26 @@ type Provider struct {
 	*openai.CompatibleProvider
 }
 
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new Groq provider.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := openai.NewCompatible(openai.CompatibleConfig{
-		APIKeyEnvVar:   envAPIKey,
-		BaseURLEnvVar:  "",
-		Capabilities:   groqCapabilities(),
-		DefaultAPIKey:  "",
-		DefaultBaseURL: defaultBaseURL,
-		Name:           providerName,
-		RequireAPIKey:  true,
+		APIKeyEnvVar:        envAPIKey,
+		BaseURLEnvVar:       "",
+		Capabilities:        groqCapabilities(),
+		DefaultAPIKey:       "",
+		DefaultBaseURL:      defaultBaseURL,
+		InlineThinking:      true, // Groq returns DeepSeek-R1 and Qwen reasoning in <think> tags.
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    nil,
+		RequireAPIKey:       true,
 	}, opts...)
 	if err != nil {
 		return nil, err
@@ -57,12 +67,15 @@ func New(opts ...config.Option) (*Provider, error) {
 // groqCapabilities returns the capabilities for the Groq provider.
 func groqCapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     false, // Groq doesn't support image inputs.
-		CompletionPDF:       false,
-		CompletionReasoning: false, // Groq doesn't support reasoning parameters.
-		CompletionStreaming: true,
-		Embedding:           false, // Groq doesn't host embedding models.
-		ListModels:          true,
+		Completion:           true,
+		CompletionImage:      false, // Groq doesn't support image inputs.
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  false, // Groq doesn't support reasoning parameters.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            false, // Groq doesn't host embedding models.
+		ListModels:           true,
 	}
 }
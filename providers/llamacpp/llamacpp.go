@@ -6,7 +6,17 @@
 package llamacpp
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/chattemplate"
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/llamaserver"
 	"github.com/mozilla-ai/any-llm-go/providers"
 	"github.com/mozilla-ai/any-llm-go/providers/openai"
 )
@@ -18,46 +28,296 @@ const (
 	defaultAPIKey  = "llama-cpp-dummy-key"
 )
 
+// Extra configuration keys.
+const (
+	extraChatTemplate = "chat_template"
+)
+
+// Object type constants.
+const (
+	objectChatCompletion      = "chat.completion"
+	objectChatCompletionChunk = "chat.completion.chunk"
+)
+
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelCardProvider  = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
+	_ providers.TextCompleter      = (*Provider)(nil)
+	_ providers.TokenCounter       = (*Provider)(nil)
 )
 
 // Provider is a thin wrapper around the generic OpenAI-compatible provider,
 // pre-configured with llama.cpp defaults and quirks.
 type Provider struct {
 	*openai.CompatibleProvider
+	server   *llamaserver.Client
+	template *chattemplate.Template
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
 // New returns a Provider that communicates with a llama.cpp server.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := openai.NewCompatible(openai.CompatibleConfig{
-		APIKeyEnvVar:   "", // we don't read from env by default
-		BaseURLEnvVar:  "",
-		Capabilities:   llamacppCapabilities(),
-		DefaultAPIKey:  defaultAPIKey,
-		DefaultBaseURL: defaultBaseURL,
-		Name:           providerName,
-		RequireAPIKey:  false, // llama.cpp doesn't care
+		APIKeyEnvVar:        "", // we don't read from env by default
+		BaseURLEnvVar:       "",
+		Capabilities:        llamacppCapabilities(),
+		DefaultAPIKey:       defaultAPIKey,
+		DefaultBaseURL:      defaultBaseURL,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    nil,
+		RequireAPIKey:       false, // llama.cpp doesn't care
 	}, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Provider{CompatibleProvider: base}, nil
+	// Resolve the server location for the native (non-OpenAI) endpoints.
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	baseURL, err := cfg.ResolveBaseURL("", defaultBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var template *chattemplate.Template
+	if v, ok := cfg.ExtraValue(extraChatTemplate); ok {
+		tmpl, _ := v.(chattemplate.Template)
+		template = &tmpl
+	}
+
+	return &Provider{
+		CompatibleProvider: base,
+		server:             llamaserver.New(providerName, baseURL, cfg.ResolveAPIKey(""), cfg.HTTPClient()),
+		template:           template,
+	}, nil
+}
+
+// WithChatTemplate renders chat messages with tmpl on the client and sends the
+// prompt to the server's raw /completion endpoint, bypassing the server's own
+// chat templating. Use it when the template the server applies is wrong for
+// the loaded model. Tools and response formats cannot be used with it.
+func WithChatTemplate(tmpl chattemplate.Template) config.Option {
+	return func(c *config.Config) error {
+		if len(tmpl.Turns) == 0 {
+			return fmt.Errorf("chat template %q has no turns", tmpl.Name)
+		}
+		return config.WithExtra(extraChatTemplate, tmpl)(c)
+	}
+}
+
+// Completion performs a chat completion request. With a client-side chat
+// template, the rendered prompt is completed by the /completion endpoint.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if p.template == nil {
+		return p.CompatibleProvider.Completion(ctx, params)
+	}
+
+	textParams, err := p.textParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.server.Complete(ctx, textParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTextCompletion(resp), nil
+}
+
+// CompletionStream performs a streaming chat completion request. With a
+// client-side chat template, the rendered prompt is streamed from the
+// /completion endpoint.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	if p.template == nil {
+		return p.CompatibleProvider.CompletionStream(ctx, params)
+	}
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		textParams, err := p.textParams(params)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		id := generateID()
+		created := time.Now().Unix()
+
+		deltas, deltaErrs := p.server.CompleteStream(ctx, textParams)
+		for delta := range deltas {
+			select {
+			case chunks <- convertTextDelta(delta, id, created):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-deltaErrs; err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Detokenize converts tokens back into text using the loaded model's tokenizer.
+func (p *Provider) Detokenize(
+	ctx context.Context,
+	params providers.DetokenizeParams,
+) (*providers.DetokenizeResponse, error) {
+	return p.server.Detokenize(ctx, params)
+}
+
+// DryRun returns the body Completion would send for params, without sending it.
+// With a client-side chat template it is the /completion request body.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(ctx context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	if p.template == nil {
+		return p.CompatibleProvider.DryRun(ctx, params)
+	}
+
+	textParams, err := p.textParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.server.CompletionBody(textParams)
+}
+
+// ModelCard reads the loaded model's context size, chat template and
+// quantization from the server.
+func (p *Provider) ModelCard(ctx context.Context, model string) (*providers.ModelCard, error) {
+	return p.server.ModelCard(ctx, model)
+}
+
+// TextCompletion completes a raw prompt with the /completion endpoint, which
+// applies no chat template.
+func (p *Provider) TextCompletion(
+	ctx context.Context,
+	params providers.TextCompletionParams,
+) (*providers.TextCompletion, error) {
+	return p.server.Complete(ctx, params)
+}
+
+// Tokenize converts text into tokens using the loaded model's tokenizer.
+// The number of tokens is an exact count for context management.
+func (p *Provider) Tokenize(
+	ctx context.Context,
+	params providers.TokenizeParams,
+) (*providers.TokenizeResponse, error) {
+	return p.server.Tokenize(ctx, params)
+}
+
+// textParams renders params with the client-side chat template as text
+// completion params.
+func (p *Provider) textParams(params providers.CompletionParams) (providers.TextCompletionParams, error) {
+	if len(params.Tools) > 0 || params.ResponseFormat != nil {
+		return providers.TextCompletionParams{}, errors.NewInvalidRequestError(
+			providerName,
+			fmt.Errorf("tools and response formats are not supported with a client-side chat template"),
+		)
+	}
+
+	prompt, err := p.template.Render(params.Messages)
+	if err != nil {
+		return providers.TextCompletionParams{}, errors.NewInvalidRequestError(providerName, err)
+	}
+
+	return providers.TextCompletionParams{
+		MaxTokens:   params.MaxTokens,
+		Model:       params.Model,
+		Prompt:      prompt,
+		Seed:        params.Seed,
+		Stop:        params.Stop,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+	}, nil
+}
+
+// convertTextCompletion converts a text completion to a chat completion.
+func convertTextCompletion(resp *providers.TextCompletion) *providers.ChatCompletion {
+	return &providers.ChatCompletion{
+		ID:      generateID(),
+		Object:  objectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []providers.Choice{{
+			Index: 0,
+			Message: providers.Message{
+				Role:    providers.RoleAssistant,
+				Content: resp.Content,
+			},
+			FinishReason: resp.FinishReason,
+		}},
+		Usage: resp.Usage,
+	}
+}
+
+// convertTextDelta converts a streamed text completion delta to a chunk.
+func convertTextDelta(delta providers.TextCompletion, id string, created int64) providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{
+		ID:      id,
+		Object:  objectChatCompletionChunk,
+		Created: created,
+		Model:   delta.Model,
+		Choices: []providers.ChunkChoice{{
+			Index:        0,
+			Delta:        providers.ChunkDelta{Content: delta.Content},
+			FinishReason: delta.FinishReason,
+		}},
+		Usage: delta.Usage,
+	}
+}
+
+// generateID generates a unique ID for responses using crypto/rand.
+func generateID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("chatcmpl-%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
 }
 
 // llamacppCapabilities returns the feature set that a typical recent llama.cpp
-// server actually implements reliably through its /v1 endpoint.
+// server actually implements reliably through its /v1 endpoint. Tools and JSON
+// schemas depend on how the server was started, such as with --jinja; use
+// Probe to detect them.
 func llamacppCapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionStreaming: true,
-		Embedding:           true,
-		ListModels:          true,
+		Completion:           true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: false,
+		CompletionStreaming:  true,
+		CompletionTools:      false,
+		Embedding:            true,
+		ListModels:           true,
 	}
 }
@@ -2,12 +2,17 @@ package llamacpp
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
 	anyllm "github.com/mozilla-ai/any-llm-go"
+	"github.com/mozilla-ai/any-llm-go/chattemplate"
 	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 )
 
@@ -59,6 +64,83 @@ func TestCapabilities(t *testing.T) {
 	require.True(t, caps.ListModels)
 }
 
+// TestChatTemplate checks that a client-side chat template sends the rendered
+// prompt to the raw /completion endpoint.
+func TestChatTemplate(t *testing.T) {
+	t.Parallel()
+
+	const expectedPrompt = "<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/completion", r.URL.Path)
+
+		var req struct {
+			Prompt string `json:"prompt"`
+			Stream bool   `json:"stream"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, expectedPrompt, req.Prompt)
+
+		if req.Stream {
+			_, _ = w.Write([]byte("data: {\"content\":\"Hel\",\"stop\":false}\n\n" +
+				"data: {\"content\":\"lo\",\"stop\":true,\"stop_type\":\"eos\"}\n\n",
+			)) // Write error surfaces in the client.
+			return
+		}
+		_, _ = w.Write([]byte(`{"content":"Hello","stop":true,"stop_type":"eos"}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	p, err := New(anyllm.WithBaseURL(server.URL+"/v1"), WithChatTemplate(chattemplate.ChatML))
+	require.NoError(t, err)
+
+	params := anyllm.CompletionParams{
+		Model:    testModel,
+		Messages: []anyllm.Message{{Role: anyllm.RoleUser, Content: "Hi"}},
+	}
+
+	t.Run("Completion", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := p.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, objectChatCompletion, resp.Object)
+		require.Equal(t, "Hello", resp.Choices[0].Message.Content)
+		require.Equal(t, anyllm.FinishReasonStop, resp.Choices[0].FinishReason)
+	})
+
+	t.Run("CompletionStream", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := p.CompletionStream(context.Background(), params)
+
+		var content strings.Builder
+		for chunk := range chunks {
+			require.Equal(t, objectChatCompletionChunk, chunk.Object)
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, "Hello", content.String())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		t.Parallel()
+
+		body, err := p.DryRun(context.Background(), params)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"prompt"`)
+	})
+
+	t.Run("rejects tools", func(t *testing.T) {
+		t.Parallel()
+
+		withTools := params
+		withTools.Tools = []anyllm.Tool{{Type: "function"}}
+		_, err := p.Completion(context.Background(), withTools)
+		require.ErrorIs(t, err, anyllm.ErrInvalidRequest)
+	})
+}
+
 // TestIntegration runs real calls against a live llama.cpp server.
 //
 // Skipped automatically if no server is responding on the default port.
@@ -4,7 +4,11 @@
 package llamafile
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/internal/llamaserver"
 	"github.com/mozilla-ai/any-llm-go/providers"
 	"github.com/mozilla-ai/any-llm-go/providers/openai"
 )
@@ -20,45 +24,109 @@ const (
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelCardProvider  = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
+	_ providers.TextCompleter      = (*Provider)(nil)
+	_ providers.TokenCounter       = (*Provider)(nil)
 )
 
 // Provider implements the providers.Provider interface for Llamafile.
 // It embeds openai.CompatibleProvider since Llamafile exposes an OpenAI-compatible API.
 type Provider struct {
 	*openai.CompatibleProvider
+	server *llamaserver.Client
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
 // New creates a new Llamafile provider.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := openai.NewCompatible(openai.CompatibleConfig{
-		APIKeyEnvVar:   "", // Llamafile doesn't use an API key env var.
-		BaseURLEnvVar:  envBaseURL,
-		Capabilities:   llamafileCapabilities(),
-		DefaultAPIKey:  defaultAPIKey,
-		DefaultBaseURL: defaultBaseURL,
-		Name:           providerName,
-		RequireAPIKey:  false,
+		APIKeyEnvVar:        "", // Llamafile doesn't use an API key env var.
+		BaseURLEnvVar:       envBaseURL,
+		Capabilities:        llamafileCapabilities(),
+		DefaultAPIKey:       defaultAPIKey,
+		DefaultBaseURL:      defaultBaseURL,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    nil,
+		RequireAPIKey:       false,
 	}, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Provider{CompatibleProvider: base}, nil
+	// Resolve the server location for the native (non-OpenAI) endpoints.
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	baseURL, err := cfg.ResolveBaseURL(envBaseURL, defaultBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		CompatibleProvider: base,
+		server:             llamaserver.New(providerName, baseURL, cfg.ResolveAPIKey(""), cfg.HTTPClient()),
+	}, nil
+}
+
+// Detokenize converts tokens back into text using the loaded model's tokenizer.
+func (p *Provider) Detokenize(
+	ctx context.Context,
+	params providers.DetokenizeParams,
+) (*providers.DetokenizeResponse, error) {
+	return p.server.Detokenize(ctx, params)
+}
+
+// ModelCard reads the loaded model's context size, chat template and
+// quantization from the server.
+func (p *Provider) ModelCard(ctx context.Context, model string) (*providers.ModelCard, error) {
+	return p.server.ModelCard(ctx, model)
+}
+
+// TextCompletion completes a raw prompt with the /completion endpoint, which
+// applies no chat template.
+func (p *Provider) TextCompletion(
+	ctx context.Context,
+	params providers.TextCompletionParams,
+) (*providers.TextCompletion, error) {
+	return p.server.Complete(ctx, params)
+}
+
+// Tokenize converts text into tokens using the loaded model's tokenizer.
+// The number of tokens is an exact count for context management.
+func (p *Provider) Tokenize(
+	ctx context.Context,
+	params providers.TokenizeParams,
+) (*providers.TokenizeResponse, error) {
+	return p.server.Tokenize(ctx, params)
 }
 
 // llamafileCapabilities returns the capabilities for the Llamafile provider.
 func llamafileCapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     true, // Depends on the model loaded.
-		CompletionPDF:       false,
-		CompletionReasoning: false, // Llamafile doesn't support reasoning natively.
-		CompletionStreaming: true,
-		Embedding:           true,
-		ListModels:          true,
+		Completion:           true,
+		CompletionImage:      true, // Depends on the model loaded.
+		CompletionJSONObject: false,
+		CompletionJSONSchema: false,
+		CompletionPDF:        false,
+		CompletionReasoning:  false, // Llamafile doesn't support reasoning natively.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true,
+		ListModels:           true,
 	}
 }
@@ -2,6 +2,8 @@ package llamafile
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -77,6 +79,41 @@ func TestProviderName(t *testing.T) {
 	require.Equal(t, "llamafile", provider.Name())
 }
 
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/tokenize", r.URL.Path)
+		_, _ = w.Write([]byte(`{"tokens":[15043,3186]}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+	require.NoError(t, err)
+
+	resp, err := provider.Tokenize(context.Background(), providers.TokenizeParams{Content: "Hello world"})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Count())
+}
+
+func TestModelCard(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/props", r.URL.Path)
+		_, _ = w.Write([]byte(`{"default_generation_settings":{"n_ctx":4096},"model_path":"TinyLlama.Q5_K_M.gguf"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+	require.NoError(t, err)
+
+	card, err := provider.ModelCard(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, 4096, card.ContextLength)
+	require.Equal(t, "Q5_K_M", card.Quantization)
+}
+
 // Integration tests - only run if Llamafile is available.
 
 func TestIntegrationCompletion(t *testing.T) {
@@ -257,6 +294,23 @@ func TestIntegrationCompletionWithTools(t *testing.T) {
 	}
 }
 
+func TestIntegrationTokenizeRoundTrip(t *testing.T) {
+	t.Parallel()
+	skipIfLlamafileUnavailable(t)
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tokens, err := provider.Tokenize(ctx, providers.TokenizeParams{Content: "Hello, world!"})
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens.Tokens)
+
+	text, err := provider.Detokenize(ctx, providers.DetokenizeParams{Tokens: tokens.Tokens})
+	require.NoError(t, err)
+	require.Contains(t, text.Content, "Hello, world!")
+}
+
 // skipIfLlamafileUnavailable skips the test if Llamafile is not running.
 func skipIfLlamafileUnavailable(t *testing.T) {
 	t.Helper()
@@ -0,0 +1,31 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+// paramMessages is the name of the messages parameter, as reported in
+// validation errors.
+const paramMessages = "messages"
+
+// ValidateMessages checks that every message has a known role, so that
+// providers which convert messages one role at a time fail the request
+// instead of silently dropping a message, and with it a tool call or its
+// result. It returns an *errors.InvalidParamError naming the message.
+func ValidateMessages(provider string, messages []Message) error {
+	for i, msg := range messages {
+		switch msg.Role {
+		case RoleAssistant, RoleSystem, RoleTool, RoleUser:
+		default:
+			return errors.NewInvalidParamError(
+				provider,
+				paramMessages,
+				fmt.Errorf("messages[%d]: unknown role %q", i, msg.Role),
+			)
+		}
+	}
+
+	return nil
+}
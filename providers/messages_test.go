@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+func TestValidateMessages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		messages []Message
+		wantErr  string
+	}{
+		{
+			name: "accepts every known role",
+			messages: []Message{
+				{Role: RoleSystem, Content: "Be brief."},
+				{Role: RoleUser, Content: "Weather?"},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Function: FunctionCall{Name: "weather"}}}},
+				NewToolResultMessage("call_1", ToolResult{Content: "sunny"}),
+			},
+		},
+		{
+			name:     "accepts no messages",
+			messages: nil,
+		},
+		{
+			name: "rejects an unknown role",
+			messages: []Message{
+				{Role: RoleUser, Content: "Hello"},
+				{Role: "developer", Content: "Be brief."},
+			},
+			wantErr: `messages[1]: unknown role "developer"`,
+		},
+		{
+			name:     "rejects an empty role",
+			messages: []Message{{Content: "Hello"}},
+			wantErr:  `messages[0]: unknown role ""`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateMessages("test", tc.messages)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorContains(t, err, tc.wantErr)
+			var invalidErr *errors.InvalidRequestError
+			require.ErrorAs(t, err, &invalidErr)
+			require.Equal(t, "messages", invalidErr.Param)
+		})
+	}
+}
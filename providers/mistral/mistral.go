@@ -4,9 +4,11 @@ package mistral
 
 import (
 	"context"
+	stderrors "errors"
 	"slices"
 
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
 	"github.com/mozilla-ai/any-llm-go/providers"
 	"github.com/mozilla-ai/any-llm-go/providers/openai"
 )
@@ -23,19 +25,26 @@ const (
 	assistantOKMessage = "OK"
 )
 
-// Object type constants for API responses.
+// OCR request constants.
 const (
-	objectChatCompletion      = "chat.completion"
-	objectChatCompletionChunk = "chat.completion.chunk"
-	objectList                = "list"
+	documentTypeURL = "document_url"
+	paramDocument   = "document"
+	paramModel      = "model"
+	pathOCR         = "ocr"
 )
 
+// Object type constants for API responses.
+const objectList = "list"
+
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DocumentProvider   = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
 
@@ -45,16 +54,49 @@ type Provider struct {
 	*openai.CompatibleProvider
 }
 
+// ocrDocument is the document of an OCR request.
+type ocrDocument struct {
+	DocumentURL string `json:"document_url"`
+	Type        string `json:"type"`
+}
+
+// ocrRequest is the body of an OCR request.
+type ocrRequest struct {
+	Document ocrDocument `json:"document"`
+	Model    string      `json:"model"`
+}
+
+// ocrResponse is the body of an OCR response.
+type ocrResponse struct {
+	Model     string              `json:"model"`
+	Pages     []providers.OCRPage `json:"pages"`
+	UsageInfo *ocrUsage           `json:"usage_info"`
+}
+
+// ocrUsage is the usage of an OCR request.
+type ocrUsage struct {
+	PagesProcessed int `json:"pages_processed"`
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new Mistral provider.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := openai.NewCompatible(openai.CompatibleConfig{
-		APIKeyEnvVar:   envAPIKey,
-		BaseURLEnvVar:  "",
-		Capabilities:   mistralCapabilities(),
-		DefaultAPIKey:  "",
-		DefaultBaseURL: defaultBaseURL,
-		Name:           providerName,
-		RequireAPIKey:  true,
+		APIKeyEnvVar:        envAPIKey,
+		BaseURLEnvVar:       "",
+		Capabilities:        mistralCapabilities(),
+		DefaultAPIKey:       "",
+		DefaultBaseURL:      defaultBaseURL,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    preprocessParams,
+		RequireAPIKey:       true,
 	}, opts...)
 	if err != nil {
 		return nil, err
@@ -63,36 +105,52 @@ func New(opts ...config.Option) (*Provider, error) {
 	return &Provider{CompatibleProvider: base}, nil
 }
 
-// Completion performs a chat completion request.
-// It overrides the base implementation to handle Mistral's API quirks.
-func (p *Provider) Completion(
-	ctx context.Context,
-	params providers.CompletionParams,
-) (*providers.ChatCompletion, error) {
-	params = preprocessParams(params)
-	return p.CompatibleProvider.Completion(ctx, params)
+// OCR reads a document with a Mistral OCR model such as mistral-ocr-latest,
+// returning the text of each page as Markdown.
+// Implements providers.DocumentProvider.
+func (p *Provider) OCR(ctx context.Context, params providers.OCRParams) (*providers.OCRResponse, error) {
+	if params.Model == "" {
+		return nil, errors.NewInvalidParamError(providerName, paramModel, stderrors.New("model is required"))
+	}
+	if params.Document == "" {
+		return nil, errors.NewInvalidParamError(providerName, paramDocument, stderrors.New("document is required"))
+	}
+
+	req := ocrRequest{
+		Document: ocrDocument{DocumentURL: params.Document, Type: documentTypeURL},
+		Model:    params.Model,
+	}
+
+	var resp ocrResponse
+	if err := p.Post(ctx, pathOCR, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return convertOCRResponse(resp), nil
 }
 
-// CompletionStream performs a streaming chat completion request.
-// It overrides the base implementation to handle Mistral's API quirks.
-func (p *Provider) CompletionStream(
-	ctx context.Context,
-	params providers.CompletionParams,
-) (<-chan providers.ChatCompletionChunk, <-chan error) {
-	params = preprocessParams(params)
-	return p.CompatibleProvider.CompletionStream(ctx, params)
+// convertOCRResponse converts an OCR response to the normalized format.
+func convertOCRResponse(resp ocrResponse) *providers.OCRResponse {
+	result := &providers.OCRResponse{Model: resp.Model, Pages: resp.Pages}
+	if resp.UsageInfo != nil {
+		result.Usage = &providers.OCRUsage{PagesProcessed: resp.UsageInfo.PagesProcessed}
+	}
+	return result
 }
 
 // mistralCapabilities returns the capabilities for the Mistral provider.
 func mistralCapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     true, // Pixtral models support vision.
-		CompletionPDF:       false,
-		CompletionReasoning: true, // Magistral models support reasoning.
-		CompletionStreaming: true,
-		Embedding:           true, // mistral-embed model.
-		ListModels:          true,
+		Completion:           true,
+		CompletionImage:      true, // Pixtral models support vision.
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  true, // Magistral models support reasoning.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true, // mistral-embed model.
+		ListModels:           true,
 	}
 }
 
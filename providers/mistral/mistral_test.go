@@ -3,12 +3,15 @@ package mistral
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/conformance"
 	"github.com/mozilla-ai/any-llm-go/errors"
 	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 	"github.com/mozilla-ai/any-llm-go/providers"
@@ -275,245 +278,110 @@ func TestPatchMessages(t *testing.T) {
 	})
 }
 
-// Integration tests - only run if Mistral API key is available.
-
-func TestIntegrationCompletion(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.SimpleMessages(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
-
-	require.NotEmpty(t, resp.ID)
-	require.Equal(t, objectChatCompletion, resp.Object)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
-	require.Equal(t, providers.RoleAssistant, resp.Choices[0].Message.Role)
-}
-
-func TestIntegrationCompletionWithSystemMessage(t *testing.T) {
+func TestOCR(t *testing.T) {
 	t.Parallel()
 
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.MessagesWithSystem(),
-	}
-
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
-
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-	require.NotEmpty(t, resp.Choices[0].Message.Content)
-}
-
-func TestIntegrationCompletionStream(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
-
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.SimpleMessages(),
-		Stream:   true,
-	}
-
-	chunks, errs := provider.CompletionStream(ctx, params)
-
-	var content strings.Builder
-	chunkCount := 0
-
-	for chunk := range chunks {
-		chunkCount++
-		require.Equal(t, objectChatCompletionChunk, chunk.Object)
-		if len(chunk.Choices) > 0 {
-			content.WriteString(chunk.Choices[0].Delta.Content)
-		}
-	}
-
-	err = <-errs
-	require.NoError(t, err)
-
-	require.Greater(t, chunkCount, 0)
-	require.NotEmpty(t, content.String())
-}
-
-func TestIntegrationListModels(t *testing.T) {
-	t.Parallel()
-
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
+	t.Run("reads document pages", func(t *testing.T) {
+		t.Parallel()
 
-	provider, err := New()
-	require.NoError(t, err)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/ocr", r.URL.Path)
+			require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, "mistral-ocr-latest", body["model"])
+			require.Equal(t, map[string]any{
+				"type":         "document_url",
+				"document_url": "data:application/pdf;base64,JVBERi0=",
+			}, body["document"])
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"model":"mistral-ocr-2505","pages":[` +
+				`{"index":0,"markdown":"# Title","images":[]},{"index":1,"markdown":"Body","images":[]}],` +
+				`"usage_info":{"pages_processed":2,"doc_size_bytes":5}}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
 
-	ctx := context.Background()
-	resp, err := provider.ListModels(ctx)
-	require.NoError(t, err)
+		resp, err := provider.OCR(context.Background(), providers.OCRParams{
+			Model:    "mistral-ocr-latest",
+			Document: "data:application/pdf;base64,JVBERi0=",
+		})
+		require.NoError(t, err)
+		require.Equal(t, &providers.OCRResponse{
+			Model: "mistral-ocr-2505",
+			Pages: []providers.OCRPage{{Index: 0, Markdown: "# Title"}, {Index: 1, Markdown: "Body"}},
+			Usage: &providers.OCRUsage{PagesProcessed: 2},
+		}, resp)
+	})
 
-	require.Equal(t, objectList, resp.Object)
-	require.NotEmpty(t, resp.Data)
-}
+	t.Run("converts errors", func(t *testing.T) {
+		t.Parallel()
 
-func TestIntegrationCompletionConversation(t *testing.T) {
-	t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid key"}}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
 
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
+		provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
+		require.NoError(t, err)
 
-	provider, err := New()
-	require.NoError(t, err)
+		_, err = provider.OCR(context.Background(), providers.OCRParams{Model: "m", Document: "https://example.com/a.pdf"})
+		require.ErrorIs(t, err, errors.ErrAuthentication)
+	})
 
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: testutil.ConversationMessages(),
-	}
+	t.Run("requires a model and a document", func(t *testing.T) {
+		t.Parallel()
 
-	resp, err := provider.Completion(ctx, params)
-	require.NoError(t, err)
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
 
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
+		_, err = provider.OCR(context.Background(), providers.OCRParams{Document: "https://example.com/a.pdf"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
 
-	// The model should remember the name "Alice".
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok, "expected string content")
-	require.Contains(t, strings.ToLower(contentStr), "alice")
+		_, err = provider.OCR(context.Background(), providers.OCRParams{Model: "mistral-ocr-latest"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
 }
 
-func TestIntegrationCompletionWithTools(t *testing.T) {
-	t.Parallel()
+// Integration tests - only run if Mistral API key is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
 
-	if testutil.SkipIfNoAPIKey(providerName) {
-		t.Skip("MISTRAL_API_KEY not set")
-	}
+	const chunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+		`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`
 
-	provider, err := New()
-	require.NoError(t, err)
+	server, released := testutil.NewStallingServer(t, "text/event-stream", strings.Repeat("data: "+chunkJSON+"\n\n", 2))
 
-	ctx := context.Background()
-	params := providers.CompletionParams{
-		Model:      testutil.TestModel(providerName),
-		Messages:   testutil.ToolCallMessages(),
-		Tools:      []providers.Tool{testutil.WeatherTool()},
-		ToolChoice: "auto",
-	}
-
-	resp, err := provider.Completion(ctx, params)
+	provider, err := New(config.WithAPIKey("test-key"), config.WithBaseURL(server.URL))
 	require.NoError(t, err)
 
-	require.NotEmpty(t, resp.ID)
-	require.Len(t, resp.Choices, 1)
-
-	// Should either have tool calls or content.
-	choice := resp.Choices[0]
-	hasToolCalls := len(choice.Message.ToolCalls) > 0
-	hasContent := choice.Message.ContentString() != ""
-	require.True(t, hasToolCalls || hasContent, "Expected tool calls or content")
-
-	if hasToolCalls {
-		require.Equal(t, "get_weather", choice.Message.ToolCalls[0].Function.Name)
-	}
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "m",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
 }
 
-func TestIntegrationAgentLoop(t *testing.T) {
+func TestIntegrationConformance(t *testing.T) {
 	t.Parallel()
 
 	if testutil.SkipIfNoAPIKey(providerName) {
 		t.Skip("MISTRAL_API_KEY not set")
 	}
 
-	provider, err := New()
-	require.NoError(t, err)
-
-	ctx := context.Background()
-	tools := []providers.Tool{testutil.WeatherTool()}
-
-	// Step 1: Send initial message asking about weather.
-	messages := []providers.Message{
-		{Role: providers.RoleUser, Content: "What is the weather in Paris? Use the get_weather tool."},
-	}
-
-	resp, err := provider.Completion(ctx, providers.CompletionParams{
-		Model:      testutil.TestModel(providerName),
-		Messages:   messages,
-		Tools:      tools,
-		ToolChoice: "auto",
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Step 2: Verify the model called the tool.
-	require.NotEmpty(t, resp.Choices[0].Message.ToolCalls, "expected model to call get_weather tool")
-	require.Equal(t, providers.FinishReasonToolCalls, resp.Choices[0].FinishReason)
-
-	tc := resp.Choices[0].Message.ToolCalls[0]
-	require.Equal(t, "get_weather", tc.Function.Name)
-	require.NotEmpty(t, tc.ID)
-
-	// Step 3: Parse the arguments - this verifies parameters were sent correctly.
-	var args struct {
-		Location string `json:"location"`
-	}
-	err = json.Unmarshal([]byte(tc.Function.Arguments), &args)
-	require.NoError(t, err, "tool arguments should be valid JSON")
-	require.NotEmpty(t, args.Location, "location argument should be present")
-	require.Contains(t, strings.ToLower(args.Location), "paris")
-
-	// Step 4: Add assistant message with tool call and tool result.
-	messages = append(messages, resp.Choices[0].Message)
-	messages = append(messages, providers.Message{
-		Role:       providers.RoleTool,
-		Content:    testutil.MockWeatherResult(t, args.Location),
-		ToolCallID: tc.ID,
-	})
-
-	// Step 5: Continue conversation with tool result.
-	resp, err = provider.Completion(ctx, providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: messages,
-		Tools:    tools,
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Step 6: Verify the model produced a final response.
-	require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok, "expected string content in final response")
-	require.NotEmpty(t, contentStr)
+	conformance.Run(t, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	}, testutil.TestModel(providerName))
 }
 
-func TestIntegrationAgentLoopMultipleParams(t *testing.T) {
+func TestIntegrationListModels(t *testing.T) {
 	t.Parallel()
 
 	if testutil.SkipIfNoAPIKey(providerName) {
@@ -524,61 +392,11 @@ func TestIntegrationAgentLoopMultipleParams(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := context.Background()
-	tools := []providers.Tool{testutil.NewTestCalculatorTool(t)}
-
-	// Ask the model to use the calculator with specific values.
-	messages := []providers.Message{
-		{Role: providers.RoleUser, Content: "Use the calculate tool to add 15 and 27 together."},
-	}
-
-	resp, err := provider.Completion(ctx, providers.CompletionParams{
-		Model:      testutil.TestModel(providerName),
-		Messages:   messages,
-		Tools:      tools,
-		ToolChoice: "auto",
-	})
-	require.NoError(t, err)
-	require.Len(t, resp.Choices, 1)
-
-	// Verify the model called the tool with correct parameters.
-	require.NotEmpty(t, resp.Choices[0].Message.ToolCalls, "expected model to call calculate tool")
-
-	tc := resp.Choices[0].Message.ToolCalls[0]
-	require.Equal(t, "calculate", tc.Function.Name)
-
-	// Parse and verify all required parameters are present.
-	var args struct {
-		A         float64 `json:"a"`
-		B         float64 `json:"b"`
-		Operation string  `json:"operation"`
-	}
-	err = json.Unmarshal([]byte(tc.Function.Arguments), &args)
-	require.NoError(t, err, "tool arguments should be valid JSON")
-
-	// Verify the parameters - this catches "wrong order" bugs.
-	require.Equal(t, 15.0, args.A, "first operand should be 15")
-	require.Equal(t, 27.0, args.B, "second operand should be 27")
-	require.Equal(t, "add", args.Operation, "operation should be 'add'")
-
-	// Complete the agent loop with tool result.
-	messages = append(messages, resp.Choices[0].Message)
-	messages = append(messages, providers.Message{
-		Role:       providers.RoleTool,
-		Content:    testutil.MockCalculatorResult(t, args.A, args.B, args.Operation),
-		ToolCallID: tc.ID,
-	})
-
-	resp, err = provider.Completion(ctx, providers.CompletionParams{
-		Model:    testutil.TestModel(providerName),
-		Messages: messages,
-		Tools:    tools,
-	})
+	resp, err := provider.ListModels(ctx)
 	require.NoError(t, err)
 
-	// Verify final response mentions the result.
-	contentStr, ok := resp.Choices[0].Message.Content.(string)
-	require.True(t, ok)
-	require.Contains(t, contentStr, "42")
+	require.Equal(t, objectList, resp.Object)
+	require.NotEmpty(t, resp.Data)
 }
 
 func TestIntegrationCompletionReasoning(t *testing.T) {
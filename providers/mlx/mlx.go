@@ -0,0 +1,96 @@
+// Package mlx provides a provider for mlx_lm.server, the OpenAI-compatible
+// server of MLX LM, which runs models on Apple silicon.
+//
+// The server needs no API key. Models are named by their Hugging Face repository,
+// such as "mlx-community/Llama-3.2-3B-Instruct-4bit", or by a local path, and
+// the server loads a requested model on first use. DefaultModel, which is also
+// used when no model is given, names the model the server was started with.
+package mlx
+
+import (
+	"context"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// DefaultModel names the model mlx_lm.server was started with, using --model.
+const DefaultModel = "default_model"
+
+// Provider configuration constants.
+const (
+	defaultAPIKey  = "mlx" // Dummy key; mlx_lm.server doesn't require auth.
+	defaultBaseURL = "http://127.0.0.1:8080/v1"
+	envBaseURL     = "MLX_BASE_URL"
+	providerName   = "mlx"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
+	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Provider implements the providers.Provider interface for mlx_lm.server.
+// It embeds openai.CompatibleProvider since the server exposes an OpenAI-compatible API.
+type Provider struct {
+	*openai.CompatibleProvider
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+// New creates a new MLX LM provider. The base URL defaults to the server's
+// default address, or the MLX_BASE_URL environment variable when set.
+func New(opts ...config.Option) (*Provider, error) {
+	base, err := openai.NewCompatible(openai.CompatibleConfig{
+		APIKeyEnvVar:        "", // mlx_lm.server doesn't use an API key.
+		BaseURLEnvVar:       envBaseURL,
+		Capabilities:        mlxCapabilities(),
+		DefaultAPIKey:       defaultAPIKey,
+		DefaultBaseURL:      defaultBaseURL,
+		InlineThinking:      true, // Reasoning models such as Qwen3 think in <think> tags.
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    preprocessParams,
+		RequireAPIKey:       false,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{CompatibleProvider: base}, nil
+}
+
+// mlxCapabilities returns the capabilities for the MLX LM provider.
+func mlxCapabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      false, // Vision models are served by mlx-vlm instead.
+		CompletionJSONObject: false,
+		CompletionJSONSchema: false,
+		CompletionPDF:        false,
+		CompletionReasoning:  false, // Reasoning is inline; there is no effort parameter.
+		CompletionStreaming:  true,
+		CompletionTools:      true, // Requires a model whose chat template supports tools.
+		Embedding:            false,
+		ListModels:           true, // Lists the models in the local Hugging Face cache.
+	}
+}
+
+// preprocessParams asks for the server's default model when none is given.
+func preprocessParams(params providers.CompletionParams) providers.CompletionParams {
+	if params.Model == "" {
+		params.Model = DefaultModel
+	}
+	return params
+}
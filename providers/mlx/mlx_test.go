@@ -0,0 +1,115 @@
+package mlx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestNew(t *testing.T) {
+	// Note: Not using t.Parallel() here because child test uses t.Setenv.
+
+	t.Run("creates provider with default settings", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+		require.NotNil(t, provider)
+		require.Equal(t, providerName, provider.Name())
+	})
+
+	t.Run("creates provider with custom base URL", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithBaseURL("http://localhost:8081/v1"))
+		require.NoError(t, err)
+		require.NotNil(t, provider)
+	})
+
+	t.Run("creates provider from MLX_BASE_URL environment variable", func(t *testing.T) {
+		t.Setenv("MLX_BASE_URL", "http://custom-host:8080/v1")
+
+		provider, err := New()
+		require.NoError(t, err)
+		require.NotNil(t, provider)
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+
+	require.True(t, caps.Completion)
+	require.True(t, caps.CompletionStreaming)
+	require.True(t, caps.CompletionTools)
+	require.False(t, caps.CompletionReasoning)
+	require.False(t, caps.CompletionImage)
+	require.False(t, caps.CompletionPDF)
+	require.False(t, caps.Embedding)
+	require.True(t, caps.ListModels)
+}
+
+func TestProviderName(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+	require.Equal(t, "mlx", provider.Name())
+}
+
+func TestCompletionModel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		model string
+		want  string
+	}{
+		{name: "defaults to the server's model", model: "", want: DefaultModel},
+		{name: "keeps a repository name", model: "mlx-community/Qwen3-4B-4bit", want: "mlx-community/Qwen3-4B-4bit"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Model string `json:"model"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&body) // A bad body fails the assertion below.
+				got = body.Model
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"id":"chatcmpl-1","object":"chat.completion","model":%q,`+
+					`"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`,
+					body.Model) // Write error surfaces in the client.
+			}))
+			t.Cleanup(server.Close)
+
+			provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+			require.NoError(t, err)
+
+			resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+				Model:    tc.model,
+				Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, "Hi", resp.Choices[0].Message.ContentString())
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package ollama
 
 import (
+	"cmp"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -9,6 +10,8 @@ import (
 	stderrors "errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/thinktag"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
@@ -33,9 +37,18 @@ const (
 	doneReasonStop   = "stop"
 )
 
+// Runtime option keys accepted in config Extra (via WithKeepAlive, WithNumCtx
+// and WithNumGPU) or per request in CompletionParams.Extra.
+const (
+	ExtraKeepAlive = "keep_alive"
+	ExtraNumCtx    = "num_ctx"
+	ExtraNumGPU    = "num_gpu"
+)
+
 // Ollama option keys.
 const (
 	optionNumCtx      = "num_ctx"
+	optionNumGPU      = "num_gpu"
 	optionNumPredict  = "num_predict"
 	optionSeed        = "seed"
 	optionStop        = "stop"
@@ -71,23 +84,31 @@ const (
 	objectModel               = "model"
 )
 
-// Thinking tag constants.
+// Model metadata keys and capabilities reported by /api/show.
 const (
-	thinkingTagClose = "</think>"
-	thinkingTagOpen  = "<think>"
+	capabilityCompletion      = "completion"
+	capabilityEmbedding       = "embedding"
+	capabilityThinking        = "thinking"
+	capabilityTools           = "tools"
+	capabilityVision          = "vision"
+	modelInfoArchitecture     = "general.architecture"
+	modelInfoContextLengthKey = ".context_length"
 )
 
 // Content part constants.
 const (
 	contentTypeImageURL = "image_url"
+	contentTypeText     = "text"
 	dataImagePrefix     = "data:image/"
 )
 
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelCardProvider  = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
@@ -105,6 +126,14 @@ type streamState struct {
 	created   int64
 	content   strings.Builder
 	reasoning strings.Builder
+	think     *thinktag.Stream // Separates reasoning models inline in <think> tags.
+	toolCalls bool             // Whether any chunk carried tool calls.
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
 }
 
 // New creates a new Ollama provider.
@@ -135,17 +164,48 @@ func New(opts ...config.Option) (*Provider, error) {
 	}, nil
 }
 
+// WithKeepAlive sets how long Ollama keeps the model loaded after a request.
+// A negative duration keeps the model loaded indefinitely; zero unloads it immediately.
+func WithKeepAlive(d time.Duration) config.Option {
+	return config.WithExtra(ExtraKeepAlive, d)
+}
+
+// WithNumCtx sets the context window size, overriding the provider default.
+func WithNumCtx(n int) config.Option {
+	return func(c *config.Config) error {
+		if n <= 0 {
+			return fmt.Errorf("num_ctx must be positive, got %d", n)
+		}
+		return config.WithExtra(ExtraNumCtx, n)(c)
+	}
+}
+
+// WithNumGPU sets the number of model layers to offload to the GPU.
+// Zero runs the model entirely on the CPU.
+func WithNumGPU(n int) config.Option {
+	return func(c *config.Config) error {
+		if n < 0 {
+			return fmt.Errorf("num_gpu must not be negative, got %d", n)
+		}
+		return config.WithExtra(ExtraNumGPU, n)(c)
+	}
+}
+
 // Capabilities returns the provider's capabilities.
 func (p *Provider) Capabilities() providers.Capabilities {
-	return providers.Capabilities{
-		Completion:          true,
-		CompletionStreaming: true,
-		CompletionReasoning: true,
-		CompletionImage:     true,
-		CompletionPDF:       false,
-		Embedding:           true,
-		ListModels:          true,
+	caps := providers.Capabilities{
+		Completion:           true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		CompletionReasoning:  true,
+		CompletionImage:      true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		Embedding:            true,
+		ListModels:           true,
 	}
+	return providers.OverrideCapabilities(caps, p.config)
 }
 
 // Completion performs a chat completion request.
@@ -153,14 +213,17 @@ func (p *Provider) Completion(
 	ctx context.Context,
 	params providers.CompletionParams,
 ) (*providers.ChatCompletion, error) {
-	req := p.convertParams(params)
+	req, err := p.convertParams(params)
+	if err != nil {
+		return nil, err
+	}
 
 	// Disable streaming for non-stream requests.
 	stream := false
 	req.Stream = &stream
 
 	var response api.ChatResponse
-	err := p.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+	err = p.client.Chat(ctx, req, func(resp api.ChatResponse) error {
 		response = resp
 		return nil
 	})
@@ -168,7 +231,7 @@ func (p *Provider) Completion(
 		return nil, p.ConvertError(err)
 	}
 
-	return convertResponse(&response), nil
+	return convertResponse(&response, p.config.ReasoningPolicy), nil
 }
 
 // CompletionStream performs a streaming chat completion request.
@@ -183,14 +246,27 @@ func (p *Provider) CompletionStream(
 		defer close(chunks)
 		defer close(errs)
 
-		req := p.convertParams(params)
-		state := newStreamState()
+		req, err := p.convertParams(params)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		state := newStreamState(p.config.ReasoningPolicy)
 
-		err := p.client.Chat(ctx, req, func(resp api.ChatResponse) error {
-			chunk := state.handleChunk(&resp)
-			chunks <- chunk
-			return nil
+		err = p.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+			select {
+			case chunks <- state.handleChunk(&resp):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		})
+		if ctx.Err() != nil {
+			// The stream was cancelled. The Ollama client drops read errors, so err may be nil.
+			errs <- ctx.Err()
+			return
+		}
 		if err != nil {
 			errs <- p.ConvertError(err)
 		}
@@ -238,6 +314,25 @@ func (p *Provider) ConvertError(err error) error {
 	return errors.NewProviderError(providerName, err)
 }
 
+// DryRun returns the body Completion would send for params, without sending it.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(_ context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	req, err := p.convertParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := false
+	req.Stream = &stream
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
 // Embedding generates embeddings for the given input.
 func (p *Provider) Embedding(
 	ctx context.Context,
@@ -248,6 +343,14 @@ func (p *Provider) Embedding(
 		Input: params.Input,
 	}
 
+	if v, ok := p.config.ExtraValue(ExtraKeepAlive); ok {
+		keepAlive, err := toDuration(v)
+		if err != nil {
+			return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("%s: %w", ExtraKeepAlive, err))
+		}
+		req.KeepAlive = &api.Duration{Duration: keepAlive}
+	}
+
 	resp, err := p.client.Embed(ctx, req)
 	if err != nil {
 		return nil, p.ConvertError(err)
@@ -266,13 +369,110 @@ func (p *Provider) ListModels(ctx context.Context) (*providers.ModelsResponse, e
 	return convertModelsResponse(resp), nil
 }
 
+// ModelCard reads the model's metadata from Ollama's /api/show endpoint. The
+// context length is the num_ctx requests are sent with, capped at the window
+// the model was trained with, and the capabilities are those Ollama reports
+// for the model.
+func (p *Provider) ModelCard(ctx context.Context, model string) (*providers.ModelCard, error) {
+	resp, err := p.client.Show(ctx, &api.ShowRequest{Model: model})
+	if err != nil {
+		return nil, p.ConvertError(err)
+	}
+
+	numCtx := defaultNumCtx
+	if v, ok := p.config.ExtraValue(ExtraNumCtx); ok {
+		if numCtx, err = toInt(v); err != nil {
+			return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("%s: %w", ExtraNumCtx, err))
+		}
+	}
+
+	card := &providers.ModelCard{
+		ChatTemplate:  resp.Template,
+		ContextLength: numCtx,
+		Family:        resp.Details.Family,
+		ID:            model,
+		ParameterSize: resp.Details.ParameterSize,
+		Quantization:  resp.Details.QuantizationLevel,
+	}
+
+	if arch, ok := resp.ModelInfo[modelInfoArchitecture].(string); ok {
+		if trained, err := toInt(resp.ModelInfo[arch+modelInfoContextLengthKey]); err == nil && trained > 0 {
+			card.ContextLength = min(card.ContextLength, trained)
+		}
+	}
+
+	if len(resp.Capabilities) > 0 {
+		caps := p.Capabilities()
+		reported := make(map[string]bool, len(resp.Capabilities))
+		for _, c := range resp.Capabilities {
+			reported[string(c)] = true
+		}
+		caps.Completion = reported[capabilityCompletion]
+		caps.CompletionImage = reported[capabilityVision]
+		caps.CompletionJSONObject = caps.Completion
+		caps.CompletionJSONSchema = caps.Completion
+		caps.CompletionReasoning = reported[capabilityThinking]
+		caps.CompletionStreaming = caps.Completion
+		caps.CompletionTools = reported[capabilityTools]
+		caps.Embedding = reported[capabilityEmbedding]
+		card.Capabilities = &caps
+	}
+
+	return card, nil
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return providerName
 }
 
+// applyRuntimeOptions sets keep_alive, num_ctx and num_gpu on the request.
+// Values in the request Extra take precedence over provider-level options.
+func (p *Provider) applyRuntimeOptions(req *api.ChatRequest, params providers.CompletionParams) error {
+	// Set default context size.
+	req.Options[optionNumCtx] = defaultNumCtx
+
+	if v, ok := p.extraValue(params, ExtraNumCtx); ok {
+		numCtx, err := toInt(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ExtraNumCtx, err)
+		}
+		req.Options[optionNumCtx] = numCtx
+	}
+
+	if v, ok := p.extraValue(params, ExtraNumGPU); ok {
+		numGPU, err := toInt(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ExtraNumGPU, err)
+		}
+		req.Options[optionNumGPU] = numGPU
+	}
+
+	if v, ok := p.extraValue(params, ExtraKeepAlive); ok {
+		keepAlive, err := toDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ExtraKeepAlive, err)
+		}
+		req.KeepAlive = &api.Duration{Duration: keepAlive}
+	}
+
+	return nil
+}
+
 // convertParams converts providers.CompletionParams to Ollama ChatRequest.
-func (p *Provider) convertParams(params providers.CompletionParams) *api.ChatRequest {
+func (p *Provider) convertParams(params providers.CompletionParams) (*api.ChatRequest, error) {
+	if slices.Contains(params.Modalities, providers.ModalityAudio) {
+		return nil, errors.NewUnsupportedParamError(providerName, "modalities")
+	}
+
+	if err := providers.ValidateTools(providerName, params.Tools, providers.SchemaLimits{}); err != nil {
+		return nil, err
+	}
+
+	if err := providers.ValidateMessages(providerName, params.Messages); err != nil {
+		return nil, err
+	}
+
 	messages := convertMessages(params.Messages)
 
 	req := &api.ChatRequest{
@@ -281,8 +481,9 @@ func (p *Provider) convertParams(params providers.CompletionParams) *api.ChatReq
 		Options:  make(map[string]any),
 	}
 
-	// Set default context size.
-	req.Options[optionNumCtx] = defaultNumCtx
+	if err := p.applyRuntimeOptions(req, params); err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, err)
+	}
 
 	if params.Temperature != nil {
 		req.Options[optionTemperature] = *params.Temperature
@@ -322,14 +523,23 @@ func (p *Provider) convertParams(params providers.CompletionParams) *api.ChatReq
 		req.Think = &think
 	}
 
-	return req
+	return req, nil
 }
 
-// newStreamState creates a new stream state.
-func newStreamState() *streamState {
+// extraValue looks up a runtime option in the request Extra, falling back to the provider config.
+func (p *Provider) extraValue(params providers.CompletionParams, key string) (any, bool) {
+	if v, ok := params.Extra[key]; ok {
+		return v, true
+	}
+	return p.config.ExtraValue(key)
+}
+
+// newStreamState creates a new stream state that handles inline reasoning per policy.
+func newStreamState(policy config.ReasoningPolicy) *streamState {
 	return &streamState{
 		id:      generateID(),
 		created: time.Now().Unix(),
+		think:   thinktag.NewStream(policy),
 	}
 }
 
@@ -355,6 +565,8 @@ func (s *streamState) handleChunk(resp *api.ChatResponse) providers.ChatCompleti
 		s.handleDone(resp, &chunk)
 	}
 
+	s.think.Chunk(&chunk)
+
 	return chunk
 }
 
@@ -386,6 +598,7 @@ func (s *streamState) buildDelta(resp *api.ChatResponse) providers.ChunkDelta {
 
 	// Handle tool calls.
 	if len(resp.Message.ToolCalls) > 0 {
+		s.toolCalls = true
 		delta.ToolCalls = convertToolCalls(resp.Message.ToolCalls)
 	}
 
@@ -393,9 +606,10 @@ func (s *streamState) buildDelta(resp *api.ChatResponse) providers.ChunkDelta {
 }
 
 // handleDone processes the final chunk when streaming is complete.
+// Ollama sends tool calls in a chunk before the final one.
 func (s *streamState) handleDone(resp *api.ChatResponse, chunk *providers.ChatCompletionChunk) {
 	finishReason := providers.FinishReasonToolCalls
-	if len(resp.Message.ToolCalls) == 0 {
+	if !s.toolCalls {
 		finishReason = convertDoneReason(resp.DoneReason)
 	}
 
@@ -426,6 +640,7 @@ func convertAssistantMessage(msg providers.Message) *api.Message {
 			}
 
 			toolCalls = append(toolCalls, api.ToolCall{
+				ID: tc.ID,
 				Function: api.ToolCallFunction{
 					Name:      tc.Function.Name,
 					Arguments: args,
@@ -448,7 +663,8 @@ func convertDoneReason(reason string) string {
 	}
 }
 
-// convertEmbeddingResponse converts an Ollama embedding response to provider format.
+// convertEmbeddingResponse converts an Ollama embedding response to provider
+// format, with the requested model if the response names none.
 func convertEmbeddingResponse(resp *api.EmbedResponse, model string) *providers.EmbeddingResponse {
 	data := make([]providers.EmbeddingData, 0, len(resp.Embeddings))
 
@@ -467,9 +683,11 @@ func convertEmbeddingResponse(resp *api.EmbedResponse, model string) *providers.
 	}
 
 	return &providers.EmbeddingResponse{
-		Object: objectList,
-		Data:   data,
-		Model:  model,
+		Object:     objectList,
+		Data:       data,
+		Model:      cmp.Or(resp.Model, model),
+		Dimensions: providers.EmbeddingDimensions(data),
+		Provider:   providerName,
 		Usage: &providers.EmbeddingUsage{
 			PromptTokens: resp.PromptEvalCount,
 			TotalTokens:  resp.PromptEvalCount,
@@ -496,10 +714,24 @@ func convertMessage(msg providers.Message) *api.Message {
 }
 
 // convertMessages converts provider messages to Ollama format.
+// A tool result without a Name takes the name of the tool call it answers.
 func convertMessages(messages []providers.Message) []api.Message {
 	result := make([]api.Message, 0, len(messages))
+	callNames := make(map[string]string)
 
 	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleAssistant:
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+			}
+		case providers.RoleTool:
+			if msg.Name == "" {
+				msg.Name = callNames[msg.ToolCallID]
+			}
+		default:
+		}
+
 		ollamaMsg := convertMessage(msg)
 		if ollamaMsg != nil {
 			result = append(result, *ollamaMsg)
@@ -528,9 +760,10 @@ func convertModelsResponse(resp *api.ListResponse) *providers.ModelsResponse {
 	}
 }
 
-// convertResponse converts an Ollama response to provider format.
-func convertResponse(resp *api.ChatResponse) *providers.ChatCompletion {
-	content, reasoning := extractThinking(resp.Message.Content, resp.Message.Thinking)
+// convertResponse converts an Ollama response to provider format, handling
+// inline reasoning per policy.
+func convertResponse(resp *api.ChatResponse, policy config.ReasoningPolicy) *providers.ChatCompletion {
+	content, reasoning := extractThinking(resp.Message.Content, resp.Message.Thinking, policy)
 
 	message := providers.Message{
 		Role:      providers.RoleAssistant,
@@ -615,8 +848,10 @@ func convertToolCalls(toolCalls []api.ToolCall) []providers.ToolCall {
 func convertToolMessage(msg providers.Message) *api.Message {
 	// Ollama uses user role for tool results.
 	return &api.Message{
-		Role:    providers.RoleUser,
-		Content: msg.ContentString(),
+		Role:       providers.RoleUser,
+		Content:    msg.ContentString(),
+		ToolCallID: msg.ToolCallID,
+		ToolName:   msg.Name,
 	}
 }
 
@@ -680,6 +915,7 @@ func convertUserMessage(msg providers.Message) *api.Message {
 
 	// Handle multi-modal messages with images.
 	if msg.IsMultiModal() {
+		ollamaMsg.Content = extractText(msg)
 		images := extractImages(msg)
 		if len(images) > 0 {
 			ollamaMsg.Images = images
@@ -715,33 +951,32 @@ func extractImages(msg providers.Message) []api.ImageData {
 	return images
 }
 
+// extractText joins the text parts of a multi-modal message.
+func extractText(msg providers.Message) string {
+	var texts []string
+	for _, part := range msg.ContentParts() {
+		if part.Type == contentTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
 // extractThinking extracts thinking content from response.
-// It checks the dedicated Thinking field first, then falls back to parsing <think> tags.
-func extractThinking(content, thinking string) (string, *providers.Reasoning) {
+// It checks the dedicated Thinking field first, then falls back to parsing <think> tags per policy.
+func extractThinking(content, thinking string, policy config.ReasoningPolicy) (string, *providers.Reasoning) {
 	// Check for dedicated thinking content first.
 	if thinking != "" {
 		return content, &providers.Reasoning{Content: thinking}
 	}
 
 	// Fall back to parsing <think> tags in content.
-	if !strings.Contains(content, thinkingTagOpen) || !strings.Contains(content, thinkingTagClose) {
-		return content, nil
-	}
-
-	parts := strings.SplitN(content, thinkingTagOpen, 2)
-	if len(parts) != 2 {
+	content, inline := thinktag.Split(content, policy)
+	if inline == "" {
 		return content, nil
 	}
 
-	thinkParts := strings.SplitN(parts[1], thinkingTagClose, 2)
-	if len(thinkParts) != 2 {
-		return content, nil
-	}
-
-	reasoning := &providers.Reasoning{Content: thinkParts[0]}
-	cleanContent := strings.TrimSpace(parts[0] + thinkParts[1])
-
-	return cleanContent, reasoning
+	return content, &providers.Reasoning{Content: inline}
 }
 
 // generateID generates a unique ID for responses using crypto/rand.
@@ -750,3 +985,42 @@ func generateID() string {
 	_, _ = rand.Read(b)
 	return fmt.Sprintf("chatcmpl-%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
 }
+
+// toDuration converts a keep_alive value to a duration, as Ollama reads it.
+// Strings are parsed with time.ParseDuration, or as seconds when they are
+// plain numbers such as "-1"; numbers, including float64 values decoded from
+// JSON, are interpreted as seconds.
+func toDuration(v any) (time.Duration, error) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, nil
+	case string:
+		if seconds, err := strconv.ParseFloat(d, 64); err == nil {
+			return toDuration(seconds)
+		}
+		return time.ParseDuration(d)
+	case int:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("unsupported duration type %T", v)
+	}
+}
+
+// toInt converts an integer option value, accepting whole float64 values as decoded from JSON.
+func toInt(v any) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("expected a whole number, got %v", n)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported integer type %T", v)
+	}
+}
@@ -2,8 +2,11 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -192,6 +195,28 @@ func TestConvertDoneReason(t *testing.T) {
 	}
 }
 
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	temperature := 0.2
+	body, err := provider.DryRun(context.Background(), providers.CompletionParams{
+		Model:       "llama3.2",
+		Messages:    testutil.SimpleMessages(),
+		Temperature: &temperature,
+	})
+	require.NoError(t, err)
+
+	var req api.ChatRequest
+	require.NoError(t, json.Unmarshal(body, &req))
+	require.Equal(t, "llama3.2", req.Model)
+	require.NotNil(t, req.Stream)
+	require.False(t, *req.Stream)
+	require.InDelta(t, temperature, req.Options[optionTemperature], 1e-9)
+}
+
 func TestExtractImages(t *testing.T) {
 	t.Parallel()
 
@@ -380,7 +405,7 @@ func TestConvertMessage(t *testing.T) {
 func TestNewStreamState(t *testing.T) {
 	t.Parallel()
 
-	state := newStreamState()
+	state := newStreamState(config.ReasoningSeparate)
 	require.NotNil(t, state)
 	require.NotEmpty(t, state.id)
 	require.Greater(t, state.created, int64(0))
@@ -412,7 +437,7 @@ func TestStreamStateHandleChunk(t *testing.T) {
 	t.Run("handles content chunk", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(config.ReasoningSeparate)
 		resp := &api.ChatResponse{
 			Model: "llama3.2",
 			Message: api.Message{
@@ -432,7 +457,7 @@ func TestStreamStateHandleChunk(t *testing.T) {
 	t.Run("handles thinking chunk", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(config.ReasoningSeparate)
 		resp := &api.ChatResponse{
 			Model: "deepseek-r1",
 			Message: api.Message{
@@ -447,10 +472,35 @@ func TestStreamStateHandleChunk(t *testing.T) {
 		require.Equal(t, "Let me think...", state.reasoning.String())
 	})
 
+	t.Run("separates inline thinking split across chunks", func(t *testing.T) {
+		t.Parallel()
+
+		state := newStreamState(config.ReasoningSeparate)
+
+		var content, reasoning strings.Builder
+		for i, piece := range []string{"<thi", "nk>Let me ", "think</th", "ink>\nHello", ""} {
+			chunk := state.handleChunk(&api.ChatResponse{
+				Model:   "deepseek-r1",
+				Message: api.Message{Content: piece},
+				Done:    i == 4,
+			})
+
+			delta := chunk.Choices[0].Delta
+			require.NotContains(t, delta.Content, "think")
+			content.WriteString(delta.Content)
+			if delta.Reasoning != nil {
+				reasoning.WriteString(delta.Reasoning.Content)
+			}
+		}
+
+		require.Equal(t, "Hello", content.String())
+		require.Equal(t, "Let me think", reasoning.String())
+	})
+
 	t.Run("handles done chunk with usage", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(config.ReasoningSeparate)
 		state.model = "llama3.2"
 		resp := &api.ChatResponse{
 			Model:      "llama3.2",
@@ -474,7 +524,7 @@ func TestStreamStateHandleChunk(t *testing.T) {
 	t.Run("handles done chunk with tool calls", func(t *testing.T) {
 		t.Parallel()
 
-		state := newStreamState()
+		state := newStreamState(config.ReasoningSeparate)
 		state.model = "llama3.2"
 
 		args := api.NewToolCallFunctionArguments()
@@ -495,6 +545,27 @@ func TestStreamStateHandleChunk(t *testing.T) {
 
 		require.Equal(t, providers.FinishReasonToolCalls, chunk.Choices[0].FinishReason)
 	})
+
+	t.Run("reports tool calls sent before the done chunk", func(t *testing.T) {
+		t.Parallel()
+
+		state := newStreamState(config.ReasoningSeparate)
+
+		args := api.NewToolCallFunctionArguments()
+		args.Set("location", "Paris")
+
+		state.handleChunk(&api.ChatResponse{
+			Model: "llama3.2",
+			Message: api.Message{
+				ToolCalls: []api.ToolCall{
+					{Function: api.ToolCallFunction{Name: "get_weather", Arguments: args}},
+				},
+			},
+		})
+		chunk := state.handleChunk(&api.ChatResponse{Model: "llama3.2", Done: true, DoneReason: doneReasonStop})
+
+		require.Equal(t, providers.FinishReasonToolCalls, chunk.Choices[0].FinishReason)
+	})
 }
 
 func TestExtractThinking(t *testing.T) {
@@ -503,7 +574,7 @@ func TestExtractThinking(t *testing.T) {
 	t.Run("returns dedicated thinking content", func(t *testing.T) {
 		t.Parallel()
 
-		content, reasoning := extractThinking("Hello", "I'm thinking...")
+		content, reasoning := extractThinking("Hello", "I'm thinking...", config.ReasoningSeparate)
 
 		require.Equal(t, "Hello", content)
 		require.NotNil(t, reasoning)
@@ -513,7 +584,7 @@ func TestExtractThinking(t *testing.T) {
 	t.Run("parses think tags from content", func(t *testing.T) {
 		t.Parallel()
 
-		content, reasoning := extractThinking("<think>Let me think</think>Hello world", "")
+		content, reasoning := extractThinking("<think>Let me think</think>Hello world", "", config.ReasoningSeparate)
 
 		require.Equal(t, "Hello world", content)
 		require.NotNil(t, reasoning)
@@ -523,7 +594,7 @@ func TestExtractThinking(t *testing.T) {
 	t.Run("returns nil reasoning when no thinking", func(t *testing.T) {
 		t.Parallel()
 
-		content, reasoning := extractThinking("Hello world", "")
+		content, reasoning := extractThinking("Hello world", "", config.ReasoningSeparate)
 
 		require.Equal(t, "Hello world", content)
 		require.Nil(t, reasoning)
@@ -616,7 +687,235 @@ func TestGenerateID(t *testing.T) {
 	require.NotEqual(t, id1, id2) // IDs should be unique.
 }
 
+func TestRuntimeOptions(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "llama3.2",
+		Messages: testutil.SimpleMessages(),
+	}
+
+	t.Run("uses default context size", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+
+		req, err := provider.convertParams(params)
+		require.NoError(t, err)
+		require.Equal(t, defaultNumCtx, req.Options[optionNumCtx])
+		require.NotContains(t, req.Options, optionNumGPU)
+		require.Nil(t, req.KeepAlive)
+	})
+
+	t.Run("applies provider options", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(WithKeepAlive(10*time.Minute), WithNumCtx(8192), WithNumGPU(0))
+		require.NoError(t, err)
+
+		req, err := provider.convertParams(params)
+		require.NoError(t, err)
+		require.Equal(t, 8192, req.Options[optionNumCtx])
+		require.Equal(t, 0, req.Options[optionNumGPU])
+		require.Equal(t, 10*time.Minute, req.KeepAlive.Duration)
+	})
+
+	t.Run("request extra overrides provider options", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(WithKeepAlive(10*time.Minute), WithNumCtx(8192))
+		require.NoError(t, err)
+
+		reqParams := params
+		reqParams.Extra = map[string]any{
+			ExtraKeepAlive: "-1s",
+			ExtraNumCtx:    float64(4096),
+			ExtraNumGPU:    99,
+		}
+
+		req, err := provider.convertParams(reqParams)
+		require.NoError(t, err)
+		require.Equal(t, 4096, req.Options[optionNumCtx])
+		require.Equal(t, 99, req.Options[optionNumGPU])
+		require.Equal(t, -time.Second, req.KeepAlive.Duration)
+	})
+
+	t.Run("rejects invalid extra values", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+
+		reqParams := params
+		reqParams.Extra = map[string]any{ExtraNumCtx: "large"}
+
+		_, err = provider.convertParams(reqParams)
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("rejects invalid provider options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(WithNumCtx(0))
+		require.Error(t, err)
+
+		_, err = New(WithNumGPU(-1))
+		require.Error(t, err)
+	})
+}
+
+func TestToDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    any
+		expected time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "duration",
+			value:    5 * time.Minute,
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "duration string",
+			value:    "10m",
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "seconds string",
+			value:    "300",
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "keep loaded string",
+			value:    "-1",
+			expected: -time.Second,
+		},
+		{
+			name:     "integer seconds",
+			value:    60,
+			expected: time.Minute,
+		},
+		{
+			name:     "float seconds from JSON",
+			value:    float64(1.5),
+			expected: 1500 * time.Millisecond,
+		},
+		{
+			name:     "keep loaded float",
+			value:    float64(-1),
+			expected: -time.Second,
+		},
+		{
+			name:    "invalid string",
+			value:   "forever",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			value:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := toDuration(tc.value)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestModelCard(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/show", r.URL.Path)
+
+		var req api.ShowRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "llama3.2", req.Model)
+
+		_, _ = w.Write([]byte(`{
+			"template": "{{ .Prompt }}",
+			"details": {"family": "llama", "parameter_size": "3.2B", "quantization_level": "Q4_K_M"},
+			"model_info": {"general.architecture": "llama", "llama.context_length": 131072},
+			"capabilities": ["completion", "tools"]
+		}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("caps the context length at num_ctx", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		card, err := provider.ModelCard(context.Background(), "llama3.2")
+		require.NoError(t, err)
+		require.Equal(t, "llama3.2", card.ID)
+		require.Equal(t, "{{ .Prompt }}", card.ChatTemplate)
+		require.Equal(t, defaultNumCtx, card.ContextLength)
+		require.Equal(t, "llama", card.Family)
+		require.Equal(t, "3.2B", card.ParameterSize)
+		require.Equal(t, "Q4_K_M", card.Quantization)
+
+		require.NotNil(t, card.Capabilities)
+		require.True(t, card.Capabilities.Completion)
+		require.True(t, card.Capabilities.CompletionTools)
+		require.False(t, card.Capabilities.CompletionImage)
+		require.False(t, card.Capabilities.CompletionReasoning)
+		require.False(t, card.Capabilities.Embedding)
+	})
+
+	t.Run("caps num_ctx at the trained context length", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithBaseURL(server.URL), WithNumCtx(200000))
+		require.NoError(t, err)
+
+		card, err := provider.ModelCard(context.Background(), "llama3.2")
+		require.NoError(t, err)
+		require.Equal(t, 131072, card.ContextLength)
+	})
+}
+
 // Integration tests - only run if Ollama is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	server, released := testutil.NewStallingServer(t, "application/x-ndjson", strings.Repeat(
+		`{"model":"llama3","created_at":"2024-01-01T00:00:00Z",`+
+			`"message":{"role":"assistant","content":"Hi"},"done":false}`+"\n", 2))
+
+	provider, err := New(config.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "llama3",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
+
+func TestStreamParity(t *testing.T) {
+	t.Parallel()
+
+	testutil.VerifyStreamParity(t, providerName, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
 
 func TestIntegrationCompletion(t *testing.T) {
 	t.Parallel()
@@ -872,3 +1171,66 @@ func skipTestIfOllamaUnavailable(t *testing.T, model string) {
 
 	t.Skipf("Ollama model %q not available (install with: ollama pull %s)", model, model)
 }
+
+func FuzzConvertMessages(f *testing.F) {
+	testutil.AddConversationSeeds(f)
+
+	provider, err := New()
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		messages := testutil.RandomConversation(seed)
+
+		req, err := provider.convertParams(providers.CompletionParams{Model: "llama3.2", Messages: messages})
+		require.NoError(t, err)
+
+		// Ollama only accepts inline images, as base64 without the data URL
+		// prefix.
+		var want []testutil.MessageEvent
+		for _, event := range testutil.MessageEvents(messages) {
+			if event.Kind == testutil.EventImage {
+				data, ok := strings.CutPrefix(event.Value, "data:image/png;base64,")
+				if !ok {
+					continue
+				}
+				event.Value = data
+			}
+			want = append(want, event)
+		}
+
+		got := requestEvents(t, req.Messages)
+		require.Equal(t, want, got)
+		testutil.RequireToolPairing(t, got)
+
+		_, err = provider.convertParams(providers.CompletionParams{
+			Model:    "llama3.2",
+			Messages: testutil.WithUnknownRole(messages, seed),
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+// requestEvents flattens the messages of a request into events.
+func requestEvents(t *testing.T, messages []api.Message) []testutil.MessageEvent {
+	t.Helper()
+
+	var events []testutil.MessageEvent
+	for _, msg := range messages {
+		if msg.ToolCallID != "" {
+			// Ollama sends tool results with the user role.
+			events = append(events, testutil.ToolResultEvent(msg.ToolCallID, msg.ToolName, msg.Content))
+			continue
+		}
+
+		if msg.Content != "" {
+			events = append(events, testutil.TextEvent(msg.Role, msg.Content))
+		}
+		for _, image := range msg.Images {
+			events = append(events, testutil.ImageEvent(msg.Role, string(image)))
+		}
+		for _, tc := range msg.ToolCalls {
+			events = append(events, testutil.ToolCallEventOf(t, msg.Role, tc.ID, tc.Function.Name, tc.Function.Arguments))
+		}
+	}
+	return events
+}
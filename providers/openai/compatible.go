@@ -3,9 +3,16 @@
 package openai
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -13,6 +20,10 @@ import (
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/deadline"
+	"github.com/mozilla-ai/any-llm-go/internal/ratelimit"
+	"github.com/mozilla-ai/any-llm-go/internal/streamstats"
+	"github.com/mozilla-ai/any-llm-go/internal/thinktag"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
@@ -21,6 +32,7 @@ const (
 	apiCodeContentFilter         = "content_filter"
 	apiCodeContentPolicyViolated = "content_policy_violation"
 	apiCodeContextLengthExceeded = "context_length_exceeded"
+	apiCodeInsufficientQuota     = "insufficient_quota"
 	apiCodeInvalidAPIKey         = "invalid_api_key"
 	apiCodeModelNotFound         = "model_not_found"
 	apiCodeRateLimitExceeded     = "rate_limit_exceeded"
@@ -37,16 +49,37 @@ const (
 
 // Content part types.
 const (
+	contentTypeFile     = "file"
 	contentTypeImageURL = "image_url"
 	contentTypeText     = "text"
 )
 
+// Response header carrying the provider's request ID.
+const headerRequestID = "x-request-id"
+
+// Response fields the SDK does not model. Azure OpenAI reports the verdicts
+// of its content filters in the filter result fields.
+const (
+	fieldAudio                = "audio"
+	fieldContentFilterResults = "content_filter_results"
+	fieldPromptFilterResults  = "prompt_filter_results"
+)
+
 // Response format types.
 const (
 	responseFormatJSONObject = "json_object"
 	responseFormatJSONSchema = "json_schema"
 )
 
+// probeMaxTokens caps the output of each probe request.
+const probeMaxTokens = 16
+
+// probeJSONPrompt is the prompt of the JSON mode probe.
+const probeJSONPrompt = `Reply with the JSON object {"ok": true}.`
+
+// probeToolPrompt is the prompt of the tool probe.
+const probeToolPrompt = "What time is it? Use the get_time tool."
+
 // CompatibleConfig contains the configuration for an OpenAI-compatible provider.
 // Fields are ordered alphabetically.
 type CompatibleConfig struct {
@@ -65,19 +98,51 @@ type CompatibleConfig struct {
 	// DefaultBaseURL is the default API base URL.
 	DefaultBaseURL string
 
+	// ExtraFields, if set, returns fields to add to the body of a completion request
+	// sent with ctx, for parameters the OpenAI API does not have.
+	ExtraFields func(ctx context.Context, params providers.CompletionParams) map[string]any
+
+	// InlineThinking reports that models may inline their reasoning in the
+	// content between <think> tags, as DeepSeek-R1 does on some hosts. It is
+	// then handled per the config's ReasoningPolicy.
+	InlineThinking bool
+
 	// Name is the provider name used in error messages.
 	Name string
 
+	// PostprocessChunk, if set, transforms each streaming chunk before it is sent.
+	PostprocessChunk func(providers.ChatCompletionChunk) providers.ChatCompletionChunk
+
+	// PostprocessResponse, if set, transforms each completion response before it is returned.
+	PostprocessResponse func(*providers.ChatCompletion) *providers.ChatCompletion
+
+	// PreprocessParams, if set, transforms completion parameters before each request.
+	// Use it for provider quirks such as unsupported fields or message ordering rules.
+	// Implementations must not mutate the params they receive (clone slices before editing).
+	PreprocessParams func(providers.CompletionParams) providers.CompletionParams
+
+	// RequestHeaders, if set, returns headers to set on each request sent with ctx,
+	// replacing any the SDK set. Use it for headers a gateway reads, such as routing
+	// or billing tags.
+	RequestHeaders func(ctx context.Context) http.Header
+
 	// RequireAPIKey indicates whether an API key is required.
 	RequireAPIKey bool
+
+	// SamplingLimits, if set, returns the sampling parameter limits of a model.
+	// Requests outside them fail with an InvalidRequestError before they are sent.
+	// When unset, the default providers.SamplingLimits apply.
+	SamplingLimits func(model string) providers.SamplingLimits
 }
 
 // Ensure CompatibleProvider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*CompatibleProvider)(nil)
+	_ providers.DryRunner          = (*CompatibleProvider)(nil)
 	_ providers.EmbeddingProvider  = (*CompatibleProvider)(nil)
 	_ providers.ErrorConverter     = (*CompatibleProvider)(nil)
 	_ providers.ModelLister        = (*CompatibleProvider)(nil)
+	_ providers.Prober             = (*CompatibleProvider)(nil)
 	_ providers.Provider           = (*CompatibleProvider)(nil)
 )
 
@@ -86,6 +151,27 @@ var (
 type CompatibleProvider struct {
 	compatibleConfig CompatibleConfig
 	client           openai.Client
+	config           *config.Config
+	rawSchemas       bool
+	reasoningPolicy  config.ReasoningPolicy
+
+	mu     sync.RWMutex
+	probed *providers.Capabilities // Set by Probe; overrides compatibleConfig.Capabilities.
+}
+
+// streamState tracks what a stream has sent, so that the final chunk servers
+// such as llama.cpp and older vLLM leave out can be synthesized.
+type streamState struct {
+	finished map[int]bool
+
+	// last holds the identifying fields of the last chunk, which the
+	// synthesized chunk repeats. Only these are copied so that observing a
+	// chunk does not move it to the heap.
+	last      providers.ChatCompletionChunk
+	refused   map[int]bool
+	sent      bool
+	toolCalls map[int]bool
+	usage     bool
 }
 
 // NewCompatible creates a new OpenAI-compatible provider.
@@ -116,21 +202,36 @@ func NewCompatible(compatCfg CompatibleConfig, opts ...config.Option) (*Compatib
 	clientOpts := []option.RequestOption{
 		option.WithAPIKey(apiKey),
 		option.WithHTTPClient(cfg.HTTPClient()),
+		option.WithMiddleware(deadline.Middleware),
 	}
 
 	if baseURL != "" {
 		clientOpts = append(clientOpts, option.WithBaseURL(baseURL))
 	}
+	if compatCfg.RequestHeaders != nil {
+		clientOpts = append(clientOpts, option.WithMiddleware(headerMiddleware(compatCfg.RequestHeaders)))
+	}
 
 	return &CompatibleProvider{
 		compatibleConfig: compatCfg,
 		client:           openai.NewClient(clientOpts...),
+		config:           cfg,
+		rawSchemas:       cfg.RawSchemas,
+		reasoningPolicy:  cfg.ReasoningPolicy,
 	}, nil
 }
 
 // Capabilities returns the provider's capabilities.
+// After a successful Probe, it returns the probed capabilities. Overrides set
+// with config.WithCapabilityOverrides apply on top of either.
 func (p *CompatibleProvider) Capabilities() providers.Capabilities {
-	return p.compatibleConfig.Capabilities
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.probed != nil {
+		return providers.OverrideCapabilities(*p.probed, p.config)
+	}
+	return providers.OverrideCapabilities(p.compatibleConfig.Capabilities, p.config)
 }
 
 // Completion performs a chat completion request.
@@ -138,18 +239,33 @@ func (p *CompatibleProvider) Completion(
 	ctx context.Context,
 	params providers.CompletionParams,
 ) (*providers.ChatCompletion, error) {
-	if err := validateCompletionParams(params); err != nil {
+	params = p.preprocessParams(params)
+
+	if err := p.validateParams(params); err != nil {
 		return nil, err
 	}
 
-	req := convertParams(params)
+	req := p.requestParams(ctx, params)
 
-	resp, err := p.client.Chat.Completions.New(ctx, req)
+	var httpResp *http.Response
+	resp, err := p.client.Chat.Completions.New(ctx, req, requestOptions(ctx, option.WithResponseInto(&httpResp))...)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // The request was cut short by the caller's deadline or cancellation.
+		}
 		return nil, p.ConvertError(err)
 	}
 
-	return convertResponse(resp), nil
+	result := convertResponse(resp)
+	if httpResp != nil {
+		result.RequestID = httpResp.Header.Get(headerRequestID)
+		result.RateLimit = ratelimit.Parse(httpResp.Header, time.Now())
+	}
+	if p.compatibleConfig.InlineThinking {
+		thinktag.Response(result, p.reasoningPolicy)
+	}
+
+	return p.postprocessResponse(result), nil
 }
 
 // CompletionStream performs a streaming chat completion request.
@@ -164,25 +280,71 @@ func (p *CompatibleProvider) CompletionStream(
 		defer close(chunks)
 		defer close(errs)
 
-		if err := validateCompletionParams(params); err != nil {
+		params := p.preprocessParams(params)
+
+		if err := p.validateParams(params); err != nil {
 			errs <- err
 			return
 		}
 
-		req := convertParams(params)
-		stream := p.client.Chat.Completions.NewStreaming(ctx, req)
+		req := p.requestParams(ctx, params)
+		var httpResp *http.Response
+		timings := streamstats.New() // Started before the request, to include its latency.
+		opts := requestOptions(ctx, option.WithResponseInto(&httpResp))
+		stream := p.client.Chat.Completions.NewStreaming(ctx, req, opts...)
+		defer func() { _ = stream.Close() }() // Releases the response body; close error is not actionable.
+
+		var think *thinktag.Stream
+		if p.compatibleConfig.InlineThinking {
+			think = thinktag.NewStream(p.reasoningPolicy)
+		}
+
+		var rateLimit *providers.RateLimitState
+		if httpResp != nil {
+			rateLimit = ratelimit.Parse(httpResp.Header, time.Now())
+		}
 
+		state := &streamState{finished: map[int]bool{}, refused: map[int]bool{}, toolCalls: map[int]bool{}}
 		for stream.Next() {
 			chunk := stream.Current()
+			timings.Observe()
+
+			converted := convertChunk(&chunk)
+			applyTimings(&converted, timings)
+			if think != nil {
+				think.Chunk(&converted)
+			}
+
+			// Only the first chunk carries the rate limit state.
+			converted.RateLimit, rateLimit = rateLimit, nil
+			state.applyRefusal(&converted)
+			state.observe(converted)
+
 			select {
-			case chunks <- convertChunk(&chunk):
+			case chunks <- p.postprocessChunk(converted):
 			case <-ctx.Done():
+				errs <- ctx.Err()
 				return
 			}
 		}
 
 		if err := stream.Err(); err != nil {
+			if ctx.Err() != nil {
+				errs <- ctx.Err() // The read failed because the stream was cancelled.
+				return
+			}
 			errs <- p.ConvertError(err)
+			return
+		}
+
+		includeUsage := params.StreamOptions != nil && params.StreamOptions.IncludeUsage
+		if final := state.finalChunk(includeUsage); final != nil {
+			applyTimings(final, timings)
+			select {
+			case chunks <- p.postprocessChunk(*final):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+			}
 		}
 	}()
 
@@ -210,6 +372,23 @@ func (p *CompatibleProvider) ConvertError(err error) error {
 	return errors.NewProviderError(name, err)
 }
 
+// DryRun returns the body Completion would send for params, without sending it.
+// Implements providers.DryRunner.
+func (p *CompatibleProvider) DryRun(ctx context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	params = p.preprocessParams(params)
+
+	if err := p.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(p.requestParams(ctx, params))
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(p.compatibleConfig.Name, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
 // Embedding generates embeddings for the given input.
 func (p *CompatibleProvider) Embedding(
 	ctx context.Context,
@@ -217,17 +396,17 @@ func (p *CompatibleProvider) Embedding(
 ) (*providers.EmbeddingResponse, error) {
 	req := convertEmbeddingParams(params)
 
-	resp, err := p.client.Embeddings.New(ctx, req)
+	resp, err := p.client.Embeddings.New(ctx, req, requestOptions(ctx)...)
 	if err != nil {
 		return nil, p.ConvertError(err)
 	}
 
-	return convertEmbeddingResponse(resp), nil
+	return convertEmbeddingResponse(resp, p.compatibleConfig.Name, params.Model), nil
 }
 
 // ListModels returns a list of available models.
 func (p *CompatibleProvider) ListModels(ctx context.Context) (*providers.ModelsResponse, error) {
-	resp, err := p.client.Models.List(ctx)
+	resp, err := p.client.Models.List(ctx, requestOptions(ctx)...)
 	if err != nil {
 		return nil, p.ConvertError(err)
 	}
@@ -253,6 +432,275 @@ func (p *CompatibleProvider) Name() string {
 	return p.compatibleConfig.Name
 }
 
+// Post sends body as JSON to path, relative to the base URL, with the
+// provider's credentials, and decodes the JSON response into out. Providers
+// built on CompatibleProvider use it for endpoints outside the OpenAI API.
+func (p *CompatibleProvider) Post(ctx context.Context, path string, body any, out any) error {
+	if err := p.client.Post(ctx, path, body, out, requestOptions(ctx)...); err != nil {
+		return p.ConvertError(err)
+	}
+	return nil
+}
+
+// Probe checks which features the endpoint supports and updates Capabilities
+// with the result. It lists models, then sends short completions: plain,
+// streaming, with a tool, and with JSON object and JSON schema response
+// formats. A feature is unsupported when the endpoint rejects its request.
+// Image, PDF, reasoning and embedding support are not probed and keep their
+// configured values.
+// Implements providers.Prober.
+func (p *CompatibleProvider) Probe(ctx context.Context, model string) (providers.Capabilities, error) {
+	caps := p.Capabilities()
+
+	models, err := p.ListModels(ctx)
+	if err != nil && probeFailed(ctx, err) {
+		return providers.Capabilities{}, err
+	}
+	caps.ListModels = err == nil
+
+	if model == "" {
+		if err != nil || len(models.Data) == 0 {
+			return providers.Capabilities{}, errors.NewInvalidRequestError(
+				p.compatibleConfig.Name,
+				stderrors.New("no model given and the endpoint lists none to probe"),
+			)
+		}
+		model = models.Data[0].ID
+	}
+
+	maxTokens := probeMaxTokens
+	base := providers.CompletionParams{
+		Model:     model,
+		Messages:  []providers.Message{{Role: providers.RoleUser, Content: "Reply with OK."}},
+		MaxTokens: &maxTokens,
+	}
+
+	// Without plain completions there is nothing else to probe.
+	if _, err := p.Completion(ctx, base); err != nil {
+		return providers.Capabilities{}, err
+	}
+	caps.Completion = true
+
+	if caps.CompletionStreaming, err = p.probeStream(ctx, base); err != nil {
+		return providers.Capabilities{}, err
+	}
+
+	// Servers such as llama.cpp without --jinja accept tools but ignore them,
+	// so tools are supported only if the reply calls one.
+	withTools := base
+	withTools.Messages = []providers.Message{{Role: providers.RoleUser, Content: probeToolPrompt}}
+	withTools.Tools = []providers.Tool{probeTool()}
+	if caps.CompletionTools, err = p.probeToolCall(ctx, withTools); err != nil {
+		return providers.Capabilities{}, err
+	}
+
+	// JSON mode requires the word "JSON" in the prompt on OpenAI.
+	withObject := base
+	withObject.Messages = []providers.Message{{Role: providers.RoleUser, Content: probeJSONPrompt}}
+	withObject.ResponseFormat = &providers.ResponseFormat{Type: responseFormatJSONObject}
+	if caps.CompletionJSONObject, err = p.probeCompletion(ctx, withObject); err != nil {
+		return providers.Capabilities{}, err
+	}
+
+	withSchema := base
+	withSchema.ResponseFormat = probeResponseFormat()
+	if caps.CompletionJSONSchema, err = p.probeCompletion(ctx, withSchema); err != nil {
+		return providers.Capabilities{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.probed = &caps
+
+	return caps, nil
+}
+
+// postprocessChunk applies the configured PostprocessChunk hook, if any.
+func (p *CompatibleProvider) postprocessChunk(chunk providers.ChatCompletionChunk) providers.ChatCompletionChunk {
+	if p.compatibleConfig.PostprocessChunk == nil {
+		return chunk
+	}
+	return p.compatibleConfig.PostprocessChunk(chunk)
+}
+
+// postprocessResponse applies the configured PostprocessResponse hook, if any.
+func (p *CompatibleProvider) postprocessResponse(resp *providers.ChatCompletion) *providers.ChatCompletion {
+	if p.compatibleConfig.PostprocessResponse == nil {
+		return resp
+	}
+	return p.compatibleConfig.PostprocessResponse(resp)
+}
+
+// preprocessParams applies the configured PreprocessParams hook, if any.
+func (p *CompatibleProvider) preprocessParams(params providers.CompletionParams) providers.CompletionParams {
+	if p.compatibleConfig.PreprocessParams != nil {
+		params = p.compatibleConfig.PreprocessParams(params)
+	}
+	if !p.rawSchemas {
+		params = strictParams(params)
+	}
+	return params
+}
+
+// probeCompletion reports whether the endpoint accepts a completion request for params.
+// It returns an error only when the probe itself failed.
+func (p *CompatibleProvider) probeCompletion(ctx context.Context, params providers.CompletionParams) (bool, error) {
+	_, err := p.Completion(ctx, params)
+	if err != nil && probeFailed(ctx, err) {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+// probeToolCall reports whether the endpoint answers a completion request for
+// params with a tool call. It returns an error only when the probe itself failed.
+func (p *CompatibleProvider) probeToolCall(ctx context.Context, params providers.CompletionParams) (bool, error) {
+	resp, err := p.Completion(ctx, params)
+	if err != nil {
+		if probeFailed(ctx, err) {
+			return false, err
+		}
+		return false, nil
+	}
+	return len(resp.Choices) > 0 && len(resp.Choices[0].Message.ToolCalls) > 0, nil
+}
+
+// probeStream reports whether the endpoint accepts a streaming request for params.
+// It returns an error only when the probe itself failed.
+func (p *CompatibleProvider) probeStream(ctx context.Context, params providers.CompletionParams) (bool, error) {
+	chunks, errs := p.CompletionStream(ctx, params)
+	for range chunks {
+		// Only the stream's error matters.
+	}
+
+	err := <-errs
+	if err != nil && probeFailed(ctx, err) {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+// requestParams converts params to a request, with the configured extra fields.
+func (p *CompatibleProvider) requestParams(
+	ctx context.Context,
+	params providers.CompletionParams,
+) openai.ChatCompletionNewParams {
+	req := convertParams(params)
+	if p.compatibleConfig.ExtraFields != nil {
+		if fields := p.compatibleConfig.ExtraFields(ctx, params); len(fields) > 0 {
+			req.SetExtraFields(fields)
+		}
+	}
+	return req
+}
+
+// validateParams validates completion parameters, including the sampling limits of the model
+// and the schemas of its tools.
+func (p *CompatibleProvider) validateParams(params providers.CompletionParams) error {
+	if err := validateCompletionParams(params); err != nil {
+		return err
+	}
+
+	if err := providers.ValidateTools(p.compatibleConfig.Name, params.Tools, providers.SchemaLimits{}); err != nil {
+		return err
+	}
+
+	var limits providers.SamplingLimits
+	if p.compatibleConfig.SamplingLimits != nil {
+		limits = p.compatibleConfig.SamplingLimits(params.Model)
+	}
+
+	return providers.ValidateSampling(p.compatibleConfig.Name, params, limits)
+}
+
+// applyRefusal records the choices in chunk that stream a refusal, and
+// reports refusal rather than stop as the finish reason of those choices.
+func (s *streamState) applyRefusal(chunk *providers.ChatCompletionChunk) {
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		s.refused[choice.Index] = s.refused[choice.Index] || choice.Delta.Refusal != ""
+		choice.FinishReason = refusalFinishReason(choice.FinishReason, s.refused[choice.Index])
+	}
+}
+
+// finalChunk returns the chunk that completes the stream if the server left
+// it out: a finish reason for every choice that has none, stop, refusal or tool_calls,
+// and, if includeUsage is set and the server sent no usage, an empty usage.
+// It returns nil if the stream is complete or sent nothing.
+func (s *streamState) finalChunk(includeUsage bool) *providers.ChatCompletionChunk {
+	if !s.sent {
+		return nil
+	}
+
+	var choices []providers.ChunkChoice
+	for _, index := range slices.Sorted(maps.Keys(s.finished)) {
+		if s.finished[index] {
+			continue
+		}
+
+		finishReason := refusalFinishReason(providers.FinishReasonStop, s.refused[index])
+		if s.toolCalls[index] {
+			finishReason = providers.FinishReasonToolCalls
+		}
+		choices = append(choices, providers.ChunkChoice{Index: index, FinishReason: finishReason})
+	}
+
+	var usage *providers.Usage
+	if includeUsage && !s.usage {
+		usage = &providers.Usage{}
+	}
+
+	if len(choices) == 0 && usage == nil {
+		return nil
+	}
+
+	return &providers.ChatCompletionChunk{
+		ID:                s.last.ID,
+		Object:            objectChatCompletionChunk,
+		Created:           s.last.Created,
+		Model:             s.last.Model,
+		Choices:           choices,
+		Usage:             usage,
+		SystemFingerprint: s.last.SystemFingerprint,
+	}
+}
+
+// observe records a chunk the stream sent.
+func (s *streamState) observe(chunk providers.ChatCompletionChunk) {
+	s.last = providers.ChatCompletionChunk{
+		ID:                chunk.ID,
+		Created:           chunk.Created,
+		Model:             chunk.Model,
+		SystemFingerprint: chunk.SystemFingerprint,
+	}
+	s.sent = true
+	s.usage = s.usage || chunk.Usage != nil
+
+	for _, choice := range chunk.Choices {
+		s.finished[choice.Index] = s.finished[choice.Index] || choice.FinishReason != ""
+		s.toolCalls[choice.Index] = s.toolCalls[choice.Index] || len(choice.Delta.ToolCalls) > 0
+	}
+}
+
+// applyTimings sets stream timings on chunks that may end the stream.
+// Servers send usage after the finish reason when it is requested, so both
+// kinds of chunk are stamped and the last one wins.
+func applyTimings(chunk *providers.ChatCompletionChunk, timings *streamstats.Recorder) {
+	finished := slices.ContainsFunc(chunk.Choices, func(c providers.ChunkChoice) bool {
+		return c.FinishReason != ""
+	})
+	if !finished && chunk.Usage == nil {
+		return
+	}
+
+	outputTokens := 0
+	if chunk.Usage != nil {
+		outputTokens = chunk.Usage.CompletionTokens
+	}
+	chunk.Timings = timings.Timings(outputTokens)
+}
+
 // convertAPIError converts an OpenAI API error to a unified error type.
 func convertAPIError(name string, apiErr *openai.Error, originalErr error) error {
 	switch apiErr.StatusCode {
@@ -269,11 +717,16 @@ func convertAPIError(name string, apiErr *openai.Error, originalErr error) error
 	case 404:
 		return errors.NewModelNotFoundError(name, originalErr)
 	case 429:
+		if apiErr.Code == apiCodeInsufficientQuota {
+			return errors.NewQuotaExceededError(name, originalErr)
+		}
 		return errors.NewRateLimitError(name, originalErr)
 	}
 
 	// Check error code for additional classification.
 	switch apiErr.Code {
+	case apiCodeInsufficientQuota:
+		return errors.NewQuotaExceededError(name, originalErr)
 	case apiCodeInvalidAPIKey:
 		return errors.NewAuthenticationError(name, originalErr)
 	case apiCodeModelNotFound:
@@ -282,11 +735,34 @@ func convertAPIError(name string, apiErr *openai.Error, originalErr error) error
 		return errors.NewRateLimitError(name, originalErr)
 	}
 
-	return errors.NewProviderError(name, originalErr)
+	providerErr := errors.NewProviderError(name, originalErr)
+	providerErr.StatusCode = apiErr.StatusCode
+	return providerErr
 }
 
 // convertAssistantMessage converts an assistant message to OpenAI format.
 func convertAssistantMessage(msg providers.Message) openai.ChatCompletionMessageParamUnion {
+	if len(msg.ToolCalls) == 0 && (msg.Audio == nil || msg.Audio.ID == "") && msg.Refusal == "" {
+		return openai.AssistantMessage(msg.ContentString())
+	}
+
+	assistant := &openai.ChatCompletionAssistantMessageParam{}
+
+	// A message that references earlier audio output may carry no text.
+	if content := msg.ContentString(); content != "" || len(msg.ToolCalls) > 0 {
+		assistant.Content = openai.ChatCompletionAssistantMessageParamContentUnion{
+			OfString: openai.String(content),
+		}
+	}
+
+	if msg.Audio != nil && msg.Audio.ID != "" {
+		assistant.Audio = openai.ChatCompletionAssistantMessageParamAudio{ID: msg.Audio.ID}
+	}
+
+	if msg.Refusal != "" {
+		assistant.Refusal = openai.String(msg.Refusal)
+	}
+
 	if len(msg.ToolCalls) > 0 {
 		toolCalls := make([]openai.ChatCompletionMessageToolCallParam, 0, len(msg.ToolCalls))
 		for _, tc := range msg.ToolCalls {
@@ -298,16 +774,10 @@ func convertAssistantMessage(msg providers.Message) openai.ChatCompletionMessage
 				},
 			})
 		}
-		return openai.ChatCompletionMessageParamUnion{
-			OfAssistant: &openai.ChatCompletionAssistantMessageParam{
-				Content: openai.ChatCompletionAssistantMessageParamContentUnion{
-					OfString: openai.String(msg.ContentString()),
-				},
-				ToolCalls: toolCalls,
-			},
-		}
+		assistant.ToolCalls = toolCalls
 	}
-	return openai.AssistantMessage(msg.ContentString())
+
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: assistant}
 }
 
 // convertChunk converts an OpenAI streaming chunk to provider format.
@@ -319,10 +789,23 @@ func convertChunk(chunk *openai.ChatCompletionChunk) providers.ChatCompletionChu
 			Delta: providers.ChunkDelta{
 				Role:    string(choice.Delta.Role),
 				Content: choice.Delta.Content,
+				Refusal: choice.Delta.Refusal,
 			},
 			FinishReason: string(choice.FinishReason),
 		}
 
+		// The SDK does not model audio deltas, so decode them from the raw JSON.
+		if field, ok := choice.Delta.JSON.ExtraFields[fieldAudio]; ok {
+			var audio providers.Audio
+			if err := json.Unmarshal([]byte(field.Raw()), &audio); err == nil {
+				chunkChoice.Delta.Audio = &audio
+			}
+		}
+
+		if field, ok := choice.JSON.ExtraFields[fieldContentFilterResults]; ok {
+			chunkChoice.ContentFilterResults = convertContentFilterResults(field.Raw())
+		}
+
 		if len(choice.Delta.ToolCalls) > 0 {
 			chunkChoice.Delta.ToolCalls = make([]providers.ToolCall, 0, len(choice.Delta.ToolCalls))
 			for _, tc := range choice.Delta.ToolCalls {
@@ -349,17 +832,48 @@ func convertChunk(chunk *openai.ChatCompletionChunk) providers.ChatCompletionChu
 		SystemFingerprint: chunk.SystemFingerprint,
 	}
 
-	if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
-		result.Usage = &providers.Usage{
-			PromptTokens:     int(chunk.Usage.PromptTokens),
-			CompletionTokens: int(chunk.Usage.CompletionTokens),
-			TotalTokens:      int(chunk.Usage.TotalTokens),
-		}
+	result.Usage = convertUsage(chunk.Usage)
+
+	if field, ok := chunk.JSON.ExtraFields[fieldPromptFilterResults]; ok {
+		result.PromptFilterResults = convertPromptFilterResults(field.Raw())
 	}
 
 	return result
 }
 
+// convertContentFilterResults converts Azure content filter results, an
+// object keyed by category, to provider format, ordered by category. Entries
+// that are not verdicts, such as the error reported when filtering failed,
+// are skipped.
+func convertContentFilterResults(raw string) []providers.ContentFilterResult {
+	var categories map[string]struct {
+		Detected bool   `json:"detected"`
+		Filtered *bool  `json:"filtered"`
+		Severity string `json:"severity"`
+	}
+	if err := json.Unmarshal([]byte(raw), &categories); err != nil {
+		return nil
+	}
+
+	results := make([]providers.ContentFilterResult, 0, len(categories))
+	for _, category := range slices.Sorted(maps.Keys(categories)) {
+		verdict := categories[category]
+		if verdict.Filtered == nil {
+			continue
+		}
+		results = append(results, providers.ContentFilterResult{
+			Category: category,
+			Filtered: *verdict.Filtered,
+			Detected: verdict.Detected,
+			Severity: verdict.Severity,
+		})
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	return results
+}
+
 // convertEmbeddingParams converts provider embedding params to OpenAI format.
 func convertEmbeddingParams(params providers.EmbeddingParams) openai.EmbeddingNewParams {
 	req := openai.EmbeddingNewParams{
@@ -397,8 +911,14 @@ func convertEmbeddingParams(params providers.EmbeddingParams) openai.EmbeddingNe
 	return req
 }
 
-// convertEmbeddingResponse converts an OpenAI embedding response to provider format.
-func convertEmbeddingResponse(resp *openai.CreateEmbeddingResponse) *providers.EmbeddingResponse {
+// convertEmbeddingResponse converts an OpenAI embedding response from the
+// provider called name to provider format, with the requested model if the
+// response names none.
+func convertEmbeddingResponse(
+	resp *openai.CreateEmbeddingResponse,
+	name string,
+	model string,
+) *providers.EmbeddingResponse {
 	data := make([]providers.EmbeddingData, 0, len(resp.Data))
 	for _, d := range resp.Data {
 		embedding := make([]float64, len(d.Embedding))
@@ -411,9 +931,11 @@ func convertEmbeddingResponse(resp *openai.CreateEmbeddingResponse) *providers.E
 	}
 
 	result := &providers.EmbeddingResponse{
-		Object: objectList,
-		Data:   data,
-		Model:  resp.Model,
+		Object:     objectList,
+		Data:       data,
+		Model:      cmp.Or(resp.Model, model),
+		Dimensions: providers.EmbeddingDimensions(data),
+		Provider:   name,
 	}
 
 	if resp.Usage.PromptTokens > 0 || resp.Usage.TotalTokens > 0 {
@@ -510,6 +1032,17 @@ func convertParams(params providers.CompletionParams) openai.ChatCompletionNewPa
 		req.ReasoningEffort = shared.ReasoningEffort(params.ReasoningEffort)
 	}
 
+	if len(params.Modalities) > 0 {
+		req.Modalities = params.Modalities
+	}
+
+	if params.Audio != nil {
+		req.Audio = openai.ChatCompletionAudioParam{
+			Format: openai.ChatCompletionAudioParamFormat(params.Audio.Format),
+			Voice:  openai.ChatCompletionAudioParamVoice(params.Audio.Voice),
+		}
+	}
+
 	if params.StreamOptions != nil && params.StreamOptions.IncludeUsage {
 		req.StreamOptions = openai.ChatCompletionStreamOptionsParam{
 			IncludeUsage: openai.Bool(true),
@@ -519,15 +1052,40 @@ func convertParams(params providers.CompletionParams) openai.ChatCompletionNewPa
 	return req
 }
 
+// convertPromptFilterResults converts Azure prompt filter results to provider
+// format.
+func convertPromptFilterResults(raw string) []providers.PromptFilterResult {
+	var prompts []struct {
+		ContentFilterResults json.RawMessage `json:"content_filter_results"` //nolint:tagliatelle // Azure API format.
+		PromptIndex          int             `json:"prompt_index"`           //nolint:tagliatelle // Azure API format.
+	}
+	if err := json.Unmarshal([]byte(raw), &prompts); err != nil {
+		return nil
+	}
+
+	results := make([]providers.PromptFilterResult, 0, len(prompts))
+	for _, prompt := range prompts {
+		results = append(results, providers.PromptFilterResult{
+			PromptIndex:          prompt.PromptIndex,
+			ContentFilterResults: convertContentFilterResults(string(prompt.ContentFilterResults)),
+		})
+	}
+	return results
+}
+
 // convertResponse converts an OpenAI response to provider format.
 func convertResponse(resp *openai.ChatCompletion) *providers.ChatCompletion {
 	choices := make([]providers.Choice, 0, len(resp.Choices))
 	for _, choice := range resp.Choices {
-		choices = append(choices, providers.Choice{
+		result := providers.Choice{
 			Index:        int(choice.Index),
 			Message:      convertResponseMessage(choice.Message),
-			FinishReason: string(choice.FinishReason),
-		})
+			FinishReason: refusalFinishReason(string(choice.FinishReason), choice.Message.Refusal != ""),
+		}
+		if field, ok := choice.JSON.ExtraFields[fieldContentFilterResults]; ok {
+			result.ContentFilterResults = convertContentFilterResults(field.Raw())
+		}
+		choices = append(choices, result)
 	}
 
 	result := &providers.ChatCompletion{
@@ -539,15 +1097,10 @@ func convertResponse(resp *openai.ChatCompletion) *providers.ChatCompletion {
 		SystemFingerprint: resp.SystemFingerprint,
 	}
 
-	if resp.Usage.PromptTokens > 0 || resp.Usage.CompletionTokens > 0 {
-		result.Usage = &providers.Usage{
-			PromptTokens:     int(resp.Usage.PromptTokens),
-			CompletionTokens: int(resp.Usage.CompletionTokens),
-			TotalTokens:      int(resp.Usage.TotalTokens),
-		}
-		if resp.Usage.CompletionTokensDetails.ReasoningTokens > 0 {
-			result.Usage.ReasoningTokens = int(resp.Usage.CompletionTokensDetails.ReasoningTokens)
-		}
+	result.Usage = convertUsage(resp.Usage)
+
+	if field, ok := resp.JSON.ExtraFields[fieldPromptFilterResults]; ok {
+		result.PromptFilterResults = convertPromptFilterResults(field.Raw())
 	}
 
 	return result
@@ -590,6 +1143,16 @@ func convertResponseMessage(msg openai.ChatCompletionMessage) providers.Message
 	result := providers.Message{
 		Role:    string(msg.Role),
 		Content: msg.Content,
+		Refusal: msg.Refusal,
+	}
+
+	if msg.Audio.ID != "" {
+		result.Audio = &providers.Audio{
+			ID:         msg.Audio.ID,
+			Data:       msg.Audio.Data,
+			ExpiresAt:  msg.Audio.ExpiresAt,
+			Transcript: msg.Audio.Transcript,
+		}
 	}
 
 	if len(msg.ToolCalls) > 0 {
@@ -626,7 +1189,7 @@ func convertToolChoice(choice any) openai.ChatCompletionToolChoiceOptionUnionPar
 		}
 	}
 	return openai.ChatCompletionToolChoiceOptionUnionParam{
-		OfAuto: openai.String("auto"),
+		OfAuto: openai.String(providers.ToolChoiceAuto),
 	}
 }
 
@@ -634,23 +1197,44 @@ func convertToolChoice(choice any) openai.ChatCompletionToolChoiceOptionUnionPar
 func convertTools(tools []providers.Tool) []openai.ChatCompletionToolParam {
 	result := make([]openai.ChatCompletionToolParam, 0, len(tools))
 	for _, tool := range tools {
-		result = append(result, openai.ChatCompletionToolParam{
-			Function: openai.FunctionDefinitionParam{
-				Name:        tool.Function.Name,
-				Description: openai.String(tool.Function.Description),
-				Parameters:  openai.FunctionParameters(tool.Function.Parameters),
-			},
-		})
+		function := openai.FunctionDefinitionParam{
+			Name:        tool.Function.Name,
+			Description: openai.String(tool.Function.Description),
+			Parameters:  openai.FunctionParameters(tool.Function.Parameters),
+		}
+		if tool.Function.Strict != nil {
+			function.Strict = openai.Bool(*tool.Function.Strict)
+		}
+		result = append(result, openai.ChatCompletionToolParam{Function: function})
 	}
 	return result
 }
 
+// convertUsage converts OpenAI token usage, returning nil when no usage was reported.
+func convertUsage(usage openai.CompletionUsage) *providers.Usage {
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		return nil
+	}
+
+	return &providers.Usage{
+		PromptTokens:     int(usage.PromptTokens),
+		CompletionTokens: int(usage.CompletionTokens),
+		TotalTokens:      int(usage.TotalTokens),
+		ReasoningTokens:  int(usage.CompletionTokensDetails.ReasoningTokens),
+		CachedTokens:     int(usage.PromptTokensDetails.CachedTokens),
+	}
+}
+
 // convertUserMessage converts a user message to OpenAI format.
 func convertUserMessage(msg providers.Message) openai.ChatCompletionMessageParamUnion {
 	if msg.IsMultiModal() {
 		parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(msg.ContentParts()))
 		for _, part := range msg.ContentParts() {
 			switch part.Type {
+			case contentTypeFile:
+				parts = append(parts, openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+					FileID: openai.String(part.FileID),
+				}))
 			case contentTypeText:
 				parts = append(parts, openai.TextContentPart(part.Text))
 			case contentTypeImageURL:
@@ -666,6 +1250,75 @@ func convertUserMessage(msg providers.Message) openai.ChatCompletionMessageParam
 	return openai.UserMessage(msg.ContentString())
 }
 
+// headerMiddleware returns SDK middleware that sets the headers returned by
+// headers on each request.
+func headerMiddleware(headers func(ctx context.Context) http.Header) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		for name, values := range headers(req.Context()) {
+			req.Header.Del(name)
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+		return next(req)
+	}
+}
+
+// probeFailed reports whether err means the probe could not run, as opposed
+// to the endpoint rejecting the feature being probed.
+func probeFailed(ctx context.Context, err error) bool {
+	return ctx.Err() != nil ||
+		stderrors.Is(err, errors.ErrAuthentication) ||
+		stderrors.Is(err, errors.ErrQuotaExceeded) ||
+		stderrors.Is(err, errors.ErrRateLimit)
+}
+
+// probeResponseFormat returns the JSON schema response format used by Probe.
+func probeResponseFormat() *providers.ResponseFormat {
+	return &providers.ResponseFormat{
+		Type: responseFormatJSONSchema,
+		JSONSchema: &providers.JSONSchema{
+			Name: "probe",
+			Schema: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"ok": map[string]any{"type": "boolean"}},
+				"required":             []string{"ok"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// probeTool returns the tool offered by Probe.
+func probeTool() providers.Tool {
+	return providers.Tool{
+		Type: "function",
+		Function: providers.Function{
+			Name:        "get_time",
+			Description: "Get the current time.",
+			Parameters:  map[string]any{"type": "object", "properties": map[string]any{}},
+		},
+	}
+}
+
+// refusalFinishReason returns reason, or FinishReasonRefusal in place of stop
+// if the choice refused. OpenAI reports a refusal as an ordinary stop with the
+// refusal message set.
+func refusalFinishReason(reason string, refused bool) string {
+	if refused && reason == providers.FinishReasonStop {
+		return providers.FinishReasonRefusal
+	}
+	return reason
+}
+
+// requestOptions returns opts with the SDK retry limit set on ctx, if any.
+func requestOptions(ctx context.Context, opts ...option.RequestOption) []option.RequestOption {
+	if retries, ok := providers.MaxProviderRetries(ctx); ok {
+		opts = append(opts, option.WithMaxRetries(retries))
+	}
+	return opts
+}
+
 // resolveAPIKey resolves the API key from config or environment.
 func resolveAPIKey(cfg *config.Config, compatCfg CompatibleConfig) string {
 	if compatCfg.APIKeyEnvVar != "" {
@@ -674,6 +1327,41 @@ func resolveAPIKey(cfg *config.Config, compatCfg CompatibleConfig) string {
 	return cfg.APIKey
 }
 
+// strictParams rewrites the schemas of strict tools and response formats in
+// params with providers.StrictSchema. Schemas that cannot be rewritten are
+// left for validation to report.
+func strictParams(params providers.CompletionParams) providers.CompletionParams {
+	cloned := false
+	for i, tool := range params.Tools {
+		if tool.Function.Strict == nil || !*tool.Function.Strict || tool.Function.Parameters == nil {
+			continue
+		}
+
+		schema, err := providers.StrictSchema(tool.Function.Parameters)
+		if err != nil {
+			continue
+		}
+		if !cloned {
+			params.Tools = slices.Clone(params.Tools) // The caller's tools must not change.
+			cloned = true
+		}
+		params.Tools[i].Function.Parameters = schema
+	}
+
+	if format := params.ResponseFormat; format != nil && format.JSONSchema != nil &&
+		format.JSONSchema.Strict != nil && *format.JSONSchema.Strict {
+		if schema, err := providers.StrictSchema(format.JSONSchema.Schema); err == nil {
+			jsonSchema := *format.JSONSchema
+			jsonSchema.Schema = schema
+			strictFormat := *format
+			strictFormat.JSONSchema = &jsonSchema
+			params.ResponseFormat = &strictFormat
+		}
+	}
+
+	return params
+}
+
 // validateCompatibleConfig validates the compatible provider configuration.
 func validateCompatibleConfig(cfg CompatibleConfig) error {
 	if cfg.Name == "" {
@@ -698,5 +1386,13 @@ func validateCompletionParams(params providers.CompletionParams) error {
 		}
 	}
 
+	if err := providers.ValidateToolChoice(params.ToolChoice); err != nil {
+		return errors.NewInvalidRequestError("", err)
+	}
+
+	if slices.Contains(params.Modalities, providers.ModalityAudio) && params.Audio == nil {
+		return errors.NewInvalidRequestError("", fmt.Errorf("audio parameters are required for audio output"))
+	}
+
 	return nil
 }
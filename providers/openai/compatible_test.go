@@ -2,15 +2,33 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/openai/openai-go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
+// Canned OpenAI API payloads for tests against a local server.
+const (
+	testChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"rewritten-model",` +
+		`"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}`
+	testCompletionJSON = `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"rewritten-model",` +
+		`"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],` +
+		`"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`
+)
+
 func TestNewCompatible(t *testing.T) {
 	// Note: Not using t.Parallel() here because child test uses t.Setenv.
 
@@ -171,6 +189,20 @@ func TestValidateCompletionParams(t *testing.T) {
 		require.Contains(t, err.Error(), "unknown message role")
 	})
 
+	t.Run("returns error for unknown tool choice", func(t *testing.T) {
+		t.Parallel()
+
+		params := providers.CompletionParams{
+			Model:      "gpt-4",
+			Messages:   []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+			ToolChoice: "sometimes",
+		}
+
+		err := validateCompletionParams(params)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported tool choice")
+	})
+
 	t.Run("accepts valid params", func(t *testing.T) {
 		t.Parallel()
 
@@ -321,3 +353,1191 @@ func TestStreamingContextCancellation(t *testing.T) {
 		// Test passes if it doesn't hang.
 	})
 }
+
+func TestCompatibleProviderHooks(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "rewritten-model", body["model"])
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: " + testChunkJSON + "\n\ndata: [DONE]\n\n")) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+		PostprocessChunk: func(chunk providers.ChatCompletionChunk) providers.ChatCompletionChunk {
+			chunk.Model = "postprocessed"
+			return chunk
+		},
+		PostprocessResponse: func(resp *providers.ChatCompletion) *providers.ChatCompletion {
+			resp.Model = "postprocessed"
+			return resp
+		},
+		PreprocessParams: func(params providers.CompletionParams) providers.CompletionParams {
+			params.Model = "rewritten-model"
+			return params
+		},
+	})
+	require.NoError(t, err)
+
+	params := providers.CompletionParams{
+		Model:    "original-model",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	}
+
+	t.Run("applies hooks to completions", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, "postprocessed", resp.Model)
+	})
+
+	t.Run("applies hooks to streams", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		count := 0
+		for chunk := range chunks {
+			count++
+			require.Equal(t, "postprocessed", chunk.Model)
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, 1, count)
+	})
+}
+
+func TestCompatibleProviderAudioOutput(t *testing.T) {
+	t.Parallel()
+
+	const (
+		audioChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-audio-preview",` +
+			`"choices":[{"index":0,"delta":{"audio":{"id":"audio_1","data":"UklG","transcript":"Hi"}}}]}`
+		audioCompletionJSON = `{"id":"cmpl-1","object":"chat.completion","created":1,` +
+			`"model":"gpt-4o-audio-preview","choices":[{"index":0,"message":{"role":"assistant","content":null,` +
+			`"audio":{"id":"audio_1","data":"UklGRg==","expires_at":1700000000,"transcript":"Hi there"}},` +
+			`"finish_reason":"stop"}]}`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, []any{providers.ModalityText, providers.ModalityAudio}, body["modalities"])
+		require.Equal(t, map[string]any{"voice": "alloy", "format": "wav"}, body["audio"])
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: " + audioChunkJSON + "\n\ndata: [DONE]\n\n")) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(audioCompletionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	params := providers.CompletionParams{
+		Model:      "gpt-4o-audio-preview",
+		Messages:   []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		Modalities: []string{providers.ModalityText, providers.ModalityAudio},
+		Audio:      &providers.AudioParams{Voice: "alloy", Format: "wav"},
+	}
+
+	t.Run("returns audio on the message", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, &providers.Audio{
+			ID:         "audio_1",
+			Data:       "UklGRg==",
+			ExpiresAt:  1700000000,
+			Transcript: "Hi there",
+		}, resp.Choices[0].Message.Audio)
+	})
+
+	t.Run("returns audio deltas when streaming", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		var audio *providers.Audio
+		for chunk := range chunks {
+			if chunk.Choices[0].Delta.Audio != nil {
+				audio = chunk.Choices[0].Delta.Audio
+			}
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, &providers.Audio{ID: "audio_1", Data: "UklG", Transcript: "Hi"}, audio)
+	})
+
+	t.Run("requires audio parameters", func(t *testing.T) {
+		t.Parallel()
+
+		noAudio := params
+		noAudio.Audio = nil
+
+		_, err := provider.Completion(context.Background(), noAudio)
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+func TestCompatibleProviderContentFilterResults(t *testing.T) {
+	t.Parallel()
+
+	// Azure OpenAI reports the prompt verdicts before the choices, and may
+	// return the part of a completion generated before it was filtered.
+	const (
+		promptFilterJSON = `"prompt_filter_results":[{"prompt_index":0,"content_filter_results":{` +
+			`"hate":{"filtered":false,"severity":"safe"},"jailbreak":{"filtered":false,"detected":false}}}]`
+		choiceFilterJSON = `"content_filter_results":{"hate":{"filtered":true,"severity":"high"},` +
+			`"error":{"code":"content_filter_error","message":"The contents are not filtered"}}`
+		completionJSON = `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",` +
+			promptFilterJSON + `,"choices":[{"index":0,"message":{"role":"assistant","content":"Partial"},` +
+			`"finish_reason":"content_filter",` + choiceFilterJSON + `}]}`
+		promptChunkJSON = `{"id":"","object":"","created":0,"model":"","choices":[],` + promptFilterJSON + `}`
+		choiceChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"delta":{"content":"Partial"},"finish_reason":"content_filter",` +
+			choiceFilterJSON + `}]}`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			stream := "data: " + promptChunkJSON + "\n\ndata: " + choiceChunkJSON + "\n\ndata: [DONE]\n\n"
+			_, _ = w.Write([]byte(stream)) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(completionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	params := providers.CompletionParams{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	}
+	wantPrompt := []providers.PromptFilterResult{{
+		PromptIndex: 0,
+		ContentFilterResults: []providers.ContentFilterResult{
+			{Category: "hate", Severity: providers.SeveritySafe},
+			{Category: "jailbreak"},
+		},
+	}}
+	wantChoice := []providers.ContentFilterResult{{Category: "hate", Filtered: true, Severity: providers.SeverityHigh}}
+
+	t.Run("returns filter results with the completion", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, wantPrompt, resp.PromptFilterResults)
+		require.Equal(t, providers.FinishReasonContentFilter, resp.Choices[0].FinishReason)
+		require.Equal(t, "Partial", resp.Choices[0].Message.ContentString())
+		require.Equal(t, wantChoice, resp.Choices[0].ContentFilterResults)
+	})
+
+	t.Run("returns filter results when streaming", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		var acc providers.Accumulator
+		for chunk := range chunks {
+			acc.Add(chunk)
+		}
+		require.NoError(t, <-errs)
+
+		streamed := acc.Completion()
+		require.Equal(t, wantPrompt, streamed.PromptFilterResults)
+		require.Equal(t, wantChoice, streamed.Choices[0].ContentFilterResults)
+	})
+}
+
+func TestCompatibleProviderRefusal(t *testing.T) {
+	t.Parallel()
+
+	// OpenAI reports a refusal as an ordinary stop with the refusal set.
+	const (
+		completionJSON = `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":null,` +
+			`"refusal":"I can't help with that."},"finish_reason":"stop"}]}`
+		refusalChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"delta":{"role":"assistant","refusal":"I can't help"}}]}`
+		restChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"delta":{"refusal":" with that."}}]}`
+		finishChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range []string{refusalChunkJSON, restChunkJSON, finishChunkJSON} {
+				_, _ = w.Write([]byte("data: " + chunk + "\n\n")) // Write error surfaces in the client.
+			}
+			_, _ = w.Write([]byte("data: [DONE]\n\n")) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(completionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	params := providers.CompletionParams{
+		Model:    "gpt-4o",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	}
+
+	t.Run("reports a refusal in the completion", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, providers.FinishReasonRefusal, resp.Choices[0].FinishReason)
+		require.Equal(t, "I can't help with that.", resp.Choices[0].Message.Refusal)
+		require.Empty(t, resp.Choices[0].Message.ContentString())
+	})
+
+	t.Run("reports a refusal when streaming", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		var acc providers.Accumulator
+		for chunk := range chunks {
+			acc.Add(chunk)
+		}
+		require.NoError(t, <-errs)
+
+		streamed := acc.Completion()
+		require.Equal(t, providers.FinishReasonRefusal, streamed.Choices[0].FinishReason)
+		require.Equal(t, "I can't help with that.", streamed.Choices[0].Message.Refusal)
+	})
+}
+
+func TestConvertAssistantMessageRefusal(t *testing.T) {
+	t.Parallel()
+
+	result := convertAssistantMessage(providers.Message{
+		Role:    providers.RoleAssistant,
+		Refusal: "I can't help with that.",
+	})
+
+	require.NotNil(t, result.OfAssistant)
+	require.Equal(t, "I can't help with that.", result.OfAssistant.Refusal.Value)
+}
+
+func TestConvertAssistantMessageAudio(t *testing.T) {
+	t.Parallel()
+
+	result := convertAssistantMessage(providers.Message{
+		Role:  providers.RoleAssistant,
+		Audio: &providers.Audio{ID: "audio_1", Transcript: "Hi there"},
+	})
+
+	require.NotNil(t, result.OfAssistant)
+	require.Equal(t, "audio_1", result.OfAssistant.Audio.ID)
+	require.False(t, result.OfAssistant.Content.OfString.Valid())
+}
+
+func TestCompatibleProviderStreamTimings(t *testing.T) {
+	t.Parallel()
+
+	const (
+		contentChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`
+		finishChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`
+		usageChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{contentChunkJSON, finishChunkJSON, usageChunkJSON} {
+			_, _ = w.Write([]byte("data: " + chunk + "\n\n")) // Write error surfaces in the client.
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n")) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Model:         "m",
+		Messages:      []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		StreamOptions: &providers.StreamOptions{IncludeUsage: true},
+	})
+
+	var received []providers.ChatCompletionChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+	}
+	require.NoError(t, <-errs)
+	require.Len(t, received, 3)
+
+	require.Nil(t, received[0].Timings)
+	require.NotNil(t, received[1].Timings)
+	require.Equal(t, 2, received[1].Timings.ChunkCount)
+
+	final := received[2].Timings
+	require.NotNil(t, final)
+	require.Equal(t, 3, final.ChunkCount)
+	require.Positive(t, final.TokensPerSecond)
+}
+
+func TestCompatibleProviderFinalChunk(t *testing.T) {
+	t.Parallel()
+
+	const (
+		contentChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`
+		finishChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`
+		toolCallChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function",` +
+			`"function":{"name":"get_weather","arguments":"{}"}}]}}]}`
+		usageChunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+			`"choices":[],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`
+	)
+
+	tests := []struct {
+		name         string
+		body         []string
+		includeUsage bool
+		wantChunks   int
+		wantFinal    *providers.ChatCompletionChunk
+	}{
+		{
+			name:         "adds finish reason and usage the server left out",
+			body:         []string{contentChunkJSON},
+			includeUsage: true,
+			wantChunks:   2,
+			wantFinal: &providers.ChatCompletionChunk{
+				Choices: []providers.ChunkChoice{{FinishReason: providers.FinishReasonStop}},
+				Usage:   &providers.Usage{},
+			},
+		},
+		{
+			name:       "finishes tool calls with tool_calls",
+			body:       []string{toolCallChunkJSON},
+			wantChunks: 2,
+			wantFinal: &providers.ChatCompletionChunk{
+				Choices: []providers.ChunkChoice{{FinishReason: providers.FinishReasonToolCalls}},
+			},
+		},
+		{
+			name:         "adds usage after a finish reason",
+			body:         []string{contentChunkJSON, finishChunkJSON},
+			includeUsage: true,
+			wantChunks:   3,
+			wantFinal:    &providers.ChatCompletionChunk{Usage: &providers.Usage{}},
+		},
+		{
+			name:         "adds nothing to a complete stream",
+			body:         []string{contentChunkJSON, finishChunkJSON, usageChunkJSON},
+			includeUsage: true,
+			wantChunks:   3,
+		},
+		{
+			name:       "adds no usage unless asked",
+			body:       []string{contentChunkJSON, finishChunkJSON},
+			wantChunks: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range tc.body {
+					_, _ = w.Write([]byte("data: " + chunk + "\n\n")) // Write error surfaces in the client.
+				}
+				_, _ = w.Write([]byte("data: [DONE]\n\n")) // Write error surfaces in the client.
+			}))
+			t.Cleanup(server.Close)
+
+			provider, err := NewCompatible(CompatibleConfig{
+				DefaultAPIKey:  "test-key",
+				DefaultBaseURL: server.URL,
+				Name:           "test-provider",
+			})
+			require.NoError(t, err)
+
+			chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+				Model:         "m",
+				Messages:      []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+				StreamOptions: &providers.StreamOptions{IncludeUsage: tc.includeUsage},
+			})
+
+			var received []providers.ChatCompletionChunk
+			for chunk := range chunks {
+				received = append(received, chunk)
+			}
+			require.NoError(t, <-errs)
+			require.Len(t, received, tc.wantChunks)
+
+			if tc.wantFinal == nil {
+				return
+			}
+			final := received[len(received)-1]
+			require.Equal(t, "chunk-1", final.ID)
+			require.Equal(t, "m", final.Model)
+			require.Equal(t, tc.wantFinal.Choices, final.Choices)
+			require.Equal(t, tc.wantFinal.Usage, final.Usage)
+			require.NotNil(t, final.Timings)
+		})
+	}
+}
+
+func TestCompatibleProviderInlineThinking(t *testing.T) {
+	t.Parallel()
+
+	const completionJSON = `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"m",` +
+		`"choices":[{"index":0,"message":{"role":"assistant","content":"<think>Hm</think>\n\nHi"},` +
+		`"finish_reason":"stop"}]}`
+
+	pieces := []string{"<thi", "nk>Hm</thi", "nk>\\n\\nH", "i"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body) // Malformed bodies are answered as non-streaming.
+		if body["stream"] != true {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(completionJSON)) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, piece := range pieces {
+			finishReason := "null"
+			if i == len(pieces)-1 {
+				finishReason = `"stop"`
+			}
+			_, _ = fmt.Fprintf(w, `data: {"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",`+
+				`"choices":[{"index":0,"delta":{"content":"%s"},"finish_reason":%s}]}`+"\n\n", piece, finishReason)
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n")) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	params := providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	}
+
+	newProvider := func(t *testing.T, policy config.ReasoningPolicy) *CompatibleProvider {
+		t.Helper()
+
+		provider, err := NewCompatible(CompatibleConfig{
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: server.URL,
+			InlineThinking: true,
+			Name:           "test-provider",
+		}, config.WithReasoningPolicy(policy))
+		require.NoError(t, err)
+		return provider
+	}
+
+	tests := []struct {
+		name          string
+		policy        config.ReasoningPolicy
+		wantContent   string
+		wantReasoning string
+	}{
+		{
+			name:          "separates reasoning",
+			policy:        config.ReasoningSeparate,
+			wantContent:   "Hi",
+			wantReasoning: "Hm",
+		},
+		{
+			name:        "strips reasoning",
+			policy:      config.ReasoningStrip,
+			wantContent: "Hi",
+		},
+		{
+			name:        "keeps reasoning in the content",
+			policy:      config.ReasoningKeep,
+			wantContent: "<think>Hm</think>\n\nHi",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := newProvider(t, tc.policy)
+
+			resp, err := provider.Completion(context.Background(), params)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantContent, resp.Choices[0].Message.Content)
+			if tc.wantReasoning == "" {
+				require.Nil(t, resp.Choices[0].Message.Reasoning)
+			} else {
+				require.Equal(t, &providers.Reasoning{Content: tc.wantReasoning}, resp.Choices[0].Message.Reasoning)
+			}
+
+			chunks, errs := provider.CompletionStream(context.Background(), params)
+
+			var content, reasoning strings.Builder
+			for chunk := range chunks {
+				delta := chunk.Choices[0].Delta
+				if tc.policy != config.ReasoningKeep {
+					require.NotContains(t, delta.Content, "think")
+				}
+				content.WriteString(delta.Content)
+				if delta.Reasoning != nil {
+					reasoning.WriteString(delta.Reasoning.Content)
+				}
+			}
+			require.NoError(t, <-errs)
+			require.Equal(t, tc.wantContent, content.String())
+			require.Equal(t, tc.wantReasoning, reasoning.String())
+		})
+	}
+}
+
+func TestCompatibleProviderRequestID(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(headerRequestID, "req_123")
+		_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "req_123", resp.RequestID)
+}
+
+func TestCompatibleProviderAppInfo(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	}, config.WithAppInfo("myapp", "1.2.0"), config.WithAppURL("https://example.com"))
+	require.NoError(t, err)
+
+	_, err = provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	require.Regexp(t, `^myapp/1\.2\.0 OpenAI/Go `, header.Get("User-Agent"))
+	require.Equal(t, "myapp", header.Get("X-Title"))
+	require.Equal(t, "https://example.com", header.Get("HTTP-Referer"))
+}
+
+func TestCompatibleProviderRateLimit(t *testing.T) {
+	t.Parallel()
+
+	const chunkJSON = `{"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"m",` +
+		`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "60")
+		w.Header().Set("x-ratelimit-remaining-requests", "59")
+		w.Header().Set("x-ratelimit-reset-requests", "1s")
+		w.Header().Set("x-ratelimit-limit-tokens", "150000")
+		w.Header().Set("x-ratelimit-remaining-tokens", "149984")
+		w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body) // Malformed bodies are answered as non-streaming.
+		if body["stream"] != true {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 2 {
+			_, _ = w.Write([]byte("data: " + chunkJSON + "\n\n")) // Write error surfaces in the client.
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n")) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	params := providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	}
+
+	want := &providers.RateLimitState{
+		Requests: &providers.RateLimit{Limit: 60, Remaining: 59, Reset: time.Second},
+		Tokens:   &providers.RateLimit{Limit: 150000, Remaining: 149984, Reset: 6 * time.Minute},
+	}
+
+	t.Run("completion", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, want, resp.RateLimit)
+	})
+
+	t.Run("first stream chunk", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+
+		var received []providers.ChatCompletionChunk
+		for chunk := range chunks {
+			received = append(received, chunk)
+		}
+		require.NoError(t, <-errs)
+		require.Len(t, received, 3) // The server sends no finish reason, so a final chunk is added.
+		require.Equal(t, want, received[0].RateLimit)
+		require.Nil(t, received[1].RateLimit)
+		require.Nil(t, received[2].RateLimit)
+	})
+}
+
+func TestCompatibleProviderDryRun(t *testing.T) {
+	t.Parallel()
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: "http://127.0.0.1:0",
+		Name:           "test-provider",
+		PreprocessParams: func(params providers.CompletionParams) providers.CompletionParams {
+			params.Model = "rewritten-" + params.Model
+			return params
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("returns the request body", func(t *testing.T) {
+		t.Parallel()
+
+		body, err := provider.DryRun(context.Background(), providers.CompletionParams{
+			Model:    "m",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+			Tools:    []providers.Tool{testutil.WeatherTool()},
+		})
+		require.NoError(t, err)
+
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(body, &req))
+		require.Equal(t, "rewritten-m", req["model"])
+		require.Len(t, req["messages"], 1)
+		require.Len(t, req["tools"], 1)
+	})
+
+	t.Run("validates params", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := provider.DryRun(context.Background(), providers.CompletionParams{Model: "m"})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+func TestCompatibleProviderStrictSchemas(t *testing.T) {
+	t.Parallel()
+
+	strict := true
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"location": map[string]any{"type": "string"},
+			"unit":     map[string]any{"type": "string", "default": "celsius"},
+		},
+		"required": []string{"location"},
+	}
+	params := providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+		Tools: []providers.Tool{{
+			Type:     "function",
+			Function: providers.Function{Name: "get_weather", Parameters: schema, Strict: &strict},
+		}},
+		ResponseFormat: &providers.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &providers.JSONSchema{Name: "weather", Schema: schema, Strict: &strict},
+		},
+	}
+
+	dryRun := func(t *testing.T, opts ...config.Option) (tool, format map[string]any) {
+		t.Helper()
+
+		provider, err := NewCompatible(CompatibleConfig{
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: "http://127.0.0.1:0",
+			Name:           "test-provider",
+		}, opts...)
+		require.NoError(t, err)
+
+		body, err := provider.DryRun(context.Background(), params)
+		require.NoError(t, err)
+
+		var req struct {
+			ResponseFormat struct {
+				JSONSchema struct {
+					Schema map[string]any `json:"schema"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+			Tools []struct {
+				Function struct {
+					Parameters map[string]any `json:"parameters"`
+					Strict     bool           `json:"strict"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.Unmarshal(body, &req))
+		require.True(t, req.Tools[0].Function.Strict)
+		return req.Tools[0].Function.Parameters, req.ResponseFormat.JSONSchema.Schema
+	}
+
+	t.Run("rewrites strict schemas", func(t *testing.T) {
+		t.Parallel()
+
+		want := map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"location": map[string]any{"type": "string"},
+				"unit":     map[string]any{"type": []any{"string", "null"}},
+			},
+			"required":             []any{"location", "unit"},
+			"additionalProperties": false,
+		}
+
+		tool, format := dryRun(t)
+		require.Equal(t, want, tool)
+		require.Equal(t, want, format)
+		require.Equal(t, []string{"location"}, schema["required"], "the caller's schema must not change")
+	})
+
+	t.Run("sends raw schemas when asked", func(t *testing.T) {
+		t.Parallel()
+
+		tool, format := dryRun(t, config.WithRawSchemas())
+		require.NotContains(t, tool, "additionalProperties")
+		require.NotContains(t, format, "additionalProperties")
+	})
+}
+
+func TestCompatibleProviderCachedTokens(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cmpl-1","object":"chat.completion","created":1,"model":"m",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":2048,"completion_tokens":10,"total_tokens":2058,` +
+			`"prompt_tokens_details":{"cached_tokens":1536}}}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1536, resp.Usage.CachedTokens)
+	require.InDelta(t, 0.75, resp.Usage.CacheHitRatio(), 1e-9)
+}
+
+func TestCompatibleProviderProbe(t *testing.T) {
+	t.Parallel()
+
+	// newServer starts a server that serves models unless listModels is false,
+	// rejects tools, and answers every other chat request with status.
+	newServer := func(t *testing.T, status int, listModels bool) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			if r.URL.Path == "/models" {
+				if !listModels {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error":{"message":"not found"}}`)) // Write error surfaces in the client.
+					return
+				}
+				_, _ = w.Write([]byte(`{"object":"list","data":[` +
+					`{"id":"served-model","object":"model","created":1,"owned_by":"vllm"}]}`)) // Write error surfaces in the client.
+				return
+			}
+
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body) // Malformed bodies are answered as plain completions.
+
+			switch {
+			case status != http.StatusOK:
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"error":{"message":"denied"}}`)) // Write error surfaces in the client.
+			case body["model"] != "served-model":
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"message":"unknown model"}}`)) // Write error surfaces in the client.
+			case body["tools"] != nil:
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"message":"tools are not supported"}}`)) // Write error surfaces in the client.
+			case body["stream"] == true:
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte("data: " + testChunkJSON + "\n\ndata: [DONE]\n\n")) // Write error surfaces in the client.
+			default:
+				_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	newProvider := func(t *testing.T, server *httptest.Server) *CompatibleProvider {
+		t.Helper()
+
+		provider, err := NewCompatible(CompatibleConfig{
+			Capabilities:   providers.Capabilities{CompletionImage: true, CompletionTools: true},
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: server.URL,
+			Name:           "test-provider",
+		})
+		require.NoError(t, err)
+
+		return provider
+	}
+
+	t.Run("detects supported features", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newProvider(t, newServer(t, http.StatusOK, true))
+
+		caps, err := provider.Probe(context.Background(), "")
+		require.NoError(t, err)
+
+		want := providers.Capabilities{
+			Completion:           true,
+			CompletionImage:      true, // Not probed, so the configured value is kept.
+			CompletionJSONObject: true,
+			CompletionJSONSchema: true,
+			CompletionStreaming:  true,
+			CompletionTools:      false,
+			ListModels:           true,
+		}
+		require.Equal(t, want, caps)
+		require.Equal(t, want, provider.Capabilities())
+	})
+
+	t.Run("requires a tool call to report tool support", func(t *testing.T) {
+		t.Parallel()
+
+		toolCallJSON := `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"served-model",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[` +
+			`{"id":"call_1","type":"function","function":{"name":"get_time","arguments":"{}"}}]},` +
+			`"finish_reason":"tool_calls"}]}`
+
+		tests := []struct {
+			name     string
+			reply    string
+			expected bool
+		}{
+			{name: "reply without a tool call", reply: testCompletionJSON, expected: false},
+			{name: "reply with a tool call", reply: toolCallJSON, expected: true},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+
+					var body map[string]any
+					_ = json.NewDecoder(r.Body).Decode(&body) // Malformed bodies are answered as plain completions.
+
+					switch {
+					case r.URL.Path == "/models":
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"error":{"message":"none"}}`)) // Write error surfaces in the client.
+					case body["stream"] == true:
+						stream := "data: " + testChunkJSON + "\n\ndata: [DONE]\n\n"
+						w.Header().Set("Content-Type", "text/event-stream")
+						_, _ = w.Write([]byte(stream)) // Write error surfaces in the client.
+					case body["tools"] != nil:
+						_, _ = w.Write([]byte(tc.reply)) // Write error surfaces in the client.
+					default:
+						_, _ = w.Write([]byte(testCompletionJSON)) // Write error surfaces in the client.
+					}
+				}))
+				t.Cleanup(server.Close)
+
+				caps, err := newProvider(t, server).Probe(context.Background(), "served-model")
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, caps.CompletionTools)
+			})
+		}
+	})
+
+	t.Run("probes the given model when listing fails", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newProvider(t, newServer(t, http.StatusOK, false))
+
+		caps, err := provider.Probe(context.Background(), "served-model")
+		require.NoError(t, err)
+		require.True(t, caps.Completion)
+		require.False(t, caps.ListModels)
+	})
+
+	t.Run("requires a model when listing fails", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newProvider(t, newServer(t, http.StatusOK, false))
+
+		_, err := provider.Probe(context.Background(), "")
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("keeps capabilities when the probe fails", func(t *testing.T) {
+		t.Parallel()
+
+		provider := newProvider(t, newServer(t, http.StatusUnauthorized, true))
+
+		_, err := provider.Probe(context.Background(), "")
+		require.ErrorIs(t, err, errors.ErrAuthentication)
+		require.True(t, provider.Capabilities().CompletionTools)
+	})
+
+	t.Run("overrides apply to probed capabilities", func(t *testing.T) {
+		t.Parallel()
+
+		server := newServer(t, http.StatusOK, true)
+		provider, err := NewCompatible(CompatibleConfig{
+			Capabilities:   providers.Capabilities{CompletionTools: true},
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: server.URL,
+			Name:           "test-provider",
+		}, config.WithCapabilityOverrides(map[string]bool{config.CapabilityCompletionTools: true}))
+		require.NoError(t, err)
+
+		caps, err := provider.Probe(context.Background(), "")
+		require.NoError(t, err)
+		require.False(t, caps.CompletionTools) // The probe reports what the endpoint accepted.
+		require.True(t, provider.Capabilities().CompletionTools)
+	})
+}
+
+func TestCompatibleProviderDeadline(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the context error without retrying", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := testutil.NewHangingServer(t)
+
+		provider, err := NewCompatible(CompatibleConfig{
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: server.URL,
+			Name:           "test-provider",
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = provider.Completion(ctx, providers.CompletionParams{
+			Model:    "m",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Len(t, requests, 1)
+	})
+
+	t.Run("limits SDK retries per call", func(t *testing.T) {
+		t.Parallel()
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			attempts.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"unavailable"}}`)) // Write error surfaces in the client.
+		}))
+		t.Cleanup(server.Close)
+
+		provider, err := NewCompatible(CompatibleConfig{
+			DefaultAPIKey:  "test-key",
+			DefaultBaseURL: server.URL,
+			Name:           "test-provider",
+		})
+		require.NoError(t, err)
+
+		_, err = provider.Completion(providers.WithMaxProviderRetries(context.Background(), 0), providers.CompletionParams{
+			Model:    "m",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.Error(t, err)
+		require.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestCompatibleProviderStreamCancellation(t *testing.T) {
+	// Note: Not using t.Parallel() here because the leak check would see the
+	// goroutines of concurrent tests.
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	server, released := testutil.NewStallingServer(t, "text/event-stream",
+		strings.Repeat("data: "+testChunkJSON+"\n\n", 2))
+
+	provider, err := NewCompatible(CompatibleConfig{
+		DefaultAPIKey:  "test-key",
+		DefaultBaseURL: server.URL,
+		Name:           "test-provider",
+	})
+	require.NoError(t, err)
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "m",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
+
+func FuzzConvertMessages(f *testing.F) {
+	testutil.AddConversationSeeds(f)
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		messages := testutil.RandomConversation(seed)
+
+		converted, err := convertMessages(messages)
+		require.NoError(t, err)
+
+		// Tool results are paired with calls by ID; their names are not sent.
+		want := testutil.MessageEvents(messages)
+		for i := range want {
+			if want[i].Kind == testutil.EventToolResult {
+				want[i].Name = ""
+			}
+		}
+
+		got := requestEvents(converted)
+		require.Equal(t, want, got)
+		testutil.RequireToolPairing(t, got)
+
+		_, err = convertMessages(testutil.WithUnknownRole(messages, seed))
+		require.ErrorContains(t, err, "unknown message role")
+	})
+}
+
+// requestEvents flattens the messages of a request into events.
+func requestEvents(messages []openai.ChatCompletionMessageParamUnion) []testutil.MessageEvent {
+	var events []testutil.MessageEvent
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			events = append(events, testutil.TextEvent(providers.RoleSystem, msg.OfSystem.Content.OfString.Value))
+		case msg.OfUser != nil:
+			if text := msg.OfUser.Content.OfString; text.Valid() {
+				events = append(events, testutil.TextEvent(providers.RoleUser, text.Value))
+			}
+			for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+				switch {
+				case part.OfImageURL != nil:
+					events = append(events, testutil.ImageEvent(providers.RoleUser, part.OfImageURL.ImageURL.URL))
+				case part.OfText != nil:
+					events = append(events, testutil.TextEvent(providers.RoleUser, part.OfText.Text))
+				default:
+					events = append(events, testutil.TextEvent(providers.RoleUser, ""))
+				}
+			}
+		case msg.OfAssistant != nil:
+			if text := msg.OfAssistant.Content.OfString; text.Valid() && text.Value != "" {
+				events = append(events, testutil.TextEvent(providers.RoleAssistant, text.Value))
+			}
+			for _, tc := range msg.OfAssistant.ToolCalls {
+				events = append(events, testutil.ToolCallEvent(providers.RoleAssistant, tc.ID, tc.Function.Name,
+					tc.Function.Arguments))
+			}
+		case msg.OfTool != nil:
+			events = append(events, testutil.ToolResultEvent(msg.OfTool.ToolCallID, "", msg.OfTool.Content.OfString.Value))
+		default:
+		}
+	}
+	return events
+}
@@ -1,6 +1,8 @@
 package openai
 
 import (
+	"context"
+
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
@@ -15,9 +17,11 @@ const (
 // Ensure Provider implements the required interfaces.
 var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
 	_ providers.EmbeddingProvider  = (*Provider)(nil)
 	_ providers.ErrorConverter     = (*Provider)(nil)
 	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
 	_ providers.Provider           = (*Provider)(nil)
 )
 
@@ -27,6 +31,12 @@ type Provider struct {
 	*CompatibleProvider
 }
 
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new OpenAI provider.
 func New(opts ...config.Option) (*Provider, error) {
 	base, err := NewCompatible(CompatibleConfig{
@@ -35,6 +45,7 @@ func New(opts ...config.Option) (*Provider, error) {
 		DefaultBaseURL: defaultBaseURL,
 		Name:           providerName,
 		RequireAPIKey:  true,
+		SamplingLimits: openAISamplingLimits,
 	}, opts...)
 	if err != nil {
 		return nil, err
@@ -43,15 +54,32 @@ func New(opts ...config.Option) (*Provider, error) {
 	return &Provider{CompatibleProvider: base}, nil
 }
 
+// isOSeriesModel reports whether model is an o-series reasoning model such as "o3" or "o4-mini".
+func isOSeriesModel(model string) bool {
+	if len(model) < 2 || model[0] != 'o' || model[1] < '0' || model[1] > '9' {
+		return false
+	}
+	return len(model) == 2 || model[2] == '-'
+}
+
 // openAICapabilities returns the capabilities for the OpenAI provider.
 func openAICapabilities() providers.Capabilities {
 	return providers.Capabilities{
-		Completion:          true,
-		CompletionImage:     true,
-		CompletionPDF:       false,
-		CompletionReasoning: true,
-		CompletionStreaming: true,
-		Embedding:           true,
-		ListModels:          true,
+		Completion:           true,
+		CompletionImage:      true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            true,
+		ListModels:           true,
 	}
 }
+
+// openAISamplingLimits returns the sampling limits of an OpenAI model.
+// The o-series reasoning models (o1, o3, o4-mini, ...) reject temperature and top_p.
+func openAISamplingLimits(model string) providers.SamplingLimits {
+	return providers.SamplingLimits{FixedSampling: isOSeriesModel(model)}
+}
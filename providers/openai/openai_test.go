@@ -60,6 +60,8 @@ func TestCapabilities(t *testing.T) {
 	require.True(t, caps.CompletionStreaming)
 	require.True(t, caps.CompletionReasoning)
 	require.True(t, caps.CompletionImage)
+	require.True(t, caps.CompletionJSONSchema)
+	require.True(t, caps.CompletionTools)
 	require.True(t, caps.Embedding)
 	require.True(t, caps.ListModels)
 }
@@ -331,6 +333,25 @@ func TestConvertMessage(t *testing.T) {
 		require.NotNil(t, result)
 	})
 
+	t.Run("converts file part to file input", func(t *testing.T) {
+		t.Parallel()
+
+		msg := providers.Message{
+			Role: providers.RoleUser,
+			Content: []providers.ContentPart{
+				{Type: contentTypeText, Text: "Summarize this report."},
+				{Type: contentTypeFile, FileID: "file-abc123"},
+			},
+		}
+		result, err := convertMessage(msg)
+		require.NoError(t, err)
+
+		parts := result.OfUser.Content.OfArrayOfContentParts
+		require.Len(t, parts, 2)
+		require.NotNil(t, parts[1].OfFile)
+		require.Equal(t, "file-abc123", parts[1].OfFile.File.FileID.Value)
+	})
+
 	t.Run("returns error for unknown role", func(t *testing.T) {
 		t.Parallel()
 
@@ -442,6 +463,57 @@ func TestConvertTools(t *testing.T) {
 
 // Integration tests - only run if API key is available.
 
+func TestSamplingLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		model string
+		fixed bool
+	}{
+		{model: "gpt-4o-mini"},
+		{model: "o1", fixed: true},
+		{model: "o3-mini", fixed: true},
+		{model: "o4-mini-2025-04-16", fixed: true},
+		{model: "omni-moderation-latest"},
+		{model: "o"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.model, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.fixed, openAISamplingLimits(tc.model).FixedSampling)
+		})
+	}
+
+	t.Run("rejects temperature for o-series models", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(config.WithAPIKey("test-key"))
+		require.NoError(t, err)
+
+		temperature := 0.2
+		_, err = provider.DryRun(context.Background(), providers.CompletionParams{
+			Model:       "o3-mini",
+			Messages:    testutil.SimpleMessages(),
+			Temperature: &temperature,
+		})
+
+		var invalidErr *errors.InvalidRequestError
+		require.ErrorAs(t, err, &invalidErr)
+		require.Equal(t, "temperature", invalidErr.Param)
+		require.Equal(t, providerName, invalidErr.Provider)
+	})
+}
+
+func TestStreamParity(t *testing.T) {
+	t.Parallel()
+
+	testutil.VerifyStreamParity(t, providerName, func(opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 func TestIntegrationCompletion(t *testing.T) {
 	t.Parallel()
 
@@ -811,6 +883,11 @@ func TestConvertError(t *testing.T) {
 			err:          newTestAPIError(t, 429, ""),
 			wantSentinel: errors.ErrRateLimit,
 		},
+		{
+			name:         "429 with insufficient_quota becomes QuotaExceededError",
+			err:          newTestAPIError(t, 429, apiCodeInsufficientQuota),
+			wantSentinel: errors.ErrQuotaExceeded,
+		},
 		{
 			name:         "404 status becomes ModelNotFoundError",
 			err:          newTestAPIError(t, 404, ""),
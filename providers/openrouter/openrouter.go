@@ -0,0 +1,246 @@
+// Package openrouter provides an OpenRouter provider implementation for any-llm.
+// OpenRouter routes requests to many upstream providers through one
+// OpenAI-compatible API. Its routing features, such as provider preferences,
+// fallback models and prompt transforms, are set with Options.
+package openrouter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// Provider configuration constants.
+const (
+	defaultBaseURL = "https://openrouter.ai/api/v1"
+	envAPIKey      = "OPENROUTER_API_KEY"
+	providerName   = "openrouter"
+)
+
+// extraOptions is the configuration key of the options set with WithOptions.
+const extraOptions = "openrouter_options"
+
+// Request body fields.
+const (
+	fieldModels     = "models"
+	fieldProvider   = "provider"
+	fieldTransforms = "transforms"
+)
+
+// Values of ProviderPreferences.DataCollection.
+const (
+	DataCollectionAllow = "allow"
+	DataCollectionDeny  = "deny"
+)
+
+// Values of ProviderPreferences.Sort.
+const (
+	SortLatency    = "latency"
+	SortPrice      = "price"
+	SortThroughput = "throughput"
+)
+
+// TransformMiddleOut compresses prompts that exceed the model's context by
+// removing messages from the middle of the conversation.
+const TransformMiddleOut = "middle-out"
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
+	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Options are OpenRouter's routing options. Fields left nil are not sent, so
+// OpenRouter's defaults apply.
+type Options struct {
+	// Models are fallback models, tried in order when the request's model is
+	// unavailable, rate limited or refuses the request.
+	Models []string
+
+	// Provider sets which upstream providers serve the request.
+	Provider *ProviderPreferences
+
+	// Transforms are prompt transforms, such as TransformMiddleOut. An empty,
+	// non-nil slice turns off the transforms OpenRouter applies by default.
+	Transforms []string
+}
+
+// Provider implements the providers.Provider interface for OpenRouter.
+// It embeds openai.CompatibleProvider since OpenRouter exposes an OpenAI-compatible API.
+type Provider struct {
+	*openai.CompatibleProvider
+}
+
+// ProviderPreferences control which upstream providers serve a request. See
+// OpenRouter's provider routing documentation for the provider names.
+type ProviderPreferences struct {
+	// AllowFallbacks allows providers other than those in Order when they
+	// are all unavailable. OpenRouter's default is true.
+	AllowFallbacks *bool `json:"allow_fallbacks,omitempty"`
+
+	// DataCollection is DataCollectionDeny to use only providers that do not
+	// store or train on prompts.
+	DataCollection string `json:"data_collection,omitempty"`
+
+	// Ignore lists providers never to use.
+	Ignore []string `json:"ignore,omitempty"`
+
+	// Only lists the providers allowed to serve the request.
+	Only []string `json:"only,omitempty"`
+
+	// Order lists providers to try first, in order.
+	Order []string `json:"order,omitempty"`
+
+	// Quantizations lists the model quantizations allowed, such as "fp8".
+	Quantizations []string `json:"quantizations,omitempty"`
+
+	// RequireParameters uses only providers that support every parameter of
+	// the request, such as tools or a response format.
+	RequireParameters *bool `json:"require_parameters,omitempty"`
+
+	// Sort orders providers by SortPrice, SortThroughput or SortLatency
+	// instead of OpenRouter's load balancing.
+	Sort string `json:"sort,omitempty"`
+}
+
+// optionsKey is the context key for per-call options.
+type optionsKey struct{}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+// New creates a new OpenRouter provider. To attribute traffic to your
+// application in OpenRouter's rankings, use config.WithAppInfo and
+// config.WithAppURL, which set the X-Title and HTTP-Referer headers.
+func New(opts ...config.Option) (*Provider, error) {
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	var defaults Options
+	if v, ok := cfg.ExtraValue(extraOptions); ok {
+		defaults, _ = v.(Options) // Set by WithOptions.
+	}
+
+	base, err := openai.NewCompatible(openai.CompatibleConfig{
+		APIKeyEnvVar:   envAPIKey,
+		BaseURLEnvVar:  "",
+		Capabilities:   openrouterCapabilities(),
+		DefaultAPIKey:  "",
+		DefaultBaseURL: defaultBaseURL,
+		ExtraFields: func(ctx context.Context, _ providers.CompletionParams) map[string]any {
+			return defaults.merge(optionsFrom(ctx)).fields()
+		},
+		InlineThinking:      false,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    nil,
+		RequireAPIKey:       true,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{CompatibleProvider: base}, nil
+}
+
+// WithOptions sets the routing options of every request. Options set on a
+// call with WithRequestOptions take precedence, field by field.
+func WithOptions(opts Options) config.Option {
+	return func(c *config.Config) error {
+		if err := opts.validate(); err != nil {
+			return err
+		}
+		return config.WithExtra(extraOptions, opts)(c)
+	}
+}
+
+// WithRequestOptions returns a context that sets the routing options of the
+// requests sent with it. Its non-nil fields replace those set with
+// WithOptions.
+func WithRequestOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// fields returns the request body fields for o.
+func (o Options) fields() map[string]any {
+	fields := make(map[string]any)
+	if o.Models != nil {
+		fields[fieldModels] = o.Models
+	}
+	if o.Provider != nil {
+		fields[fieldProvider] = o.Provider
+	}
+	if o.Transforms != nil {
+		fields[fieldTransforms] = o.Transforms
+	}
+	return fields
+}
+
+// merge returns o with the non-nil fields of override.
+func (o Options) merge(override Options) Options {
+	if override.Models != nil {
+		o.Models = override.Models
+	}
+	if override.Provider != nil {
+		o.Provider = override.Provider
+	}
+	if override.Transforms != nil {
+		o.Transforms = override.Transforms
+	}
+	return o
+}
+
+// validate checks the values of o that OpenRouter restricts.
+func (o Options) validate() error {
+	if o.Provider == nil {
+		return nil
+	}
+
+	switch o.Provider.DataCollection {
+	case "", DataCollectionAllow, DataCollectionDeny:
+	default:
+		return fmt.Errorf("unknown data collection policy %q", o.Provider.DataCollection)
+	}
+	switch o.Provider.Sort {
+	case "", SortLatency, SortPrice, SortThroughput:
+	default:
+		return fmt.Errorf("unknown provider sort %q", o.Provider.Sort)
+	}
+	return nil
+}
+
+// openrouterCapabilities returns the capabilities for the OpenRouter provider.
+// They depend on the model; Probe a model to find out what it supports.
+func openrouterCapabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      true, // Vision models only.
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        false,
+		CompletionReasoning:  false, // OpenRouter takes reasoning settings in its own "reasoning" object.
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		Embedding:            false,
+		ListModels:           true,
+	}
+}
+
+// optionsFrom returns the per-call options in ctx.
+func optionsFrom(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options) // Zero when unset.
+	return opts
+}
@@ -0,0 +1,157 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testParams are the parameters of a minimal completion request.
+var testParams = providers.CompletionParams{
+	Model:    "anthropic/claude-3.5-sonnet",
+	Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hello"}},
+}
+
+// testBody returns the request body the provider would send with ctx, as a map.
+func testBody(t *testing.T, ctx context.Context, provider *Provider) map[string]any {
+	t.Helper()
+
+	raw, err := provider.DryRun(ctx, testParams)
+	require.NoError(t, err)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(raw, &body))
+	return body
+}
+
+func TestNew(t *testing.T) {
+	// Note: Not using t.Parallel() here because child test uses t.Setenv.
+
+	t.Run("returns error when API key is missing", func(t *testing.T) {
+		t.Setenv(envAPIKey, "")
+
+		provider, err := New()
+		require.Nil(t, provider)
+
+		var missingKeyErr *errors.MissingAPIKeyError
+		require.ErrorAs(t, err, &missingKeyErr)
+		require.Equal(t, envAPIKey, missingKeyErr.EnvVar)
+	})
+
+	t.Run("rejects unknown option values", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(config.WithAPIKey("sk-or-test"), WithOptions(Options{Provider: &ProviderPreferences{Sort: "cost"}}))
+		require.ErrorContains(t, err, `unknown provider sort "cost"`)
+
+		_, err = New(config.WithAPIKey("sk-or-test"), WithOptions(Options{Provider: &ProviderPreferences{DataCollection: "never"}}))
+		require.ErrorContains(t, err, `unknown data collection policy "never"`)
+	})
+}
+
+func TestOptions(t *testing.T) {
+	t.Parallel()
+
+	allowFallbacks := false
+	provider, err := New(
+		config.WithAPIKey("sk-or-test"),
+		WithOptions(Options{
+			Models: []string{"openai/gpt-4o"},
+			Provider: &ProviderPreferences{
+				AllowFallbacks: &allowFallbacks,
+				DataCollection: DataCollectionDeny,
+				Order:          []string{"Anthropic", "Amazon Bedrock"},
+				Sort:           SortThroughput,
+			},
+		}),
+	)
+	require.NoError(t, err)
+
+	t.Run("sends the provider's options", func(t *testing.T) {
+		t.Parallel()
+
+		body := testBody(t, context.Background(), provider)
+		require.Equal(t, []any{"openai/gpt-4o"}, body["models"])
+		require.Equal(t, map[string]any{
+			"allow_fallbacks": false,
+			"data_collection": "deny",
+			"order":           []any{"Anthropic", "Amazon Bedrock"},
+			"sort":            "throughput",
+		}, body["provider"])
+		require.NotContains(t, body, "transforms")
+	})
+
+	t.Run("request options replace them field by field", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := WithRequestOptions(context.Background(), Options{
+			Provider:   &ProviderPreferences{Only: []string{"Groq"}},
+			Transforms: []string{},
+		})
+		body := testBody(t, ctx, provider)
+		require.Equal(t, []any{"openai/gpt-4o"}, body["models"])
+		require.Equal(t, map[string]any{"only": []any{"Groq"}}, body["provider"])
+		require.Equal(t, []any{}, body["transforms"])
+	})
+
+	t.Run("sends nothing by default", func(t *testing.T) {
+		t.Parallel()
+
+		plain, err := New(config.WithAPIKey("sk-or-test"))
+		require.NoError(t, err)
+
+		body := testBody(t, context.Background(), plain)
+		require.NotContains(t, body, "models")
+		require.NotContains(t, body, "provider")
+		require.NotContains(t, body, "transforms")
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"gen-1","object":"chat.completion","model":"anthropic/claude-3.5-sonnet",` + // Write error surfaces in the client.
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(
+		config.WithAPIKey("sk-or-test"),
+		config.WithBaseURL(server.URL+"/api/v1"),
+		config.WithAppInfo("myapp", ""),
+		config.WithAppURL("https://myapp.example.com"),
+	)
+	require.NoError(t, err)
+
+	ctx := WithRequestOptions(context.Background(), Options{Transforms: []string{TransformMiddleOut}})
+	resp, err := provider.Completion(ctx, testParams)
+	require.NoError(t, err)
+	require.Equal(t, "Hi", resp.Choices[0].Message.ContentString())
+
+	require.Equal(t, []any{"middle-out"}, body["transforms"])
+	require.Equal(t, "myapp", header.Get("X-Title"))
+	require.Equal(t, "https://myapp.example.com", header.Get("HTTP-Referer"))
+}
+
+func TestProviderName(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(config.WithAPIKey("sk-or-test"))
+	require.NoError(t, err)
+	require.Equal(t, "openrouter", provider.Name())
+}
@@ -68,6 +68,12 @@ var (
 	_ providers.CapabilityProvider = (*Provider)(nil)
 )
 
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
 // New creates a new platform provider.
 func New(opts ...config.Option) (*Provider, error) {
 	cfg, err := config.New(opts...)
@@ -118,15 +124,19 @@ func (p *Provider) Name() string {
 // Since this is a proxy, capabilities depend on the underlying provider.
 func (p *Provider) Capabilities() providers.Capabilities {
 	// Return full capabilities since we can proxy to any provider.
-	return providers.Capabilities{
-		Completion:          true,
-		CompletionStreaming: true,
-		CompletionReasoning: true,
-		CompletionImage:     true,
-		CompletionPDF:       true,
-		Embedding:           true,
-		ListModels:          true,
+	caps := providers.Capabilities{
+		Completion:           true,
+		CompletionStreaming:  true,
+		CompletionTools:      true,
+		CompletionReasoning:  true,
+		CompletionImage:      true,
+		CompletionJSONObject: true,
+		CompletionJSONSchema: true,
+		CompletionPDF:        true,
+		Embedding:            true,
+		ListModels:           true,
 	}
+	return providers.OverrideCapabilities(caps, p.config)
 }
 
 // initializeProvider initializes the underlying provider for the given provider name.
@@ -281,7 +291,13 @@ func (p *Provider) CompletionStream(
 			previousChunkTime = &currentTime
 
 			collectedChunks = append(collectedChunks, chunk)
-			chunks <- chunk
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
 		}
 
 		// Check for upstream errors
@@ -11,6 +11,7 @@ import (
 
 	"github.com/mozilla-ai/any-llm-go/config"
 	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
 	"github.com/mozilla-ai/any-llm-go/providers"
 )
 
@@ -155,6 +156,52 @@ func TestCompletionStreamDoesNotMutateParams(t *testing.T) {
 	require.Equal(t, false, params.StreamOptions.IncludeUsage)
 }
 
+func TestCompletionStreamCancellation(t *testing.T) {
+	testutil.VerifyNoGoroutineLeaks(t)
+
+	// The underlying stream closes released when its goroutine exits.
+	released := make(chan struct{})
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		ctx context.Context,
+		_ providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk)
+		errs := make(chan error, 1)
+
+		go func() {
+			defer close(released)
+			defer close(chunks)
+			defer close(errs)
+
+			for {
+				select {
+				case chunks <- providers.ChatCompletionChunk{
+					Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "Hi"}}},
+				}:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+		}()
+
+		return chunks, errs
+	}
+
+	provider, err := New(config.WithAPIKey("ANY.v1.test.fingerprint-dGVzdHByaXZhdGVrZXkxMjM0NTY3ODkwMTI="))
+	require.NoError(t, err)
+	provider.underlyingProvider = mock
+	provider.underlyingName = "openai"
+
+	testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return provider.CompletionStream(ctx, providers.CompletionParams{
+			Model:    "openai:gpt-4",
+			Messages: testutil.SimpleMessages(),
+		})
+	}, released)
+}
+
 // Integration tests - require actual platform connection and ANY_LLM_KEY
 
 func TestIntegrationOpenAICompletion(t *testing.T) {
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+// Factory creates a provider with the given options.
+type Factory func(ctx context.Context, opts ...config.Option) (Provider, error)
+
+// registry maps provider names to their factories.
+var registry = struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+// New creates the provider registered under name, which is matched without
+// regard to case. Built-in providers register themselves when their package is
+// imported; import providers/all to register every one of them. It returns an
+// UnsupportedProviderError if no provider is registered under name.
+func New(ctx context.Context, name string, opts ...config.Option) (Provider, error) {
+	registry.mu.RLock()
+	factory, ok := registry.factories[strings.ToLower(name)]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.NewUnsupportedProviderError(name)
+	}
+	return factory(ctx, opts...)
+}
+
+// Register makes a provider available to New under name, which is matched
+// without regard to case. Provider packages call it from init. It panics if
+// name is empty, factory is nil, or name is already registered.
+func Register(name string, factory Factory) {
+	if name == "" {
+		panic("providers: Register called with an empty name")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("providers: Register called with a nil factory for %q", name))
+	}
+
+	key := strings.ToLower(name)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.factories[key]; ok {
+		panic(fmt.Sprintf("providers: Register called twice for %q", name))
+	}
+	registry.factories[key] = factory
+}
+
+// Registered returns the names of the registered providers, sorted.
+func Registered() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
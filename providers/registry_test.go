@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+// contextKey keys the value the registry test passes through New.
+type contextKey struct{}
+
+// testUnregister removes the provider registered under name when t ends, so
+// the tests can run repeatedly.
+func testUnregister(t *testing.T, name string) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		delete(registry.factories, name)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("creates registered providers by name", func(t *testing.T) {
+		t.Parallel()
+
+		testUnregister(t, "registry-test")
+
+		var gotKey string
+		var gotValue any
+		Register("Registry-Test", func(ctx context.Context, opts ...config.Option) (Provider, error) {
+			cfg, err := config.New(opts...)
+			require.NoError(t, err)
+			gotKey = cfg.APIKey
+			gotValue = ctx.Value(contextKey{})
+			return nil, nil
+		})
+
+		ctx := context.WithValue(context.Background(), contextKey{}, "value")
+		_, err := New(ctx, "registry-test", config.WithAPIKey("key"))
+		require.NoError(t, err)
+		require.Equal(t, "key", gotKey)
+		require.Equal(t, "value", gotValue)
+		require.Contains(t, Registered(), "registry-test")
+	})
+
+	t.Run("rejects unknown names", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(context.Background(), "registry-unknown")
+		require.ErrorIs(t, err, errors.ErrUnsupportedProvider)
+	})
+
+	t.Run("panics on invalid registrations", func(t *testing.T) {
+		t.Parallel()
+
+		testUnregister(t, "registry-duplicate")
+
+		factory := func(context.Context, ...config.Option) (Provider, error) { return nil, nil }
+		Register("registry-duplicate", factory)
+
+		require.Panics(t, func() { Register("", factory) })
+		require.Panics(t, func() { Register("registry-nil", nil) })
+		require.Panics(t, func() { Register("REGISTRY-DUPLICATE", factory) })
+	})
+}
@@ -0,0 +1,25 @@
+package providers
+
+import "context"
+
+// maxProviderRetriesKey is the context key for WithMaxProviderRetries.
+type maxProviderRetriesKey struct{}
+
+// MaxProviderRetries returns the retry limit set on ctx with
+// WithMaxProviderRetries, and whether one was set.
+func MaxProviderRetries(ctx context.Context) (int, bool) {
+	retries, ok := ctx.Value(maxProviderRetriesKey{}).(int)
+	return retries, ok
+}
+
+// WithMaxProviderRetries returns a copy of ctx that limits how many times the
+// provider's SDK retries a failed request on its own, for requests made with
+// it. Zero disables the SDK's retries, leaving them to the caller, for
+// example to the retry package. Negative values are treated as zero.
+//
+// Without it, each SDK keeps its default: the Anthropic and OpenAI SDKs, and
+// OpenAI-compatible providers built on the latter, retry twice. Providers
+// whose SDKs do not retry ignore the limit.
+func WithMaxProviderRetries(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, maxProviderRetriesKey{}, max(retries, 0))
+}
@@ -0,0 +1,383 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+// paramTools is the name of the tools parameter, as reported in validation errors.
+const paramTools = "tools"
+
+// schemaTypes are the types a JSON Schema "type" keyword may name.
+var schemaTypes = []string{"array", "boolean", "integer", "null", "number", "object", "string"}
+
+// strictUnsupportedKeywords are the keywords OpenAI strict mode rejects.
+var strictUnsupportedKeywords = []string{
+	"allOf", "contains", "default", "dependentRequired", "dependentSchemas", "else", "if", "maxContains",
+	"maxProperties", "minContains", "minProperties", "not", "patternProperties", "propertyNames", "then",
+	"unevaluatedItems", "unevaluatedProperties", "uniqueItems",
+}
+
+// SchemaLimits describes the subset of JSON Schema a provider accepts in tool
+// parameters. The zero value accepts every keyword.
+type SchemaLimits struct {
+	// Keywords, when set, are the only schema keywords the provider accepts.
+	Keywords []string
+}
+
+// StrictSchema returns a copy of schema rewritten for OpenAI strict mode, so
+// that one schema works for every provider: every object schema requires all
+// of its properties and forbids others, properties that were optional become
+// nullable instead, oneOf becomes anyOf, and keywords strict mode rejects,
+// such as allOf and default, are removed. schema itself is not modified.
+func StrictSchema(schema map[string]any) (map[string]any, error) {
+	strict, err := decodeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	strictify(strict)
+	return strict, nil
+}
+
+// ValidateTools checks that tools are well formed: each is a function with a
+// unique name, whose Parameters, if set, are a valid JSON Schema (draft
+// 2020-12) for an object that uses only the keywords allowed by limits. It
+// returns an *errors.InvalidRequestError naming the tool and the path of the
+// offending keyword, so requests fail before they are sent instead of with an
+// opaque error from the provider.
+func ValidateTools(provider string, tools []Tool, limits SchemaLimits) error {
+	names := make(map[string]bool, len(tools))
+	for i, tool := range tools {
+		name := tool.Function.Name
+
+		var err error
+		switch {
+		case tool.Type != "" && tool.Type != ToolChoiceTypeFunction:
+			err = fmt.Errorf("tools[%d]: unsupported tool type %q", i, tool.Type)
+		case name == "":
+			err = fmt.Errorf("tools[%d]: function name is required", i)
+		case names[name]:
+			err = fmt.Errorf("tool %q is defined more than once", name)
+		default:
+			names[name] = true
+			if err = validateParameters(tool.Function.Parameters, limits); err != nil {
+				err = fmt.Errorf("tool %q: %w", name, err)
+			}
+		}
+		if err != nil {
+			return errors.NewInvalidParamError(provider, paramTools, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeSchema returns a deep copy of schema decoded from its JSON encoding,
+// so that values such as []string become the []any the provider receives.
+func decodeSchema(schema map[string]any) (map[string]any, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema cannot be encoded as JSON: %w", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("schema cannot be decoded as JSON: %w", err)
+	}
+	return decoded, nil
+}
+
+// isObjectSchema reports whether a decoded schema describes an object.
+func isObjectSchema(schema map[string]any) bool {
+	if _, ok := schema["properties"]; ok {
+		return true
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "object"
+	case []any:
+		return slices.Contains(t, any("object"))
+	default:
+		return false
+	}
+}
+
+// nullable returns a decoded schema that also accepts null.
+func nullable(schema any) any {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return schema
+	}
+
+	if values, ok := s["enum"].([]any); ok && !slices.Contains(values, nil) {
+		s["enum"] = append(values, nil)
+	}
+
+	switch t := s["type"].(type) {
+	case string:
+		if t != "null" {
+			s["type"] = []any{t, "null"}
+		}
+		return s
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			s["type"] = append(t, "null")
+		}
+		return s
+	default:
+	}
+
+	if _, ok := s["enum"]; ok {
+		return s
+	}
+	if alternatives, ok := s["anyOf"].([]any); ok {
+		s["anyOf"] = append(alternatives, map[string]any{"type": "null"})
+		return s
+	}
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
+
+// schemaKind returns the JSON type of a decoded JSON value, for error messages.
+func schemaKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// strictify rewrites a decoded schema, and the schemas within it, for OpenAI
+// strict mode, in place.
+func strictify(schema any) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return
+	}
+
+	for _, keyword := range strictUnsupportedKeywords {
+		delete(s, keyword)
+	}
+	if alternatives, ok := s["oneOf"]; ok {
+		if _, ok := s["anyOf"]; !ok {
+			s["anyOf"] = alternatives
+		}
+		delete(s, "oneOf")
+	}
+
+	for _, keyword := range []string{"$defs", "definitions", "properties"} {
+		if schemas, ok := s[keyword].(map[string]any); ok {
+			for _, child := range schemas {
+				strictify(child)
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "items", "prefixItems"} {
+		switch v := s[keyword].(type) {
+		case []any:
+			for _, child := range v {
+				strictify(child)
+			}
+		case map[string]any:
+			strictify(v)
+		default:
+		}
+	}
+
+	if !isObjectSchema(s) {
+		return
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	required, _ := s["required"].([]any)
+	names := slices.Sorted(maps.Keys(properties))
+	for _, name := range names {
+		if !slices.Contains(required, any(name)) {
+			properties[name] = nullable(properties[name])
+		}
+	}
+	s["required"] = names
+	s["additionalProperties"] = false
+}
+
+// validateKeyword checks the value of a schema keyword at path.
+func validateKeyword(path, keyword string, value any, limits SchemaLimits) error {
+	switch keyword {
+	case "type":
+		return validateSchemaType(path, value)
+	case "$defs", "definitions", "dependentSchemas", "patternProperties", "properties":
+		return validateSchemaMap(path, value, limits)
+	case "additionalProperties", "contains", "else", "if", "not", "propertyNames", "then",
+		"unevaluatedItems", "unevaluatedProperties":
+		return validateSchema(path, value, limits)
+	case "items":
+		// Drafts before 2020-12 allow an array of schemas for tuples.
+		if _, ok := value.([]any); ok {
+			return validateSchemaList(path, value, limits)
+		}
+		return validateSchema(path, value, limits)
+	case "allOf", "anyOf", "oneOf", "prefixItems":
+		return validateSchemaList(path, value, limits)
+	case "enum":
+		if values, ok := value.([]any); !ok || len(values) == 0 {
+			return fmt.Errorf("%s: must be a non-empty array, got %v", path, value)
+		}
+		return nil
+	case "required":
+		return validateStringList(path, value)
+	case "exclusiveMaximum", "exclusiveMinimum", "maximum", "minimum":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: must be a number, got %s", path, schemaKind(value))
+		}
+		return nil
+	case "multipleOf":
+		if n, ok := value.(float64); !ok || n <= 0 {
+			return fmt.Errorf("%s: must be a positive number, got %v", path, value)
+		}
+		return nil
+	case "maxContains", "maxItems", "maxLength", "maxProperties",
+		"minContains", "minItems", "minLength", "minProperties":
+		return validateNonNegativeInteger(path, value)
+	case "$anchor", "$comment", "$id", "$ref", "$schema", "description", "format", "pattern", "title":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: must be a string, got %s", path, schemaKind(value))
+		}
+		return nil
+	case "nullable", "uniqueItems":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean, got %s", path, schemaKind(value))
+		}
+		return nil
+	default:
+		// Other keywords, such as default and examples, are annotations.
+		return nil
+	}
+}
+
+// validateNonNegativeInteger checks the value of a keyword such as minLength.
+func validateNonNegativeInteger(path string, value any) error {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != float64(int64(n)) {
+		return fmt.Errorf("%s: must be a non-negative integer, got %v", path, value)
+	}
+	return nil
+}
+
+// validateParameters checks the parameters of a tool, as decoded from their
+// JSON encoding.
+func validateParameters(parameters map[string]any, limits SchemaLimits) error {
+	if parameters == nil {
+		return nil
+	}
+
+	schema, err := decodeSchema(parameters)
+	if err != nil {
+		return fmt.Errorf("parameters: %w", err)
+	}
+
+	if t, ok := schema["type"]; ok && t != "object" {
+		return fmt.Errorf("parameters: must describe an object, got type %v", t)
+	}
+
+	return validateSchema("parameters", schema, limits)
+}
+
+// validateSchema checks a JSON Schema at path.
+func validateSchema(path string, schema any, limits SchemaLimits) error {
+	keywords, ok := schema.(map[string]any)
+	if !ok {
+		// Draft 2020-12 allows true and false as schemas.
+		if _, ok := schema.(bool); ok {
+			return nil
+		}
+		return fmt.Errorf("%s: schema must be an object or a boolean, got %s", path, schemaKind(schema))
+	}
+
+	for _, keyword := range slices.Sorted(maps.Keys(keywords)) {
+		if limits.Keywords != nil && !slices.Contains(limits.Keywords, keyword) {
+			return fmt.Errorf("%s: keyword %q is not supported", path, keyword)
+		}
+		if err := validateKeyword(path+"."+keyword, keyword, keywords[keyword], limits); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSchemaList checks a keyword whose value is an array of schemas.
+func validateSchemaList(path string, value any, limits SchemaLimits) error {
+	schemas, ok := value.([]any)
+	if !ok || len(schemas) == 0 {
+		return fmt.Errorf("%s: must be a non-empty array of schemas, got %s", path, schemaKind(value))
+	}
+
+	for i, schema := range schemas {
+		if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), schema, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSchemaMap checks a keyword whose value maps names to schemas.
+func validateSchemaMap(path string, value any, limits SchemaLimits) error {
+	schemas, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: must be an object of schemas, got %s", path, schemaKind(value))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(schemas)) {
+		if err := validateSchema(path+"."+name, schemas[name], limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSchemaType checks the value of the type keyword: a type name or an
+// array of them.
+func validateSchemaType(path string, value any) error {
+	names := []any{value}
+	if list, ok := value.([]any); ok {
+		names = list
+	}
+
+	for _, name := range names {
+		if s, ok := name.(string); !ok || !slices.Contains(schemaTypes, s) {
+			return fmt.Errorf("%s: unknown type %v, want one of %v", path, name, schemaTypes)
+		}
+	}
+	return nil
+}
+
+// validateStringList checks a keyword whose value is an array of strings.
+func validateStringList(path string, value any) error {
+	values, ok := value.([]any)
+	if !ok {
+		return fmt.Errorf("%s: must be an array of strings, got %s", path, schemaKind(value))
+	}
+
+	for _, v := range values {
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: must be an array of strings, got %v", path, value)
+		}
+	}
+	return nil
+}
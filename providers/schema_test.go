@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+func TestStrictSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		schema map[string]any
+		want   map[string]any
+	}{
+		{
+			name: "requires every property and forbids others",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":  map[string]any{"type": "string"},
+					"email": map[string]any{"type": "string"},
+				},
+				"required": []string{"name"},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":  map[string]any{"type": "string"},
+					"email": map[string]any{"type": []any{"string", "null"}},
+				},
+				"required":             []string{"email", "name"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			name: "makes optional properties nullable",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"unit":  map[string]any{"type": "string", "enum": []string{"c", "f"}},
+					"tags":  map[string]any{"type": []string{"array", "null"}, "items": map[string]any{"type": "string"}},
+					"where": map[string]any{"$ref": "#/$defs/place"},
+					"when":  map[string]any{"anyOf": []any{map[string]any{"type": "string"}}},
+				},
+				"$defs": map[string]any{"place": map[string]any{"type": "string"}},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"unit": map[string]any{"type": []any{"string", "null"}, "enum": []any{"c", "f", nil}},
+					"tags": map[string]any{"type": []any{"array", "null"}, "items": map[string]any{"type": "string"}},
+					"where": map[string]any{"anyOf": []any{
+						map[string]any{"$ref": "#/$defs/place"},
+						map[string]any{"type": "null"},
+					}},
+					"when": map[string]any{"anyOf": []any{
+						map[string]any{"type": "string"},
+						map[string]any{"type": "null"},
+					}},
+				},
+				"$defs":                map[string]any{"place": map[string]any{"type": "string"}},
+				"required":             []string{"tags", "unit", "when", "where"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			name: "rewrites nested objects and removes unsupported keywords",
+			schema: map[string]any{
+				"type":          "object",
+				"minProperties": 1,
+				"properties": map[string]any{
+					"items": map[string]any{
+						"type":        "array",
+						"uniqueItems": true,
+						"items": map[string]any{
+							"oneOf": []any{
+								map[string]any{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+								map[string]any{"type": "string", "default": "none"},
+							},
+						},
+					},
+				},
+				"required": []string{"items"},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"items": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"anyOf": []any{
+								map[string]any{
+									"type":                 "object",
+									"properties":           map[string]any{"id": map[string]any{"type": []any{"integer", "null"}}},
+									"required":             []string{"id"},
+									"additionalProperties": false,
+								},
+								map[string]any{"type": "string"},
+							},
+						},
+					},
+				},
+				"required":             []string{"items"},
+				"additionalProperties": false,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := StrictSchema(tc.schema)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("rejects schemas that are not JSON", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := StrictSchema(map[string]any{"type": make(chan int)})
+		require.Error(t, err)
+	})
+}
+
+func TestValidateTools(t *testing.T) {
+	t.Parallel()
+
+	tool := func(name string, parameters map[string]any) Tool {
+		return Tool{Type: "function", Function: Function{Name: name, Parameters: parameters}}
+	}
+	object := func(properties map[string]any) map[string]any {
+		return map[string]any{"type": "object", "properties": properties}
+	}
+	subset := SchemaLimits{Keywords: []string{"description", "enum", "items", "properties", "required", "type"}}
+
+	tests := []struct {
+		name    string
+		tools   []Tool
+		limits  SchemaLimits
+		wantErr string
+	}{
+		{name: "no tools"},
+		{name: "no parameters", tools: []Tool{tool("now", nil)}},
+		{
+			name: "valid schema",
+			tools: []Tool{tool("search", map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+					"limit": map[string]any{"type": []string{"integer", "null"}, "maximum": 100},
+					"tags":  map[string]any{"type": "array", "items": map[string]any{"enum": []string{"a", "b"}}},
+					"extra": true,
+				},
+				"required":             []string{"query"},
+				"additionalProperties": false,
+				"examples":             []any{map[string]any{"query": "go"}},
+			})},
+		},
+		{
+			name:    "unsupported tool type",
+			tools:   []Tool{{Type: "retrieval", Function: Function{Name: "search"}}},
+			wantErr: `tools[0]: unsupported tool type "retrieval"`,
+		},
+		{
+			name:    "missing name",
+			tools:   []Tool{tool("now", nil), tool("", nil)},
+			wantErr: "tools[1]: function name is required",
+		},
+		{
+			name:    "duplicate name",
+			tools:   []Tool{tool("now", nil), tool("now", nil)},
+			wantErr: `tool "now" is defined more than once`,
+		},
+		{
+			name:    "parameters not an object",
+			tools:   []Tool{tool("now", map[string]any{"type": "string"})},
+			wantErr: `tool "now": parameters: must describe an object, got type string`,
+		},
+		{
+			name:    "unknown type",
+			tools:   []Tool{tool("search", object(map[string]any{"query": map[string]any{"type": "text"}}))},
+			wantErr: `tool "search": parameters.properties.query.type: unknown type text`,
+		},
+		{
+			name:    "property not a schema",
+			tools:   []Tool{tool("search", object(map[string]any{"query": "string"}))},
+			wantErr: `tool "search": parameters.properties.query: schema must be an object or a boolean, got string`,
+		},
+		{
+			name:    "required not strings",
+			tools:   []Tool{tool("search", map[string]any{"type": "object", "required": "query"})},
+			wantErr: `tool "search": parameters.required: must be an array of strings, got string`,
+		},
+		{
+			name:    "empty enum",
+			tools:   []Tool{tool("search", object(map[string]any{"kind": map[string]any{"enum": []string{}}}))},
+			wantErr: `tool "search": parameters.properties.kind.enum: must be a non-empty array`,
+		},
+		{
+			name:    "negative length",
+			tools:   []Tool{tool("search", object(map[string]any{"query": map[string]any{"maxLength": -1}}))},
+			wantErr: `tool "search": parameters.properties.query.maxLength: must be a non-negative integer, got -1`,
+		},
+		{
+			name: "nested anyOf",
+			tools: []Tool{tool("search", object(map[string]any{
+				"when": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, 3}},
+			}))},
+			wantErr: `tool "search": parameters.properties.when.anyOf[1]: schema must be an object or a boolean, got number`,
+		},
+		{
+			name: "keywords within limits",
+			tools: []Tool{tool("search", object(map[string]any{
+				"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			}))},
+			limits: subset,
+		},
+		{
+			name: "keyword outside limits",
+			tools: []Tool{tool("search", object(map[string]any{
+				"query": map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+			}))},
+			limits:  subset,
+			wantErr: `tool "search": parameters.properties.query: keyword "pattern" is not supported`,
+		},
+		{
+			name:   "property names are not keywords",
+			tools:  []Tool{tool("search", object(map[string]any{"pattern": map[string]any{"type": "string"}}))},
+			limits: subset,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateTools("test", tc.tools, tc.limits)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var invalidErr *errors.InvalidRequestError
+			require.ErrorAs(t, err, &invalidErr)
+			require.Equal(t, "tools", invalidErr.Param)
+			require.Equal(t, "test", invalidErr.Provider)
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
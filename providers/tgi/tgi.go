@@ -0,0 +1,657 @@
+// Package tgi provides a Hugging Face Text Generation Inference (TGI) provider
+// implementation for any-llm.
+//
+// TGI exposes an OpenAI-compatible Messages API (TGI >= 1.4). For older servers
+// the provider falls back to the native /generate and /generate_stream endpoints,
+// and the native /info endpoint is available for model metadata.
+package tgi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/llamaserver"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/providers/openai"
+)
+
+// Provider configuration constants.
+const (
+	defaultAPIKey  = "tgi" // Dummy key; local TGI servers don't require auth.
+	defaultBaseURL = "http://localhost:8080/v1"
+	envAPIKey      = "HF_TOKEN"
+	envBaseURL     = "TGI_BASE_URL"
+	providerName   = "tgi"
+)
+
+// Extra configuration keys.
+const (
+	extraNativeGenerate = "native_generate"
+)
+
+// Native endpoint paths, relative to the server root.
+const (
+	pathGenerate       = "/generate"
+	pathGenerateStream = "/generate_stream"
+	pathInfo           = "/info"
+)
+
+// HTTP and server-sent event constants.
+const (
+	authorizationBearer  = "Bearer "
+	contentTypeJSON      = "application/json"
+	headerAuthorization  = "Authorization"
+	headerContentType    = "Content-Type"
+	initialStreamBufSize = 64 * 1024
+	maxStreamLineSize    = 1024 * 1024
+	sseDataPrefix        = "data:"
+)
+
+// TGI finish reasons.
+const (
+	tgiFinishEOSToken     = "eos_token"
+	tgiFinishLength       = "length"
+	tgiFinishStopSequence = "stop_sequence"
+)
+
+// Prompt rendering constants for the native fallback.
+const (
+	contentTypeText      = "text"
+	promptAssistantLabel = "Assistant"
+	promptSystemLabel    = "System"
+	promptToolLabel      = "Tool"
+	promptUserLabel      = "User"
+	promptTurnFormat     = "%s: %s\n\n"
+)
+
+// Object type constants.
+const (
+	objectChatCompletion      = "chat.completion"
+	objectChatCompletionChunk = "chat.completion.chunk"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.DryRunner          = (*Provider)(nil)
+	_ providers.ErrorConverter     = (*Provider)(nil)
+	_ providers.ModelLister        = (*Provider)(nil)
+	_ providers.Prober             = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// Info describes the model served by a TGI instance, as reported by /info.
+type Info struct {
+	DockerLabel           string `json:"docker_label"`
+	MaxBestOf             int    `json:"max_best_of"`
+	MaxConcurrentRequests int    `json:"max_concurrent_requests"`
+	MaxInputLength        int    `json:"max_input_length"` // Reported by TGI < 2.0.
+	MaxInputTokens        int    `json:"max_input_tokens"`
+	MaxStopSequences      int    `json:"max_stop_sequences"`
+	MaxTotalTokens        int    `json:"max_total_tokens"`
+	ModelDeviceType       string `json:"model_device_type"`
+	ModelDType            string `json:"model_dtype"`
+	ModelID               string `json:"model_id"`
+	ModelPipelineTag      string `json:"model_pipeline_tag"`
+	ModelSHA              string `json:"model_sha"`
+	SHA                   string `json:"sha"`
+	Version               string `json:"version"`
+}
+
+// Provider implements the providers.Provider interface for TGI.
+// It embeds openai.CompatibleProvider since TGI exposes an OpenAI-compatible API.
+type Provider struct {
+	*openai.CompatibleProvider
+	apiKey         string
+	httpClient     *http.Client
+	nativeGenerate bool
+	serverURL      string
+}
+
+// generateDetails holds the generation details returned by the native endpoints.
+type generateDetails struct {
+	FinishReason    string `json:"finish_reason"`
+	GeneratedTokens int    `json:"generated_tokens"`
+}
+
+// generateParameters holds the sampling parameters for the native endpoints.
+type generateParameters struct {
+	Details      bool     `json:"details"`
+	MaxNewTokens *int     `json:"max_new_tokens,omitempty"`
+	Seed         *int     `json:"seed,omitempty"`
+	Stop         []string `json:"stop,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	TopP         *float64 `json:"top_p,omitempty"`
+}
+
+// generateRequest is the request body for /generate and /generate_stream.
+type generateRequest struct {
+	Inputs     string             `json:"inputs"`
+	Parameters generateParameters `json:"parameters"`
+}
+
+// generateResponse is the response body for /generate.
+type generateResponse struct {
+	Details       *generateDetails `json:"details"`
+	GeneratedText string           `json:"generated_text"`
+}
+
+// streamEvent is a single server-sent event from /generate_stream.
+type streamEvent struct {
+	Details *generateDetails `json:"details"`
+	Error   string           `json:"error"`
+	Token   streamToken      `json:"token"`
+}
+
+// streamToken is the token carried by a /generate_stream event.
+type streamToken struct {
+	Special bool   `json:"special"`
+	Text    string `json:"text"`
+}
+
+func init() {
+	providers.Register(providerName, func(_ context.Context, opts ...config.Option) (providers.Provider, error) {
+		return New(opts...)
+	})
+}
+
+// New creates a new TGI provider.
+func New(opts ...config.Option) (*Provider, error) {
+	// Resolve the server location and fallback mode for the native endpoints.
+	cfg, err := config.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	nativeGenerate := false
+	if v, ok := cfg.ExtraValue(extraNativeGenerate); ok {
+		nativeGenerate, _ = v.(bool)
+	}
+
+	base, err := openai.NewCompatible(openai.CompatibleConfig{
+		APIKeyEnvVar:        envAPIKey,
+		BaseURLEnvVar:       envBaseURL,
+		Capabilities:        tgiCapabilities(nativeGenerate),
+		DefaultAPIKey:       defaultAPIKey,
+		DefaultBaseURL:      defaultBaseURL,
+		Name:                providerName,
+		PostprocessChunk:    nil,
+		PostprocessResponse: nil,
+		PreprocessParams:    nil,
+		RequireAPIKey:       false,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL, err := cfg.ResolveBaseURL(envBaseURL, defaultBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		CompatibleProvider: base,
+		apiKey:             cfg.ResolveAPIKey(envAPIKey),
+		httpClient:         cfg.HTTPClient(),
+		nativeGenerate:     nativeGenerate,
+		serverURL:          llamaserver.ServerRoot(baseURL),
+	}, nil
+}
+
+// WithNativeGenerate makes the provider always use the native /generate and
+// /generate_stream endpoints instead of first trying the Messages API.
+// Use it for TGI servers older than 1.4 to avoid a failed request per call.
+func WithNativeGenerate() config.Option {
+	return config.WithExtra(extraNativeGenerate, true)
+}
+
+// Completion performs a chat completion request.
+// It falls back to the native /generate endpoint when the server does not
+// expose the Messages API.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if p.nativeGenerate {
+		return p.generate(ctx, params)
+	}
+
+	resp, err := p.CompatibleProvider.Completion(ctx, params)
+	if isMissingMessagesAPI(err) {
+		return p.generate(ctx, params)
+	}
+
+	return resp, err
+}
+
+// CompletionStream performs a streaming chat completion request.
+// It falls back to the native /generate_stream endpoint when the server does
+// not expose the Messages API.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	if p.nativeGenerate {
+		return p.generateStream(ctx, params)
+	}
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		upstreamChunks, upstreamErrs := p.CompatibleProvider.CompletionStream(ctx, params)
+
+		received := false
+		for chunk := range upstreamChunks {
+			received = true
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		err := <-upstreamErrs
+		if received || !isMissingMessagesAPI(err) {
+			if err != nil {
+				errs <- err
+			}
+			return
+		}
+
+		// The Messages API is missing; replay the request on the native endpoint.
+		nativeChunks, nativeErrs := p.generateStream(ctx, params)
+		for chunk := range nativeChunks {
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-nativeErrs; err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// DryRun returns the body Completion would send for params, without sending it.
+// With native generation enabled it is the /generate request body. The
+// automatic fallback to /generate is not predicted, since it depends on the server.
+// Implements providers.DryRunner.
+func (p *Provider) DryRun(ctx context.Context, params providers.CompletionParams) (json.RawMessage, error) {
+	if !p.nativeGenerate {
+		return p.CompatibleProvider.DryRun(ctx, params)
+	}
+
+	req, err := convertGenerateRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+	}
+
+	return body, nil
+}
+
+// Info returns metadata about the model served by the TGI instance.
+func (p *Provider) Info(ctx context.Context) (*Info, error) {
+	resp, err := p.do(ctx, http.MethodGet, pathInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+	var info Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, errors.NewProviderError(providerName, fmt.Errorf("decoding %s response: %w", pathInfo, err))
+	}
+
+	return &info, nil
+}
+
+// do sends a request to a native endpoint, returning an error for non-2xx statuses.
+// The caller must close the response body.
+func (p *Provider) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("encoding request: %w", err))
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.serverURL+path, reqBody)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, err)
+	}
+	if body != nil {
+		req.Header.Set(headerContentType, contentTypeJSON)
+	}
+	if p.apiKey != "" {
+		req.Header.Set(headerAuthorization, authorizationBearer+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.NewProviderError(providerName, err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+		respBody, _ := io.ReadAll(resp.Body)     // Best effort; body is only used for the error message.
+		statusErr := fmt.Errorf("%s returned status %d: %s", path, resp.StatusCode, bytes.TrimSpace(respBody))
+		return nil, errors.FromStatusCode(providerName, resp.StatusCode, statusErr)
+	}
+
+	return resp, nil
+}
+
+// generate performs a completion request against the native /generate endpoint.
+func (p *Provider) generate(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	req, err := convertGenerateRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, pathGenerate, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+	var genResp generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+		return nil, errors.NewProviderError(providerName, fmt.Errorf("decoding %s response: %w", pathGenerate, err))
+	}
+
+	return convertGenerateResponse(&genResp, params.Model), nil
+}
+
+// generateStream performs a streaming completion request against the native /generate_stream endpoint.
+func (p *Provider) generateStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		req, err := convertGenerateRequest(params)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		resp, err := p.do(ctx, http.MethodPost, pathGenerateStream, req)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer func() { _ = resp.Body.Close() }() // Close error is not actionable after reading.
+
+		id := generateID()
+		created := time.Now().Unix()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, initialStreamBufSize), maxStreamLineSize)
+
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), sseDataPrefix)
+			if !ok {
+				continue
+			}
+
+			var event streamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				errs <- errors.NewProviderError(providerName, fmt.Errorf("decoding stream event: %w", err))
+				return
+			}
+
+			if event.Error != "" {
+				errs <- errors.NewProviderError(providerName, stderrors.New(event.Error))
+				return
+			}
+
+			select {
+			case chunks <- convertStreamEvent(&event, id, created, params.Model):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() != nil {
+				errs <- ctx.Err() // The read failed because the stream was cancelled.
+				return
+			}
+			errs <- errors.NewProviderError(providerName, err)
+		}
+	}()
+
+	return chunks, errs
+}
+
+// convertFinishReason converts a TGI finish reason to an OpenAI finish reason.
+func convertFinishReason(reason string) string {
+	switch reason {
+	case tgiFinishLength:
+		return providers.FinishReasonLength
+	case tgiFinishEOSToken, tgiFinishStopSequence:
+		return providers.FinishReasonStop
+	default:
+		return providers.FinishReasonStop
+	}
+}
+
+// convertGenerateRequest converts completion params to a native generate request.
+// The native API has no tools, tool choice, response formats or images, so
+// requests that use them are rejected rather than answered without them.
+func convertGenerateRequest(params providers.CompletionParams) (*generateRequest, error) {
+	if len(params.Messages) == 0 {
+		return nil, errors.NewInvalidRequestError(providerName, fmt.Errorf("at least one message is required"))
+	}
+	toolChoice := params.ToolChoice != nil && params.ToolChoice != providers.ToolChoiceNone
+	if len(params.Tools) > 0 || toolChoice || params.ResponseFormat != nil {
+		return nil, errors.NewInvalidRequestError(
+			providerName,
+			fmt.Errorf("tools, tool choice and response formats are not supported by the native generate API"),
+		)
+	}
+
+	prompt, err := renderPrompt(params.Messages)
+	if err != nil {
+		return nil, errors.NewInvalidRequestError(providerName, err)
+	}
+
+	req := &generateRequest{
+		Inputs: prompt,
+		Parameters: generateParameters{
+			Details:      true,
+			MaxNewTokens: params.MaxTokens,
+			Seed:         params.Seed,
+			Stop:         params.Stop,
+		},
+	}
+
+	// TGI rejects a temperature of zero and top_p outside (0, 1); omitting them
+	// gives the same greedy/unrestricted sampling.
+	if params.Temperature != nil && *params.Temperature > 0 {
+		req.Parameters.Temperature = params.Temperature
+	}
+	if params.TopP != nil && *params.TopP > 0 && *params.TopP < 1 {
+		req.Parameters.TopP = params.TopP
+	}
+
+	return req, nil
+}
+
+// convertGenerateResponse converts a native generate response to provider format.
+func convertGenerateResponse(resp *generateResponse, model string) *providers.ChatCompletion {
+	finishReason := providers.FinishReasonStop
+	var usage *providers.Usage
+	if resp.Details != nil {
+		finishReason = convertFinishReason(resp.Details.FinishReason)
+		// The native API does not report prompt tokens, so there is no total.
+		usage = &providers.Usage{CompletionTokens: resp.Details.GeneratedTokens}
+	}
+
+	return &providers.ChatCompletion{
+		ID:      generateID(),
+		Object:  objectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []providers.Choice{{
+			Index: 0,
+			Message: providers.Message{
+				Role:    providers.RoleAssistant,
+				Content: resp.GeneratedText,
+			},
+			FinishReason: finishReason,
+		}},
+		Usage: usage,
+	}
+}
+
+// convertStreamEvent converts a /generate_stream event to a streaming chunk.
+func convertStreamEvent(event *streamEvent, id string, created int64, model string) providers.ChatCompletionChunk {
+	choice := providers.ChunkChoice{Index: 0}
+	if !event.Token.Special {
+		choice.Delta.Content = event.Token.Text
+	}
+
+	chunk := providers.ChatCompletionChunk{
+		ID:      id,
+		Object:  objectChatCompletionChunk,
+		Created: created,
+		Model:   model,
+	}
+
+	// The final event carries the generation details.
+	if event.Details != nil {
+		choice.FinishReason = convertFinishReason(event.Details.FinishReason)
+		chunk.Usage = &providers.Usage{CompletionTokens: event.Details.GeneratedTokens}
+	}
+
+	chunk.Choices = []providers.ChunkChoice{choice}
+	return chunk
+}
+
+// generateID generates a unique ID for responses using crypto/rand.
+func generateID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("chatcmpl-%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
+}
+
+// isMissingMessagesAPI reports whether err indicates the server has no
+// /v1/chat/completions route. TGI serves a single model and ignores the model
+// name, so a 404 can only mean the route itself is missing.
+func isMissingMessagesAPI(err error) bool {
+	return stderrors.Is(err, errors.ErrModelNotFound)
+}
+
+// messageText returns the text content of a message, joining text parts of
+// multi-modal content. It returns an error for content the native API cannot
+// carry: tool calls and parts other than text.
+func messageText(msg providers.Message) (string, error) {
+	if len(msg.ToolCalls) > 0 {
+		return "", fmt.Errorf("tool calls are not supported by the native generate API")
+	}
+	if !msg.IsMultiModal() {
+		return msg.ContentString(), nil
+	}
+
+	texts := make([]string, 0, len(msg.ContentParts()))
+	for _, part := range msg.ContentParts() {
+		if part.Type != contentTypeText {
+			return "", fmt.Errorf("content type %q is not supported by the native generate API", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// renderPrompt renders messages as a plain transcript prompt for the native endpoints,
+// ending with an open assistant turn. The native API has no chat template support,
+// so the transcript is not the format the model was trained on; models follow it
+// less reliably than the Messages API.
+func renderPrompt(messages []providers.Message) (string, error) {
+	var b strings.Builder
+	for _, msg := range messages {
+		label, err := roleLabel(msg.Role)
+		if err != nil {
+			return "", err
+		}
+		text, err := messageText(msg)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, promptTurnFormat, label, text)
+	}
+	b.WriteString(promptAssistantLabel + ":")
+	return b.String(), nil
+}
+
+// roleLabel returns the transcript label for a message role.
+func roleLabel(role string) (string, error) {
+	switch role {
+	case providers.RoleAssistant:
+		return promptAssistantLabel, nil
+	case providers.RoleSystem:
+		return promptSystemLabel, nil
+	case providers.RoleTool:
+		return promptToolLabel, nil
+	case providers.RoleUser:
+		return promptUserLabel, nil
+	default:
+		return "", fmt.Errorf("unknown message role: %q", role)
+	}
+}
+
+// tgiCapabilities returns the capabilities for the TGI provider. Images and
+// tools need the Messages API, so they are off with native generation.
+func tgiCapabilities(nativeGenerate bool) providers.Capabilities {
+	return providers.Capabilities{
+		Completion:           true,
+		CompletionImage:      !nativeGenerate, // Depends on the model loaded.
+		CompletionJSONObject: false,
+		CompletionJSONSchema: false, // TGI uses its own grammar format instead.
+		CompletionPDF:        false,
+		CompletionReasoning:  false,
+		CompletionStreaming:  true,
+		CompletionTools:      !nativeGenerate,
+		Embedding:            false, // Embeddings are served by TEI, not TGI.
+		ListModels:           true,
+	}
+}
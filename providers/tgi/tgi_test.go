@@ -0,0 +1,409 @@
+package tgi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Test constants.
+const (
+	testModel                  = "tgi"
+	testTGIAvailabilityTimeout = 5 * time.Second
+)
+
+func TestNew(t *testing.T) {
+	// Note: Not using t.Parallel() here because child test uses t.Setenv.
+
+	t.Run("creates provider with default settings", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+		require.NotNil(t, provider)
+		require.Equal(t, providerName, provider.Name())
+		require.Equal(t, "http://localhost:8080", provider.serverURL)
+	})
+
+	t.Run("creates provider from TGI_BASE_URL environment variable", func(t *testing.T) {
+		t.Setenv("TGI_BASE_URL", "http://custom-host:3000/v1")
+
+		provider, err := New()
+		require.NoError(t, err)
+		require.Equal(t, "http://custom-host:3000", provider.serverURL)
+	})
+
+	t.Run("enables native generate", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(WithNativeGenerate())
+		require.NoError(t, err)
+		require.True(t, provider.nativeGenerate)
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+
+	require.True(t, caps.Completion)
+	require.True(t, caps.CompletionStreaming)
+	require.False(t, caps.CompletionReasoning)
+	require.False(t, caps.Embedding)
+	require.True(t, caps.ListModels)
+	require.True(t, caps.CompletionTools)
+	require.True(t, caps.CompletionImage)
+
+	t.Run("native generation has no tools or images", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(WithNativeGenerate())
+		require.NoError(t, err)
+
+		caps := provider.Capabilities()
+		require.False(t, caps.CompletionTools)
+		require.False(t, caps.CompletionImage)
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{Model: testModel, Messages: testutil.SimpleMessages()}
+
+	t.Run("messages api", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+
+		body, err := provider.DryRun(context.Background(), params)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"messages"`)
+	})
+
+	t.Run("native generate", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(WithNativeGenerate())
+		require.NoError(t, err)
+
+		body, err := provider.DryRun(context.Background(), params)
+		require.NoError(t, err)
+
+		var req generateRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		require.NotEmpty(t, req.Inputs)
+	})
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, pathInfo, r.URL.Path)
+		_, _ = w.Write([]byte(`{
+			"model_id": "HuggingFaceH4/zephyr-7b-beta",
+			"model_dtype": "torch.float16",
+			"max_input_tokens": 4095,
+			"max_total_tokens": 4096,
+			"version": "2.4.0"
+		}`)) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+	require.NoError(t, err)
+
+	info, err := provider.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "HuggingFaceH4/zephyr-7b-beta", info.ModelID)
+	require.Equal(t, "torch.float16", info.ModelDType)
+	require.Equal(t, 4095, info.MaxInputTokens)
+	require.Equal(t, 4096, info.MaxTotalTokens)
+	require.Equal(t, "2.4.0", info.Version)
+}
+
+func TestCompletionFallback(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathGenerate:
+			var req generateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.True(t, strings.HasSuffix(req.Inputs, promptAssistantLabel+":"))
+			require.True(t, req.Parameters.Details)
+
+			_, _ = w.Write([]byte(`{
+				"generated_text": "Hello there!",
+				"details": {"finish_reason": "eos_token", "generated_tokens": 3}
+			}`)) // Write error surfaces in the client.
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL + "/v1"))
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    testModel,
+		Messages: testutil.SimpleMessages(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, objectChatCompletion, resp.Object)
+	require.Equal(t, "Hello there!", resp.Choices[0].Message.Content)
+	require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
+	require.Equal(t, 3, resp.Usage.CompletionTokens)
+	require.Zero(t, resp.Usage.TotalTokens)
+}
+
+func TestCompletionStreamNative(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, pathGenerateStream, r.URL.Path)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(strings.Join([]string{
+			`data:{"token":{"id":1,"text":"Hello","special":false}}`,
+			``,
+			`data:{"token":{"id":2,"text":" world","special":false}}`,
+			``,
+			`data:{"token":{"id":3,"text":"</s>","special":true},` +
+				`"generated_text":"Hello world","details":{"finish_reason":"length","generated_tokens":3}}`,
+			``,
+		}, "\n"))) // Write error surfaces in the client.
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL), WithNativeGenerate())
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Model:    testModel,
+		Messages: testutil.SimpleMessages(),
+	})
+
+	var content strings.Builder
+	var last providers.ChatCompletionChunk
+	for chunk := range chunks {
+		require.Equal(t, objectChatCompletionChunk, chunk.Object)
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		last = chunk
+	}
+	require.NoError(t, <-errs)
+
+	require.Equal(t, "Hello world", content.String())
+	require.Equal(t, providers.FinishReasonLength, last.Choices[0].FinishReason)
+	require.Equal(t, 3, last.Usage.CompletionTokens)
+}
+
+func TestCompletionStreamNativeError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":"Input validation error","error_type":"validation"}`, http.StatusUnprocessableEntity)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.WithBaseURL(server.URL), WithNativeGenerate())
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+		Model:    testModel,
+		Messages: testutil.SimpleMessages(),
+	})
+	for range chunks {
+		t.Fatal("expected no chunks")
+	}
+	require.ErrorIs(t, <-errs, errors.ErrInvalidRequest)
+}
+
+func TestConvertGenerateRequest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("omits values TGI rejects", func(t *testing.T) {
+		t.Parallel()
+
+		temperature := 0.0
+		topP := 1.0
+		req, err := convertGenerateRequest(providers.CompletionParams{
+			Messages:    testutil.SimpleMessages(),
+			Temperature: &temperature,
+			TopP:        &topP,
+		})
+		require.NoError(t, err)
+		require.Nil(t, req.Parameters.Temperature)
+		require.Nil(t, req.Parameters.TopP)
+	})
+
+	rejected := []struct {
+		name   string
+		params providers.CompletionParams
+	}{
+		{
+			name: "tools",
+			params: providers.CompletionParams{
+				Messages: testutil.SimpleMessages(),
+				Tools:    []providers.Tool{{Type: "function", Function: providers.Function{Name: "get_weather"}}},
+			},
+		},
+		{
+			name: "tool choice",
+			params: providers.CompletionParams{
+				Messages:   testutil.SimpleMessages(),
+				ToolChoice: providers.ToolChoiceRequired,
+			},
+		},
+		{
+			name: "response formats",
+			params: providers.CompletionParams{
+				Messages:       testutil.SimpleMessages(),
+				ResponseFormat: &providers.ResponseFormat{Type: "json_object"},
+			},
+		},
+		{
+			name: "images",
+			params: providers.CompletionParams{
+				Messages: []providers.Message{{Role: providers.RoleUser, Content: []providers.ContentPart{
+					{Type: contentTypeText, Text: "What is this?"},
+					{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/cat.png"}},
+				}}},
+			},
+		},
+		{
+			name: "tool calls",
+			params: providers.CompletionParams{
+				Messages: []providers.Message{
+					{Role: providers.RoleUser, Content: "What's the weather?"},
+					{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{{
+						ID:       "call_1",
+						Type:     "function",
+						Function: providers.FunctionCall{Name: "get_weather", Arguments: "{}"},
+					}}},
+				},
+			},
+		},
+	}
+	for _, tc := range rejected {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := convertGenerateRequest(tc.params)
+			require.ErrorIs(t, err, errors.ErrInvalidRequest)
+		})
+	}
+
+	t.Run("rejects unknown roles", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertGenerateRequest(providers.CompletionParams{
+			Messages: []providers.Message{{Role: "unknown", Content: "Hi"}},
+		})
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+}
+
+func TestRenderPrompt(t *testing.T) {
+	t.Parallel()
+
+	prompt, err := renderPrompt([]providers.Message{
+		{Role: providers.RoleSystem, Content: "Be brief."},
+		{Role: providers.RoleUser, Content: []providers.ContentPart{
+			{Type: contentTypeText, Text: "Hi"},
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "System: Be brief.\n\nUser: Hi\n\nAssistant:", prompt)
+}
+
+// Integration tests - only run if TGI is available.
+func TestCompletionStreamCancellation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		opts []config.Option
+		path string
+	}{
+		{
+			name: "messages api",
+			body: strings.Repeat(`data: {"id":"chunk-1","object":"chat.completion.chunk","created":1,"model":"tgi",`+
+				`"choices":[{"index":0,"delta":{"content":"Hi"}}]}`+"\n\n", 2),
+			path: "/v1",
+		},
+		{
+			name: "native generate",
+			body: strings.Repeat(`data:{"token":{"id":1,"text":"Hello","special":false}}`+"\n\n", 2),
+			opts: []config.Option{WithNativeGenerate()},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.VerifyNoGoroutineLeaks(t)
+
+			server, released := testutil.NewStallingServer(t, "text/event-stream", tc.body)
+
+			provider, err := New(append([]config.Option{config.WithBaseURL(server.URL + tc.path)}, tc.opts...)...)
+			require.NoError(t, err)
+
+			testutil.VerifyStreamCancellation(t, func(ctx context.Context) (<-chan providers.ChatCompletionChunk, <-chan error) {
+				return provider.CompletionStream(ctx, providers.CompletionParams{
+					Model:    testModel,
+					Messages: testutil.SimpleMessages(),
+				})
+			}, released)
+		})
+	}
+}
+
+func TestIntegrationCompletion(t *testing.T) {
+	t.Parallel()
+	skipIfTGIUnavailable(t)
+
+	provider, err := New()
+	require.NoError(t, err)
+
+	resp, err := provider.Completion(context.Background(), providers.CompletionParams{
+		Model:    testModel,
+		Messages: testutil.SimpleMessages(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	require.NotEmpty(t, resp.Choices[0].Message.Content)
+}
+
+// skipIfTGIUnavailable skips the test if TGI is not running.
+func skipIfTGIUnavailable(t *testing.T) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTGIAvailabilityTimeout)
+	defer cancel()
+
+	provider, err := New()
+	if err != nil {
+		t.Skip("TGI not available")
+	}
+
+	if _, err = provider.Info(ctx); err != nil {
+		t.Skip("TGI not available")
+	}
+}
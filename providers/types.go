@@ -4,16 +4,51 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
 )
 
-// Finish reasons.
+// Content filter severities, from least to most severe.
+const (
+	SeveritySafe   = "safe"
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Finish reasons. FinishReasonRefusal marks a choice the model declined to
+// answer on policy grounds, as opposed to FinishReasonContentFilter, where a
+// filter outside the model withheld or cut off its output.
 const (
 	FinishReasonContentFilter = "content_filter"
 	FinishReasonLength        = "length"
+	FinishReasonRefusal       = "refusal"
 	FinishReasonStop          = "stop"
 	FinishReasonToolCalls     = "tool_calls"
 )
 
+// Job kinds.
+const (
+	JobKindImage = "image"
+	JobKindVideo = "video"
+)
+
+// Job statuses.
+const (
+	JobStatusFailed    = "failed"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+)
+
+// Output modalities.
+const (
+	ModalityAudio = "audio"
+	ModalityText  = "text"
+)
+
 // Reasoning effort levels for extended thinking.
 const (
 	ReasoningEffortAuto   ReasoningEffort = "auto"
@@ -31,12 +66,54 @@ const (
 	RoleUser      = "user"
 )
 
+// Tool choice modes.
+// CompletionParams.ToolChoice accepts one of these, or a ToolChoice built with ToolChoiceForFunction.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// Sampling parameter names, as reported in validation errors.
+const (
+	paramMaxTokens   = "max_tokens"
+	paramTemperature = "temperature"
+	paramTopP        = "top_p"
+)
+
+// defaultMaxTemperature is the highest temperature accepted when SamplingLimits does not set one.
+const defaultMaxTemperature = 2
+
+// ToolChoiceTypeFunction is the ToolChoice type that forces a specific function.
+const ToolChoiceTypeFunction = "function"
+
+// toolErrorPrefix marks failed tool results in the text sent to providers
+// without an error flag.
+const toolErrorPrefix = "Error: "
+
 // CapabilityProvider is an optional interface for providers to report capabilities.
 type CapabilityProvider interface {
 	Provider
 	Capabilities() Capabilities
 }
 
+// DocumentProvider is an optional interface for providers that can read
+// documents with OCR, returning the text of each page as Markdown.
+type DocumentProvider interface {
+	Provider
+	OCR(ctx context.Context, params OCRParams) (*OCRResponse, error)
+}
+
+// DryRunner is an optional interface for providers that can build a completion
+// request without sending it. DryRun validates and converts params exactly as
+// Completion would, including any provider-specific message patching and tool
+// schema conversion, and returns the JSON request body. Use it to debug
+// conversion or to lint prompt configurations in CI.
+type DryRunner interface {
+	Provider
+	DryRun(ctx context.Context, params CompletionParams) (json.RawMessage, error)
+}
+
 // EmbeddingProvider is an optional interface for providers that support embeddings.
 type EmbeddingProvider interface {
 	Provider
@@ -51,12 +128,44 @@ type ErrorConverter interface {
 	ConvertError(err error) error
 }
 
+// JobProvider is an optional interface for providers that generate media
+// with long-running jobs, such as video generation. SubmitJob starts a job,
+// GetJob polls it by ID, and FetchArtifact downloads an output that the job
+// only returns by URI. The jobs package polls jobs until they finish.
+type JobProvider interface {
+	Provider
+	FetchArtifact(ctx context.Context, artifact Artifact) ([]byte, error)
+	GetJob(ctx context.Context, id string) (*Job, error)
+	SubmitJob(ctx context.Context, params JobParams) (*Job, error)
+}
+
+// ModelCardProvider is an optional interface for self-hosted servers that
+// report metadata about the models they serve, such as llama.cpp and Ollama.
+// ModelCard reads it from the running server, so context length and
+// capability decisions match the model actually loaded rather than static
+// defaults.
+type ModelCardProvider interface {
+	Provider
+	ModelCard(ctx context.Context, model string) (*ModelCard, error)
+}
+
 // ModelLister is an optional interface for providers that support listing models.
 type ModelLister interface {
 	Provider
 	ListModels(ctx context.Context) (*ModelsResponse, error)
 }
 
+// Prober is an optional interface for providers that can check their
+// capabilities against the live endpoint. Static capabilities are often wrong
+// for self-hosted OpenAI-compatible servers, whose features depend on the
+// server, its version and the loaded model. Probe sends small requests to find
+// out what the endpoint accepts, and Capabilities reports the result from then
+// on. An empty model probes the first model the endpoint lists.
+type Prober interface {
+	CapabilityProvider
+	Probe(ctx context.Context, model string) (Capabilities, error)
+}
+
 // Provider is the core interface that all LLM providers must implement.
 type Provider interface {
 	// Name returns the provider's identifier (e.g., "openai", "anthropic").
@@ -66,65 +175,161 @@ type Provider interface {
 	Completion(ctx context.Context, params CompletionParams) (*ChatCompletion, error)
 
 	// CompletionStream performs a streaming chat completion request.
+	// Cancelling ctx always ends the stream: the producing goroutine exits and
+	// releases the response body, the error channel receives ctx.Err() unless
+	// another error was sent first, and both channels are closed. Callers may
+	// stop reading chunks once they have cancelled.
 	CompletionStream(ctx context.Context, params CompletionParams) (<-chan ChatCompletionChunk, <-chan error)
 }
 
+// TextCompleter is an optional interface for providers that can complete a
+// raw prompt without applying a chat template, such as llama.cpp servers. The
+// prompt is sent to the model as it is, so it must already contain any
+// special tokens the model expects.
+type TextCompleter interface {
+	Provider
+	TextCompletion(ctx context.Context, params TextCompletionParams) (*TextCompletion, error)
+}
+
+// TokenCounter is an optional interface for providers that can tokenize text
+// with the served model's own tokenizer, giving exact token counts.
+type TokenCounter interface {
+	Provider
+	Detokenize(ctx context.Context, params DetokenizeParams) (*DetokenizeResponse, error)
+	Tokenize(ctx context.Context, params TokenizeParams) (*TokenizeResponse, error)
+}
+
 // ReasoningEffort levels for extended thinking.
 type ReasoningEffort string
 
 // Capabilities describes what features a provider supports.
 type Capabilities struct {
-	Completion          bool
-	CompletionImage     bool
-	CompletionPDF       bool
-	CompletionReasoning bool
-	CompletionStreaming bool
-	Embedding           bool
-	ListModels          bool
+	Completion           bool
+	CompletionImage      bool
+	CompletionJSONObject bool
+	CompletionJSONSchema bool
+	CompletionPDF        bool
+	CompletionReasoning  bool
+	CompletionStreaming  bool
+	CompletionTools      bool
+	Embedding            bool
+	ListModels           bool
 }
 
 // ChatCompletion represents a chat completion response in OpenAI format.
+// RequestID is the provider's identifier for the request (for example the
+// x-request-id header), for correlating application logs with provider logs.
+// RateLimit is the rate limit state the provider reported with the response.
+// Arm names the experiment arm that served the request, when it was routed by
+// router.Split. PromptVariant names the system prompt variant that was sent,
+// when the request went through router.Prompts. PromptFilterResults are the
+// verdicts of the provider's content filters on the prompts, when it reports
+// them (as Azure OpenAI does). ResponseFormatMode names how a structured
+// output request was sent, when it went through structured.Provider.
+// Provenance records where the response came from, when it went through
+// provenance.Provider.
 type ChatCompletion struct {
-	ID                string   `json:"id"`
-	Object            string   `json:"object"`
-	Created           int64    `json:"created"`
-	Model             string   `json:"model"`
-	Choices           []Choice `json:"choices"`
-	Usage             *Usage   `json:"usage,omitempty"`
-	SystemFingerprint string   `json:"system_fingerprint,omitempty"`
+	ID                  string               `json:"id"`
+	Object              string               `json:"object"`
+	Created             int64                `json:"created"`
+	Model               string               `json:"model"`
+	Choices             []Choice             `json:"choices"`
+	Usage               *Usage               `json:"usage,omitempty"`
+	SystemFingerprint   string               `json:"system_fingerprint,omitempty"`
+	RequestID           string               `json:"request_id,omitempty"`
+	RateLimit           *RateLimitState      `json:"rate_limit,omitempty"`
+	Arm                 string               `json:"arm,omitempty"`
+	PromptVariant       string               `json:"prompt_variant,omitempty"`
+	PromptFilterResults []PromptFilterResult `json:"prompt_filter_results,omitempty"`
+	ResponseFormatMode  string               `json:"response_format_mode,omitempty"`
+	Provenance          *Provenance          `json:"provenance,omitempty"`
+}
+
+// Artifact is an output of a job: its data, or a URI to fetch it from with
+// JobProvider.FetchArtifact.
+type Artifact struct {
+	Data     []byte `json:"data,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+// Audio represents audio generated by the model.
+// In streaming chunks each delta carries a fragment: every Data fragment is
+// base64-encoded on its own, and Transcript fragments concatenate in order.
+type Audio struct {
+	ID         string `json:"id,omitempty"`
+	Data       string `json:"data,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// AudioParams configures audio output. It is required when Modalities includes ModalityAudio.
+type AudioParams struct {
+	Voice  string `json:"voice"`
+	Format string `json:"format"`
 }
 
 // ChatCompletionChunk represents a streaming chunk in OpenAI format.
+// RateLimit is set on the first chunk of a stream when the provider reported
+// its rate limit state in the response headers. Arm is set on every chunk of
+// a stream routed by router.Split, and PromptVariant on every chunk of a
+// stream that went through router.Prompts. PromptFilterResults are set on the
+// chunk that carries the provider's content filter verdicts on the prompts.
+// ResponseFormatMode is set on every chunk of a stream that went through
+// structured.Provider. Provenance is set on the last chunk of a stream that
+// went through provenance.Provider.
 type ChatCompletionChunk struct {
-	ID                string        `json:"id"`
-	Object            string        `json:"object"`
-	Created           int64         `json:"created"`
-	Model             string        `json:"model"`
-	Choices           []ChunkChoice `json:"choices"`
-	Usage             *Usage        `json:"usage,omitempty"`
-	SystemFingerprint string        `json:"system_fingerprint,omitempty"`
+	ID                  string               `json:"id"`
+	Object              string               `json:"object"`
+	Created             int64                `json:"created"`
+	Model               string               `json:"model"`
+	Choices             []ChunkChoice        `json:"choices"`
+	Usage               *Usage               `json:"usage,omitempty"`
+	SystemFingerprint   string               `json:"system_fingerprint,omitempty"`
+	Timings             *Timings             `json:"timings,omitempty"`
+	RateLimit           *RateLimitState      `json:"rate_limit,omitempty"`
+	Arm                 string               `json:"arm,omitempty"`
+	PromptVariant       string               `json:"prompt_variant,omitempty"`
+	PromptFilterResults []PromptFilterResult `json:"prompt_filter_results,omitempty"`
+	ResponseFormatMode  string               `json:"response_format_mode,omitempty"`
+	Provenance          *Provenance          `json:"provenance,omitempty"`
 }
 
 // Choice represents a completion choice.
+// ContentFilterResults are the verdicts of the provider's content filters on
+// the choice, when it reports them. A choice with FinishReasonContentFilter
+// may still hold the part of the message generated before it was filtered.
+// StopSequence is the stop sequence that ended the choice, for providers
+// that report it.
 type Choice struct {
-	Index        int     `json:"index"`
-	Message      Message `json:"message"`
-	FinishReason string  `json:"finish_reason,omitempty"`
+	Index                int                   `json:"index"`
+	Message              Message               `json:"message"`
+	FinishReason         string                `json:"finish_reason,omitempty"`
+	StopSequence         string                `json:"stop_sequence,omitempty"`
+	ContentFilterResults []ContentFilterResult `json:"content_filter_results,omitempty"`
 }
 
 // ChunkChoice represents a choice in a streaming chunk.
+// ContentFilterResults are the content filter verdicts on the content
+// streamed so far, when the provider reports them. StopSequence is set with
+// the finish reason, as in Choice.
 type ChunkChoice struct {
-	Index        int        `json:"index"`
-	Delta        ChunkDelta `json:"delta"`
-	FinishReason string     `json:"finish_reason,omitempty"`
+	Index                int                   `json:"index"`
+	Delta                ChunkDelta            `json:"delta"`
+	FinishReason         string                `json:"finish_reason,omitempty"`
+	StopSequence         string                `json:"stop_sequence,omitempty"`
+	ContentFilterResults []ContentFilterResult `json:"content_filter_results,omitempty"`
 }
 
-// ChunkDelta represents the delta content in a streaming chunk.
+// ChunkDelta represents the delta content in a streaming chunk. Refusal is a
+// fragment of the refusal message, as in Message.
 type ChunkDelta struct {
 	Role      string     `json:"role,omitempty"`
 	Content   string     `json:"content,omitempty"`
+	Refusal   string     `json:"refusal,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 	Reasoning *Reasoning `json:"reasoning,omitempty"`
+	Audio     *Audio     `json:"audio,omitempty"`
 }
 
 // CompletionParams represents normalized parameters for chat completion requests.
@@ -144,14 +349,41 @@ type CompletionParams struct {
 	ReasoningEffort   ReasoningEffort `json:"reasoning_effort,omitempty"`
 	Seed              *int            `json:"seed,omitempty"`
 	User              string          `json:"user,omitempty"`
+	Modalities        []string        `json:"modalities,omitempty"`
+	Audio             *AudioParams    `json:"audio,omitempty"`
 	Extra             map[string]any  `json:"-"`
 }
 
 // ContentPart represents a part of a multi-modal message.
+// A part of type "file" references a file already uploaded to the provider by
+// FileID (an OpenAI or Anthropic file ID, or a Gemini file URI), so large
+// documents such as PDFs are not re-sent with every request.
 type ContentPart struct {
 	Type     string    `json:"type"`
 	Text     string    `json:"text,omitempty"`
 	ImageURL *ImageURL `json:"image_url,omitempty"`
+	FileID   string    `json:"file_id,omitempty"`
+}
+
+// ContentFilterResult is a content filter's verdict on one category of
+// content, such as "hate" or "jailbreak". Severity-rated categories set
+// Severity to one of the Severity constants; detection categories set
+// Detected.
+type ContentFilterResult struct {
+	Category string `json:"category"`
+	Filtered bool   `json:"filtered"`
+	Detected bool   `json:"detected,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// DetokenizeParams represents parameters for detokenization requests.
+type DetokenizeParams struct {
+	Tokens []int `json:"tokens"`
+}
+
+// DetokenizeResponse represents the text reconstructed from a list of tokens.
+type DetokenizeResponse struct {
+	Content string `json:"content"`
 }
 
 // EmbeddingData represents a single embedding.
@@ -171,11 +403,17 @@ type EmbeddingParams struct {
 }
 
 // EmbeddingResponse represents an embedding response in OpenAI format.
+// Model, Dimensions and Provider identify the vector space the embeddings
+// live in: embeddings are only comparable with others from the same model
+// with the same number of dimensions. Model is the model the provider
+// reports, or the requested model if it reports none.
 type EmbeddingResponse struct {
-	Object string          `json:"object"`
-	Data   []EmbeddingData `json:"data"`
-	Model  string          `json:"model"`
-	Usage  *EmbeddingUsage `json:"usage,omitempty"`
+	Object     string          `json:"object"`
+	Data       []EmbeddingData `json:"data"`
+	Model      string          `json:"model"`
+	Dimensions int             `json:"dimensions,omitempty"`
+	Provider   string          `json:"provider,omitempty"`
+	Usage      *EmbeddingUsage `json:"usage,omitempty"`
 }
 
 // EmbeddingUsage represents token usage for embeddings.
@@ -185,10 +423,13 @@ type EmbeddingUsage struct {
 }
 
 // Function represents a function definition for tool calling.
+// Strict asks providers that support it (OpenAI) to make the model's
+// arguments always match Parameters.
 type Function struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description,omitempty"`
 	Parameters  map[string]any `json:"parameters,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
 }
 
 // FunctionCall represents the function being called.
@@ -203,6 +444,31 @@ type ImageURL struct {
 	Detail string `json:"detail,omitempty"`
 }
 
+// Job represents a media generation job. Status is one of the JobStatus
+// constants; Error describes why a failed job failed. Jobs that complete at
+// submission, such as image generation on some providers, have no ID.
+type Job struct {
+	ID        string     `json:"id,omitempty"`
+	Kind      string     `json:"kind"`
+	Model     string     `json:"model,omitempty"`
+	Status    string     `json:"status"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// JobParams represents parameters for media generation jobs. Kind is one of
+// the JobKind constants. Image is an image to start from, such as the first
+// frame of a video, as a URL or data URL. N is the number of outputs.
+type JobParams struct {
+	Model          string    `json:"model"`
+	Kind           string    `json:"kind"`
+	Prompt         string    `json:"prompt"`
+	NegativePrompt string    `json:"negative_prompt,omitempty"`
+	AspectRatio    string    `json:"aspect_ratio,omitempty"`
+	Image          *ImageURL `json:"image,omitempty"`
+	N              int       `json:"n,omitempty"`
+}
+
 // JSONSchema for structured output.
 type JSONSchema struct {
 	Name        string         `json:"name"`
@@ -211,14 +477,20 @@ type JSONSchema struct {
 	Strict      *bool          `json:"strict,omitempty"`
 }
 
-// Message represents a chat message in OpenAI format.
+// Message represents a chat message in OpenAI format. Refusal is the
+// model's explanation for declining to answer, for providers that return it
+// apart from the content; the choice's finish reason is then
+// FinishReasonRefusal.
 type Message struct {
-	Role       string     `json:"role"`
-	Content    any        `json:"content"`
-	Name       string     `json:"name,omitempty"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
-	Reasoning  *Reasoning `json:"reasoning,omitempty"`
+	Role       string      `json:"role"`
+	Content    any         `json:"content"`
+	Refusal    string      `json:"refusal,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+	ToolResult *ToolResult `json:"tool_result,omitempty"`
+	Reasoning  *Reasoning  `json:"reasoning,omitempty"`
+	Audio      *Audio      `json:"audio,omitempty"`
 }
 
 // Model represents a model from the list models API.
@@ -229,12 +501,100 @@ type Model struct {
 	OwnedBy string `json:"owned_by"`
 }
 
+// ModelCard describes a model as served by a self-hosted server. Fields the
+// server does not report are left empty. ContextLength is the context window
+// requests are served with, which may be smaller than the window the model
+// was trained with. Capabilities is set when the server reports what the
+// model supports.
+type ModelCard struct {
+	Capabilities  *Capabilities `json:"capabilities,omitempty"`
+	ChatTemplate  string        `json:"chat_template,omitempty"`
+	ContextLength int           `json:"context_length,omitempty"`
+	Family        string        `json:"family,omitempty"`
+	ID            string        `json:"id"`
+	ParameterSize string        `json:"parameter_size,omitempty"`
+	Quantization  string        `json:"quantization,omitempty"`
+}
+
 // ModelsResponse represents a list models response.
 type ModelsResponse struct {
 	Object string  `json:"object"`
 	Data   []Model `json:"data"`
 }
 
+// OCRPage is the text of one page of a document read with OCR.
+type OCRPage struct {
+	Index    int    `json:"index"`
+	Markdown string `json:"markdown"`
+}
+
+// OCRParams represents parameters for OCR requests. Document is the URL of
+// the document, or a data URL such as "data:application/pdf;base64,...".
+type OCRParams struct {
+	Model    string `json:"model"`
+	Document string `json:"document"`
+}
+
+// OCRResponse represents the pages read from a document, in order.
+type OCRResponse struct {
+	Model string    `json:"model"`
+	Pages []OCRPage `json:"pages"`
+	Usage *OCRUsage `json:"usage,omitempty"`
+}
+
+// OCRUsage represents the usage of an OCR request.
+type OCRUsage struct {
+	PagesProcessed int `json:"pages_processed"`
+}
+
+// PromptFilterResult holds the content filter verdicts on one prompt of a
+// request.
+type PromptFilterResult struct {
+	PromptIndex          int                   `json:"prompt_index"`
+	ContentFilterResults []ContentFilterResult `json:"content_filter_results,omitempty"`
+}
+
+// Provenance records where a response came from, for downstream systems that
+// track AI-generated content.
+type Provenance struct {
+	// GeneratedAt is when the response was received.
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// Model is the model that generated the response.
+	Model string `json:"model"`
+
+	// PromptVersion identifies the prompt the request was built from, when
+	// it is known.
+	PromptVersion string `json:"prompt_version,omitempty"`
+
+	// Provider is the name of the provider that served the request.
+	Provider string `json:"provider"`
+
+	// RequestID is the provider's identifier for the request, when it
+	// reported one.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// RateLimit is the state of one rate limit window.
+type RateLimit struct {
+	// Limit is the maximum allowed in the window.
+	Limit int `json:"limit"`
+
+	// Remaining is how much of the limit is left in the window.
+	Remaining int `json:"remaining"`
+
+	// Reset is the time until the window resets, measured from when the response was received.
+	Reset time.Duration `json:"reset,omitempty"`
+}
+
+// RateLimitState is the rate limit state reported by a provider, parsed from
+// headers such as x-ratelimit-remaining-requests. A window is nil when the
+// provider did not report it.
+type RateLimitState struct {
+	Requests *RateLimit `json:"requests,omitempty"`
+	Tokens   *RateLimit `json:"tokens,omitempty"`
+}
+
 // Reasoning represents extended thinking/reasoning content.
 type Reasoning struct {
 	Content string `json:"content,omitempty"`
@@ -246,11 +606,69 @@ type ResponseFormat struct {
 	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
 }
 
+// SamplingLimits describes the sampling parameter values a provider or model
+// accepts. The zero value accepts temperature in [0, 2] and top_p in [0, 1].
+type SamplingLimits struct {
+	// ExclusiveTemperatureTopP rejects requests that set both temperature and top_p.
+	ExclusiveTemperatureTopP bool
+
+	// FixedSampling rejects temperature and top_p, for models that do not
+	// accept them such as OpenAI's o-series reasoning models.
+	FixedSampling bool
+
+	// MaxTemperature is the highest accepted temperature. Zero means 2.
+	MaxTemperature float64
+}
+
 // StreamOptions contains options for streaming responses.
 type StreamOptions struct {
 	IncludeUsage bool `json:"include_usage,omitempty"`
 }
 
+// TextCompletion is the result of a raw text completion.
+type TextCompletion struct {
+	Content      string `json:"content"`
+	FinishReason string `json:"finish_reason"`
+	Model        string `json:"model"`
+	Usage        *Usage `json:"usage,omitempty"`
+}
+
+// TextCompletionParams represents a raw text completion request: a prompt
+// string in place of messages, with the sampling parameters of
+// CompletionParams.
+type TextCompletionParams struct {
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// Timings reports the cadence and throughput of a stream. It is set on the
+// final chunk (the one carrying the finish reason or usage) and covers every
+// chunk emitted up to and including it.
+type Timings struct {
+	ChunkCount       int           `json:"chunk_count"`
+	Duration         time.Duration `json:"duration"`
+	TimeToFirstChunk time.Duration `json:"time_to_first_chunk"`
+	MeanChunkGap     time.Duration `json:"mean_chunk_gap"`
+	MaxChunkGap      time.Duration `json:"max_chunk_gap"`
+	TokensPerSecond  float64       `json:"tokens_per_second,omitempty"`
+}
+
+// TokenizeParams represents parameters for tokenization requests.
+type TokenizeParams struct {
+	Content    string `json:"content"`
+	AddSpecial bool   `json:"add_special,omitempty"`
+}
+
+// TokenizeResponse represents the tokens produced for a piece of text.
+type TokenizeResponse struct {
+	Tokens []int `json:"tokens"`
+}
+
 // Tool represents a tool/function that can be called.
 type Tool struct {
 	Type     string   `json:"type"`
@@ -275,12 +693,38 @@ type ToolChoiceFunction struct {
 	Name string `json:"name"`
 }
 
+// ToolResult is the outcome of a tool call, sent back in a RoleTool message
+// built with NewToolResultMessage. Providers with a native error flag, such as
+// Anthropic and Gemini, receive IsError as that flag; others receive the
+// message's text content, which marks errors with an "Error: " prefix.
+type ToolResult struct {
+	// Content is the result as text, or the error message if IsError is set.
+	Content string `json:"content,omitempty"`
+
+	// Data is a structured result, sent as JSON when Content is empty.
+	Data any `json:"data,omitempty"`
+
+	// IsError reports that the tool failed.
+	IsError bool `json:"is_error,omitempty"`
+}
+
 // Usage represents token usage information.
+// CachedTokens is the part of PromptTokens served from the provider's prompt cache.
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
 	ReasoningTokens  int `json:"reasoning_tokens,omitempty"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+}
+
+// CacheHitRatio returns the fraction of prompt tokens served from the provider's
+// prompt cache, between 0 and 1. It is 0 when no prompt tokens were reported.
+func (u *Usage) CacheHitRatio() float64 {
+	if u == nil || u.PromptTokens == 0 {
+		return 0
+	}
+	return float64(u.CachedTokens) / float64(u.PromptTokens)
 }
 
 // ContentParts extracts content parts from a message.
@@ -319,7 +763,165 @@ func (m *Message) ContentString() string {
 	return ""
 }
 
+// Done reports whether the job has finished, successfully or not.
+func (j *Job) Done() bool {
+	return j.Status == JobStatusFailed || j.Status == JobStatusSucceeded
+}
+
+// Count returns the number of tokens in the response.
+func (r *TokenizeResponse) Count() int {
+	return len(r.Tokens)
+}
+
 // IsMultiModal returns true if the message contains multi-modal content.
 func (m *Message) IsMultiModal() bool {
 	return m.ContentParts() != nil
 }
+
+// EmbeddingDimensions returns the number of dimensions of the embeddings in
+// data, for providers to set EmbeddingResponse.Dimensions. It is 0 if data is
+// empty.
+func EmbeddingDimensions(data []EmbeddingData) int {
+	if len(data) == 0 {
+		return 0
+	}
+	return len(data[0].Embedding)
+}
+
+// NewToolResultMessage returns a RoleTool message carrying result as the
+// answer to the tool call with the given ID. The message's content is set to
+// the result's text, prefixed with "Error: " if the tool failed.
+func NewToolResultMessage(toolCallID string, result ToolResult) Message {
+	content := result.Text()
+	if result.IsError {
+		content = toolErrorPrefix + content
+	}
+
+	return Message{
+		Role:       RoleTool,
+		Content:    content,
+		ToolCallID: toolCallID,
+		ToolResult: &result,
+	}
+}
+
+// Text returns the result as text: Content if set, otherwise Data encoded
+// as JSON.
+func (r ToolResult) Text() string {
+	if r.Content != "" || r.Data == nil {
+		return r.Content
+	}
+
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return fmt.Sprint(r.Data)
+	}
+	return string(data)
+}
+
+// OverrideCapabilities returns caps with the overrides set by
+// config.WithCapabilityOverrides applied. Providers call it in Capabilities,
+// so that users can correct what a provider reports for a nonstandard
+// endpoint. A nil cfg leaves caps unchanged.
+func OverrideCapabilities(caps Capabilities, cfg *config.Config) Capabilities {
+	if cfg == nil {
+		return caps
+	}
+
+	fields := map[string]*bool{
+		config.CapabilityCompletion:           &caps.Completion,
+		config.CapabilityCompletionImage:      &caps.CompletionImage,
+		config.CapabilityCompletionJSONObject: &caps.CompletionJSONObject,
+		config.CapabilityCompletionJSONSchema: &caps.CompletionJSONSchema,
+		config.CapabilityCompletionPDF:        &caps.CompletionPDF,
+		config.CapabilityCompletionReasoning:  &caps.CompletionReasoning,
+		config.CapabilityCompletionStreaming:  &caps.CompletionStreaming,
+		config.CapabilityCompletionTools:      &caps.CompletionTools,
+		config.CapabilityEmbedding:            &caps.Embedding,
+		config.CapabilityListModels:           &caps.ListModels,
+	}
+	for name, value := range cfg.CapabilityOverrides {
+		if field, ok := fields[name]; ok {
+			*field = value
+		}
+	}
+	return caps
+}
+
+// ToolChoiceForFunction returns a tool choice that forces the model to call the named function.
+func ToolChoiceForFunction(name string) ToolChoice {
+	return ToolChoice{
+		Type:     ToolChoiceTypeFunction,
+		Function: &ToolChoiceFunction{Name: name},
+	}
+}
+
+// ValidateSampling checks the temperature, top_p and max_tokens of params
+// against limits. It returns an *errors.InvalidRequestError naming the
+// offending parameter, so requests fail before they are sent.
+func ValidateSampling(provider string, params CompletionParams, limits SamplingLimits) error {
+	maxTemperature := limits.MaxTemperature
+	if maxTemperature == 0 {
+		maxTemperature = defaultMaxTemperature
+	}
+
+	if params.Temperature != nil {
+		switch {
+		case limits.FixedSampling:
+			return errors.NewInvalidParamError(provider, paramTemperature,
+				fmt.Errorf("temperature is not supported by model %q", params.Model))
+		case *params.Temperature < 0 || *params.Temperature > maxTemperature:
+			return errors.NewInvalidParamError(provider, paramTemperature,
+				fmt.Errorf("temperature must be between 0 and %g, got %g", maxTemperature, *params.Temperature))
+		default:
+		}
+	}
+
+	if params.TopP != nil {
+		switch {
+		case limits.FixedSampling:
+			return errors.NewInvalidParamError(provider, paramTopP,
+				fmt.Errorf("top_p is not supported by model %q", params.Model))
+		case *params.TopP < 0 || *params.TopP > 1:
+			return errors.NewInvalidParamError(provider, paramTopP,
+				fmt.Errorf("top_p must be between 0 and 1, got %g", *params.TopP))
+		case limits.ExclusiveTemperatureTopP && params.Temperature != nil:
+			return errors.NewInvalidParamError(provider, paramTopP,
+				fmt.Errorf("temperature and top_p cannot both be set for model %q", params.Model))
+		default:
+		}
+	}
+
+	if params.MaxTokens != nil && *params.MaxTokens <= 0 {
+		return errors.NewInvalidParamError(provider, paramMaxTokens,
+			fmt.Errorf("max_tokens must be positive, got %d", *params.MaxTokens))
+	}
+
+	return nil
+}
+
+// ValidateToolChoice checks that choice is nil, one of the tool choice mode constants,
+// or a ToolChoice that names a function.
+func ValidateToolChoice(choice any) error {
+	switch v := choice.(type) {
+	case nil:
+		return nil
+	case string:
+		switch v {
+		case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+			return nil
+		default:
+			return fmt.Errorf("unsupported tool choice %q", v)
+		}
+	case ToolChoice:
+		if v.Type != "" && v.Type != ToolChoiceTypeFunction {
+			return fmt.Errorf("unsupported tool choice type %q", v.Type)
+		}
+		if v.Function == nil || v.Function.Name == "" {
+			return fmt.Errorf("tool choice function name is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported tool choice value of type %T", choice)
+	}
+}
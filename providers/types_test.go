@@ -0,0 +1,204 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/config"
+	"github.com/mozilla-ai/any-llm-go/errors"
+)
+
+func TestOverrideCapabilities(t *testing.T) {
+	t.Parallel()
+
+	caps := Capabilities{Completion: true, CompletionTools: true}
+	require.Equal(t, caps, OverrideCapabilities(caps, nil))
+
+	cfg, err := config.New(config.WithCapabilityOverrides(map[string]bool{
+		config.CapabilityCompletionImage: true,
+		config.CapabilityCompletionTools: false,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, Capabilities{Completion: true, CompletionImage: true}, OverrideCapabilities(caps, cfg))
+}
+
+func TestToolChoiceForFunction(t *testing.T) {
+	t.Parallel()
+
+	choice := ToolChoiceForFunction("get_weather")
+
+	require.Equal(t, ToolChoiceTypeFunction, choice.Type)
+	require.NotNil(t, choice.Function)
+	require.Equal(t, "get_weather", choice.Function.Name)
+}
+
+func TestValidateSampling(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(v float64) *float64 { return &v }
+	maxTokens := 0
+
+	tests := []struct {
+		name      string
+		params    CompletionParams
+		limits    SamplingLimits
+		wantParam string
+	}{
+		{name: "unset", params: CompletionParams{}},
+		{name: "defaults", params: CompletionParams{Temperature: ptr(2), TopP: ptr(1)}},
+		{name: "temperature above default", params: CompletionParams{Temperature: ptr(2.1)}, wantParam: "temperature"},
+		{name: "negative temperature", params: CompletionParams{Temperature: ptr(-0.1)}, wantParam: "temperature"},
+		{
+			name:      "temperature above limit",
+			params:    CompletionParams{Temperature: ptr(1.5)},
+			limits:    SamplingLimits{MaxTemperature: 1},
+			wantParam: "temperature",
+		},
+		{name: "top_p above one", params: CompletionParams{TopP: ptr(1.2)}, wantParam: "top_p"},
+		{
+			name:      "fixed sampling",
+			params:    CompletionParams{Model: "o3", Temperature: ptr(0.5)},
+			limits:    SamplingLimits{FixedSampling: true},
+			wantParam: "temperature",
+		},
+		{
+			name:      "fixed sampling top_p",
+			params:    CompletionParams{Model: "o3", TopP: ptr(0.5)},
+			limits:    SamplingLimits{FixedSampling: true},
+			wantParam: "top_p",
+		},
+		{
+			name:      "exclusive temperature and top_p",
+			params:    CompletionParams{Temperature: ptr(0.5), TopP: ptr(0.9)},
+			limits:    SamplingLimits{ExclusiveTemperatureTopP: true},
+			wantParam: "top_p",
+		},
+		{
+			name:   "exclusive with only top_p",
+			params: CompletionParams{TopP: ptr(0.9)},
+			limits: SamplingLimits{ExclusiveTemperatureTopP: true},
+		},
+		{name: "non-positive max_tokens", params: CompletionParams{MaxTokens: &maxTokens}, wantParam: "max_tokens"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateSampling("test", tc.params, tc.limits)
+			if tc.wantParam == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var invalidErr *errors.InvalidRequestError
+			require.ErrorAs(t, err, &invalidErr)
+			require.Equal(t, tc.wantParam, invalidErr.Param)
+			require.Equal(t, "test", invalidErr.Provider)
+		})
+	}
+}
+
+func TestValidateToolChoice(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		choice  any
+		wantErr bool
+	}{
+		{name: "nil", choice: nil},
+		{name: "auto", choice: ToolChoiceAuto},
+		{name: "none", choice: ToolChoiceNone},
+		{name: "required", choice: ToolChoiceRequired},
+		{name: "named function", choice: ToolChoiceForFunction("get_weather")},
+		{
+			name:   "named function without type",
+			choice: ToolChoice{Function: &ToolChoiceFunction{Name: "get_weather"}},
+		},
+		{name: "unknown string", choice: "sometimes", wantErr: true},
+		{name: "function without name", choice: ToolChoice{Type: ToolChoiceTypeFunction}, wantErr: true},
+		{
+			name:    "unknown type",
+			choice:  ToolChoice{Type: "tool", Function: &ToolChoiceFunction{Name: "get_weather"}},
+			wantErr: true,
+		},
+		{name: "unsupported value", choice: 42, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateToolChoice(tc.choice)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewToolResultMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		result      ToolResult
+		wantContent string
+	}{
+		{
+			name:        "text result",
+			result:      ToolResult{Content: "sunny, 22°C"},
+			wantContent: "sunny, 22°C",
+		},
+		{
+			name:        "structured result",
+			result:      ToolResult{Data: map[string]any{"celsius": 22}},
+			wantContent: `{"celsius":22}`,
+		},
+		{
+			name:        "failed result",
+			result:      ToolResult{Content: "city not found", IsError: true},
+			wantContent: "Error: city not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			msg := NewToolResultMessage("call_1", tc.result)
+
+			require.Equal(t, RoleTool, msg.Role)
+			require.Equal(t, "call_1", msg.ToolCallID)
+			require.Equal(t, tc.wantContent, msg.ContentString())
+			require.Equal(t, tc.result, *msg.ToolResult)
+		})
+	}
+}
+
+func TestUsageCacheHitRatio(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		usage *Usage
+		want  float64
+	}{
+		{name: "nil usage", usage: nil, want: 0},
+		{name: "no prompt tokens", usage: &Usage{}, want: 0},
+		{name: "no cache hits", usage: &Usage{PromptTokens: 100}, want: 0},
+		{name: "partial cache hit", usage: &Usage{PromptTokens: 200, CachedTokens: 50}, want: 0.25},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.InDelta(t, tc.want, tc.usage.CacheHitRatio(), 1e-9)
+		})
+	}
+}
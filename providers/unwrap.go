@@ -0,0 +1,31 @@
+package providers
+
+// Wrapper is implemented by providers that wrap another provider, such as the
+// retry, truncate and telemetry wrappers.
+type Wrapper interface {
+	// Unwrap returns the wrapped provider.
+	Unwrap() Provider
+}
+
+// As finds the first provider in the chain of p and the providers it wraps
+// that implements T, and reports whether there is one. The chain is followed
+// through Unwrap, so optional interfaces such as CapabilityProvider or
+// TokenCounter are found behind wrappers that do not implement them
+// themselves. A wrapper that implements T is returned before the provider it
+// wraps.
+func As[T any](p Provider) (T, bool) {
+	for p != nil {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+
+		w, ok := p.(Wrapper)
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// baseProvider is a provider that reports its capabilities.
+type baseProvider struct {
+	caps Capabilities
+}
+
+func (p baseProvider) Capabilities() Capabilities { return p.caps }
+
+func (p baseProvider) Completion(context.Context, CompletionParams) (*ChatCompletion, error) {
+	return nil, nil
+}
+
+func (p baseProvider) CompletionStream(
+	context.Context,
+	CompletionParams,
+) (<-chan ChatCompletionChunk, <-chan error) {
+	return nil, nil
+}
+
+func (p baseProvider) Name() string { return "base" }
+
+// wrappingProvider wraps a provider without implementing its optional
+// interfaces.
+type wrappingProvider struct {
+	Provider
+}
+
+func (p wrappingProvider) Unwrap() Provider { return p.Provider }
+
+// capableWrapper wraps a provider and reports capabilities of its own.
+type capableWrapper struct {
+	wrappingProvider
+}
+
+func (p capableWrapper) Capabilities() Capabilities { return Capabilities{Embedding: true} }
+
+func TestAs(t *testing.T) {
+	t.Parallel()
+
+	base := baseProvider{caps: Capabilities{CompletionTools: true}}
+
+	tests := []struct {
+		name     string
+		provider Provider
+		wantOK   bool
+		want     Capabilities
+	}{
+		{name: "provider itself", provider: base, wantOK: true, want: base.caps},
+		{
+			name:     "through wrappers",
+			provider: wrappingProvider{wrappingProvider{base}},
+			wantOK:   true,
+			want:     base.caps,
+		},
+		{
+			name:     "outermost implementation first",
+			provider: capableWrapper{wrappingProvider{base}},
+			wantOK:   true,
+			want:     Capabilities{Embedding: true},
+		},
+		{name: "wrapped nil", provider: wrappingProvider{}, wantOK: false},
+		{name: "nil", provider: nil, wantOK: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cp, ok := As[CapabilityProvider](tc.provider)
+			require.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				require.Equal(t, tc.want, cp.Capabilities())
+			}
+		})
+	}
+}
@@ -0,0 +1,487 @@
+// Package ragpack packs retrieved snippets into a context block for
+// retrieval-augmented generation. It picks the snippets that fit a token
+// budget, either by score alone or trading score for diversity with maximal
+// marginal relevance (MMR), and reports where each packed snippet came from
+// so that answers can cite their sources.
+package ragpack
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/tokencount"
+)
+
+// Packing defaults.
+const (
+	// defaultContextShare is the share of the model's context window the
+	// block may use when the budget comes from the model card.
+	defaultContextShare = 0.5
+
+	defaultLambda    = 0.5
+	defaultSeparator = "\n\n"
+)
+
+// Packing strategies.
+const (
+	// StrategyGreedy packs snippets in order of score, skipping those that no
+	// longer fit.
+	StrategyGreedy Strategy = "greedy"
+
+	// StrategyMMR packs snippets by maximal marginal relevance: each next
+	// snippet balances its score against its similarity to the snippets
+	// already packed, which keeps near-duplicates out of the block.
+	StrategyMMR Strategy = "mmr"
+)
+
+// Format renders a snippet in the context block. n is the snippet's citation
+// number, counted from 1 in block order.
+type Format func(n int, snippet Snippet) string
+
+// Option configures a Packer.
+type Option func(*Packer) error
+
+// Packer packs snippets into a context block within a token budget.
+type Packer struct {
+	budget    int
+	counter   *tokencount.Counter
+	format    Format
+	lambda    float64
+	model     string
+	provider  providers.Provider
+	reserve   int
+	separator string
+	strategy  Strategy
+}
+
+// Placement records where a packed snippet came from and where it is in the
+// context block.
+type Placement struct {
+	// Citation is the snippet's citation number in the block, counted from 1.
+	Citation int `json:"citation"`
+
+	// End is the byte offset in the block just past the snippet.
+	End int `json:"end"`
+
+	// ID is the snippet's ID.
+	ID string `json:"id,omitempty"`
+
+	// Index is the snippet's index in the candidates passed to Pack.
+	Index int `json:"index"`
+
+	// Score is the snippet's retrieval score.
+	Score float64 `json:"score"`
+
+	// Source is the snippet's source.
+	Source string `json:"source,omitempty"`
+
+	// Start is the byte offset of the snippet in the block.
+	Start int `json:"start"`
+
+	// Tokens is the snippet's token count, as formatted in the block.
+	Tokens int `json:"tokens"`
+}
+
+// Result is a packed context block.
+type Result struct {
+	// Budget is the token budget the block was packed to.
+	Budget int `json:"budget"`
+
+	// Context is the context block.
+	Context string `json:"context"`
+
+	// Dropped are the indexes of the candidates that were not packed, in
+	// candidate order.
+	Dropped []int `json:"dropped,omitempty"`
+
+	// Placements are the packed snippets, in block order.
+	Placements []Placement `json:"placements"`
+
+	// Tokens is the block's token count. Snippets are counted on their own,
+	// so it may differ slightly from a count of the whole block.
+	Tokens int `json:"tokens"`
+}
+
+// Snippet is a retrieved piece of text to consider for the context block.
+type Snippet struct {
+	// Embedding is the snippet's embedding. It is required by StrategyMMR.
+	Embedding []float64
+
+	// ID identifies the snippet to the caller, such as a chunk ID in a vector
+	// store.
+	ID string
+
+	// Score is the snippet's retrieval score. Higher is more relevant; any
+	// scale works.
+	Score float64
+
+	// Source says where the snippet is from, such as a URL or file name.
+	Source string
+
+	// Text is the snippet's text.
+	Text string
+}
+
+// Strategy selects how snippets are chosen.
+type Strategy string
+
+// New returns a Packer. By default it packs greedily by score, renders
+// snippets with DefaultFormat and separates them with a blank line. A budget
+// is required: set it with WithBudget, or use WithModel to take half the
+// model's context window from its model card.
+func New(opts ...Option) (*Packer, error) {
+	p := &Packer{
+		format:    DefaultFormat,
+		lambda:    defaultLambda,
+		separator: defaultSeparator,
+		strategy:  StrategyGreedy,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.budget == 0 && p.provider == nil {
+		return nil, fmt.Errorf("a token budget is required; set one with WithBudget or WithModel")
+	}
+
+	counter, err := tokencount.New(p.provider)
+	if err != nil {
+		return nil, err
+	}
+	p.counter = counter
+
+	return p, nil
+}
+
+// DefaultFormat renders a snippet as its citation number in brackets, its
+// source if any, and its text:
+//
+//	[1] (https://example.com/guide) Text of the snippet.
+func DefaultFormat(n int, snippet Snippet) string {
+	var b strings.Builder
+	b.WriteString("[" + strconv.Itoa(n) + "] ")
+	if snippet.Source != "" {
+		b.WriteString("(" + snippet.Source + ") ")
+	}
+	b.WriteString(snippet.Text)
+	return b.String()
+}
+
+// WithBudget sets the token budget of the context block.
+func WithBudget(tokens int) Option {
+	return func(p *Packer) error {
+		if tokens <= 0 {
+			return fmt.Errorf("budget must be positive, got %d", tokens)
+		}
+		p.budget = tokens
+		return nil
+	}
+}
+
+// WithFormat sets how each snippet is rendered in the block.
+func WithFormat(format Format) Option {
+	return func(p *Packer) error {
+		if format == nil {
+			return fmt.Errorf("format must not be nil")
+		}
+		p.format = format
+		return nil
+	}
+}
+
+// WithLambda sets the MMR trade-off between relevance and diversity, from 0
+// (only diversity) to 1 (only score, like StrategyGreedy). The default is 0.5.
+func WithLambda(lambda float64) Option {
+	return func(p *Packer) error {
+		if math.IsNaN(lambda) || lambda < 0 || lambda > 1 {
+			return fmt.Errorf("lambda must be between 0 and 1, got %v", lambda)
+		}
+		p.lambda = lambda
+		return nil
+	}
+}
+
+// WithModel counts tokens with model's tokenizer when provider implements
+// providers.TokenCounter; otherwise they are estimated. Without WithBudget,
+// the budget comes from the model card when provider implements
+// providers.ModelCardProvider.
+func WithModel(provider providers.Provider, model string) Option {
+	return func(p *Packer) error {
+		if provider == nil {
+			return fmt.Errorf("provider must not be nil")
+		}
+		p.model = model
+		p.provider = provider
+		return nil
+	}
+}
+
+// WithReserve sets how many tokens of the model's context window to keep free
+// for the prompt and the reply when the budget comes from the model card. The
+// block then uses the rest of the window instead of half of it.
+func WithReserve(tokens int) Option {
+	return func(p *Packer) error {
+		if tokens <= 0 {
+			return fmt.Errorf("reserve must be positive, got %d", tokens)
+		}
+		p.reserve = tokens
+		return nil
+	}
+}
+
+// WithSeparator sets the text between snippets in the block.
+func WithSeparator(separator string) Option {
+	return func(p *Packer) error {
+		p.separator = separator
+		return nil
+	}
+}
+
+// WithStrategy sets how snippets are chosen.
+func WithStrategy(strategy Strategy) Option {
+	return func(p *Packer) error {
+		switch strategy {
+		case StrategyGreedy, StrategyMMR:
+			p.strategy = strategy
+			return nil
+		default:
+			return fmt.Errorf("unknown strategy %q", strategy)
+		}
+	}
+}
+
+// Pack chooses snippets that fit the budget and renders them into a context
+// block. Snippets without text are dropped. A snippet that does not fit is
+// skipped, and smaller ones after it may still be packed.
+func (p *Packer) Pack(ctx context.Context, snippets []Snippet) (*Result, error) {
+	budget, err := p.resolveBudget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.strategy == StrategyMMR {
+		for i, snippet := range snippets {
+			if snippet.Text != "" && len(snippet.Embedding) == 0 {
+				return nil, fmt.Errorf("snippet %d has no embedding, which the MMR strategy needs", i)
+			}
+		}
+	}
+
+	// Snippets are counted with the largest citation number, so counts are
+	// never below the rendered size.
+	costs := make([]int, len(snippets))
+	for i, snippet := range snippets {
+		if snippet.Text != "" {
+			costs[i] = p.countTokens(ctx, p.format(len(snippets), snippet)+p.separator)
+		}
+	}
+
+	var order []int
+	switch p.strategy {
+	case StrategyMMR:
+		order = p.selectMMR(snippets, costs, budget)
+	default:
+		order = selectGreedy(snippets, costs, budget)
+	}
+
+	return p.render(snippets, costs, order, budget), nil
+}
+
+// countTokens returns the token count of text, using the provider's tokenizer when available.
+func (p *Packer) countTokens(ctx context.Context, text string) int {
+	tokens, _ := p.counter.CountText(ctx, text) // Estimates are good enough to pack by.
+	return tokens
+}
+
+// render builds the result for the snippets at order.
+func (p *Packer) render(snippets []Snippet, costs []int, order []int, budget int) *Result {
+	result := &Result{Budget: budget, Placements: make([]Placement, 0, len(order))}
+
+	var b strings.Builder
+	packed := make([]bool, len(snippets))
+	for n, i := range order {
+		if n > 0 {
+			b.WriteString(p.separator)
+		}
+
+		snippet := snippets[i]
+		start := b.Len()
+		b.WriteString(p.format(n+1, snippet))
+
+		result.Placements = append(result.Placements, Placement{
+			Citation: n + 1,
+			End:      b.Len(),
+			ID:       snippet.ID,
+			Index:    i,
+			Score:    snippet.Score,
+			Source:   snippet.Source,
+			Start:    start,
+			Tokens:   costs[i],
+		})
+		result.Tokens += costs[i]
+		packed[i] = true
+	}
+	result.Context = b.String()
+
+	for i := range snippets {
+		if !packed[i] {
+			result.Dropped = append(result.Dropped, i)
+		}
+	}
+
+	return result
+}
+
+// resolveBudget returns the token budget: the configured one, or a share of
+// the model's context window from its model card.
+func (p *Packer) resolveBudget(ctx context.Context) (int, error) {
+	if p.budget > 0 {
+		return p.budget, nil
+	}
+
+	cards, ok := providers.As[providers.ModelCardProvider](p.provider)
+	if !ok {
+		return 0, fmt.Errorf("provider %s has no model cards; set a budget with WithBudget", p.provider.Name())
+	}
+	card, err := cards.ModelCard(ctx, p.model)
+	if err != nil {
+		return 0, err
+	}
+	if card.ContextLength == 0 {
+		return 0, fmt.Errorf("model card of %q has no context length; set a budget with WithBudget", p.model)
+	}
+
+	budget := int(float64(card.ContextLength) * defaultContextShare)
+	if p.reserve > 0 {
+		budget = card.ContextLength - p.reserve
+	}
+	if budget <= 0 {
+		return 0, fmt.Errorf("reserve of %d tokens leaves no room in the %d-token context window",
+			p.reserve, card.ContextLength)
+	}
+
+	return budget, nil
+}
+
+// selectMMR returns the indexes of the snippets to pack by maximal marginal
+// relevance, in the order they were chosen. Scores are rescaled to [0, 1] so
+// that they weigh against cosine similarities.
+func (p *Packer) selectMMR(snippets []Snippet, costs []int, budget int) []int {
+	relevance := normalize(snippets)
+
+	var order []int
+	used := 0
+	remaining := make([]bool, len(snippets))
+	for i, snippet := range snippets {
+		remaining[i] = snippet.Text != "" && costs[i] <= budget
+	}
+
+	for {
+		best, bestValue := -1, math.Inf(-1)
+		for i := range snippets {
+			if !remaining[i] {
+				continue
+			}
+			if used+costs[i] > budget {
+				remaining[i] = false
+				continue
+			}
+
+			similarity := 0.0
+			for _, j := range order {
+				similarity = max(similarity, cosine(snippets[i].Embedding, snippets[j].Embedding))
+			}
+
+			value := p.lambda*relevance[i] - (1-p.lambda)*similarity
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		if best < 0 {
+			return order
+		}
+
+		order = append(order, best)
+		used += costs[best]
+		remaining[best] = false
+	}
+}
+
+// cosine returns the cosine similarity of x and y, or 0 if either is zero or
+// their lengths differ.
+func cosine(x []float64, y []float64) float64 {
+	if len(x) != len(y) {
+		return 0
+	}
+
+	var dot, normX, normY float64
+	for i := range x {
+		dot += x[i] * y[i]
+		normX += x[i] * x[i]
+		normY += y[i] * y[i]
+	}
+	if normX == 0 || normY == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normX*normY)
+}
+
+// normalize rescales the snippets' scores to [0, 1]. Equal scores all become 1.
+func normalize(snippets []Snippet) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, snippet := range snippets {
+		lo = min(lo, snippet.Score)
+		hi = max(hi, snippet.Score)
+	}
+
+	relevance := make([]float64, len(snippets))
+	for i, snippet := range snippets {
+		relevance[i] = 1
+		if hi > lo {
+			relevance[i] = (snippet.Score - lo) / (hi - lo)
+		}
+	}
+	return relevance
+}
+
+// selectGreedy returns the indexes of the snippets to pack in order of score,
+// skipping those that no longer fit. Ties keep candidate order.
+func selectGreedy(snippets []Snippet, costs []int, budget int) []int {
+	byScore := make([]int, 0, len(snippets))
+	for i, snippet := range snippets {
+		if snippet.Text != "" {
+			byScore = append(byScore, i)
+		}
+	}
+	slices.SortStableFunc(byScore, func(a, b int) int {
+		switch {
+		case snippets[a].Score > snippets[b].Score:
+			return -1
+		case snippets[a].Score < snippets[b].Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	var order []int
+	used := 0
+	for _, i := range byScore {
+		if used+costs[i] <= budget {
+			order = append(order, i)
+			used += costs[i]
+		}
+	}
+	return order
+}
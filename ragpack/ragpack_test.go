@@ -0,0 +1,262 @@
+package ragpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+)
+
+// packedIndexes returns the candidate indexes of the packed snippets, in block order.
+func packedIndexes(result *Result) []int {
+	indexes := make([]int, 0, len(result.Placements))
+	for _, placement := range result.Placements {
+		indexes = append(indexes, placement.Index)
+	}
+	return indexes
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "requires a budget", wantErr: "a token budget is required; set one with WithBudget or WithModel"},
+		{name: "rejects a zero budget", opts: []Option{WithBudget(0)}, wantErr: "budget must be positive, got 0"},
+		{
+			name:    "rejects a nil format",
+			opts:    []Option{WithBudget(10), WithFormat(nil)},
+			wantErr: "format must not be nil",
+		},
+		{
+			name:    "rejects a lambda above 1",
+			opts:    []Option{WithBudget(10), WithLambda(1.5)},
+			wantErr: "lambda must be between 0 and 1, got 1.5",
+		},
+		{name: "rejects a nil provider", opts: []Option{WithModel(nil, "m")}, wantErr: "provider must not be nil"},
+		{
+			name:    "rejects a negative reserve",
+			opts:    []Option{WithBudget(10), WithReserve(-1)},
+			wantErr: "reserve must be positive, got -1",
+		},
+		{
+			name:    "rejects an unknown strategy",
+			opts:    []Option{WithBudget(10), WithStrategy("random")},
+			wantErr: `unknown strategy "random"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tc.opts...)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("accepts a model instead of a budget", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"))
+		require.NoError(t, err)
+		require.Equal(t, StrategyGreedy, p.strategy)
+	})
+}
+
+func TestPack(t *testing.T) {
+	t.Parallel()
+
+	snippets := []Snippet{
+		{ID: "a", Score: 0.2, Text: "one two three"},
+		{ID: "b", Score: 0.9, Source: "guide.md", Text: "four five six seven"},
+		{ID: "c", Score: 0.5, Text: "eight"},
+		{ID: "d", Score: 0.7, Text: "nine ten eleven twelve thirteen fourteen"},
+	}
+
+	t.Run("packs greedily by score", func(t *testing.T) {
+		t.Parallel()
+
+		// With the citation, b costs 6 tokens, d 7, c 2 and a 4.
+		p, err := New(WithBudget(12), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 0}, packedIndexes(result))
+		require.Equal(t, []int{3}, result.Dropped)
+		require.Equal(t, 12, result.Tokens)
+		require.Equal(t, 12, result.Budget)
+		require.Equal(t, "[1] (guide.md) four five six seven\n\n[2] eight\n\n[3] one two three", result.Context)
+	})
+
+	t.Run("records provenance", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(12), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, Placement{
+			Citation: 1,
+			End:      34,
+			ID:       "b",
+			Index:    1,
+			Score:    0.9,
+			Source:   "guide.md",
+			Start:    0,
+			Tokens:   6,
+		}, result.Placements[0])
+
+		for _, placement := range result.Placements {
+			text := result.Context[placement.Start:placement.End]
+			require.Equal(t, DefaultFormat(placement.Citation, snippets[placement.Index]), text)
+		}
+	})
+
+	t.Run("estimates tokens without a tokenizer", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(8))
+		require.NoError(t, err)
+
+		// "[4] eight\n\n" is 11 characters, or 3 estimated tokens.
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 0}, packedIndexes(result))
+		require.Equal(t, 8, result.Tokens)
+	})
+
+	t.Run("drops snippets without text", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(100), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), []Snippet{{Score: 1}, {Score: 0.5, Text: "kept"}})
+		require.NoError(t, err)
+		require.Equal(t, []int{1}, packedIndexes(result))
+		require.Equal(t, []int{0}, result.Dropped)
+	})
+
+	t.Run("applies a custom format and separator", func(t *testing.T) {
+		t.Parallel()
+
+		format := func(n int, s Snippet) string { return s.ID + ": " + s.Text }
+		p, err := New(WithBudget(10), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"), WithFormat(format), WithSeparator("\n---\n"))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets[:3])
+		require.NoError(t, err)
+		require.Equal(t, "b: four five six seven\n---\nc: eight", result.Context)
+	})
+
+	t.Run("takes the budget from the model card", func(t *testing.T) {
+		t.Parallel()
+
+		provider := testutil.ModelCardMock{
+			TokenCountingMock: testutil.NewTokenCountingMock(testutil.NewMockProvider()),
+			ContextLength:     24,
+		}
+		p, err := New(WithModel(provider, "m"))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, 12, result.Budget)
+
+		p, err = New(WithModel(provider, "m"), WithReserve(4))
+		require.NoError(t, err)
+
+		result, err = p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, 20, result.Budget)
+		require.Equal(t, []int{1, 3, 2, 0}, packedIndexes(result))
+	})
+
+	t.Run("needs a model card without a budget", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"))
+		require.NoError(t, err)
+
+		_, err = p.Pack(context.Background(), snippets)
+		require.EqualError(t, err, "provider mock has no model cards; set a budget with WithBudget")
+	})
+
+	t.Run("rejects a reserve larger than the window", func(t *testing.T) {
+		t.Parallel()
+
+		provider := testutil.ModelCardMock{
+			TokenCountingMock: testutil.NewTokenCountingMock(testutil.NewMockProvider()),
+			ContextLength:     8,
+		}
+		p, err := New(WithModel(provider, "m"), WithReserve(8))
+		require.NoError(t, err)
+
+		_, err = p.Pack(context.Background(), snippets)
+		require.EqualError(t, err, "reserve of 8 tokens leaves no room in the 8-token context window")
+	})
+}
+
+func TestPackMMR(t *testing.T) {
+	t.Parallel()
+
+	// b is a near-duplicate of a; c covers something else.
+	snippets := []Snippet{
+		{Embedding: []float64{1, 0}, ID: "a", Score: 0.9, Text: "alpha"},
+		{Embedding: []float64{0.99, 0.1}, ID: "b", Score: 0.85, Text: "alpha again"},
+		{Embedding: []float64{0, 1}, ID: "c", Score: 0.6, Text: "gamma"},
+	}
+
+	t.Run("prefers diverse snippets", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(100), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"), WithStrategy(StrategyMMR))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 2, 1}, packedIndexes(result))
+	})
+
+	t.Run("follows score with a lambda of 1", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(100), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"), WithStrategy(StrategyMMR), WithLambda(1))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 1, 2}, packedIndexes(result))
+	})
+
+	t.Run("respects the budget", func(t *testing.T) {
+		t.Parallel()
+
+		// a and c cost 2 tokens each and b 3.
+		p, err := New(WithBudget(4), WithModel(testutil.NewTokenCountingMock(testutil.NewMockProvider()), "m"), WithStrategy(StrategyMMR))
+		require.NoError(t, err)
+
+		result, err := p.Pack(context.Background(), snippets)
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 2}, packedIndexes(result))
+		require.Equal(t, []int{1}, result.Dropped)
+	})
+
+	t.Run("requires embeddings", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(WithBudget(100), WithStrategy(StrategyMMR))
+		require.NoError(t, err)
+
+		_, err = p.Pack(context.Background(), []Snippet{{Text: "no embedding"}})
+		require.EqualError(t, err, "snippet 0 has no embedding, which the MMR strategy needs")
+	})
+}
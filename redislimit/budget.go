@@ -0,0 +1,304 @@
+package redislimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// partBudget is the part of a budget's state that holds its spend.
+const partBudget = "budget"
+
+// tokensPerMillion converts per-million token prices to per-token prices.
+const tokensPerMillion = 1_000_000
+
+// spendScript adds ARGV[2] cost, ARGV[3] tokens and ARGV[4] requests to the
+// spend of the current window of ARGV[1] milliseconds in the budget hash,
+// KEYS[1]. Windows are aligned to the Unix epoch, and a new window starts
+// from zero. It returns the cost, as a string, the tokens and the requests
+// spent in the window, and the milliseconds until it ends.
+const spendScript = serverNow + `
+local window = tonumber(ARGV[1])
+local current = math.floor(now / window)
+local ends = (current + 1) * window
+local state = redis.call('HMGET', KEYS[1], 'window', 'cost', 'tokens', 'requests')
+local cost, tokens, requests = 0, 0, 0
+if tonumber(state[1]) == current then
+	cost, tokens, requests = tonumber(state[2]), tonumber(state[3]), tonumber(state[4])
+end
+local addCost, addTokens, addRequests = tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+if addCost > 0 or addTokens > 0 or addRequests > 0 then
+	cost, tokens, requests = cost + addCost, tokens + addTokens, requests + addRequests
+	redis.call('HSET', KEYS[1], 'window', current, 'cost', tostring(cost), 'tokens', tokens, 'requests', requests)
+	redis.call('PEXPIREAT', KEYS[1], ends)
+end
+return {tostring(cost), tokens, requests, ends - now}
+`
+
+// Ensure Budget implements the required interfaces.
+var _ providers.Provider = (*Budget)(nil)
+
+// Budget wraps a provider and caps what it may spend in a time window,
+// together with every replica that shares the Redis key. Requests over the
+// budget fail with errors.ErrQuotaExceeded until the window ends. Limits are
+// checked before each request, so the request that crosses a limit
+// completes.
+type Budget struct {
+	client   Client
+	key      string
+	limits   Limits
+	pricing  *pricing
+	provider providers.Provider
+	window   time.Duration
+}
+
+// BudgetOption configures a Budget.
+type BudgetOption func(*Budget) error
+
+// Limits are the limits of a budget. Zero fields are not limited.
+type Limits struct {
+	// MaxCost limits the estimated cost, in the currency of WithPricing.
+	MaxCost float64
+
+	// MaxRequests limits the number of completion requests.
+	MaxRequests int
+
+	// MaxTokens limits the total tokens.
+	MaxTokens int
+}
+
+// Spend is what has been spent in a budget's current window.
+type Spend struct {
+	// Cost is the cost estimated from the prices set with WithPricing, or 0
+	// without them.
+	Cost float64
+
+	// Requests is the number of completion requests.
+	Requests int
+
+	// ResetIn is the time until the window ends and the spend starts over.
+	ResetIn time.Duration
+
+	// Tokens is the total tokens.
+	Tokens int
+}
+
+// pricing is the price of a million prompt and completion tokens.
+type pricing struct {
+	inputPerMillion  float64
+	outputPerMillion float64
+}
+
+// NewBudget wraps provider so that it spends within limits per window,
+// together with every other Budget using key. Windows are aligned to the
+// Unix epoch, so a 24 hour window starts at midnight UTC. A cost limit
+// requires WithPricing.
+func NewBudget(
+	provider providers.Provider,
+	client Client,
+	key string,
+	limits Limits,
+	window time.Duration,
+	opts ...BudgetOption,
+) (*Budget, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	if limits.MaxCost < 0 || limits.MaxRequests < 0 || limits.MaxTokens < 0 {
+		return nil, fmt.Errorf("budget limits must not be negative, got %+v", limits)
+	}
+	if limits == (Limits{}) {
+		return nil, fmt.Errorf("at least one budget limit is required")
+	}
+	if window < time.Millisecond {
+		return nil, fmt.Errorf("window must be at least 1ms, got %v", window)
+	}
+
+	b := &Budget{
+		client:   client,
+		key:      key,
+		limits:   limits,
+		provider: provider,
+		window:   window,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if limits.MaxCost > 0 && b.pricing == nil {
+		return nil, fmt.Errorf("a cost limit requires WithPricing")
+	}
+
+	return b, nil
+}
+
+// WithPricing sets the price of a million prompt and completion tokens, used
+// to estimate the cost of requests.
+func WithPricing(inputPerMillion, outputPerMillion float64) BudgetOption {
+	return func(b *Budget) error {
+		if inputPerMillion < 0 || outputPerMillion < 0 {
+			return fmt.Errorf("prices must not be negative, got %v and %v", inputPerMillion, outputPerMillion)
+		}
+
+		b.pricing = &pricing{inputPerMillion: inputPerMillion, outputPerMillion: outputPerMillion}
+		return nil
+	}
+}
+
+// Completion performs a chat completion request if the budget allows it, and
+// adds it to the spend.
+func (b *Budget) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if err := b.check(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := b.provider.Completion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.record(ctx, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request if the budget
+// allows it, and adds it to the spend when it ends. A stream that fails
+// before reporting its usage is not counted.
+func (b *Budget) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		if err := b.check(ctx); err != nil {
+			outErrs <- err
+			return
+		}
+
+		if b.limits.MaxCost > 0 || b.limits.MaxTokens > 0 {
+			params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+		}
+		usage, err := relay(ctx, b.provider, params, out)
+		if err == nil || usage != nil {
+			if recordErr := b.record(ctx, usage); err == nil {
+				err = recordErr
+			}
+		}
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (b *Budget) Name() string {
+	return b.provider.Name()
+}
+
+// Spend returns what has been spent in the current window.
+func (b *Budget) Spend(ctx context.Context) (Spend, error) {
+	return b.add(ctx, 0, 0, 0)
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (b *Budget) Unwrap() providers.Provider {
+	return b.provider
+}
+
+// add adds to the spend of the current window and returns it.
+func (b *Budget) add(ctx context.Context, cost float64, tokens int, requests int) (Spend, error) {
+	reply, err := eval(ctx, b.client, spendScript, []string{hashKey(b.key, partBudget)},
+		milliseconds(b.window), cost, tokens, requests)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 4 {
+		return Spend{}, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+
+	var spend Spend
+	var resetIn int
+	if spend.Cost, err = toFloat(values[0]); err != nil {
+		return Spend{}, err
+	}
+	if spend.Tokens, err = toInt(values[1]); err != nil {
+		return Spend{}, err
+	}
+	if spend.Requests, err = toInt(values[2]); err != nil {
+		return Spend{}, err
+	}
+	if resetIn, err = toInt(values[3]); err != nil {
+		return Spend{}, err
+	}
+	spend.ResetIn = time.Duration(resetIn) * time.Millisecond
+
+	return spend, nil
+}
+
+// check returns an error if the budget is used up.
+func (b *Budget) check(ctx context.Context) error {
+	spend, err := b.Spend(ctx)
+	if err != nil {
+		return err
+	}
+
+	var exceeded string
+	switch l := b.limits; {
+	case l.MaxCost > 0 && spend.Cost >= l.MaxCost:
+		exceeded = fmt.Sprintf("cost %v of %v", spend.Cost, l.MaxCost)
+	case l.MaxRequests > 0 && spend.Requests >= l.MaxRequests:
+		exceeded = fmt.Sprintf("%d of %d requests", spend.Requests, l.MaxRequests)
+	case l.MaxTokens > 0 && spend.Tokens >= l.MaxTokens:
+		exceeded = fmt.Sprintf("%d of %d tokens", spend.Tokens, l.MaxTokens)
+	default:
+		return nil
+	}
+
+	return errors.NewQuotaExceededError(b.provider.Name(), fmt.Errorf(
+		"budget %q has used %s, resets in %v", b.key, exceeded, spend.ResetIn,
+	))
+}
+
+// record adds a request with usage to the spend. It is counted even if ctx
+// was canceled once the request completed.
+func (b *Budget) record(ctx context.Context, usage *providers.Usage) error {
+	var cost float64
+	var tokens int
+	if usage != nil {
+		tokens = usage.TotalTokens
+		if b.pricing != nil {
+			cost = (float64(usage.PromptTokens)*b.pricing.inputPerMillion +
+				float64(usage.CompletionTokens)*b.pricing.outputPerMillion) / tokensPerMillion
+		}
+	}
+
+	// The spend is only needed before requests.
+	_, err := b.add(context.WithoutCancel(ctx), cost, tokens, 1)
+	return err
+}
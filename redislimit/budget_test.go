@@ -0,0 +1,164 @@
+package redislimit
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestNewBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  Client
+		key     string
+		limits  Limits
+		window  time.Duration
+		opts    []BudgetOption
+		wantErr string
+	}{
+		{name: "client", key: "k", limits: Limits{MaxRequests: 1}, window: time.Hour, wantErr: "redis client is required"},
+		{name: "key", client: newFakeRedis(), limits: Limits{MaxRequests: 1}, window: time.Hour, wantErr: "key is required"},
+		{
+			name:    "negative limit",
+			client:  newFakeRedis(),
+			key:     "k",
+			limits:  Limits{MaxTokens: -1},
+			window:  time.Hour,
+			wantErr: "budget limits must not be negative, got {MaxCost:0 MaxRequests:0 MaxTokens:-1}",
+		},
+		{name: "no limit", client: newFakeRedis(), key: "k", window: time.Hour, wantErr: "at least one budget limit is required"},
+		{
+			name:    "window",
+			client:  newFakeRedis(),
+			key:     "k",
+			limits:  Limits{MaxRequests: 1},
+			wantErr: "window must be at least 1ms, got 0s",
+		},
+		{
+			name:    "cost without pricing",
+			client:  newFakeRedis(),
+			key:     "k",
+			limits:  Limits{MaxCost: 10},
+			window:  time.Hour,
+			wantErr: "a cost limit requires WithPricing",
+		},
+		{
+			name:    "negative price",
+			client:  newFakeRedis(),
+			key:     "k",
+			limits:  Limits{MaxCost: 10},
+			window:  time.Hour,
+			opts:    []BudgetOption{WithPricing(-1, 1)},
+			wantErr: "prices must not be negative, got -1 and 1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewBudget(testutil.NewMockProvider(), tc.client, tc.key, tc.limits, tc.window, tc.opts...)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hi"}},
+	}
+	midnight := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	t.Run("shares the spend between replicas", func(t *testing.T) {
+		t.Parallel()
+
+		redis := newFakeRedis()
+		redis.now = midnight.Add(18 * time.Hour)
+		replica := func() *Budget {
+			b, err := NewBudget(testutil.NewMockProvider(), redis, "team", Limits{MaxCost: 30}, 24*time.Hour,
+				WithPricing(1_000_000, 2_000_000))
+			require.NoError(t, err)
+			return b
+		}
+		a, b := replica(), replica()
+
+		_, err := a.Completion(context.Background(), params)
+		require.NoError(t, err)
+		_, err = b.Completion(context.Background(), params)
+		require.NoError(t, err)
+
+		_, err = a.Completion(context.Background(), params)
+		require.ErrorIs(t, err, errors.ErrQuotaExceeded)
+		require.EqualError(t, err, `[mock] quota_exceeded: budget "team" has used cost 40 of 30, resets in 6h0m0s`)
+
+		spend, err := b.Spend(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Spend{Cost: 40, Requests: 2, ResetIn: 6 * time.Hour, Tokens: 30}, spend)
+		require.Equal(t, []string{"{team}:budget"}, redis.calls[0].keys)
+	})
+
+	t.Run("starts over in the next window", func(t *testing.T) {
+		t.Parallel()
+
+		redis := newFakeRedis()
+		redis.now = midnight.Add(time.Hour)
+		b, err := NewBudget(testutil.NewMockProvider(), redis, "k", Limits{MaxRequests: 1}, time.Hour)
+		require.NoError(t, err)
+
+		_, err = b.Completion(context.Background(), params)
+		require.NoError(t, err)
+		_, err = b.Completion(context.Background(), params)
+		require.ErrorIs(t, err, errors.ErrQuotaExceeded)
+
+		redis.now = midnight.Add(2 * time.Hour)
+		_, err = b.Completion(context.Background(), params)
+		require.NoError(t, err)
+	})
+
+	t.Run("does not count failed requests", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, stderrors.New("unavailable")
+		}
+		b, err := NewBudget(mock, newFakeRedis(), "k", Limits{MaxRequests: 1}, time.Hour)
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err = b.Completion(context.Background(), params)
+			require.EqualError(t, err, "unavailable")
+		}
+	})
+
+	t.Run("counts streams", func(t *testing.T) {
+		t.Parallel()
+
+		mock := streamingMock()
+		b, err := NewBudget(mock, newFakeRedis(), "k", Limits{MaxTokens: 100}, time.Hour)
+		require.NoError(t, err)
+
+		chunks, errs := b.CompletionStream(context.Background(), params)
+		for range chunks {
+		}
+		require.NoError(t, <-errs)
+		require.True(t, mock.CompletionStreamCalls[0].StreamOptions.IncludeUsage)
+
+		spend, err := b.Spend(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 15, spend.Tokens)
+		require.Equal(t, 1, spend.Requests)
+	})
+}
@@ -0,0 +1,337 @@
+package redislimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Parts of a rate limiter's state.
+const (
+	partRequests = "requests"
+	partTokens   = "tokens"
+)
+
+// unboundedWait is the maximum wait that lets requests wait for as long as
+// their context allows.
+const unboundedWait = -1
+
+// bucketFunctions are the Lua functions for token buckets, stored as hashes
+// of their level and the time it was measured at. A bucket holds up to
+// capacity and refills at capacity per window.
+const bucketFunctions = serverNow + `
+local function level(key, capacity, window)
+	local state = redis.call('HMGET', key, 'level', 'at')
+	local l = tonumber(state[1])
+	if not l then
+		return capacity
+	end
+	return math.min(capacity, l + math.max(0, now - tonumber(state[2])) * capacity / window)
+end
+
+local function store(key, l, capacity, window)
+	redis.call('HSET', key, 'level', tostring(l), 'at', now)
+	redis.call('PEXPIRE', key, math.ceil((capacity - l) * window / capacity) + 1)
+end
+`
+
+// acquireScript takes a request from the request bucket, KEYS[1], if it has
+// one and the token bucket, KEYS[2], is not in debt. ARGV holds the capacity
+// and window in milliseconds of each bucket; a capacity of 0 disables it. It
+// returns 0 if the request may start, or else the milliseconds to wait.
+const acquireScript = bucketFunctions + `
+local rc, rw, tc, tw = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local wait, requests = 0, 0
+if rc > 0 then
+	requests = level(KEYS[1], rc, rw)
+	if requests < 1 then
+		wait = math.ceil((1 - requests) * rw / rc)
+	end
+end
+if tc > 0 then
+	local tokens = level(KEYS[2], tc, tw)
+	if tokens <= 0 then
+		wait = math.max(wait, math.floor(-tokens * tw / tc) + 1)
+	end
+end
+if wait > 0 then
+	return wait
+end
+if rc > 0 then
+	store(KEYS[1], requests - 1, rc, rw)
+end
+return 0
+`
+
+// debitScript takes ARGV[3] tokens from the token bucket, KEYS[1], with the
+// capacity and window in milliseconds in ARGV[1] and ARGV[2]. The bucket may
+// go into debt.
+const debitScript = bucketFunctions + `
+local capacity, window = tonumber(ARGV[1]), tonumber(ARGV[2])
+store(KEYS[1], level(KEYS[1], capacity, window) - tonumber(ARGV[3]), capacity, window)
+return 0
+`
+
+// Ensure RateLimiter implements the required interfaces.
+var _ providers.Provider = (*RateLimiter)(nil)
+
+// RateLimiter wraps a provider and limits the rate of its requests and tokens
+// across every replica that shares the Redis key. Limits are token buckets:
+// a limit of n per window allows bursts of n and refills evenly over the
+// window.
+//
+// The token count of a request is only known once it completes, so tokens
+// are taken afterwards, and requests are held back while the bucket is in
+// debt. The request that uses up the tokens completes.
+type RateLimiter struct {
+	client   Client
+	key      string
+	maxWait  time.Duration
+	provider providers.Provider
+	requests rate
+	tokens   rate
+}
+
+// RateLimiterOption configures a RateLimiter.
+type RateLimiterOption func(*RateLimiter) error
+
+// rate is a limit of n per window.
+type rate struct {
+	n      int
+	window time.Duration
+}
+
+// NewRateLimiter wraps provider so that its requests share the rate limits
+// stored under key with every other RateLimiter using the key. At least one
+// of WithRequests and WithTokens is required. By default, requests over the
+// limit wait for as long as their context allows; use WithMaxWait to bound
+// the wait.
+func NewRateLimiter(
+	provider providers.Provider,
+	client Client,
+	key string,
+	opts ...RateLimiterOption,
+) (*RateLimiter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+
+	r := &RateLimiter{
+		client:   client,
+		key:      key,
+		maxWait:  unboundedWait,
+		provider: provider,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.requests.n == 0 && r.tokens.n == 0 {
+		return nil, fmt.Errorf("at least one of WithRequests and WithTokens is required")
+	}
+
+	return r, nil
+}
+
+// WithMaxWait sets how long a request may wait for the rate limits before it
+// fails with errors.ErrRateLimit. A request that would have to wait longer
+// fails at once, and 0 fails every request over the limit.
+func WithMaxWait(wait time.Duration) RateLimiterOption {
+	return func(r *RateLimiter) error {
+		if wait < 0 {
+			return fmt.Errorf("max wait must not be negative, got %v", wait)
+		}
+		r.maxWait = wait
+		return nil
+	}
+}
+
+// WithRequests limits the requests to n per window.
+func WithRequests(n int, per time.Duration) RateLimiterOption {
+	return func(r *RateLimiter) error {
+		limit, err := newRate("request", n, per)
+		if err != nil {
+			return err
+		}
+		r.requests = limit
+		return nil
+	}
+}
+
+// WithTokens limits the total tokens of the requests to n per window. Streams
+// are sent with usage reporting on so that their tokens are counted.
+func WithTokens(n int, per time.Duration) RateLimiterOption {
+	return func(r *RateLimiter) error {
+		limit, err := newRate("token", n, per)
+		if err != nil {
+			return err
+		}
+		r.tokens = limit
+		return nil
+	}
+}
+
+// Completion performs a chat completion request once the rate limits allow
+// it, and takes its tokens from the token limit.
+func (r *RateLimiter) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := r.provider.Completion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.debit(ctx, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request once the rate
+// limits allow it, and takes its tokens from the token limit when it ends.
+func (r *RateLimiter) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		if err := r.acquire(ctx); err != nil {
+			outErrs <- err
+			return
+		}
+
+		if r.tokens.n > 0 {
+			params.StreamOptions = &providers.StreamOptions{IncludeUsage: true}
+		}
+		usage, err := relay(ctx, r.provider, params, out)
+		if debitErr := r.debit(ctx, usage); err == nil {
+			err = debitErr
+		}
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (r *RateLimiter) Name() string {
+	return r.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (r *RateLimiter) Unwrap() providers.Provider {
+	return r.provider
+}
+
+// acquire waits until the rate limits allow a request and takes it. It fails
+// when the wait would exceed the maximum or ctx is done.
+func (r *RateLimiter) acquire(ctx context.Context) error {
+	keys := []string{hashKey(r.key, partRequests), hashKey(r.key, partTokens)}
+	start := time.Now()
+
+	for {
+		reply, err := eval(ctx, r.client, acquireScript, keys,
+			r.requests.n, milliseconds(r.requests.window), r.tokens.n, milliseconds(r.tokens.window))
+		if err != nil {
+			return err
+		}
+		ms, err := toInt(reply)
+		if err != nil {
+			return err
+		}
+		if ms == 0 {
+			return nil
+		}
+
+		wait := time.Duration(ms) * time.Millisecond
+		if r.maxWait != unboundedWait && time.Since(start)+wait > r.maxWait {
+			rateErr := errors.NewRateLimitError(r.provider.Name(), fmt.Errorf(
+				"rate limit %q allows another request in %v", r.key, wait,
+			))
+			rateErr.RetryAfter = int(math.Ceil(wait.Seconds()))
+			return rateErr
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// debit takes the tokens of usage from the token limit. It is counted even if
+// ctx was canceled once the tokens were used.
+func (r *RateLimiter) debit(ctx context.Context, usage *providers.Usage) error {
+	if r.tokens.n == 0 || usage == nil || usage.TotalTokens == 0 {
+		return nil
+	}
+
+	// The reply is always 0.
+	_, err := eval(context.WithoutCancel(ctx), r.client, debitScript, []string{hashKey(r.key, partTokens)},
+		r.tokens.n, milliseconds(r.tokens.window), usage.TotalTokens)
+	return err
+}
+
+// newRate returns a limit of n of what per window.
+func newRate(what string, n int, window time.Duration) (rate, error) {
+	if n <= 0 {
+		return rate{}, fmt.Errorf("%s limit must be positive, got %d", what, n)
+	}
+	if window < time.Millisecond {
+		return rate{}, fmt.Errorf("%s limit window must be at least 1ms, got %v", what, window)
+	}
+	return rate{n: n, window: window}, nil
+}
+
+// relay streams the completion from provider to out, and returns the usage
+// reported by the stream.
+func relay(
+	ctx context.Context,
+	provider providers.Provider,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+) (*providers.Usage, error) {
+	var usage *providers.Usage
+	chunks, errs := provider.CompletionStream(ctx, params)
+	for chunk := range chunks {
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return usage, ctx.Err()
+		}
+	}
+
+	return usage, <-errs
+}
@@ -0,0 +1,182 @@
+package redislimit
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  Client
+		key     string
+		opts    []RateLimiterOption
+		wantErr string
+	}{
+		{name: "client", key: "k", opts: []RateLimiterOption{WithRequests(1, time.Second)}, wantErr: "redis client is required"},
+		{name: "key", client: newFakeRedis(), opts: []RateLimiterOption{WithRequests(1, time.Second)}, wantErr: "key is required"},
+		{
+			name:    "limits",
+			client:  newFakeRedis(),
+			key:     "k",
+			wantErr: "at least one of WithRequests and WithTokens is required",
+		},
+		{
+			name:    "request limit",
+			client:  newFakeRedis(),
+			key:     "k",
+			opts:    []RateLimiterOption{WithRequests(0, time.Second)},
+			wantErr: "request limit must be positive, got 0",
+		},
+		{
+			name:    "token window",
+			client:  newFakeRedis(),
+			key:     "k",
+			opts:    []RateLimiterOption{WithTokens(100, time.Microsecond)},
+			wantErr: "token limit window must be at least 1ms, got 1µs",
+		},
+		{
+			name:    "max wait",
+			client:  newFakeRedis(),
+			key:     "k",
+			opts:    []RateLimiterOption{WithRequests(1, time.Second), WithMaxWait(-time.Second)},
+			wantErr: "max wait must not be negative, got -1s",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRateLimiter(testutil.NewMockProvider(), tc.client, tc.key, tc.opts...)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "m",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hi"}},
+	}
+
+	t.Run("shares the request limit between replicas", func(t *testing.T) {
+		t.Parallel()
+
+		redis := newFakeRedis()
+		replica := func(key string) *RateLimiter {
+			r, err := NewRateLimiter(testutil.NewMockProvider(), redis, key,
+				WithRequests(2, time.Hour), WithMaxWait(0))
+			require.NoError(t, err)
+			return r
+		}
+		a, b := replica("api-key"), replica("api-key")
+
+		_, err := a.Completion(context.Background(), params)
+		require.NoError(t, err)
+		_, err = b.Completion(context.Background(), params)
+		require.NoError(t, err)
+
+		_, err = a.Completion(context.Background(), params)
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		var rateErr *errors.RateLimitError
+		require.ErrorAs(t, err, &rateErr)
+		require.Equal(t, 1800, rateErr.RetryAfter)
+
+		_, err = replica("other-key").Completion(context.Background(), params)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"{api-key}:requests", "{api-key}:tokens"}, redis.calls[0].keys)
+		require.Equal(t, []any{2, int64(3_600_000), 0, int64(0)}, redis.calls[0].args)
+	})
+
+	t.Run("waits for the request limit", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := NewRateLimiter(testutil.NewMockProvider(), newFakeRedis(), "k", WithRequests(1, 50*time.Millisecond))
+		require.NoError(t, err)
+
+		start := time.Now()
+		for range 2 {
+			_, err := r.Completion(context.Background(), params)
+			require.NoError(t, err)
+		}
+		require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := NewRateLimiter(testutil.NewMockProvider(), newFakeRedis(), "k", WithRequests(1, time.Hour))
+		require.NoError(t, err)
+		_, err = r.Completion(context.Background(), params)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = r.Completion(ctx, params)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("holds requests while the token limit is in debt", func(t *testing.T) {
+		t.Parallel()
+
+		redis := newFakeRedis()
+		r, err := NewRateLimiter(testutil.NewMockProvider(), redis, "k", WithTokens(10, time.Hour), WithMaxWait(0))
+		require.NoError(t, err)
+
+		_, err = r.Completion(context.Background(), params)
+		require.NoError(t, err)
+		_, err = r.Completion(context.Background(), params)
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		require.InDelta(t, -5, redis.hashes["{k}:tokens"]["level"], 0.01)
+	})
+
+	t.Run("counts streamed tokens", func(t *testing.T) {
+		t.Parallel()
+
+		mock := streamingMock()
+		redis := newFakeRedis()
+		r, err := NewRateLimiter(mock, redis, "k", WithTokens(100, time.Hour))
+		require.NoError(t, err)
+
+		chunks, errs := r.CompletionStream(context.Background(), params)
+		var n int
+		for range chunks {
+			n++
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, 2, n)
+		require.True(t, mock.CompletionStreamCalls[0].StreamOptions.IncludeUsage)
+		require.InDelta(t, 85, redis.hashes["{k}:tokens"]["level"], 0.01)
+	})
+
+	t.Run("fails requests when redis fails", func(t *testing.T) {
+		t.Parallel()
+
+		redis := newFakeRedis()
+		redis.err = stderrors.New("connection refused")
+		mock := testutil.NewMockProvider()
+		r, err := NewRateLimiter(mock, redis, "k", WithRequests(1, time.Second))
+		require.NoError(t, err)
+
+		_, err = r.Completion(context.Background(), params)
+		require.EqualError(t, err, "redis: connection refused")
+		require.Empty(t, mock.CompletionCalls)
+
+		_, errs := r.CompletionStream(context.Background(), params)
+		require.EqualError(t, <-errs, "redis: connection refused")
+	})
+}
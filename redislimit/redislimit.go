@@ -0,0 +1,89 @@
+// Package redislimit enforces rate limits and budgets across every replica
+// of a service by keeping their state in Redis, where the limits of the limit
+// package and of chat sessions only hold within one process.
+//
+// RateLimiter shares request and token rate limits, such as the quota of an
+// API key, between replicas. Budget caps what the replicas spend together in
+// a time window, such as a day.
+//
+// State is updated by Lua scripts, so each check is atomic and uses the Redis
+// server's clock, and the package works with any Redis client that can run
+// them. With github.com/redis/go-redis, for example:
+//
+//	type goRedis struct{ *redis.Client }
+//
+//	func (c goRedis) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+package redislimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// serverNow is the Lua snippet that sets now to the Redis server's time in
+// milliseconds. Writes after TIME need effects replication before Redis 7.
+const serverNow = `
+redis.replicate_commands()
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+// Client runs Lua scripts on a Redis server.
+type Client interface {
+	// Eval runs script with the given keys and arguments, as the EVAL command
+	// does, and returns its reply: int64 for integers, string for bulk
+	// strings and []any for arrays.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// eval runs script on client and wraps its errors.
+func eval(ctx context.Context, client Client, script string, keys []string, args ...any) (any, error) {
+	reply, err := client.Eval(ctx, script, keys, args...)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return reply, nil
+}
+
+// hashKey returns the key for part of the state under key. The key is wrapped
+// in a hash tag so that all parts of it are stored in the same slot of a Redis
+// cluster, as scripts require.
+func hashKey(key string, part string) string {
+	return "{" + key + "}:" + part
+}
+
+// milliseconds returns d in whole milliseconds, rounded up.
+func milliseconds(d time.Duration) int64 {
+	return int64(math.Ceil(float64(d) / float64(time.Millisecond)))
+}
+
+// toFloat converts a script reply to a float. Lua numbers are truncated to
+// integers in replies, so fractions are returned as strings.
+func toFloat(reply any) (float64, error) {
+	switch v := reply.(type) {
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("redis: unexpected reply %q", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("redis: unexpected reply %v of type %T", reply, reply)
+	}
+}
+
+// toInt converts an integer script reply to an int.
+func toInt(reply any) (int, error) {
+	v, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %v of type %T", reply, reply)
+	}
+	return int(v), nil
+}
@@ -0,0 +1,166 @@
+package redislimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// fakeRedis is a Client that runs the package's scripts in Go, on hashes kept
+// in memory, as Redis would run them in Lua.
+type fakeRedis struct {
+	calls  []fakeCall
+	err    error
+	hashes map[string]map[string]float64
+	mu     sync.Mutex
+	now    time.Time
+}
+
+// fakeCall is a script run on a fakeRedis.
+type fakeCall struct {
+	args   []any
+	keys   []string
+	script string
+}
+
+// newFakeRedis returns an empty fakeRedis that uses the wall clock unless its
+// now is set.
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{hashes: map[string]map[string]float64{}}
+}
+
+// Eval runs script as Redis would.
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, fakeCall{args: args, keys: keys, script: script})
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	now := float64(time.Now().UnixMilli())
+	if !f.now.IsZero() {
+		now = float64(f.now.UnixMilli())
+	}
+
+	switch script {
+	case acquireScript:
+		return f.acquire(now, keys, fakeNumbers(args)), nil
+	case debitScript:
+		n := fakeNumbers(args)
+		f.store(keys[0], now, f.level(keys[0], now, n[0], n[1])-n[2])
+		return int64(0), nil
+	case spendScript:
+		return f.spend(now, keys[0], fakeNumbers(args)), nil
+	default:
+		return nil, fmt.Errorf("unknown script")
+	}
+}
+
+// acquire runs acquireScript.
+func (f *fakeRedis) acquire(now float64, keys []string, n []float64) any {
+	rc, rw, tc, tw := n[0], n[1], n[2], n[3]
+
+	var wait, requests float64
+	if rc > 0 {
+		requests = f.level(keys[0], now, rc, rw)
+		if requests < 1 {
+			wait = math.Ceil((1 - requests) * rw / rc)
+		}
+	}
+	if tc > 0 {
+		if tokens := f.level(keys[1], now, tc, tw); tokens <= 0 {
+			wait = max(wait, math.Floor(-tokens*tw/tc)+1)
+		}
+	}
+	if wait > 0 {
+		return int64(wait)
+	}
+	if rc > 0 {
+		f.store(keys[0], now, requests-1)
+	}
+	return int64(0)
+}
+
+// level returns the level of the bucket at key.
+func (f *fakeRedis) level(key string, now float64, capacity float64, window float64) float64 {
+	state, ok := f.hashes[key]
+	if !ok {
+		return capacity
+	}
+	return min(capacity, state["level"]+max(0, now-state["at"])*capacity/window)
+}
+
+// spend runs spendScript.
+func (f *fakeRedis) spend(now float64, key string, n []float64) any {
+	window := n[0]
+	current := math.Floor(now / window)
+	ends := (current + 1) * window
+
+	state := f.hashes[key]
+	if state == nil || state["window"] != current {
+		state = map[string]float64{"window": current}
+	}
+	if n[1] > 0 || n[2] > 0 || n[3] > 0 {
+		state["cost"] += n[1]
+		state["tokens"] += n[2]
+		state["requests"] += n[3]
+		f.hashes[key] = state
+	}
+
+	return []any{
+		fmt.Sprint(state["cost"]),
+		int64(state["tokens"]),
+		int64(state["requests"]),
+		int64(ends - now),
+	}
+}
+
+// store sets the level of the bucket at key.
+func (f *fakeRedis) store(key string, now float64, level float64) {
+	f.hashes[key] = map[string]float64{"at": now, "level": level}
+}
+
+// fakeNumbers converts script arguments to numbers, as Lua's tonumber does.
+func fakeNumbers(args []any) []float64 {
+	n := make([]float64, 0, len(args))
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case int:
+			n = append(n, float64(v))
+		case int64:
+			n = append(n, float64(v))
+		case float64:
+			n = append(n, v)
+		default:
+			panic(fmt.Sprintf("unexpected argument %v of type %T", arg, arg))
+		}
+	}
+	return n
+}
+
+// streamingMock returns a mock whose streams report usage in their last
+// chunk.
+func streamingMock() *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(context.Context, providers.CompletionParams) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 2)
+		errs := make(chan error, 1)
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "Hello"}}},
+		}
+		chunks <- providers.ChatCompletionChunk{
+			Usage: &providers.Usage{CompletionTokens: 5, PromptTokens: 10, TotalTokens: 15},
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+	return mock
+}
@@ -0,0 +1,258 @@
+// Package reload rebuilds a provider from a configuration file while it runs,
+// so that API keys can be rotated and traffic shifted without a restart. The
+// file is read again on SIGHUP and when it changes on disk, and a Builder
+// turns its contents into a provider, such as a router with new weights.
+//
+// Requests that started before a reload finish on the provider they started
+// with; requests that start after it use the new one. A file that fails to
+// build keeps the previous provider in service.
+package reload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// defaultPollInterval is how often the file is checked for changes.
+const defaultPollInterval = 2 * time.Second
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Builder builds a provider from the contents of the configuration file. The
+// format of the file is up to the Builder.
+type Builder func(ctx context.Context, data []byte) (providers.Provider, error)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider serves requests with the provider last built from the
+// configuration file.
+type Provider struct {
+	build        Builder
+	current      atomic.Pointer[active]
+	mu           sync.Mutex
+	onReload     func(err error)
+	path         string
+	pollInterval time.Duration
+	seen         fileState
+	signals      []os.Signal
+}
+
+// active holds the provider in service.
+type active struct {
+	provider providers.Provider
+}
+
+// fileState is the configuration file as last read, whether or not it built.
+type fileState struct {
+	data    []byte
+	modTime time.Time
+	size    int64
+}
+
+// New reads the configuration file at path, builds a provider from it, and
+// watches the file until ctx is done. It fails if the first build fails.
+func New(ctx context.Context, path string, build Builder, opts ...Option) (*Provider, error) {
+	if build == nil {
+		return nil, fmt.Errorf("builder is required")
+	}
+
+	p := &Provider{
+		build:        build,
+		path:         path,
+		pollInterval: defaultPollInterval,
+		signals:      []os.Signal{syscall.SIGHUP},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	// Signals are caught from now on, so that one sent early does not stop the process.
+	var sigs chan os.Signal
+	if len(p.signals) > 0 {
+		sigs = make(chan os.Signal, 1)
+		signal.Notify(sigs, p.signals...)
+	}
+
+	go p.watch(ctx, sigs)
+	return p, nil
+}
+
+// WithOnReload sets a function called after every reload triggered by a
+// signal or a file change, with the error if the reload failed. Use it to
+// log reloads; the previous provider stays in service after a failure.
+func WithOnReload(fn func(err error)) Option {
+	return func(p *Provider) error {
+		p.onReload = fn
+		return nil
+	}
+}
+
+// WithPollInterval sets how often the file is checked for changes. The
+// default is 2 seconds; 0 turns checking off, leaving signals and Reload.
+func WithPollInterval(d time.Duration) Option {
+	return func(p *Provider) error {
+		if d < 0 {
+			return fmt.Errorf("poll interval must not be negative, got %v", d)
+		}
+
+		p.pollInterval = d
+		return nil
+	}
+}
+
+// WithSignals sets the signals that trigger a reload. The default is SIGHUP;
+// with no signals, signals trigger no reloads.
+func WithSignals(signals ...os.Signal) Option {
+	return func(p *Provider) error {
+		p.signals = signals
+		return nil
+	}
+}
+
+// Completion performs a chat completion request on the current provider.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	return p.Current().Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request on the
+// current provider. The stream runs to its end on that provider, even if the
+// configuration is reloaded meanwhile.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	return p.Current().CompletionStream(ctx, params)
+}
+
+// Current returns the provider built from the configuration file last loaded.
+func (p *Provider) Current() providers.Provider {
+	return p.current.Load().provider
+}
+
+// Name returns the name of the current provider.
+func (p *Provider) Name() string {
+	return p.Current().Name()
+}
+
+// Reload reads the configuration file and builds a new provider from it. On
+// failure, the current provider stays in service.
+func (p *Provider) Reload(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	// Remembered even if the build fails, so a bad file is not retried until it changes.
+	p.seen = fileState{data: data, modTime: info.ModTime(), size: info.Size()}
+
+	provider, err := p.build(ctx, data)
+	if err != nil {
+		return fmt.Errorf("building provider from %s: %w", p.path, err)
+	}
+	if provider == nil {
+		return fmt.Errorf("building provider from %s: builder returned no provider", p.path)
+	}
+
+	p.current.Store(&active{provider: provider})
+	return nil
+}
+
+// changed reports whether the file differs from the one last read. Files
+// whose modification time and size are unchanged are not read.
+func (p *Provider) changed() bool {
+	p.mu.Lock()
+	seen := p.seen
+	p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		// A file being replaced may be missing for a moment.
+		return false
+	}
+	if info.ModTime().Equal(seen.modTime) && info.Size() == seen.size {
+		return false
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return false
+	}
+	if !bytes.Equal(data, seen.data) {
+		return true
+	}
+
+	// Touched but not changed; remember it so the file is not read again.
+	p.mu.Lock()
+	if bytes.Equal(p.seen.data, data) {
+		p.seen.modTime = info.ModTime()
+		p.seen.size = info.Size()
+	}
+	p.mu.Unlock()
+	return false
+}
+
+// reload reloads the configuration and reports the outcome to the hook.
+func (p *Provider) reload(ctx context.Context) {
+	err := p.Reload(ctx)
+	if p.onReload != nil {
+		p.onReload(err)
+	}
+}
+
+// watch reloads the configuration on signals received on sigs and on file
+// changes until ctx is done.
+func (p *Provider) watch(ctx context.Context, sigs chan os.Signal) {
+	if sigs != nil {
+		defer signal.Stop(sigs)
+	}
+
+	var tick <-chan time.Time
+	if p.pollInterval > 0 {
+		ticker := time.NewTicker(p.pollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			p.reload(ctx)
+		case <-tick:
+			if p.changed() {
+				p.reload(ctx)
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testConfig is the configuration file format used by the tests.
+type testConfig struct {
+	Reply string `json:"reply"`
+}
+
+// testBuild builds a mock provider that replies with the configured reply.
+func testBuild(_ context.Context, data []byte) (providers.Provider, error) {
+	var cfg testConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Reply == "" {
+		return nil, fmt.Errorf("reply is required")
+	}
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return testutil.MockChatCompletion(cfg.Reply), nil
+	}
+	return mock, nil
+}
+
+// writeTestConfig writes a config file with reply to path.
+func writeTestConfig(t *testing.T, path string, reply string) {
+	t.Helper()
+
+	data, err := json.Marshal(testConfig{Reply: reply})
+	require.NoError(t, err)
+	writeTestFile(t, path, data)
+}
+
+// writeTestFile replaces the file at path with data atomically, as an
+// operator would, so that a poll never sees it half-written.
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, data, 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// testReply returns the reply of p to a completion.
+func testReply(t *testing.T, p providers.Provider) string {
+	t.Helper()
+
+	resp, err := p.Completion(context.Background(), providers.CompletionParams{Model: "m"})
+	require.NoError(t, err)
+	return resp.Choices[0].Message.ContentString()
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	_, err := New(context.Background(), path, testBuild)
+	require.ErrorContains(t, err, "reading config")
+
+	writeTestConfig(t, path, "")
+	_, err = New(context.Background(), path, testBuild)
+	require.ErrorContains(t, err, "reply is required")
+
+	_, err = New(context.Background(), path, nil)
+	require.EqualError(t, err, "builder is required")
+
+	_, err = New(context.Background(), path, testBuild, WithPollInterval(-time.Second))
+	require.EqualError(t, err, "poll interval must not be negative, got -1s")
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("swaps the provider", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeTestConfig(t, path, "old")
+
+		p, err := New(t.Context(), path, testBuild, WithPollInterval(0), WithSignals())
+		require.NoError(t, err)
+		require.Equal(t, "old", testReply(t, p))
+		require.Equal(t, "mock", p.Name())
+
+		writeTestConfig(t, path, "new")
+		require.NoError(t, p.Reload(context.Background()))
+		require.Equal(t, "new", testReply(t, p))
+	})
+
+	t.Run("keeps the provider when the build fails", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeTestConfig(t, path, "old")
+
+		p, err := New(t.Context(), path, testBuild, WithPollInterval(0), WithSignals())
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		require.ErrorContains(t, p.Reload(context.Background()), "building provider from")
+		require.Equal(t, "old", testReply(t, p))
+	})
+
+	t.Run("in-flight streams finish on their provider", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeTestConfig(t, path, "old")
+
+		release := make(chan struct{})
+		build := func(ctx context.Context, data []byte) (providers.Provider, error) {
+			provider, err := testBuild(ctx, data)
+			if err != nil {
+				return nil, err
+			}
+			mock := provider.(*testutil.MockProvider)
+			reply := testReply(t, mock)
+			mock.CompletionStreamFunc = func(
+				context.Context,
+				providers.CompletionParams,
+			) (<-chan providers.ChatCompletionChunk, <-chan error) {
+				chunks := make(chan providers.ChatCompletionChunk)
+				errs := make(chan error)
+				go func() {
+					defer close(chunks)
+					defer close(errs)
+					<-release
+					chunks <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: reply}}}}
+				}()
+				return chunks, errs
+			}
+			return mock, nil
+		}
+
+		p, err := New(t.Context(), path, build, WithPollInterval(0), WithSignals())
+		require.NoError(t, err)
+
+		chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{Model: "m"})
+		writeTestConfig(t, path, "new")
+		require.NoError(t, p.Reload(context.Background()))
+		close(release)
+
+		chunk := <-chunks
+		require.Equal(t, "old", chunk.Choices[0].Delta.Content)
+		require.NoError(t, <-errs)
+		require.Equal(t, "new", testReply(t, p))
+	})
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reloads when the file changes", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeTestConfig(t, path, "old")
+
+		var mu sync.Mutex
+		var reloads []error
+		p, err := New(t.Context(), path, testBuild,
+			WithPollInterval(10*time.Millisecond),
+			WithSignals(),
+			WithOnReload(func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				reloads = append(reloads, err)
+			}),
+		)
+		require.NoError(t, err)
+
+		writeTestFile(t, path, []byte("not json"))
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(reloads) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, "old", testReply(t, p))
+
+		// A file that failed is not retried until it changes.
+		time.Sleep(50 * time.Millisecond)
+		writeTestConfig(t, path, "new")
+		require.Eventually(t, func() bool {
+			return testReply(t, p) == "new"
+		}, 5*time.Second, 10*time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, reloads, 2)
+		require.Error(t, reloads[0])
+		require.NoError(t, reloads[1])
+	})
+
+	t.Run("reloads on a signal", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeTestConfig(t, path, "old")
+
+		reloaded := make(chan error, 1)
+		p, err := New(t.Context(), path, testBuild,
+			WithPollInterval(0),
+			WithSignals(syscall.SIGHUP),
+			WithOnReload(func(err error) { reloaded <- err }),
+		)
+		require.NoError(t, err)
+
+		writeTestConfig(t, path, "new")
+		self, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, self.Signal(syscall.SIGHUP))
+		require.NoError(t, <-reloaded)
+		require.Equal(t, "new", testReply(t, p))
+	})
+}
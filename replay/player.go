@@ -0,0 +1,237 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// playerName is the name of a Player with no live provider and an empty trace.
+const playerName = "replay"
+
+// Ensure Player implements the required interfaces.
+var _ providers.Provider = (*Player)(nil)
+
+// Player is a provider that answers requests from a trace, to run an
+// application against a recorded session and take over from any point.
+//
+// Requests are matched with the trace's steps in order. Before step from,
+// each request that matches its step's model and messages gets the recorded
+// response, exactly as it was recorded, errors included. From step from on,
+// and from the first request that does not match, every request goes to the
+// live provider. Wrap the Player in a Recorder to trace the new branch.
+type Player struct {
+	from     int
+	live     providers.Provider
+	mu       sync.Mutex
+	next     int
+	replayed int
+	trace    *Trace
+	wentLive bool
+}
+
+// NewPlayer returns a Player that replays trace up to step from and sends
+// later requests to live. A from equal to the number of steps replays the
+// whole trace. live may be nil, in which case requests that cannot be
+// answered from the trace fail.
+func NewPlayer(trace *Trace, live providers.Provider, from int) (*Player, error) {
+	if trace == nil {
+		return nil, fmt.Errorf("trace is required")
+	}
+	if from < 0 || from > len(trace.Steps) {
+		return nil, fmt.Errorf("step %d out of range: trace has %d steps", from, len(trace.Steps))
+	}
+
+	return &Player{from: from, live: live, trace: trace}, nil
+}
+
+// Completion answers a chat completion request from the trace, or from the
+// live provider once playback has gone live.
+func (p *Player) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	step, err := p.take(params)
+	if err != nil {
+		return nil, err
+	}
+	if step == nil {
+		return p.live.Completion(ctx, params)
+	}
+
+	if err := step.err(); err != nil {
+		return nil, err
+	}
+	return step.Completion(), nil
+}
+
+// CompletionStream answers a streaming chat completion request from the
+// trace, or from the live provider once playback has gone live. Steps
+// recorded as completions are replayed as a single chunk.
+func (p *Player) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	step, err := p.take(params)
+	if err == nil && step == nil {
+		return p.live.CompletionStream(ctx, params)
+	}
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for _, chunk := range step.replayChunks() {
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if err := step.err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the live provider's name, or the name of the provider the
+// trace was recorded with.
+func (p *Player) Name() string {
+	if p.live != nil {
+		return p.live.Name()
+	}
+	if len(p.trace.Steps) > 0 {
+		return p.trace.Steps[0].Provider
+	}
+	return playerName
+}
+
+// Replayed returns how many requests have been answered from the trace.
+func (p *Player) Replayed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.replayed
+}
+
+// take matches params with the next step. It returns the step to replay, or
+// nil if the request goes to the live provider.
+func (p *Player) take(params providers.CompletionParams) (*Step, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := p.next
+	p.next++
+
+	if !p.wentLive && i < p.from {
+		step := &p.trace.Steps[i]
+		if sameRequest(step.Params, params) {
+			p.replayed++
+			return step, nil
+		}
+		p.wentLive = true
+		if p.live == nil {
+			return nil, fmt.Errorf("request %d does not match step %d of the trace", i, step.Index)
+		}
+	}
+	p.wentLive = true
+
+	if p.live == nil {
+		return nil, fmt.Errorf("request %d is past the replayed steps and no live provider is set", i)
+	}
+	return nil, nil
+}
+
+// err returns the step's recorded error, if any. The original error's type
+// is not recorded, so it is returned as a provider error.
+func (s *Step) err() error {
+	if s.Error == "" {
+		return nil
+	}
+
+	return errors.NewProviderError(s.Provider, stderrors.New(s.Error))
+}
+
+// replayChunks returns the step's response as stream chunks: the recorded
+// chunks, or a single chunk holding a recorded completion.
+func (s *Step) replayChunks() []providers.ChatCompletionChunk {
+	if s.Stream || s.Response == nil {
+		return s.Chunks
+	}
+
+	resp := s.Response
+	chunk := providers.ChatCompletionChunk{
+		Created:           resp.Created,
+		ID:                resp.ID,
+		Model:             resp.Model,
+		SystemFingerprint: resp.SystemFingerprint,
+		Usage:             resp.Usage,
+	}
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		chunk.Choices = append(chunk.Choices, providers.ChunkChoice{
+			ContentFilterResults: choice.ContentFilterResults,
+			Delta: providers.ChunkDelta{
+				Audio:     msg.Audio,
+				Content:   msg.ContentString(),
+				Reasoning: msg.Reasoning,
+				Role:      msg.Role,
+				ToolCalls: msg.ToolCalls,
+			},
+			FinishReason: choice.FinishReason,
+			Index:        choice.Index,
+			StopSequence: choice.StopSequence,
+		})
+	}
+	return []providers.ChatCompletionChunk{chunk}
+}
+
+// requestKey returns the model and messages of params as canonical JSON.
+// Messages are decoded generically and encoded again so content parts
+// compare equal whether they were built in code or read from a trace.
+func requestKey(params providers.CompletionParams) ([]byte, error) {
+	b, err := json.Marshal(struct {
+		Messages []providers.Message `json:"messages"`
+		Model    string              `json:"model"`
+	}{Messages: params.Messages, Model: params.Model})
+	if err != nil {
+		return nil, err
+	}
+
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// sameRequest reports whether a and b have the same model and messages.
+// Requests that cannot be encoded never match.
+func sameRequest(a, b providers.CompletionParams) bool {
+	keyA, err := requestKey(a)
+	if err != nil {
+		return false
+	}
+	keyB, err := requestKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(keyA, keyB)
+}
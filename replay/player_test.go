@@ -0,0 +1,193 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestNewPlayer(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewPlayer(nil, nil, 0)
+	require.Error(t, err)
+
+	trace := recordAgent(t)
+	_, err = NewPlayer(trace, nil, 3)
+	require.ErrorContains(t, err, "out of range")
+
+	_, err = NewPlayer(trace, nil, -1)
+	require.ErrorContains(t, err, "out of range")
+}
+
+func TestPlayer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replays the whole trace", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+		player, err := NewPlayer(trace, nil, len(trace.Steps))
+		require.NoError(t, err)
+
+		require.Equal(t, "model-a: "+weatherResult, runAgent(t, player))
+		require.Equal(t, 2, player.Replayed())
+		require.Equal(t, "mock", player.Name())
+
+		_, err = player.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorContains(t, err, "no live provider")
+	})
+
+	t.Run("goes live from the chosen step", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+
+		var live int
+		mock := agentProvider()
+		complete := mock.CompletionFunc
+		mock.CompletionFunc = func(ctx context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			live++
+			params.Model = "live"
+			return complete(ctx, params)
+		}
+
+		player, err := NewPlayer(trace, mock, 1)
+		require.NoError(t, err)
+
+		require.Equal(t, "live: "+weatherResult, runAgent(t, player))
+		require.Equal(t, 1, player.Replayed())
+		require.Equal(t, 1, live)
+	})
+
+	t.Run("goes live when the application diverges", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+		player, err := NewPlayer(trace, testutil.NewMockProvider(), len(trace.Steps))
+		require.NoError(t, err)
+
+		resp, err := player.Completion(context.Background(), providers.CompletionParams{
+			Model:    "model-a",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "Something else"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+		require.Equal(t, 0, player.Replayed())
+	})
+
+	t.Run("fails on divergence without a live provider", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+		player, err := NewPlayer(trace, nil, len(trace.Steps))
+		require.NoError(t, err)
+
+		_, err = player.Completion(context.Background(), providers.CompletionParams{
+			Model:    "model-b",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "What's the weather in Paris?"}},
+		})
+		require.ErrorContains(t, err, "does not match step 0")
+	})
+
+	t.Run("replays recorded errors", func(t *testing.T) {
+		t.Parallel()
+
+		trace := &Trace{Steps: []Step{{
+			Error:    "rate limited",
+			Params:   providers.CompletionParams{Messages: testutil.SimpleMessages()},
+			Provider: "mock",
+		}}}
+		player, err := NewPlayer(trace, nil, 1)
+		require.NoError(t, err)
+
+		_, err = player.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.ErrorContains(t, err, "rate limited")
+	})
+}
+
+func TestPlayerCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replays recorded chunks", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		recorder, err := New(testutil.NewMockProvider(), &buf)
+		require.NoError(t, err)
+
+		params := providers.CompletionParams{Model: "model-a", Messages: testutil.SimpleMessages()}
+		_, err = drain(recorder.CompletionStream(context.Background(), params))
+		require.NoError(t, err)
+
+		trace, err := Read(&buf)
+		require.NoError(t, err)
+		player, err := NewPlayer(trace, nil, 1)
+		require.NoError(t, err)
+
+		chunks, errs := player.CompletionStream(context.Background(), params)
+		var count int
+		for range chunks {
+			count++
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("streams recorded completions as one chunk", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+		player, err := NewPlayer(trace, nil, 1)
+		require.NoError(t, err)
+
+		resp, err := drain(player.CompletionStream(context.Background(), trace.Steps[0].Params))
+		require.NoError(t, err)
+		require.Equal(t, trace.Steps[0].Response.Choices[0].Message.ToolCalls, resp.Choices[0].Message.ToolCalls)
+		require.Equal(t, providers.FinishReasonToolCalls, resp.Choices[0].FinishReason)
+	})
+
+	t.Run("streams live past the replayed steps", func(t *testing.T) {
+		t.Parallel()
+
+		player, err := NewPlayer(&Trace{}, testutil.NewMockProvider(), 0)
+		require.NoError(t, err)
+
+		resp, err := drain(player.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+	})
+}
+
+func TestSameRequest(t *testing.T) {
+	t.Parallel()
+
+	built := providers.CompletionParams{
+		Model: "model-a",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: []providers.ContentPart{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/a.png"}},
+		}}},
+	}
+	decoded := providers.CompletionParams{
+		Model: "model-a",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: []any{
+			map[string]any{"text": "What is this?", "type": "text"},
+			map[string]any{"image_url": map[string]any{"url": "https://example.com/a.png"}, "type": "image_url"},
+		}}},
+	}
+
+	require.True(t, sameRequest(built, decoded))
+
+	decoded.Model = "model-b"
+	require.False(t, sameRequest(built, decoded))
+}
@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Placeholders rendered for non-text content parts.
+const (
+	placeholderFile  = "[file]"
+	placeholderImage = "[image]"
+)
+
+// Render writes the trace to w as a readable transcript. Each step shows
+// the messages added since the previous step, so an agent's conversation
+// reads top to bottom once, followed by the response, its tool calls, its
+// finish reason and token usage, or the error. A step that sends the
+// previous step's request again, possibly with more messages, is marked as a
+// retry; one whose messages continue neither starts a new conversation and
+// shows all of its messages.
+func (t *Trace) Render(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	var previous []providers.Message
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		messages := step.Params.Messages
+
+		renderHeader(bw, step)
+
+		start := 0
+		if i > 0 {
+			prevRequest := t.Steps[i-1].Params.Messages
+			switch {
+			case isPrefix(previous, messages):
+				start = len(previous)
+			case isPrefix(prevRequest, messages):
+				start = len(prevRequest)
+				_, _ = fmt.Fprintf(bw, "(retry of step %d)\n", t.Steps[i-1].Index) // Errors surface in Flush.
+			default:
+				_, _ = fmt.Fprintln(bw, "(new conversation)") // Errors surface in Flush.
+			}
+		}
+		for _, msg := range messages[start:] {
+			renderMessage(bw, "", msg)
+		}
+		resp := step.Completion()
+		if resp != nil && len(resp.Choices) > 0 {
+			renderMessage(bw, "→ ", resp.Choices[0].Message)
+			renderOutcome(bw, resp)
+		}
+		if step.Error != "" {
+			_, _ = fmt.Fprintf(bw, "→ error: %s\n", step.Error) // Errors surface in Flush.
+		}
+		_, _ = fmt.Fprintln(bw) // Errors surface in Flush.
+
+		previous, _ = t.Messages(i) // i is in range.
+	}
+
+	return bw.Flush()
+}
+
+// isPrefix reports whether prefix is the start of messages.
+func isPrefix(prefix, messages []providers.Message) bool {
+	if len(prefix) > len(messages) {
+		return false
+	}
+	for i := range prefix {
+		if !sameMessage(prefix[i], messages[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// messageText returns the text of msg, with placeholders for non-text parts.
+func messageText(msg providers.Message) string {
+	parts := msg.ContentParts()
+	if parts == nil {
+		return msg.ContentString()
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.ImageURL != nil:
+			texts = append(texts, placeholderImage)
+		case part.FileID != "":
+			texts = append(texts, placeholderFile)
+		default:
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// renderHeader writes the line that opens a step.
+func renderHeader(w io.Writer, step *Step) {
+	mode := "completion"
+	if step.Stream {
+		mode = "stream"
+	}
+
+	_, _ = fmt.Fprintf(w, "=== step %d · %s %s · %s · %s\n", // Errors surface in Flush.
+		step.Index, step.Provider, step.Params.Model, mode, step.Latency.Round(time.Millisecond))
+}
+
+// renderMessage writes msg, its tool calls and the tool call it answers,
+// with each line after the first indented under the role.
+func renderMessage(w io.Writer, prefix string, msg providers.Message) {
+	label := prefix + msg.Role
+	if msg.ToolCallID != "" {
+		label += " [" + msg.ToolCallID + "]"
+	}
+
+	if text := messageText(msg); text != "" {
+		indent := "\n" + strings.Repeat(" ", len([]rune(label))+2)
+		_, _ = fmt.Fprintf(w, "%s: %s\n", label, strings.ReplaceAll(text, "\n", indent)) // Errors surface in Flush.
+	} else if len(msg.ToolCalls) == 0 {
+		_, _ = fmt.Fprintf(w, "%s: (empty)\n", label) // Errors surface in Flush.
+	}
+
+	for _, call := range msg.ToolCalls {
+		_, _ = fmt.Fprintf(w, "%s: call %s(%s) [%s]\n", // Errors surface in Flush.
+			label, call.Function.Name, call.Function.Arguments, call.ID)
+	}
+}
+
+// renderOutcome writes the finish reason and token usage of resp.
+func renderOutcome(w io.Writer, resp *providers.ChatCompletion) {
+	var details []string
+	if reason := resp.Choices[0].FinishReason; reason != "" {
+		details = append(details, "finish: "+reason)
+	}
+	if resp.Usage != nil {
+		details = append(details, fmt.Sprintf("tokens: %d in, %d out", resp.Usage.PromptTokens, resp.Usage.CompletionTokens))
+	}
+	if len(details) > 0 {
+		_, _ = fmt.Fprintf(w, "  (%s)\n", strings.Join(details, ", ")) // Errors surface in Flush.
+	}
+}
+
+// sameMessage reports whether a and b are the same message, comparing their
+// role, text, tool calls and the tool call they answer.
+func sameMessage(a, b providers.Message) bool {
+	if a.Role != b.Role || a.ToolCallID != b.ToolCallID || messageText(a) != messageText(b) {
+		return false
+	}
+	if len(a.ToolCalls) != len(b.ToolCalls) {
+		return false
+	}
+	for i := range a.ToolCalls {
+		if a.ToolCalls[i].ID != b.ToolCalls[i].ID || a.ToolCalls[i].Function != b.ToolCalls[i].Function {
+			return false
+		}
+	}
+	return true
+}
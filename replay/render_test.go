@@ -0,0 +1,80 @@
+package replay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+func TestTraceRender(t *testing.T) {
+	t.Parallel()
+
+	trace := recordAgent(t)
+	trace.Steps = append(trace.Steps, Step{
+		Error:    "rate limited",
+		Index:    2,
+		Params:   providers.CompletionParams{Model: "model-a", Messages: []providers.Message{{Role: providers.RoleUser, Content: "Hi"}}},
+		Provider: "mock",
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Render(&buf))
+	out := buf.String()
+
+	require.Contains(t, out, "=== step 0 · mock model-a · completion")
+	require.Contains(t, out, "user: What's the weather in Paris?\n")
+	require.Contains(t, out, `→ assistant: call get_weather({"location":"Paris"}) [call_1]`)
+	require.Contains(t, out, "(finish: tool_calls, tokens: 10 in, 20 out)")
+	require.Contains(t, out, "tool [call_1]: "+weatherResult+"\n")
+	require.Contains(t, out, "→ assistant: model-a: "+weatherResult+"\n")
+	require.Contains(t, out, "(new conversation)\nuser: Hi\n→ error: rate limited\n")
+
+	// Each message is shown once, in the step that added it.
+	require.Equal(t, 1, strings.Count(out, "What's the weather in Paris?"))
+	require.Equal(t, 1, strings.Count(out, "call get_weather"))
+}
+
+func TestTraceRenderRetry(t *testing.T) {
+	t.Parallel()
+
+	hi := providers.Message{Role: providers.RoleUser, Content: "Hi"}
+	anyone := providers.Message{Role: providers.RoleUser, Content: "Anyone?"}
+
+	// The second step resends the first request with a message added, without
+	// the first step's answer.
+	trace := &Trace{Steps: []Step{
+		{
+			Index:    0,
+			Params:   providers.CompletionParams{Model: "model-a", Messages: []providers.Message{hi}},
+			Provider: "mock",
+			Response: testutil.MockChatCompletion("Hello"),
+		},
+		{
+			Index:    1,
+			Params:   providers.CompletionParams{Model: "model-a", Messages: []providers.Message{hi, anyone}},
+			Provider: "mock",
+		},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Render(&buf))
+	require.Contains(t, buf.String(), "(retry of step 0)\nuser: Anyone?\n")
+}
+
+func TestRenderMessage(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	renderMessage(&buf, "", providers.Message{Role: providers.RoleUser, Content: []providers.ContentPart{
+		{Type: "text", Text: "First line\nsecond line"},
+		{Type: "image_url", ImageURL: &providers.ImageURL{URL: "data:image/png;base64,AAAA"}},
+	}})
+	renderMessage(&buf, "", providers.Message{Role: providers.RoleAssistant})
+
+	require.Equal(t, "user: First line\n      second line\n      [image]\nassistant: (empty)\n", buf.String())
+}
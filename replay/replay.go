@@ -0,0 +1,377 @@
+// Package replay records sessions for debugging. A Recorder wraps a provider
+// and writes every request it handles, with the full response or every
+// streamed chunk, to a portable trace file of JSON lines. A Trace read back
+// from the file renders the session as a transcript, re-sends any recorded
+// request, and drives a Player, which answers an application's requests from
+// the trace up to a chosen step and from a live provider after it.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// traceVersion is the trace format written by this package.
+const traceVersion = 1
+
+// maxLineSize bounds one trace line, which holds a whole request and response.
+const maxLineSize = 64 << 20
+
+// Ensure Recorder implements the required interfaces.
+var _ providers.Provider = (*Recorder)(nil)
+
+// Option configures a Recorder.
+type Option func(*Recorder) error
+
+// Recorder wraps a provider and writes a step to a trace for each request.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	next     int
+	onError  func(error)
+	provider providers.Provider
+	session  string
+	w        io.Writer
+}
+
+// Step is one recorded request and its outcome. Response is set for
+// completions and Chunks for streams; a request that failed has Error set,
+// along with whatever arrived before the failure.
+type Step struct {
+	Chunks   []providers.ChatCompletionChunk `json:"chunks,omitempty"`
+	Error    string                          `json:"error,omitempty"`
+	Extra    map[string]any                  `json:"extra,omitempty"`
+	Index    int                             `json:"index"`
+	Latency  time.Duration                   `json:"latency"`
+	Params   providers.CompletionParams      `json:"params"`
+	Provider string                          `json:"provider"`
+	Response *providers.ChatCompletion       `json:"response,omitempty"`
+	Session  string                          `json:"session,omitempty"`
+	Stream   bool                            `json:"stream"`
+	Time     time.Time                       `json:"time"`
+	Version  int                             `json:"version"`
+}
+
+// Trace is a recorded session, with its steps in the order they were made.
+type Trace struct {
+	Steps []Step
+}
+
+// New wraps provider so each request is written to w as one JSON line when it
+// completes.
+//
+// By default a failure to write a step is returned as the request's error so
+// the trace has no gaps. Use WithErrorHandler to handle write failures
+// without failing requests.
+func New(provider providers.Provider, w io.Writer, opts ...Option) (*Recorder, error) {
+	if w == nil {
+		return nil, fmt.Errorf("trace writer is required")
+	}
+
+	r := &Recorder{provider: provider, w: w}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Load reads the trace file at path.
+func Load(path string) (*Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening trace: %w", err)
+	}
+	defer func() { _ = f.Close() }() // Read-only file; close errors carry no data.
+
+	return Read(f)
+}
+
+// Read reads a trace written by a Recorder from r. Blank lines are skipped.
+func Read(r io.Reader) (*Trace, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+
+	trace := &Trace{}
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var step Step
+		if err := json.Unmarshal(scanner.Bytes(), &step); err != nil {
+			return nil, fmt.Errorf("reading trace line %d: %w", line, err)
+		}
+		if step.Version > traceVersion {
+			return nil, fmt.Errorf("reading trace line %d: unsupported trace version %d", line, step.Version)
+		}
+		step.Params.Extra = step.Extra
+		trace.Steps = append(trace.Steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading trace: %w", err)
+	}
+
+	return trace, nil
+}
+
+// WithErrorHandler calls fn with trace write errors instead of failing the request.
+func WithErrorHandler(fn func(error)) Option {
+	return func(r *Recorder) error {
+		if fn == nil {
+			return fmt.Errorf("error handler must not be nil")
+		}
+
+		r.onError = fn
+		return nil
+	}
+}
+
+// WithSession labels every step with id, so traces of several sessions can
+// share a file and be told apart with Trace.Session.
+func WithSession(id string) Option {
+	return func(r *Recorder) error {
+		if id == "" {
+			return fmt.Errorf("session ID must not be empty")
+		}
+
+		r.session = id
+		return nil
+	}
+}
+
+// Completion performs a chat completion request and records it.
+func (r *Recorder) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	step := r.newStep(params)
+
+	resp, err := r.provider.Completion(ctx, params)
+	step.Latency = time.Since(step.Time)
+	step.Response = resp
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	if writeErr := r.write(step); writeErr != nil && err == nil {
+		return nil, writeErr
+	}
+
+	return resp, err
+}
+
+// CompletionStream performs a streaming chat completion request and records
+// it, with every chunk, once the stream ends.
+func (r *Recorder) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		step := r.newStep(params)
+		step.Stream = true
+
+		err := r.forwardStream(ctx, params, out, &step)
+		step.Latency = time.Since(step.Time)
+		if err != nil {
+			step.Error = err.Error()
+		}
+
+		if writeErr := r.write(step); writeErr != nil && err == nil {
+			err = writeErr
+		}
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (r *Recorder) Name() string {
+	return r.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (r *Recorder) Unwrap() providers.Provider {
+	return r.provider
+}
+
+// Completion returns the step's response, assembled from its chunks for
+// streamed steps. It returns nil if nothing was received.
+func (s *Step) Completion() *providers.ChatCompletion {
+	if !s.Stream {
+		return s.Response
+	}
+	if len(s.Chunks) == 0 {
+		return nil
+	}
+
+	var acc providers.Accumulator
+	for _, chunk := range s.Chunks {
+		acc.Add(chunk)
+	}
+	return acc.Completion()
+}
+
+// Messages returns the conversation as it stood after step i: the step's
+// request messages followed by its response message, if there was one. Use
+// it to fork a session from any point.
+func (t *Trace) Messages(i int) ([]providers.Message, error) {
+	step, err := t.step(i)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := append([]providers.Message(nil), step.Params.Messages...)
+	if resp := step.Completion(); resp != nil && len(resp.Choices) > 0 {
+		messages = append(messages, resp.Choices[0].Message)
+	}
+	return messages, nil
+}
+
+// Rerun sends the request of step i to provider again and returns the new
+// response. Edits are applied to a copy of the recorded request before it is
+// sent, to change the model, the messages, or anything else and see how the
+// response differs.
+func (t *Trace) Rerun(
+	ctx context.Context,
+	provider providers.Provider,
+	i int,
+	edits ...func(*providers.CompletionParams),
+) (*providers.ChatCompletion, error) {
+	step, err := t.step(i)
+	if err != nil {
+		return nil, err
+	}
+
+	params := cloneParams(step.Params)
+	params.Stream = false
+	for _, edit := range edits {
+		edit(&params)
+	}
+
+	return provider.Completion(ctx, params)
+}
+
+// Session returns a trace of the steps recorded with the given session ID,
+// renumbered from zero.
+func (t *Trace) Session(id string) *Trace {
+	session := &Trace{}
+	for _, step := range t.Steps {
+		if step.Session != id {
+			continue
+		}
+		step.Index = len(session.Steps)
+		session.Steps = append(session.Steps, step)
+	}
+	return session
+}
+
+// forwardStream forwards the wrapped stream to out while collecting its
+// chunks into step. It returns the stream's error, if any.
+func (r *Recorder) forwardStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+	step *Step,
+) error {
+	chunks, errs := r.provider.CompletionStream(ctx, params)
+
+	for chunk := range chunks {
+		step.Chunks = append(step.Chunks, chunk)
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return <-errs
+}
+
+// newStep starts a step for params, taking the next index.
+func (r *Recorder) newStep(params providers.CompletionParams) Step {
+	r.mu.Lock()
+	index := r.next
+	r.next++
+	r.mu.Unlock()
+
+	return Step{
+		Extra:    params.Extra,
+		Index:    index,
+		Params:   params,
+		Provider: r.provider.Name(),
+		Session:  r.session,
+		Time:     time.Now(),
+		Version:  traceVersion,
+	}
+}
+
+// step returns step i, or an error if the trace has no such step.
+func (t *Trace) step(i int) (*Step, error) {
+	if i < 0 || i >= len(t.Steps) {
+		return nil, fmt.Errorf("step %d out of range: trace has %d steps", i, len(t.Steps))
+	}
+
+	return &t.Steps[i], nil
+}
+
+// write appends step to the trace. Write errors are passed to the error
+// handler when one is set, or returned.
+func (r *Recorder) write(step Step) error {
+	err := r.writeLine(step)
+	if err != nil && r.onError != nil {
+		r.onError(err)
+		return nil
+	}
+	return err
+}
+
+// writeLine writes step as one JSON line.
+func (r *Recorder) writeLine(step Step) error {
+	line, err := json.Marshal(step)
+	if err != nil {
+		return fmt.Errorf("encoding trace step: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing trace step: %w", err)
+	}
+	return nil
+}
+
+// cloneParams returns a copy of params whose messages, stop sequences and
+// tools can be edited without changing the original.
+func cloneParams(params providers.CompletionParams) providers.CompletionParams {
+	params.Messages = append([]providers.Message(nil), params.Messages...)
+	params.Stop = append([]string(nil), params.Stop...)
+	params.Tools = append([]providers.Tool(nil), params.Tools...)
+	return params
+}
@@ -0,0 +1,310 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// weatherResult is the weather tool's result in recorded sessions.
+const weatherResult = "Sunny, 22°C"
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, stderrors.New("disk full")
+}
+
+// agentProvider returns a mock that asks for the weather tool until a tool
+// result arrives, then answers with the result.
+func agentProvider() *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		last := params.Messages[len(params.Messages)-1]
+		if last.Role != providers.RoleTool {
+			return testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+			}}), nil
+		}
+		return testutil.MockChatCompletion(fmt.Sprintf("%s: %s", params.Model, last.ContentString())), nil
+	}
+	return mock
+}
+
+// runAgent runs a two-step tool loop against provider and returns the final answer.
+func runAgent(t *testing.T, provider providers.Provider) string {
+	t.Helper()
+
+	params := providers.CompletionParams{
+		Model:    "model-a",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "What's the weather in Paris?"}},
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	}
+
+	resp, err := provider.Completion(context.Background(), params)
+	require.NoError(t, err)
+	msg := resp.Choices[0].Message
+	require.Len(t, msg.ToolCalls, 1)
+
+	params.Messages = append(params.Messages, msg, providers.NewToolResultMessage(
+		msg.ToolCalls[0].ID, providers.ToolResult{Content: weatherResult},
+	))
+	resp, err = provider.Completion(context.Background(), params)
+	require.NoError(t, err)
+	return resp.Choices[0].Message.ContentString()
+}
+
+// recordAgent runs the agent through a Recorder and returns the trace.
+func recordAgent(t *testing.T) *Trace {
+	t.Helper()
+
+	var buf bytes.Buffer
+	recorder, err := New(agentProvider(), &buf)
+	require.NoError(t, err)
+	runAgent(t, recorder)
+
+	trace, err := Read(&buf)
+	require.NoError(t, err)
+	return trace
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(testutil.NewMockProvider(), nil)
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), &bytes.Buffer{}, WithSession(""))
+	require.Error(t, err)
+
+	_, err = New(testutil.NewMockProvider(), &bytes.Buffer{}, WithErrorHandler(nil))
+	require.Error(t, err)
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records completions", func(t *testing.T) {
+		t.Parallel()
+
+		trace := recordAgent(t)
+		require.Len(t, trace.Steps, 2)
+
+		first, second := trace.Steps[0], trace.Steps[1]
+		require.Equal(t, 0, first.Index)
+		require.Equal(t, 1, second.Index)
+		require.Equal(t, "mock", first.Provider)
+		require.Equal(t, "model-a", first.Params.Model)
+		require.Len(t, first.Params.Tools, 1)
+		require.False(t, first.Stream)
+		require.Equal(t, "get_weather", first.Response.Choices[0].Message.ToolCalls[0].Function.Name)
+		require.Len(t, second.Params.Messages, 3)
+		require.Equal(t, "model-a: "+weatherResult, second.Response.Choices[0].Message.ContentString())
+	})
+
+	t.Run("records every chunk of streams", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		recorder, err := New(testutil.NewMockProvider(), &buf, WithSession("session-1"))
+		require.NoError(t, err)
+
+		acc, err := drain(recorder.CompletionStream(context.Background(), providers.CompletionParams{
+			Model:    "model-a",
+			Messages: testutil.SimpleMessages(),
+			Extra:    map[string]any{"top_k": float64(40)},
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", acc.Choices[0].Message.ContentString())
+
+		trace, err := Read(&buf)
+		require.NoError(t, err)
+		require.Len(t, trace.Steps, 1)
+
+		step := trace.Steps[0]
+		require.True(t, step.Stream)
+		require.Equal(t, "session-1", step.Session)
+		require.Len(t, step.Chunks, 3)
+		require.Equal(t, map[string]any{"top_k": float64(40)}, step.Params.Extra)
+		require.Equal(t, "Hello World", step.Completion().Choices[0].Message.ContentString())
+	})
+
+	t.Run("records errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+
+		var buf bytes.Buffer
+		recorder, err := New(mock, &buf)
+		require.NoError(t, err)
+
+		_, err = recorder.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+
+		trace, err := Read(&buf)
+		require.NoError(t, err)
+		require.Contains(t, trace.Steps[0].Error, "slow down")
+		require.Nil(t, trace.Steps[0].Response)
+	})
+
+	t.Run("fails when the step cannot be written", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, err := New(testutil.NewMockProvider(), failingWriter{})
+		require.NoError(t, err)
+
+		_, err = recorder.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorContains(t, err, "disk full")
+
+		_, err = drain(recorder.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.ErrorContains(t, err, "disk full")
+	})
+
+	t.Run("passes write errors to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		var handled error
+		recorder, err := New(testutil.NewMockProvider(), failingWriter{}, WithErrorHandler(func(err error) { handled = err }))
+		require.NoError(t, err)
+
+		resp, err := recorder.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		require.ErrorContains(t, handled, "disk full")
+	})
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+		want    int
+	}{
+		{name: "empty", input: "", want: 0},
+		{name: "skips blank lines", input: `{"index":0,"version":1}` + "\n\n" + `{"index":1,"version":1}` + "\n", want: 2},
+		{name: "invalid JSON", input: `{"index":0}` + "\n" + "not json\n", wantErr: "line 2"},
+		{name: "newer version", input: `{"index":0,"version":99}`, wantErr: "unsupported trace version 99"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			trace, err := Read(strings.NewReader(tc.input))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, trace.Steps, tc.want)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	recorder, err := New(agentProvider(), f)
+	require.NoError(t, err)
+	runAgent(t, recorder)
+	require.NoError(t, f.Close())
+
+	trace, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, trace.Steps, 2)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.jsonl"))
+	require.Error(t, err)
+}
+
+func TestTraceMessages(t *testing.T) {
+	t.Parallel()
+
+	trace := recordAgent(t)
+
+	messages, err := trace.Messages(0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, providers.RoleAssistant, messages[1].Role)
+	require.Len(t, messages[1].ToolCalls, 1)
+
+	_, err = trace.Messages(2)
+	require.ErrorContains(t, err, "out of range")
+}
+
+func TestTraceRerun(t *testing.T) {
+	t.Parallel()
+
+	trace := recordAgent(t)
+
+	resp, err := trace.Rerun(context.Background(), agentProvider(), 1, func(params *providers.CompletionParams) {
+		params.Model = "model-b"
+	})
+	require.NoError(t, err)
+	require.Equal(t, "model-b: "+weatherResult, resp.Choices[0].Message.ContentString())
+
+	// The recorded request is unchanged.
+	require.Equal(t, "model-a", trace.Steps[1].Params.Model)
+
+	_, err = trace.Rerun(context.Background(), agentProvider(), -1)
+	require.ErrorContains(t, err, "out of range")
+}
+
+func TestTraceSession(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	for _, id := range []string{"a", "b", "a"} {
+		recorder, err := New(testutil.NewMockProvider(), &buf, WithSession(id))
+		require.NoError(t, err)
+		_, err = recorder.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+	}
+
+	trace, err := Read(&buf)
+	require.NoError(t, err)
+
+	session := trace.Session("a")
+	require.Len(t, session.Steps, 2)
+	require.Equal(t, 0, session.Steps[0].Index)
+	require.Equal(t, 1, session.Steps[1].Index)
+	require.Empty(t, trace.Session("c").Steps)
+}
+
+// drain reads a stream to the end and returns the accumulated completion and
+// the stream's error.
+func drain(chunks <-chan providers.ChatCompletionChunk, errs <-chan error) (*providers.ChatCompletion, error) {
+	var acc providers.Accumulator
+	for chunk := range chunks {
+		acc.Add(chunk)
+	}
+	return acc.Completion(), <-errs
+}
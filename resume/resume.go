@@ -0,0 +1,392 @@
+// Package resume wraps a provider so that a stream interrupted by a transient
+// error, such as a dropped connection, is resumed from where it stopped
+// instead of restarting from the beginning. Optionally, responses cut off by
+// the output token limit are continued the same way.
+package resume
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// DefaultContinuePrompt is the user message Continue sends when given an empty prompt.
+const DefaultContinuePrompt = "Your previous message was cut off. Continue exactly where it stopped, " +
+	"without repeating any of it."
+
+// providerAnthropic is the name of the provider that continues a trailing
+// assistant message natively.
+const providerAnthropic = "anthropic"
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and resumes interrupted streams.
+type Provider struct {
+	maxContinuations int
+	policy           retry.Policy
+	provider         providers.Provider
+	strategy         Strategy
+}
+
+// Strategy builds the request that resumes an interrupted stream, from the
+// original params and the content received before the interruption.
+// Strategies must not modify params.
+type Strategy func(params providers.CompletionParams, partial string) providers.CompletionParams
+
+// stream tracks what an interrupted stream has delivered so far.
+type stream struct {
+	content strings.Builder
+	id      string
+
+	// continuationsLeft is how many more times a response cut off by the
+	// output token limit may be continued.
+	continuationsLeft int
+
+	// lastUsage is the usage reported by the current request.
+	lastUsage providers.Usage
+
+	// resumable is false once the stream has delivered output that a resumed
+	// request cannot reproduce, such as tool calls or extra choices.
+	resumable bool
+
+	// trimLeading drops leading whitespace from resumed content, since the
+	// whitespace that ended the partial content was already delivered.
+	trimLeading bool
+
+	// truncated is set when the current request stopped at the output token
+	// limit and will be continued.
+	truncated bool
+
+	// usage is the usage reported by the earlier requests.
+	usage providers.Usage
+}
+
+// New wraps provider so that interrupted streams are resumed. By default,
+// errors that retry.IsTransient accepts are resumed with retry.Backoff, and
+// the Strategy is Prefill for Anthropic and Continue for other providers.
+// Completion requests are passed through unchanged unless continuation is
+// enabled with WithMaxContinuations.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{
+		policy:   retry.Backoff{},
+		provider: provider,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.strategy == nil {
+		p.strategy = defaultStrategy(provider.Name())
+	}
+
+	return p, nil
+}
+
+// WithMaxContinuations continues responses cut off by the output token limit,
+// up to n times per request. The strategy builds each continuation request
+// from the content so far, and the parts are joined into one response. It is
+// meant for long outputs, such as reports, from models with a small output
+// limit. Zero, the default, disables continuation.
+func WithMaxContinuations(n int) Option {
+	return func(p *Provider) error {
+		if n < 0 {
+			return fmt.Errorf("max continuations must not be negative, got %d", n)
+		}
+		p.maxContinuations = n
+		return nil
+	}
+}
+
+// WithPolicy sets the policy that decides whether, and after what delay, a
+// failed stream is resumed. Attempts are counted across the whole stream.
+func WithPolicy(policy retry.Policy) Option {
+	return func(p *Provider) error {
+		if policy == nil {
+			return fmt.Errorf("policy must not be nil")
+		}
+		p.policy = policy
+		return nil
+	}
+}
+
+// WithStrategy sets how interrupted streams are resumed.
+func WithStrategy(strategy Strategy) Option {
+	return func(p *Provider) error {
+		if strategy == nil {
+			return fmt.Errorf("strategy must not be nil")
+		}
+		p.strategy = strategy
+		return nil
+	}
+}
+
+// Completion performs a chat completion request. It is not retried; wrap the
+// provider with retry for that. With continuation enabled, a response cut off
+// by the output token limit is continued, and the returned response joins the
+// parts: its content is the whole text, its finish reason that of the last
+// part, and its usage the sum of all parts.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := p.provider.Completion(ctx, params)
+	if err != nil || !truncated(resp) || p.maxContinuations == 0 {
+		return resp, err
+	}
+
+	content := resp.Choices[0].Message.ContentString()
+	last := resp
+	for n := 0; n < p.maxContinuations && truncated(last); n++ {
+		last, err = p.provider.Completion(ctx, p.strategy(params, content))
+		if err != nil {
+			return nil, err
+		}
+		if len(last.Choices) == 0 {
+			break
+		}
+
+		part := last.Choices[0].Message.ContentString()
+		if strings.TrimRightFunc(content, unicode.IsSpace) != content {
+			part = strings.TrimLeftFunc(part, unicode.IsSpace)
+		}
+		content += part
+
+		resp.Choices[0].FinishReason = last.Choices[0].FinishReason
+		resp.Choices[0].Message.ToolCalls = last.Choices[0].Message.ToolCalls
+		resp.Usage = addUsage(resp.Usage, last.Usage)
+	}
+	resp.Choices[0].Message.Content = content
+
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request. When the
+// stream fails with an error the policy accepts, it is re-issued with the
+// content received so far, using the strategy, and the new output is appended
+// to the same channel. Chunks keep the ID of the first stream. Streams that
+// have delivered tool calls or more than one choice are not resumed.
+// With continuation enabled, a stream cut off by the output token limit is
+// continued the same way, without its length finish reason. Reported usage
+// adds up every request.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		s := &stream{continuationsLeft: p.maxContinuations, resumable: true}
+		req := params
+
+		for attempt := 1; ; {
+			err := p.forwardStream(ctx, req, out, s)
+			switch {
+			case err == nil && !s.truncated:
+				return
+			case err == nil:
+				// Continuations are not failures, so they do not count as attempts.
+			default:
+				delay, ok := p.policy.ShouldRetry(err, attempt)
+				attempt++
+				if ctx.Err() != nil || !s.resumable || !ok || wait(ctx, delay) != nil {
+					outErrs <- err
+					return
+				}
+			}
+
+			req = params
+			if partial := s.content.String(); partial != "" {
+				req = p.strategy(params, partial)
+				s.trimLeading = strings.TrimRightFunc(partial, unicode.IsSpace) != partial
+			}
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// forwardStream runs one streaming attempt, forwarding its chunks to out and
+// recording them in s. It returns the attempt's error, if any.
+func (p *Provider) forwardStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+	s *stream,
+) error {
+	s.truncated = false
+	chunks, errs := p.provider.CompletionStream(ctx, params)
+	defer s.endRequest()
+
+	for chunk := range chunks {
+		s.observe(&chunk)
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return <-errs
+}
+
+// endRequest adds the usage of the request that ended to the total.
+func (s *stream) endRequest() {
+	s.usage = *addUsage(&s.usage, &s.lastUsage)
+	s.lastUsage = providers.Usage{}
+}
+
+// observe records chunk's content in s and rewrites chunk to continue the
+// stream seamlessly: it takes the first stream's ID, loses the leading
+// whitespace that was already delivered, and reports the usage of every
+// request. A length finish reason is dropped if the stream will be continued.
+func (s *stream) observe(chunk *providers.ChatCompletionChunk) {
+	if s.id == "" {
+		s.id = chunk.ID
+	} else if chunk.ID != "" {
+		chunk.ID = s.id
+	}
+
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if choice.Index != 0 || len(choice.Delta.ToolCalls) > 0 {
+			s.resumable = false
+		}
+
+		if s.trimLeading {
+			choice.Delta.Content = strings.TrimLeftFunc(choice.Delta.Content, unicode.IsSpace)
+			s.trimLeading = choice.Delta.Content == ""
+		}
+		s.content.WriteString(choice.Delta.Content)
+
+		if choice.Index == 0 && choice.FinishReason == providers.FinishReasonLength &&
+			s.resumable && s.continuationsLeft > 0 {
+			choice.FinishReason = ""
+			s.continuationsLeft--
+			s.truncated = true
+		}
+	}
+
+	if chunk.Usage != nil {
+		s.lastUsage = *chunk.Usage
+		chunk.Usage = addUsage(&s.usage, chunk.Usage)
+	}
+}
+
+// Continue returns a Strategy that sends the partial content as an assistant
+// message followed by a user message with prompt, asking the model to go on.
+// It works with every provider, though the model may repeat or rephrase some
+// text at the seam. An empty prompt uses DefaultContinuePrompt.
+func Continue(prompt string) Strategy {
+	if prompt == "" {
+		prompt = DefaultContinuePrompt
+	}
+
+	return func(params providers.CompletionParams, partial string) providers.CompletionParams {
+		params.Messages = append(slices.Clip(params.Messages),
+			providers.Message{Role: providers.RoleAssistant, Content: partial},
+			providers.Message{Role: providers.RoleUser, Content: prompt},
+		)
+		return params
+	}
+}
+
+// Prefill is a Strategy that sends the partial content as a trailing assistant
+// message, which the model continues word for word. Only providers that
+// support assistant prefill, such as Anthropic, continue the message; others
+// answer it instead. Trailing whitespace is trimmed, since Anthropic rejects it.
+func Prefill(params providers.CompletionParams, partial string) providers.CompletionParams {
+	params.Messages = append(slices.Clip(params.Messages), providers.Message{
+		Role:    providers.RoleAssistant,
+		Content: strings.TrimRightFunc(partial, unicode.IsSpace),
+	})
+	return params
+}
+
+// addUsage returns the sum of a and b, or nil if both are nil.
+func addUsage(a *providers.Usage, b *providers.Usage) *providers.Usage {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	var sum providers.Usage
+	for _, u := range []*providers.Usage{a, b} {
+		if u == nil {
+			continue
+		}
+		sum.PromptTokens += u.PromptTokens
+		sum.CompletionTokens += u.CompletionTokens
+		sum.TotalTokens += u.TotalTokens
+		sum.ReasoningTokens += u.ReasoningTokens
+		sum.CachedTokens += u.CachedTokens
+	}
+	return &sum
+}
+
+// defaultStrategy returns the Strategy used for the provider with the given name.
+func defaultStrategy(name string) Strategy {
+	if name == providerAnthropic {
+		return Prefill
+	}
+	return Continue("")
+}
+
+// truncated reports whether resp is a single text choice cut off by the output
+// token limit, which can be continued.
+func truncated(resp *providers.ChatCompletion) bool {
+	if len(resp.Choices) != 1 {
+		return false
+	}
+	choice := resp.Choices[0]
+	return choice.FinishReason == providers.FinishReasonLength &&
+		len(choice.Message.ToolCalls) == 0 &&
+		!choice.Message.IsMultiModal() &&
+		choice.Message.ContentString() != ""
+}
+
+// wait blocks for delay or until ctx is done, returning the context error in the latter case.
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,367 @@
+package resume
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// noDelay retries transient errors up to three attempts without waiting.
+var noDelay = retry.PolicyFunc(func(err error, attempt int) (time.Duration, bool) {
+	return 0, attempt < 3 && retry.IsTransient(err)
+})
+
+// lengthChunk returns a chunk with the given ID and content that ends its
+// stream at the output token limit, with usage.
+func lengthChunk(id, content string) providers.ChatCompletionChunk {
+	chunk := testutil.ContentChunk(id, content)
+	chunk.Choices[0].FinishReason = providers.FinishReasonLength
+	chunk.Usage = &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	return chunk
+}
+
+// partsProvider returns a mock named name whose completions return parts in
+// order, each cut off by the output token limit except the last.
+func partsProvider(name string, parts ...string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.NameFunc = func() string { return name }
+
+	calls := 0
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion(parts[calls])
+		resp.Usage = &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+		calls++
+		if calls < len(parts) {
+			resp.Choices[0].FinishReason = providers.FinishReasonLength
+		}
+		return resp, nil
+	}
+
+	return mock
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(testutil.NewMockProvider(), WithPolicy(nil))
+	require.EqualError(t, err, "policy must not be nil")
+
+	_, err = New(testutil.NewMockProvider(), WithStrategy(nil))
+	require.EqualError(t, err, "strategy must not be nil")
+
+	_, err = New(testutil.NewMockProvider(), WithMaxContinuations(-1))
+	require.EqualError(t, err, "max continuations must not be negative, got -1")
+
+	provider, err := New(testutil.NewMockProvider(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "mock", provider.Name())
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("continues responses cut off by the length limit", func(t *testing.T) {
+		t.Parallel()
+
+		mock := partsProvider("openai", "Chapter one.", " Chapter two.", " The end.")
+		provider, err := New(mock, WithMaxContinuations(3))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "Chapter one. Chapter two. The end.", resp.Choices[0].Message.Content)
+		require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
+		require.Equal(t, &providers.Usage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45}, resp.Usage)
+
+		require.Len(t, mock.CompletionCalls, 3)
+		continued := mock.CompletionCalls[2].Messages
+		require.Equal(t, "Chapter one. Chapter two.", continued[len(continued)-2].Content)
+		require.Equal(t, DefaultContinuePrompt, continued[len(continued)-1].Content)
+	})
+
+	t.Run("stitches prefilled parts", func(t *testing.T) {
+		t.Parallel()
+
+		mock := partsProvider("anthropic", "The answer is ", " forty-two.")
+		provider, err := New(mock, WithMaxContinuations(1))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "The answer is forty-two.", resp.Choices[0].Message.Content)
+
+		prefilled := mock.CompletionCalls[1].Messages
+		require.Equal(t, providers.Message{Role: providers.RoleAssistant, Content: "The answer is"}, prefilled[len(prefilled)-1])
+	})
+
+	t.Run("stops after the max continuations", func(t *testing.T) {
+		t.Parallel()
+
+		mock := partsProvider("openai", "A", "B", "C")
+		provider, err := New(mock, WithMaxContinuations(1))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "AB", resp.Choices[0].Message.Content)
+		require.Equal(t, providers.FinishReasonLength, resp.Choices[0].FinishReason)
+		require.Len(t, mock.CompletionCalls, 2)
+	})
+
+	t.Run("passes responses through by default", func(t *testing.T) {
+		t.Parallel()
+
+		mock := partsProvider("openai", "A", "B")
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "A", resp.Choices[0].Message.Content)
+		require.Equal(t, providers.FinishReasonLength, resp.Choices[0].FinishReason)
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+
+	t.Run("returns continuation errors", func(t *testing.T) {
+		t.Parallel()
+
+		failure := stderrors.New("overloaded")
+		mock := partsProvider("openai", "A", "B")
+		next := mock.CompletionFunc
+		mock.CompletionFunc = func(ctx context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if len(mock.CompletionCalls) > 1 {
+				return nil, failure
+			}
+			return next(ctx, params)
+		}
+		provider, err := New(mock, WithMaxContinuations(2))
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, failure)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	dropped := errors.NewProviderError("mock", stderrors.New("connection reset by peer"))
+
+	t.Run("resumes anthropic streams with a prefill", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{
+				Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("msg_1", "The answer is ")},
+				Err:    dropped,
+			},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("msg_2", " forty-two.")}},
+		)
+		mock.NameFunc = func() string { return "anthropic" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		got, err := testutil.Collect(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "The answer is forty-two.", testutil.ChunksContent(got))
+		require.Len(t, got, 2)
+		require.Equal(t, "msg_1", got[1].ID)
+
+		require.Len(t, mock.CompletionStreamCalls, 2)
+		resumed := mock.CompletionStreamCalls[1].Messages
+		require.Len(t, resumed, len(testutil.SimpleMessages())+1)
+		require.Equal(t, providers.Message{Role: providers.RoleAssistant, Content: "The answer is"}, resumed[len(resumed)-1])
+	})
+
+	t.Run("asks other providers to continue", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c1", "Once upon")}, Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c2", " a time.")}},
+		)
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Once upon a time.", content)
+
+		resumed := mock.CompletionStreamCalls[1].Messages
+		require.Equal(t, providers.Message{Role: providers.RoleAssistant, Content: "Once upon"}, resumed[len(resumed)-2])
+		require.Equal(t, providers.Message{Role: providers.RoleUser, Content: DefaultContinuePrompt}, resumed[len(resumed)-1])
+	})
+
+	t.Run("retries failures before the first chunk unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c1", "Hi")}},
+		)
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		params := providers.CompletionParams{Messages: testutil.SimpleMessages()}
+		content, err := testutil.CollectContent(provider.CompletionStream(context.Background(), params))
+		require.NoError(t, err)
+		require.Equal(t, "Hi", content)
+		require.Equal(t, params, mock.CompletionStreamCalls[1])
+	})
+
+	t.Run("does not resume after tool calls", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(testutil.ScriptedStream{
+			Chunks: []providers.ChatCompletionChunk{{Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{
+				ToolCalls: []providers.ToolCall{{ID: "call_1", Function: providers.FunctionCall{Name: "get_weather"}}},
+			}}}}},
+			Err: dropped,
+		})
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		_, err = testutil.Collect(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.Len(t, mock.CompletionStreamCalls, 1)
+	})
+
+	t.Run("returns errors the policy rejects", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(testutil.ScriptedStream{
+			Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c1", "Hi")},
+			Err:    errors.NewContextLengthError("mock", stderrors.New("too long")),
+		})
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		_, err = testutil.Collect(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.ErrorIs(t, err, errors.ErrContextLength)
+		require.Len(t, mock.CompletionStreamCalls, 1)
+	})
+
+	t.Run("gives up after the policy's attempts", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c1", "A")}, Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c2", "B")}, Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c3", "C")}, Err: dropped},
+		)
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay))
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.Equal(t, "ABC", content)
+		require.Len(t, mock.CompletionStreamCalls, 3)
+
+		// Each resume sends everything received so far.
+		resumed := mock.CompletionStreamCalls[2].Messages
+		require.Equal(t, "AB", resumed[len(resumed)-2].Content)
+	})
+}
+
+func TestCompletionStreamContinuation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("continues streams cut off by the length limit", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{lengthChunk("msg_1", "The answer is ")}},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{
+				testutil.ContentChunk("msg_2", " forty-"),
+				lengthChunk("msg_2", "two"),
+			}},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{lengthChunk("msg_3", ".")}},
+		)
+		mock.NameFunc = func() string { return "anthropic" }
+		provider, err := New(mock, WithMaxContinuations(2))
+		require.NoError(t, err)
+
+		chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		})
+
+		var acc providers.Accumulator
+		var finishReasons []string
+		for chunk := range chunks {
+			acc.Add(chunk)
+			finishReasons = append(finishReasons, chunk.Choices[0].FinishReason)
+		}
+		require.NoError(t, <-errs)
+
+		completion := acc.Completion()
+		require.Equal(t, "The answer is forty-two.", completion.Choices[0].Message.Content)
+		require.Equal(t, "msg_1", completion.ID)
+		require.Equal(t, []string{"", "", "", providers.FinishReasonLength}, finishReasons)
+		require.Equal(t, &providers.Usage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45}, completion.Usage)
+
+		require.Len(t, mock.CompletionStreamCalls, 3)
+		prefilled := mock.CompletionStreamCalls[2].Messages
+		require.Equal(t, "The answer is forty-two", prefilled[len(prefilled)-1].Content)
+	})
+
+	t.Run("does not count continuations as attempts", func(t *testing.T) {
+		t.Parallel()
+
+		dropped := errors.NewProviderError("mock", stderrors.New("connection reset by peer"))
+		mock := testutil.ScriptedStreams(
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{lengthChunk("c1", "A")}},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{lengthChunk("c2", "B")}},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c3", "C")}, Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c4", "D")}, Err: dropped},
+			testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c5", "E")}},
+		)
+		mock.NameFunc = func() string { return "openai" }
+		provider, err := New(mock, WithPolicy(noDelay), WithMaxContinuations(2))
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(provider.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "ABCDE", content)
+	})
+}
+
+func TestStrategies(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{Messages: testutil.SimpleMessages()}
+	original := len(params.Messages)
+
+	prefilled := Prefill(params, "Partial answer\n\n")
+	require.Equal(t, "Partial answer", prefilled.Messages[len(prefilled.Messages)-1].Content)
+
+	continued := Continue("Go on.")(params, "Partial answer")
+	require.Equal(t, "Go on.", continued.Messages[len(continued.Messages)-1].Content)
+
+	// The original params are not modified.
+	require.Len(t, params.Messages, original)
+}
@@ -0,0 +1,261 @@
+// Package retry wraps a provider so failed requests are retried according to
+// a pluggable Policy.
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+// Default backoff settings, used for zero-valued Backoff fields.
+const (
+	defaultBaseDelay   = 500 * time.Millisecond
+	defaultMaxAttempts = 3
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Policy decides whether a failed request is retried.
+type Policy interface {
+	// ShouldRetry is called after attempt (starting at 1) failed with err.
+	// It returns how long to wait before the next attempt and whether to make one.
+	ShouldRetry(err error, attempt int) (time.Duration, bool)
+}
+
+// PolicyFunc adapts an ordinary function to the Policy interface.
+type PolicyFunc func(err error, attempt int) (time.Duration, bool)
+
+// ShouldRetry calls f(err, attempt).
+func (f PolicyFunc) ShouldRetry(err error, attempt int) (time.Duration, bool) {
+	return f(err, attempt)
+}
+
+// Backoff retries transient errors with exponential backoff.
+// Rate limit errors and provider errors that are network failures or 5xx
+// responses are retried; all other errors are returned immediately.
+// Zero-valued fields use the package defaults.
+type Backoff struct {
+	// BaseDelay is the delay before the second attempt; it doubles for each further attempt.
+	BaseDelay time.Duration
+
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+
+	// MaxDelay caps the delay between attempts.
+	MaxDelay time.Duration
+}
+
+// ShouldRetry implements Policy.
+func (b Backoff) ShouldRetry(err error, attempt int) (time.Duration, bool) {
+	maxAttempts := b.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if attempt >= maxAttempts || !IsTransient(err) {
+		return 0, false
+	}
+
+	var rateLimitErr *errors.RateLimitError
+	if stderrors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+		return time.Duration(rateLimitErr.RetryAfter) * time.Second, true
+	}
+
+	baseDelay := b.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultBaseDelay
+	}
+	maxDelay := b.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	delay := baseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay, true
+}
+
+// Provider wraps a provider and retries failed requests according to its policy.
+type Provider struct {
+	policy   Policy
+	provider providers.Provider
+}
+
+// New wraps provider so failed requests are retried according to policy.
+// A nil policy uses Backoff with default settings.
+func New(provider providers.Provider, policy Policy) *Provider {
+	if policy == nil {
+		policy = Backoff{}
+	}
+
+	return &Provider{
+		policy:   policy,
+		provider: provider,
+	}
+}
+
+// Completion performs a chat completion request, retrying failed attempts.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	var resp *providers.ChatCompletion
+	_, err := Do(ctx, p.policy, p.provider.Name(), func() error {
+		var err error
+		resp, err = p.provider.Completion(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request.
+// A stream is only retried if it fails before delivering its first chunk;
+// once output has been forwarded, errors are returned as-is.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		for attempt := 1; ; attempt++ {
+			started, err := p.forwardStream(ctx, params, chunks)
+			if err == nil {
+				return
+			}
+
+			delay, ok := p.policy.ShouldRetry(err, attempt)
+			if started || !ok {
+				errs <- err
+				return
+			}
+			scheduled(ctx, p.provider.Name(), attempt, delay, err)
+			if wait(ctx, delay) != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// forwardStream runs one streaming attempt, forwarding its chunks to out.
+// It reports whether any chunk was forwarded and the attempt's error, if any.
+func (p *Provider) forwardStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+) (bool, error) {
+	chunks, errs := p.provider.CompletionStream(ctx, params)
+
+	started := false
+	for chunk := range chunks {
+		select {
+		case out <- chunk:
+			started = true
+		case <-ctx.Done():
+			return started, ctx.Err()
+		}
+	}
+
+	return started, <-errs
+}
+
+// Do calls fn until it succeeds or policy stops retrying, waiting between
+// attempts, and returns the number of attempts made and the last error. Use
+// it to retry requests a Provider does not wrap, such as embeddings. Retries
+// are reported to the telemetry subscribers in ctx as made to provider.
+func Do(ctx context.Context, policy Policy, provider string, fn func() error) (int, error) {
+	if policy == nil {
+		policy = Backoff{}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return attempt, nil
+		}
+
+		delay, ok := policy.ShouldRetry(err, attempt)
+		if !ok {
+			return attempt, err
+		}
+		scheduled(ctx, provider, attempt, delay, err)
+		if wait(ctx, delay) != nil {
+			return attempt, err
+		}
+	}
+}
+
+// IsTransient reports whether err is likely to succeed on retry: rate limits,
+// queue timeouts, network failures and 5xx responses.
+func IsTransient(err error) bool {
+	if stderrors.Is(err, errors.ErrRateLimit) || stderrors.Is(err, errors.ErrQueueTimeout) {
+		return true
+	}
+
+	var providerErr *errors.ProviderError
+	if !stderrors.As(err, &providerErr) {
+		return false
+	}
+
+	// A zero status code means no response was received (e.g. a network error).
+	return providerErr.StatusCode == 0 || providerErr.StatusCode >= http.StatusInternalServerError
+}
+
+// scheduled reports to the telemetry subscribers in ctx that a failed attempt
+// at a request to provider will be retried.
+func scheduled(ctx context.Context, provider string, attempt int, delay time.Duration, err error) {
+	telemetry.Emit(ctx, telemetry.RetryScheduled{
+		Attempt:  attempt,
+		Delay:    delay,
+		Err:      err,
+		Provider: provider,
+		Time:     time.Now(),
+	})
+}
+
+// wait blocks for delay or until ctx is done, returning the context error in the latter case.
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,290 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+const testProviderName = "mock"
+
+// immediate retries every error up to maxAttempts without waiting.
+func immediate(maxAttempts int) Policy {
+	return PolicyFunc(func(_ error, attempt int) (time.Duration, bool) {
+		return 0, attempt < maxAttempts
+	})
+}
+
+// statusError returns a provider error carrying the given HTTP status code.
+func statusError(statusCode int) error {
+	err := errors.NewProviderError(testProviderName, stderrors.New("upstream failure"))
+	err.StatusCode = statusCode
+	return err
+}
+
+func TestBackoffShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	rateLimited := errors.NewRateLimitError(testProviderName, stderrors.New("slow down"))
+	rateLimitedWithHint := errors.NewRateLimitError(testProviderName, stderrors.New("slow down"))
+	rateLimitedWithHint.RetryAfter = 7
+
+	tests := []struct {
+		name      string
+		backoff   Backoff
+		err       error
+		attempt   int
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{
+			name:      "retries rate limits with exponential delay",
+			backoff:   Backoff{BaseDelay: time.Second},
+			err:       rateLimited,
+			attempt:   2,
+			wantDelay: 2 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "honors retry-after",
+			err:       rateLimitedWithHint,
+			attempt:   1,
+			wantDelay: 7 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "retries server errors",
+			err:       statusError(http.StatusServiceUnavailable),
+			attempt:   1,
+			wantDelay: defaultBaseDelay,
+			wantRetry: true,
+		},
+		{
+			name:      "caps delay",
+			backoff:   Backoff{BaseDelay: time.Second, MaxAttempts: 10, MaxDelay: 3 * time.Second},
+			err:       statusError(http.StatusBadGateway),
+			attempt:   5,
+			wantDelay: 3 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:    "stops after max attempts",
+			err:     rateLimited,
+			attempt: defaultMaxAttempts,
+		},
+		{
+			name:    "does not retry invalid requests",
+			err:     errors.NewInvalidRequestError(testProviderName, stderrors.New("bad")),
+			attempt: 1,
+		},
+		{
+			name:    "does not retry content filter errors",
+			err:     errors.NewContentFilterError(testProviderName, stderrors.New("blocked")),
+			attempt: 1,
+		},
+		{
+			name:    "does not retry client status codes",
+			err:     statusError(http.StatusConflict),
+			attempt: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			delay, retry := tc.backoff.ShouldRetry(tc.err, tc.attempt)
+			require.Equal(t, tc.wantRetry, retry)
+			require.Equal(t, tc.wantDelay, delay)
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retries until success", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if len(mock.CompletionCalls) < 3 {
+				return nil, statusError(529)
+			}
+			return testutil.MockChatCompletion("Hello"), nil
+		}
+
+		events := make(chan telemetry.Event, 5)
+		ctx := telemetry.NewContext(context.Background(), telemetry.Channel(events))
+
+		resp, err := New(mock, immediate(5)).Completion(ctx, providers.CompletionParams{})
+		require.NoError(t, err)
+		require.Equal(t, "Hello", resp.Choices[0].Message.Content)
+		require.Len(t, mock.CompletionCalls, 3)
+
+		require.Len(t, events, 2)
+		for attempt := 1; attempt <= 2; attempt++ {
+			retry, ok := (<-events).(telemetry.RetryScheduled)
+			require.True(t, ok)
+			require.Equal(t, attempt, retry.Attempt)
+			require.Equal(t, testProviderName, retry.Provider)
+		}
+	})
+
+	t.Run("returns last error when policy gives up", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError(testProviderName, stderrors.New("slow down"))
+		}
+
+		_, err := New(mock, immediate(2)).Completion(context.Background(), providers.CompletionParams{})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		require.Len(t, mock.CompletionCalls, 2)
+	})
+
+	t.Run("stops waiting when context is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, statusError(http.StatusInternalServerError)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := New(mock, Backoff{BaseDelay: time.Hour}).Completion(ctx, providers.CompletionParams{})
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	failingStream := func() (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk)
+		errs := make(chan error, 1)
+		close(chunks)
+		errs <- statusError(http.StatusServiceUnavailable)
+		close(errs)
+		return chunks, errs
+	}
+
+	t.Run("retries streams that fail before the first chunk", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		succeed := mock.CompletionStreamFunc
+		mock.CompletionStreamFunc = func(
+			ctx context.Context,
+			params providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			if len(mock.CompletionStreamCalls) == 1 {
+				return failingStream()
+			}
+			return succeed(ctx, params)
+		}
+
+		chunks, errs := New(mock, immediate(3)).CompletionStream(context.Background(), providers.CompletionParams{})
+		count := 0
+		for range chunks {
+			count++
+		}
+		require.NoError(t, <-errs)
+		require.Equal(t, 3, count)
+		require.Len(t, mock.CompletionStreamCalls, 2)
+	})
+
+	t.Run("does not retry after output has started", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionStreamFunc = func(
+			_ context.Context,
+			_ providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			chunks := make(chan providers.ChatCompletionChunk, 1)
+			errs := make(chan error, 1)
+			chunks <- providers.ChatCompletionChunk{ID: "partial"}
+			close(chunks)
+			errs <- statusError(http.StatusServiceUnavailable)
+			close(errs)
+			return chunks, errs
+		}
+
+		chunks, errs := New(mock, immediate(3)).CompletionStream(context.Background(), providers.CompletionParams{})
+		for range chunks {
+		}
+		require.ErrorIs(t, <-errs, errors.ErrProvider)
+		require.Len(t, mock.CompletionStreamCalls, 1)
+	})
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the number of attempts", func(t *testing.T) {
+		t.Parallel()
+
+		events := make(chan telemetry.Event, 5)
+		ctx := telemetry.NewContext(context.Background(), telemetry.Channel(events))
+
+		calls := 0
+		attempts, err := Do(ctx, immediate(5), testProviderName, func() error {
+			calls++
+			if calls < 3 {
+				return statusError(http.StatusServiceUnavailable)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+		require.Len(t, events, 2)
+	})
+
+	t.Run("returns last error when policy gives up", func(t *testing.T) {
+		t.Parallel()
+
+		attempts, err := Do(context.Background(), immediate(2), testProviderName, func() error {
+			return errors.NewRateLimitError(testProviderName, stderrors.New("slow down"))
+		})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		require.Equal(t, 2, attempts)
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, IsTransient(errors.NewRateLimitError(testProviderName, stderrors.New("slow down"))))
+	require.True(t, IsTransient(errors.NewQueueTimeoutError(testProviderName, stderrors.New("queue is full"))))
+	require.True(t, IsTransient(errors.NewProviderError(testProviderName, stderrors.New("connection reset"))))
+	require.True(t, IsTransient(statusError(529)))
+	require.False(t, IsTransient(statusError(http.StatusNotFound)))
+	require.False(t, IsTransient(errors.NewQuotaExceededError(testProviderName, stderrors.New("out of credits"))))
+	require.False(t, IsTransient(errors.NewAuthenticationError(testProviderName, stderrors.New("bad key"))))
+	require.False(t, IsTransient(stderrors.New("unclassified")))
+}
+
+func TestNameAndUnwrap(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	provider := New(mock, nil)
+
+	require.Equal(t, testProviderName, provider.Name())
+	require.Same(t, mock, provider.Unwrap())
+	require.Equal(t, Backoff{}, provider.policy)
+}
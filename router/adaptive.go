@@ -0,0 +1,358 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Adaptive routing defaults.
+const (
+	defaultExploration = 0.05
+	defaultWindow      = 100
+)
+
+// adaptiveName is the name an Adaptive router reports.
+const adaptiveName = "adaptive"
+
+// tokensPerMillion converts token counts to the unit route prices use.
+const tokensPerMillion = 1_000_000
+
+// Ensure Adaptive implements the required interfaces.
+var _ providers.Provider = (*Adaptive)(nil)
+
+// Adaptive sends each request to the route with the best recent record: the
+// lowest weighted sum of latency, error rate and cost over a moving window of
+// requests. Routes that have not been tried yet are tried first, and a small
+// share of requests goes to a random route so that the record of a route that
+// has recovered is updated.
+type Adaptive struct {
+	exploration float64
+	mu          sync.Mutex
+	rand        func() float64
+	routes      []AdaptiveRoute
+	stats       []*window
+	weights     Weights
+	window      int
+}
+
+// AdaptiveOption configures an Adaptive router.
+type AdaptiveOption func(*Adaptive) error
+
+// AdaptiveRoute is a route with the prices used to estimate its cost.
+type AdaptiveRoute struct {
+	Route
+
+	// InputPerMillion and OutputPerMillion are the prices of a million prompt
+	// and completion tokens. Any currency works, as long as all routes use
+	// the same one. Leave both zero for free routes such as local models.
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// RouteStats summarizes a route's record over the current window.
+type RouteStats struct {
+	// Cost is the mean estimated cost of a successful request.
+	Cost float64
+
+	// ErrorRate is the share of requests that failed, between 0 and 1.
+	ErrorRate float64
+
+	// Latency is the mean latency: the full response time for completions,
+	// and the time to the first chunk for streams.
+	Latency time.Duration
+
+	// Requests is the number of requests in the window.
+	Requests int
+}
+
+// Weights sets how much latency, error rate and cost count when routes are
+// compared. Each measure is divided by its highest value among the routes
+// before it is weighted, so the weights are relative to each other.
+type Weights struct {
+	Cost      float64
+	ErrorRate float64
+	Latency   float64
+}
+
+// outcome is the record of one request.
+type outcome struct {
+	cost    float64
+	failed  bool
+	latency time.Duration
+}
+
+// window holds the most recent outcomes of a route.
+type window struct {
+	next     int
+	outcomes []outcome
+}
+
+// NewAdaptive returns an Adaptive router over routes. By default latency,
+// error rate and cost are weighted equally, the window holds the last 100
+// requests of each route, and 5% of requests explore a random route.
+func NewAdaptive(routes []AdaptiveRoute, opts ...AdaptiveOption) (*Adaptive, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+
+	a := &Adaptive{
+		exploration: defaultExploration,
+		rand:        rand.Float64,
+		routes:      routes,
+		weights:     Weights{Cost: 1, ErrorRate: 1, Latency: 1},
+		window:      defaultWindow,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+
+	a.stats = make([]*window, len(routes))
+	for i := range a.stats {
+		a.stats[i] = &window{}
+	}
+
+	return a, nil
+}
+
+// WithExploration sets the share of requests, between 0 and 1, sent to a
+// random route instead of the best one.
+func WithExploration(rate float64) AdaptiveOption {
+	return func(a *Adaptive) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("exploration rate must be between 0 and 1, got %v", rate)
+		}
+		a.exploration = rate
+		return nil
+	}
+}
+
+// WithWeights sets how much latency, error rate and cost count. Weights must
+// not be negative, and at least one must be positive.
+func WithWeights(weights Weights) AdaptiveOption {
+	return func(a *Adaptive) error {
+		if weights.Cost < 0 || weights.ErrorRate < 0 || weights.Latency < 0 {
+			return fmt.Errorf("weights must not be negative, got %+v", weights)
+		}
+		if weights.Cost+weights.ErrorRate+weights.Latency == 0 {
+			return fmt.Errorf("at least one weight must be positive")
+		}
+		a.weights = weights
+		return nil
+	}
+}
+
+// WithWindow sets how many recent requests of each route are considered.
+func WithWindow(size int) AdaptiveOption {
+	return func(a *Adaptive) error {
+		if size <= 0 {
+			return fmt.Errorf("window size must be positive, got %d", size)
+		}
+		a.window = size
+		return nil
+	}
+}
+
+// Completion performs a chat completion request on the best route and
+// records its outcome.
+func (a *Adaptive) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	i := a.pick()
+	route := a.routes[i]
+
+	start := time.Now()
+	resp, err := route.Provider.Completion(ctx, route.params(params))
+	if ctx.Err() == nil {
+		o := outcome{failed: err != nil, latency: time.Since(start)}
+		if err == nil {
+			o.cost = route.cost(resp.Usage)
+		}
+		a.record(i, o)
+	}
+
+	return resp, err
+}
+
+// CompletionStream performs a streaming chat completion request on the best
+// route and records its outcome once the stream ends.
+func (a *Adaptive) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	i := a.pick()
+	route := a.routes[i]
+
+	start := time.Now()
+	chunks, errs := route.Provider.CompletionStream(ctx, route.params(params))
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		var o outcome
+		var usage *providers.Usage
+		for chunk := range chunks {
+			if o.latency == 0 {
+				o.latency = time.Since(start)
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		err := <-errs
+		if ctx.Err() == nil {
+			if o.latency == 0 {
+				o.latency = time.Since(start)
+			}
+			o.failed = err != nil
+			if err == nil {
+				o.cost = route.cost(usage)
+			}
+			a.record(i, o)
+		}
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns "adaptive", since responses may come from any route.
+func (a *Adaptive) Name() string {
+	return adaptiveName
+}
+
+// Stats returns each route's record over the current window, in the order
+// the routes were given.
+func (a *Adaptive) Stats() []RouteStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := make([]RouteStats, len(a.stats))
+	for i, w := range a.stats {
+		stats[i] = w.summary()
+	}
+	return stats
+}
+
+// pick returns the index of the route for the next request.
+func (a *Adaptive) pick() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.routes) > 1 && a.rand() < a.exploration {
+		return min(int(a.rand()*float64(len(a.routes))), len(a.routes)-1)
+	}
+
+	stats := make([]RouteStats, len(a.stats))
+	var maxStats RouteStats
+	for i, w := range a.stats {
+		stats[i] = w.summary()
+		if stats[i].Requests == 0 {
+			return i
+		}
+		maxStats.Cost = max(maxStats.Cost, stats[i].Cost)
+		maxStats.ErrorRate = max(maxStats.ErrorRate, stats[i].ErrorRate)
+		maxStats.Latency = max(maxStats.Latency, stats[i].Latency)
+	}
+
+	best, bestScore := 0, 0.0
+	for i, s := range stats {
+		score := a.weights.Cost*ratio(s.Cost, maxStats.Cost) +
+			a.weights.ErrorRate*ratio(s.ErrorRate, maxStats.ErrorRate) +
+			a.weights.Latency*ratio(float64(s.Latency), float64(maxStats.Latency))
+		if i == 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// record adds o to the window of route i.
+func (a *Adaptive) record(i int, o outcome) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w := a.stats[i]
+	if len(w.outcomes) < a.window {
+		w.outcomes = append(w.outcomes, o)
+		return
+	}
+	w.outcomes[w.next] = o
+	w.next = (w.next + 1) % a.window
+}
+
+// cost estimates the cost of a request with usage.
+func (r AdaptiveRoute) cost(usage *providers.Usage) float64 {
+	return usageCost(usage, r.InputPerMillion, r.OutputPerMillion)
+}
+
+// summary returns the window's statistics. Cost averages successful requests
+// only, since failed requests have no usage.
+func (w *window) summary() RouteStats {
+	s := RouteStats{Requests: len(w.outcomes)}
+	if s.Requests == 0 {
+		return s
+	}
+
+	var failed, succeeded int
+	var latency time.Duration
+	for _, o := range w.outcomes {
+		latency += o.latency
+		if o.failed {
+			failed++
+			continue
+		}
+		succeeded++
+		s.Cost += o.cost
+	}
+
+	if succeeded > 0 {
+		s.Cost /= float64(succeeded)
+	}
+	s.ErrorRate = float64(failed) / float64(s.Requests)
+	s.Latency = latency / time.Duration(s.Requests)
+	return s
+}
+
+// ratio returns v divided by limit, or 0 when limit is 0.
+func ratio(v float64, limit float64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return v / limit
+}
+
+// usageCost estimates the cost of a request with usage at the given prices
+// per million tokens. Requests without usage are estimated at zero.
+func usageCost(usage *providers.Usage, inputPerMillion float64, outputPerMillion float64) float64 {
+	if usage == nil {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*inputPerMillion +
+		float64(usage.CompletionTokens)*outputPerMillion) / tokensPerMillion
+}
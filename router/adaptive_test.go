@@ -0,0 +1,278 @@
+package router
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// newTestAdaptive returns an Adaptive router over routes without exploration.
+func newTestAdaptive(t *testing.T, routes []AdaptiveRoute, opts ...AdaptiveOption) *Adaptive {
+	t.Helper()
+
+	a, err := NewAdaptive(routes, append([]AdaptiveOption{WithExploration(0)}, opts...)...)
+	require.NoError(t, err)
+	return a
+}
+
+// usageProvider returns a mock whose completions report the given usage.
+func usageProvider(prompt int, completion int) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion("Hello")
+		resp.Usage = &providers.Usage{
+			CompletionTokens: completion,
+			PromptTokens:     prompt,
+			TotalTokens:      prompt + completion,
+		}
+		return resp, nil
+	}
+	return mock
+}
+
+func TestNewAdaptive(t *testing.T) {
+	t.Parallel()
+
+	routes := []AdaptiveRoute{{Route: Route{Provider: testutil.NewMockProvider()}}}
+
+	tests := []struct {
+		name    string
+		routes  []AdaptiveRoute
+		opts    []AdaptiveOption
+		wantErr string
+	}{
+		{
+			name:    "requires routes",
+			wantErr: "at least one route is required",
+		},
+		{
+			name:    "rejects exploration rates over 1",
+			routes:  routes,
+			opts:    []AdaptiveOption{WithExploration(1.5)},
+			wantErr: "exploration rate must be between 0 and 1, got 1.5",
+		},
+		{
+			name:    "rejects negative weights",
+			routes:  routes,
+			opts:    []AdaptiveOption{WithWeights(Weights{Cost: -1})},
+			wantErr: "weights must not be negative, got {Cost:-1 ErrorRate:0 Latency:0}",
+		},
+		{
+			name:    "rejects zero weights",
+			routes:  routes,
+			opts:    []AdaptiveOption{WithWeights(Weights{})},
+			wantErr: "at least one weight must be positive",
+		},
+		{
+			name:    "rejects empty windows",
+			routes:  routes,
+			opts:    []AdaptiveOption{WithWindow(0)},
+			wantErr: "window size must be positive, got 0",
+		},
+		{
+			name:   "accepts valid options",
+			routes: routes,
+			opts:   []AdaptiveOption{nil, WithWeights(Weights{Latency: 1}), WithWindow(10)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := NewAdaptive(tc.routes, tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "adaptive", a.Name())
+		})
+	}
+}
+
+func TestAdaptiveCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tries every route before comparing them", func(t *testing.T) {
+		t.Parallel()
+
+		first := testutil.NewMockProvider()
+		second := testutil.NewMockProvider()
+		a := newTestAdaptive(t, []AdaptiveRoute{
+			{Route: Route{Provider: first}},
+			{Route: Route{Model: "second-model", Provider: second}},
+		})
+
+		for range 2 {
+			_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, first.CompletionCalls, 1)
+		require.Len(t, second.CompletionCalls, 1)
+		require.Equal(t, "second-model", second.CompletionCalls[0].Model)
+	})
+
+	t.Run("prefers cheaper routes", func(t *testing.T) {
+		t.Parallel()
+
+		expensive := usageProvider(1000, 500)
+		cheap := usageProvider(1000, 500)
+		a := newTestAdaptive(t, []AdaptiveRoute{
+			{Route: Route{Provider: expensive}, InputPerMillion: 3, OutputPerMillion: 15},
+			{Route: Route{Provider: cheap}, InputPerMillion: 0.25, OutputPerMillion: 1.25},
+		}, WithWeights(Weights{Cost: 1}))
+
+		for range 5 {
+			_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, expensive.CompletionCalls, 1)
+		require.Len(t, cheap.CompletionCalls, 4)
+		require.InDelta(t, 0.0105, a.Stats()[0].Cost, 1e-9)
+	})
+
+	t.Run("avoids failing routes", func(t *testing.T) {
+		t.Parallel()
+
+		failing := testutil.NewMockProvider()
+		failing.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("mock", stderrors.New("unavailable"))
+		}
+		healthy := testutil.NewMockProvider()
+		a := newTestAdaptive(t, []AdaptiveRoute{
+			{Route: Route{Provider: failing}},
+			{Route: Route{Provider: healthy}},
+		}, WithWeights(Weights{ErrorRate: 1}))
+
+		_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrProvider)
+		for range 3 {
+			_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+		}
+
+		stats := a.Stats()
+		require.Equal(t, RouteStats{ErrorRate: 1, Latency: stats[0].Latency, Requests: 1}, stats[0])
+		require.Equal(t, 3, stats[1].Requests)
+	})
+
+	t.Run("prefers faster routes", func(t *testing.T) {
+		t.Parallel()
+
+		slow := testutil.NewMockProvider()
+		slow.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			time.Sleep(20 * time.Millisecond)
+			return testutil.MockChatCompletion("slow"), nil
+		}
+		fast := testutil.NewMockProvider()
+		a := newTestAdaptive(t, []AdaptiveRoute{
+			{Route: Route{Provider: slow}},
+			{Route: Route{Provider: fast}},
+		})
+
+		for range 4 {
+			_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+		}
+
+		require.Len(t, slow.CompletionCalls, 1)
+		require.Len(t, fast.CompletionCalls, 3)
+	})
+
+	t.Run("explores a random route", func(t *testing.T) {
+		t.Parallel()
+
+		first := testutil.NewMockProvider()
+		second := testutil.NewMockProvider()
+		a := newTestAdaptive(t, []AdaptiveRoute{
+			{Route: Route{Provider: first}},
+			{Route: Route{Provider: second}},
+		}, WithExploration(0.5))
+
+		// The first roll decides to explore and the second picks the route,
+		// even though the untried first route would otherwise be chosen.
+		rolls := []float64{0.1, 0.9}
+		a.rand = func() float64 {
+			roll := rolls[0]
+			rolls = rolls[1:]
+			return roll
+		}
+
+		_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Empty(t, first.CompletionCalls)
+		require.Len(t, second.CompletionCalls, 1)
+	})
+
+	t.Run("keeps only the window", func(t *testing.T) {
+		t.Parallel()
+
+		a := newTestAdaptive(t, []AdaptiveRoute{{Route: Route{Provider: testutil.NewMockProvider()}}}, WithWindow(3))
+
+		for range 5 {
+			_, err := a.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 3, a.Stats()[0].Requests)
+	})
+
+	t.Run("does not record cancelled requests", func(t *testing.T) {
+		t.Parallel()
+
+		a := newTestAdaptive(t, []AdaptiveRoute{{Route: Route{Provider: testutil.NewMockProvider()}}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _ = a.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+
+		require.Zero(t, a.Stats()[0].Requests)
+	})
+}
+
+func TestAdaptiveCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 2)
+		errs := make(chan error)
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "Hi"}}},
+		}
+		chunks <- providers.ChatCompletionChunk{
+			Usage: &providers.Usage{CompletionTokens: 1_000_000, PromptTokens: 1_000_000},
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+	a := newTestAdaptive(t, []AdaptiveRoute{{Route: Route{Provider: mock}, InputPerMillion: 1, OutputPerMillion: 2}})
+
+	chunks, errs := a.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	})
+	count := 0
+	for range chunks {
+		count++
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, 2, count)
+
+	stats := a.Stats()[0]
+	require.Equal(t, 1, stats.Requests)
+	require.InDelta(t, 3.0, stats.Cost, 1e-9)
+}
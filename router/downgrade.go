@@ -0,0 +1,271 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// downgradeName is the name a Downgrade router reports.
+const downgradeName = "downgrade"
+
+// Ensure Downgrade implements the required interfaces.
+var _ providers.Provider = (*Downgrade)(nil)
+
+// Downgrade keeps spending within a budget by moving requests to cheaper
+// tiers, such as from a large model to a small one, as the spend over a
+// moving time window grows. Each tier takes over once the share of the budget
+// spent reaches its threshold, and the router moves back up once older spend
+// leaves the window.
+//
+// The budget is not a hard limit: once the last threshold is crossed,
+// requests keep going to the cheapest tier.
+type Downgrade struct {
+	budget       float64
+	current      int
+	mu           sync.Mutex
+	now          func() time.Time
+	onTierChange func(TierChange)
+	spend        []spend
+	tiers        []Tier
+	window       time.Duration
+}
+
+// DowngradeOption configures a Downgrade router.
+type DowngradeOption func(*Downgrade) error
+
+// Tier is a route with its prices and the share of the budget at which it
+// takes over.
+type Tier struct {
+	Route
+
+	// InputPerMillion and OutputPerMillion are the prices of a million prompt
+	// and completion tokens, in the budget's currency.
+	InputPerMillion  float64
+	OutputPerMillion float64
+
+	// Threshold is the share of the budget, between 0 and 1, spent in the
+	// window at which this tier is used. It must be 0 for the first tier.
+	Threshold float64
+}
+
+// TierChange reports that a Downgrade router moved to another tier.
+type TierChange struct {
+	// Budget is the router's budget.
+	Budget float64
+
+	// From and To are the indexes of the previous and the new tier.
+	From int
+	To   int
+
+	// Spent is the spend in the window when the change happened.
+	Spent float64
+}
+
+// spend is the cost of one request.
+type spend struct {
+	at   time.Time
+	cost float64
+}
+
+// NewDowngrade returns a Downgrade router that spends up to budget per window
+// over tiers, ordered from the preferred tier to the cheapest, with
+// increasing thresholds.
+func NewDowngrade(tiers []Tier, budget float64, window time.Duration, opts ...DowngradeOption) (*Downgrade, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("at least one tier is required")
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("budget must be positive, got %v", budget)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive, got %v", window)
+	}
+	if tiers[0].Threshold != 0 {
+		return nil, fmt.Errorf("threshold of the first tier must be 0, got %v", tiers[0].Threshold)
+	}
+	for i := 1; i < len(tiers); i++ {
+		threshold := tiers[i].Threshold
+		if threshold <= tiers[i-1].Threshold || threshold > 1 {
+			return nil, fmt.Errorf("thresholds must increase up to 1, got %v after %v", threshold, tiers[i-1].Threshold)
+		}
+	}
+
+	d := &Downgrade{
+		budget: budget,
+		now:    time.Now,
+		tiers:  tiers,
+		window: window,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// WithTierChangeHandler sets a function called when the router moves to
+// another tier, for example to log or alert on downgrades. It is called
+// before the request that triggered the change is sent, and must be safe for
+// concurrent use.
+func WithTierChangeHandler(fn func(TierChange)) DowngradeOption {
+	return func(d *Downgrade) error {
+		if fn == nil {
+			return fmt.Errorf("tier change handler must not be nil")
+		}
+		d.onTierChange = fn
+		return nil
+	}
+}
+
+// Completion performs a chat completion request on the tier the current
+// spend allows, and adds its cost to the spend.
+func (d *Downgrade) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	i := d.pick()
+	tier := d.tiers[i]
+
+	resp, err := tier.Provider.Completion(ctx, tier.params(params))
+	if err != nil {
+		return nil, err
+	}
+
+	d.record(tier.cost(resp.Usage))
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request on the tier
+// the current spend allows, and adds its cost to the spend once the stream
+// reports usage.
+func (d *Downgrade) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	i := d.pick()
+	tier := d.tiers[i]
+
+	chunks, errs := tier.Provider.CompletionStream(ctx, tier.params(params))
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for chunk := range chunks {
+			if chunk.Usage != nil {
+				// Streams that fail later were still billed for their tokens.
+				d.record(tier.cost(chunk.Usage))
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns "downgrade", since responses may come from any tier.
+func (d *Downgrade) Name() string {
+	return downgradeName
+}
+
+// Spent returns the spend in the current window.
+func (d *Downgrade) Spent() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.spent()
+}
+
+// Tier returns the index of the tier the next request goes to.
+func (d *Downgrade) Tier() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.tierFor(d.spent())
+}
+
+// pick returns the index of the tier for the next request, reporting a
+// change of tier to the handler.
+func (d *Downgrade) pick() int {
+	d.mu.Lock()
+	spent := d.spent()
+	i := d.tierFor(spent)
+	change := TierChange{Budget: d.budget, From: d.current, To: i, Spent: spent}
+	d.current = i
+	d.mu.Unlock()
+
+	if change.From != change.To && d.onTierChange != nil {
+		d.onTierChange(change)
+	}
+	return i
+}
+
+// record adds a request's cost to the spend.
+func (d *Downgrade) record(cost float64) {
+	if cost == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.spend = append(d.spend, spend{at: d.now(), cost: cost})
+}
+
+// spent drops spend older than the window and returns the rest. The caller
+// must hold d.mu.
+func (d *Downgrade) spent() float64 {
+	cutoff := d.now().Add(-d.window)
+
+	expired := 0
+	for expired < len(d.spend) && !d.spend[expired].at.After(cutoff) {
+		expired++
+	}
+	d.spend = d.spend[expired:]
+
+	total := 0.0
+	for _, s := range d.spend {
+		total += s.cost
+	}
+	return total
+}
+
+// tierFor returns the index of the tier for spent: the last tier whose
+// threshold has been reached.
+func (d *Downgrade) tierFor(spent float64) int {
+	share := spent / d.budget
+
+	i := 0
+	for i+1 < len(d.tiers) && share >= d.tiers[i+1].Threshold {
+		i++
+	}
+	return i
+}
+
+// cost estimates the cost of a request with usage.
+func (t Tier) cost(usage *providers.Usage) float64 {
+	return usageCost(usage, t.InputPerMillion, t.OutputPerMillion)
+}
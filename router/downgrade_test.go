@@ -0,0 +1,228 @@
+package router
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// downgradeTiers returns a large and a small tier whose requests cost 3 and
+// 1, taking over at half the budget.
+func downgradeTiers() []Tier {
+	return []Tier{
+		{Route: Route{Model: "large", Provider: usageProvider(1_000_000, 0)}, InputPerMillion: 3},
+		{Route: Route{Model: "small", Provider: usageProvider(1_000_000, 0)}, InputPerMillion: 1, Threshold: 0.5},
+	}
+}
+
+// newTestDowngrade returns a Downgrade router over downgradeTiers with a
+// budget of 10 per hour, a clock the test controls and the tier changes it
+// reports.
+func newTestDowngrade(t *testing.T) (*Downgrade, *time.Time, func() []TierChange) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var changes []TierChange
+	d, err := NewDowngrade(downgradeTiers(), 10, time.Hour, WithTierChangeHandler(func(c TierChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, c)
+	}))
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	return d, &now, func() []TierChange {
+		mu.Lock()
+		defer mu.Unlock()
+		return changes
+	}
+}
+
+func TestNewDowngrade(t *testing.T) {
+	t.Parallel()
+
+	route := Route{Provider: testutil.NewMockProvider()}
+
+	tests := []struct {
+		name    string
+		tiers   []Tier
+		budget  float64
+		window  time.Duration
+		opts    []DowngradeOption
+		wantErr string
+	}{
+		{
+			name:    "requires tiers",
+			budget:  10,
+			window:  time.Hour,
+			wantErr: "at least one tier is required",
+		},
+		{
+			name:    "rejects non-positive budgets",
+			tiers:   []Tier{{Route: route}},
+			window:  time.Hour,
+			wantErr: "budget must be positive, got 0",
+		},
+		{
+			name:    "rejects non-positive windows",
+			tiers:   []Tier{{Route: route}},
+			budget:  10,
+			wantErr: "window must be positive, got 0s",
+		},
+		{
+			name:    "requires the first tier to start at 0",
+			tiers:   []Tier{{Route: route, Threshold: 0.2}},
+			budget:  10,
+			window:  time.Hour,
+			wantErr: "threshold of the first tier must be 0, got 0.2",
+		},
+		{
+			name:    "rejects thresholds that do not increase",
+			tiers:   []Tier{{Route: route}, {Route: route, Threshold: 0.8}, {Route: route, Threshold: 0.5}},
+			budget:  10,
+			window:  time.Hour,
+			wantErr: "thresholds must increase up to 1, got 0.5 after 0.8",
+		},
+		{
+			name:    "rejects thresholds over 1",
+			tiers:   []Tier{{Route: route}, {Route: route, Threshold: 1.5}},
+			budget:  10,
+			window:  time.Hour,
+			wantErr: "thresholds must increase up to 1, got 1.5 after 0",
+		},
+		{
+			name:    "rejects nil handlers",
+			tiers:   []Tier{{Route: route}},
+			budget:  10,
+			window:  time.Hour,
+			opts:    []DowngradeOption{WithTierChangeHandler(nil)},
+			wantErr: "tier change handler must not be nil",
+		},
+		{
+			name:   "accepts valid tiers",
+			tiers:  []Tier{{Route: route}, {Route: route, Threshold: 0.5}, {Route: route, Threshold: 1}},
+			budget: 10,
+			window: time.Hour,
+			opts:   []DowngradeOption{nil},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewDowngrade(tc.tiers, tc.budget, tc.window, tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDowngradeCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("moves to cheaper tiers as the budget is spent", func(t *testing.T) {
+		t.Parallel()
+
+		d, _, changes := newTestDowngrade(t)
+
+		var models []string
+		for range 4 {
+			_, err := d.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+			models = append(models, d.tiers[d.Tier()].Model)
+		}
+
+		// Two large requests spend 6 of 10, past the small tier's threshold.
+		require.Equal(t, []string{"large", "small", "small", "small"}, models)
+		require.InDelta(t, 8, d.Spent(), 1e-9)
+		require.Equal(t, []TierChange{{Budget: 10, From: 0, To: 1, Spent: 6}}, changes())
+	})
+
+	t.Run("moves back once spend leaves the window", func(t *testing.T) {
+		t.Parallel()
+
+		d, now, changes := newTestDowngrade(t)
+		for range 3 {
+			_, err := d.Completion(context.Background(), providers.CompletionParams{})
+			require.NoError(t, err)
+		}
+		require.Equal(t, 1, d.Tier())
+
+		*now = now.Add(time.Hour)
+		_, err := d.Completion(context.Background(), providers.CompletionParams{})
+		require.NoError(t, err)
+
+		require.Equal(t, "large", d.tiers[0].Provider.(*testutil.MockProvider).CompletionCalls[2].Model)
+		require.InDelta(t, 3, d.Spent(), 1e-9)
+		require.Equal(t, []TierChange{
+			{Budget: 10, From: 0, To: 1, Spent: 6},
+			{Budget: 10, From: 1, To: 0, Spent: 0},
+		}, changes())
+	})
+
+	t.Run("does not charge failed requests", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("mock", stderrors.New("boom"))
+		}
+		d, err := NewDowngrade([]Tier{{Route: Route{Provider: mock}, InputPerMillion: 3}}, 10, time.Hour)
+		require.NoError(t, err)
+
+		_, err = d.Completion(context.Background(), providers.CompletionParams{})
+		require.ErrorIs(t, err, errors.ErrProvider)
+		require.Zero(t, d.Spent())
+	})
+}
+
+func TestDowngradeCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("charges the usage the stream reports", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			chunks := make(chan providers.ChatCompletionChunk, 2)
+			errs := make(chan error)
+			chunks <- providers.ChatCompletionChunk{
+				Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "Hello"}}},
+			}
+			chunks <- providers.ChatCompletionChunk{
+				Choices: []providers.ChunkChoice{{}},
+				Usage:   &providers.Usage{PromptTokens: 500_000, CompletionTokens: 100_000},
+			}
+			close(chunks)
+			close(errs)
+			return chunks, errs
+		}
+
+		d, err := NewDowngrade([]Tier{
+			{Route: Route{Provider: mock}, InputPerMillion: 2, OutputPerMillion: 10},
+		}, 10, time.Hour)
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(d.CompletionStream(context.Background(), providers.CompletionParams{}))
+		require.NoError(t, err)
+		require.Equal(t, "Hello", content)
+		require.InDelta(t, 2, d.Spent(), 1e-9)
+	})
+}
@@ -0,0 +1,240 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+// fallbackName is the name a Fallback reports.
+const fallbackName = "fallback"
+
+// Ensure Fallback implements the required interfaces.
+var _ providers.Provider = (*Fallback)(nil)
+
+// Fallback sends each request to its routes in order, moving on to the next
+// route when one fails. Streams fail over only until they emit content: once
+// text, reasoning or a tool call has reached the caller, a failure is
+// returned as a *PartialStreamError instead, since switching providers would
+// splice two different answers together.
+type Fallback struct {
+	routes []Route
+}
+
+// PartialStreamError reports that a stream failed after part of the response
+// had been delivered. Partial holds what was delivered, so callers can decide
+// whether to resume from it, for example by sending it back as an assistant
+// message to continue, or to restart the request.
+type PartialStreamError struct {
+	// Err is the error that ended the stream.
+	Err error
+
+	// Partial is the response delivered before the error, accumulated from
+	// its chunks.
+	Partial *providers.ChatCompletion
+
+	// Provider is the name of the provider whose stream failed.
+	Provider string
+}
+
+// relay forwards a stream's chunks and accumulates them, so that a failure
+// can be reported with what was delivered.
+type relay struct {
+	acc      providers.Accumulator
+	provider string
+	sent     int
+}
+
+// NewFallback returns a Fallback that tries routes in order.
+func NewFallback(routes ...Route) (*Fallback, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+	for i, route := range routes {
+		if route.Provider == nil {
+			return nil, fmt.Errorf("route %d has no provider", i)
+		}
+	}
+
+	return &Fallback{routes: routes}, nil
+}
+
+// Completion performs a chat completion request on each route in turn until
+// one succeeds, emitting a telemetry.FallbackTriggered event for each switch.
+// When every route fails, the first route's error is returned.
+func (f *Fallback) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	var firstErr error
+	for i, route := range f.routes {
+		resp, err := route.Provider.Completion(ctx, route.params(params))
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+		if i+1 < len(f.routes) {
+			f.fallback(ctx, i, err)
+		}
+	}
+
+	return nil, firstErr
+}
+
+// CompletionStream performs a streaming chat completion request on each route
+// in turn until one succeeds. Chunks without content, such as a leading role
+// chunk, are held back until the first content arrives, so a stream that
+// fails before any content is dropped entirely and the next route starts
+// cleanly. A failure after content was delivered ends the stream with a
+// *PartialStreamError. When every route fails, the first route's error is
+// returned.
+func (f *Fallback) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		var firstErr error
+		for i, route := range f.routes {
+			delivered, err := f.stream(ctx, route, params, out)
+			switch {
+			case err == nil:
+				return
+			case delivered || ctx.Err() != nil:
+				outErrs <- err
+				return
+			}
+
+			if firstErr == nil {
+				firstErr = err
+			}
+			if i+1 < len(f.routes) {
+				f.fallback(ctx, i, err)
+			}
+		}
+
+		outErrs <- firstErr
+	}()
+
+	return out, outErrs
+}
+
+// Name returns "fallback", since responses may come from any route.
+func (f *Fallback) Name() string {
+	return fallbackName
+}
+
+// Error returns the error, noting that the stream was cut short.
+func (e *PartialStreamError) Error() string {
+	return fmt.Sprintf("stream from %s failed after partial output: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the error that ended the stream.
+func (e *PartialStreamError) Unwrap() error {
+	return e.Err
+}
+
+// fallback reports to the telemetry subscribers in ctx that route from
+// failed and the next route is tried in its place.
+func (f *Fallback) fallback(ctx context.Context, from int, err error) {
+	telemetry.Emit(ctx, telemetry.FallbackTriggered{
+		Err:  err,
+		From: f.routes[from].Provider.Name(),
+		Time: time.Now(),
+		To:   f.routes[from+1].Provider.Name(),
+	})
+}
+
+// stream streams route's response to out, holding back chunks until the
+// first one with content. It returns whether content was delivered, and how
+// the stream ended.
+func (f *Fallback) stream(
+	ctx context.Context,
+	route Route,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Stops the route's stream if the caller goes away.
+
+	chunks, errs := route.Provider.CompletionStream(ctx, route.params(params))
+	r := &relay{provider: route.Provider.Name()}
+
+	var held []providers.ChatCompletionChunk
+	delivered := false
+	for chunk := range chunks {
+		if !delivered && !hasContent(chunk) {
+			held = append(held, chunk)
+			continue
+		}
+
+		for _, c := range append(held, chunk) {
+			if err := r.send(ctx, out, c); err != nil {
+				return delivered, err
+			}
+		}
+		held = nil
+		delivered = true
+	}
+
+	if err := <-errs; err != nil {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		return delivered, r.fail(err)
+	}
+
+	// The stream ended without content, such as with only a finish reason.
+	for _, c := range held {
+		if err := r.send(ctx, out, c); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// fail returns err, as a *PartialStreamError if chunks were delivered.
+func (r *relay) fail(err error) error {
+	if r.sent == 0 {
+		return err
+	}
+	return &PartialStreamError{Err: err, Partial: r.acc.Completion(), Provider: r.provider}
+}
+
+// send delivers chunk to out.
+func (r *relay) send(ctx context.Context, out chan<- providers.ChatCompletionChunk, chunk providers.ChatCompletionChunk) error {
+	select {
+	case out <- chunk:
+		r.acc.Add(chunk)
+		r.sent++
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// hasContent reports whether chunk carries generated output.
+func hasContent(chunk providers.ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		if delta.Content != "" || delta.Refusal != "" || len(delta.ToolCalls) > 0 || delta.Audio != nil ||
+			(delta.Reasoning != nil && delta.Reasoning.Content != "") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package router
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+// failingStream returns a mock whose stream delivers chunks and then fails
+// with err.
+func failingStream(name string, err error, chunks ...providers.ChatCompletionChunk) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.NameFunc = func() string { return name }
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		out := make(chan providers.ChatCompletionChunk, len(chunks))
+		errs := make(chan error, 1)
+		for _, chunk := range chunks {
+			out <- chunk
+		}
+		errs <- err
+		close(out)
+		close(errs)
+		return out, errs
+	}
+	return mock
+}
+
+// roleChunk returns a chunk that only announces the assistant role.
+func roleChunk() providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{
+		Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Role: providers.RoleAssistant}}},
+	}
+}
+
+func TestNewFallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		routes  []Route
+		wantErr string
+	}{
+		{name: "no routes", wantErr: "at least one route is required"},
+		{
+			name:    "route without a provider",
+			routes:  []Route{{Provider: testutil.NewMockProvider()}, {Model: "m"}},
+			wantErr: "route 1 has no provider",
+		},
+		{name: "valid", routes: []Route{{Provider: testutil.NewMockProvider()}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := NewFallback(tc.routes...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "fallback", f.Name())
+		})
+	}
+}
+
+func TestFallbackCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("falls back to the next route", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.NameFunc = func() string { return "primary" }
+		primary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("primary", stderrors.New("unavailable"))
+		}
+		secondary := testutil.NewMockProvider()
+		f, err := NewFallback(Route{Provider: primary}, Route{Model: "backup-model", Provider: secondary})
+		require.NoError(t, err)
+
+		events := make(chan telemetry.Event, 1)
+		ctx := telemetry.NewContext(context.Background(), telemetry.Channel(events))
+
+		resp, err := f.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages(), Model: "main-model"})
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+		require.Equal(t, "backup-model", secondary.CompletionCalls[0].Model)
+
+		fallback, ok := (<-events).(telemetry.FallbackTriggered)
+		require.True(t, ok)
+		require.Equal(t, "primary", fallback.From)
+		require.Equal(t, "mock", fallback.To)
+		require.ErrorIs(t, fallback.Err, errors.ErrProvider)
+	})
+
+	t.Run("returns the first error when every route fails", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("primary", stderrors.New("unavailable"))
+		}
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("secondary", stderrors.New("slow down"))
+		}
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		_, err = f.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("stops on context errors", func(t *testing.T) {
+		t.Parallel()
+
+		primary, _ := stalledProvider()
+		secondary := testutil.NewMockProvider()
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), hedgeDelay)
+		defer cancel()
+
+		_, err = f.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, secondary.CompletionCalls)
+	})
+}
+
+func TestFallbackCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("falls back when a stream fails before content", func(t *testing.T) {
+		t.Parallel()
+
+		primary := failingStream("primary", errors.NewProviderError("primary", stderrors.New("overloaded")), roleChunk())
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf("Backup", " answer")
+		}
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		events := make(chan telemetry.Event, 1)
+		ctx := telemetry.NewContext(context.Background(), telemetry.Channel(events))
+
+		var chunks []providers.ChatCompletionChunk
+		out, errs := f.CompletionStream(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		for chunk := range out {
+			chunks = append(chunks, chunk)
+		}
+		require.NoError(t, <-errs)
+
+		// The primary's role chunk was held back and dropped.
+		require.Len(t, chunks, 2)
+		require.Equal(t, "Backup", chunks[0].Choices[0].Delta.Content)
+
+		fallback, ok := (<-events).(telemetry.FallbackTriggered)
+		require.True(t, ok)
+		require.Equal(t, "primary", fallback.From)
+	})
+
+	t.Run("returns a partial stream error after content", func(t *testing.T) {
+		t.Parallel()
+
+		cause := errors.NewProviderError("primary", stderrors.New("connection reset"))
+		primary := failingStream("primary", cause, roleChunk(), testutil.ContentChunk("", "Once upon"), testutil.ContentChunk("", " a time"))
+		secondary := testutil.NewMockProvider()
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(f.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.Equal(t, "Once upon a time", content)
+		require.Empty(t, secondary.CompletionStreamCalls)
+
+		var partialErr *PartialStreamError
+		require.ErrorAs(t, err, &partialErr)
+		require.Equal(t, "primary", partialErr.Provider)
+		require.Equal(t, "Once upon a time", partialErr.Partial.Choices[0].Message.ContentString())
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("returns the first error when every route fails", func(t *testing.T) {
+		t.Parallel()
+
+		primary := failingStream("primary", errors.NewProviderError("primary", stderrors.New("unavailable")))
+		secondary := failingStream("secondary", errors.NewRateLimitError("secondary", stderrors.New("slow down")))
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		_, err = testutil.Collect(f.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		var providerErr *errors.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, "primary", providerErr.Provider)
+	})
+
+	t.Run("stops on context errors", func(t *testing.T) {
+		t.Parallel()
+
+		primary, _ := stalledProvider()
+		secondary := testutil.NewMockProvider()
+		f, err := NewFallback(Route{Provider: primary}, Route{Provider: secondary})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), hedgeDelay)
+		defer cancel()
+
+		_, err = testutil.Collect(f.CompletionStream(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()}))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, secondary.CompletionStreamCalls)
+	})
+}
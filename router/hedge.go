@@ -0,0 +1,269 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+// defaultHedgeDelay is the hedge delay used when NewHedge is given a non-positive one.
+const defaultHedgeDelay = 2 * time.Second
+
+// hedgeName is the name a Hedge reports.
+const hedgeName = "hedge"
+
+// Ensure Hedge implements the required interfaces.
+var _ providers.Provider = (*Hedge)(nil)
+
+// Hedge sends each request to a primary route and, if it has not answered
+// within a delay, sends the same request to a secondary route as well. The
+// first successful response wins and the other request is cancelled. This
+// trades some duplicate spend for a lower tail latency.
+type Hedge struct {
+	delay  time.Duration
+	routes []Route
+}
+
+// completionResult is the outcome of one route's completion request.
+type completionResult struct {
+	err   error
+	route int
+	resp  *providers.ChatCompletion
+}
+
+// streamResult is the start of one route's stream: its first chunk, or how
+// it ended if it had none. A stream that ends without chunks or an error has
+// an error too, so that it cannot win the race.
+type streamResult struct {
+	chunks   <-chan providers.ChatCompletionChunk
+	err      error
+	errs     <-chan error
+	first    *providers.ChatCompletionChunk
+	provider string
+	route    int
+}
+
+// NewHedge returns a Hedge that sends requests to primary, and to secondary
+// once primary has not answered within delay. A non-positive delay uses two
+// seconds; set it near the primary's usual p95 latency, so that only slow
+// requests are hedged.
+func NewHedge(primary Route, secondary Route, delay time.Duration) *Hedge {
+	if delay <= 0 {
+		delay = defaultHedgeDelay
+	}
+
+	return &Hedge{
+		delay:  delay,
+		routes: []Route{primary, secondary},
+	}
+}
+
+// Completion performs a chat completion request, hedged as described on
+// Hedge. If the primary fails before the delay, the secondary is started at
+// once, and a telemetry.FallbackTriggered event is emitted. When both fail,
+// the primary's error is returned.
+func (h *Hedge) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Cancels the request that did not win.
+
+	results := make(chan completionResult, len(h.routes))
+	start := func(i int) {
+		route := h.routes[i]
+		go func() {
+			resp, err := route.Provider.Completion(ctx, route.params(params))
+			results <- completionResult{err: err, route: i, resp: resp}
+		}()
+	}
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	errs := make([]error, len(h.routes))
+	started, pending := 1, 1
+	start(0)
+
+	for {
+		select {
+		case <-timer.C:
+			if started < len(h.routes) {
+				start(started)
+				started++
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.resp, nil
+			}
+			errs[r.route] = r.err
+
+			switch {
+			case started < len(h.routes):
+				h.fallback(ctx, r.route, started, r.err)
+				start(started)
+				started++
+				pending++
+			case pending == 0:
+				return nil, errs[0]
+			default:
+				// Wait for the request still in flight.
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// CompletionStream performs a streaming chat completion request, hedged as
+// described on Hedge. The race is decided by the first chunk: the first
+// stream to deliver one is forwarded and the other is cancelled. A stream
+// that ends without chunks fails like one that returns an error. Errors
+// after that point are returned as a *PartialStreamError. When both streams
+// fail before their first chunk, the primary's error is returned.
+func (h *Hedge) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		cancels := make([]context.CancelFunc, len(h.routes))
+		defer func() {
+			for _, cancel := range cancels {
+				if cancel != nil {
+					cancel()
+				}
+			}
+		}()
+
+		results := make(chan streamResult, len(h.routes))
+		start := func(i int) {
+			var routeCtx context.Context
+			routeCtx, cancels[i] = context.WithCancel(ctx)
+			go h.startStream(routeCtx, i, params, results)
+		}
+
+		timer := time.NewTimer(h.delay)
+		defer timer.Stop()
+
+		errs := make([]error, len(h.routes))
+		started, pending := 1, 1
+		start(0)
+
+		for {
+			select {
+			case <-timer.C:
+				if started < len(h.routes) {
+					start(started)
+					started++
+					pending++
+				}
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					for i, cancel := range cancels {
+						if i != r.route && cancel != nil {
+							cancel()
+						}
+					}
+					if err := forwardStream(ctx, r, out); err != nil {
+						outErrs <- err
+					}
+					return
+				}
+				errs[r.route] = r.err
+
+				switch {
+				case started < len(h.routes):
+					h.fallback(ctx, r.route, started, r.err)
+					start(started)
+					started++
+					pending++
+				case pending == 0:
+					outErrs <- errs[0]
+					return
+				default:
+					// Wait for the stream still in flight.
+				}
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns "hedge", since responses may come from either route.
+func (h *Hedge) Name() string {
+	return hedgeName
+}
+
+// fallback reports to the telemetry subscribers in ctx that route from
+// failed and route to is started in its place.
+func (h *Hedge) fallback(ctx context.Context, from int, to int, err error) {
+	telemetry.Emit(ctx, telemetry.FallbackTriggered{
+		Err:  err,
+		From: h.routes[from].Provider.Name(),
+		Time: time.Now(),
+		To:   h.routes[to].Provider.Name(),
+	})
+}
+
+// startStream starts the stream for route i and reports its first chunk, or
+// how it ended if it had none, to results. A stream that ends without chunks
+// or an error is reported as a provider error.
+func (h *Hedge) startStream(
+	ctx context.Context,
+	i int,
+	params providers.CompletionParams,
+	results chan<- streamResult,
+) {
+	route := h.routes[i]
+	chunks, errs := route.Provider.CompletionStream(ctx, route.params(params))
+
+	r := streamResult{chunks: chunks, errs: errs, provider: route.Provider.Name(), route: i}
+	if chunk, ok := <-chunks; ok {
+		r.first = &chunk
+	} else if r.err = <-errs; r.err == nil {
+		r.err = errors.NewProviderError(r.provider, fmt.Errorf("stream ended without output"))
+	}
+	results <- r
+}
+
+// forwardStream forwards the winning stream r to out, starting with its first
+// chunk. It returns the stream's error, if any, as a *PartialStreamError.
+func forwardStream(ctx context.Context, r streamResult, out chan<- providers.ChatCompletionChunk) error {
+	fwd := &relay{provider: r.provider}
+	if r.first != nil {
+		if err := fwd.send(ctx, out, *r.first); err != nil {
+			return err
+		}
+	}
+
+	for chunk := range r.chunks {
+		if err := fwd.send(ctx, out, chunk); err != nil {
+			return err
+		}
+	}
+
+	if err := <-r.errs; err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fwd.fail(err)
+	}
+	return nil
+}
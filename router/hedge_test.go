@@ -0,0 +1,308 @@
+package router
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/telemetry"
+)
+
+// hedgeDelay keeps the tests fast while leaving the primary time to answer first.
+const hedgeDelay = 20 * time.Millisecond
+
+// stalledProvider returns a mock whose requests block until cancelled, and a
+// channel that is closed once one of them has been.
+func stalledProvider() (*testutil.MockProvider, <-chan struct{}) {
+	cancelled := make(chan struct{})
+	mock := testutil.NewMockProvider()
+
+	mock.CompletionFunc = func(ctx context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+	mock.CompletionStreamFunc = func(
+		ctx context.Context,
+		_ providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk)
+		errs := make(chan error, 1)
+		go func() {
+			defer close(chunks)
+			defer close(errs)
+			<-ctx.Done()
+			close(cancelled)
+			errs <- ctx.Err()
+		}()
+		return chunks, errs
+	}
+
+	return mock, cancelled
+}
+
+// streamOf returns a stream that delivers content as one chunk per string.
+func streamOf(content ...string) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	chunks := make(chan providers.ChatCompletionChunk, len(content))
+	errs := make(chan error)
+	for _, c := range content {
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: c}}},
+		}
+	}
+	close(chunks)
+	close(errs)
+	return chunks, errs
+}
+
+func TestNewHedge(t *testing.T) {
+	t.Parallel()
+
+	h := NewHedge(Route{Provider: testutil.NewMockProvider()}, Route{Provider: testutil.NewMockProvider()}, 0)
+	require.Equal(t, defaultHedgeDelay, h.delay)
+	require.Equal(t, "hedge", h.Name())
+}
+
+func TestHedgeCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the primary response without hedging", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		secondary := testutil.NewMockProvider()
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, time.Hour)
+
+		resp, err := h.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+		require.Empty(t, secondary.CompletionCalls)
+	})
+
+	t.Run("returns the secondary response and cancels a slow primary", func(t *testing.T) {
+		t.Parallel()
+
+		primary, cancelled := stalledProvider()
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return testutil.MockChatCompletion("from secondary"), nil
+		}
+		h := NewHedge(Route{Provider: primary}, Route{Model: "backup-model", Provider: secondary}, hedgeDelay)
+
+		resp, err := h.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			Model:    "main-model",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "from secondary", resp.Choices[0].Message.ContentString())
+		require.Equal(t, "backup-model", secondary.CompletionCalls[0].Model)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("primary request was not cancelled")
+		}
+	})
+
+	t.Run("starts the secondary at once when the primary fails", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("mock", stderrors.New("unavailable"))
+		}
+		h := NewHedge(Route{Provider: primary}, Route{Provider: testutil.NewMockProvider()}, time.Hour)
+
+		events := make(chan telemetry.Event, 1)
+		ctx := telemetry.NewContext(context.Background(), telemetry.Channel(events))
+
+		resp, err := h.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "Hello World", resp.Choices[0].Message.ContentString())
+
+		fallback, ok := (<-events).(telemetry.FallbackTriggered)
+		require.True(t, ok)
+		require.Equal(t, "mock", fallback.From)
+		require.Equal(t, "mock", fallback.To)
+		require.ErrorIs(t, fallback.Err, errors.ErrProvider)
+	})
+
+	t.Run("returns the primary error when both fail", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewProviderError("primary", stderrors.New("unavailable"))
+		}
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("secondary", stderrors.New("slow down"))
+		}
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, hedgeDelay)
+
+		_, err := h.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("returns context errors", func(t *testing.T) {
+		t.Parallel()
+
+		primary, _ := stalledProvider()
+		secondary, _ := stalledProvider()
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, hedgeDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*hedgeDelay)
+		defer cancel()
+
+		_, err := h.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestHedgeCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("forwards the primary stream without hedging", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf("Hello", ", world")
+		}
+		secondary := testutil.NewMockProvider()
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, time.Hour)
+
+		content, err := testutil.CollectContent(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world", content)
+		require.Empty(t, secondary.CompletionStreamCalls)
+	})
+
+	t.Run("forwards the secondary stream and cancels a slow primary", func(t *testing.T) {
+		t.Parallel()
+
+		primary, cancelled := stalledProvider()
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf("Backup", " answer")
+		}
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, hedgeDelay)
+
+		content, err := testutil.CollectContent(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Backup answer", content)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("primary stream was not cancelled")
+		}
+	})
+
+	t.Run("starts the secondary at once when the primary stream is empty", func(t *testing.T) {
+		t.Parallel()
+
+		primary := testutil.NewMockProvider()
+		primary.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf()
+		}
+		secondary := testutil.NewMockProvider()
+		secondary.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf("Backup", " answer")
+		}
+		h := NewHedge(Route{Provider: primary}, Route{Provider: secondary}, time.Hour)
+
+		content, err := testutil.CollectContent(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "Backup answer", content)
+	})
+
+	t.Run("returns a provider error when both streams are empty", func(t *testing.T) {
+		t.Parallel()
+
+		empty := testutil.NewMockProvider()
+		empty.CompletionStreamFunc = func(
+			context.Context,
+			providers.CompletionParams,
+		) (<-chan providers.ChatCompletionChunk, <-chan error) {
+			return streamOf()
+		}
+		h := NewHedge(Route{Provider: empty}, Route{Provider: empty}, hedgeDelay)
+
+		chunks, err := testutil.Collect(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.Empty(t, chunks)
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("returns a partial stream error after the first chunk", func(t *testing.T) {
+		t.Parallel()
+
+		cause := errors.NewProviderError("primary", stderrors.New("connection reset"))
+		primary := failingStream("primary", cause, testutil.ContentChunk("", "Hello"))
+		h := NewHedge(Route{Provider: primary}, Route{Provider: testutil.NewMockProvider()}, time.Hour)
+
+		content, err := testutil.CollectContent(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		require.Equal(t, "Hello", content)
+
+		var partialErr *PartialStreamError
+		require.ErrorAs(t, err, &partialErr)
+		require.Equal(t, "Hello", partialErr.Partial.Choices[0].Message.ContentString())
+		require.ErrorIs(t, err, errors.ErrProvider)
+	})
+
+	t.Run("returns the primary error when both fail", func(t *testing.T) {
+		t.Parallel()
+
+		failing := func(name string) *testutil.MockProvider {
+			mock := testutil.NewMockProvider()
+			mock.CompletionStreamFunc = func(
+				context.Context,
+				providers.CompletionParams,
+			) (<-chan providers.ChatCompletionChunk, <-chan error) {
+				chunks := make(chan providers.ChatCompletionChunk)
+				errs := make(chan error, 1)
+				errs <- errors.NewProviderError(name, stderrors.New("unavailable"))
+				close(chunks)
+				close(errs)
+				return chunks, errs
+			}
+			return mock
+		}
+		h := NewHedge(Route{Provider: failing("primary")}, Route{Provider: failing("secondary")}, hedgeDelay)
+
+		_, err := testutil.Collect(h.CompletionStream(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+		}))
+		var providerErr *errors.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, "primary", providerErr.Provider)
+	})
+}
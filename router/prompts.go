@@ -0,0 +1,198 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Ensure Prompts implements the required interfaces.
+var _ providers.Provider = (*Prompts)(nil)
+
+// Prompts samples one of several system prompt variants for each request, by
+// weight, for experiments with prompts. Responses carry the name of the
+// variant that was sent in their PromptVariant field.
+//
+// Like Split, Prompts sends requests whose context carries a sticky key (see
+// WithStickyKey) to the same variant. Other requests are assigned at random.
+// Prompts can be placed behind the arms of a Split to experiment with models
+// and prompts together.
+type Prompts struct {
+	provider providers.Provider
+	rand     func() float64
+	total    float64
+	variants []Variant
+}
+
+// Variant is one system prompt of a Prompts experiment.
+type Variant struct {
+	// Name identifies the variant in responses' PromptVariant field.
+	Name string
+
+	// Prompt replaces the request's system message, or is added as the first
+	// message when the request has none. An empty Prompt leaves the request
+	// as it is, for a control variant that keeps the application's prompt.
+	Prompt string
+
+	// Weight is the variant's share of the traffic, relative to the other
+	// variants' weights.
+	Weight float64
+}
+
+// NewPrompts returns a Prompts that sends requests to provider with one of
+// variants as the system prompt. Variants need distinct names and weights
+// that are not negative, and at least one weight must be positive.
+func NewPrompts(provider providers.Provider, variants ...Variant) (*Prompts, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("at least one variant is required")
+	}
+
+	var total float64
+	names := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		if v.Name == "" {
+			return nil, fmt.Errorf("variant %d has no name", i)
+		}
+		if names[v.Name] {
+			return nil, fmt.Errorf("variant names must differ, %q is used twice", v.Name)
+		}
+		names[v.Name] = true
+
+		if v.Weight < 0 || math.IsNaN(v.Weight) || math.IsInf(v.Weight, 0) {
+			return nil, fmt.Errorf("weight of variant %q must be a non-negative number, got %v", v.Name, v.Weight)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one variant needs a positive weight")
+	}
+
+	return &Prompts{
+		provider: provider,
+		rand:     rand.Float64,
+		total:    total,
+		variants: append([]Variant(nil), variants...),
+	}, nil
+}
+
+// Completion performs a chat completion request with the variant chosen for
+// ctx, and sets the response's PromptVariant field.
+func (p *Prompts) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	variant := p.variant(ctx)
+
+	resp, err := p.provider.Completion(ctx, variant.params(params))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.PromptVariant = variant.Name
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request with the
+// variant chosen for ctx, and sets the PromptVariant field of every chunk.
+func (p *Prompts) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	variant := p.variant(ctx)
+	chunks, errs := p.provider.CompletionStream(ctx, variant.params(params))
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for chunk := range chunks {
+			chunk.PromptVariant = variant.Name
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Prompts) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Prompts) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// variant returns the variant for a request with ctx. Sticky keys are hashed
+// together with every variant name, so that separate experiments assign keys
+// independently.
+func (p *Prompts) variant(ctx context.Context) Variant {
+	var roll float64
+	if key, ok := ctx.Value(stickyKey{}).(string); ok {
+		h := fnv.New64a()
+		for _, v := range p.variants {
+			_, _ = fmt.Fprintf(h, "%s\x00", v.Name) // Hash writes never fail.
+		}
+		_, _ = fmt.Fprint(h, key) // Hash writes never fail.
+		roll = float64(h.Sum64()%10000) / 10000
+	} else {
+		roll = p.rand()
+	}
+
+	target := roll * p.total
+	for _, v := range p.variants {
+		if target < v.Weight {
+			return v
+		}
+		target -= v.Weight
+	}
+
+	// Rounding can leave target just past the last weight; the last variant
+	// with a positive weight takes it.
+	for i := len(p.variants) - 1; i >= 0; i-- {
+		if p.variants[i].Weight > 0 {
+			return p.variants[i]
+		}
+	}
+	return p.variants[len(p.variants)-1]
+}
+
+// params returns params with the variant's prompt as the system message. The
+// messages are copied, so the caller's slice is not changed.
+func (v Variant) params(params providers.CompletionParams) providers.CompletionParams {
+	if v.Prompt == "" {
+		return params
+	}
+
+	system := providers.Message{Role: providers.RoleSystem, Content: v.Prompt}
+	if len(params.Messages) > 0 && params.Messages[0].Role == providers.RoleSystem {
+		messages := append([]providers.Message(nil), params.Messages...)
+		messages[0] = system
+		params.Messages = messages
+		return params
+	}
+
+	params.Messages = append([]providers.Message{system}, params.Messages...)
+	return params
+}
@@ -0,0 +1,236 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// newTestPrompts returns a Prompts over a mock with three variants weighted
+// 1:2:1, with the given random roll.
+func newTestPrompts(t *testing.T, roll float64) (*Prompts, *testutil.MockProvider) {
+	t.Helper()
+
+	mock := testutil.NewMockProvider()
+	p, err := NewPrompts(mock,
+		Variant{Name: "control", Weight: 1},
+		Variant{Name: "terse", Prompt: "Answer in one sentence.", Weight: 2},
+		Variant{Name: "friendly", Prompt: "Be warm and friendly.", Weight: 1},
+	)
+	require.NoError(t, err)
+	p.rand = func() float64 { return roll }
+
+	return p, mock
+}
+
+func TestNewPrompts(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+
+	tests := []struct {
+		name     string
+		provider providers.Provider
+		variants []Variant
+		wantErr  string
+	}{
+		{
+			name:     "requires a provider",
+			variants: []Variant{{Name: "a", Weight: 1}},
+			wantErr:  "provider is required",
+		},
+		{
+			name:     "requires variants",
+			provider: mock,
+			wantErr:  "at least one variant is required",
+		},
+		{
+			name:     "requires names",
+			provider: mock,
+			variants: []Variant{{Name: "a", Weight: 1}, {Weight: 1}},
+			wantErr:  "variant 1 has no name",
+		},
+		{
+			name:     "rejects duplicate names",
+			provider: mock,
+			variants: []Variant{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}},
+			wantErr:  `variant names must differ, "a" is used twice`,
+		},
+		{
+			name:     "rejects negative weights",
+			provider: mock,
+			variants: []Variant{{Name: "a", Weight: -1}},
+			wantErr:  `weight of variant "a" must be a non-negative number, got -1`,
+		},
+		{
+			name:     "rejects NaN weights",
+			provider: mock,
+			variants: []Variant{{Name: "a", Weight: math.NaN()}},
+			wantErr:  `weight of variant "a" must be a non-negative number, got NaN`,
+		},
+		{
+			name:     "requires a positive weight",
+			provider: mock,
+			variants: []Variant{{Name: "a"}, {Name: "b"}},
+			wantErr:  "at least one variant needs a positive weight",
+		},
+		{
+			name:     "accepts zero weights next to positive ones",
+			provider: mock,
+			variants: []Variant{{Name: "a", Weight: 1}, {Name: "b"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := NewPrompts(tc.provider, tc.variants...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "mock", p.Name())
+			require.Same(t, mock, p.Unwrap())
+		})
+	}
+}
+
+func TestPromptsCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("picks variants by weight", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			roll float64
+			want string
+		}{
+			{roll: 0, want: "control"},
+			{roll: 0.24, want: "control"},
+			{roll: 0.25, want: "terse"},
+			{roll: 0.74, want: "terse"},
+			{roll: 0.75, want: "friendly"},
+			{roll: 0.99, want: "friendly"},
+		}
+
+		for _, tc := range tests {
+			p, _ := newTestPrompts(t, tc.roll)
+
+			resp, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, resp.PromptVariant, "roll %v", tc.roll)
+		}
+	})
+
+	t.Run("replaces the system message", func(t *testing.T) {
+		t.Parallel()
+
+		p, mock := newTestPrompts(t, 0.5)
+		messages := []providers.Message{
+			{Role: providers.RoleSystem, Content: "You are helpful."},
+			{Role: providers.RoleUser, Content: "Hi"},
+		}
+
+		_, err := p.Completion(context.Background(), providers.CompletionParams{Messages: messages})
+		require.NoError(t, err)
+		require.Equal(t, []providers.Message{
+			{Role: providers.RoleSystem, Content: "Answer in one sentence."},
+			{Role: providers.RoleUser, Content: "Hi"},
+		}, mock.CompletionCalls[0].Messages)
+		require.Equal(t, "You are helpful.", messages[0].Content, "the caller's messages must not change")
+	})
+
+	t.Run("adds a system message when there is none", func(t *testing.T) {
+		t.Parallel()
+
+		p, mock := newTestPrompts(t, 0.9)
+
+		_, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		sent := mock.CompletionCalls[0].Messages
+		require.Len(t, sent, len(testutil.SimpleMessages())+1)
+		require.Equal(t, providers.Message{Role: providers.RoleSystem, Content: "Be warm and friendly."}, sent[0])
+	})
+
+	t.Run("leaves requests alone for variants without a prompt", func(t *testing.T) {
+		t.Parallel()
+
+		p, mock := newTestPrompts(t, 0)
+
+		_, err := p.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, testutil.SimpleMessages(), mock.CompletionCalls[0].Messages)
+	})
+
+	t.Run("keeps sticky keys on one variant", func(t *testing.T) {
+		t.Parallel()
+
+		p, _ := newTestPrompts(t, 0)
+		p.rand = func() float64 { panic("sticky requests must not roll") }
+
+		counts := map[string]int{}
+		for i := range 400 {
+			ctx := WithStickyKey(context.Background(), fmt.Sprintf("user-%d", i))
+
+			first, err := p.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+			second, err := p.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+
+			require.Equal(t, first.PromptVariant, second.PromptVariant)
+			counts[first.PromptVariant]++
+		}
+
+		// Keys are spread across the variants, roughly by weight.
+		require.InDelta(t, 100, counts["control"], 40)
+		require.InDelta(t, 200, counts["terse"], 50)
+		require.InDelta(t, 100, counts["friendly"], 40)
+	})
+
+	t.Run("tags responses behind a split", func(t *testing.T) {
+		t.Parallel()
+
+		p, _ := newTestPrompts(t, 0.5)
+		s, err := NewSplit(Arm{Route: Route{Provider: p}}, Arm{Route: Route{Provider: p}}, 0)
+		require.NoError(t, err)
+
+		resp, err := s.Completion(context.Background(), providers.CompletionParams{Messages: testutil.SimpleMessages()})
+		require.NoError(t, err)
+		require.Equal(t, "control", resp.Arm)
+		require.Equal(t, "terse", resp.PromptVariant)
+	})
+}
+
+func TestPromptsCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	p, mock := newTestPrompts(t, 0.5)
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return streamOf("Hello", ", world")
+	}
+
+	chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	})
+
+	var acc providers.Accumulator
+	var variants []string
+	for chunk := range chunks {
+		variants = append(variants, chunk.PromptVariant)
+		acc.Add(chunk)
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, []string{"terse", "terse"}, variants)
+	require.Equal(t, "terse", acc.Completion().PromptVariant)
+}
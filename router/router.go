@@ -0,0 +1,26 @@
+// Package router dispatches requests across several providers. Each routing
+// strategy is itself a providers.Provider, so routers can be wrapped and
+// nested like any other provider.
+package router
+
+import (
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Route is a provider that a router can send requests to.
+type Route struct {
+	// Model replaces the request's model when set, since model names
+	// usually differ between providers.
+	Model string
+
+	// Provider handles the requests sent to this route.
+	Provider providers.Provider
+}
+
+// params returns params with the route's model applied.
+func (r Route) params(params providers.CompletionParams) providers.CompletionParams {
+	if r.Model != "" {
+		params.Model = r.Model
+	}
+	return params
+}
@@ -0,0 +1,156 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Default arm names, used for arms without a name.
+const (
+	defaultControlName   = "control"
+	defaultTreatmentName = "treatment"
+)
+
+// splitName is the name a Split reports.
+const splitName = "split"
+
+// Ensure Split implements the required interfaces.
+var _ providers.Provider = (*Split)(nil)
+
+// Arm is one side of a Split experiment.
+type Arm struct {
+	// Name identifies the arm in responses' Arm field.
+	Name string
+
+	Route
+}
+
+// Split divides traffic between a control and a treatment arm by percentage,
+// for controlled experiments with models or providers. Responses carry the
+// name of the arm that served them in their Arm field.
+//
+// Requests whose context carries a sticky key (see WithStickyKey) always go
+// to the same arm, so that a user or conversation sees one model throughout.
+// Other requests are assigned at random.
+type Split struct {
+	control   Arm
+	percent   float64
+	rand      func() float64
+	treatment Arm
+}
+
+// stickyKey is the context key for the sticky key set by WithStickyKey.
+type stickyKey struct{}
+
+// NewSplit returns a Split that sends percent (between 0 and 100) of the
+// traffic to treatment and the rest to control. Arms without a name are
+// called "control" and "treatment".
+func NewSplit(control Arm, treatment Arm, percent float64) (*Split, error) {
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("percent must be between 0 and 100, got %v", percent)
+	}
+
+	if control.Name == "" {
+		control.Name = defaultControlName
+	}
+	if treatment.Name == "" {
+		treatment.Name = defaultTreatmentName
+	}
+	if control.Name == treatment.Name {
+		return nil, fmt.Errorf("arm names must differ, both are %q", control.Name)
+	}
+
+	return &Split{
+		control:   control,
+		percent:   percent,
+		rand:      rand.Float64,
+		treatment: treatment,
+	}, nil
+}
+
+// Completion performs a chat completion request on the arm chosen for ctx,
+// and sets the response's Arm field.
+func (s *Split) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	arm := s.arm(ctx)
+
+	resp, err := arm.Provider.Completion(ctx, arm.params(params))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Arm = arm.Name
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request on the arm
+// chosen for ctx, and sets the Arm field of every chunk.
+func (s *Split) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	arm := s.arm(ctx)
+	chunks, errs := arm.Provider.CompletionStream(ctx, arm.params(params))
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for chunk := range chunks {
+			chunk.Arm = arm.Name
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-errs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns "split", since responses may come from either arm.
+func (s *Split) Name() string {
+	return splitName
+}
+
+// arm returns the arm for a request with ctx. Sticky keys are hashed together
+// with both arm names, so that separate experiments assign keys independently.
+func (s *Split) arm(ctx context.Context) Arm {
+	var roll float64
+	if key, ok := ctx.Value(stickyKey{}).(string); ok {
+		h := fnv.New64a()
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s", s.control.Name, s.treatment.Name, key) // Hash writes never fail.
+		roll = float64(h.Sum64()%10000) / 10000
+	} else {
+		roll = s.rand()
+	}
+
+	if roll*100 < s.percent {
+		return s.treatment
+	}
+	return s.control
+}
+
+// WithStickyKey returns a copy of ctx carrying key, such as a user or
+// conversation ID. Split sends every request with the same key to the same
+// arm, as long as the arms' names stay the same. Raising the percentage only
+// moves keys from control to treatment.
+func WithStickyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, stickyKey{}, key)
+}
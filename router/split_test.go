@@ -0,0 +1,179 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// newTestSplit returns a Split between two mocks, with the given random roll.
+func newTestSplit(
+	t *testing.T,
+	percent float64,
+	roll float64,
+) (*Split, *testutil.MockProvider, *testutil.MockProvider) {
+	t.Helper()
+
+	control := testutil.NewMockProvider()
+	treatment := testutil.NewMockProvider()
+	s, err := NewSplit(
+		Arm{Name: "sonnet", Route: Route{Provider: control}},
+		Arm{Name: "haiku", Route: Route{Model: "claude-haiku", Provider: treatment}},
+		percent,
+	)
+	require.NoError(t, err)
+	s.rand = func() float64 { return roll }
+
+	return s, control, treatment
+}
+
+func TestNewSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		control   Arm
+		treatment Arm
+		percent   float64
+		wantErr   string
+	}{
+		{
+			name:    "rejects negative percentages",
+			percent: -1,
+			wantErr: "percent must be between 0 and 100, got -1",
+		},
+		{
+			name:    "rejects percentages over 100",
+			percent: 101,
+			wantErr: "percent must be between 0 and 100, got 101",
+		},
+		{
+			name:      "rejects duplicate arm names",
+			control:   Arm{Name: "a"},
+			treatment: Arm{Name: "a"},
+			percent:   50,
+			wantErr:   `arm names must differ, both are "a"`,
+		},
+		{
+			name:    "names unnamed arms",
+			percent: 50,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := NewSplit(tc.control, tc.treatment, tc.percent)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "control", s.control.Name)
+			require.Equal(t, "treatment", s.treatment.Name)
+			require.Equal(t, "split", s.Name())
+		})
+	}
+}
+
+func TestSplitCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("routes rolls below the percentage to treatment", func(t *testing.T) {
+		t.Parallel()
+
+		s, control, treatment := newTestSplit(t, 20, 0.1)
+
+		resp, err := s.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			Model:    "claude-sonnet",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "haiku", resp.Arm)
+		require.Empty(t, control.CompletionCalls)
+		require.Equal(t, "claude-haiku", treatment.CompletionCalls[0].Model)
+	})
+
+	t.Run("routes other rolls to control", func(t *testing.T) {
+		t.Parallel()
+
+		s, control, treatment := newTestSplit(t, 20, 0.2)
+
+		resp, err := s.Completion(context.Background(), providers.CompletionParams{
+			Messages: testutil.SimpleMessages(),
+			Model:    "claude-sonnet",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "sonnet", resp.Arm)
+		require.Equal(t, "claude-sonnet", control.CompletionCalls[0].Model)
+		require.Empty(t, treatment.CompletionCalls)
+	})
+
+	t.Run("keeps sticky keys on one arm", func(t *testing.T) {
+		t.Parallel()
+
+		s, _, _ := newTestSplit(t, 50, 0)
+		s.rand = func() float64 { panic("sticky requests must not roll") }
+
+		treated := 0
+		for i := range 200 {
+			ctx := WithStickyKey(context.Background(), fmt.Sprintf("user-%d", i))
+
+			first, err := s.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+			second, err := s.Completion(ctx, providers.CompletionParams{Messages: testutil.SimpleMessages()})
+			require.NoError(t, err)
+
+			require.Equal(t, first.Arm, second.Arm)
+			if first.Arm == "haiku" {
+				treated++
+			}
+		}
+
+		// Keys are spread across both arms, roughly by percentage.
+		require.InDelta(t, 100, treated, 30)
+	})
+
+	t.Run("only moves sticky keys to treatment when the percentage grows", func(t *testing.T) {
+		t.Parallel()
+
+		small, _, _ := newTestSplit(t, 10, 0)
+		large, _, _ := newTestSplit(t, 60, 0)
+
+		for i := range 200 {
+			ctx := WithStickyKey(context.Background(), fmt.Sprintf("user-%d", i))
+			if small.arm(ctx).Name == "haiku" {
+				require.Equal(t, "haiku", large.arm(ctx).Name)
+			}
+		}
+	})
+}
+
+func TestSplitCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	s, _, treatment := newTestSplit(t, 100, 0.5)
+	treatment.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return streamOf("Hello", ", world")
+	}
+
+	chunks, errs := s.CompletionStream(context.Background(), providers.CompletionParams{
+		Messages: testutil.SimpleMessages(),
+	})
+
+	var arms []string
+	for chunk := range chunks {
+		arms = append(arms, chunk.Arm)
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, []string{"haiku", "haiku"}, arms)
+}
@@ -0,0 +1,181 @@
+// Package speculative answers requests with a draft from a fast, cheap model,
+// such as a local one, and has a stronger model verify the draft. The draft is
+// served when the verifier approves it, and the verifier's correction
+// otherwise, so the stronger model only writes a full answer when the draft
+// falls short.
+package speculative
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Which completion was served.
+const (
+	// ServedDraft means the verifier approved the draft.
+	ServedDraft Served = "draft"
+
+	// ServedVerifier means the verifier corrected the draft, or answered
+	// itself because the draft request failed.
+	ServedVerifier Served = "verifier"
+)
+
+// approvalToken is the verifier's reply when the draft needs no changes.
+const approvalToken = "APPROVED"
+
+// defaultInstructions tells the verifier how to review the draft.
+const defaultInstructions = "Review the draft answer to the conversation above. " +
+	"If it is correct and complete, reply with exactly " + approvalToken + ". " +
+	"Otherwise reply with the corrected answer in full, written as the answer itself, " +
+	"without mentioning the draft."
+
+// draftFormat frames the draft answer and the instructions for the verifier.
+const draftFormat = "Draft answer:\n%s\n\n%s"
+
+// Option configures a Speculator.
+type Option func(*Speculator) error
+
+// Result holds the draft, the verification and which of them was served.
+type Result struct {
+	// Draft is the draft completion, or nil if the draft request failed.
+	Draft *providers.ChatCompletion
+
+	// DraftErr is the draft request error, if any.
+	DraftErr error
+
+	// Served is which completion answers the request.
+	Served Served
+
+	// Verification is the verifier's completion: its approval of the draft,
+	// its correction, or its own answer when the draft failed.
+	Verification *providers.ChatCompletion
+}
+
+// Served is which completion a Speculator served.
+type Served string
+
+// Speculator drafts answers with one model and verifies them with another.
+type Speculator struct {
+	draft        Target
+	instructions string
+	verifier     Target
+}
+
+// Target is a provider and the model to request from it.
+type Target struct {
+	// Model replaces the request's model when set.
+	Model string
+
+	// Provider handles the requests.
+	Provider providers.Provider
+}
+
+// New returns a Speculator that drafts answers with draft and verifies them
+// with verifier.
+func New(draft, verifier Target, opts ...Option) (*Speculator, error) {
+	if draft.Provider == nil {
+		return nil, fmt.Errorf("draft provider must not be nil")
+	}
+	if verifier.Provider == nil {
+		return nil, fmt.Errorf("verifier provider must not be nil")
+	}
+
+	s := &Speculator{
+		draft:        draft,
+		instructions: defaultInstructions,
+		verifier:     verifier,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// WithInstructions replaces the instructions the verifier reviews the draft
+// with. They must ask for exactly "APPROVED" when the draft needs no changes,
+// and for the corrected answer otherwise.
+func WithInstructions(instructions string) Option {
+	return func(s *Speculator) error {
+		if strings.TrimSpace(instructions) == "" {
+			return fmt.Errorf("instructions must not be empty")
+		}
+
+		s.instructions = instructions
+		return nil
+	}
+}
+
+// Complete drafts an answer to params and has the verifier check it. If the
+// draft request fails, the verifier answers params itself. It fails only if
+// the verifier request fails.
+func (s *Speculator) Complete(ctx context.Context, params providers.CompletionParams) (*Result, error) {
+	draft, draftErr := s.draft.Provider.Completion(ctx, s.draft.params(params))
+	if draftErr == nil && len(draft.Choices) == 0 {
+		draft, draftErr = nil, errors.NewProviderError(s.draft.Provider.Name(), fmt.Errorf("draft returned no choices"))
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if draftErr != nil {
+		resp, err := s.verifier.Provider.Completion(ctx, s.verifier.params(params))
+		if err != nil {
+			return nil, err
+		}
+		return &Result{DraftErr: draftErr, Served: ServedVerifier, Verification: resp}, nil
+	}
+
+	req := s.verifier.params(params)
+	req.Messages = append(slices.Clone(params.Messages), providers.Message{
+		Role:    providers.RoleUser,
+		Content: fmt.Sprintf(draftFormat, draft.Choices[0].Message.ContentString(), s.instructions),
+	})
+
+	resp, err := s.verifier.Provider.Completion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.NewProviderError(s.verifier.Provider.Name(), fmt.Errorf("verifier returned no choices"))
+	}
+
+	served := ServedVerifier
+	if approved(resp.Choices[0].Message.ContentString()) {
+		served = ServedDraft
+	}
+
+	return &Result{Draft: draft, Served: served, Verification: resp}, nil
+}
+
+// Answer returns the served completion.
+func (r *Result) Answer() *providers.ChatCompletion {
+	if r.Served == ServedDraft {
+		return r.Draft
+	}
+	return r.Verification
+}
+
+// params returns params with the target's model applied.
+func (t Target) params(params providers.CompletionParams) providers.CompletionParams {
+	if t.Model != "" {
+		params.Model = t.Model
+	}
+	return params
+}
+
+// approved reports whether a verifier reply approves the draft.
+func approved(reply string) bool {
+	return strings.EqualFold(strings.Trim(strings.TrimSpace(reply), ".!\"'`*"), approvalToken)
+}
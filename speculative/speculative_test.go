@@ -0,0 +1,152 @@
+package speculative
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// answering returns a mock provider that always answers with content and
+// records the requests it receives.
+func answering(content string, requests *[]providers.CompletionParams) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		if requests != nil {
+			*requests = append(*requests, params)
+		}
+		return testutil.MockChatCompletion(content), nil
+	}
+	return mock
+}
+
+// failing returns a mock provider whose requests fail with err.
+func failing(err error) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return nil, err
+	}
+	return mock
+}
+
+func testParams() providers.CompletionParams {
+	return providers.CompletionParams{
+		Model:    "requested",
+		Messages: []providers.Message{{Role: providers.RoleUser, Content: "What is 6 x 7?"}},
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+
+	_, err := New(Target{}, Target{Provider: mock})
+	require.Error(t, err)
+
+	_, err = New(Target{Provider: mock}, Target{})
+	require.Error(t, err)
+
+	_, err = New(Target{Provider: mock}, Target{Provider: mock}, WithInstructions(" "))
+	require.Error(t, err)
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serves an approved draft", func(t *testing.T) {
+		t.Parallel()
+
+		var verified []providers.CompletionParams
+		s, err := New(
+			Target{Model: "small", Provider: answering("42", nil)},
+			Target{Model: "large", Provider: answering("Approved.", &verified)},
+		)
+		require.NoError(t, err)
+
+		params := testParams()
+		result, err := s.Complete(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, ServedDraft, result.Served)
+		require.Equal(t, "42", result.Answer().Choices[0].Message.ContentString())
+
+		require.Len(t, verified, 1)
+		require.Equal(t, "large", verified[0].Model)
+		require.Len(t, verified[0].Messages, 2)
+		require.Contains(t, verified[0].Messages[1].ContentString(), "Draft answer:\n42")
+		require.Len(t, params.Messages, 1, "the request's messages must not change")
+	})
+
+	t.Run("serves the verifier's correction", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := New(
+			Target{Provider: answering("41", nil)},
+			Target{Provider: answering("6 x 7 is 42.", nil)},
+		)
+		require.NoError(t, err)
+
+		result, err := s.Complete(context.Background(), testParams())
+		require.NoError(t, err)
+		require.Equal(t, ServedVerifier, result.Served)
+		require.Equal(t, "41", result.Draft.Choices[0].Message.ContentString())
+		require.Equal(t, "6 x 7 is 42.", result.Answer().Choices[0].Message.ContentString())
+	})
+
+	t.Run("has the verifier answer when the draft fails", func(t *testing.T) {
+		t.Parallel()
+
+		draftErr := stderrors.New("draft server down")
+		var verified []providers.CompletionParams
+		s, err := New(
+			Target{Provider: failing(draftErr)},
+			Target{Provider: answering("42", &verified)},
+		)
+		require.NoError(t, err)
+
+		result, err := s.Complete(context.Background(), testParams())
+		require.NoError(t, err)
+		require.ErrorIs(t, result.DraftErr, draftErr)
+		require.Nil(t, result.Draft)
+		require.Equal(t, ServedVerifier, result.Served)
+		require.Equal(t, "42", result.Answer().Choices[0].Message.ContentString())
+		require.Equal(t, testParams().Messages, verified[0].Messages)
+	})
+
+	t.Run("fails when the verifier fails", func(t *testing.T) {
+		t.Parallel()
+
+		verifierErr := stderrors.New("verifier down")
+		s, err := New(Target{Provider: answering("42", nil)}, Target{Provider: failing(verifierErr)})
+		require.NoError(t, err)
+
+		_, err = s.Complete(context.Background(), testParams())
+		require.ErrorIs(t, err, verifierErr)
+	})
+}
+
+func TestApproved(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		reply    string
+		expected bool
+	}{
+		{reply: "APPROVED", expected: true},
+		{reply: " approved.\n", expected: true},
+		{reply: "**Approved**", expected: true},
+		{reply: "Not approved", expected: false},
+		{reply: "APPROVED, but 6 x 7 is 42", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.reply, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, approved(tc.reply))
+		})
+	}
+}
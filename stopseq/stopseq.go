@@ -0,0 +1,292 @@
+// Package stopseq wraps a provider so that stop sequences are handled the
+// same way whichever provider serves the request. Some providers, such as
+// TGI, return the matched stop sequence at the end of the content, while
+// most leave it out; stopseq makes the content either always exclude it, the
+// default, or always include it when the matched sequence is known.
+package stopseq
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Stop sequence modes.
+const (
+	// ModeExclude removes the matched stop sequence, and anything after it,
+	// from the content. It is the default.
+	ModeExclude Mode = "exclude"
+
+	// ModeInclude ends the content with the matched stop sequence when it is
+	// known: when the provider returned it in the content, or reported it in
+	// the choice's StopSequence.
+	ModeInclude Mode = "include"
+)
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Mode is whether content includes the stop sequence that ended it.
+type Mode string
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and normalizes stop sequences in its content.
+type Provider struct {
+	mode     Mode
+	provider providers.Provider
+}
+
+// matcher finds stop sequences in the text of one streamed choice.
+type matcher struct {
+	// held is text that could be the start of a stop sequence, held back
+	// until the next chunk shows whether it is.
+	held string
+
+	// matched is the stop sequence found in the text, after which all text
+	// is dropped.
+	matched string
+}
+
+// New wraps provider so that content excludes the stop sequence that ended
+// it, or includes it with WithMode(ModeInclude).
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{mode: ModeExclude, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithMode sets whether content includes the stop sequence that ended it.
+func WithMode(mode Mode) Option {
+	return func(p *Provider) error {
+		switch mode {
+		case ModeExclude, ModeInclude:
+			p.mode = mode
+			return nil
+		default:
+			return fmt.Errorf("unknown stop sequence mode %q", mode)
+		}
+	}
+}
+
+// Completion performs a chat completion request and normalizes the stop
+// sequence in each choice's content. A stop sequence found in the content is
+// recorded in the choice's StopSequence.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := p.provider.Completion(ctx, params)
+	if err != nil || len(params.Stop) == 0 {
+		return resp, err
+	}
+
+	for i, choice := range resp.Choices {
+		content, ok := choice.Message.Content.(string)
+		if !ok {
+			continue
+		}
+
+		if idx, stop := firstStop(content, params.Stop); idx >= 0 {
+			content = content[:idx]
+			resp.Choices[i].StopSequence = stop
+		}
+		if stop := resp.Choices[i].StopSequence; p.mode == ModeInclude && stop != "" {
+			content += stop
+		}
+		resp.Choices[i].Message.Content = content
+	}
+
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request and
+// normalizes the stop sequence in each choice's content. Text that could be
+// the start of a stop sequence is held back until the next chunk shows
+// whether it is, so it may arrive one chunk late. If the stream ends without
+// a finish chunk, or fails, text still held back is sent in a last chunk
+// before the error.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	in, inErrs := p.provider.CompletionStream(ctx, params)
+	if len(params.Stop) == 0 {
+		return in, inErrs
+	}
+
+	out := make(chan providers.ChatCompletionChunk)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		matchers := make(map[int]*matcher)
+		var last providers.ChatCompletionChunk
+		for chunk := range in {
+			last = chunk
+			chunk.Choices = append([]providers.ChunkChoice(nil), chunk.Choices...)
+			for i, choice := range chunk.Choices {
+				m, ok := matchers[choice.Index]
+				if !ok {
+					m = &matcher{}
+					matchers[choice.Index] = m
+				}
+				chunk.Choices[i] = p.process(m, choice, params.Stop)
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if held := heldChunk(last, matchers); held != nil {
+			select {
+			case out <- *held:
+			case <-ctx.Done():
+				outErrs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-inErrs; err != nil {
+			outErrs <- err
+		}
+	}()
+
+	return out, outErrs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// process returns choice with its text replaced by the text m releases. When
+// the choice finishes, the held text is released, followed by the stop
+// sequence in ModeInclude.
+func (p *Provider) process(m *matcher, choice providers.ChunkChoice, stops []string) providers.ChunkChoice {
+	text := m.feed(choice.Delta.Content, stops)
+
+	if choice.FinishReason != "" {
+		if m.matched != "" {
+			choice.StopSequence = m.matched
+		} else {
+			text += m.held
+		}
+		m.held = ""
+
+		if p.mode == ModeInclude && choice.StopSequence != "" {
+			text += choice.StopSequence
+		}
+	}
+
+	choice.Delta.Content = text
+	return choice
+}
+
+// feed adds text to the stream and returns the text that can be delivered:
+// everything before a stop sequence, less any ending that could be the start
+// of one.
+func (m *matcher) feed(text string, stops []string) string {
+	if m.matched != "" || text == "" {
+		return ""
+	}
+
+	text = m.held + text
+	if idx, stop := firstStop(text, stops); idx >= 0 {
+		m.held = ""
+		m.matched = stop
+		return text[:idx]
+	}
+
+	n := partialStop(text, stops)
+	m.held = text[len(text)-n:]
+	return text[:len(text)-n]
+}
+
+// firstStop returns the index of the first stop sequence in s and the
+// sequence, or -1 if s contains none. Of sequences starting at the same
+// index, the longest is returned.
+func firstStop(s string, stops []string) (int, string) {
+	first, match := -1, ""
+	for _, stop := range stops {
+		if stop == "" {
+			continue
+		}
+
+		idx := strings.Index(s, stop)
+		if idx < 0 {
+			continue
+		}
+		if first < 0 || idx < first || (idx == first && len(stop) > len(match)) {
+			first, match = idx, stop
+		}
+	}
+
+	return first, match
+}
+
+// heldChunk returns a chunk like last that releases the text matchers still
+// hold, by choice index, or nil if they hold none.
+func heldChunk(last providers.ChatCompletionChunk, matchers map[int]*matcher) *providers.ChatCompletionChunk {
+	var choices []providers.ChunkChoice
+	for _, index := range slices.Sorted(maps.Keys(matchers)) {
+		m := matchers[index]
+		if m.held == "" {
+			continue
+		}
+		choices = append(choices, providers.ChunkChoice{Index: index, Delta: providers.ChunkDelta{Content: m.held}})
+		m.held = ""
+	}
+	if len(choices) == 0 {
+		return nil
+	}
+
+	return &providers.ChatCompletionChunk{
+		Choices: choices,
+		Created: last.Created,
+		ID:      last.ID,
+		Model:   last.Model,
+		Object:  last.Object,
+	}
+}
+
+// partialStop returns the length of the longest ending of s that is the start
+// of a stop sequence.
+func partialStop(s string, stops []string) int {
+	longest := 0
+	for _, stop := range stops {
+		for n := min(len(s), len(stop)-1); n > longest; n-- {
+			if strings.HasSuffix(s, stop[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+
+	return longest
+}
@@ -0,0 +1,238 @@
+package stopseq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// completing returns a mock provider that completes with content and the
+// stop sequence it reports.
+func completing(content, reported string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp := testutil.MockChatCompletion(content)
+		resp.Choices[0].StopSequence = reported
+		return resp, nil
+	}
+	return mock
+}
+
+// streaming returns a mock provider that streams deltas, then a finish chunk
+// reporting the stop sequence.
+func streaming(reported string, deltas ...string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		out := make(chan providers.ChatCompletionChunk, len(deltas)+1)
+		errs := make(chan error)
+		for _, delta := range deltas {
+			out <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{
+				Delta: providers.ChunkDelta{Content: delta},
+			}}}
+		}
+		out <- providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{
+			FinishReason: providers.FinishReasonStop,
+			StopSequence: reported,
+		}}}
+		close(out)
+		close(errs)
+		return out, errs
+	}
+	return mock
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(testutil.NewMockProvider(), nil)
+	require.NoError(t, err)
+	require.Equal(t, ModeExclude, p.mode)
+	require.Equal(t, "mock", p.Name())
+	require.NotNil(t, p.Unwrap())
+
+	p, err = New(testutil.NewMockProvider(), WithMode(ModeInclude))
+	require.NoError(t, err)
+	require.Equal(t, ModeInclude, p.mode)
+
+	_, err = New(testutil.NewMockProvider(), WithMode("trim"))
+	require.Error(t, err)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	stops := []string{"\nUser:", "END"}
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		content  string
+		reported string
+		want     string
+		wantStop string
+	}{
+		{name: "exclude echoed", mode: ModeExclude, content: "Hi.\nUser:", want: "Hi.", wantStop: "\nUser:"},
+		{name: "exclude text after", mode: ModeExclude, content: "Hi.END more", want: "Hi.", wantStop: "END"},
+		{name: "exclude reported", mode: ModeExclude, content: "Hi.", reported: "END", want: "Hi.", wantStop: "END"},
+		{name: "exclude unknown", mode: ModeExclude, content: "Hi.", want: "Hi."},
+		{name: "include echoed", mode: ModeInclude, content: "Hi.\nUser:", want: "Hi.\nUser:", wantStop: "\nUser:"},
+		{name: "include reported", mode: ModeInclude, content: "Hi.", reported: "END", want: "Hi.END", wantStop: "END"},
+		{name: "include unknown", mode: ModeInclude, content: "Hi.", want: "Hi."},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(completing(tc.content, tc.reported), WithMode(tc.mode))
+			require.NoError(t, err)
+
+			resp, err := p.Completion(context.Background(), providers.CompletionParams{Stop: stops})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, resp.Choices[0].Message.ContentString())
+			require.Equal(t, tc.wantStop, resp.Choices[0].StopSequence)
+		})
+	}
+
+	t.Run("leaves requests without stop sequences alone", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New(completing("Hi.END", ""))
+		require.NoError(t, err)
+
+		resp, err := p.Completion(context.Background(), providers.CompletionParams{})
+		require.NoError(t, err)
+		require.Equal(t, "Hi.END", resp.Choices[0].Message.ContentString())
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	stops := []string{"\nUser:", "END"}
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		deltas   []string
+		reported string
+		want     string
+		wantStop string
+	}{
+		{
+			name:     "exclude echoed across chunks",
+			mode:     ModeExclude,
+			deltas:   []string{"Hi.", "\nUs", "er:", " ignored"},
+			want:     "Hi.",
+			wantStop: "\nUser:",
+		},
+		{
+			// "\nUs" is held back until "ing" rules out "\nUser:", and "EN"
+			// until "D" completes "END".
+			name:     "releases held text that is not a stop sequence",
+			mode:     ModeExclude,
+			deltas:   []string{"Hi.\n", "Us", "ing EN", "D", "ings"},
+			want:     "Hi.\nUsing ",
+			wantStop: "END",
+		},
+		{
+			name:   "releases held text at the end",
+			mode:   ModeExclude,
+			deltas: []string{"Hi.\nUs"},
+			want:   "Hi.\nUs",
+		},
+		{
+			name:     "include echoed",
+			mode:     ModeInclude,
+			deltas:   []string{"Hi.", "EN", "D"},
+			want:     "Hi.END",
+			wantStop: "END",
+		},
+		{
+			name:     "include reported",
+			mode:     ModeInclude,
+			deltas:   []string{"Hi."},
+			reported: "\nUser:",
+			want:     "Hi.\nUser:",
+			wantStop: "\nUser:",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(streaming(tc.reported, tc.deltas...), WithMode(tc.mode))
+			require.NoError(t, err)
+
+			chunks, errs := p.CompletionStream(context.Background(), providers.CompletionParams{Stop: stops})
+			var content strings.Builder
+			var last providers.ChunkChoice
+			for chunk := range chunks {
+				content.WriteString(chunk.Choices[0].Delta.Content)
+				last = chunk.Choices[0]
+			}
+			require.NoError(t, <-errs)
+			require.Equal(t, tc.want, content.String())
+			require.Equal(t, providers.FinishReasonStop, last.FinishReason)
+			require.Equal(t, tc.wantStop, last.StopSequence)
+		})
+	}
+}
+
+func TestCompletionStreamWithoutFinish(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{Stop: []string{"\nUser:"}}
+
+	t.Run("releases held text when the stream just ends", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(testutil.ScriptedStream{Chunks: []providers.ChatCompletionChunk{
+			testutil.ContentChunk("c1", "Hi."),
+			testutil.ContentChunk("c1", "\nUs"),
+		}})
+		p, err := New(mock)
+		require.NoError(t, err)
+
+		got, err := testutil.Collect(p.CompletionStream(context.Background(), params))
+		require.NoError(t, err)
+		require.Equal(t, "Hi.\nUs", testutil.ChunksContent(got))
+		require.Equal(t, "c1", got[len(got)-1].ID)
+	})
+
+	t.Run("releases held text before the error", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.ScriptedStreams(testutil.ScriptedStream{
+			Chunks: []providers.ChatCompletionChunk{testutil.ContentChunk("c1", "Hi.\nUs")},
+			Err:    errors.New("connection reset"),
+		})
+		p, err := New(mock)
+		require.NoError(t, err)
+
+		content, err := testutil.CollectContent(p.CompletionStream(context.Background(), params))
+		require.EqualError(t, err, "connection reset")
+		require.Equal(t, "Hi.\nUs", content)
+	})
+}
+
+func TestPartialStop(t *testing.T) {
+	t.Parallel()
+
+	stops := []string{"\nUser:", "END"}
+	require.Equal(t, 0, partialStop("Hi.", stops))
+	require.Equal(t, 1, partialStop("Hi.\n", stops))
+	require.Equal(t, 3, partialStop("Hi.\nUs", stops))
+	require.Equal(t, 2, partialStop("THE EN", stops))
+	require.Equal(t, 0, partialStop("", stops))
+}
@@ -0,0 +1,271 @@
+// Package structured wraps a provider to pick the best structured output
+// mechanism it supports. A request with a JSON schema response format is sent
+// with the schema when the provider accepts one, in JSON mode with the schema
+// in the system prompt when it only has JSON mode, and with the schema in the
+// prompt alone otherwise. Responses record the mechanism used in their
+// ResponseFormatMode field.
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Response format modes, from the most to the least reliable. Responses
+// record the mode their request was sent with in ResponseFormatMode.
+const (
+	// ModeJSONSchema sends the schema as the response format.
+	ModeJSONSchema = "json_schema"
+
+	// ModeJSONObject sends a JSON object response format, with the schema in
+	// the system prompt.
+	ModeJSONObject = "json_object"
+
+	// ModePrompt sends no response format, and asks for JSON in the system
+	// prompt.
+	ModePrompt = "prompt"
+)
+
+// Prompt text.
+const (
+	instructions       = "Respond with only a JSON object, with no other text and no code block."
+	schemaInstructions = "Respond with only a JSON object that matches the following JSON schema, " +
+		"with no other text and no code block:\n\n%s"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// modeRank orders the modes, most reliable first.
+var modeRank = map[string]int{ModeJSONSchema: 0, ModeJSONObject: 1, ModePrompt: 2}
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and picks the structured output mechanism for
+// each request.
+type Provider struct {
+	maxMode  string
+	provider providers.Provider
+}
+
+// New wraps provider so that requests with a JSON response format are sent
+// with the most reliable mechanism it supports, according to Capabilities.
+// Providers that do not report capabilities are assumed to support JSON
+// schemas.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{maxMode: ModeJSONSchema, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithMaxMode caps the mechanism at mode, for models that report support for
+// a response format but follow it poorly. With ModePrompt, requests are always
+// sent without a response format.
+func WithMaxMode(mode string) Option {
+	return func(p *Provider) error {
+		if _, ok := modeRank[mode]; !ok {
+			return fmt.Errorf("unknown response format mode %q", mode)
+		}
+
+		p.maxMode = mode
+		return nil
+	}
+}
+
+// Capabilities returns the wrapped provider's capabilities, with JSON object
+// and JSON schema response formats.
+func (p *Provider) Capabilities() providers.Capabilities {
+	caps := providers.Capabilities{Completion: true, CompletionStreaming: true}
+	if cp, ok := providers.As[providers.CapabilityProvider](p.provider); ok {
+		caps = cp.Capabilities()
+	}
+
+	caps.CompletionJSONObject = true
+	caps.CompletionJSONSchema = true
+	return caps
+}
+
+// Completion performs a chat completion request, sending its response format
+// with the best supported mechanism. With ModePrompt, a reply wrapped in a
+// Markdown code block is unwrapped.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	mode := p.mode(params.ResponseFormat)
+	if mode == "" {
+		return p.provider.Completion(ctx, params)
+	}
+
+	resp, err := p.provider.Completion(ctx, convertParams(params, mode))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.ResponseFormatMode = mode
+	if mode == ModePrompt {
+		for i := range resp.Choices {
+			msg := &resp.Choices[i].Message
+			if !msg.IsMultiModal() {
+				msg.Content = unwrapCodeBlock(msg.ContentString())
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// CompletionStream performs a streaming chat completion request, sending its
+// response format with the best supported mechanism, and sets the
+// ResponseFormatMode field of every chunk. Streamed content is passed through
+// as it is, code blocks included.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	mode := p.mode(params.ResponseFormat)
+	if mode == "" {
+		return p.provider.CompletionStream(ctx, params)
+	}
+
+	in, inErrs := p.provider.CompletionStream(ctx, convertParams(params, mode))
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		for chunk := range in {
+			chunk.ResponseFormatMode = mode
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-inErrs; err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// mode returns the mode to send format with, or "" if format does not ask
+// for JSON.
+func (p *Provider) mode(format *providers.ResponseFormat) string {
+	if format == nil {
+		return ""
+	}
+
+	var mode string
+	switch format.Type {
+	case ModeJSONSchema:
+		if format.JSONSchema == nil {
+			return ""
+		}
+		mode = ModeJSONSchema
+	case ModeJSONObject:
+		mode = ModeJSONObject
+	default:
+		return ""
+	}
+
+	if cp, ok := providers.As[providers.CapabilityProvider](p.provider); ok {
+		caps := cp.Capabilities()
+		if mode == ModeJSONSchema && !caps.CompletionJSONSchema {
+			mode = ModeJSONObject
+		}
+		if mode == ModeJSONObject && !caps.CompletionJSONObject {
+			mode = ModePrompt
+		}
+	}
+
+	if modeRank[mode] < modeRank[p.maxMode] {
+		return p.maxMode
+	}
+	return mode
+}
+
+// convertParams returns params with its response format sent in mode. Modes
+// other than ModeJSONSchema describe the expected output in the system prompt.
+func convertParams(params providers.CompletionParams, mode string) providers.CompletionParams {
+	format := params.ResponseFormat
+
+	switch mode {
+	case ModeJSONSchema:
+		return params
+	case ModeJSONObject:
+		params.ResponseFormat = &providers.ResponseFormat{Type: ModeJSONObject}
+	case ModePrompt:
+		params.ResponseFormat = nil
+	default:
+	}
+
+	prompt := instructions
+	if format.JSONSchema != nil {
+		schema, err := json.MarshalIndent(format.JSONSchema.Schema, "", "  ")
+		if err == nil {
+			prompt = fmt.Sprintf(schemaInstructions, schema)
+		}
+	}
+
+	if len(params.Messages) > 0 && params.Messages[0].Role == providers.RoleSystem &&
+		!params.Messages[0].IsMultiModal() {
+		system := params.Messages[0]
+		system.Content = system.ContentString() + "\n\n" + prompt
+		params.Messages = append([]providers.Message{system}, params.Messages[1:]...)
+	} else {
+		system := providers.Message{Role: providers.RoleSystem, Content: prompt}
+		params.Messages = append([]providers.Message{system}, params.Messages...)
+	}
+
+	return params
+}
+
+// unwrapCodeBlock returns text without the Markdown code block around it, if
+// it is one.
+func unwrapCodeBlock(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return text
+	}
+
+	body := strings.TrimSuffix(trimmed[3:], "```")
+	if i := strings.IndexByte(body, '\n'); i >= 0 && !strings.ContainsAny(body[:i], "{[") {
+		body = body[i+1:]
+	}
+	return strings.TrimSpace(body)
+}
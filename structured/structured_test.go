@@ -0,0 +1,230 @@
+package structured
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// supporting returns a mock provider that answers with content and reports
+// support for the given response formats.
+func supporting(content string, jsonObject bool, jsonSchema bool) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+		return testutil.MockChatCompletion(content), nil
+	}
+	mock.CapabilitiesFunc = func() providers.Capabilities {
+		return providers.Capabilities{
+			Completion:           true,
+			CompletionJSONObject: jsonObject,
+			CompletionJSONSchema: jsonSchema,
+			CompletionStreaming:  true,
+		}
+	}
+	return mock
+}
+
+// schemaParams returns params asking for a JSON schema response format.
+func schemaParams() providers.CompletionParams {
+	return providers.CompletionParams{
+		Model:    "model",
+		Messages: testutil.MessagesWithSystem(),
+		ResponseFormat: &providers.ResponseFormat{
+			Type: ModeJSONSchema,
+			JSONSchema: &providers.JSONSchema{
+				Name: "answer",
+				Schema: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				},
+			},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(testutil.NewMockProvider(), nil, WithMaxMode(ModePrompt))
+	require.NoError(t, err)
+	require.Equal(t, ModePrompt, provider.maxMode)
+	require.Equal(t, "mock", provider.Name())
+
+	_, err = New(testutil.NewMockProvider(), WithMaxMode("grammar"))
+	require.Error(t, err)
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(testutil.NewMockProvider())
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+	require.True(t, caps.CompletionJSONObject)
+	require.True(t, caps.CompletionJSONSchema)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sends the schema when supported", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting(`{"city":"Paris"}`, true, true)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		params := schemaParams()
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, ModeJSONSchema, resp.ResponseFormatMode)
+		require.Equal(t, params, mock.CompletionCalls[0])
+	})
+
+	t.Run("falls back to JSON mode with the schema in the prompt", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting(`{"city":"Paris"}`, true, false)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		params := schemaParams()
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, ModeJSONObject, resp.ResponseFormatMode)
+
+		sent := mock.CompletionCalls[0]
+		require.Equal(t, &providers.ResponseFormat{Type: ModeJSONObject}, sent.ResponseFormat)
+		require.Len(t, sent.Messages, len(params.Messages))
+		require.Contains(t, sent.Messages[0].ContentString(), params.Messages[0].ContentString())
+		require.Contains(t, sent.Messages[0].ContentString(), `"city"`)
+		require.Equal(t, testutil.MessagesWithSystem(), params.Messages, "params must not be mutated")
+	})
+
+	t.Run("falls back to the prompt and unwraps code blocks", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting("```json\n{\"city\":\"Paris\"}\n```", false, false)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), schemaParams())
+		require.NoError(t, err)
+		require.Equal(t, ModePrompt, resp.ResponseFormatMode)
+		require.Equal(t, `{"city":"Paris"}`, resp.Choices[0].Message.ContentString())
+		require.Nil(t, mock.CompletionCalls[0].ResponseFormat)
+		require.Contains(t, mock.CompletionCalls[0].Messages[0].ContentString(), `"city"`)
+	})
+
+	t.Run("caps the mode", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting(`{}`, true, true)
+		provider, err := New(mock, WithMaxMode(ModeJSONObject))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), schemaParams())
+		require.NoError(t, err)
+		require.Equal(t, ModeJSONObject, resp.ResponseFormatMode)
+	})
+
+	t.Run("prepends a system prompt for JSON mode requests", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting(`{}`, false, false)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		params := providers.CompletionParams{
+			Model:          "model",
+			Messages:       testutil.SimpleMessages(),
+			ResponseFormat: &providers.ResponseFormat{Type: ModeJSONObject},
+		}
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, ModePrompt, resp.ResponseFormatMode)
+
+		sent := mock.CompletionCalls[0]
+		require.Len(t, sent.Messages, len(params.Messages)+1)
+		require.Equal(t, providers.Message{Role: providers.RoleSystem, Content: instructions}, sent.Messages[0])
+	})
+
+	t.Run("passes other requests through", func(t *testing.T) {
+		t.Parallel()
+
+		mock := supporting("Hello", false, false)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		params := providers.CompletionParams{Model: "model", Messages: testutil.SimpleMessages()}
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Empty(t, resp.ResponseFormatMode)
+		require.Equal(t, params, mock.CompletionCalls[0])
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := supporting("", true, false)
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 2)
+		errs := make(chan error, 1)
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: `{"city":`}}},
+		}
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: `"Paris"}`}}},
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	provider, err := New(mock)
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), schemaParams())
+	var acc providers.Accumulator
+	for chunk := range chunks {
+		require.Equal(t, ModeJSONObject, chunk.ResponseFormatMode)
+		acc.Add(chunk)
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, ModeJSONObject, acc.Completion().ResponseFormatMode)
+	require.Equal(t, ModeJSONObject, mock.CompletionStreamCalls[0].ResponseFormat.Type)
+}
+
+func TestUnwrapCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "json block", text: "```json\n{\"a\": 1}\n```", want: `{"a": 1}`},
+		{name: "bare block", text: "```\n{\"a\": 1}\n```", want: `{"a": 1}`},
+		{name: "one line block", text: "```{\"a\": 1}```", want: `{"a": 1}`},
+		{name: "plain JSON", text: ` {"a": 1} `, want: ` {"a": 1} `},
+		{name: "text around a block", text: "Here:\n```json\n{}\n```", want: "Here:\n```json\n{}\n```"},
+		{name: "fence only", text: "```", want: "```"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, unwrapCodeBlock(tc.text))
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package summarize summarizes documents of any length with map-reduce: the
+// text is split into chunks that fit the model's context window, the chunks
+// are summarized in parallel, and the summaries are combined, in as many
+// rounds as it takes, into one.
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/bulk"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/tokencount"
+)
+
+// Summarizer defaults.
+const (
+	defaultConcurrency      = 4
+	defaultContextWindow    = 8192
+	defaultMaxSummaryTokens = 512
+
+	// promptMargin is the share of the context window left free, since token
+	// counts of chunks are summed and chat templates add tokens of their own.
+	promptMargin = 0.1
+)
+
+// Default prompts.
+const (
+	DefaultMapPrompt = "Summarize the following text. Keep every key fact, figure and name, " +
+		"and leave out anything else. Reply with the summary only."
+	DefaultReducePrompt = "The following are summaries of consecutive parts of one document. " +
+		"Combine them into a single summary of the whole document, without repeating yourself. " +
+		"Reply with the summary only."
+)
+
+// separators are tried in order to split text that does not fit a chunk.
+var separators = []string{"\n\n", "\n", ". ", " "}
+
+// Option configures a Summarizer.
+type Option func(*Summarizer) error
+
+// Result is a summary and what it took to produce it.
+type Result struct {
+	// Calls is the number of completion requests made.
+	Calls int
+
+	// Chunks is the number of chunks the text was split into.
+	Chunks int
+
+	// Rounds is the number of rounds of summarization, including the first.
+	Rounds int
+
+	// Summary is the summary of the whole text.
+	Summary string
+
+	// Usage is the total usage of all requests.
+	Usage providers.Usage
+}
+
+// Summarizer summarizes documents with map-reduce.
+type Summarizer struct {
+	concurrency      int
+	contextWindow    int
+	counter          *tokencount.Counter
+	mapPrompt        string
+	maxSummaryTokens int
+	model            string
+	provider         providers.Provider
+	reducePrompt     string
+}
+
+// piece is a span of text with its token count.
+type piece struct {
+	text   string
+	tokens int
+}
+
+// New returns a Summarizer that uses model on provider. By default it assumes
+// an 8192-token context window, asks for summaries of up to 512 tokens and
+// runs four requests at a time. Token counts come from the provider's
+// tokenizer when it implements providers.TokenCounter, and are estimated
+// otherwise.
+func New(provider providers.Provider, model string, opts ...Option) (*Summarizer, error) {
+	counter, err := tokencount.New(provider)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summarizer{
+		concurrency:      defaultConcurrency,
+		contextWindow:    defaultContextWindow,
+		counter:          counter,
+		mapPrompt:        DefaultMapPrompt,
+		maxSummaryTokens: defaultMaxSummaryTokens,
+		model:            model,
+		provider:         provider,
+		reducePrompt:     DefaultReducePrompt,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.chunkTokens() <= 0 {
+		return nil, fmt.Errorf("context window of %d tokens leaves no room for text after %d summary tokens",
+			s.contextWindow, s.maxSummaryTokens)
+	}
+
+	return s, nil
+}
+
+// WithConcurrency sets how many requests run at the same time.
+func WithConcurrency(n int) Option {
+	return func(s *Summarizer) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", n)
+		}
+		s.concurrency = n
+		return nil
+	}
+}
+
+// WithContextWindow sets the model's context window in tokens, which bounds
+// the size of each chunk.
+func WithContextWindow(tokens int) Option {
+	return func(s *Summarizer) error {
+		if tokens <= 0 {
+			return fmt.Errorf("context window must be positive, got %d", tokens)
+		}
+		s.contextWindow = tokens
+		return nil
+	}
+}
+
+// WithMaxSummaryTokens sets the maximum length of each summary in tokens.
+func WithMaxSummaryTokens(tokens int) Option {
+	return func(s *Summarizer) error {
+		if tokens <= 0 {
+			return fmt.Errorf("max summary tokens must be positive, got %d", tokens)
+		}
+		s.maxSummaryTokens = tokens
+		return nil
+	}
+}
+
+// WithPrompts sets the system prompts for summarizing chunks of the text
+// (mapPrompt) and for combining summaries (reducePrompt).
+func WithPrompts(mapPrompt string, reducePrompt string) Option {
+	return func(s *Summarizer) error {
+		if mapPrompt == "" || reducePrompt == "" {
+			return fmt.Errorf("prompts must not be empty")
+		}
+		s.mapPrompt = mapPrompt
+		s.reducePrompt = reducePrompt
+		return nil
+	}
+}
+
+// Summarize summarizes text. Text that fits in one chunk takes a single
+// request; longer text is split into chunks, which are summarized in
+// parallel, and the summaries are combined in further rounds until one is
+// left.
+func (s *Summarizer) Summarize(ctx context.Context, text string) (*Result, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.NewInvalidRequestError(s.provider.Name(), fmt.Errorf("text to summarize is empty"))
+	}
+
+	chunks := s.split(ctx, text)
+	result := &Result{Chunks: len(chunks)}
+	prompt := s.mapPrompt
+	for {
+		result.Rounds++
+		summaries, err := s.summarizeAll(ctx, prompt, chunks, result)
+		if err != nil {
+			return nil, err
+		}
+		if len(summaries) == 1 {
+			result.Summary = summaries[0]
+			return result, nil
+		}
+
+		next := s.group(ctx, summaries)
+		if len(next) == len(summaries) {
+			return nil, fmt.Errorf("summaries of %d tokens each do not fit the context window in pairs; "+
+				"lower the max summary tokens", s.maxSummaryTokens)
+		}
+		chunks = next
+		prompt = s.reducePrompt
+	}
+}
+
+// chunkTokens returns the maximum size of a chunk in tokens.
+func (s *Summarizer) chunkTokens() int {
+	return int(float64(s.contextWindow)*(1-promptMargin)) - s.maxSummaryTokens -
+		tokencount.Estimate(s.mapPrompt+s.reducePrompt)
+}
+
+// countTokens returns the token count of text, using the provider's tokenizer when available.
+func (s *Summarizer) countTokens(ctx context.Context, text string) int {
+	tokens, _ := s.counter.CountText(ctx, text) // Estimates are good enough to split by.
+	return tokens
+}
+
+// group packs summaries into as few chunks as fit the chunk size, in order.
+func (s *Summarizer) group(ctx context.Context, summaries []string) []string {
+	pieces := make([]piece, len(summaries))
+	for i, summary := range summaries {
+		pieces[i] = piece{text: summary + "\n\n", tokens: s.countTokens(ctx, summary)}
+	}
+	return pack(pieces, s.chunkTokens())
+}
+
+// split divides text into chunks that fit the chunk size, preferring to
+// break between paragraphs, then lines, sentences and words.
+func (s *Summarizer) split(ctx context.Context, text string) []string {
+	return pack(s.splitPieces(ctx, text, 0), s.chunkTokens())
+}
+
+// splitPieces splits text at separators[level], recursing with the next
+// separator into pieces that are still too large.
+func (s *Summarizer) splitPieces(ctx context.Context, text string, level int) []piece {
+	limit := s.chunkTokens()
+
+	// Small pieces are estimated, to avoid a tokenizer call per word; the
+	// estimate only matters close to the limit.
+	tokens := tokencount.Estimate(text)
+	if tokens > limit/4 {
+		tokens = s.countTokens(ctx, text)
+	}
+	if tokens <= limit {
+		return []piece{{text: text, tokens: tokens}}
+	}
+	if level == len(separators) {
+		return cut(text, limit)
+	}
+
+	var pieces []piece
+	for _, part := range strings.SplitAfter(text, separators[level]) {
+		if part != "" {
+			pieces = append(pieces, s.splitPieces(ctx, part, level+1)...)
+		}
+	}
+	return pieces
+}
+
+// summarizeAll summarizes each chunk with prompt, adding the requests to result.
+func (s *Summarizer) summarizeAll(
+	ctx context.Context,
+	prompt string,
+	chunks []string,
+	result *Result,
+) ([]string, error) {
+	params := make([]providers.CompletionParams, len(chunks))
+	for i, chunk := range chunks {
+		params[i] = providers.CompletionParams{
+			MaxTokens: &s.maxSummaryTokens,
+			Messages: []providers.Message{
+				{Role: providers.RoleSystem, Content: prompt},
+				{Role: providers.RoleUser, Content: strings.TrimSpace(chunk)},
+			},
+			Model: s.model,
+		}
+	}
+
+	results := bulk.CompleteAll(ctx, s.provider, params, bulk.Options{Concurrency: s.concurrency})
+
+	summaries := make([]string, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		result.Calls++
+		if r.Response.Usage != nil {
+			result.Usage.PromptTokens += r.Response.Usage.PromptTokens
+			result.Usage.CompletionTokens += r.Response.Usage.CompletionTokens
+			result.Usage.TotalTokens += r.Response.Usage.TotalTokens
+		}
+		if len(r.Response.Choices) == 0 {
+			return nil, errors.NewProviderError(s.provider.Name(), fmt.Errorf("summary of chunk %d has no choices", i))
+		}
+		summaries[i] = strings.TrimSpace(r.Response.Choices[0].Message.ContentString())
+	}
+	return summaries, nil
+}
+
+// cut splits text that has no separators left into pieces of about limit
+// tokens, estimated from its length.
+func cut(text string, limit int) []piece {
+	runes := []rune(text)
+	size := max(limit*tokencount.CharsPerToken, 1)
+
+	var pieces []piece
+	for start := 0; start < len(runes); start += size {
+		part := string(runes[start:min(start+size, len(runes))])
+		pieces = append(pieces, piece{text: part, tokens: tokencount.Estimate(part)})
+	}
+	return pieces
+}
+
+// pack concatenates consecutive pieces into chunks of at most limit tokens.
+func pack(pieces []piece, limit int) []string {
+	var chunks []string
+	var chunk strings.Builder
+	tokens := 0
+
+	for _, p := range pieces {
+		if chunk.Len() > 0 && tokens+p.tokens > limit {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			tokens = 0
+		}
+		chunk.WriteString(p.text)
+		tokens += p.tokens
+	}
+	if chunk.Len() > 0 {
+		chunks = append(chunks, chunk.String())
+	}
+
+	return chunks
+}
@@ -0,0 +1,233 @@
+package summarize
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/tokencount"
+)
+
+// summarizingMock returns a mock that answers each request with a short
+// summary naming its prompt, and records the requests' user messages.
+func summarizingMock() (*testutil.MockProvider, func() []string) {
+	var mu sync.Mutex
+	var inputs []string
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		input := params.Messages[1].ContentString()
+
+		mu.Lock()
+		inputs = append(inputs, input)
+		mu.Unlock()
+
+		kind := "map"
+		if params.Messages[0].Content == DefaultReducePrompt {
+			kind = "reduce"
+		}
+
+		resp := testutil.MockChatCompletion(fmt.Sprintf("%s summary of %d chars", kind, len(input)))
+		resp.Usage = &providers.Usage{CompletionTokens: 5, PromptTokens: 100, TotalTokens: 105}
+		return resp, nil
+	}
+
+	return mock, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return inputs
+	}
+}
+
+// document returns n paragraphs of about 300 characters.
+func document(n int) string {
+	paragraphs := make([]string, n)
+	for i := range paragraphs {
+		paragraphs[i] = fmt.Sprintf("Paragraph %d. %s", i, strings.Repeat("Lorem ipsum dolor sit amet. ", 10))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name:    "rejects non-positive concurrency",
+			opts:    []Option{WithConcurrency(0)},
+			wantErr: "concurrency must be positive, got 0",
+		},
+		{
+			name:    "rejects non-positive context windows",
+			opts:    []Option{WithContextWindow(-1)},
+			wantErr: "context window must be positive, got -1",
+		},
+		{
+			name:    "rejects non-positive summary lengths",
+			opts:    []Option{WithMaxSummaryTokens(0)},
+			wantErr: "max summary tokens must be positive, got 0",
+		},
+		{
+			name:    "rejects empty prompts",
+			opts:    []Option{WithPrompts("", "combine")},
+			wantErr: "prompts must not be empty",
+		},
+		{
+			name:    "rejects context windows without room for text",
+			opts:    []Option{WithContextWindow(600), WithMaxSummaryTokens(512)},
+			wantErr: "context window of 600 tokens leaves no room for text after 512 summary tokens",
+		},
+		{
+			name: "accepts valid options",
+			opts: []Option{nil, WithConcurrency(2), WithContextWindow(32000), WithPrompts("map", "reduce")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(testutil.NewMockProvider(), "model", tc.opts...)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("summarizes short text in one request", func(t *testing.T) {
+		t.Parallel()
+
+		mock, inputs := summarizingMock()
+		s, err := New(mock, "model")
+		require.NoError(t, err)
+
+		result, err := s.Summarize(context.Background(), "A short note.")
+		require.NoError(t, err)
+		require.Equal(t, "map summary of 13 chars", result.Summary)
+		require.Equal(t, 1, result.Calls)
+		require.Equal(t, 1, result.Chunks)
+		require.Equal(t, 1, result.Rounds)
+		require.Equal(t, []string{"A short note."}, inputs())
+
+		params := mock.CompletionCalls[0]
+		require.Equal(t, "model", params.Model)
+		require.Equal(t, 512, *params.MaxTokens)
+		require.Equal(t, DefaultMapPrompt, params.Messages[0].Content)
+	})
+
+	t.Run("maps chunks and reduces their summaries", func(t *testing.T) {
+		t.Parallel()
+
+		mock, inputs := summarizingMock()
+		s, err := New(mock, "model", WithConcurrency(1), WithContextWindow(400), WithMaxSummaryTokens(20))
+		require.NoError(t, err)
+
+		text := document(20)
+		result, err := s.Summarize(context.Background(), text)
+		require.NoError(t, err)
+
+		require.Greater(t, result.Chunks, 1)
+		require.Equal(t, 2, result.Rounds)
+		require.Equal(t, result.Chunks+1, result.Calls)
+		require.Equal(t, providers.Usage{
+			CompletionTokens: 5 * result.Calls,
+			PromptTokens:     100 * result.Calls,
+			TotalTokens:      105 * result.Calls,
+		}, result.Usage)
+		require.True(t, strings.HasPrefix(result.Summary, "reduce summary"))
+
+		// Every chunk fits, and together the chunks hold the whole text.
+		var mapped []string
+		for _, input := range inputs()[:result.Chunks] {
+			require.LessOrEqual(t, tokencount.Estimate(input), s.chunkTokens())
+			mapped = append(mapped, input)
+		}
+		require.Equal(t, strings.Fields(text), strings.Fields(strings.Join(mapped, " ")))
+	})
+
+	t.Run("reduces in several rounds when summaries do not fit together", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return testutil.MockChatCompletion(strings.Repeat("summary ", 60)), nil
+		}
+		s, err := New(mock, "model", WithContextWindow(400), WithMaxSummaryTokens(20))
+		require.NoError(t, err)
+
+		result, err := s.Summarize(context.Background(), document(20))
+		require.NoError(t, err)
+		require.Greater(t, result.Rounds, 2)
+	})
+
+	t.Run("uses the provider's tokenizer", func(t *testing.T) {
+		t.Parallel()
+
+		mock, _ := summarizingMock()
+		s, err := New(testutil.NewTokenCountingMock(mock), "model", WithContextWindow(400), WithMaxSummaryTokens(20))
+		require.NoError(t, err)
+
+		// Counting words, the whole document fits in one chunk, although
+		// its estimate from length does not.
+		text := strings.Repeat("word ", 250)
+		require.Greater(t, tokencount.Estimate(text), s.chunkTokens())
+
+		result, err := s.Summarize(context.Background(), text)
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Chunks)
+	})
+
+	t.Run("cuts text without separators", func(t *testing.T) {
+		t.Parallel()
+
+		mock, _ := summarizingMock()
+		s, err := New(mock, "model", WithContextWindow(400), WithMaxSummaryTokens(20))
+		require.NoError(t, err)
+
+		result, err := s.Summarize(context.Background(), strings.Repeat("x", 5000))
+		require.NoError(t, err)
+		require.Greater(t, result.Chunks, 1)
+	})
+
+	t.Run("rejects empty text", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := New(testutil.NewMockProvider(), "model")
+		require.NoError(t, err)
+
+		_, err = s.Summarize(context.Background(), " \n ")
+		require.ErrorIs(t, err, errors.ErrInvalidRequest)
+	})
+
+	t.Run("returns request errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+		s, err := New(mock, "model")
+		require.NoError(t, err)
+
+		_, err = s.Summarize(context.Background(), "Some text.")
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+	})
+}
@@ -0,0 +1,274 @@
+// Package telemetry reports the lifecycle of requests as typed events, so
+// dashboards and debuggers can observe requests without scraping logs.
+//
+// Events go to the subscribers carried by a request's context. New wraps a
+// provider so that every request through it carries a subscriber and reports
+// when it starts and finishes, each stream chunk, and prompt cache hits.
+// Wrappers deeper in the chain report their own events to the same
+// subscribers: retry reports RetryScheduled, and router.Hedge and
+// router.Fallback report FallbackTriggered. Subscribers can also be attached to a single request's
+// context with NewContext.
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// CacheHit reports that the provider served part of a prompt from its cache.
+type CacheHit struct {
+	CachedTokens int
+	Model        string
+	PromptTokens int
+	Provider     string
+	Time         time.Time
+}
+
+// ChunkReceived reports a stream chunk. Index counts the chunks of the
+// stream from zero.
+type ChunkReceived struct {
+	Chunk    providers.ChatCompletionChunk
+	Index    int
+	Provider string
+	Time     time.Time
+}
+
+// Event is one of CacheHit, ChunkReceived, FallbackTriggered,
+// RequestFinished, RequestStarted and RetryScheduled.
+type Event interface {
+	isEvent()
+}
+
+// FallbackTriggered reports that a request failed on one provider and was
+// sent to another.
+type FallbackTriggered struct {
+	Err  error
+	From string
+	Time time.Time
+	To   string
+}
+
+// Provider wraps a provider and reports the lifecycle of its requests.
+type Provider struct {
+	provider    providers.Provider
+	subscribers []Subscriber
+}
+
+// RequestFinished reports that a request finished. For streams it is sent
+// once the stream ends. Usage is the response's usage, if it reported any.
+type RequestFinished struct {
+	Duration time.Duration
+	Err      error
+	Model    string
+	Provider string
+	Stream   bool
+	Time     time.Time
+	Usage    *providers.Usage
+}
+
+// RequestStarted reports that a request was sent.
+type RequestStarted struct {
+	Model    string
+	Provider string
+	Stream   bool
+	Time     time.Time
+}
+
+// RetryScheduled reports that a failed attempt will be retried after Delay.
+// Attempt is the number of the attempt that failed, from one.
+type RetryScheduled struct {
+	Attempt  int
+	Delay    time.Duration
+	Err      error
+	Provider string
+	Time     time.Time
+}
+
+// Subscriber receives events. Events are delivered synchronously, on the
+// goroutine handling the request, so Handle must return quickly and be safe
+// for concurrent use.
+type Subscriber interface {
+	Handle(event Event)
+}
+
+// SubscriberFunc adapts an ordinary function to the Subscriber interface.
+type SubscriberFunc func(event Event)
+
+// subscribersKey is the context key for the subscribers of a request.
+type subscribersKey struct{}
+
+// New wraps provider so that its requests report their lifecycle to
+// subscribers, along with the events of any wrapper it calls.
+func New(provider providers.Provider, subscribers ...Subscriber) *Provider {
+	return &Provider{provider: provider, subscribers: subscribers}
+}
+
+// Completion performs a chat completion request, reporting its start, its
+// end and any cache hit.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	ctx = NewContext(ctx, p.subscribers...)
+	start := p.started(ctx, params, false)
+
+	resp, err := p.provider.Completion(ctx, params)
+
+	var usage *providers.Usage
+	if resp != nil {
+		usage = resp.Usage
+	}
+	p.finished(ctx, params, false, start, usage, err)
+
+	return resp, err
+}
+
+// CompletionStream performs a streaming chat completion request, reporting
+// its start, each chunk, its end and any cache hit.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	ctx = NewContext(ctx, p.subscribers...)
+	start := p.started(ctx, params, true)
+
+	in, inErrs := p.provider.CompletionStream(ctx, params)
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		var usage *providers.Usage
+		index := 0
+		for chunk := range in {
+			Emit(ctx, ChunkReceived{Chunk: chunk, Index: index, Provider: p.provider.Name(), Time: time.Now()})
+			index++
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				p.finished(ctx, params, true, start, usage, ctx.Err())
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		err := <-inErrs
+		p.finished(ctx, params, true, start, usage, err)
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Handle calls f(event).
+func (f SubscriberFunc) Handle(event Event) {
+	f(event)
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// finished reports the end of a request started at start, and a cache hit if
+// its usage has cached tokens.
+func (p *Provider) finished(
+	ctx context.Context,
+	params providers.CompletionParams,
+	stream bool,
+	start time.Time,
+	usage *providers.Usage,
+	err error,
+) {
+	now := time.Now()
+	name := p.provider.Name()
+
+	if usage != nil && usage.CachedTokens > 0 {
+		Emit(ctx, CacheHit{
+			CachedTokens: usage.CachedTokens,
+			Model:        params.Model,
+			PromptTokens: usage.PromptTokens,
+			Provider:     name,
+			Time:         now,
+		})
+	}
+
+	Emit(ctx, RequestFinished{
+		Duration: now.Sub(start),
+		Err:      err,
+		Model:    params.Model,
+		Provider: name,
+		Stream:   stream,
+		Time:     now,
+		Usage:    usage,
+	})
+}
+
+// started reports the start of a request and returns its start time.
+func (p *Provider) started(ctx context.Context, params providers.CompletionParams, stream bool) time.Time {
+	now := time.Now()
+	Emit(ctx, RequestStarted{Model: params.Model, Provider: p.provider.Name(), Stream: stream, Time: now})
+	return now
+}
+
+func (CacheHit) isEvent()          {}
+func (ChunkReceived) isEvent()     {}
+func (FallbackTriggered) isEvent() {}
+func (RequestFinished) isEvent()   {}
+func (RequestStarted) isEvent()    {}
+func (RetryScheduled) isEvent()    {}
+
+// Channel returns a subscriber that sends events to ch. Events are dropped
+// when ch is full, so that a slow reader never delays requests; give ch a
+// buffer large enough for the expected bursts.
+func Channel(ch chan<- Event) Subscriber {
+	return SubscriberFunc(func(event Event) {
+		select {
+		case ch <- event:
+		default:
+		}
+	})
+}
+
+// Emit sends event to the subscribers carried by ctx. Wrappers call it to
+// report their own events; it does nothing when ctx carries no subscribers.
+func Emit(ctx context.Context, event Event) {
+	subscribers, _ := ctx.Value(subscribersKey{}).([]Subscriber)
+	for _, s := range subscribers {
+		s.Handle(event)
+	}
+}
+
+// NewContext returns a copy of ctx that also carries subscribers, in addition
+// to any it carries already. Events emitted with the returned context go to
+// all of them.
+func NewContext(ctx context.Context, subscribers ...Subscriber) context.Context {
+	if len(subscribers) == 0 {
+		return ctx
+	}
+
+	existing, _ := ctx.Value(subscribersKey{}).([]Subscriber)
+	all := make([]Subscriber, 0, len(existing)+len(subscribers))
+	all = append(all, existing...)
+	all = append(all, subscribers...)
+	return context.WithValue(ctx, subscribersKey{}, all)
+}
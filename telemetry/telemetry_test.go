@@ -0,0 +1,181 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// recorder is a subscriber that records the events it receives.
+type recorder struct {
+	events []Event
+	mu     sync.Mutex
+}
+
+func (r *recorder) Handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// types returns the types of the recorded events, in order.
+func (r *recorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, fmt.Sprintf("%T", event))
+	}
+	return types
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports start, cache hit and finish", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			resp := testutil.MockChatCompletion("Hi")
+			resp.Usage = &providers.Usage{CachedTokens: 80, PromptTokens: 100, TotalTokens: 110}
+			return resp, nil
+		}
+
+		rec := &recorder{}
+		provider := New(mock, rec)
+
+		_, err := provider.Completion(context.Background(), providers.CompletionParams{
+			Model:    "model",
+			Messages: testutil.SimpleMessages(),
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"telemetry.RequestStarted",
+			"telemetry.CacheHit",
+			"telemetry.RequestFinished",
+		}, rec.types())
+
+		started := rec.events[0].(RequestStarted)
+		require.Equal(t, "mock", started.Provider)
+		require.Equal(t, "model", started.Model)
+		require.False(t, started.Stream)
+
+		hit := rec.events[1].(CacheHit)
+		require.Equal(t, 80, hit.CachedTokens)
+		require.Equal(t, 100, hit.PromptTokens)
+
+		finished := rec.events[2].(RequestFinished)
+		require.NoError(t, finished.Err)
+		require.Equal(t, 110, finished.Usage.TotalTokens)
+		require.False(t, finished.Time.Before(started.Time))
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, fmt.Errorf("boom")
+		}
+
+		rec := &recorder{}
+		_, err := New(mock, rec).Completion(context.Background(), providers.CompletionParams{Model: "model"})
+		require.Error(t, err)
+
+		require.Equal(t, []string{"telemetry.RequestStarted", "telemetry.RequestFinished"}, rec.types())
+		require.EqualError(t, rec.events[1].(RequestFinished).Err, "boom")
+	})
+
+	t.Run("passes subscribers to wrapped providers", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(ctx context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			Emit(ctx, RetryScheduled{Attempt: 1, Provider: "inner"})
+			return testutil.MockChatCompletion("Hi"), nil
+		}
+
+		outer := &recorder{}
+		inner := &recorder{}
+		ctx := NewContext(context.Background(), outer)
+
+		_, err := New(mock, inner).Completion(ctx, providers.CompletionParams{Model: "model"})
+		require.NoError(t, err)
+
+		want := []string{"telemetry.RequestStarted", "telemetry.RetryScheduled", "telemetry.RequestFinished"}
+		require.Equal(t, want, outer.types())
+		require.Equal(t, want, inner.types())
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, 2)
+		errs := make(chan error, 1)
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "Hel"}}},
+		}
+		chunks <- providers.ChatCompletionChunk{
+			Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: "lo"}}},
+			Usage:   &providers.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	rec := &recorder{}
+	chunks, errs := New(mock, rec).CompletionStream(context.Background(), providers.CompletionParams{Model: "model"})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+
+	require.Equal(t, []string{
+		"telemetry.RequestStarted",
+		"telemetry.ChunkReceived",
+		"telemetry.ChunkReceived",
+		"telemetry.RequestFinished",
+	}, rec.types())
+	require.True(t, rec.events[0].(RequestStarted).Stream)
+	require.Equal(t, 1, rec.events[2].(ChunkReceived).Index)
+	require.Equal(t, "lo", rec.events[2].(ChunkReceived).Chunk.Choices[0].Delta.Content)
+	require.Equal(t, 12, rec.events[3].(RequestFinished).Usage.TotalTokens)
+}
+
+func TestChannel(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan Event, 1)
+	ctx := NewContext(context.Background(), Channel(ch))
+
+	Emit(ctx, RequestStarted{Model: "a"})
+	Emit(ctx, RequestStarted{Model: "b"}) // Dropped: the channel is full.
+
+	require.Equal(t, RequestStarted{Model: "a"}, <-ch)
+	require.Empty(t, ch)
+}
+
+func TestEmitWithoutSubscribers(t *testing.T) {
+	t.Parallel()
+
+	require.NotPanics(t, func() {
+		Emit(context.Background(), CacheHit{})
+	})
+	require.Equal(t, context.Background(), NewContext(context.Background()))
+}
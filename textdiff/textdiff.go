@@ -0,0 +1,261 @@
+// Package textdiff compares two texts, such as a model's output before and
+// after a model or prompt upgrade, word by word or sentence by sentence. It
+// reports what changed and a similarity score, so regression tests can
+// tolerate rewording while catching real changes.
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Operations.
+const (
+	// OpDelete is text only in the first text.
+	OpDelete OpKind = "delete"
+
+	// OpEqual is text in both texts.
+	OpEqual OpKind = "equal"
+
+	// OpInsert is text only in the second text.
+	OpInsert OpKind = "insert"
+)
+
+// Diff is the difference between two texts.
+type Diff struct {
+	// Ops transform the first text into the second. Consecutive units of the
+	// same kind are merged into one operation.
+	Ops []Op
+
+	// Similarity is twice the number of units the texts share, divided by
+	// the total number of units in both: 1 for identical texts and 0 for
+	// texts with nothing in common. Two empty texts are identical.
+	Similarity float64
+}
+
+// Op is one operation of a diff.
+type Op struct {
+	Kind OpKind
+	Text string
+}
+
+// OpKind is the kind of an operation.
+type OpKind string
+
+// edit is one step of an edit script.
+type edit struct {
+	kind OpKind
+	unit unit
+}
+
+// unit is a token or sentence, with the whitespace before it.
+type unit struct {
+	space string
+	text  string
+}
+
+// Sentences compares a and b sentence by sentence. Sentences end with ".",
+// "!" or "?" followed by whitespace, or at a line break, and are compared
+// with their surrounding whitespace trimmed.
+func Sentences(a, b string) *Diff {
+	return diff(splitSentences(a), splitSentences(b))
+}
+
+// Tokens compares a and b token by token. Tokens are words, numbers and
+// single punctuation characters; whitespace separates tokens but is not
+// compared.
+func Tokens(a, b string) *Diff {
+	return diff(splitTokens(a), splitTokens(b))
+}
+
+// String renders the diff inline, with deleted text in [-...-] and inserted
+// text in {+...+}, as git diff --word-diff does.
+func (d *Diff) String() string {
+	var b strings.Builder
+	for _, op := range d.Ops {
+		switch op.Kind {
+		case OpDelete:
+			b.WriteString("[-" + op.Text + "-]")
+		case OpInsert:
+			b.WriteString("{+" + op.Text + "+}")
+		case OpEqual:
+			b.WriteString(op.Text)
+		default:
+		}
+	}
+	return b.String()
+}
+
+// add appends u to the diff, merging it into the last operation if it is of
+// the same kind.
+func (d *Diff) add(kind OpKind, u unit) {
+	if n := len(d.Ops); n > 0 && d.Ops[n-1].Kind == kind {
+		d.Ops[n-1].Text += u.space + u.text
+		return
+	}
+
+	text := u.text
+	if len(d.Ops) > 0 {
+		text = u.space + text
+	}
+	d.Ops = append(d.Ops, Op{Kind: kind, Text: text})
+}
+
+// backtrack follows trace back from the end of a and b and returns the edit
+// script in order.
+func backtrack(a, b []unit, trace [][]int) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// at returns v[k] before step d.
+		at := func(k int) int { return trace[d][k+d+1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{kind: OpEqual, unit: b[y-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			edits = append(edits, edit{kind: OpInsert, unit: b[y-1]})
+			y--
+		} else {
+			edits = append(edits, edit{kind: OpDelete, unit: a[x-1]})
+			x--
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// diff returns the diff between units a and b.
+func diff(a, b []unit) *Diff {
+	d := &Diff{Similarity: 1}
+	if total := len(a) + len(b); total > 0 {
+		equal := 0
+		for _, e := range script(a, b) {
+			if e.kind == OpEqual {
+				equal++
+			}
+			d.add(e.kind, e.unit)
+		}
+		d.Similarity = 2 * float64(equal) / float64(total)
+	}
+
+	return d
+}
+
+// script returns the shortest edit script turning a into b, using Myers'
+// algorithm. Equal units are taken from b, so their spacing is that of the
+// second text.
+func script(a, b []unit) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds v before step d, for k in [-d-1, d+1].
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].text == b[y].text {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	return nil // Unreachable: maxD steps always reach the end.
+}
+
+// splitSentences splits s into sentences.
+func splitSentences(s string) []unit {
+	var units []unit
+	var space string
+	start := 0
+
+	flush := func(end int) {
+		if text := strings.TrimSpace(s[start:end]); text != "" {
+			units = append(units, unit{space: space, text: text})
+			space = " "
+		}
+		start = end
+	}
+
+	for i, r := range s {
+		switch {
+		case r == '\n':
+			flush(i)
+		case r == '.' || r == '!' || r == '?':
+			next, _ := utf8.DecodeRuneInString(s[i+1:])
+			if i+1 == len(s) || unicode.IsSpace(next) {
+				flush(i + 1)
+			}
+		default:
+		}
+	}
+	flush(len(s))
+
+	return units
+}
+
+// splitTokens splits s into words, numbers and punctuation characters.
+func splitTokens(s string) []unit {
+	var units []unit
+	var space strings.Builder
+	var word strings.Builder
+
+	flushWord := func() {
+		if word.Len() > 0 {
+			units = append(units, unit{space: space.String(), text: word.String()})
+			space.Reset()
+			word.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'':
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flushWord()
+			space.WriteRune(r)
+		default:
+			flushWord()
+			units = append(units, unit{space: space.String(), text: string(r)})
+			space.Reset()
+		}
+	}
+	flushWord()
+
+	return units
+}
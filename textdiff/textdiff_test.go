@@ -0,0 +1,84 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		a              string
+		b              string
+		want           string
+		wantSimilarity float64
+	}{
+		{
+			name:           "identical",
+			a:              "Paris is the capital.",
+			b:              "Paris  is the\ncapital.",
+			want:           "Paris  is the\ncapital.",
+			wantSimilarity: 1,
+		},
+		{name: "both empty", wantSimilarity: 1},
+		{name: "all inserted", b: "Hello world", want: "{+Hello world+}"},
+		{name: "all deleted", a: "Hello world", want: "[-Hello world-]"},
+		{
+			name:           "substitutions",
+			a:              "The cat sat on the mat.",
+			b:              "The dog sat on a mat!",
+			want:           "The[- cat-]{+ dog+} sat on[- the-]{+ a+} mat[-.-]{+!+}",
+			wantSimilarity: 8.0 / 14,
+		},
+		{name: "contractions", a: "It's 42", b: "It's 43", want: "It's[- 42-]{+ 43+}", wantSimilarity: 0.5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := Tokens(tc.a, tc.b)
+			require.Equal(t, tc.want, d.String())
+			require.InDelta(t, tc.wantSimilarity, d.Similarity, 1e-9)
+		})
+	}
+}
+
+func TestSentences(t *testing.T) {
+	t.Parallel()
+
+	d := Sentences("One. Two! Three?\nFour", "One. Three? Five\nFour")
+	require.Equal(t, []Op{
+		{Kind: OpEqual, Text: "One."},
+		{Kind: OpDelete, Text: " Two!"},
+		{Kind: OpEqual, Text: " Three?"},
+		{Kind: OpInsert, Text: " Five"},
+		{Kind: OpEqual, Text: " Four"},
+	}, d.Ops)
+	require.InDelta(t, 6.0/8, d.Similarity, 1e-9)
+
+	// Periods inside sentences do not split them.
+	require.Len(t, splitSentences("Version 1.5 is out. Update now."), 2)
+}
+
+func TestScriptIsMinimal(t *testing.T) {
+	t.Parallel()
+
+	a := strings.Repeat("a b c ", 50)
+	b := strings.Repeat("a c b ", 50)
+
+	d := Tokens(a, b)
+	var equal int
+	for _, op := range d.Ops {
+		if op.Kind == OpEqual {
+			equal += len(splitTokens(op.Text))
+		}
+	}
+	// The longest common subsequence keeps two of every three tokens.
+	require.Equal(t, 100, equal)
+	require.InDelta(t, 2*100.0/300, d.Similarity, 1e-9)
+}
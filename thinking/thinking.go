@@ -0,0 +1,177 @@
+// Package thinking splits a stream into its reasoning and its content, for
+// user interfaces that render a model's reasoning in a collapsible
+// "thinking" pane next to the answer. It works with any provider that
+// streams reasoning, such as Anthropic, Gemini and DeepSeek, and with
+// reasoning that models inline in <think> tags, which providers move to the
+// Reasoning field by default.
+package thinking
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Stream is a stream split into reasoning and content. Read both channels
+// until they are closed, or cancel the context, so that the stream's
+// goroutines can finish.
+type Stream struct {
+	// Content carries the content deltas of the first choice. It is closed
+	// when the stream ends.
+	Content <-chan string
+
+	// Reasoning carries the reasoning deltas of the first choice. It is closed
+	// when reasoning is over: when the first content or tool call arrives, or
+	// when the stream ends. Reasoning that arrives after that is only in the
+	// completion returned by Wait.
+	Reasoning <-chan string
+
+	done      chan struct{}
+	err       error
+	reasoned  atomic.Int64
+	result    *providers.ChatCompletion
+	startedAt time.Time
+}
+
+// Done returns a channel that is closed when the stream has ended and Wait
+// would not block.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// ReasoningDuration returns how long the model reasoned, from the first
+// reasoning delta to the end of reasoning, for showing "thought for 12s". It
+// is 0 until Reasoning is closed, and if there was no reasoning.
+func (s *Stream) ReasoningDuration() time.Duration {
+	return time.Duration(s.reasoned.Load())
+}
+
+// Wait waits for the stream to end and returns the whole completion, as
+// providers.Accumulator joins it, with the stream's error. If ctx is done
+// first, the error is ctx.Err().
+func (s *Stream) Wait() (*providers.ChatCompletion, error) {
+	<-s.done
+	return s.result, s.err
+}
+
+// split reads chunks, sends the first choice's reasoning and content to their
+// channels and accumulates the completion.
+func (s *Stream) split(
+	ctx context.Context,
+	chunks <-chan providers.ChatCompletionChunk,
+	errs <-chan error,
+	contentIn chan<- string,
+	reasoningIn chan<- string,
+) {
+	defer close(s.done)
+	defer close(contentIn)
+
+	// endReasoning closes the reasoning channel, once.
+	reasoningOpen := true
+	endReasoning := func() {
+		if !reasoningOpen {
+			return
+		}
+		reasoningOpen = false
+		if !s.startedAt.IsZero() {
+			s.reasoned.Store(int64(time.Since(s.startedAt)))
+		}
+		close(reasoningIn)
+	}
+	defer endReasoning()
+
+	send := func(in chan<- string, text string) bool {
+		select {
+		case in <- text:
+			return true
+		case <-ctx.Done():
+			s.err = ctx.Err()
+			return false
+		}
+	}
+
+	var acc providers.Accumulator
+	for chunk := range chunks {
+		acc.Add(chunk)
+
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+
+			delta := choice.Delta
+			if reasoningOpen && delta.Reasoning != nil && delta.Reasoning.Content != "" {
+				if s.startedAt.IsZero() {
+					s.startedAt = time.Now()
+				}
+				if !send(reasoningIn, delta.Reasoning.Content) {
+					return
+				}
+			}
+			if delta.Content != "" || len(delta.ToolCalls) > 0 {
+				endReasoning()
+			}
+			if delta.Content != "" && !send(contentIn, delta.Content) {
+				return
+			}
+		}
+	}
+
+	s.result = acc.Completion()
+	if err := <-errs; err != nil {
+		s.err = err
+	}
+}
+
+// CompletionStream performs a streaming chat completion request on provider
+// and splits its output. See Split.
+func CompletionStream(ctx context.Context, provider providers.Provider, params providers.CompletionParams) *Stream {
+	chunks, errs := provider.CompletionStream(ctx, params)
+	return Split(ctx, chunks, errs)
+}
+
+// Split splits a stream of chunks into reasoning and content. Each channel is
+// buffered without bound, so reading one never holds up the other: a
+// consumer may read all reasoning first and then the content. Deltas that
+// arrive faster than they are read are joined into one.
+func Split(ctx context.Context, chunks <-chan providers.ChatCompletionChunk, errs <-chan error) *Stream {
+	content, reasoning := make(chan string), make(chan string)
+	contentIn, reasoningIn := make(chan string), make(chan string)
+	s := &Stream{Content: content, Reasoning: reasoning, done: make(chan struct{})}
+
+	go forward(ctx, contentIn, content)
+	go forward(ctx, reasoningIn, reasoning)
+	go s.split(ctx, chunks, errs, contentIn, reasoningIn)
+
+	return s
+}
+
+// forward sends the text received on in to out, joining the text that
+// arrives while out is not being read. It closes out once in is closed and
+// everything has been sent, or when ctx is done.
+func forward(ctx context.Context, in <-chan string, out chan<- string) {
+	defer close(out)
+
+	pending := ""
+	for in != nil || pending != "" {
+		var send chan<- string
+		if pending != "" {
+			send = out
+		}
+
+		select {
+		case text, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			pending += text
+		case send <- pending:
+			pending = ""
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package thinking
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testStream returns a finished stream of chunks that ends with err.
+func testStream(err error, chunks ...providers.ChatCompletionChunk) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	out := make(chan providers.ChatCompletionChunk, len(chunks))
+	errs := make(chan error, 1)
+	for _, chunk := range chunks {
+		out <- chunk
+	}
+	if err != nil {
+		errs <- err
+	}
+	close(out)
+	close(errs)
+	return out, errs
+}
+
+// testDelta returns a chunk with the first choice's delta.
+func testDelta(delta providers.ChunkDelta) providers.ChatCompletionChunk {
+	return providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{{Delta: delta}}}
+}
+
+// testReasoning returns a chunk with a reasoning delta.
+func testReasoning(text string) providers.ChatCompletionChunk {
+	return testDelta(providers.ChunkDelta{Reasoning: &providers.Reasoning{Content: text}})
+}
+
+// testRead returns all text received on ch.
+func testRead(ch <-chan string) string {
+	text := ""
+	for delta := range ch {
+		text += delta
+	}
+	return text
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reasoning then content", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := testStream(nil,
+			testReasoning("Let me "),
+			testReasoning("think."),
+			testDelta(providers.ChunkDelta{Content: "The answer "}),
+			testReasoning("ignored"),
+			testDelta(providers.ChunkDelta{Content: "is 42."}),
+			providers.ChatCompletionChunk{
+				Choices: []providers.ChunkChoice{{FinishReason: providers.FinishReasonStop}},
+				Usage:   &providers.Usage{TotalTokens: 9},
+			},
+		)
+		s := Split(context.Background(), chunks, errs)
+
+		// Reading the content first does not hold up the reasoning.
+		require.Equal(t, "The answer is 42.", testRead(s.Content))
+		require.Equal(t, "Let me think.", testRead(s.Reasoning))
+
+		resp, err := s.Wait()
+		require.NoError(t, err)
+		require.Equal(t, "The answer is 42.", resp.Choices[0].Message.ContentString())
+		require.Equal(t, "Let me think.ignored", resp.Choices[0].Message.Reasoning.Content)
+		require.Equal(t, 9, resp.Usage.TotalTokens)
+		require.Positive(t, s.ReasoningDuration())
+	})
+
+	t.Run("a tool call ends reasoning", func(t *testing.T) {
+		t.Parallel()
+
+		call := providers.ToolCall{ID: "call_1", Function: providers.FunctionCall{Name: "weather", Arguments: "{}"}}
+		chunks, errs := testStream(nil,
+			testReasoning("I need the weather."),
+			testDelta(providers.ChunkDelta{ToolCalls: []providers.ToolCall{call}}),
+		)
+		s := Split(context.Background(), chunks, errs)
+
+		require.Equal(t, "I need the weather.", testRead(s.Reasoning))
+		require.Empty(t, testRead(s.Content))
+
+		resp, err := s.Wait()
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("no reasoning", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := testStream(nil, testDelta(providers.ChunkDelta{Content: "Hi"}))
+		s := Split(context.Background(), chunks, errs)
+
+		require.Empty(t, testRead(s.Reasoning))
+		require.Equal(t, "Hi", testRead(s.Content))
+		<-s.Done()
+		require.Zero(t, s.ReasoningDuration())
+	})
+
+	t.Run("other choices are left out", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := testStream(nil, providers.ChatCompletionChunk{Choices: []providers.ChunkChoice{
+			{Index: 1, Delta: providers.ChunkDelta{Content: "second"}},
+			{Index: 0, Delta: providers.ChunkDelta{Content: "first"}},
+		}})
+		s := Split(context.Background(), chunks, errs)
+
+		require.Equal(t, "first", testRead(s.Content))
+		require.Empty(t, testRead(s.Reasoning))
+	})
+
+	t.Run("reports the stream error", func(t *testing.T) {
+		t.Parallel()
+
+		chunks, errs := testStream(stderrors.New("connection reset"), testReasoning("Hm"))
+		s := Split(context.Background(), chunks, errs)
+
+		require.Equal(t, "Hm", testRead(s.Reasoning))
+		require.Empty(t, testRead(s.Content))
+		_, err := s.Wait()
+		require.EqualError(t, err, "connection reset")
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		chunks := make(chan providers.ChatCompletionChunk)
+		errs := make(chan error)
+		s := Split(ctx, chunks, errs)
+
+		chunks <- testReasoning("Hm")
+		cancel()
+		go func() {
+			// The splitter is blocked on its next send or on the next chunk.
+			chunks <- testReasoning("more")
+			close(chunks)
+			close(errs)
+		}()
+
+		for range s.Reasoning {
+		}
+		for range s.Content {
+		}
+		<-s.Done()
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionStreamFunc = func(
+		context.Context,
+		providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return testStream(nil, testReasoning("Hm"), testDelta(providers.ChunkDelta{Content: "Hi"}))
+	}
+
+	s := CompletionStream(context.Background(), mock, providers.CompletionParams{Model: "m"})
+	require.Equal(t, "Hm", testRead(s.Reasoning))
+	require.Equal(t, "Hi", testRead(s.Content))
+	require.Len(t, mock.CompletionStreamCalls, 1)
+}
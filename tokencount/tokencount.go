@@ -0,0 +1,227 @@
+// Package tokencount annotates the messages of a conversation with their
+// token counts and a running total, for deciding what to truncate and for
+// showing how much of a context window a conversation uses. Counts come from
+// the provider's tokenizer when it implements providers.TokenCounter, and are
+// estimated otherwise.
+package tokencount
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// CharsPerToken is the number of bytes of text per token that estimates
+// assume, a rough average for English text.
+const CharsPerToken = 4
+
+// Counting defaults.
+const (
+	// defaultCacheSize is the number of exact counts kept, so that counting a
+	// growing conversation again only tokenizes its new messages.
+	defaultCacheSize = 1024
+)
+
+// Annotation is a message with its token count.
+type Annotation struct {
+	// Estimated reports whether Tokens was estimated from the message's
+	// length rather than counted by the provider's tokenizer.
+	Estimated bool
+
+	// Message is the annotated message.
+	Message providers.Message
+
+	// Tokens is the token count of the message.
+	Tokens int
+
+	// Total is the running total: the token count of this message and every
+	// message before it.
+	Total int
+}
+
+// Annotations are the annotated messages of a conversation, in order.
+type Annotations []Annotation
+
+// Counter counts the tokens of messages.
+type Counter struct {
+	cache     map[string]int
+	cacheSize int
+	mu        sync.Mutex
+	overhead  int
+	provider  providers.Provider
+}
+
+// Option configures a Counter.
+type Option func(*Counter) error
+
+// New returns a Counter that counts tokens with provider's tokenizer, if it
+// has one. A nil provider estimates every count.
+func New(provider providers.Provider, opts ...Option) (*Counter, error) {
+	c := &Counter{cacheSize: defaultCacheSize, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// WithCacheSize sets how many exact counts are kept, keyed by message text.
+// A size of 0 disables the cache. The default is 1024.
+func WithCacheSize(size int) Option {
+	return func(c *Counter) error {
+		if size < 0 {
+			return fmt.Errorf("cache size must not be negative, got %d", size)
+		}
+		c.cacheSize = size
+		return nil
+	}
+}
+
+// WithMessageOverhead adds tokens to the count of every message, for the
+// role and formatting tokens a chat template wraps each message in. Without
+// it, only the message's text is counted.
+func WithMessageOverhead(tokens int) Option {
+	return func(c *Counter) error {
+		if tokens < 0 {
+			return fmt.Errorf("message overhead must not be negative, got %d", tokens)
+		}
+		c.overhead = tokens
+		return nil
+	}
+}
+
+// Annotate returns messages annotated with their token counts and running
+// totals. A message the tokenizer fails on has its count estimated.
+func (c *Counter) Annotate(ctx context.Context, messages []providers.Message) Annotations {
+	annotations := make(Annotations, len(messages))
+	total := 0
+	for i, msg := range messages {
+		tokens, estimated := c.Count(ctx, msg)
+		total += tokens
+		annotations[i] = Annotation{Estimated: estimated, Message: msg, Tokens: tokens, Total: total}
+	}
+	return annotations
+}
+
+// Count returns the token count of msg and whether it was estimated. The
+// text of a message is its content, or the text of its content parts, and
+// the names and arguments of its tool calls; images and other media are not
+// counted.
+func (c *Counter) Count(ctx context.Context, msg providers.Message) (int, bool) {
+	tokens, estimated := c.CountText(ctx, messageText(msg))
+	return tokens + c.overhead, estimated
+}
+
+// CountText returns the token count of text and whether it was estimated,
+// without the message overhead.
+func (c *Counter) CountText(ctx context.Context, text string) (int, bool) {
+	if tokens, ok := c.countExact(ctx, text); ok {
+		return tokens, false
+	}
+	return Estimate(text), true
+}
+
+// Estimated reports whether any count was estimated.
+func (a Annotations) Estimated() bool {
+	for _, annotation := range a {
+		if annotation.Estimated {
+			return true
+		}
+	}
+	return false
+}
+
+// Fits returns the index of the first message from which the rest of the
+// conversation fits within budget tokens, so that messages[i:] is the longest
+// tail that fits. It returns len(a) if not even the last message fits.
+func (a Annotations) Fits(budget int) int {
+	total := a.Total()
+	for i, annotation := range a {
+		if total <= budget {
+			return i
+		}
+		total -= annotation.Tokens
+	}
+	return len(a)
+}
+
+// Messages returns the annotated messages.
+func (a Annotations) Messages() []providers.Message {
+	messages := make([]providers.Message, len(a))
+	for i, annotation := range a {
+		messages[i] = annotation.Message
+	}
+	return messages
+}
+
+// Total returns the token count of the whole conversation.
+func (a Annotations) Total() int {
+	if len(a) == 0 {
+		return 0
+	}
+	return a[len(a)-1].Total
+}
+
+// countExact counts the tokens of text with the provider's tokenizer, if it
+// has one, using the cache.
+func (c *Counter) countExact(ctx context.Context, text string) (int, bool) {
+	counter, ok := providers.As[providers.TokenCounter](c.provider)
+	if !ok {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	tokens, cached := c.cache[text]
+	c.mu.Unlock()
+	if cached {
+		return tokens, true
+	}
+
+	resp, err := counter.Tokenize(ctx, providers.TokenizeParams{Content: text})
+	if err != nil {
+		return 0, false
+	}
+	tokens = resp.Count()
+
+	if c.cacheSize > 0 {
+		c.mu.Lock()
+		// The cache starts over when full; counts are cheap to redo.
+		if c.cache == nil || len(c.cache) >= c.cacheSize {
+			c.cache = make(map[string]int)
+		}
+		c.cache[text] = tokens
+		c.mu.Unlock()
+	}
+	return tokens, true
+}
+
+// Estimate approximates the token count of text from its length, at
+// CharsPerToken bytes per token.
+func Estimate(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// messageText returns the text of msg that is counted.
+func messageText(msg providers.Message) string {
+	text := ""
+	if msg.IsMultiModal() {
+		for _, part := range msg.ContentParts() {
+			text += part.Text
+		}
+	} else {
+		text = msg.ContentString()
+	}
+
+	for _, tc := range msg.ToolCalls {
+		text += tc.Function.Name + tc.Function.Arguments
+	}
+	return text
+}
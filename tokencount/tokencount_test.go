@@ -0,0 +1,161 @@
+package tokencount
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// conversation returns a system prompt followed by user and assistant turns.
+func conversation() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleSystem, Content: "be brief"},
+		{Role: providers.RoleUser, Content: "one two three four"},
+		{Role: providers.RoleAssistant, Content: "five six seven eight"},
+		{Role: providers.RoleUser, Content: "nine ten"},
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(nil, WithCacheSize(-1))
+	require.EqualError(t, err, "cache size must not be negative, got -1")
+
+	_, err = New(nil, WithMessageOverhead(-1))
+	require.EqualError(t, err, "message overhead must not be negative, got -1")
+}
+
+func TestAnnotate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts with the tokenizer", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()), WithMessageOverhead(1))
+		require.NoError(t, err)
+
+		annotations := c.Annotate(context.Background(), conversation())
+		require.Equal(t, conversation(), annotations.Messages())
+		require.Equal(t, []int{3, 5, 5, 3}, testTokens(annotations))
+		require.Equal(t, []int{3, 8, 13, 16}, testTotals(annotations))
+		require.Equal(t, 16, annotations.Total())
+		require.False(t, annotations.Estimated())
+	})
+
+	t.Run("estimates without a tokenizer", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New(testutil.NewMockProvider())
+		require.NoError(t, err)
+
+		annotations := c.Annotate(context.Background(), []providers.Message{
+			{Role: providers.RoleUser, Content: []providers.ContentPart{
+				{Type: "text", Text: "12345678"},
+				{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/a.png"}},
+			}},
+			{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{
+				{ID: "call_1", Function: providers.FunctionCall{Name: "fn", Arguments: "{}"}},
+			}},
+		})
+		require.Equal(t, []int{2, 1}, testTokens(annotations))
+		require.True(t, annotations[0].Estimated)
+		require.True(t, annotations.Estimated())
+	})
+
+	t.Run("estimates when the tokenizer fails", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewTokenCountingMock(testutil.NewMockProvider())
+		mock.FailOn = "fail"
+		c, err := New(mock)
+		require.NoError(t, err)
+
+		annotations := c.Annotate(context.Background(), []providers.Message{
+			{Role: providers.RoleUser, Content: "one two"},
+			{Role: providers.RoleUser, Content: "fail now"},
+		})
+		require.Equal(t, []int{2, 2}, testTokens(annotations))
+		require.False(t, annotations[0].Estimated)
+		require.True(t, annotations[1].Estimated)
+	})
+
+	t.Run("tokenizes only new messages", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewTokenCountingMock(testutil.NewMockProvider())
+		c, err := New(mock)
+		require.NoError(t, err)
+
+		_ = c.Annotate(context.Background(), conversation()[:2]) // Warms the cache.
+		annotations := c.Annotate(context.Background(), conversation())
+		require.Equal(t, 12, annotations.Total())
+		require.Equal(t, int32(4), mock.TokenizeCalls.Load())
+	})
+
+	t.Run("cache can be disabled", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewTokenCountingMock(testutil.NewMockProvider())
+		c, err := New(mock, WithCacheSize(0))
+		require.NoError(t, err)
+
+		for range 2 {
+			_ = c.Annotate(context.Background(), conversation()) // Only the calls matter.
+		}
+		require.Equal(t, int32(8), mock.TokenizeCalls.Load())
+	})
+}
+
+func TestFits(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()))
+	require.NoError(t, err)
+	annotations := c.Annotate(context.Background(), conversation())
+
+	tests := []struct {
+		name   string
+		budget int
+		want   int
+	}{
+		{name: "everything fits", budget: 12, want: 0},
+		{name: "drops the oldest", budget: 10, want: 1},
+		{name: "keeps the last two", budget: 6, want: 2},
+		{name: "last message only", budget: 2, want: 3},
+		{name: "nothing fits", budget: 1, want: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, annotations.Fits(tc.budget))
+		})
+	}
+
+	require.Equal(t, 0, Annotations(nil).Fits(0))
+	require.Equal(t, 0, Annotations(nil).Total())
+}
+
+// testTokens returns the token count of each annotation.
+func testTokens(annotations Annotations) []int {
+	counts := make([]int, len(annotations))
+	for i, annotation := range annotations {
+		counts[i] = annotation.Tokens
+	}
+	return counts
+}
+
+// testTotals returns the running total of each annotation.
+func testTotals(annotations Annotations) []int {
+	counts := make([]int, len(annotations))
+	for i, annotation := range annotations {
+		counts[i] = annotation.Total
+	}
+	return counts
+}
@@ -0,0 +1,636 @@
+// Package toolemu wraps a provider to emulate tool calling for models without
+// native support, such as small models served by llamafile or llama.cpp. The
+// tools are described in the system prompt, the model is asked to answer with
+// a JSON tool invocation, and invocations found in its output are returned as
+// tool calls. Earlier tool calls and results in the conversation are sent as
+// plain text.
+//
+// With WithForceToolUse, it also makes a required tool choice binding on
+// providers that do not enforce it, by asking the model again when it
+// answers without the tool call.
+package toolemu
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// Prompt text. The JSON format in instructions is the one parseToolCalls reads.
+const (
+	instructions = "You can call the following tools:\n\n%s\n" +
+		"To call tools, reply with only a JSON object, and nothing else, in this format:\n" +
+		`{"tool_calls": [{"name": "<tool name>", "arguments": {<arguments>}}]}` + "\n" +
+		"Otherwise, answer normally. The results of tool calls are given to you in messages " +
+		"that start with \"" + toolResultPrefix + "\"."
+	remindAnyTool    = "You did not call a tool. You must call at least one tool."
+	remindTool       = "You did not call the tool %q. You must call it."
+	requireAnyTool   = "\n\nYou must call at least one tool."
+	requireTool      = "\n\nYou must call the tool %q."
+	toolResultPrefix = "Tool result"
+)
+
+// Tool call output.
+const (
+	objectChatCompletionChunk = "chat.completion.chunk"
+	toolCallIDPrefix          = "call_"
+)
+
+// Ensure Provider implements the required interfaces.
+var (
+	_ providers.CapabilityProvider = (*Provider)(nil)
+	_ providers.Provider           = (*Provider)(nil)
+)
+
+// ErrNoToolCall is returned when a request with a required tool choice is
+// still answered without the tool call after the retries of WithForceToolUse.
+var ErrNoToolCall = stderrors.New("toolemu: model did not call the required tool")
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and emulates tool calling.
+type Provider struct {
+	auto         bool
+	forceRetries int
+	provider     providers.Provider
+}
+
+// invocation is a tool call as the model writes it.
+type invocation struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// invocations is the reply the model is asked for to call tools.
+type invocations struct {
+	ToolCalls []invocation `json:"tool_calls"` //nolint:tagliatelle // Key the prompt asks the model for.
+}
+
+// New wraps provider so that requests with tools are sent without them, and
+// tool invocations in the model's output are returned as tool calls. Use
+// WithAuto to emulate only when the provider lacks native tool calling.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	p := &Provider{provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithAuto emulates tool calling only when the wrapped provider reports, with
+// Capabilities, that it does not support tools. Providers that do not report
+// capabilities are assumed to support them. Probe an OpenAI-compatible
+// provider first to find out what the server behind it supports.
+func WithAuto() Option {
+	return func(p *Provider) error {
+		p.auto = true
+		return nil
+	}
+}
+
+// WithForceToolUse makes a required tool choice binding for providers that do
+// not enforce it, natively or when emulating. When ToolChoice is "required"
+// or names a function and the reply does not call it, the model is reminded
+// and asked again, up to retries times, before the request fails with
+// ErrNoToolCall. Streams with such a tool choice are delivered once a reply
+// calls the tool. The usage of every attempt is added up.
+func WithForceToolUse(retries int) Option {
+	return func(p *Provider) error {
+		if retries < 1 {
+			return fmt.Errorf("force tool use retries must be positive, got %d", retries)
+		}
+		p.forceRetries = retries
+		return nil
+	}
+}
+
+// Capabilities returns the wrapped provider's capabilities, with tool calling.
+func (p *Provider) Capabilities() providers.Capabilities {
+	caps := providers.Capabilities{Completion: true, CompletionStreaming: true}
+	if cp, ok := providers.As[providers.CapabilityProvider](p.provider); ok {
+		caps = cp.Capabilities()
+	}
+
+	caps.CompletionTools = true
+	return caps
+}
+
+// Completion performs a chat completion request, emulating tool calling when
+// params has tools.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if !p.forces(params) {
+		return p.complete(ctx, params)
+	}
+
+	var usage *providers.Usage
+	attempt := params
+	for range p.forceRetries + 1 {
+		resp, err := p.complete(ctx, attempt)
+		if err != nil {
+			return nil, err
+		}
+		usage = addUsage(usage, resp.Usage)
+
+		if len(resp.Choices) > 0 && callsRequiredTool(resp.Choices[0].Message, params.ToolChoice) {
+			resp.Usage = usage
+			return resp, nil
+		}
+		attempt = remind(params, resp)
+	}
+
+	return nil, fmt.Errorf("%w after %d attempts", ErrNoToolCall, p.forceRetries+1)
+}
+
+// CompletionStream performs a streaming chat completion request, emulating
+// tool calling when params has tools. Content that may be a tool invocation
+// is held back until it is known not to be; an invocation arrives as a single
+// chunk with its tool calls once the stream ends. Only the first choice is
+// checked for invocations.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	if !p.forces(params) {
+		return p.stream(ctx, params)
+	}
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		held, err := p.forceStream(ctx, params)
+		if err != nil {
+			errs <- err
+			return
+		}
+		for _, chunk := range held {
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// complete performs a chat completion request, emulating tool calling when
+// params has tools.
+func (p *Provider) complete(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	if !p.emulates(params) {
+		return p.provider.Completion(ctx, params)
+	}
+
+	resp, err := p.provider.Completion(ctx, convertParams(params))
+	if err != nil || !callsTools(params.ToolChoice) {
+		return resp, err
+	}
+
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		calls, ok := parseToolCalls(choice.Message.ContentString(), params.Tools)
+		if !ok {
+			continue
+		}
+
+		choice.Message.Content = ""
+		choice.Message.ToolCalls = calls
+		choice.FinishReason = providers.FinishReasonToolCalls
+	}
+
+	return resp, nil
+}
+
+// emulates reports whether tool calling is emulated for params.
+func (p *Provider) emulates(params providers.CompletionParams) bool {
+	if len(params.Tools) == 0 {
+		return false
+	}
+	if !p.auto {
+		return true
+	}
+
+	cp, ok := providers.As[providers.CapabilityProvider](p.provider)
+	return ok && !cp.Capabilities().CompletionTools
+}
+
+// forceStream reads streams of params until one calls the required tool, and
+// returns its chunks, with the usage of every attempt on its last usage chunk.
+func (p *Provider) forceStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) ([]providers.ChatCompletionChunk, error) {
+	var usage *providers.Usage
+	attempt := params
+	for range p.forceRetries + 1 {
+		var held []providers.ChatCompletionChunk
+		var acc providers.Accumulator
+		chunks, errs := p.stream(ctx, attempt)
+		for chunk := range chunks {
+			held = append(held, chunk)
+			acc.Add(chunk)
+		}
+		if err := <-errs; err != nil {
+			return nil, err
+		}
+
+		resp := acc.Completion()
+		if len(resp.Choices) > 0 && callsRequiredTool(resp.Choices[0].Message, params.ToolChoice) {
+			for i := len(held) - 1; i >= 0; i-- {
+				if held[i].Usage != nil {
+					held[i].Usage = addUsage(usage, held[i].Usage)
+					break
+				}
+			}
+			return held, nil
+		}
+		usage = addUsage(usage, resp.Usage)
+		attempt = remind(params, resp)
+	}
+
+	return nil, fmt.Errorf("%w after %d attempts", ErrNoToolCall, p.forceRetries+1)
+}
+
+// forces reports whether the tool choice of params is made binding.
+func (p *Provider) forces(params providers.CompletionParams) bool {
+	if p.forceRetries == 0 || len(params.Tools) == 0 {
+		return false
+	}
+
+	switch c := params.ToolChoice.(type) {
+	case string:
+		return c == providers.ToolChoiceRequired
+	case providers.ToolChoice:
+		return c.Function != nil
+	default:
+		return false
+	}
+}
+
+// stream performs a streaming chat completion request, emulating tool calling
+// when params has tools.
+func (p *Provider) stream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	if !p.emulates(params) {
+		return p.provider.CompletionStream(ctx, params)
+	}
+
+	in, inErrs := p.provider.CompletionStream(ctx, convertParams(params))
+	if !callsTools(params.ToolChoice) {
+		return in, inErrs
+	}
+
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		send := func(chunk providers.ChatCompletionChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return false
+			}
+		}
+
+		var held []providers.ChatCompletionChunk
+		var text strings.Builder
+		holding := true
+
+		for chunk := range in {
+			if !holding {
+				if !send(chunk) {
+					return
+				}
+				continue
+			}
+
+			held = append(held, chunk)
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Index == 0 {
+				text.WriteString(chunk.Choices[0].Delta.Content)
+			}
+			if mayInvoke(text.String()) {
+				continue
+			}
+
+			holding = false
+			for _, c := range held {
+				if !send(c) {
+					return
+				}
+			}
+			held = nil
+		}
+
+		if err := <-inErrs; err != nil {
+			errs <- err
+			return
+		}
+
+		if holding {
+			if calls, ok := parseToolCalls(text.String(), params.Tools); ok {
+				held = []providers.ChatCompletionChunk{toolCallChunk(held, calls)}
+			}
+		}
+		for _, c := range held {
+			if !send(c) {
+				return
+			}
+		}
+	}()
+
+	return chunks, errs
+}
+
+// DescribeTools lists tools with their descriptions and parameter schemas,
+// one per line, as they are described to the model.
+func DescribeTools(tools []providers.Tool) string {
+	var b strings.Builder
+	for _, tool := range tools {
+		fmt.Fprintf(&b, "- %s", tool.Function.Name)
+		if tool.Function.Description != "" {
+			fmt.Fprintf(&b, ": %s", tool.Function.Description)
+		}
+		if tool.Function.Parameters != nil {
+			schema, err := json.Marshal(tool.Function.Parameters)
+			if err == nil {
+				fmt.Fprintf(&b, "\n  Arguments schema: %s", schema)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// addUsage returns the sum of a and b, or nil if both are nil.
+func addUsage(a *providers.Usage, b *providers.Usage) *providers.Usage {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	var sum providers.Usage
+	for _, u := range []*providers.Usage{a, b} {
+		if u == nil {
+			continue
+		}
+		sum.PromptTokens += u.PromptTokens
+		sum.CompletionTokens += u.CompletionTokens
+		sum.TotalTokens += u.TotalTokens
+		sum.ReasoningTokens += u.ReasoningTokens
+		sum.CachedTokens += u.CachedTokens
+	}
+	return &sum
+}
+
+// arguments returns tool call arguments as JSON, or an empty object if they
+// are not valid JSON.
+func arguments(args string) json.RawMessage {
+	if !json.Valid([]byte(args)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+// callsRequiredTool reports whether msg calls the tool a required tool
+// choice asks for: any tool, or the named function.
+func callsRequiredTool(msg providers.Message, choice any) bool {
+	c, ok := choice.(providers.ToolChoice)
+	if !ok || c.Function == nil {
+		return len(msg.ToolCalls) > 0
+	}
+
+	return slices.ContainsFunc(msg.ToolCalls, func(tc providers.ToolCall) bool {
+		return tc.Function.Name == c.Function.Name
+	})
+}
+
+// callsTools reports whether a tool choice lets the model call tools.
+func callsTools(choice any) bool {
+	mode, ok := choice.(string)
+	return !ok || mode != providers.ToolChoiceNone
+}
+
+// convertMessages converts tool calls and tool results in messages to text.
+func convertMessages(messages []providers.Message) []providers.Message {
+	converted := make([]providers.Message, 0, len(messages))
+	callNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == providers.RoleAssistant && len(msg.ToolCalls) > 0:
+			calls := make([]invocation, 0, len(msg.ToolCalls))
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				calls = append(calls, invocation{Name: tc.Function.Name, Arguments: arguments(tc.Function.Arguments)})
+			}
+
+			data, _ := json.Marshal(invocations{ToolCalls: calls}) // Arguments are valid JSON.
+			text := strings.TrimSpace(msg.ContentString() + "\n" + string(data))
+			converted = append(converted, providers.Message{Role: providers.RoleAssistant, Content: text})
+		case msg.Role == providers.RoleTool:
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			converted = append(converted, providers.Message{
+				Role:    providers.RoleUser,
+				Content: fmt.Sprintf("%s for %s:\n%s", toolResultPrefix, name, msg.ContentString()),
+			})
+		default:
+			converted = append(converted, msg)
+		}
+	}
+
+	return converted
+}
+
+// convertParams returns params without tools, describing them in the system
+// prompt instead.
+func convertParams(params providers.CompletionParams) providers.CompletionParams {
+	tools, choice := params.Tools, params.ToolChoice
+	params.Tools = nil
+	params.ToolChoice = nil
+	params.ParallelToolCalls = nil
+	params.Messages = convertMessages(params.Messages)
+
+	if !callsTools(choice) {
+		return params
+	}
+
+	prompt := fmt.Sprintf(instructions, DescribeTools(tools))
+	switch c := choice.(type) {
+	case string:
+		if c == providers.ToolChoiceRequired {
+			prompt += requireAnyTool
+		}
+	case providers.ToolChoice:
+		if c.Function != nil {
+			prompt += fmt.Sprintf(requireTool, c.Function.Name)
+		}
+	default:
+	}
+
+	if len(params.Messages) > 0 && params.Messages[0].Role == providers.RoleSystem &&
+		!params.Messages[0].IsMultiModal() {
+		params.Messages[0].Content = params.Messages[0].ContentString() + "\n\n" + prompt
+	} else {
+		system := providers.Message{Role: providers.RoleSystem, Content: prompt}
+		params.Messages = append([]providers.Message{system}, params.Messages...)
+	}
+
+	return params
+}
+
+// generateID returns a random tool call ID.
+func generateID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b) // crypto/rand.Read never fails.
+	return toolCallIDPrefix + hex.EncodeToString(b)
+}
+
+// mayInvoke reports whether text, the start of a reply, may be a tool
+// invocation: a JSON object, possibly in a Markdown code block.
+func mayInvoke(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "```")
+}
+
+// parseToolCalls returns the tool calls in text, if text is a tool invocation
+// of tools. Both {"tool_calls": [...]} and a single {"name": ..., "arguments":
+// ...} are accepted, optionally in a Markdown code block, and arguments may be
+// an object or a string of JSON.
+func parseToolCalls(text string, tools []providers.Tool) ([]providers.ToolCall, bool) {
+	text = strings.TrimSpace(text)
+	if fenced, ok := strings.CutPrefix(text, "```"); ok {
+		fenced = strings.TrimPrefix(fenced, "json")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fenced), "```"))
+	}
+
+	var reply invocations
+	if err := json.Unmarshal([]byte(text), &reply); err != nil {
+		return nil, false
+	}
+	if len(reply.ToolCalls) == 0 {
+		var single invocation
+		if err := json.Unmarshal([]byte(text), &single); err != nil || single.Name == "" {
+			return nil, false
+		}
+		reply.ToolCalls = []invocation{single}
+	}
+
+	names := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		names[tool.Function.Name] = true
+	}
+
+	calls := make([]providers.ToolCall, 0, len(reply.ToolCalls))
+	for _, inv := range reply.ToolCalls {
+		if !names[inv.Name] {
+			return nil, false
+		}
+
+		args := bytes.TrimSpace(inv.Arguments)
+		var s string
+		if json.Unmarshal(args, &s) == nil {
+			args = []byte(s)
+		}
+		if len(args) == 0 || bytes.Equal(args, []byte("null")) {
+			args = []byte("{}")
+		}
+
+		calls = append(calls, providers.ToolCall{
+			ID:       generateID(),
+			Type:     providers.ToolChoiceTypeFunction,
+			Function: providers.FunctionCall{Name: inv.Name, Arguments: string(args)},
+		})
+	}
+
+	return calls, true
+}
+
+// remind returns params followed by the reply that did not call the required
+// tool, if it had any content, and a reminder to call it.
+func remind(params providers.CompletionParams, resp *providers.ChatCompletion) providers.CompletionParams {
+	messages := slices.Clip(params.Messages)
+	if len(resp.Choices) > 0 && resp.Choices[0].Message.ContentString() != "" {
+		messages = append(messages, providers.Message{
+			Role:    providers.RoleAssistant,
+			Content: resp.Choices[0].Message.ContentString(),
+		})
+	}
+
+	reminder := remindAnyTool
+	if c, ok := params.ToolChoice.(providers.ToolChoice); ok && c.Function != nil {
+		reminder = fmt.Sprintf(remindTool, c.Function.Name)
+	}
+	params.Messages = append(messages, providers.Message{Role: providers.RoleUser, Content: reminder})
+	return params
+}
+
+// toolCallChunk returns the chunk that replaces held, the chunks of a stream
+// whose content is a tool invocation, with calls.
+func toolCallChunk(held []providers.ChatCompletionChunk, calls []providers.ToolCall) providers.ChatCompletionChunk {
+	chunk := providers.ChatCompletionChunk{Object: objectChatCompletionChunk}
+	for _, c := range held {
+		chunk.ID, chunk.Created, chunk.Model = c.ID, c.Created, c.Model
+		chunk.SystemFingerprint = c.SystemFingerprint
+		if c.Usage != nil {
+			chunk.Usage = c.Usage
+		}
+		if c.Timings != nil {
+			chunk.Timings = c.Timings
+		}
+		if c.RateLimit != nil {
+			chunk.RateLimit = c.RateLimit
+		}
+	}
+
+	chunk.Choices = []providers.ChunkChoice{{
+		Delta:        providers.ChunkDelta{Role: providers.RoleAssistant, ToolCalls: calls},
+		FinishReason: providers.FinishReasonToolCalls,
+	}}
+	return chunk
+}
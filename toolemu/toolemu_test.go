@@ -0,0 +1,496 @@
+package toolemu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+)
+
+// replying returns a mock provider whose completions answer with content, and
+// whose streams send content in the given pieces.
+func replying(pieces ...string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		content := ""
+		for _, piece := range pieces {
+			content += piece
+		}
+		return &providers.ChatCompletion{
+			ID:    "cmpl-1",
+			Model: params.Model,
+			Choices: []providers.Choice{{
+				Message:      providers.Message{Role: providers.RoleAssistant, Content: content},
+				FinishReason: providers.FinishReasonStop,
+			}},
+		}, nil
+	}
+	mock.CompletionStreamFunc = func(
+		_ context.Context,
+		params providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		chunks := make(chan providers.ChatCompletionChunk, len(pieces)+1)
+		errs := make(chan error, 1)
+		for _, piece := range pieces {
+			chunks <- providers.ChatCompletionChunk{
+				ID:      "chunk-1",
+				Model:   params.Model,
+				Choices: []providers.ChunkChoice{{Delta: providers.ChunkDelta{Content: piece}}},
+			}
+		}
+		chunks <- providers.ChatCompletionChunk{
+			ID:      "chunk-1",
+			Model:   params.Model,
+			Choices: []providers.ChunkChoice{{FinishReason: providers.FinishReasonStop}},
+			Usage:   &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		}
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+	return mock
+}
+
+// replyingInTurn returns a mock provider whose nth request, completion or
+// stream, answers with replies[n] in one piece.
+func replyingInTurn(replies ...string) *testutil.MockProvider {
+	mock := testutil.NewMockProvider()
+	n := 0
+	next := func() *testutil.MockProvider {
+		reply := replying(replies[min(n, len(replies)-1)])
+		n++
+		return reply
+	}
+	mock.CompletionFunc = func(ctx context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		resp, err := next().CompletionFunc(ctx, params)
+		if err == nil {
+			resp.Usage = &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+		}
+		return resp, err
+	}
+	mock.CompletionStreamFunc = func(
+		ctx context.Context,
+		params providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		return next().CompletionStreamFunc(ctx, params)
+	}
+	return mock
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(testutil.NewMockProvider(), nil, WithAuto())
+	require.NoError(t, err)
+	require.True(t, provider.auto)
+	require.Equal(t, "mock", provider.Name())
+
+	_, err = New(testutil.NewMockProvider(), WithForceToolUse(0))
+	require.EqualError(t, err, "force tool use retries must be positive, got 0")
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New(testutil.NewMockProvider())
+	require.NoError(t, err)
+
+	caps := provider.Capabilities()
+	require.True(t, caps.CompletionTools)
+	require.True(t, caps.Embedding)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "small-model",
+		Messages: testutil.MessagesWithSystem(),
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	}
+
+	t.Run("returns invocations as tool calls", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replying(`{"tool_calls": [{"name": "get_weather", "arguments": {"location": "Paris"}}]}`)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+
+		choice := resp.Choices[0]
+		require.Equal(t, providers.FinishReasonToolCalls, choice.FinishReason)
+		require.Empty(t, choice.Message.ContentString())
+		require.Len(t, choice.Message.ToolCalls, 1)
+		require.Equal(t, "get_weather", choice.Message.ToolCalls[0].Function.Name)
+		require.JSONEq(t, `{"location": "Paris"}`, choice.Message.ToolCalls[0].Function.Arguments)
+		require.NotEmpty(t, choice.Message.ToolCalls[0].ID)
+
+		sent := mock.CompletionCalls[0]
+		require.Empty(t, sent.Tools)
+		require.Nil(t, sent.ToolChoice)
+		require.Len(t, sent.Messages, len(params.Messages))
+		require.Equal(t, providers.RoleSystem, sent.Messages[0].Role)
+		require.Contains(t, sent.Messages[0].ContentString(), params.Messages[0].ContentString())
+		require.Contains(t, sent.Messages[0].ContentString(), "- get_weather: ")
+	})
+
+	t.Run("returns answers as they are", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(replying("It is sunny."))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Equal(t, "It is sunny.", resp.Choices[0].Message.ContentString())
+		require.Empty(t, resp.Choices[0].Message.ToolCalls)
+		require.Equal(t, providers.FinishReasonStop, resp.Choices[0].FinishReason)
+	})
+
+	t.Run("ignores invocations when tools are disabled", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replying(`{"name": "get_weather", "arguments": {}}`)
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		noTools := params
+		noTools.ToolChoice = providers.ToolChoiceNone
+		resp, err := provider.Completion(context.Background(), noTools)
+		require.NoError(t, err)
+		require.Empty(t, resp.Choices[0].Message.ToolCalls)
+		require.NotContains(t, mock.CompletionCalls[0].Messages[0].ContentString(), "get_weather")
+	})
+
+	t.Run("names the required tool", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replying("ok")
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		required := params
+		required.ToolChoice = providers.ToolChoiceForFunction("get_weather")
+		_, err = provider.Completion(context.Background(), required)
+		require.NoError(t, err)
+		require.Contains(t, mock.CompletionCalls[0].Messages[0].ContentString(), `You must call the tool "get_weather".`)
+	})
+
+	t.Run("sends tools natively in auto mode when supported", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replying("ok")
+		mock.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionTools: true}
+		}
+		provider, err := New(mock, WithAuto())
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Len(t, mock.CompletionCalls[0].Tools, 1)
+	})
+
+	t.Run("emulates in auto mode when unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replying("ok")
+		provider, err := New(mock, WithAuto())
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Empty(t, mock.CompletionCalls[0].Tools)
+	})
+
+	t.Run("emulates in auto mode behind wrappers", func(t *testing.T) {
+		t.Parallel()
+
+		// The retry wrapper reports no capabilities; the provider it wraps,
+		// like llamafile, cannot call tools natively.
+		mock := replying("ok")
+		mock.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionStreaming: true}
+		}
+		provider, err := New(retry.New(mock, nil), WithAuto())
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Empty(t, mock.CompletionCalls[0].Tools)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	params := providers.CompletionParams{
+		Model:    "small-model",
+		Messages: testutil.SimpleMessages(),
+		Tools:    []providers.Tool{testutil.WeatherTool()},
+	}
+
+	collect := func(t *testing.T, provider *Provider) []providers.ChatCompletionChunk {
+		t.Helper()
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		var received []providers.ChatCompletionChunk
+		for chunk := range chunks {
+			received = append(received, chunk)
+		}
+		require.NoError(t, <-errs)
+		return received
+	}
+
+	t.Run("joins an invocation into one tool call chunk", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(replying(
+			"```json\n", `{"name": "get_weather", `, `"arguments": "{\"location\": \"Paris\"}"}`, "\n```",
+		))
+		require.NoError(t, err)
+
+		received := collect(t, provider)
+		require.Len(t, received, 1)
+
+		choice := received[0].Choices[0]
+		require.Equal(t, providers.FinishReasonToolCalls, choice.FinishReason)
+		require.Len(t, choice.Delta.ToolCalls, 1)
+		require.Equal(t, "get_weather", choice.Delta.ToolCalls[0].Function.Name)
+		require.JSONEq(t, `{"location": "Paris"}`, choice.Delta.ToolCalls[0].Function.Arguments)
+		require.Equal(t, "chunk-1", received[0].ID)
+		require.Equal(t, 15, received[0].Usage.TotalTokens)
+	})
+
+	t.Run("streams answers once they cannot be invocations", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(replying("It is ", "sunny."))
+		require.NoError(t, err)
+
+		received := collect(t, provider)
+		require.Len(t, received, 3)
+		require.Equal(t, "It is ", received[0].Choices[0].Delta.Content)
+		require.Equal(t, providers.FinishReasonStop, received[2].Choices[0].FinishReason)
+	})
+
+	t.Run("sends held content that is not an invocation", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(replying(`{"answer": 42}`))
+		require.NoError(t, err)
+
+		received := collect(t, provider)
+		require.Len(t, received, 2)
+		require.Equal(t, `{"answer": 42}`, received[0].Choices[0].Delta.Content)
+	})
+}
+
+func TestForceToolUse(t *testing.T) {
+	t.Parallel()
+
+	const invocation = `{"name": "get_weather", "arguments": {"location": "Paris"}}`
+	params := providers.CompletionParams{
+		Model:      "small-model",
+		Messages:   testutil.SimpleMessages(),
+		Tools:      []providers.Tool{testutil.WeatherTool()},
+		ToolChoice: providers.ToolChoiceRequired,
+	}
+
+	t.Run("asks again until the model calls a tool", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replyingInTurn("It is probably sunny.", invocation)
+		provider, err := New(mock, WithForceToolUse(2))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), params)
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		require.Equal(t, 30, resp.Usage.TotalTokens)
+
+		require.Len(t, mock.CompletionCalls, 2)
+		retry := mock.CompletionCalls[1].Messages
+		require.Len(t, retry, len(mock.CompletionCalls[0].Messages)+2)
+		require.Equal(t, "It is probably sunny.", retry[len(retry)-2].ContentString())
+		require.Equal(t, remindAnyTool, retry[len(retry)-1].ContentString())
+	})
+
+	t.Run("checks native replies for the named tool", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		calls := []providers.ToolCall{{ID: "call_1", Function: providers.FunctionCall{Name: "other", Arguments: "{}"}}}
+		mock.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return testutil.MockChatCompletionWithToolCalls(calls), nil
+		}
+		mock.CapabilitiesFunc = func() providers.Capabilities {
+			return providers.Capabilities{Completion: true, CompletionTools: true}
+		}
+		provider, err := New(mock, WithAuto(), WithForceToolUse(1))
+		require.NoError(t, err)
+
+		named := params
+		named.ToolChoice = providers.ToolChoiceForFunction("get_weather")
+		_, err = provider.Completion(context.Background(), named)
+		require.ErrorIs(t, err, ErrNoToolCall)
+		require.EqualError(t, err, "toolemu: model did not call the required tool after 2 attempts")
+
+		require.Len(t, mock.CompletionCalls, 2)
+		require.Len(t, mock.CompletionCalls[1].Tools, 1)
+		retry := mock.CompletionCalls[1].Messages
+		require.Equal(t, `You did not call the tool "get_weather". You must call it.`, retry[len(retry)-1].ContentString())
+		require.Equal(t, providers.RoleUser, retry[len(retry)-2].Role)
+	})
+
+	t.Run("leaves other tool choices alone", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replyingInTurn("It is sunny.")
+		provider, err := New(mock, WithForceToolUse(2))
+		require.NoError(t, err)
+
+		auto := params
+		auto.ToolChoice = providers.ToolChoiceAuto
+		resp, err := provider.Completion(context.Background(), auto)
+		require.NoError(t, err)
+		require.Equal(t, "It is sunny.", resp.Choices[0].Message.ContentString())
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+
+	t.Run("streams the reply that calls a tool", func(t *testing.T) {
+		t.Parallel()
+
+		mock := replyingInTurn("It is probably sunny.", invocation)
+		provider, err := New(mock, WithForceToolUse(1))
+		require.NoError(t, err)
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		var received []providers.ChatCompletionChunk
+		for chunk := range chunks {
+			received = append(received, chunk)
+		}
+		require.NoError(t, <-errs)
+
+		require.Len(t, received, 1)
+		require.Len(t, received[0].Choices[0].Delta.ToolCalls, 1)
+		require.Equal(t, 30, received[0].Usage.TotalTokens)
+		require.Len(t, mock.CompletionStreamCalls, 2)
+	})
+
+	t.Run("fails streams that never call a tool", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(replyingInTurn("No."), WithForceToolUse(1))
+		require.NoError(t, err)
+
+		chunks, errs := provider.CompletionStream(context.Background(), params)
+		for range chunks {
+		}
+		require.ErrorIs(t, <-errs, ErrNoToolCall)
+	})
+}
+
+func TestConvertMessages(t *testing.T) {
+	t.Parallel()
+
+	converted := convertMessages(testutil.AgentLoopMessages())
+	for _, msg := range converted {
+		require.Empty(t, msg.ToolCalls)
+		require.NotEqual(t, providers.RoleTool, msg.Role)
+	}
+
+	messages := []providers.Message{
+		{Role: providers.RoleUser, Content: "Weather in Paris?"},
+		{
+			Role:    providers.RoleAssistant,
+			Content: "Let me check.",
+			ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Function: providers.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+			}},
+		},
+		providers.NewToolResultMessage("call_1", providers.ToolResult{Content: "sunny"}),
+	}
+
+	converted = convertMessages(messages)
+	require.Equal(t, []providers.Message{
+		{Role: providers.RoleUser, Content: "Weather in Paris?"},
+		{
+			Role: providers.RoleAssistant,
+			Content: "Let me check.\n" +
+				`{"tool_calls":[{"name":"get_weather","arguments":{"location":"Paris"}}]}`,
+		},
+		{Role: providers.RoleUser, Content: "Tool result for get_weather:\nsunny"},
+	}, converted)
+}
+
+func TestParseToolCalls(t *testing.T) {
+	t.Parallel()
+
+	tools := []providers.Tool{testutil.WeatherTool(), testutil.DateTool()}
+
+	tests := []struct {
+		name      string
+		text      string
+		wantNames []string
+		wantArgs  []string
+	}{
+		{
+			name: "tool calls object",
+			text: `{"tool_calls": [{"name": "get_weather", "arguments": {"location": "Paris"}}, ` +
+				`{"name": "get_current_date"}]}`,
+			wantNames: []string{"get_weather", "get_current_date"},
+			wantArgs:  []string{`{"location": "Paris"}`, `{}`},
+		},
+		{
+			name:      "single invocation",
+			text:      ` {"name": "get_weather", "arguments": {"location": "Paris"}} `,
+			wantNames: []string{"get_weather"},
+			wantArgs:  []string{`{"location": "Paris"}`},
+		},
+		{
+			name:      "code block with string arguments",
+			text:      "```json\n{\"name\": \"get_weather\", \"arguments\": \"{\\\"location\\\": \\\"Paris\\\"}\"}\n```",
+			wantNames: []string{"get_weather"},
+			wantArgs:  []string{`{"location": "Paris"}`},
+		},
+		{
+			name: "unknown tool",
+			text: `{"name": "get_stock_price", "arguments": {}}`,
+		},
+		{
+			name: "other JSON",
+			text: `{"answer": 42}`,
+		},
+		{
+			name: "text",
+			text: "It is sunny in Paris.",
+		},
+		{
+			name: "text around an invocation",
+			text: `Sure: {"name": "get_weather", "arguments": {}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls, ok := parseToolCalls(tc.text, tools)
+			require.Equal(t, tc.wantNames != nil, ok)
+			require.Len(t, calls, len(tc.wantNames))
+			for i, call := range calls {
+				require.Equal(t, tc.wantNames[i], call.Function.Name)
+				require.JSONEq(t, tc.wantArgs[i], call.Function.Arguments)
+				require.Equal(t, providers.ToolChoiceTypeFunction, call.Type)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+// Package transcript defines a versioned JSON format for conversations, so
+// they can move between the anyllm command, chat sessions, replay traces, the
+// eval harness and applications. A transcript holds the messages of a
+// conversation, including tool calls and reasoning, with the usage and
+// provenance of each response.
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/mozilla-ai/any-llm-go/chat"
+	"github.com/mozilla-ai/any-llm-go/eval"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/replay"
+)
+
+// Format identifies transcript files in their format field.
+const Format = "any-llm-transcript"
+
+// Version is the transcript format version written by this package. Files of
+// later versions are refused.
+const Version = 1
+
+// Transcript is a conversation in the transcript format. Create one with New
+// or one of the From functions, and add to it with Add and AddCompletion.
+type Transcript struct {
+	// Created is when the conversation started, if known.
+	Created time.Time `json:"created,omitzero"`
+
+	// Format is always Format.
+	Format string `json:"format"`
+
+	// ID identifies the conversation, such as a chat session ID.
+	ID string `json:"id,omitempty"`
+
+	// Metadata holds application-defined labels, such as a user or a ticket.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Model is the model the conversation was held with, if it used one.
+	Model string `json:"model,omitempty"`
+
+	// ParentID is the ID of the conversation this one was forked from.
+	ParentID string `json:"parentId,omitempty"`
+
+	// Provider is the provider the conversation was held with, if it used
+	// one.
+	Provider string `json:"provider,omitempty"`
+
+	// Turns are the messages of the conversation, in order.
+	Turns []Turn `json:"turns"`
+
+	// Usage is the usage of the whole conversation, if known. It may cover
+	// requests whose responses are not in Turns, such as retries.
+	Usage *providers.Usage `json:"usage,omitempty"`
+
+	// Version is the format version of the transcript.
+	Version int `json:"version"`
+}
+
+// Turn is one message of a transcript. Responses carry what is known of the
+// request that produced them.
+type Turn struct {
+	// FinishReason is why the response ended.
+	FinishReason string `json:"finishReason,omitempty"`
+
+	// Message is the message.
+	Message providers.Message `json:"message"`
+
+	// Provenance is where the response came from, when it was recorded.
+	Provenance *providers.Provenance `json:"provenance,omitempty"`
+
+	// Usage is the usage of the request that produced the response.
+	Usage *providers.Usage `json:"usage,omitempty"`
+}
+
+// New returns a transcript of messages.
+func New(messages ...providers.Message) *Transcript {
+	t := &Transcript{Format: Format, Version: Version}
+	t.Add(messages...)
+	return t
+}
+
+// Add adds messages to the end of the transcript.
+func (t *Transcript) Add(messages ...providers.Message) {
+	for _, msg := range messages {
+		t.Turns = append(t.Turns, Turn{Message: msg})
+	}
+}
+
+// AddCompletion adds the first choice of resp to the end of the transcript,
+// with its usage and provenance, and adds its usage to the transcript's.
+func (t *Transcript) AddCompletion(resp *providers.ChatCompletion) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return
+	}
+
+	choice := resp.Choices[0]
+	t.Turns = append(t.Turns, Turn{
+		FinishReason: choice.FinishReason,
+		Message:      choice.Message,
+		Provenance:   resp.Provenance,
+		Usage:        resp.Usage,
+	})
+	t.addUsage(resp.Usage)
+}
+
+// Case returns the transcript as an eval case named name: the messages before
+// the last response are the conversation, and the last response's text is
+// the expected output. It fails if the transcript does not end with a
+// response.
+func (t *Transcript) Case(name string) (eval.Case, error) {
+	last := len(t.Turns) - 1
+	if last < 0 || t.Turns[last].Message.Role != providers.RoleAssistant {
+		return eval.Case{}, fmt.Errorf("transcript does not end with an assistant message")
+	}
+
+	return eval.Case{
+		Expected: t.Turns[last].Message.ContentString(),
+		Messages: t.messages(last),
+		Name:     name,
+	}, nil
+}
+
+// Messages returns the messages of the transcript, such as to continue the
+// conversation with a provider.
+func (t *Transcript) Messages() []providers.Message {
+	return t.messages(len(t.Turns))
+}
+
+// Session starts a chat session that continues the conversation with
+// provider. The params are used as the template for every request;
+// params.Messages is replaced with the transcript's messages. The session's
+// usage starts at the transcript's.
+func (t *Transcript) Session(
+	provider providers.Provider,
+	params providers.CompletionParams,
+	opts ...chat.Option,
+) (*chat.Session, error) {
+	params.Messages = t.Messages()
+	if t.Usage != nil {
+		opts = append([]chat.Option{chat.WithUsage(chat.Usage{Usage: *t.Usage})}, opts...)
+	}
+	return chat.NewSession(provider, params, opts...)
+}
+
+// Write writes the transcript to w as indented JSON.
+func (t *Transcript) Write(w io.Writer) error {
+	out := *t
+	out.Format, out.Version = Format, Version
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+	return nil
+}
+
+// addUsage adds usage to the transcript's.
+func (t *Transcript) addUsage(usage *providers.Usage) {
+	if usage == nil {
+		return
+	}
+	if t.Usage == nil {
+		t.Usage = &providers.Usage{}
+	}
+
+	t.Usage.PromptTokens += usage.PromptTokens
+	t.Usage.CompletionTokens += usage.CompletionTokens
+	t.Usage.TotalTokens += usage.TotalTokens
+	t.Usage.ReasoningTokens += usage.ReasoningTokens
+	t.Usage.CachedTokens += usage.CachedTokens
+}
+
+// messages returns the messages of the first n turns.
+func (t *Transcript) messages(n int) []providers.Message {
+	messages := make([]providers.Message, 0, n)
+	for _, turn := range t.Turns[:n] {
+		messages = append(messages, turn.Message)
+	}
+	return messages
+}
+
+// FromSession returns a transcript of a chat session's conversation, with
+// its ID, parent and usage.
+func FromSession(s *chat.Session) *Transcript {
+	t := New(s.Messages()...)
+	t.ID = s.ID()
+	t.ParentID = s.ParentID()
+
+	if usage := s.Usage(); usage.Requests > 0 {
+		t.Usage = &usage.Usage
+	}
+	return t
+}
+
+// FromTrace returns a transcript of the conversation of a replay trace as it
+// stood after its last step. Each response in it carries the usage and
+// provenance recorded with it, and the transcript's usage is that of every
+// step. The trace must hold one conversation; use Trace.Session to pick one.
+func FromTrace(trace *replay.Trace) (*Transcript, error) {
+	last := len(trace.Steps) - 1
+	if last < 0 {
+		return nil, fmt.Errorf("trace has no steps")
+	}
+
+	messages, err := trace.Messages(last)
+	if err != nil {
+		return nil, err
+	}
+
+	t := New(messages...)
+	t.Created = trace.Steps[0].Time
+	t.ID = trace.Steps[last].Session
+	t.Model = trace.Steps[last].Params.Model
+	t.Provider = trace.Steps[last].Provider
+
+	for i := range trace.Steps {
+		step := &trace.Steps[i]
+		resp := step.Completion()
+		if resp == nil {
+			continue
+		}
+		t.addUsage(resp.Usage)
+
+		// The response is part of the conversation if the later steps sent
+		// it back, after the same messages.
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		sent := append(slices.Clone(step.Params.Messages), resp.Choices[0].Message)
+		if !isPrefix(sent, messages) {
+			continue
+		}
+		turn := &t.Turns[len(sent)-1]
+		turn.FinishReason = resp.Choices[0].FinishReason
+		turn.Provenance = resp.Provenance
+		turn.Usage = resp.Usage
+	}
+
+	return t, nil
+}
+
+// Load reads a transcript from the file at path.
+func Load(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening transcript: %w", err)
+	}
+	defer func() { _ = f.Close() }() // Read-only file; close errors carry no data.
+
+	return Read(f)
+}
+
+// Read reads a transcript from r. It fails on files that are not transcripts,
+// on later format versions and on messages with unknown roles.
+func Read(r io.Reader) (*Transcript, error) {
+	var t Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+
+	if t.Format != Format {
+		return nil, fmt.Errorf("reading transcript: format is %q, want %q", t.Format, Format)
+	}
+	if t.Version < 1 || t.Version > Version {
+		return nil, fmt.Errorf("reading transcript: unsupported version %d", t.Version)
+	}
+
+	for i := range t.Turns {
+		msg := &t.Turns[i].Message
+		content, err := decodeContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("reading transcript: turn %d: %w", i, err)
+		}
+		msg.Content = content
+	}
+	if err := providers.ValidateMessages("", t.Messages()); err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+
+	return &t, nil
+}
+
+// decodeContent turns message content decoded from JSON back into the type
+// providers expect: parts decode as []any, and become []ContentPart.
+func decodeContent(content any) (any, error) {
+	values, ok := content.([]any)
+	if !ok {
+		return content, nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var parts []providers.ContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, fmt.Errorf("decoding content parts: %w", err)
+	}
+	return parts, nil
+}
+
+// isPrefix reports whether prefix is the start of messages, comparing the
+// messages as JSON.
+func isPrefix(prefix []providers.Message, messages []providers.Message) bool {
+	if len(prefix) > len(messages) {
+		return false
+	}
+
+	a, errA := json.Marshal(prefix)
+	b, errB := json.Marshal(messages[:len(prefix)])
+	return errA == nil && errB == nil && string(a) == string(b)
+}
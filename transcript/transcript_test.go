@@ -0,0 +1,169 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/chat"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/replay"
+)
+
+// weatherCall is a tool call made in the test conversations.
+var weatherCall = providers.ToolCall{
+	ID:       "call_1",
+	Type:     "function",
+	Function: providers.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`},
+}
+
+// toolConversation returns a conversation with a tool call and its result.
+func toolConversation() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleUser, Content: "Weather in Paris?"},
+		{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{weatherCall}},
+		{Role: providers.RoleTool, ToolCallID: "call_1", Content: "Sunny"},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	resp := testutil.MockChatCompletion("It is sunny.")
+	resp.Provenance = &providers.Provenance{GeneratedAt: created, Model: "m", Provider: "mock"}
+
+	original := New(toolConversation()...)
+	original.Add(providers.Message{Role: providers.RoleUser, Content: []providers.ContentPart{
+		{Type: "text", Text: "And this?"},
+		{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/sky.png"}},
+	}})
+	original.AddCompletion(resp)
+	original.Created = created
+	original.ID = "session-1"
+	original.Metadata = map[string]string{"user": "u1"}
+
+	var buf bytes.Buffer
+	require.NoError(t, original.Write(&buf))
+	require.Contains(t, buf.String(), `"format": "any-llm-transcript"`)
+	require.Contains(t, buf.String(), `"version": 1`)
+
+	read, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, original, read)
+	require.Equal(t, &providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, read.Usage)
+	require.Equal(t, providers.FinishReasonStop, read.Turns[4].FinishReason)
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "not a transcript",
+			input:   `{"messages": []}`,
+			wantErr: `reading transcript: format is "", want "any-llm-transcript"`,
+		},
+		{
+			name:    "later version",
+			input:   `{"format": "any-llm-transcript", "version": 2, "turns": []}`,
+			wantErr: "reading transcript: unsupported version 2",
+		},
+		{
+			name:    "unknown role",
+			input:   `{"format": "any-llm-transcript", "version": 1, "turns": [{"message": {"role": "robot"}}]}`,
+			wantErr: `reading transcript: invalid_request: messages[0]: unknown role "robot"`,
+		},
+		{name: "invalid JSON", input: `{`, wantErr: "reading transcript: unexpected EOF"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Read(strings.NewReader(tc.input))
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCase(t *testing.T) {
+	t.Parallel()
+
+	tr := New(toolConversation()...)
+	_, err := tr.Case("weather")
+	require.EqualError(t, err, "transcript does not end with an assistant message")
+
+	tr.AddCompletion(testutil.MockChatCompletion("It is sunny."))
+	c, err := tr.Case("weather")
+	require.NoError(t, err)
+	require.Equal(t, "weather", c.Name)
+	require.Equal(t, "It is sunny.", c.Expected)
+	require.Equal(t, toolConversation(), c.Messages)
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	session, err := chat.NewSession(mock, providers.CompletionParams{Model: "m"})
+	require.NoError(t, err)
+	_, err = session.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Hi"})
+	require.NoError(t, err)
+
+	exported := FromSession(session)
+	require.Equal(t, session.ID(), exported.ID)
+	require.Len(t, exported.Turns, 2)
+	require.Equal(t, 15, exported.Usage.TotalTokens)
+
+	resumed, err := exported.Session(mock, providers.CompletionParams{Model: "m"})
+	require.NoError(t, err)
+	_, err = resumed.Send(context.Background(), providers.Message{Role: providers.RoleUser, Content: "Again"})
+	require.NoError(t, err)
+	require.Len(t, mock.CompletionCalls[1].Messages, 3)
+	require.Equal(t, 30, resumed.Usage().TotalTokens)
+}
+
+func TestFromTrace(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	first := testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{weatherCall})
+	retried := testutil.MockChatCompletion("A retry that was dropped")
+	last := testutil.MockChatCompletion("It is sunny.")
+	last.Provenance = &providers.Provenance{Model: "m", Provider: "mock"}
+
+	// The application sends the tool call back as it was received.
+	question := toolConversation()[:1]
+	conversation := append(slices.Clone(question), first.Choices[0].Message, toolConversation()[2])
+	trace := &replay.Trace{Steps: []replay.Step{
+		{Params: providers.CompletionParams{Model: "m", Messages: question}, Provider: "mock", Response: retried, Time: started},
+		{Params: providers.CompletionParams{Model: "m", Messages: question}, Provider: "mock", Response: first},
+		{Params: providers.CompletionParams{Model: "m", Messages: conversation}, Provider: "mock", Response: last},
+	}}
+
+	tr, err := FromTrace(trace)
+	require.NoError(t, err)
+	require.Equal(t, started, tr.Created)
+	require.Equal(t, "m", tr.Model)
+	require.Equal(t, "mock", tr.Provider)
+	require.Len(t, tr.Turns, 4)
+	require.Equal(t, providers.FinishReasonToolCalls, tr.Turns[1].FinishReason)
+	require.Equal(t, first.Usage, tr.Turns[1].Usage)
+	require.Equal(t, last.Provenance, tr.Turns[3].Provenance)
+	require.Nil(t, tr.Turns[2].Usage)
+	require.Equal(t, 60, tr.Usage.TotalTokens)
+
+	_, err = FromTrace(&replay.Trace{})
+	require.EqualError(t, err, "trace has no steps")
+}
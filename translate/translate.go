@@ -0,0 +1,548 @@
+// Package translate translates sets of strings, such as an application's
+// message catalog, into other languages with any provider. Strings are sent
+// in batches with a JSON schema response format, glossary terms that occur in
+// a batch are added to its prompt, and each translation is checked to keep
+// the placeholders and ICU MessageFormat arguments of its source. Rejected
+// translations are sent again with the reason they were rejected.
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mozilla-ai/any-llm-go/bulk"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/retry"
+	"github.com/mozilla-ai/any-llm-go/structured"
+	"github.com/mozilla-ai/any-llm-go/tokencount"
+)
+
+// Translator defaults.
+const (
+	defaultAttempts    = 3
+	defaultBatchSize   = 25
+	defaultBatchTokens = 2000
+	defaultConcurrency = 4
+)
+
+// Prompt text.
+const (
+	glossaryInstructions = "Translate these terms as given:"
+	promptInstructions   = "You are a professional software localizer. Translate the text of each string " +
+		"into %s. Keep placeholders such as {name}, {{name}}, %%s and %%1$d exactly as they are. Keep ICU " +
+		"MessageFormat syntax: translate the text inside plural and select options, but never argument " +
+		"names, types or selectors. Keep markup, and leading and trailing whitespace. A note describes " +
+		"where a string is used. If a string has a rejected translation, fix the problem given with it. " +
+		"Reply with a translation for every string, under its id."
+	sourceInstructions = "The strings are in %s."
+)
+
+// responseSchema is the JSON schema of a batch's translations.
+var responseSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"translations": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id":   map[string]any{"type": "string"},
+					"text": map[string]any{"type": "string"},
+				},
+				"required":             []string{"id", "text"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"translations"},
+	"additionalProperties": false,
+}
+
+// Entry is a string to translate.
+type Entry struct {
+	// Key identifies the string, as in a message catalog.
+	Key string
+
+	// Note, if set, tells the model where the string is used, such as
+	// "button label" or "shown when the cart is empty".
+	Note string
+
+	// Text is the string to translate.
+	Text string
+}
+
+// Failure is a string that could not be translated into a language.
+type Failure struct {
+	// Err is why the string was not translated: the request's error, or why
+	// the last translation was rejected.
+	Err error
+
+	// Key is the key of the string.
+	Key string
+
+	// Language is the language it was being translated into.
+	Language string
+
+	// Translation is the last translation rejected, if any.
+	Translation string
+}
+
+// Option configures a Translator.
+type Option func(*Translator) error
+
+// Result is the translations of a set of strings and what they took.
+type Result struct {
+	// Calls is the number of completion requests made.
+	Calls int
+
+	// Failures are the strings that could not be translated, by language and
+	// then in input order.
+	Failures []Failure
+
+	// Translations maps each language to the keys of the strings translated
+	// into it and their translations.
+	Translations map[string]map[string]string
+
+	// Usage is the total usage of all requests.
+	Usage providers.Usage
+}
+
+// Translator translates sets of strings.
+type Translator struct {
+	attempts       int
+	batchSize      int
+	batchTokens    int
+	concurrency    int
+	glossaries     map[string]map[string]string
+	instructions   string
+	model          string
+	patterns       []*regexp.Regexp
+	provider       providers.Provider
+	retry          retry.Policy
+	sourceLanguage string
+	validators     map[string][]Validator
+}
+
+// batch is the strings sent into one language in one request.
+type batch struct {
+	items    []*item
+	language string
+}
+
+// item is one string to translate into one language. After an attempt, err
+// is why it was not accepted, and retryable is set if it may be sent again,
+// with rejected holding the translation that was rejected, if any.
+type item struct {
+	entry     Entry
+	err       error
+	index     int
+	language  string
+	rejected  string
+	retryable bool
+}
+
+// requestString is a string as sent to the model.
+type requestString struct {
+	ID       string `json:"id"`
+	Note     string `json:"note,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+	Rejected string `json:"rejectedTranslation,omitempty"`
+	Text     string `json:"text"`
+}
+
+// response is the translations of a batch as returned by the model.
+type response struct {
+	Translations []struct {
+		ID   string `json:"id"`
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+// New returns a Translator that uses model on provider. Requests go through
+// structured.New, so the response schema is sent with the most reliable
+// mechanism the provider supports. By default it sends up to 25 strings of
+// about 2000 tokens in all per request, runs four requests at a time, and
+// gives each string three attempts to pass validation.
+func New(provider providers.Provider, model string, opts ...Option) (*Translator, error) {
+	wrapped, err := structured.New(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Translator{
+		attempts:    defaultAttempts,
+		batchSize:   defaultBatchSize,
+		batchTokens: defaultBatchTokens,
+		concurrency: defaultConcurrency,
+		glossaries:  map[string]map[string]string{},
+		model:       model,
+		patterns:    slices.Clone(defaultPlaceholders),
+		provider:    wrapped,
+		validators:  map[string][]Validator{},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// WithAttempts sets how many times a string is sent before a rejected
+// translation is reported as a failure.
+func WithAttempts(n int) Option {
+	return func(t *Translator) error {
+		if n <= 0 {
+			return fmt.Errorf("attempts must be positive, got %d", n)
+		}
+		t.attempts = n
+		return nil
+	}
+}
+
+// WithBatchSize sets the maximum number of strings per request, n, and the
+// estimated number of tokens they may add up to. A string larger than tokens
+// is sent on its own.
+func WithBatchSize(n int, tokens int) Option {
+	return func(t *Translator) error {
+		if n <= 0 || tokens <= 0 {
+			return fmt.Errorf("batch size must be positive, got %d strings and %d tokens", n, tokens)
+		}
+		t.batchSize = n
+		t.batchTokens = tokens
+		return nil
+	}
+}
+
+// WithConcurrency sets how many requests run at the same time.
+func WithConcurrency(n int) Option {
+	return func(t *Translator) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", n)
+		}
+		t.concurrency = n
+		return nil
+	}
+}
+
+// WithGlossary sets how terms are translated into language. Each batch's
+// prompt lists the terms that occur in its strings, matched without regard to
+// case. It may be given once per language.
+func WithGlossary(language string, terms map[string]string) Option {
+	return func(t *Translator) error {
+		if language == "" {
+			return fmt.Errorf("glossary language is required")
+		}
+		t.glossaries[language] = maps.Clone(terms)
+		return nil
+	}
+}
+
+// WithInstructions adds instructions to the prompt, such as the tone or
+// register to use.
+func WithInstructions(instructions string) Option {
+	return func(t *Translator) error {
+		t.instructions = instructions
+		return nil
+	}
+}
+
+// WithPlaceholders adds patterns for placeholders that must be kept as they
+// are, such as `:\w+` for Laravel-style placeholders. Mustache, printf and
+// Python placeholders are matched by default, and ICU arguments are always
+// checked.
+func WithPlaceholders(patterns ...*regexp.Regexp) Option {
+	return func(t *Translator) error {
+		if slices.Contains(patterns, nil) {
+			return fmt.Errorf("placeholder pattern is nil")
+		}
+		t.patterns = append(t.patterns, patterns...)
+		return nil
+	}
+}
+
+// WithRetry sets the policy for retrying failed requests, such as rate
+// limited ones. Without it, a failed request fails its strings.
+func WithRetry(policy retry.Policy) Option {
+	return func(t *Translator) error {
+		t.retry = policy
+		return nil
+	}
+}
+
+// WithSourceLanguage names the language the strings are in. Without it, the
+// model works it out.
+func WithSourceLanguage(language string) Option {
+	return func(t *Translator) error {
+		t.sourceLanguage = language
+		return nil
+	}
+}
+
+// WithValidator adds a check for translations into language, or into every
+// language if language is empty. It runs after the placeholder and ICU
+// checks, and a translation it rejects is sent again with its error.
+func WithValidator(language string, v Validator) Option {
+	return func(t *Translator) error {
+		if v == nil {
+			return fmt.Errorf("validator is nil")
+		}
+		t.validators[language] = append(t.validators[language], v)
+		return nil
+	}
+}
+
+// Translate translates entries into each of languages. Strings whose
+// translation is rejected are sent again, with the rejected translation and
+// the reason, until they pass or run out of attempts. Strings that cannot be
+// translated are reported in the result's Failures rather than as an error;
+// once ctx is done, the strings left fail with the context error. Empty
+// strings are kept as they are without a request.
+func (t *Translator) Translate(ctx context.Context, entries []Entry, languages ...string) (*Result, error) {
+	if err := validateInput(entries, languages); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Translations: make(map[string]map[string]string, len(languages))}
+	var pending []*item
+	for _, language := range languages {
+		result.Translations[language] = make(map[string]string, len(entries))
+		for i, entry := range entries {
+			if entry.Text == "" {
+				result.Translations[language][entry.Key] = ""
+				continue
+			}
+			pending = append(pending, &item{entry: entry, index: i, language: language})
+		}
+	}
+
+	var failed []*item
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var rejected []*item
+		for _, it := range t.translateAll(ctx, pending, result) {
+			if it.err == nil {
+				continue
+			}
+			if it.retryable && attempt < t.attempts && ctx.Err() == nil {
+				rejected = append(rejected, it)
+				continue
+			}
+			failed = append(failed, it)
+		}
+		pending = rejected
+	}
+
+	order := make(map[string]int, len(languages))
+	for i, language := range languages {
+		order[language] = i
+	}
+	slices.SortFunc(failed, func(a, b *item) int {
+		if a.language != b.language {
+			return order[a.language] - order[b.language]
+		}
+		return a.index - b.index
+	})
+	for _, it := range failed {
+		result.Failures = append(result.Failures, Failure{
+			Err:         it.err,
+			Key:         it.entry.Key,
+			Language:    it.language,
+			Translation: it.rejected,
+		})
+	}
+
+	return result, nil
+}
+
+// batches groups items by language, in order, and splits each group into
+// batches of at most batchSize strings and about batchTokens tokens.
+func (t *Translator) batches(items []*item) []batch {
+	var batches []batch
+	var current batch
+	tokens := 0
+
+	for _, it := range items {
+		size := tokencount.Estimate(it.entry.Text + it.entry.Note + it.rejected)
+		full := len(current.items) == t.batchSize || tokens+size > t.batchTokens
+		if len(current.items) > 0 && (current.language != it.language || full) {
+			batches = append(batches, current)
+			current, tokens = batch{}, 0
+		}
+		current.language = it.language
+		current.items = append(current.items, it)
+		tokens += size
+	}
+	if len(current.items) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// params returns the request for b.
+func (t *Translator) params(b batch) providers.CompletionParams {
+	prompt := fmt.Sprintf(promptInstructions, b.language)
+	if t.sourceLanguage != "" {
+		prompt += " " + fmt.Sprintf(sourceInstructions, t.sourceLanguage)
+	}
+	if t.instructions != "" {
+		prompt += "\n\n" + t.instructions
+	}
+	if terms := t.terms(b); len(terms) > 0 {
+		prompt += "\n\n" + glossaryInstructions + "\n" + strings.Join(terms, "\n")
+	}
+
+	strs := make([]requestString, len(b.items))
+	for i, it := range b.items {
+		strs[i] = requestString{ID: strconv.Itoa(i + 1), Note: it.entry.Note, Text: it.entry.Text}
+		if it.retryable {
+			strs[i].Problem = it.err.Error()
+			strs[i].Rejected = it.rejected
+		}
+	}
+	content, _ := json.Marshal(map[string]any{"strings": strs}) // Strings always marshal.
+
+	strict := true
+	return providers.CompletionParams{
+		Messages: []providers.Message{
+			{Role: providers.RoleSystem, Content: prompt},
+			{Role: providers.RoleUser, Content: string(content)},
+		},
+		Model: t.model,
+		ResponseFormat: &providers.ResponseFormat{
+			JSONSchema: &providers.JSONSchema{Name: "translations", Schema: responseSchema, Strict: &strict},
+			Type:       structured.ModeJSONSchema,
+		},
+	}
+}
+
+// terms returns the glossary entries for the terms that occur in b's strings,
+// sorted, as prompt lines.
+func (t *Translator) terms(b batch) []string {
+	glossary := t.glossaries[b.language]
+	if len(glossary) == 0 {
+		return nil
+	}
+
+	var text strings.Builder
+	for _, it := range b.items {
+		text.WriteString(strings.ToLower(it.entry.Text))
+		text.WriteByte('\n')
+	}
+
+	var terms []string
+	for _, term := range slices.Sorted(maps.Keys(glossary)) {
+		if strings.Contains(text.String(), strings.ToLower(term)) {
+			terms = append(terms, fmt.Sprintf("- %s: %s", term, glossary[term]))
+		}
+	}
+	return terms
+}
+
+// translateAll sends items in batches, adding the requests to result and the
+// accepted translations to its Translations. It returns items, with the
+// outcome of the attempt recorded on each.
+func (t *Translator) translateAll(ctx context.Context, items []*item, result *Result) []*item {
+	batches := t.batches(items)
+	params := make([]providers.CompletionParams, len(batches))
+	for i, b := range batches {
+		params[i] = t.params(b)
+	}
+
+	responses := bulk.CompleteAll(ctx, t.provider, params, bulk.Options{Concurrency: t.concurrency, Retry: t.retry})
+	for i, r := range responses {
+		b := batches[i]
+		if r.Err != nil {
+			for _, it := range b.items {
+				it.err, it.rejected, it.retryable = r.Err, "", false
+			}
+			continue
+		}
+
+		result.Calls++
+		if r.Response.Usage != nil {
+			result.Usage.PromptTokens += r.Response.Usage.PromptTokens
+			result.Usage.CompletionTokens += r.Response.Usage.CompletionTokens
+			result.Usage.TotalTokens += r.Response.Usage.TotalTokens
+		}
+
+		translations, err := parseResponse(r.Response)
+		for j, it := range b.items {
+			text, ok := translations[strconv.Itoa(j+1)]
+			switch {
+			case err != nil:
+				it.err = err
+			case !ok:
+				it.err = fmt.Errorf("translation is missing from the response")
+			default:
+				it.err = t.check(b.language, it.entry.Text, text)
+			}
+
+			it.rejected, it.retryable = text, it.err != nil
+			if it.err == nil {
+				result.Translations[b.language][it.entry.Key] = text
+			}
+		}
+	}
+
+	return items
+}
+
+// parseResponse returns the translations in resp by id.
+func parseResponse(resp *providers.ChatCompletion) (map[string]string, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("response has no choices")
+	}
+
+	var parsed response
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.ContentString()), &parsed); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	translations := make(map[string]string, len(parsed.Translations))
+	for _, tr := range parsed.Translations {
+		translations[tr.ID] = tr.Text
+	}
+	return translations, nil
+}
+
+// validateInput checks that there are distinct languages to translate into,
+// and that every entry has a unique key.
+func validateInput(entries []Entry, languages []string) error {
+	if len(languages) == 0 {
+		return fmt.Errorf("at least one language is required")
+	}
+	seenLanguages := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		if language == "" {
+			return fmt.Errorf("language must not be empty")
+		}
+		if seenLanguages[language] {
+			return fmt.Errorf("duplicate language %q", language)
+		}
+		seenLanguages[language] = true
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if entry.Key == "" {
+			return fmt.Errorf("entry %d has no key", i)
+		}
+		if seen[entry.Key] {
+			return fmt.Errorf("duplicate key %q", entry.Key)
+		}
+		seen[entry.Key] = true
+	}
+	return nil
+}
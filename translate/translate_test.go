@@ -0,0 +1,263 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// request is a batch as the fake localizer received it.
+type request struct {
+	language string
+	prompt   string
+	strings  []requestString
+}
+
+// localizer returns a mock that answers each batch with translate applied to
+// its strings, and a function that returns the batches received so far.
+func localizer(translate func(language string, s requestString) string) (*testutil.MockProvider, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+
+	mock := testutil.NewMockProvider()
+	mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+		prompt := params.Messages[0].ContentString()
+		_, rest, _ := strings.Cut(prompt, "into ")
+		language, _, _ := strings.Cut(rest, ".")
+
+		var body struct {
+			Strings []requestString `json:"strings"`
+		}
+		if err := json.Unmarshal([]byte(params.Messages[1].ContentString()), &body); err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		requests = append(requests, request{language: language, prompt: prompt, strings: body.Strings})
+		mu.Unlock()
+
+		var resp response
+		for _, s := range body.Strings {
+			resp.Translations = append(resp.Translations, struct {
+				ID   string `json:"id"`
+				Text string `json:"text"`
+			}{ID: s.ID, Text: translate(language, s)})
+		}
+		content, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return testutil.MockChatCompletion(string(content)), nil
+	}
+
+	return mock, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// tagged translates text by tagging it with the language.
+func tagged(language string, s requestString) string {
+	return "[" + language + "] " + s.Text
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opt     Option
+		wantErr string
+	}{
+		{name: "attempts", opt: WithAttempts(0), wantErr: "attempts must be positive, got 0"},
+		{
+			name:    "batch size",
+			opt:     WithBatchSize(10, 0),
+			wantErr: "batch size must be positive, got 10 strings and 0 tokens",
+		},
+		{name: "concurrency", opt: WithConcurrency(-1), wantErr: "concurrency must be positive, got -1"},
+		{name: "glossary", opt: WithGlossary("", nil), wantErr: "glossary language is required"},
+		{name: "placeholders", opt: WithPlaceholders(nil), wantErr: "placeholder pattern is nil"},
+		{name: "validator", opt: WithValidator("fr", nil), wantErr: "validator is nil"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(testutil.NewMockProvider(), "m", tc.opt)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Key: "cart.empty", Note: "shown when the cart is empty", Text: "Your cart is empty"},
+		{Key: "cart.checkout", Text: "Checkout"},
+		{Key: "blank", Text: ""},
+		{Key: "cart.items", Text: "{count, plural, one {# item} other {# items}}"},
+	}
+
+	t.Run("translates in batches per language", func(t *testing.T) {
+		t.Parallel()
+
+		provider, requests := localizer(tagged)
+		tr, err := New(provider, "m",
+			WithBatchSize(2, 1000),
+			WithGlossary("fr", map[string]string{"Cart": "panier", "wishlist": "liste d'envies"}),
+			WithSourceLanguage("English"),
+		)
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), entries, "fr", "de")
+		require.NoError(t, err)
+		require.Empty(t, result.Failures)
+		require.Equal(t, 4, result.Calls)
+		require.Equal(t, 60, result.Usage.TotalTokens)
+		require.Equal(t, map[string]string{
+			"blank":         "",
+			"cart.checkout": "[fr] Checkout",
+			"cart.empty":    "[fr] Your cart is empty",
+			"cart.items":    "[fr] {count, plural, one {# item} other {# items}}",
+		}, result.Translations["fr"])
+		require.Equal(t, "[de] Checkout", result.Translations["de"]["cart.checkout"])
+
+		for _, r := range requests() {
+			require.LessOrEqual(t, len(r.strings), 2)
+			require.Contains(t, r.prompt, "The strings are in English.")
+			if r.language == "de" {
+				require.NotContains(t, r.prompt, "panier")
+				continue
+			}
+			if r.strings[0].Text == "Your cart is empty" {
+				require.Contains(t, r.prompt, "- Cart: panier")
+				require.NotContains(t, r.prompt, "wishlist")
+				require.Equal(t, "shown when the cart is empty", r.strings[0].Note)
+			}
+		}
+	})
+
+	t.Run("resends rejected translations with the problem", func(t *testing.T) {
+		t.Parallel()
+
+		provider, requests := localizer(func(_ string, s requestString) string {
+			if s.Problem == "" {
+				return "Bonjour"
+			}
+			return "Bonjour, {name} !"
+		})
+		tr, err := New(provider, "m")
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), []Entry{{Key: "hello", Text: "Hello, {name}!"}}, "fr")
+		require.NoError(t, err)
+		require.Empty(t, result.Failures)
+		require.Equal(t, "Bonjour, {name} !", result.Translations["fr"]["hello"])
+		require.Equal(t, 2, result.Calls)
+
+		retried := requests()[1].strings[0]
+		require.Equal(t, "Bonjour", retried.Rejected)
+		require.Equal(t, `ICU argument "name" is missing`, retried.Problem)
+	})
+
+	t.Run("reports strings that run out of attempts", func(t *testing.T) {
+		t.Parallel()
+
+		provider, _ := localizer(func(string, requestString) string { return "Bonjour" })
+		tr, err := New(provider, "m", WithAttempts(2))
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), []Entry{
+			{Key: "bye", Text: "Goodbye"},
+			{Key: "hello", Text: "Hello, {name}!"},
+		}, "fr")
+		require.NoError(t, err)
+		require.Equal(t, 2, result.Calls)
+		require.Equal(t, map[string]string{"bye": "Bonjour"}, result.Translations["fr"])
+		require.Len(t, result.Failures, 1)
+
+		failure := result.Failures[0]
+		require.Equal(t, "hello", failure.Key)
+		require.Equal(t, "fr", failure.Language)
+		require.Equal(t, "Bonjour", failure.Translation)
+		require.EqualError(t, failure.Err, `ICU argument "name" is missing`)
+	})
+
+	t.Run("resends empty translations", func(t *testing.T) {
+		t.Parallel()
+
+		provider, requests := localizer(func(_ string, s requestString) string {
+			if s.Problem == "" {
+				return ""
+			}
+			return "Bonjour"
+		})
+		tr, err := New(provider, "m")
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), []Entry{{Key: "hello", Text: "Hello"}}, "fr")
+		require.NoError(t, err)
+		require.Equal(t, "Bonjour", result.Translations["fr"]["hello"])
+		require.Equal(t, "translation is empty", requests()[1].strings[0].Problem)
+	})
+
+	t.Run("fails strings of failed requests without resending them", func(t *testing.T) {
+		t.Parallel()
+
+		provider := testutil.NewMockProvider()
+		provider.CompletionFunc = func(context.Context, providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+		tr, err := New(provider, "m")
+		require.NoError(t, err)
+
+		result, err := tr.Translate(context.Background(), entries, "fr")
+		require.NoError(t, err)
+		require.Len(t, provider.CompletionCalls, 1)
+		require.Zero(t, result.Calls)
+		require.Len(t, result.Failures, 3)
+		require.Equal(t, "cart.empty", result.Failures[0].Key)
+		require.ErrorIs(t, result.Failures[0].Err, errors.ErrRateLimit)
+	})
+
+	t.Run("validates input", func(t *testing.T) {
+		t.Parallel()
+
+		tr, err := New(testutil.NewMockProvider(), "m")
+		require.NoError(t, err)
+
+		tests := []struct {
+			entries   []Entry
+			languages []string
+			wantErr   string
+		}{
+			{entries: entries, wantErr: "at least one language is required"},
+			{entries: entries, languages: []string{"fr", ""}, wantErr: "language must not be empty"},
+			{entries: entries, languages: []string{"fr", "fr"}, wantErr: `duplicate language "fr"`},
+			{entries: []Entry{{Text: "Hi"}}, languages: []string{"fr"}, wantErr: "entry 0 has no key"},
+			{
+				entries:   []Entry{{Key: "a", Text: "Hi"}, {Key: "a", Text: "Bye"}},
+				languages: []string{"fr"},
+				wantErr:   `duplicate key "a"`,
+			},
+		}
+		for i, tc := range tests {
+			_, err := tr.Translate(context.Background(), tc.entries, tc.languages...)
+			require.EqualError(t, err, tc.wantErr, fmt.Sprintf("case %d", i))
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ICU argument types whose options are sub-messages.
+const (
+	icuPlural        = "plural"
+	icuSelect        = "select"
+	icuSelectOrdinal = "selectordinal"
+)
+
+// defaultPlaceholders match placeholders outside ICU MessageFormat:
+// Mustache-style {{name}}, printf-style %s, %1$d and %.2f, and Python-style
+// %(name)s.
+var defaultPlaceholders = []*regexp.Regexp{
+	regexp.MustCompile(`\{\{[^{}]*\}\}`),
+	regexp.MustCompile(`%\(\w+\)[sd]`),
+	regexp.MustCompile(`%(?:\d+\$)?[-+0#]*\d*(?:\.\d+)?[sdifuxXeEgGcqvp]`),
+}
+
+// Validator checks a translation of source, returning why it is not
+// acceptable.
+type Validator func(source string, translation string) error
+
+// icuParser reads ICU MessageFormat arguments.
+type icuParser struct {
+	pos  int
+	text string
+}
+
+// check returns why translation is not an acceptable translation of source:
+// it is empty while source is not, it does not keep source's placeholders,
+// or its ICU MessageFormat arguments differ from source's. Arguments are
+// compared by name, type and, for plural and select, selectors, in any
+// order. ICU syntax is only checked when source is valid ICU, so that text
+// with literal braces is left alone.
+func (t *Translator) check(language string, source string, translation string) error {
+	if strings.TrimSpace(translation) == "" && strings.TrimSpace(source) != "" {
+		return fmt.Errorf("translation is empty")
+	}
+
+	if err := compare("placeholder", t.placeholders(source), t.placeholders(translation)); err != nil {
+		return err
+	}
+
+	sourceArgs, err := parseICU(t.stripPlaceholders(source))
+	if err == nil {
+		translationArgs, err := parseICU(t.stripPlaceholders(translation))
+		if err != nil {
+			return fmt.Errorf("invalid ICU syntax: %w", err)
+		}
+		if err := compare("ICU argument", sourceArgs, translationArgs); err != nil {
+			return err
+		}
+	}
+
+	for _, validate := range append(t.validators[""], t.validators[language]...) {
+		if err := validate(source, translation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeholders returns the placeholders in text, sorted.
+func (t *Translator) placeholders(text string) []string {
+	var found []string
+	for _, pattern := range t.patterns {
+		found = append(found, pattern.FindAllString(text, -1)...)
+		text = pattern.ReplaceAllString(text, "")
+	}
+	slices.Sort(found)
+	return found
+}
+
+// stripPlaceholders returns text without its placeholders, so that Mustache
+// braces are not read as ICU arguments.
+func (t *Translator) stripPlaceholders(text string) string {
+	for _, pattern := range t.patterns {
+		text = pattern.ReplaceAllString(text, "")
+	}
+	return text
+}
+
+// argument reads an argument after its opening brace, through its closing
+// brace, and returns its signature followed by those of nested arguments.
+func (p *icuParser) argument() ([]string, error) {
+	name, end := p.until(",}")
+	if end == 0 {
+		return nil, fmt.Errorf("unclosed '{'")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("argument without a name")
+	}
+	if end == '}' {
+		return []string{name}, nil
+	}
+
+	kind, end := p.until(",}")
+	switch {
+	case end == 0:
+		return nil, fmt.Errorf("unclosed '{'")
+	case end == '}':
+		return []string{name + "," + kind}, nil
+	case kind == icuPlural || kind == icuSelect || kind == icuSelectOrdinal:
+		return p.options(name + "," + kind)
+	default:
+		// A style such as "::currency/EUR" or "short"; styles are not translated.
+		style, end := p.until("}")
+		if end == 0 {
+			return nil, fmt.Errorf("unclosed '{'")
+		}
+		return []string{name + "," + kind + "," + style}, nil
+	}
+}
+
+// message reads text and arguments up to an unmatched closing brace, which it
+// consumes, or the end of the text if top is set.
+func (p *icuParser) message(top bool) ([]string, error) {
+	var args []string
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		p.pos++
+
+		switch c {
+		case '\'':
+			p.quoted()
+		case '{':
+			nested, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, nested...)
+		case '}':
+			if top {
+				return nil, fmt.Errorf("unmatched '}'")
+			}
+			return args, nil
+		}
+	}
+
+	if !top {
+		return nil, fmt.Errorf("unclosed '{'")
+	}
+	return args, nil
+}
+
+// options reads the selectors and sub-messages of a plural or select
+// argument through its closing brace.
+func (p *icuParser) options(signature string) ([]string, error) {
+	var selectors, nested []string
+	for {
+		p.skipSpace()
+		if p.pos == len(p.text) {
+			return nil, fmt.Errorf("unclosed '{'")
+		}
+		if p.text[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		selector, end := p.until("{}")
+		if end != '{' || selector == "" {
+			return nil, fmt.Errorf("option of %q without a message", signature)
+		}
+		if rest, ok := strings.CutPrefix(selector, "offset:"); ok {
+			// An offset precedes the first selector, as in "offset:1 =0".
+			i := strings.IndexFunc(rest, isSpace)
+			if i < 0 {
+				return nil, fmt.Errorf("option of %q without a message", signature)
+			}
+			selector = strings.TrimSpace(rest[i:])
+		}
+
+		args, err := p.message(false)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		nested = append(nested, args...)
+	}
+
+	slices.Sort(selectors)
+	return append([]string{signature + "[" + strings.Join(selectors, " ") + "]"}, nested...), nil
+}
+
+// quoted skips quoted literal text after an apostrophe. As in ICU, an
+// apostrophe only starts quoted text before a brace or another apostrophe.
+func (p *icuParser) quoted() {
+	if p.pos == len(p.text) {
+		return
+	}
+	switch p.text[p.pos] {
+	case '\'':
+		p.pos++
+	case '{', '}':
+		end := strings.IndexByte(p.text[p.pos:], '\'')
+		if end < 0 {
+			p.pos = len(p.text)
+			return
+		}
+		p.pos += end + 1
+	}
+}
+
+// skipSpace skips whitespace.
+func (p *icuParser) skipSpace() {
+	for p.pos < len(p.text) && isSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// until reads up to the first of stops, which it consumes, and returns the
+// text read, trimmed, and the stop found, or 0 at the end of the text.
+func (p *icuParser) until(stops string) (string, byte) {
+	i := strings.IndexAny(p.text[p.pos:], stops)
+	if i < 0 {
+		p.pos = len(p.text)
+		return "", 0
+	}
+
+	text := strings.TrimSpace(p.text[p.pos : p.pos+i])
+	stop := p.text[p.pos+i]
+	p.pos += i + 1
+	return text, stop
+}
+
+// compare returns an error naming the first item of want missing from got,
+// or the first item of got that is not in want. Both must be sorted.
+func compare(kind string, want []string, got []string) error {
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case j == len(got) || (i < len(want) && want[i] < got[j]):
+			return fmt.Errorf("%s %q is missing", kind, want[i])
+		case i == len(want) || got[j] < want[i]:
+			return fmt.Errorf("%s %q was added", kind, got[j])
+		default:
+			i++
+			j++
+		}
+	}
+	return nil
+}
+
+// isSpace reports whether r is ICU pattern whitespace.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// parseICU returns the signatures of the ICU MessageFormat arguments in
+// text, sorted: each argument's name and type, with the selectors of plural
+// and select arguments.
+func parseICU(text string) ([]string, error) {
+	p := &icuParser{text: text}
+	args, err := p.message(true)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(args)
+	return args, nil
+}
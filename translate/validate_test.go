@@ -0,0 +1,154 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	const plural = "{count, plural, =0 {No files} one {# file} other {# files}}"
+
+	tests := []struct {
+		name        string
+		source      string
+		translation string
+		wantErr     string
+	}{
+		{name: "plain text", source: "Save", translation: "Enregistrer"},
+		{name: "empty translation", source: "Save", translation: " ", wantErr: "translation is empty"},
+		{
+			name:        "reordered ICU arguments",
+			source:      "{user} shared {file}",
+			translation: "{file} partagé par {user}",
+		},
+		{
+			name:        "missing ICU argument",
+			source:      "Hello, {name}!",
+			translation: "Bonjour !",
+			wantErr:     `ICU argument "name" is missing`,
+		},
+		{
+			name:        "renamed ICU argument",
+			source:      "Hello, {name}!",
+			translation: "Bonjour, {nom} !",
+			wantErr:     `ICU argument "name" is missing`,
+		},
+		{
+			name:        "translated plural options",
+			source:      plural,
+			translation: "{count, plural, =0 {Aucun fichier} one {# fichier} other {# fichiers}}",
+		},
+		{
+			name:        "added plural selector",
+			source:      plural,
+			translation: "{count, plural, =0 {Aucun} one {# plik} few {# pliki} other {# plików}}",
+			wantErr:     `ICU argument "count,plural[=0 few one other]" was added`,
+		},
+		{
+			name:        "translated selector",
+			source:      "{gender, select, male {He} female {She} other {They}} replied",
+			translation: "{gender, select, homme {Il} femme {Elle} other {Iel}} a répondu",
+			wantErr:     `ICU argument "gender,select[female male other]" is missing`,
+		},
+		{
+			name:        "nested arguments",
+			source:      "{count, plural, offset:1 one {{name} liked it} other {{name} and # others liked it}}",
+			translation: "{count, plural, offset:1 one {{name} a aimé} other {{name} et # autres ont aimé}}",
+		},
+		{
+			name:        "unclosed argument",
+			source:      "Hello, {name}!",
+			translation: "Bonjour, {name !",
+			wantErr:     "invalid ICU syntax: unclosed '{'",
+		},
+		{
+			name:        "quoted braces",
+			source:      "Use '{name}' for {what}",
+			translation: "Utilisez '{name}' pour {what}",
+		},
+		{
+			name:        "apostrophe before an argument quotes it",
+			source:      "Open {file}",
+			translation: "Ouvrir l'{file}",
+			wantErr:     `ICU argument "file" is missing`,
+		},
+		{
+			name:        "literal braces in a source that is not ICU",
+			source:      "Send {\"a\": 1} or }",
+			translation: "Envoyez",
+		},
+		{
+			name:        "printf placeholders",
+			source:      "%d of %s",
+			translation: "%s : %d",
+		},
+		{
+			name:        "missing printf placeholder",
+			source:      "%1$s has %2$d items",
+			translation: "%1$s a des articles",
+			wantErr:     `placeholder "%2$d" is missing`,
+		},
+		{
+			name:        "percent sign is not a placeholder",
+			source:      "50% off",
+			translation: "50 % de réduction",
+		},
+		{
+			name:        "mustache placeholders",
+			source:      "Hi {{ user.name }}",
+			translation: "Salut",
+			wantErr:     `placeholder "{{ user.name }}" is missing`,
+		},
+		{
+			name:        "added placeholder",
+			source:      "Done",
+			translation: "Fait %(count)s",
+			wantErr:     `placeholder "%(count)s" was added`,
+		},
+	}
+
+	tr, err := New(testutil.NewMockProvider(), "m")
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tr.check("fr", tc.source, tc.translation)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	t.Parallel()
+
+	noExclamation := func(_ string, translation string) error {
+		if strings.HasSuffix(translation, "!") && !strings.HasSuffix(translation, " !") {
+			return fmt.Errorf("French puts a space before '!'")
+		}
+		return nil
+	}
+	tr, err := New(testutil.NewMockProvider(), "m",
+		WithPlaceholders(regexp.MustCompile(`:\w+`)),
+		WithValidator("fr", noExclamation),
+	)
+	require.NoError(t, err)
+
+	require.EqualError(t, tr.check("fr", "Hi :name", "Salut"), `placeholder ":name" is missing`)
+	require.EqualError(t, tr.check("fr", "Hi :name!", "Salut :name!"), "French puts a space before '!'")
+	require.NoError(t, tr.check("fr", "Hi :name!", "Salut :name !"))
+	require.NoError(t, tr.check("de", "Hi :name!", "Hallo :name!"))
+}
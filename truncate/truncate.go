@@ -0,0 +1,271 @@
+// Package truncate wraps a provider so requests that overflow the model's
+// context window are retried once with the oldest messages removed.
+package truncate
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/tokencount"
+)
+
+// defaultTrimRatio is the share of conversation tokens removed when no budget is set.
+const defaultTrimRatio = 0.25
+
+// Ensure Provider implements the required interfaces.
+var _ providers.Provider = (*Provider)(nil)
+
+// Option configures a Provider.
+type Option func(*Provider) error
+
+// Provider wraps a provider and retries context length errors with a trimmed conversation.
+type Provider struct {
+	budget   int
+	counter  *tokencount.Counter
+	provider providers.Provider
+}
+
+// New wraps provider so a request failing with errors.ErrContextLength is
+// retried once after trimming the oldest turns of the conversation.
+func New(provider providers.Provider, opts ...Option) (*Provider, error) {
+	counter, err := tokencount.New(provider)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{counter: counter, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithTokenBudget trims the conversation to at most tokens before retrying.
+// Without a budget, the conversation is trimmed to the model's context length
+// less MaxTokens when the provider reports it with a model card, and otherwise
+// roughly a quarter of the conversation is removed.
+func WithTokenBudget(tokens int) Option {
+	return func(p *Provider) error {
+		if tokens <= 0 {
+			return fmt.Errorf("token budget must be positive, got %d", tokens)
+		}
+
+		p.budget = tokens
+		return nil
+	}
+}
+
+// Completion performs a chat completion request, trimming and retrying once on context overflow.
+func (p *Provider) Completion(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (*providers.ChatCompletion, error) {
+	resp, err := p.provider.Completion(ctx, params)
+	if !stderrors.Is(err, errors.ErrContextLength) {
+		return resp, err
+	}
+
+	trimmed, ok := p.trim(ctx, params.Messages, p.targetBudget(ctx, params))
+	if !ok {
+		return nil, err
+	}
+
+	params.Messages = trimmed
+	return p.provider.Completion(ctx, params)
+}
+
+// CompletionStream performs a streaming chat completion request.
+// The stream is retried only if it fails with a context length error before
+// delivering its first chunk.
+func (p *Provider) CompletionStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+) (<-chan providers.ChatCompletionChunk, <-chan error) {
+	chunks := make(chan providers.ChatCompletionChunk)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+
+		started, err := p.forwardStream(ctx, params, chunks)
+		if started || !stderrors.Is(err, errors.ErrContextLength) {
+			if err != nil {
+				errs <- err
+			}
+			return
+		}
+
+		trimmed, ok := p.trim(ctx, params.Messages, p.targetBudget(ctx, params))
+		if !ok {
+			errs <- err
+			return
+		}
+
+		params.Messages = trimmed
+		if _, err := p.forwardStream(ctx, params, chunks); err != nil {
+			errs <- err
+		}
+	}()
+
+	return chunks, errs
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider, for access to its optional interfaces.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// forwardStream runs one streaming attempt, forwarding its chunks to out.
+// It reports whether any chunk was forwarded and the attempt's error, if any.
+func (p *Provider) forwardStream(
+	ctx context.Context,
+	params providers.CompletionParams,
+	out chan<- providers.ChatCompletionChunk,
+) (bool, error) {
+	chunks, errs := p.provider.CompletionStream(ctx, params)
+
+	started := false
+	for chunk := range chunks {
+		select {
+		case out <- chunk:
+			started = true
+		case <-ctx.Done():
+			return started, ctx.Err()
+		}
+	}
+
+	return started, <-errs
+}
+
+// targetBudget returns the token budget to trim to: the configured budget, or
+// the model card's context length less the tokens reserved for the reply.
+// It returns 0 when neither is known.
+func (p *Provider) targetBudget(ctx context.Context, params providers.CompletionParams) int {
+	if p.budget > 0 {
+		return p.budget
+	}
+
+	cards, ok := providers.As[providers.ModelCardProvider](p.provider)
+	if !ok {
+		return 0
+	}
+	card, err := cards.ModelCard(ctx, params.Model)
+	if err != nil || card.ContextLength == 0 {
+		return 0
+	}
+
+	budget := card.ContextLength
+	if params.MaxTokens != nil {
+		budget -= *params.MaxTokens
+	}
+	return max(budget, 0)
+}
+
+// trim removes the oldest turns until the conversation fits the budget (or has
+// shrunk by the default ratio when budget is 0). A turn is a user message and
+// the messages that answer it, so the kept history still starts with a user
+// message. When only the latest turn is left, the oldest assistant messages
+// after its user message go instead, each with its tool results. System
+// messages, the latest user message and the latest assistant message with its
+// tool results are always kept. It reports false when nothing could be removed.
+func (p *Provider) trim(
+	ctx context.Context,
+	messages []providers.Message,
+	budget int,
+) ([]providers.Message, bool) {
+	counts := make([]int, len(messages))
+	total := 0
+	for i, msg := range messages {
+		counts[i], _ = p.counter.Count(ctx, msg) // Estimates are good enough to trim by.
+		total += counts[i]
+	}
+
+	target := budget
+	if target == 0 {
+		target = int(float64(total) * (1 - defaultTrimRatio))
+	}
+
+	removed := make([]bool, len(messages))
+	removedAny := false
+	remove := func(span [2]int) {
+		for i := span[0]; i < span[1]; i++ {
+			if messages[i].Role == providers.RoleSystem {
+				continue
+			}
+			removed[i] = true
+			removedAny = true
+			total -= counts[i]
+		}
+	}
+
+	turns := spans(messages, 0, func(msg providers.Message) bool { return msg.Role == providers.RoleUser })
+	if len(turns) == 0 {
+		return nil, false
+	}
+	for _, turn := range turns[:len(turns)-1] {
+		if total <= target {
+			break
+		}
+		remove(turn)
+	}
+
+	// Within the latest turn, tool results go with the assistant message
+	// that requested them.
+	latest := turns[len(turns)-1]
+	if total > target && messages[latest[0]].Role == providers.RoleUser {
+		replies := spans(messages, latest[0]+1, func(msg providers.Message) bool {
+			return msg.Role != providers.RoleTool
+		})
+		for i := 0; i < len(replies)-1 && total > target; i++ {
+			remove(replies[i])
+		}
+	}
+
+	if !removedAny {
+		return nil, false
+	}
+
+	result := make([]providers.Message, 0, len(messages))
+	for i, msg := range messages {
+		if !removed[i] {
+			result = append(result, msg)
+		}
+	}
+
+	return result, true
+}
+
+// spans splits the non-system messages from start on into [start, end) index
+// ranges, each beginning at a message for which begins reports true. Messages
+// before the first such message form a span of their own. System messages
+// inside a span are left for the caller to skip.
+func spans(messages []providers.Message, start int, begins func(providers.Message) bool) [][2]int {
+	var result [][2]int
+	for i := start; i < len(messages); i++ {
+		if messages[i].Role == providers.RoleSystem {
+			continue
+		}
+		if len(result) == 0 || begins(messages[i]) {
+			result = append(result, [2]int{i, len(messages)})
+			if len(result) > 1 {
+				result[len(result)-2][1] = i
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,281 @@
+package truncate
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// conversation returns a system prompt followed by alternating user and assistant turns.
+func conversation() []providers.Message {
+	return []providers.Message{
+		{Role: providers.RoleSystem, Content: "be brief"},
+		{Role: providers.RoleUser, Content: "one two three four"},
+		{Role: providers.RoleAssistant, Content: "five six seven eight"},
+		{Role: providers.RoleUser, Content: "nine ten"},
+	}
+}
+
+// contents returns the text content of each message.
+func contents(messages []providers.Message) []string {
+	result := make([]string, len(messages))
+	for i, msg := range messages {
+		result[i] = msg.ContentString()
+	}
+	return result
+}
+
+func contextLengthError() error {
+	return errors.NewContextLengthError("mock", stderrors.New("too long"))
+}
+
+// toolCall returns an assistant message requesting a weather lookup.
+func toolCall(id string) providers.Message {
+	return providers.Message{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{
+		{ID: id, Function: providers.FunctionCall{Name: "get_weather", Arguments: `{}`}},
+	}}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(testutil.NewMockProvider(), WithTokenBudget(0))
+	require.Error(t, err)
+
+	provider, err := New(testutil.NewMockProvider(), WithTokenBudget(100))
+	require.NoError(t, err)
+	require.Equal(t, 100, provider.budget)
+	require.Equal(t, "mock", provider.Name())
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("trims and retries once on context overflow", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if len(params.Messages) == 4 {
+				return nil, contextLengthError()
+			}
+			return testutil.MockChatCompletion("ok"), nil
+		}
+
+		provider, err := New(testutil.NewTokenCountingMock(mock), WithTokenBudget(8))
+		require.NoError(t, err)
+
+		resp, err := provider.Completion(context.Background(), providers.CompletionParams{Messages: conversation()})
+		require.NoError(t, err)
+		require.Equal(t, "ok", resp.Choices[0].Message.Content)
+
+		require.Len(t, mock.CompletionCalls, 2)
+		retried := mock.CompletionCalls[1].Messages
+		require.Len(t, retried, 2)
+		require.Equal(t, providers.RoleSystem, retried[0].Role)
+		require.Equal(t, "nine ten", retried[1].Content)
+	})
+
+	t.Run("returns other errors unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, errors.NewRateLimitError("mock", stderrors.New("slow down"))
+		}
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{Messages: conversation()})
+		require.ErrorIs(t, err, errors.ErrRateLimit)
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+
+	t.Run("returns error when nothing can be trimmed", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, _ providers.CompletionParams) (*providers.ChatCompletion, error) {
+			return nil, contextLengthError()
+		}
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+
+		_, err = provider.Completion(context.Background(), providers.CompletionParams{
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "a very long question"}},
+		})
+		require.ErrorIs(t, err, errors.ErrContextLength)
+		require.Len(t, mock.CompletionCalls, 1)
+	})
+}
+
+func TestCompletionStream(t *testing.T) {
+	t.Parallel()
+
+	mock := testutil.NewMockProvider()
+	succeed := mock.CompletionStreamFunc
+	mock.CompletionStreamFunc = func(
+		ctx context.Context,
+		params providers.CompletionParams,
+	) (<-chan providers.ChatCompletionChunk, <-chan error) {
+		if len(params.Messages) == 4 {
+			chunks := make(chan providers.ChatCompletionChunk)
+			errs := make(chan error, 1)
+			close(chunks)
+			errs <- contextLengthError()
+			close(errs)
+			return chunks, errs
+		}
+		return succeed(ctx, params)
+	}
+
+	provider, err := New(mock)
+	require.NoError(t, err)
+
+	chunks, errs := provider.CompletionStream(context.Background(), providers.CompletionParams{Messages: conversation()})
+	count := 0
+	for range chunks {
+		count++
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, 3, count)
+	require.Len(t, mock.CompletionStreamCalls, 2)
+}
+
+func TestTrim(t *testing.T) {
+	t.Parallel()
+
+	t.Run("removes tool results with their call", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewMockProvider(), WithTokenBudget(1))
+		require.NoError(t, err)
+
+		trimmed, ok := provider.trim(context.Background(), []providers.Message{
+			{Role: providers.RoleSystem, Content: "be brief"},
+			{Role: providers.RoleAssistant, ToolCalls: []providers.ToolCall{
+				{ID: "call_1", Function: providers.FunctionCall{Name: "get_weather", Arguments: `{}`}},
+			}},
+			{Role: providers.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+			{Role: providers.RoleUser, Content: "thanks"},
+		}, 1)
+		require.True(t, ok)
+		require.Len(t, trimmed, 2)
+		require.Equal(t, providers.RoleSystem, trimmed[0].Role)
+		require.Equal(t, "thanks", trimmed[1].Content)
+	})
+
+	t.Run("removes a quarter of the conversation by default", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()))
+		require.NoError(t, err)
+
+		// 12 tokens in total; the target is 9, so only the oldest turn goes.
+		trimmed, ok := provider.trim(context.Background(), conversation(), 0)
+		require.True(t, ok)
+		require.Len(t, trimmed, 2)
+		require.Equal(t, "nine ten", trimmed[1].Content)
+	})
+
+	t.Run("keeps the history starting with a user message", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()), WithTokenBudget(9))
+		require.NoError(t, err)
+
+		trimmed, ok := provider.trim(context.Background(), []providers.Message{
+			{Role: providers.RoleSystem, Content: "be brief"},
+			{Role: providers.RoleUser, Content: "one two three four five six"},
+			{Role: providers.RoleAssistant, Content: "seven"},
+			{Role: providers.RoleUser, Content: "eight"},
+			{Role: providers.RoleAssistant, Content: "nine"},
+			{Role: providers.RoleUser, Content: "ten"},
+		}, 9)
+		require.True(t, ok)
+		require.Equal(t, []string{"be brief", "eight", "nine", "ten"}, contents(trimmed))
+		require.Equal(t, providers.RoleUser, trimmed[1].Role)
+	})
+
+	t.Run("keeps the latest tool call with its results", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()), WithTokenBudget(1))
+		require.NoError(t, err)
+
+		_, ok := provider.trim(context.Background(), []providers.Message{
+			{Role: providers.RoleUser, Content: "what is the weather"},
+			toolCall("call_1"),
+			{Role: providers.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+		}, 1)
+		require.False(t, ok)
+	})
+
+	t.Run("removes older tool calls within the latest turn", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewTokenCountingMock(testutil.NewMockProvider()), WithTokenBudget(1))
+		require.NoError(t, err)
+
+		trimmed, ok := provider.trim(context.Background(), []providers.Message{
+			{Role: providers.RoleUser, Content: "what is the weather"},
+			toolCall("call_1"),
+			{Role: providers.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+			toolCall("call_2"),
+			{Role: providers.RoleTool, ToolCallID: "call_2", Content: "warm"},
+		}, 1)
+		require.True(t, ok)
+		require.Equal(t, []string{"what is the weather", "", "warm"}, contents(trimmed))
+		require.Equal(t, "call_2", trimmed[1].ToolCalls[0].ID)
+		testutil.RequireToolPairing(t, testutil.MessageEvents(trimmed))
+	})
+}
+
+func TestTargetBudget(t *testing.T) {
+	t.Parallel()
+
+	maxTokens := 2
+	params := providers.CompletionParams{Model: "model", Messages: conversation(), MaxTokens: &maxTokens}
+	mock := testutil.ModelCardMock{
+		TokenCountingMock: testutil.NewTokenCountingMock(testutil.NewMockProvider()),
+		ContextLength:     8,
+	}
+
+	t.Run("uses the model card's context length less MaxTokens", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(mock)
+		require.NoError(t, err)
+		require.Equal(t, 6, provider.targetBudget(context.Background(), params))
+
+		// 12 tokens in total; both older turns go to fit 6.
+		trimmed, ok := provider.trim(context.Background(), params.Messages, 6)
+		require.True(t, ok)
+		require.Len(t, trimmed, 2)
+		require.Equal(t, "nine ten", trimmed[1].Content)
+	})
+
+	t.Run("prefers the configured budget", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(mock, WithTokenBudget(10))
+		require.NoError(t, err)
+		require.Equal(t, 10, provider.targetBudget(context.Background(), params))
+	})
+
+	t.Run("is unset without a model card", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New(testutil.NewMockProvider())
+		require.NoError(t, err)
+		require.Zero(t, provider.targetBudget(context.Background(), params))
+	})
+}
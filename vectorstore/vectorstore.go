@@ -0,0 +1,358 @@
+// Package vectorstore is a small in-memory vector store. It embeds documents
+// with a provider's embedding model and finds those closest to a query by
+// cosine similarity, and it provides a prebuilt "search_memory" tool for the
+// agent package, so that a retrieval-augmented agent can be built without an
+// external vector database. Search is exhaustive, which is fast enough for
+// tens of thousands of documents; move to a vector database beyond that.
+package vectorstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mozilla-ai/any-llm-go/agent"
+	"github.com/mozilla-ai/any-llm-go/errors"
+	"github.com/mozilla-ai/any-llm-go/providers"
+	"github.com/mozilla-ai/any-llm-go/ragpack"
+)
+
+// SearchMemoryTool is the name of the tool returned by Store.SearchTool.
+const SearchMemoryTool = "search_memory"
+
+// defaultBatchSize is how many documents are embedded per request.
+const defaultBatchSize = 64
+
+// Document is a piece of text to store.
+type Document struct {
+	// ID identifies the document. Adding a document with an ID already in the
+	// store replaces it.
+	ID string `json:"id"`
+
+	// Metadata holds caller data about the document, returned with matches.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Source says where the document is from, such as a URL or file name.
+	Source string `json:"source,omitempty"`
+
+	// Text is the document's text. It is what is embedded.
+	Text string `json:"text"`
+}
+
+// Match is a document found by a search.
+type Match struct {
+	Document
+
+	// Embedding is the document's embedding.
+	Embedding []float64 `json:"-"`
+
+	// Score is the cosine similarity of the document to the query, from -1 to
+	// 1. Higher is closer.
+	Score float64 `json:"score"`
+}
+
+// Option configures a Store.
+type Option func(*Store) error
+
+// Store holds documents and their embeddings. It is safe for concurrent use.
+type Store struct {
+	batchSize  int
+	dimensions int
+	entries    []entry
+	ids        map[string]int
+	model      string
+	mu         sync.RWMutex
+	provider   providers.EmbeddingProvider
+}
+
+// entry is a stored document.
+type entry struct {
+	doc       Document
+	embedding []float64
+}
+
+// New returns an empty Store that embeds with model on provider.
+func New(provider providers.EmbeddingProvider, model string, opts ...Option) (*Store, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("embedding provider is required")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("embedding model is required")
+	}
+
+	s := &Store{batchSize: defaultBatchSize, ids: make(map[string]int), model: model, provider: provider}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// WithBatchSize sets how many documents are embedded per request. The default
+// is 64.
+func WithBatchSize(size int) Option {
+	return func(s *Store) error {
+		if size <= 0 {
+			return fmt.Errorf("batch size must be positive, got %d", size)
+		}
+
+		s.batchSize = size
+		return nil
+	}
+}
+
+// Add embeds docs and stores them. Documents whose ID is already stored are
+// replaced. If embedding fails, nothing is stored.
+func (s *Store) Add(ctx context.Context, docs ...Document) error {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		if doc.ID == "" {
+			return fmt.Errorf("document %d has no ID", i)
+		}
+		texts[i] = doc.Text
+	}
+
+	embeddings, err := s.embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkDimensions(embeddings); err != nil {
+		return err
+	}
+	for i, doc := range docs {
+		e := entry{doc: doc, embedding: embeddings[i]}
+		if idx, ok := s.ids[doc.ID]; ok {
+			s.entries[idx] = e
+			continue
+		}
+		s.ids[doc.ID] = len(s.entries)
+		s.entries = append(s.entries, e)
+	}
+	return nil
+}
+
+// Delete removes the documents with the given IDs and returns how many were
+// stored.
+func (s *Store) Delete(ids ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		idx, ok := s.ids[id]
+		if !ok {
+			continue
+		}
+
+		// Move the last entry into the gap.
+		last := len(s.entries) - 1
+		s.entries[idx] = s.entries[last]
+		s.ids[s.entries[idx].doc.ID] = idx
+		s.entries = s.entries[:last]
+		delete(s.ids, id)
+		deleted++
+	}
+	return deleted
+}
+
+// Get returns the document with the given ID.
+func (s *Store) Get(id string) (Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, ok := s.ids[id]
+	if !ok {
+		return Document{}, false
+	}
+	return s.entries[idx].doc, true
+}
+
+// Len returns the number of stored documents.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.entries)
+}
+
+// Search embeds query and returns the k documents closest to it, closest
+// first.
+func (s *Store) Search(ctx context.Context, query string, k int) ([]Match, error) {
+	embeddings, err := s.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return s.SearchEmbedding(embeddings[0], k)
+}
+
+// SearchEmbedding returns the k documents closest to embedding, closest
+// first. The embedding must come from the store's model.
+func (s *Store) SearchEmbedding(embedding []float64, k int) ([]Match, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.entries) == 0 {
+		return nil, nil
+	}
+	if len(embedding) != s.dimensions {
+		return nil, fmt.Errorf("query embedding has %d dimensions, want %d", len(embedding), s.dimensions)
+	}
+
+	matches := make([]Match, len(s.entries))
+	for i, e := range s.entries {
+		matches[i] = Match{Document: e.doc, Embedding: e.embedding, Score: cosine(embedding, e.embedding)}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return matches[:min(k, len(matches))], nil
+}
+
+// SearchTool returns a tool that searches the store, and its function, for
+// agent.RunTools. The tool is named SearchMemoryTool and takes a query; it
+// returns the k closest documents, numbered and formatted by
+// ragpack.DefaultFormat.
+//
+//	tool, fn := store.SearchTool(4)
+//	params.Tools = append(params.Tools, tool)
+//	result, err := agent.RunTools(ctx, provider, params, map[string]agent.ToolFunc{vectorstore.SearchMemoryTool: fn})
+func (s *Store) SearchTool(k int) (providers.Tool, agent.ToolFunc) {
+	tool := providers.Tool{
+		Type: "function",
+		Function: providers.Function{
+			Name:        SearchMemoryTool,
+			Description: "Search memory for passages relevant to a query. Returns the closest passages, most relevant first.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "What to search for, in natural language.",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+
+	fn := func(ctx context.Context, arguments string) (providers.ToolResult, error) {
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return providers.ToolResult{}, fmt.Errorf("invalid arguments: %w", err)
+		}
+		if strings.TrimSpace(args.Query) == "" {
+			return providers.ToolResult{}, fmt.Errorf("query is required")
+		}
+
+		matches, err := s.Search(ctx, args.Query, k)
+		if err != nil {
+			return providers.ToolResult{}, err
+		}
+		if len(matches) == 0 {
+			return providers.ToolResult{Content: "No matching passages."}, nil
+		}
+
+		lines := make([]string, len(matches))
+		for i, m := range matches {
+			lines[i] = ragpack.DefaultFormat(i+1, m.Snippet())
+		}
+		return providers.ToolResult{Content: strings.Join(lines, "\n\n")}, nil
+	}
+
+	return tool, fn
+}
+
+// Snippet returns the match as a ragpack snippet, to pack matches into a
+// context block.
+func (m Match) Snippet() ragpack.Snippet {
+	return ragpack.Snippet{
+		Embedding: m.Embedding,
+		ID:        m.ID,
+		Score:     m.Score,
+		Source:    m.Source,
+		Text:      m.Text,
+	}
+}
+
+// checkDimensions checks that embeddings all have the store's dimensions,
+// and sets them from the first embeddings stored. The caller holds the lock.
+func (s *Store) checkDimensions(embeddings [][]float64) error {
+	dims := s.dimensions
+	for i, e := range embeddings {
+		if len(e) == 0 {
+			return fmt.Errorf("embedding %d is empty", i)
+		}
+		if dims == 0 {
+			dims = len(e)
+		}
+		if len(e) != dims {
+			return fmt.Errorf("embedding %d has %d dimensions, want %d", i, len(e), dims)
+		}
+	}
+
+	s.dimensions = dims
+	return nil
+}
+
+// embed returns the embeddings of texts, in order.
+func (s *Store) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+
+	for start := 0; start < len(texts); start += s.batchSize {
+		end := min(start+s.batchSize, len(texts))
+
+		resp, err := s.provider.Embedding(ctx, providers.EmbeddingParams{Model: s.model, Input: texts[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != end-start {
+			return nil, errors.NewProviderError(s.provider.Name(),
+				fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), end-start))
+		}
+
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= end-start {
+				return nil, errors.NewProviderError(s.provider.Name(), fmt.Errorf("embedding index %d out of range", data.Index))
+			}
+			embeddings[start+data.Index] = data.Embedding
+		}
+	}
+
+	return embeddings, nil
+}
+
+// cosine returns the cosine similarity of x and y, which have the same length,
+// or 0 if either is zero.
+func cosine(x []float64, y []float64) float64 {
+	var dot, normX, normY float64
+	for i := range x {
+		dot += x[i] * y[i]
+		normX += x[i] * x[i]
+		normY += y[i] * y[i]
+	}
+	if normX == 0 || normY == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normX*normY)
+}
@@ -0,0 +1,246 @@
+package vectorstore
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mozilla-ai/any-llm-go/agent"
+	"github.com/mozilla-ai/any-llm-go/internal/testutil"
+	"github.com/mozilla-ai/any-llm-go/providers"
+)
+
+// testTopics are the axes of topicEmbedding.
+var testTopics = []string{"cat", "dog", "fish"}
+
+// topicEmbedding embeds text by how often it mentions each of testTopics.
+func topicEmbedding(text string) []float64 {
+	embedding := make([]float64, len(testTopics))
+	for i, topic := range testTopics {
+		embedding[i] = float64(strings.Count(text, topic))
+	}
+	return embedding
+}
+
+// testStore returns a store holding a document about each topic.
+func testStore(t *testing.T, opts ...Option) (*Store, *testutil.MockProvider) {
+	t.Helper()
+
+	mock := testutil.NewEmbeddingMock(topicEmbedding)
+	store, err := New(mock, "embed-v1", opts...)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(context.Background(),
+		Document{ID: "cats", Source: "pets.md", Text: "A cat sleeps. Every cat purrs."},
+		Document{ID: "dogs", Text: "A dog barks at the cat."},
+		Document{ID: "fish", Text: "A fish swims.", Metadata: map[string]string{"tank": "3"}},
+	))
+	return store, mock
+}
+
+// testIDs returns the IDs of matches.
+func testIDs(matches []Match) []string {
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(nil, "embed-v1")
+	require.EqualError(t, err, "embedding provider is required")
+
+	_, err = New(testutil.NewEmbeddingMock(topicEmbedding), "")
+	require.EqualError(t, err, "embedding model is required")
+
+	_, err = New(testutil.NewEmbeddingMock(topicEmbedding), "embed-v1", WithBatchSize(0))
+	require.EqualError(t, err, "batch size must be positive, got 0")
+}
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	t.Run("embeds in batches", func(t *testing.T) {
+		t.Parallel()
+
+		store, mock := testStore(t, WithBatchSize(2))
+		require.Equal(t, 3, store.Len())
+		require.Len(t, mock.EmbeddingCalls, 2)
+		require.Equal(t, "embed-v1", mock.EmbeddingCalls[0].Model)
+
+		doc, ok := store.Get("fish")
+		require.True(t, ok)
+		require.Equal(t, "3", doc.Metadata["tank"])
+	})
+
+	t.Run("replaces a document with the same ID", func(t *testing.T) {
+		t.Parallel()
+
+		store, _ := testStore(t)
+		require.NoError(t, store.Add(context.Background(), Document{ID: "fish", Text: "A dog."}))
+		require.Equal(t, 3, store.Len())
+
+		matches, err := store.Search(context.Background(), "dog", 2)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"dogs", "fish"}, testIDs(matches))
+	})
+
+	t.Run("rejects documents without an ID", func(t *testing.T) {
+		t.Parallel()
+
+		store, _ := testStore(t)
+		err := store.Add(context.Background(), Document{Text: "no ID"})
+		require.EqualError(t, err, "document 0 has no ID")
+	})
+
+	t.Run("stores nothing when embedding fails", func(t *testing.T) {
+		t.Parallel()
+
+		store, mock := testStore(t)
+		mock.EmbeddingFunc = func(context.Context, providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			return nil, stderrors.New("rate limited")
+		}
+
+		err := store.Add(context.Background(), Document{ID: "birds", Text: "A bird sings."})
+		require.EqualError(t, err, "rate limited")
+		require.Equal(t, 3, store.Len())
+	})
+
+	t.Run("rejects embeddings of other dimensions", func(t *testing.T) {
+		t.Parallel()
+
+		store, mock := testStore(t)
+		mock.EmbeddingFunc = func(context.Context, providers.EmbeddingParams) (*providers.EmbeddingResponse, error) {
+			return &providers.EmbeddingResponse{Data: []providers.EmbeddingData{{Embedding: []float64{1, 0}}}}, nil
+		}
+
+		err := store.Add(context.Background(), Document{ID: "birds", Text: "A bird sings."})
+		require.EqualError(t, err, "embedding 0 has 2 dimensions, want 3")
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	store, _ := testStore(t)
+	require.Equal(t, 1, store.Delete("cats", "unknown"))
+	require.Equal(t, 2, store.Len())
+
+	_, ok := store.Get("cats")
+	require.False(t, ok)
+	doc, ok := store.Get("fish")
+	require.True(t, ok)
+	require.Equal(t, "A fish swims.", doc.Text)
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	store, _ := testStore(t)
+
+	tests := []struct {
+		name  string
+		query string
+		k     int
+		want  []string
+	}{
+		{name: "closest first", query: "cat", k: 3, want: []string{"cats", "dogs", "fish"}},
+		{name: "top k", query: "dog", k: 1, want: []string{"dogs"}},
+		{name: "k beyond the store", query: "fish", k: 10, want: []string{"fish", "cats", "dogs"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			matches, err := store.Search(context.Background(), tc.query, tc.k)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, testIDs(matches))
+		})
+	}
+
+	t.Run("scores are cosine similarities", func(t *testing.T) {
+		t.Parallel()
+
+		matches, err := store.Search(context.Background(), "cat", 1)
+		require.NoError(t, err)
+		require.InDelta(t, 1.0, matches[0].Score, 1e-9)
+		require.Equal(t, "pets.md", matches[0].Snippet().Source)
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := store.Search(context.Background(), "cat", 0)
+		require.EqualError(t, err, "k must be positive, got 0")
+
+		_, err = store.SearchEmbedding([]float64{1}, 1)
+		require.EqualError(t, err, "query embedding has 1 dimensions, want 3")
+	})
+
+	t.Run("empty store", func(t *testing.T) {
+		t.Parallel()
+
+		empty, err := New(testutil.NewEmbeddingMock(topicEmbedding), "embed-v1")
+		require.NoError(t, err)
+		matches, err := empty.Search(context.Background(), "cat", 1)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+}
+
+func TestSearchTool(t *testing.T) {
+	t.Parallel()
+
+	store, _ := testStore(t)
+	tool, fn := store.SearchTool(2)
+	require.Equal(t, SearchMemoryTool, tool.Function.Name)
+
+	t.Run("returns formatted passages", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := fn(context.Background(), `{"query":"cat"}`)
+		require.NoError(t, err)
+		require.Equal(t, "[1] (pets.md) A cat sleeps. Every cat purrs.\n\n[2] A dog barks at the cat.", result.Content)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := fn(context.Background(), `{"query":" "}`)
+		require.EqualError(t, err, "query is required")
+
+		_, err = fn(context.Background(), `not json`)
+		require.ErrorContains(t, err, "invalid arguments")
+	})
+
+	t.Run("runs in an agent", func(t *testing.T) {
+		t.Parallel()
+
+		mock := testutil.NewMockProvider()
+		mock.CompletionFunc = func(_ context.Context, params providers.CompletionParams) (*providers.ChatCompletion, error) {
+			if len(params.Messages) == 1 {
+				return testutil.MockChatCompletionWithToolCalls([]providers.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: providers.FunctionCall{Name: SearchMemoryTool, Arguments: `{"query":"fish"}`},
+				}}), nil
+			}
+			return testutil.MockChatCompletion(params.Messages[len(params.Messages)-1].ContentString()), nil
+		}
+
+		result, err := agent.RunTools(context.Background(), mock, providers.CompletionParams{
+			Model:    "test-model",
+			Messages: []providers.Message{{Role: providers.RoleUser, Content: "What swims?"}},
+			Tools:    []providers.Tool{tool},
+		}, map[string]agent.ToolFunc{SearchMemoryTool: fn}, agent.WithStrategy(agent.StrategyNative))
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Steps)
+		require.True(t, strings.HasPrefix(result.Completion.Choices[0].Message.ContentString(), "[1] A fish swims."))
+	})
+}